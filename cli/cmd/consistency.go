@@ -0,0 +1,60 @@
+//go:build !test
+// +build !test
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/cello-proj/cello/internal/requests"
+
+	"github.com/spf13/cobra"
+)
+
+// consistencyCmd represents the consistency command
+var consistencyCmd = &cobra.Command{
+	Use:   "consistency",
+	Short: "Checks the consistency of the service's database and credentials provider",
+	Long:  "Checks and repairs the consistency of the projects and tokens in the service's database and credentials provider. Requires an admin token.",
+}
+
+// consistencyCheckCmd represents the consistency check command
+var consistencyCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Reports the discrepancies between the database and credentials provider",
+	Long:  "Lists the projects, targets and tokens of the database and credentials provider, and the discrepancies between them",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.CheckConsistency(context.Background())
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// consistencyRepairCmd represents the consistency repair command
+var consistencyRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Repairs the discrepancies between the database and credentials provider",
+	Long:  "Removes projects and tokens which only exist in the database or the credentials provider. Projects with targets are not removed.",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.RepairConsistency(context.Background(), requests.RepairConsistency{DryRun: dryRun})
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(consistencyCmd)
+	consistencyCmd.AddCommand(consistencyCheckCmd, consistencyRepairCmd)
+
+	consistencyRepairCmd.Flags().BoolVar(&dryRun, "dry_run", true, "Report the repairs without making them, set to false to make them")
+}
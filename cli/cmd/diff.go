@@ -23,7 +23,7 @@ var diffCmd = &cobra.Command{
 			cobra.CheckErr(err)
 		}
 
-		apiCl := api.NewClient(argoCloudOpsServiceAddr(), token)
+		apiCl := newAPIClient(token)
 
 		resp, err := apiCl.Diff(context.Background(), api.TargetOperationInput{Path: gitPath, ProjectName: projectName, SHA: gitSHA, TargetName: targetName})
 		if err != nil {
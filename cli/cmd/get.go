@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/cello-proj/cello/cli/internal/api"
-
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +16,7 @@ var getCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
-		apiCl := api.NewClient(argoCloudOpsServiceAddr(), "")
+		apiCl := newAPIClient("")
 
 		status, err := apiCl.GetWorkflowStatus(context.Background(), name)
 		if err != nil {
@@ -0,0 +1,128 @@
+//go:build !test
+// +build !test
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cello-proj/cello/cli/internal/helpers"
+	"github.com/cello-proj/cello/internal/requests"
+
+	"github.com/spf13/cobra"
+)
+
+// identityCmd represents the identity command
+var identityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "Manages identities",
+	Long:  "Manages identities and the roles bound to them. Requires an admin token.",
+}
+
+// identityCreateCmd represents the identity create command
+var identityCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates an identity",
+	Long:  "Creates an identity, outputting its token",
+	Run: func(cmd *cobra.Command, args []string) {
+		roles, err := helpers.ParseRoleBindings(identityRoles)
+		if err != nil {
+			cobra.CheckErr(fmt.Errorf("unable to generate roles, error: %w", err))
+		}
+
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.CreateIdentity(context.Background(), requests.CreateIdentity{Name: identityName, Roles: roles})
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// identityListCmd represents the identity list command
+var identityListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists identities",
+	Long:  "Lists identities",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.ListIdentities(context.Background())
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// identityGetCmd represents the identity get command
+var identityGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Gets an identity",
+	Long:  "Gets an identity",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.GetIdentity(context.Background(), identityName)
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// identityUpdateCmd represents the identity update command
+var identityUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Updates the roles of an identity",
+	Long:  "Replaces the roles bound to an identity",
+	Run: func(cmd *cobra.Command, args []string) {
+		roles, err := helpers.ParseRoleBindings(identityRoles)
+		if err != nil {
+			cobra.CheckErr(fmt.Errorf("unable to generate roles, error: %w", err))
+		}
+
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.UpdateIdentityRoles(context.Background(), identityName, requests.UpdateIdentityRoles{Roles: roles})
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// identityDeleteCmd represents the identity delete command
+var identityDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes an identity",
+	Long:  "Deletes an identity, revoking its token",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		if err := apiCl.DeleteIdentity(context.Background(), identityName); err != nil {
+			cobra.CheckErr(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(identityCmd)
+	identityCmd.AddCommand(identityCreateCmd, identityListCmd, identityGetCmd, identityUpdateCmd, identityDeleteCmd)
+
+	for _, c := range []*cobra.Command{identityCreateCmd, identityGetCmd, identityUpdateCmd, identityDeleteCmd} {
+		c.Flags().StringVarP(&identityName, "name", "i", "", "Name of identity")
+		c.MarkFlagRequired("name")
+	}
+
+	for _, c := range []*cobra.Command{identityCreateCmd, identityUpdateCmd} {
+		c.Flags().StringArrayVarP(&identityRoles, "role", "r", nil, "Role, optionally bound to a project, can be repeated (-r operator -r project-admin=project1)")
+		c.MarkFlagRequired("role")
+	}
+}
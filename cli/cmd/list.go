@@ -7,8 +7,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/cello-proj/cello/cli/internal/api"
-
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +16,7 @@ var listCmd = &cobra.Command{
 	Short: "List workflow executions for a given project and target",
 	Long:  "List workflow executions for a given project and target",
 	Run: func(cmd *cobra.Command, args []string) {
-		apiCl := api.NewClient(argoCloudOpsServiceAddr(), "")
+		apiCl := newAPIClient("")
 
 		resp, err := apiCl.GetWorkflows(context.Background(), projectName, targetName)
 		if err != nil {
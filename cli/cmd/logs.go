@@ -6,8 +6,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/cello-proj/cello/cli/internal/api"
-
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +18,7 @@ var logsCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		workflowName := args[0]
 
-		apiCl := api.NewClient(argoCloudOpsServiceAddr(), "")
+		apiCl := newAPIClient("")
 
 		ctx := context.Background()
 		if streamLogs {
@@ -0,0 +1,59 @@
+//go:build !test
+// +build !test
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/cello-proj/cello/internal/requests"
+
+	"github.com/spf13/cobra"
+)
+
+// projectCmd represents the project command
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manages a project",
+	Long:  "Manages a project. Requires an admin token.",
+}
+
+// projectUpdateCmd represents the project update command
+var projectUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Updates the token settings and workflow quota of a project",
+	Long:  "Updates the token settings and workflow quota of a project. Only the flags which are set are updated, setting one to 0 restores the service's default.",
+	Run: func(cmd *cobra.Command, args []string) {
+		input := requests.UpdateProject{}
+		if cmd.Flags().Changed("token_limit") {
+			input.TokenLimit = &tokenLimit
+		}
+		if cmd.Flags().Changed("token_ttl") {
+			input.TokenTTL = &tokenTTL
+		}
+		if cmd.Flags().Changed("workflow_quota") {
+			input.WorkflowQuota = &workflowQuota
+		}
+
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.UpdateProject(context.Background(), projectName, input)
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(projectCmd)
+	projectCmd.AddCommand(projectUpdateCmd)
+
+	projectUpdateCmd.Flags().StringVarP(&projectName, "project_name", "n", "", "Name of project")
+	projectUpdateCmd.Flags().IntVar(&tokenLimit, "token_limit", 0, "Number of tokens the project can have")
+	projectUpdateCmd.Flags().IntVar(&tokenTTL, "token_ttl", 0, "Default TTL of the project's tokens in seconds")
+	projectUpdateCmd.Flags().IntVar(&workflowQuota, "workflow_quota", 0, "Number of workflows the project can run concurrently")
+
+	projectUpdateCmd.MarkFlagRequired("project_name")
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
 
+	"github.com/cello-proj/cello/cli/internal/api"
+
 	"github.com/spf13/cobra"
 )
 
@@ -17,14 +20,23 @@ var rootCmd = &cobra.Command{
 var (
 	// Flags
 	argumentsCSV            string
+	dryRun                  bool
 	environmentVariablesCSV string
 	framework               string
 	gitPath                 string
 	gitSHA                  string
+	gracePeriod             int
+	identityName            string
+	identityRoles           []string
 	parametersCSV           string
 	projectName             string
 	streamLogs              bool
 	targetName              string
+	tokenID                 string
+	tokenLimit              int
+	tokenScopes             []string
+	tokenTTL                int
+	workflowQuota           int
 	workflowTemplateName    string
 	workflowType            string
 
@@ -60,12 +72,37 @@ func argoCloudOpsUserToken() (string, error) {
 	legacyKey := "ARGO_CLOUDOPS_USER_TOKEN" // #nosec G101
 	key := "CELLO_USER_TOKEN"
 	result := envOrLegacy(key, legacyKey)
-	if len(result) == 0 {
+	// Requests are authenticated by the client certificate when there is no
+	// token.
+	if len(result) == 0 && os.Getenv(clientCertFileKey) == "" {
 		return "", fmt.Errorf("%s not found", key)
 	}
 	return result, nil
 }
 
+const (
+	clientCertFileKey = "CELLO_CLIENT_CERT_FILE"
+	clientKeyFileKey  = "CELLO_CLIENT_KEY_FILE"
+)
+
+// newAPIClient returns an API client of the service authorized by the token,
+// presenting the client certificate when one is configured.
+func newAPIClient(token string) api.Client {
+	certFile, keyFile := os.Getenv(clientCertFileKey), os.Getenv(clientKeyFileKey)
+	if certFile == "" && keyFile == "" {
+		return api.NewClient(argoCloudOpsServiceAddr(), token)
+	}
+	if certFile == "" || keyFile == "" {
+		cobra.CheckErr(fmt.Errorf("both %s and %s are required", clientCertFileKey, clientKeyFileKey))
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		cobra.CheckErr(fmt.Errorf("unable to load client certificate: %w", err))
+	}
+	return api.NewClient(argoCloudOpsServiceAddr(), token, api.WithClientCertificate(cert))
+}
+
 func envOrLegacy(key, legacyKey string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -0,0 +1,137 @@
+//go:build !test
+// +build !test
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cello-proj/cello/cli/internal/api"
+	"github.com/cello-proj/cello/cli/internal/helpers"
+	"github.com/cello-proj/cello/internal/requests"
+
+	"github.com/spf13/cobra"
+)
+
+// tokenCmd represents the token command
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manages the tokens of a project",
+	Long:  "Manages the tokens of a project. Requires an admin token.",
+}
+
+// tokenCreateCmd represents the token create command
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates a token for a project",
+	Long:  "Creates a token for a project",
+	Run: func(cmd *cobra.Command, args []string) {
+		scopes, err := helpers.ParseTokenScopes(tokenScopes)
+		if err != nil {
+			cobra.CheckErr(fmt.Errorf("unable to generate scopes, error: %w", err))
+		}
+
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.CreateToken(context.Background(), projectName, requests.CreateToken{Scopes: scopes, TTL: tokenTTL})
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// tokenListCmd represents the token list command
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the tokens of a project",
+	Long:  "Lists the tokens of a project",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.ListTokens(context.Background(), projectName)
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// tokenRotateCmd represents the token rotate command
+var tokenRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Rotates a token of a project",
+	Long:  "Creates a replacement for a token of a project, with the same scopes, and revokes the token once the grace period ends",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		resp, err := apiCl.RotateToken(context.Background(), projectName, tokenID, requests.RotateToken{GracePeriod: gracePeriod, TTL: tokenTTL})
+		if err != nil {
+			cobra.CheckErr(err)
+		}
+
+		printJSON(resp)
+	},
+}
+
+// tokenDeleteCmd represents the token delete command
+var tokenDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes a token of a project",
+	Long:  "Deletes a token of a project",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiCl := adminAPIClient()
+
+		if err := apiCl.DeleteToken(context.Background(), projectName, tokenID); err != nil {
+			cobra.CheckErr(err)
+		}
+	},
+}
+
+// adminAPIClient returns an API client authorized by the user token, which
+// must be an admin token.
+func adminAPIClient() api.Client {
+	token, err := argoCloudOpsUserToken()
+	if err != nil {
+		cobra.CheckErr(err)
+	}
+
+	return newAPIClient(token)
+}
+
+// printJSON outputs the response, as our current contract is to output json.
+func printJSON(resp interface{}) {
+	output, err := json.Marshal(resp)
+	if err != nil {
+		cobra.CheckErr(fmt.Errorf("unable to generate output, error: %w", err))
+	}
+
+	fmt.Println(string(output))
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRotateCmd, tokenDeleteCmd)
+
+	for _, c := range []*cobra.Command{tokenCreateCmd, tokenListCmd, tokenRotateCmd, tokenDeleteCmd} {
+		c.Flags().StringVarP(&projectName, "project_name", "n", "", "Name of project")
+		c.MarkFlagRequired("project_name")
+	}
+
+	for _, c := range []*cobra.Command{tokenRotateCmd, tokenDeleteCmd} {
+		c.Flags().StringVarP(&tokenID, "token_id", "i", "", "ID of token")
+		c.MarkFlagRequired("token_id")
+	}
+
+	tokenCreateCmd.Flags().StringArrayVarP(&tokenScopes, "scope", "s", nil, "Target and CSV of operations the token is restricted to, can be repeated (-s target1=diff,sync)")
+
+	for _, c := range []*cobra.Command{tokenCreateCmd, tokenRotateCmd} {
+		c.Flags().IntVar(&tokenTTL, "ttl", 0, "TTL of the token in seconds (Default: the project's token TTL)")
+	}
+
+	tokenRotateCmd.Flags().IntVarP(&gracePeriod, "grace_period", "g", 0, "Seconds the rotated token remains valid (Default: revoked immediately)")
+}
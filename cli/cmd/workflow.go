@@ -7,7 +7,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/cello-proj/cello/cli/internal/api"
 	"github.com/cello-proj/cello/cli/internal/helpers"
 	"github.com/cello-proj/cello/internal/requests"
 
@@ -44,7 +43,7 @@ var workflowCmd = &cobra.Command{
 			cobra.CheckErr(fmt.Errorf("unable to generate parameters, error: %w", err))
 		}
 
-		apiCl := api.NewClient(argoCloudOpsServiceAddr(), token)
+		apiCl := newAPIClient(token)
 
 		input := requests.CreateWorkflow{
 			Arguments:            arguments,
@@ -33,8 +33,19 @@ type Client struct {
 	endpoint   string
 }
 
+// Option configures the TLS of an API client.
+type Option func(*tls.Config)
+
+// WithClientCertificate presents the certificate to services requiring
+// mutual TLS, which authenticates requests without an auth token.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *tls.Config) {
+		c.Certificates = append(c.Certificates, cert)
+	}
+}
+
 // NewClient returns a new API client.
-func NewClient(endpoint, authToken string) Client {
+func NewClient(endpoint, authToken string, opts ...Option) Client {
 	// Automatically disable TLS verification if it's a local endpoint.
 	// TODO handle this better.
 	tr := &http.Transport{}
@@ -43,6 +54,14 @@ func NewClient(endpoint, authToken string) Client {
 			InsecureSkipVerify: true, // #nosec G402
 		}
 	}
+	if len(opts) > 0 {
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{}
+		}
+		for _, opt := range opts {
+			opt(tr.TLSClientConfig)
+		}
+	}
 
 	return Client{
 		authToken:  authToken,
@@ -221,6 +240,195 @@ func (c *Client) ExecuteWorkflow(ctx context.Context, input requests.CreateWorkf
 	return output, nil
 }
 
+// CreateIdentity creates an identity, the response contains its token.
+func (c *Client) CreateIdentity(ctx context.Context, input requests.CreateIdentity) (responses.CreateIdentity, error) {
+	url := fmt.Sprintf("%s/identities", c.endpoint)
+
+	body, err := c.request(ctx, http.MethodPost, url, input)
+	if err != nil {
+		return responses.CreateIdentity{}, err
+	}
+
+	var output responses.CreateIdentity
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.CreateIdentity{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// ListIdentities lists the identities.
+func (c *Client) ListIdentities(ctx context.Context) ([]responses.GetIdentity, error) {
+	url := fmt.Sprintf("%s/identities", c.endpoint)
+
+	body, err := c.request(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []responses.GetIdentity
+	if err := json.Unmarshal(body, &output); err != nil {
+		return nil, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// GetIdentity gets an identity.
+func (c *Client) GetIdentity(ctx context.Context, name string) (responses.GetIdentity, error) {
+	url := fmt.Sprintf("%s/identities/%s", c.endpoint, name)
+
+	body, err := c.request(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return responses.GetIdentity{}, err
+	}
+
+	var output responses.GetIdentity
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.GetIdentity{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// UpdateIdentityRoles replaces the roles of an identity.
+func (c *Client) UpdateIdentityRoles(ctx context.Context, name string, input requests.UpdateIdentityRoles) (responses.GetIdentity, error) {
+	url := fmt.Sprintf("%s/identities/%s/roles", c.endpoint, name)
+
+	body, err := c.request(ctx, http.MethodPut, url, input)
+	if err != nil {
+		return responses.GetIdentity{}, err
+	}
+
+	var output responses.GetIdentity
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.GetIdentity{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// DeleteIdentity deletes an identity.
+func (c *Client) DeleteIdentity(ctx context.Context, name string) error {
+	url := fmt.Sprintf("%s/identities/%s", c.endpoint, name)
+
+	_, err := c.request(ctx, http.MethodDelete, url, nil)
+	return err
+}
+
+// CreateToken creates a token for the project.
+func (c *Client) CreateToken(ctx context.Context, project string, input requests.CreateToken) (responses.CreateToken, error) {
+	url := fmt.Sprintf("%s/projects/%s/tokens", c.endpoint, project)
+
+	body, err := c.request(ctx, http.MethodPost, url, input)
+	if err != nil {
+		return responses.CreateToken{}, err
+	}
+
+	var output responses.CreateToken
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.CreateToken{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// DeleteToken deletes a token of the project.
+func (c *Client) DeleteToken(ctx context.Context, project, tokenID string) error {
+	url := fmt.Sprintf("%s/projects/%s/tokens/%s", c.endpoint, project, tokenID)
+
+	_, err := c.request(ctx, http.MethodDelete, url, nil)
+	return err
+}
+
+// ListTokens lists the tokens of the project.
+func (c *Client) ListTokens(ctx context.Context, project string) ([]responses.ListTokens, error) {
+	url := fmt.Sprintf("%s/projects/%s/tokens", c.endpoint, project)
+
+	body, err := c.request(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []responses.ListTokens
+	if err := json.Unmarshal(body, &output); err != nil {
+		return nil, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// RotateToken replaces a token of the project, revoking it after the grace
+// period of the input.
+func (c *Client) RotateToken(ctx context.Context, project, tokenID string, input requests.RotateToken) (responses.RotateToken, error) {
+	url := fmt.Sprintf("%s/projects/%s/tokens/%s/rotate", c.endpoint, project, tokenID)
+
+	body, err := c.request(ctx, http.MethodPost, url, input)
+	if err != nil {
+		return responses.RotateToken{}, err
+	}
+
+	var output responses.RotateToken
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.RotateToken{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// CheckConsistency reports the discrepancies between the service's database
+// and credentials provider.
+func (c *Client) CheckConsistency(ctx context.Context) (responses.Consistency, error) {
+	url := fmt.Sprintf("%s/consistency", c.endpoint)
+
+	body, err := c.request(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return responses.Consistency{}, err
+	}
+
+	var output responses.Consistency
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.Consistency{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// RepairConsistency repairs the discrepancies between the service's database
+// and credentials provider, or reports the repairs on a dry run.
+func (c *Client) RepairConsistency(ctx context.Context, input requests.RepairConsistency) (responses.Consistency, error) {
+	url := fmt.Sprintf("%s/consistency/repair", c.endpoint)
+
+	body, err := c.request(ctx, http.MethodPost, url, input)
+	if err != nil {
+		return responses.Consistency{}, err
+	}
+
+	var output responses.Consistency
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.Consistency{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
+// UpdateProject updates the token settings of the project.
+func (c *Client) UpdateProject(ctx context.Context, project string, input requests.UpdateProject) (responses.GetProject, error) {
+	url := fmt.Sprintf("%s/projects/%s", c.endpoint, project)
+
+	body, err := c.request(ctx, http.MethodPatch, url, input)
+	if err != nil {
+		return responses.GetProject{}, err
+	}
+
+	var output responses.GetProject
+	if err := json.Unmarshal(body, &output); err != nil {
+		return responses.GetProject{}, fmt.Errorf("unable to parse response: %w", err)
+	}
+
+	return output, nil
+}
+
 // Sync submits a "sync" for the provided project target.
 func (c *Client) Sync(ctx context.Context, input TargetOperationInput) (responses.Sync, error) {
 	output, err := c.targetOperation(ctx, input, sync)
@@ -255,6 +463,43 @@ func (c *Client) getRequest(ctx context.Context, url string) ([]byte, error) {
 	return body, nil
 }
 
+// request makes an authorized api request with the input, when set, as the
+// JSON body and returns the response body.
+func (c *Client) request(ctx context.Context, method, url string, input interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if input != nil {
+		b, err := json.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create api request body, error: %w", err)
+		}
+		reqBody = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create api request: %w", err)
+	}
+
+	req.Header.Add("Authorization", c.authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to make api call: %w", err)
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body. status code: %d, error: %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode >= 300 || resp.StatusCode < 200 {
+		return nil, fmt.Errorf("received unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
 func (c *Client) targetOperation(ctx context.Context, input TargetOperationInput, operationType string) (responses.TargetOperation, error) {
 	url := fmt.Sprintf("%s/projects/%s/targets/%s/operations", c.endpoint, input.ProjectName, input.TargetName)
 
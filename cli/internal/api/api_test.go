@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/cello-proj/cello/internal/requests"
 	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -901,6 +903,541 @@ func TestExec(t *testing.T) {
 	}
 }
 
+func TestCreateToken(t *testing.T) {
+	input := requests.CreateToken{
+		Scopes: types.TokenScopes{{Target: "target1", Operations: []string{"diff"}}},
+		TTL:    86400,
+	}
+
+	tests := []struct {
+		name                  string
+		apiRespBody           []byte
+		apiRespStatusCode     int
+		endpoint              string          // Used to create new request error.
+		mockHTTPClient        *mockHTTPClient // Only used when needed.
+		writeBadContentLength bool            // Used to create response body error.
+		want                  responses.CreateToken
+		wantErr               error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "create_token_response_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: responses.CreateToken{
+				CreatedAt: "2022-06-21T14:56:10.341066-07:00",
+				ExpiresAt: "2022-06-22T14:56:10.341066-07:00",
+				Scopes:    input.Scopes,
+				Token:     "vault:role-id:secret",
+				TokenID:   "token1",
+			},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusInternalServerError,
+			wantErr:           fmt.Errorf("received unexpected status code: 500, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+		{
+			name:     "error creating http request",
+			endpoint: string('\f'),
+			wantErr:  fmt.Errorf(`unable to create api request: parse "\f/projects/project1/tokens": net/url: invalid control character in URL`),
+		},
+		{
+			name:           "error making http request",
+			mockHTTPClient: &mockHTTPClient{errDo: fmt.Errorf("boom")},
+			wantErr:        fmt.Errorf("unable to make api call: boom"),
+		},
+		{
+			name:                  "error reading body",
+			apiRespBody:           nil,
+			apiRespStatusCode:     http.StatusOK,
+			writeBadContentLength: true,
+			wantErr:               fmt.Errorf("error reading response body. status code: %d, error: unexpected EOF", http.StatusOK),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantURL := "/projects/project1/tokens"
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != wantURL {
+					http.NotFound(w, r)
+				}
+
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+
+				if tt.writeBadContentLength {
+					w.Header().Set("Content-Length", "1")
+				}
+
+				// Make sure the request we received is what we want
+				body, err := io.ReadAll(r.Body)
+				r.Body.Close()
+
+				assert.Nil(t, err, "unable to read request body")
+
+				assert.JSONEq(t, string(readFile(t, "create_token_request_good.json")), string(body))
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			if tt.endpoint != "" {
+				client.endpoint = tt.endpoint
+			}
+
+			if tt.mockHTTPClient != nil {
+				client.httpClient = tt.mockHTTPClient
+			}
+
+			output, err := client.CreateToken(context.Background(), "project1", input)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              responses.Consistency
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "consistency_response_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: responses.Consistency{
+				Projects: []responses.ConsistencyProject{{
+					Name:                      "project1",
+					InDatabase:                true,
+					InCredentialsProvider:     true,
+					Targets:                   []string{"target1"},
+					DatabaseTokens:            []string{"token1", "token2"},
+					CredentialsProviderTokens: []string{"token1"},
+				}},
+				Discrepancies: []responses.ConsistencyDiscrepancy{{
+					Type:    "token_not_in_credentials_provider",
+					Project: "project1",
+					TokenID: "token2",
+					Action:  "delete_database_token",
+				}},
+				DryRun: true,
+			},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusNotFound,
+			wantErr:           fmt.Errorf("received unexpected status code: 404, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/consistency" || r.Method != http.MethodGet {
+					http.NotFound(w, r)
+					return
+				}
+
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.CheckConsistency(context.Background())
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestListTokens(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              []responses.ListTokens
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "list_tokens_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: []responses.ListTokens{{
+				CreatedAt: "2022-06-21T14:56:10.341066-07:00",
+				ExpiresAt: "2023-06-21T14:56:10.341066-07:00",
+				TokenID:   "token1",
+			}},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusNotFound,
+			wantErr:           fmt.Errorf("received unexpected status code: 404, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/projects/project1/tokens" || r.Method != http.MethodGet {
+					http.NotFound(w, r)
+					return
+				}
+
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.ListTokens(context.Background(), "project1")
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiRespStatusCode int
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespStatusCode: http.StatusOK,
+		},
+		{
+			name:              "error non-200 response",
+			apiRespStatusCode: http.StatusInternalServerError,
+			wantErr:           fmt.Errorf("received unexpected status code: 500, body: "),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/projects/project1/tokens/token1" || r.Method != http.MethodDelete {
+					http.NotFound(w, r)
+					return
+				}
+
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			err := client.DeleteToken(context.Background(), "project1", "token1")
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestRepairConsistency(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              responses.Consistency
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "consistency_response_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: responses.Consistency{
+				Projects: []responses.ConsistencyProject{{
+					Name:                      "project1",
+					InDatabase:                true,
+					InCredentialsProvider:     true,
+					Targets:                   []string{"target1"},
+					DatabaseTokens:            []string{"token1", "token2"},
+					CredentialsProviderTokens: []string{"token1"},
+				}},
+				Discrepancies: []responses.ConsistencyDiscrepancy{{
+					Type:    "token_not_in_credentials_provider",
+					Project: "project1",
+					TokenID: "token2",
+					Action:  "delete_database_token",
+				}},
+				DryRun: true,
+			},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusNotFound,
+			wantErr:           fmt.Errorf("received unexpected status code: 404, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/consistency/repair" || r.Method != http.MethodPost {
+					http.NotFound(w, r)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				r.Body.Close()
+
+				assert.Nil(t, err, "unable to read request body")
+				assert.JSONEq(t, string(readFile(t, "repair_consistency_request_good.json")), string(body))
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.RepairConsistency(context.Background(), requests.RepairConsistency{DryRun: true})
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestRotateToken(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              responses.RotateToken
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "rotate_token_response_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: responses.RotateToken{
+				CreatedAt:              "2022-06-21T14:56:10.341066-07:00",
+				ExpiresAt:              "2023-06-21T14:56:10.341066-07:00",
+				Token:                  "vault:role-id:secret2",
+				TokenID:                "token2",
+				PreviousTokenExpiresAt: "2022-06-21T15:56:10.341066-07:00",
+				PreviousTokenID:        "token1",
+			},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusNotFound,
+			wantErr:           fmt.Errorf("received unexpected status code: 404, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/projects/project1/tokens/token1/rotate" || r.Method != http.MethodPost {
+					http.NotFound(w, r)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				r.Body.Close()
+
+				assert.Nil(t, err, "unable to read request body")
+				assert.JSONEq(t, string(readFile(t, "rotate_token_request_good.json")), string(body))
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.RotateToken(context.Background(), "project1", "token1", requests.RotateToken{GracePeriod: 3600})
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestUpdateProject(t *testing.T) {
+	tokenLimit := 5
+
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              responses.GetProject
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "update_project_response_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: responses.GetProject{
+				Name:       "project1",
+				Repository: "https://github.com/cello-proj/cello.git",
+				TokenLimit: 5,
+				TokenTTL:   31593600,
+			},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusBadRequest,
+			wantErr:           fmt.Errorf("received unexpected status code: 400, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/projects/project1" || r.Method != http.MethodPatch {
+					http.NotFound(w, r)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				r.Body.Close()
+
+				assert.Nil(t, err, "unable to read request body")
+				assert.JSONEq(t, string(readFile(t, "update_project_request_good.json")), string(body))
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.UpdateProject(context.Background(), "project1", requests.UpdateProject{TokenLimit: &tokenLimit})
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
 type mockHTTPClient struct {
 	errDo error
 }
@@ -937,3 +1474,289 @@ var (
 		WorkflowTemplateName: "cello-single-step-vault-aws",
 	}
 )
+
+func TestCreateIdentity(t *testing.T) {
+	input := requests.CreateIdentity{
+		Name:  "teamlead1",
+		Roles: types.RoleBindings{{Role: "project-admin", Project: "project1"}},
+	}
+
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              responses.CreateIdentity
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "create_identity_response_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: responses.CreateIdentity{
+				Name:  "teamlead1",
+				Roles: input.Roles,
+				Token: "vault:identity/teamlead1:secret",
+			},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusBadRequest,
+			wantErr:           fmt.Errorf("received unexpected status code: 400, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/identities" || r.Method != http.MethodPost {
+					http.NotFound(w, r)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				r.Body.Close()
+
+				assert.Nil(t, err, "unable to read request body")
+
+				assert.JSONEq(t, string(readFile(t, "create_identity_request_good.json")), string(body))
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.CreateIdentity(context.Background(), input)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestListIdentities(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              []responses.GetIdentity
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "list_identities_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: []responses.GetIdentity{{
+				CreatedAt: "2022-06-21T14:56:10Z",
+				Name:      "teamlead1",
+				Roles:     types.RoleBindings{{Role: "project-admin", Project: "project1"}},
+			}},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusUnauthorized,
+			wantErr:           fmt.Errorf("received unexpected status code: 401, body: boom"),
+		},
+		{
+			name:              "error non-json response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: 200,
+			wantErr:           fmt.Errorf("unable to parse response: invalid character 'b' looking for beginning of value"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/identities" || r.Method != http.MethodGet {
+					http.NotFound(w, r)
+					return
+				}
+
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.ListIdentities(context.Background())
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestUpdateIdentityRoles(t *testing.T) {
+	input := requests.UpdateIdentityRoles{Roles: types.RoleBindings{{Role: "viewer"}}}
+
+	tests := []struct {
+		name              string
+		apiRespBody       []byte
+		apiRespStatusCode int
+		want              responses.GetIdentity
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespBody:       readFile(t, "update_identity_roles_response_good.json"),
+			apiRespStatusCode: http.StatusOK,
+			want: responses.GetIdentity{
+				CreatedAt: "2022-06-21T14:56:10Z",
+				Name:      "teamlead1",
+				Roles:     input.Roles,
+			},
+		},
+		{
+			name:              "error non-200 response",
+			apiRespBody:       []byte("boom"),
+			apiRespStatusCode: http.StatusNotFound,
+			wantErr:           fmt.Errorf("received unexpected status code: 404, body: boom"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/identities/teamlead1/roles" || r.Method != http.MethodPut {
+					http.NotFound(w, r)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				r.Body.Close()
+
+				assert.Nil(t, err, "unable to read request body")
+
+				assert.JSONEq(t, string(readFile(t, "update_identity_roles_request_good.json")), string(body))
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+				fmt.Fprint(w, string(tt.apiRespBody))
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			output, err := client.UpdateIdentityRoles(context.Background(), "teamlead1", input)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestDeleteIdentity(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiRespStatusCode int
+		wantErr           error
+	}{
+		{
+			name:              "good",
+			apiRespStatusCode: http.StatusOK,
+		},
+		{
+			name:              "error non-200 response",
+			apiRespStatusCode: http.StatusInternalServerError,
+			wantErr:           fmt.Errorf("received unexpected status code: 500, body: "),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/identities/teamlead1" || r.Method != http.MethodDelete {
+					http.NotFound(w, r)
+					return
+				}
+
+				assert.Equal(t, r.Header.Get("Authorization"), authToken)
+
+				w.WriteHeader(tt.apiRespStatusCode)
+			}))
+			defer server.Close()
+
+			client := Client{
+				authToken:  authToken,
+				endpoint:   server.URL,
+				httpClient: &http.Client{},
+			}
+
+			err := client.DeleteIdentity(context.Background(), "teamlead1")
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestNewClientWithClientCertificate(t *testing.T) {
+	cert := tls.Certificate{Certificate: [][]byte{[]byte("cert")}}
+
+	tests := []struct {
+		name               string
+		endpoint           string
+		wantInsecureVerify bool
+	}{
+		{
+			name:     "remote endpoint",
+			endpoint: "https://cello.example.com",
+		},
+		{
+			name:               "local endpoint",
+			endpoint:           defaultLocalSecureURI,
+			wantInsecureVerify: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(tt.endpoint, "", WithClientCertificate(cert))
+
+			tr := client.httpClient.(*http.Client).Transport.(*http.Transport)
+			assert.Equal(t, []tls.Certificate{cert}, tr.TLSClientConfig.Certificates)
+			assert.Equal(t, tt.wantInsecureVerify, tr.TLSClientConfig.InsecureSkipVerify)
+		})
+	}
+}
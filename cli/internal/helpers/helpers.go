@@ -8,6 +8,8 @@ package helpers
 import (
 	"fmt"
 	"strings"
+
+	"github.com/cello-proj/cello/internal/types"
 )
 
 // GenerateParameters converts a csv of equals separated values into a map of strings.
@@ -58,3 +60,35 @@ func ParseEqualsSeparatedCSVToMap(s string) (map[string]string, error) {
 	}
 	return r, nil
 }
+
+// ParseTokenScopes converts equals separated targets and csv of operations
+// (target1=diff,sync) into token scopes.
+func ParseTokenScopes(scopes []string) (types.TokenScopes, error) {
+	r := types.TokenScopes{}
+	for _, s := range scopes {
+		v := strings.SplitN(s, "=", 2)
+		if len(v) != 2 || v[0] == "" || v[1] == "" {
+			return r, fmt.Errorf("could not parse scope %s", s)
+		}
+		r = append(r, types.TokenScope{Target: v[0], Operations: strings.Split(v[1], ",")})
+	}
+	return r, nil
+}
+
+// ParseRoleBindings converts roles, optionally bound to a project with an
+// equals separated value (project-admin=project1), into role bindings.
+func ParseRoleBindings(roles []string) (types.RoleBindings, error) {
+	r := types.RoleBindings{}
+	for _, s := range roles {
+		v := strings.SplitN(s, "=", 2)
+		if v[0] == "" || (len(v) == 2 && v[1] == "") {
+			return r, fmt.Errorf("could not parse role %s", s)
+		}
+		b := types.RoleBinding{Role: v[0]}
+		if len(v) == 2 {
+			b.Project = v[1]
+		}
+		r = append(r, b)
+	}
+	return r, nil
+}
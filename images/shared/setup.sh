@@ -7,14 +7,20 @@
 # credentials which are used to run the framework.
 
 credentials_file=/root/.aws/credentials
+azure_credentials_file=/root/.azure/credentials.env
+gcp_credentials_file=/root/.config/gcloud/credentials.env
+kube_config_file=/root/.kube/config
 
 export VAULT_TOKEN=$1
 export PROJECT_NAME=$2
 export TARGET_NAME=$3
+# Workflows submitted before targets had types only use AWS accounts.
+export TARGET_TYPE=${4:-aws_account}
+export CREDENTIAL_TYPE=${5:-}
 
 usage() {
     echo
-    echo "$0 VAULT_TOKEN PROJECT_NAME TARGET_NAME"
+    echo "$0 VAULT_TOKEN PROJECT_NAME TARGET_NAME [TARGET_TYPE] [CREDENTIAL_TYPE]"
     echo
     echo "CODE_URI env variable must be set with S3 uri for zip archive "
     echo "VAULT_ADDR env variable must have valid vault endpoint"
@@ -57,30 +63,140 @@ echo "Starting setup."
 echo "CODE_URI: $CODE_URI"
 echo "PROJECT_NAME: $PROJECT_NAME"
 echo "TARGET_NAME: $TARGET_NAME"
+echo "TARGET_TYPE: $TARGET_TYPE"
 echo "VAULT_ADDR: $VAULT_ADDR"
 
 #
 # Get credentials from vault
 #
-vault_project_prefix='aws/sts/argo-cloudops'
-target="${vault_project_prefix}-projects-${PROJECT_NAME}-target-${TARGET_NAME}"
-
 token_head=`echo $VAULT_TOKEN |cut -b1-8`
-echo "Exchanging token '${token_head}...' via '$VAULT_ADDR' for target '$target'"
-
-creds=$(vault read --format json $target | \
-    jq -r '"aws_access_key_id=\(.data.access_key)\naws_secret_access_key=\(.data.secret_key)\naws_session_token=\(.data.security_token)"')
 
-echo "Exchanging token successful."
-
-echo "Writing credentials to '$credentials_file'."
-cat > $credentials_file <<EOF
+# The token only allows a few uses, the credentials are read from the path of
+# the target type.
+vault_target="argo-cloudops-projects-${PROJECT_NAME}-target-${TARGET_NAME}"
+case "$TARGET_TYPE" in
+kubernetes_cluster)
+    # kubernetes_cluster targets have their own secrets engine mount.
+    target="kubernetes-${vault_target}"
+    kube_config=$(vault read --format json "${target}/config")
+
+    # Default to the first allowed namespace.
+    if [ -z "${KUBERNETES_NAMESPACE:-}" ]; then
+        KUBERNETES_NAMESPACE=$(vault read --format json "${target}/roles/target" | \
+            jq -r '.data.allowed_kubernetes_namespaces[0]')
+    fi
+
+    echo "Exchanging token '${token_head}...' via '$VAULT_ADDR' for target '$target' namespace '$KUBERNETES_NAMESPACE'"
+
+    kube_token=$(vault write --format json "${target}/creds/target" \
+        kubernetes_namespace="$KUBERNETES_NAMESPACE" | jq -r '.data.service_account_token')
+
+    echo "Exchanging token successful."
+
+    echo "Writing kubeconfig to '$kube_config_file'."
+    mkdir -p `dirname $kube_config_file`
+    kube_ca=$(echo "$kube_config" | jq -r '.data.kubernetes_ca_cert')
+    if [ -n "$kube_ca" ]; then
+        kube_ca_data="certificate-authority-data: $(echo "$kube_ca" | base64 | tr -d '\n')"
+    else
+        kube_ca_data=""
+    fi
+    cat > $kube_config_file <<EOF
+apiVersion: v1
+kind: Config
+clusters:
+- name: target
+  cluster:
+    server: $(echo "$kube_config" | jq -r '.data.kubernetes_host')
+    $kube_ca_data
+contexts:
+- name: target
+  context:
+    cluster: target
+    namespace: $KUBERNETES_NAMESPACE
+    user: target
+current-context: target
+users:
+- name: target
+  user:
+    token: $kube_token
+EOF
+    ;;
+azure_subscription)
+    # azure_subscription targets have their own secrets engine mount.
+    target="azure-${vault_target}"
+    echo "Exchanging token '${token_head}...' via '$VAULT_ADDR' for target '$target'"
+
+    azure_config=$(vault read --format json "${target}/config")
+    azure_creds=$(vault read --format json "${target}/creds/target")
+
+    echo "Exchanging token successful."
+
+    echo "Writing credentials to '$azure_credentials_file'."
+    mkdir -p `dirname $azure_credentials_file`
+    cat > $azure_credentials_file <<EOF
+export ARM_CLIENT_ID=$(echo "$azure_creds" | jq -r '.data.client_id')
+export ARM_CLIENT_SECRET=$(echo "$azure_creds" | jq -r '.data.client_secret')
+export ARM_SUBSCRIPTION_ID=$(echo "$azure_config" | jq -r '.data.subscription_id')
+export ARM_TENANT_ID=$(echo "$azure_config" | jq -r '.data.tenant_id')
+EOF
+    ;;
+gcp_project)
+    # gcp_project targets are rolesets or impersonated accounts of the GCP
+    # secrets engine, both issuing OAuth2 access tokens.
+    if [ "$CREDENTIAL_TYPE" == "impersonated_account" ]; then
+        target="gcp/impersonated-account/${vault_target}/token"
+    else
+        target="gcp/roleset/${vault_target}/token"
+    fi
+    echo "Exchanging token '${token_head}...' via '$VAULT_ADDR' for target '$target'"
+
+    gcp_token=$(vault read --format json "$target" | jq -r '.data.token')
+
+    echo "Exchanging token successful."
+
+    echo "Writing credentials to '$gcp_credentials_file'."
+    mkdir -p `dirname $gcp_credentials_file`
+    cat > $gcp_credentials_file <<EOF
+export CLOUDSDK_AUTH_ACCESS_TOKEN=$gcp_token
+export GOOGLE_OAUTH_ACCESS_TOKEN=$gcp_token
+EOF
+    ;;
+aws_account)
+    # iam_user targets issue credentials from aws/creds instead of aws/sts.
+    sts_args=""
+    if [ "$CREDENTIAL_TYPE" == "iam_user" ]; then
+        target="aws/creds/${vault_target}"
+    else
+        target="aws/sts/${vault_target}"
+
+        # Targets with several role_arns need AWS_ROLE_ARN to select one.
+        if [ -n "${AWS_ROLE_ARN:-}" ]; then
+            sts_args="role_arn=$AWS_ROLE_ARN"
+        fi
+    fi
+
+    echo "Exchanging token '${token_head}...' via '$VAULT_ADDR' for target '$target'"
+
+    creds=$(vault read --format json $target $sts_args | \
+        jq -r '"aws_access_key_id=\(.data.access_key)\naws_secret_access_key=\(.data.secret_key)" + if .data.security_token then "\naws_session_token=\(.data.security_token)" else "" end')
+
+    echo "Exchanging token successful."
+
+    echo "Writing credentials to '$credentials_file'."
+    cat > $credentials_file <<EOF
 [default]
 $creds
 EOF
 
-arn=`aws sts get-caller-identity --output text --query Arn`
-echo "Arn of role assumed '$arn'."
+    arn=`aws sts get-caller-identity --output text --query Arn`
+    echo "Arn of role assumed '$arn'."
+    ;;
+*)
+    echo "Error: target type '$TARGET_TYPE' is not supported"
+    exit 1
+    ;;
+esac
 
 if [[ "$CODE_URI" =~ ^s3://.* ]]; then
     echo "Downloading $CODE_URI from S3."
@@ -3,7 +3,9 @@ package requests
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/cello-proj/cello/internal/types"
 	"github.com/cello-proj/cello/internal/validations"
@@ -109,17 +111,133 @@ func (req CreateGitWorkflow) Validate() error {
 	return validations.ValidateStruct(req)
 }
 
+// CreateIdentity request.
+type CreateIdentity struct {
+	Name  string             `json:"name" valid:"required~name is required,alphanum~name must be alphanumeric,stringlength(4|32)~name must be between 4 and 32 characters"`
+	Roles types.RoleBindings `json:"roles"`
+}
+
+// Validate validates CreateIdentity.
+func (req CreateIdentity) Validate() error {
+	return validations.Validate(
+		func() error { return validations.ValidateStruct(req) },
+		func() error { return validateRoles(req.Roles) },
+	)
+}
+
 // CreateTarget request.
 type CreateTarget types.Target
 
+// CreateWebhook request.
+type CreateWebhook struct {
+	URL string `json:"url" valid:"required~url is required"`
+	// Events are the events the webhook is notified of, all of them when
+	// empty.
+	Events []string `json:"events"`
+}
+
+// Validate validates CreateWebhook.
+func (req CreateWebhook) Validate() error {
+	return validations.Validate(
+		func() error { return validations.ValidateStruct(req) },
+		req.validateURL,
+		req.validateEvents,
+	)
+}
+
+// validateURL validates the URL, http is allowed so webhooks can be
+// delivered to receivers in the cluster. Metadata services are denied, names
+// resolving to them are refused when delivering.
+func (req CreateWebhook) validateURL() error {
+	if !validations.IsValidHTTPURL(req.URL) {
+		return errors.New("url must be an absolute http or https url")
+	}
+
+	// The URL was parsed by IsValidHTTPURL.
+	u, _ := url.Parse(req.URL)
+	if validations.IsMetadataHost(u.Hostname()) {
+		return errors.New("url must not be a link-local or metadata service host")
+	}
+	return nil
+}
+
+func (req CreateWebhook) validateEvents() error {
+	allowed := map[string]bool{}
+	for _, event := range types.WebhookEvents {
+		allowed[event] = true
+	}
+
+	for _, event := range req.Events {
+		if !allowed[event] {
+			return fmt.Errorf("events must be one of '%s'", strings.Join(types.WebhookEvents, " "))
+		}
+	}
+	return nil
+}
+
+// CreateToken request.
+type CreateToken struct {
+	Scopes types.TokenScopes `json:"scopes"`
+	// TTL of the token in seconds, the project's default is used when zero.
+	TTL int `json:"ttl"`
+}
+
+// Validate validates CreateToken.
+func (req CreateToken) Validate(optionalValidations ...func() error) error {
+	v := []func() error{
+		req.Scopes.Validate,
+		func() error { return validateTokenTTL("ttl", req.TTL, 0, 0) },
+	}
+	v = append(v, optionalValidations...)
+
+	return validations.Validate(v...)
+}
+
+// ValidateTTL is an optional validation should be passed as parameter to
+// Validate().
+func (req CreateToken) ValidateTTL(min, max time.Duration) func() error {
+	return func() error {
+		return validateTokenTTL("ttl", req.TTL, min, max)
+	}
+}
+
+// ValidateOperations is an optional validation should be passed as parameter
+// to Validate().
+func (req CreateToken) ValidateOperations(types []string) func() error {
+	return func() error {
+		allowed := map[string]bool{}
+		for _, t := range types {
+			allowed[t] = true
+		}
+
+		for _, scope := range req.Scopes {
+			for _, op := range scope.Operations {
+				if !allowed[op] {
+					return fmt.Errorf("scope operations must be one of '%s'", strings.Join(types, " "))
+				}
+			}
+		}
+		return nil
+	}
+}
+
 // CreateProject request.
 type CreateProject struct {
 	Name       string `json:"name" valid:"required~name is required,alphanum~name must be alphanumeric,stringlength(4|32)~name must be between 4 and 32 characters"`
 	Repository string `json:"repository" valid:"required~repository is required"`
+	// TokenLimit is the number of tokens the project can have, the service's
+	// default is used when zero.
+	TokenLimit int `json:"token_limit"`
+	// TokenTTL is the default TTL of the project's tokens in seconds, the
+	// service's default is used when zero.
+	TokenTTL int `json:"token_ttl"`
+	// WorkflowQuota is the number of workflows the project can run
+	// concurrently, the service's default is used when zero.
+	WorkflowQuota int `json:"workflow_quota"`
 }
 
 // Validate validates CreateProject.
-func (req CreateProject) Validate() error {
+func (req CreateProject) Validate(optionalValidations ...func() error) error {
 	v := []func() error{
 		func() error { return validations.ValidateStruct(req) },
 		func() error {
@@ -128,11 +246,178 @@ func (req CreateProject) Validate() error {
 			}
 			return nil
 		},
+		func() error { return validateTokenLimit(req.TokenLimit) },
+		func() error { return validateTokenTTL("token_ttl", req.TokenTTL, 0, 0) },
+		func() error { return validateWorkflowQuota(req.WorkflowQuota) },
 	}
+	v = append(v, optionalValidations...)
 
 	return validations.Validate(v...)
 }
 
+// ValidateTokenTTL is an optional validation should be passed as parameter
+// to Validate().
+func (req CreateProject) ValidateTokenTTL(min, max time.Duration) func() error {
+	return func() error {
+		return validateTokenTTL("token_ttl", req.TokenTTL, min, max)
+	}
+}
+
+// UpdateProject request. Only the fields which are set are updated, setting
+// a field to zero restores the service's default.
+type UpdateProject struct {
+	TokenLimit    *int `json:"token_limit"`
+	TokenTTL      *int `json:"token_ttl"`
+	WorkflowQuota *int `json:"workflow_quota"`
+}
+
+// Validate validates UpdateProject.
+func (req UpdateProject) Validate(optionalValidations ...func() error) error {
+	v := []func() error{
+		func() error {
+			if req.TokenLimit == nil && req.TokenTTL == nil && req.WorkflowQuota == nil {
+				return errors.New("token_limit, token_ttl or workflow_quota is required")
+			}
+			return nil
+		},
+		func() error {
+			if req.TokenLimit == nil {
+				return nil
+			}
+			return validateTokenLimit(*req.TokenLimit)
+		},
+		func() error {
+			if req.TokenTTL == nil {
+				return nil
+			}
+			return validateTokenTTL("token_ttl", *req.TokenTTL, 0, 0)
+		},
+		func() error {
+			if req.WorkflowQuota == nil {
+				return nil
+			}
+			return validateWorkflowQuota(*req.WorkflowQuota)
+		},
+	}
+	v = append(v, optionalValidations...)
+
+	return validations.Validate(v...)
+}
+
+// ValidateTokenTTL is an optional validation should be passed as parameter
+// to Validate().
+func (req UpdateProject) ValidateTokenTTL(min, max time.Duration) func() error {
+	return func() error {
+		if req.TokenTTL == nil {
+			return nil
+		}
+		return validateTokenTTL("token_ttl", *req.TokenTTL, min, max)
+	}
+}
+
+// UpdateIdentityRoles request. The roles replace the roles of the identity.
+type UpdateIdentityRoles struct {
+	Roles types.RoleBindings `json:"roles"`
+}
+
+// Validate validates UpdateIdentityRoles.
+func (req UpdateIdentityRoles) Validate() error {
+	return validateRoles(req.Roles)
+}
+
+// validateRoles validates the roles of an identity, which must have at least
+// one.
+func validateRoles(roles types.RoleBindings) error {
+	if len(roles) == 0 {
+		return errors.New("roles is required")
+	}
+	return roles.Validate()
+}
+
+// RepairConsistency request.
+type RepairConsistency struct {
+	// DryRun reports the repairs without making them, repairs are only made
+	// when it is set to false.
+	DryRun bool `json:"dry_run"`
+}
+
+// RotateToken request.
+type RotateToken struct {
+	// GracePeriod is the number of seconds the rotated token remains valid,
+	// it is revoked immediately when zero.
+	GracePeriod int `json:"grace_period"`
+	// TTL of the replacement token in seconds, the project's default is used
+	// when zero.
+	TTL int `json:"ttl"`
+}
+
+// Validate validates RotateToken.
+func (req RotateToken) Validate(optionalValidations ...func() error) error {
+	v := []func() error{
+		func() error {
+			if req.GracePeriod < 0 {
+				return errors.New("grace_period cannot be negative")
+			}
+			return nil
+		},
+		func() error { return validateTokenTTL("ttl", req.TTL, 0, 0) },
+	}
+	v = append(v, optionalValidations...)
+
+	return validations.Validate(v...)
+}
+
+// ValidateGracePeriod is an optional validation should be passed as parameter
+// to Validate().
+func (req RotateToken) ValidateGracePeriod(max time.Duration) func() error {
+	return func() error {
+		if time.Duration(req.GracePeriod)*time.Second > max {
+			return fmt.Errorf("grace_period cannot be greater than %d seconds", int64(max.Seconds()))
+		}
+		return nil
+	}
+}
+
+// ValidateTTL is an optional validation should be passed as parameter to
+// Validate().
+func (req RotateToken) ValidateTTL(min, max time.Duration) func() error {
+	return func() error {
+		return validateTokenTTL("ttl", req.TTL, min, max)
+	}
+}
+
+// validateTokenLimit validates a project's token limit, where zero is the
+// service's default.
+func validateTokenLimit(limit int) error {
+	if limit < 0 {
+		return errors.New("token_limit cannot be negative")
+	}
+	return nil
+}
+
+// validateWorkflowQuota validates a project's workflow quota, where zero is
+// the default.
+func validateWorkflowQuota(quota int) error {
+	if quota < 0 {
+		return errors.New("workflow_quota cannot be negative")
+	}
+	return nil
+}
+
+// validateTokenTTL validates a token TTL in seconds, where zero is the
+// default. The TTL is only bounded by min and max when max is set.
+func validateTokenTTL(field string, ttl int, min, max time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("%s cannot be negative", field)
+	}
+
+	d := time.Duration(ttl) * time.Second
+	if ttl == 0 || max == 0 || (d >= min && d <= max) {
+		return nil
+	}
+	return fmt.Errorf("%s must be between %d and %d seconds", field, int64(min.Seconds()), int64(max.Seconds()))
+}
+
 // TargetOperation represents a target operation request.
 // TODO evaluate this vs. CreateGitWorkflow.
 type TargetOperation struct {
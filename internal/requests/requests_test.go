@@ -3,7 +3,9 @@ package requests
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/cello-proj/cello/internal/types"
 	"github.com/cello-proj/cello/internal/validations"
 
 	"github.com/stretchr/testify/assert"
@@ -577,14 +579,339 @@ func TestCreateProjectValidate(t *testing.T) {
 			},
 			wantErr: errors.New("repository must be a git uri"),
 		},
+		{
+			name: "valid token settings",
+			req: CreateProject{
+				Name:          "project1",
+				Repository:    "https://github.com/cello-proj/cello.git",
+				TokenLimit:    5,
+				TokenTTL:      86400,
+				WorkflowQuota: 3,
+			},
+		},
+		{
+			name: "negative token limit",
+			req: CreateProject{
+				Name:       "project1",
+				Repository: "https://github.com/cello-proj/cello.git",
+				TokenLimit: -1,
+			},
+			wantErr: errors.New("token_limit cannot be negative"),
+		},
+		{
+			name: "negative workflow quota",
+			req: CreateProject{
+				Name:          "project1",
+				Repository:    "https://github.com/cello-proj/cello.git",
+				WorkflowQuota: -1,
+			},
+			wantErr: errors.New("workflow_quota cannot be negative"),
+		},
+		{
+			name: "token ttl out of bounds",
+			req: CreateProject{
+				Name:       "project1",
+				Repository: "https://github.com/cello-proj/cello.git",
+				TokenTTL:   60,
+			},
+			wantErr: errors.New("token_ttl must be between 3600 and 604800 seconds"),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate(tt.req.ValidateTokenTTL(time.Hour, 7*24*time.Hour))
 			if tt.wantErr != nil {
-				assert.EqualError(t, tt.req.Validate(), tt.wantErr.Error())
+				assert.EqualError(t, err, tt.wantErr.Error())
 			} else {
-				assert.Equal(t, tt.wantErr, tt.req.Validate())
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCreateIdentityValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CreateIdentity
+		wantErr error
+	}{
+		{
+			name: "valid",
+			req: CreateIdentity{
+				Name:  "teamlead1",
+				Roles: types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			},
+		},
+		{
+			name: "missing name",
+			req: CreateIdentity{
+				Roles: types.RoleBindings{{Role: types.RoleViewer}},
+			},
+			wantErr: errors.New("name is required"),
+		},
+		{
+			name: "name must be alphanumeric",
+			req: CreateIdentity{
+				Name:  "team-lead",
+				Roles: types.RoleBindings{{Role: types.RoleViewer}},
+			},
+			wantErr: errors.New("name must be alphanumeric"),
+		},
+		{
+			name: "missing roles",
+			req: CreateIdentity{
+				Name: "teamlead1",
+			},
+			wantErr: errors.New("roles is required"),
+		},
+		{
+			name: "invalid roles",
+			req: CreateIdentity{
+				Name:  "teamlead1",
+				Roles: types.RoleBindings{{Role: types.RoleProjectAdmin}},
+			},
+			wantErr: errors.New("role 'project-admin' must be bound to a project"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUpdateIdentityRolesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     UpdateIdentityRoles
+		wantErr error
+	}{
+		{
+			name: "valid",
+			req:  UpdateIdentityRoles{Roles: types.RoleBindings{{Role: types.RoleOperator, Project: "project1"}}},
+		},
+		{
+			name:    "missing roles",
+			wantErr: errors.New("roles is required"),
+		},
+		{
+			name:    "invalid roles",
+			req:     UpdateIdentityRoles{Roles: types.RoleBindings{{Role: types.RoleAdmin, Project: "project1"}}},
+			wantErr: errors.New("role 'admin' cannot be bound to a project"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUpdateProjectValidate(t *testing.T) {
+	limit, ttl, negative, short := 5, 86400, -1, 60
+
+	tests := []struct {
+		name    string
+		req     UpdateProject
+		wantErr error
+	}{
+		{
+			name: "valid",
+			req:  UpdateProject{TokenLimit: &limit, TokenTTL: &ttl},
+		},
+		{
+			name:    "missing fields",
+			wantErr: errors.New("token_limit, token_ttl or workflow_quota is required"),
+		},
+		{
+			name: "workflow quota only",
+			req:  UpdateProject{WorkflowQuota: &limit},
+		},
+		{
+			name:    "negative workflow quota",
+			req:     UpdateProject{WorkflowQuota: &negative},
+			wantErr: errors.New("workflow_quota cannot be negative"),
+		},
+		{
+			name:    "negative token limit",
+			req:     UpdateProject{TokenLimit: &negative},
+			wantErr: errors.New("token_limit cannot be negative"),
+		},
+		{
+			name:    "token ttl out of bounds",
+			req:     UpdateProject{TokenTTL: &short},
+			wantErr: errors.New("token_ttl must be between 3600 and 604800 seconds"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate(tt.req.ValidateTokenTTL(time.Hour, 7*24*time.Hour))
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRotateTokenValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     RotateToken
+		wantErr error
+	}{
+		{
+			name: "valid without grace period",
+		},
+		{
+			name: "valid",
+			req:  RotateToken{GracePeriod: 3600, TTL: 86400},
+		},
+		{
+			name:    "negative grace period",
+			req:     RotateToken{GracePeriod: -1},
+			wantErr: errors.New("grace_period cannot be negative"),
+		},
+		{
+			name:    "grace period too long",
+			req:     RotateToken{GracePeriod: 86401},
+			wantErr: errors.New("grace_period cannot be greater than 86400 seconds"),
+		},
+		{
+			name:    "ttl out of bounds",
+			req:     RotateToken{TTL: 60},
+			wantErr: errors.New("ttl must be between 3600 and 604800 seconds"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate(tt.req.ValidateGracePeriod(24*time.Hour), tt.req.ValidateTTL(time.Hour, 7*24*time.Hour))
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateTokenValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CreateToken
+		wantErr error
+	}{
+		{
+			name: "valid without scopes",
+		},
+		{
+			name: "valid",
+			req: CreateToken{
+				Scopes: types.TokenScopes{{Target: "prod", Operations: []string{"diff"}}},
+			},
+		},
+		{
+			name: "invalid scopes",
+			req: CreateToken{
+				Scopes: types.TokenScopes{{Target: "prod"}},
+			},
+			wantErr: errors.New("scope for target 'prod' must have operations"),
+		},
+		{
+			name: "invalid operation",
+			req: CreateToken{
+				Scopes: types.TokenScopes{{Target: "prod", Operations: []string{"deploy"}}},
+			},
+			wantErr: errors.New("scope operations must be one of 'diff sync'"),
+		},
+		{
+			name:    "negative ttl",
+			req:     CreateToken{TTL: -1},
+			wantErr: errors.New("ttl cannot be negative"),
+		},
+		{
+			name:    "ttl out of bounds",
+			req:     CreateToken{TTL: 700000},
+			wantErr: errors.New("ttl must be between 3600 and 604800 seconds"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate(tt.req.ValidateOperations([]string{"diff", "sync"}), tt.req.ValidateTTL(time.Hour, 7*24*time.Hour))
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CreateWebhook
+		wantErr error
+	}{
+		{
+			name: "valid",
+			req: CreateWebhook{
+				URL:    "https://hooks.example.com/cello",
+				Events: []string{types.WebhookEventSucceeded, types.WebhookEventFailed},
+			},
+		},
+		{
+			name: "valid local receiver",
+			req:  CreateWebhook{URL: "http://localhost:8080/events"},
+		},
+		{
+			name:    "missing url",
+			wantErr: errors.New("url is required"),
+		},
+		{
+			name:    "invalid url",
+			req:     CreateWebhook{URL: "hooks.example.com/cello"},
+			wantErr: errors.New("url must be an absolute http or https url"),
+		},
+		{
+			name:    "metadata service url",
+			req:     CreateWebhook{URL: "http://169.254.169.254/latest/meta-data/"},
+			wantErr: errors.New("url must not be a link-local or metadata service host"),
+		},
+		{
+			name: "invalid event",
+			req: CreateWebhook{
+				URL:    "https://hooks.example.com/cello",
+				Events: []string{"workflow.deleted"},
+			},
+			wantErr: errors.New("events must be one of 'workflow.started workflow.succeeded workflow.failed workflow.errored'"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
 			}
 		})
 	}
@@ -1,5 +1,48 @@
 package responses
 
+import "github.com/cello-proj/cello/internal/types"
+
+// Consistency represents the responses for CheckConsistency and
+// RepairConsistency.
+type Consistency struct {
+	Projects      []ConsistencyProject     `json:"projects"`
+	Discrepancies []ConsistencyDiscrepancy `json:"discrepancies"`
+	// DryRun is set when repairing without making changes.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// ConsistencyProject is the state of a project in the database and the
+// credentials provider. Targets are only stored in the credentials provider.
+type ConsistencyProject struct {
+	Name                      string   `json:"name"`
+	InDatabase                bool     `json:"in_database"`
+	InCredentialsProvider     bool     `json:"in_credentials_provider"`
+	Targets                   []string `json:"targets"`
+	DatabaseTokens            []string `json:"database_tokens"`
+	CredentialsProviderTokens []string `json:"credentials_provider_tokens"`
+}
+
+// ConsistencyDiscrepancy is a difference between the database and the
+// credentials provider, and the action repairing it.
+type ConsistencyDiscrepancy struct {
+	Type    string `json:"type"`
+	Project string `json:"project"`
+	TokenID string `json:"token_id,omitempty"`
+	Action  string `json:"action"`
+	// Reason explains why a discrepancy is not repaired automatically.
+	Reason   string `json:"reason,omitempty"`
+	Repaired bool   `json:"repaired"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CreateIdentity represents the responses for CreateIdentity. The token is
+// only returned when the identity is created.
+type CreateIdentity struct {
+	Name  string             `json:"name"`
+	Roles types.RoleBindings `json:"roles"`
+	Token string             `json:"token"`
+}
+
 // CreateProject represents the responses for CreateProject.
 type CreateProject struct {
 	Token   string `json:"token"`
@@ -8,10 +51,21 @@ type CreateProject struct {
 
 // CreateToken represents the responses for CreateToken.
 type CreateToken struct {
-	CreatedAt string `json:"created_at"`
-	ExpiresAt string `json:"expires_at"`
-	Token     string `json:"token"`
-	TokenID   string `json:"token_id"`
+	CreatedAt string            `json:"created_at"`
+	ExpiresAt string            `json:"expires_at"`
+	Scopes    types.TokenScopes `json:"scopes,omitempty"`
+	Token     string            `json:"token"`
+	TokenID   string            `json:"token_id"`
+}
+
+// CreateWebhook represents the responses for CreateWebhook. The secret is
+// only returned when the webhook is created.
+type CreateWebhook struct {
+	CreatedAt string   `json:"created_at"`
+	Events    []string `json:"events,omitempty"`
+	Secret    string   `json:"secret"`
+	URL       string   `json:"url"`
+	WebhookID string   `json:"webhook_id"`
 }
 
 // Diff represents the responses for Diff.
@@ -25,6 +79,14 @@ type ExecuteWorkflow struct {
 	WorkflowName string `json:"workflow_name"`
 }
 
+// GetIdentity represents the responses for GetIdentity, ListIdentities and
+// UpdateIdentityRoles.
+type GetIdentity struct {
+	CreatedAt string             `json:"created_at"`
+	Name      string             `json:"name"`
+	Roles     types.RoleBindings `json:"roles"`
+}
+
 // GetLogs represents the responses for GetLogs.
 type GetLogs struct {
 	Logs []string `json:"logs"`
@@ -34,6 +96,25 @@ type GetLogs struct {
 type GetProject struct {
 	Name       string `json:"name"`
 	Repository string `json:"repository"`
+	TokenLimit int    `json:"token_limit,omitempty"`
+	TokenTTL   int    `json:"token_ttl,omitempty"`
+	// WorkflowQuota is the number of workflows the project can run
+	// concurrently, it is omitted when unlimited.
+	WorkflowQuota int `json:"workflow_quota,omitempty"`
+}
+
+// GetSubmission represents the responses for GetSubmission, and for queued
+// CreateWorkflow and target operations.
+type GetSubmission struct {
+	ID     string `json:"submission_id"`
+	Status string `json:"status"`
+	// WorkflowName is set once the workflow is submitted.
+	WorkflowName string `json:"workflow_name,omitempty"`
+	// Error is why the submission failed, or why its last attempt failed
+	// while it is queued.
+	Error     string `json:"error,omitempty"`
+	Attempts  int    `json:"attempts"`
+	CreatedAt string `json:"created_at"`
 }
 
 // GetWorkflows represents the responses for GetWorkflows.
@@ -49,10 +130,32 @@ type GetWorkflowStatus struct {
 
 // ListTokens represents the responses for ListTokens.
 type ListTokens struct {
-	CreatedAt string `json:"created_at"`
-	ExpiresAt string `json:"expires_at"`
-	ProjectID string `json:"project,omitempty"`
-	TokenID   string `json:"token_id"`
+	CreatedAt string            `json:"created_at"`
+	ExpiresAt string            `json:"expires_at"`
+	ProjectID string            `json:"project,omitempty"`
+	Scopes    types.TokenScopes `json:"scopes,omitempty"`
+	TokenID   string            `json:"token_id"`
+}
+
+// ListWebhooks represents the responses for ListWebhooks.
+type ListWebhooks struct {
+	CreatedAt string   `json:"created_at"`
+	Events    []string `json:"events,omitempty"`
+	URL       string   `json:"url"`
+	WebhookID string   `json:"webhook_id"`
+}
+
+// RotateToken represents the responses for RotateToken.
+type RotateToken struct {
+	CreatedAt string            `json:"created_at"`
+	ExpiresAt string            `json:"expires_at"`
+	Scopes    types.TokenScopes `json:"scopes,omitempty"`
+	Token     string            `json:"token"`
+	TokenID   string            `json:"token_id"`
+	// PreviousTokenExpiresAt is when the rotated token is revoked, it is
+	// empty when the token was revoked immediately.
+	PreviousTokenExpiresAt string `json:"previous_token_expires_at,omitempty"`
+	PreviousTokenID        string `json:"previous_token_id"`
 }
 
 // Sync represents the responses for Sync.
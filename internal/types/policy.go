@@ -0,0 +1,122 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cello-proj/cello/internal/validations"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// vaultPolicyCapabilities lists the capabilities allowed in a Vault policy.
+var vaultPolicyCapabilities = map[string]bool{
+	"create": true,
+	"read":   true,
+	"update": true,
+	"patch":  true,
+	"delete": true,
+	"list":   true,
+	"sudo":   true,
+	"deny":   true,
+}
+
+// ProjectPolicy is the template the Vault policy of a project is generated
+// from.
+type ProjectPolicy struct {
+	// Targets restricts the targets the project can read credentials for.
+	// All targets of the project can be read when empty.
+	Targets []string `json:"targets"`
+	// ExtraPolicy is Vault policy HCL appended to the generated policy, to
+	// allow additional paths.
+	ExtraPolicy string `json:"extra_policy"`
+}
+
+// Validate validates the ProjectPolicy.
+func (p ProjectPolicy) Validate() error {
+	v := []func() error{
+		func() error {
+			seen := map[string]bool{}
+			for _, t := range p.Targets {
+				if !validations.IsValidTargetName(t) {
+					return fmt.Errorf("targets contains an invalid target name '%s'", t)
+				}
+
+				if seen[t] {
+					return fmt.Errorf("targets contains duplicate target '%s'", t)
+				}
+				seen[t] = true
+			}
+			return nil
+		},
+		func() error {
+			if strings.TrimSpace(p.ExtraPolicy) == "" {
+				return nil
+			}
+			return validateVaultPolicy(p.ExtraPolicy)
+		},
+	}
+
+	return validations.Validate(v...)
+}
+
+// AllowsTarget returns whether the policy allows reading credentials for the
+// target.
+func (p ProjectPolicy) AllowsTarget(target string) bool {
+	if len(p.Targets) == 0 {
+		return true
+	}
+
+	for _, t := range p.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// validateVaultPolicy validates the HCL of a Vault policy, which must only
+// contain path blocks with valid capabilities.
+func validateVaultPolicy(policy string) error {
+	root, err := hcl.Parse(policy)
+	if err != nil {
+		return fmt.Errorf("extra_policy is not valid hcl: %w", err)
+	}
+
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return errors.New("extra_policy is not valid hcl")
+	}
+
+	for _, item := range list.Items {
+		if len(item.Keys) != 2 || item.Keys[0].Token.Value() != "path" {
+			return errors.New("extra_policy must only contain path blocks")
+		}
+
+		path, _ := item.Keys[1].Token.Value().(string)
+		if path == "" {
+			return errors.New("extra_policy path cannot be empty")
+		}
+
+		var rules struct {
+			Capabilities []string `hcl:"capabilities"`
+		}
+		if err := hcl.DecodeObject(&rules, item.Val); err != nil {
+			return fmt.Errorf("extra_policy path '%s' is invalid: %w", path, err)
+		}
+
+		if len(rules.Capabilities) == 0 {
+			return fmt.Errorf("extra_policy path '%s' must have capabilities", path)
+		}
+
+		for _, c := range rules.Capabilities {
+			if !vaultPolicyCapabilities[c] {
+				return fmt.Errorf("extra_policy path '%s' has invalid capability '%s'", path, c)
+			}
+		}
+	}
+
+	return nil
+}
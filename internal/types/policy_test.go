@@ -0,0 +1,90 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ProjectPolicy
+		wantErr error
+	}{
+		{
+			name: "valid empty",
+		},
+		{
+			name: "valid",
+			policy: ProjectPolicy{
+				Targets: []string{"target1", "target_2"},
+				ExtraPolicy: `path "secret/data/project1/*" {
+  capabilities = ["read", "list"]
+}
+
+path "transit/encrypt/project1" {
+  capabilities = ["update"]
+  allowed_parameters = { "plaintext" = [] }
+}`,
+			},
+		},
+		{
+			name: "invalid target name",
+			policy: ProjectPolicy{
+				Targets: []string{"target-1"},
+			},
+			wantErr: errors.New("targets contains an invalid target name 'target-1'"),
+		},
+		{
+			name: "duplicate target",
+			policy: ProjectPolicy{
+				Targets: []string{"target1", "target1"},
+			},
+			wantErr: errors.New("targets contains duplicate target 'target1'"),
+		},
+		{
+			name: "only path blocks",
+			policy: ProjectPolicy{
+				ExtraPolicy: `name = "policy"`,
+			},
+			wantErr: errors.New("extra_policy must only contain path blocks"),
+		},
+		{
+			name: "missing capabilities",
+			policy: ProjectPolicy{
+				ExtraPolicy: `path "secret/*" {}`,
+			},
+			wantErr: errors.New("extra_policy path 'secret/*' must have capabilities"),
+		},
+		{
+			name: "invalid capability",
+			policy: ProjectPolicy{
+				ExtraPolicy: `path "secret/*" { capabilities = ["write"] }`,
+			},
+			wantErr: errors.New("extra_policy path 'secret/*' has invalid capability 'write'"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr != nil {
+				assert.EqualError(t, tt.policy.Validate(), tt.wantErr.Error())
+			} else {
+				assert.NoError(t, tt.policy.Validate())
+			}
+		})
+	}
+
+	t.Run("invalid hcl", func(t *testing.T) {
+		err := ProjectPolicy{ExtraPolicy: `path "secret/*" { capabilities = ["read"]`}.Validate()
+		assert.ErrorContains(t, err, "extra_policy is not valid hcl")
+	})
+}
+
+func TestProjectPolicyAllowsTarget(t *testing.T) {
+	assert.True(t, ProjectPolicy{}.AllowsTarget("target1"))
+	assert.True(t, ProjectPolicy{Targets: []string{"target1", "target2"}}.AllowsTarget("target2"))
+	assert.False(t, ProjectPolicy{Targets: []string{"target1"}}.AllowsTarget("target2"))
+}
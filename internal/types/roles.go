@@ -0,0 +1,129 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cello-proj/cello/internal/validations"
+)
+
+// Roles which can be bound to identities.
+const (
+	// RoleAdmin has every permission, it can only be bound to all projects.
+	RoleAdmin = "admin"
+	// RoleProjectAdmin manages the targets and tokens of a project, it can
+	// only be bound to a project.
+	RoleProjectAdmin = "project-admin"
+	// RoleOperator runs workflows.
+	RoleOperator = "operator"
+	// RoleViewer reads projects, targets and workflows.
+	RoleViewer = "viewer"
+)
+
+// Roles lists the roles which can be bound to identities.
+var Roles = []string{RoleAdmin, RoleProjectAdmin, RoleOperator, RoleViewer}
+
+// Permissions checked by the service for each route.
+const (
+	PermissionConsistency   = "consistency"
+	PermissionIdentities    = "identities"
+	PermissionProjectsRead  = "projects:read"
+	PermissionProjectsWrite = "projects:write"
+	PermissionTargetsRead   = "targets:read"
+	PermissionTargetsWrite  = "targets:write"
+	PermissionTokensRead    = "tokens:read"
+	PermissionTokensWrite   = "tokens:write"
+	PermissionWebhooksRead  = "webhooks:read"
+	PermissionWebhooksWrite = "webhooks:write"
+	PermissionWorkflowsRead = "workflows:read"
+	PermissionWorkflowsRun  = "workflows:run"
+)
+
+// rolePermissions maps each role, other than admin, to its permissions.
+var rolePermissions = map[string][]string{
+	RoleProjectAdmin: {
+		PermissionProjectsRead,
+		PermissionTargetsRead,
+		PermissionTargetsWrite,
+		PermissionTokensRead,
+		PermissionTokensWrite,
+		PermissionWebhooksRead,
+		PermissionWebhooksWrite,
+		PermissionWorkflowsRead,
+		PermissionWorkflowsRun,
+	},
+	RoleOperator: {
+		PermissionProjectsRead,
+		PermissionTargetsRead,
+		PermissionWorkflowsRead,
+		PermissionWorkflowsRun,
+	},
+	RoleViewer: {
+		PermissionProjectsRead,
+		PermissionTargetsRead,
+		PermissionWorkflowsRead,
+	},
+}
+
+// RoleBinding grants a role on a project, or on all projects when the
+// project is empty.
+type RoleBinding struct {
+	Role    string `json:"role"`
+	Project string `json:"project,omitempty"`
+}
+
+// RoleBindings are the roles bound to an identity.
+type RoleBindings []RoleBinding
+
+// Validate validates RoleBindings.
+func (b RoleBindings) Validate() error {
+	seen := map[RoleBinding]bool{}
+	for _, binding := range b {
+		switch binding.Role {
+		case RoleAdmin:
+			if binding.Project != "" {
+				return fmt.Errorf("role '%s' cannot be bound to a project", binding.Role)
+			}
+		case RoleProjectAdmin:
+			if binding.Project == "" {
+				return fmt.Errorf("role '%s' must be bound to a project", binding.Role)
+			}
+		case RoleOperator, RoleViewer:
+		default:
+			return fmt.Errorf("roles must be one of '%s'", strings.Join(Roles, " "))
+		}
+
+		if binding.Project != "" && !validations.IsValidProjectName(binding.Project) {
+			return fmt.Errorf("roles contains an invalid project name '%s'", binding.Project)
+		}
+
+		if seen[binding] {
+			return fmt.Errorf("roles contains duplicate role '%s'", binding.Role)
+		}
+		seen[binding] = true
+	}
+	return nil
+}
+
+// Allows returns whether the bindings grant the permission on the project.
+// Permissions which are not specific to a project, such as creating
+// projects, are checked with an empty project and only granted by bindings
+// to all projects.
+func (b RoleBindings) Allows(permission, project string) bool {
+	for _, binding := range b {
+		if binding.Project != "" && binding.Project != project {
+			continue
+		}
+
+		if binding.Role == RoleAdmin {
+			return true
+		}
+
+		for _, p := range rolePermissions[binding.Role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleBindingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings RoleBindings
+		wantErr  error
+	}{
+		{
+			name: "valid empty",
+		},
+		{
+			name: "valid",
+			bindings: RoleBindings{
+				{Role: RoleAdmin},
+				{Role: RoleProjectAdmin, Project: "project1"},
+				{Role: RoleOperator},
+				{Role: RoleViewer, Project: "project2"},
+			},
+		},
+		{
+			name:     "invalid role",
+			bindings: RoleBindings{{Role: "owner"}},
+			wantErr:  errors.New("roles must be one of 'admin project-admin operator viewer'"),
+		},
+		{
+			name:     "admin bound to project",
+			bindings: RoleBindings{{Role: RoleAdmin, Project: "project1"}},
+			wantErr:  errors.New("role 'admin' cannot be bound to a project"),
+		},
+		{
+			name:     "project admin not bound to project",
+			bindings: RoleBindings{{Role: RoleProjectAdmin}},
+			wantErr:  errors.New("role 'project-admin' must be bound to a project"),
+		},
+		{
+			name:     "invalid project name",
+			bindings: RoleBindings{{Role: RoleViewer, Project: "project-1"}},
+			wantErr:  errors.New("roles contains an invalid project name 'project-1'"),
+		},
+		{
+			name: "duplicate binding",
+			bindings: RoleBindings{
+				{Role: RoleViewer, Project: "project1"},
+				{Role: RoleViewer, Project: "project1"},
+			},
+			wantErr: errors.New("roles contains duplicate role 'viewer'"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.bindings.Validate()
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRoleBindingsAllows(t *testing.T) {
+	tests := []struct {
+		name       string
+		bindings   RoleBindings
+		permission string
+		project    string
+		want       bool
+	}{
+		{
+			name:       "no bindings",
+			permission: PermissionProjectsRead,
+			project:    "project1",
+		},
+		{
+			name:       "admin allows everything",
+			bindings:   RoleBindings{{Role: RoleAdmin}},
+			permission: PermissionIdentities,
+			want:       true,
+		},
+		{
+			name:       "project admin allows project permission",
+			bindings:   RoleBindings{{Role: RoleProjectAdmin, Project: "project1"}},
+			permission: PermissionTokensWrite,
+			project:    "project1",
+			want:       true,
+		},
+		{
+			name:       "project admin does not allow other projects",
+			bindings:   RoleBindings{{Role: RoleProjectAdmin, Project: "project1"}},
+			permission: PermissionTokensWrite,
+			project:    "project2",
+		},
+		{
+			name:       "project admin does not allow permissions of all projects",
+			bindings:   RoleBindings{{Role: RoleProjectAdmin, Project: "project1"}},
+			permission: PermissionProjectsWrite,
+		},
+		{
+			name:       "project admin does not allow project writes",
+			bindings:   RoleBindings{{Role: RoleProjectAdmin, Project: "project1"}},
+			permission: PermissionProjectsWrite,
+			project:    "project1",
+		},
+		{
+			name:       "operator of all projects allows running workflows",
+			bindings:   RoleBindings{{Role: RoleOperator}},
+			permission: PermissionWorkflowsRun,
+			project:    "project2",
+			want:       true,
+		},
+		{
+			name:       "operator does not allow reading webhooks",
+			bindings:   RoleBindings{{Role: RoleOperator, Project: "project1"}},
+			permission: PermissionWebhooksRead,
+			project:    "project1",
+		},
+		{
+			name:       "viewer does not allow running workflows",
+			bindings:   RoleBindings{{Role: RoleViewer, Project: "project1"}},
+			permission: PermissionWorkflowsRun,
+			project:    "project1",
+		},
+		{
+			name: "any binding allows",
+			bindings: RoleBindings{
+				{Role: RoleViewer, Project: "project1"},
+				{Role: RoleOperator, Project: "project2"},
+			},
+			permission: PermissionWorkflowsRun,
+			project:    "project2",
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.bindings.Allows(tt.permission, tt.project))
+		})
+	}
+}
@@ -2,22 +2,124 @@ package types
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/cello-proj/cello/internal/validations"
 )
 
+// Supported target types.
+const (
+	TargetTypeAWSAccount        = "aws_account"
+	TargetTypeAzureSubscription = "azure_subscription"
+	TargetTypeGCPProject        = "gcp_project"
+	TargetTypeKubernetesCluster = "kubernetes_cluster"
+)
+
+// Supported AWS credential types.
+const (
+	AWSCredentialTypeAssumedRole     = "assumed_role"
+	AWSCredentialTypeFederationToken = "federation_token"
+	AWSCredentialTypeIAMUser         = "iam_user"
+)
+
+// Supported GCP credential types.
+const (
+	GCPCredentialTypeImpersonatedAccount = "impersonated_account"
+	GCPCredentialTypeRoleset             = "roleset"
+)
+
+// Supported Azure credential types.
+const (
+	AzureCredentialTypeServicePrincipal = "service_principal"
+)
+
+// Supported Kubernetes credential types.
+const (
+	// KubernetesCredentialTypeRole binds an existing Role or ClusterRole to a
+	// service account generated for each token.
+	KubernetesCredentialTypeRole = "role"
+	// KubernetesCredentialTypeServiceAccount issues tokens for an existing
+	// service account.
+	KubernetesCredentialTypeServiceAccount = "service_account"
+)
+
+// TargetTypes lists the supported target types.
+var TargetTypes = []string{TargetTypeAWSAccount, TargetTypeAzureSubscription, TargetTypeGCPProject, TargetTypeKubernetesCluster}
+
+// Workflow events webhooks are notified of.
+const (
+	WebhookEventStarted   = "workflow.started"
+	WebhookEventSucceeded = "workflow.succeeded"
+	WebhookEventFailed    = "workflow.failed"
+	WebhookEventErrored   = "workflow.errored"
+)
+
+// WebhookEvents lists the workflow events webhooks can be notified of.
+var WebhookEvents = []string{WebhookEventStarted, WebhookEventSucceeded, WebhookEventFailed, WebhookEventErrored}
+
+// targetPropertiesValidators maps each target type to the validation of its
+// properties.
+var targetPropertiesValidators = map[string]func(TargetProperties) error{
+	TargetTypeAWSAccount:        TargetProperties.ValidateAWS,
+	TargetTypeAzureSubscription: TargetProperties.ValidateAzure,
+	TargetTypeGCPProject:        TargetProperties.ValidateGCP,
+	TargetTypeKubernetesCluster: TargetProperties.ValidateKubernetes,
+}
+
 type Target struct {
 	Name       string           `json:"name" valid:"required~name is required,alphanumunderscore~name must be alphanumeric underscore,stringlength(4|32)~name must be between 4 and 32 characters"`
 	Properties TargetProperties `json:"properties"`
 	Type       string           `json:"type" valid:"required~type is required"`
 }
 
-// TargetProperties for target
+// TargetProperties for target. Which properties apply depends on the target
+// type.
 type TargetProperties struct {
-	CredentialType string   `json:"credential_type" valid:"required~credential_type is required"`
+	CredentialType string `json:"credential_type" valid:"required~credential_type is required"`
+
+	// aws_account
+	ExternalID     string   `json:"external_id,omitempty"`
 	PolicyArns     []string `json:"policy_arns"`
 	PolicyDocument string   `json:"policy_document"`
-	RoleArn        string   `json:"role_arn" valid:"required~role_arn is required"`
+	RoleArn        string   `json:"role_arn"`
+	// RoleArns allows an assumed_role target to assume one of several roles.
+	// Only one of RoleArn and RoleArns can be set.
+	RoleArns []string `json:"role_arns,omitempty"`
+	// SessionDuration is the lifetime of STS credentials in seconds, Vault's
+	// default is used when not set.
+	SessionDuration int               `json:"session_duration,omitempty"`
+	SessionTags     map[string]string `json:"session_tags,omitempty"`
+
+	// azure_subscription
+	ClientID        string                `json:"client_id,omitempty"`
+	RoleAssignments []AzureRoleAssignment `json:"role_assignments,omitempty"`
+	SubscriptionID  string                `json:"subscription_id,omitempty"`
+	TenantID        string                `json:"tenant_id,omitempty"`
+
+	// gcp_project
+	// Bindings maps GCP resource names to the roles granted on them.
+	Bindings            map[string][]string `json:"bindings,omitempty"`
+	ProjectID           string              `json:"project_id,omitempty"`
+	ServiceAccountEmail string              `json:"service_account_email,omitempty"`
+	TokenScopes         []string            `json:"token_scopes,omitempty"`
+
+	// kubernetes_cluster
+	AllowedNamespaces  []string `json:"allowed_namespaces,omitempty"`
+	APIServer          string   `json:"api_server,omitempty"`
+	CACert             string   `json:"ca_cert,omitempty"`
+	KubernetesRoleName string   `json:"kubernetes_role_name,omitempty"`
+	KubernetesRoleType string   `json:"kubernetes_role_type,omitempty"`
+	// ServiceAccountName is shared by all namespaces in AllowedNamespaces.
+	ServiceAccountName string `json:"service_account_name,omitempty"`
+}
+
+// AzureRoleAssignment grants an Azure role, by name or ID, on a scope.
+type AzureRoleAssignment struct {
+	RoleID   string `json:"role_id,omitempty"`
+	RoleName string `json:"role_name,omitempty"`
+	Scope    string `json:"scope"`
 }
 
 // Validate validates Target.
@@ -25,30 +127,102 @@ func (target Target) Validate() error {
 	v := []func() error{
 		func() error { return validations.ValidateStruct(target) },
 		func() error {
-			if target.Type != "aws_account" {
-				return errors.New("type must be one of 'aws_account'")
+			validate, ok := targetPropertiesValidators[target.Type]
+			if !ok {
+				return fmt.Errorf("type must be one of '%s'", strings.Join(TargetTypes, " "))
 			}
-			return nil
+			return validate(target.Properties)
 		},
-		target.Properties.Validate,
 	}
 
 	return validations.Validate(v...)
 }
 
-// Validate validates TargetProperties.
-func (properties TargetProperties) Validate() error {
+// setProperties returns the names of the type specific properties which are
+// set, by target type.
+func (properties TargetProperties) setProperties() map[string][]string {
+	set := func(fields map[string]bool) []string {
+		names := []string{}
+		for name, isSet := range fields {
+			if isSet {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	return map[string][]string{
+		TargetTypeAWSAccount: set(map[string]bool{
+			"external_id":      properties.ExternalID != "",
+			"policy_arns":      len(properties.PolicyArns) > 0,
+			"policy_document":  properties.PolicyDocument != "",
+			"role_arn":         properties.RoleArn != "",
+			"role_arns":        len(properties.RoleArns) > 0,
+			"session_duration": properties.SessionDuration != 0,
+			"session_tags":     len(properties.SessionTags) > 0,
+		}),
+		TargetTypeAzureSubscription: set(map[string]bool{
+			"client_id":        properties.ClientID != "",
+			"role_assignments": len(properties.RoleAssignments) > 0,
+			"subscription_id":  properties.SubscriptionID != "",
+			"tenant_id":        properties.TenantID != "",
+		}),
+		TargetTypeGCPProject: set(map[string]bool{
+			"bindings":              len(properties.Bindings) > 0,
+			"project_id":            properties.ProjectID != "",
+			"service_account_email": properties.ServiceAccountEmail != "",
+			"token_scopes":          len(properties.TokenScopes) > 0,
+		}),
+		TargetTypeKubernetesCluster: set(map[string]bool{
+			"allowed_namespaces":   len(properties.AllowedNamespaces) > 0,
+			"api_server":           properties.APIServer != "",
+			"ca_cert":              properties.CACert != "",
+			"kubernetes_role_name": properties.KubernetesRoleName != "",
+			"kubernetes_role_type": properties.KubernetesRoleType != "",
+			"service_account_name": properties.ServiceAccountName != "",
+		}),
+	}
+}
+
+// validateOnly ensures no properties of other target types are set.
+func (properties TargetProperties) validateOnly(targetType string) error {
+	set := properties.setProperties()
+	for _, t := range TargetTypes {
+		if t != targetType && len(set[t]) > 0 {
+			return fmt.Errorf("%s not supported for %s", strings.Join(set[t], ", "), targetType)
+		}
+	}
+	return nil
+}
+
+// AllRoleArns returns the role ARNs of an aws_account target, whether set
+// with RoleArn or RoleArns.
+func (properties TargetProperties) AllRoleArns() []string {
+	if properties.RoleArn != "" {
+		return append([]string{properties.RoleArn}, properties.RoleArns...)
+	}
+	return properties.RoleArns
+}
+
+// ValidateAWS validates TargetProperties for an aws_account target.
+func (properties TargetProperties) ValidateAWS() error {
 	v := []func() error{
 		func() error { return validations.ValidateStruct(properties) },
+		func() error { return properties.validateOnly(TargetTypeAWSAccount) },
 		func() error {
-			if properties.CredentialType != "assumed_role" {
-				return errors.New("credential_type must be one of 'assumed_role'")
-			}
-
-			if !validations.IsValidARN(properties.RoleArn) {
-				return errors.New("role_arn must be a valid arn")
+			switch properties.CredentialType {
+			case AWSCredentialTypeAssumedRole:
+				return properties.validateAWSAssumedRole()
+			case AWSCredentialTypeFederationToken:
+				return properties.validateAWSFederationToken()
+			case AWSCredentialTypeIAMUser:
+				return properties.validateAWSIAMUser()
+			default:
+				return fmt.Errorf("credential_type must be one of '%s %s %s'", AWSCredentialTypeAssumedRole, AWSCredentialTypeFederationToken, AWSCredentialTypeIAMUser)
 			}
-
+		},
+		func() error {
 			if len(properties.PolicyArns) > 5 {
 				return errors.New("policy_arns cannot be more than 5")
 			}
@@ -65,9 +239,266 @@ func (properties TargetProperties) Validate() error {
 	return validations.Validate(v...)
 }
 
+func (properties TargetProperties) validateAWSAssumedRole() error {
+	if properties.RoleArn == "" && len(properties.RoleArns) == 0 {
+		return errors.New("role_arn is required")
+	}
+
+	if properties.RoleArn != "" && len(properties.RoleArns) > 0 {
+		return errors.New("only one of role_arn or role_arns can be set")
+	}
+
+	if properties.RoleArn != "" && !validations.IsValidARN(properties.RoleArn) {
+		return errors.New("role_arn must be a valid arn")
+	}
+
+	for _, arn := range properties.RoleArns {
+		if !validations.IsValidARN(arn) {
+			return errors.New("role_arns contains an invalid arn")
+		}
+	}
+
+	// STS allows up to 12 hours when the role's maximum session duration
+	// allows it.
+	if err := properties.validateAWSSessionDuration(43200); err != nil {
+		return err
+	}
+
+	if properties.ExternalID != "" && !validations.IsValidAWSExternalID(properties.ExternalID) {
+		return errors.New("external_id must be 2 to 1224 characters of letters, digits and +=,.@:/-_")
+	}
+
+	if len(properties.SessionTags) > 50 {
+		return errors.New("session_tags cannot be more than 50")
+	}
+
+	for key, value := range properties.SessionTags {
+		if !validations.IsValidAWSTagKey(key) || !validations.IsValidAWSTagValue(value) {
+			return errors.New("session_tags contains an invalid tag")
+		}
+	}
+	return nil
+}
+
+func (properties TargetProperties) validateAWSFederationToken() error {
+	if len(properties.AllRoleArns()) > 0 {
+		return errors.New("role_arn and role_arns are not supported for federation_token")
+	}
+
+	if properties.ExternalID != "" || len(properties.SessionTags) > 0 {
+		return errors.New("external_id and session_tags are not supported for federation_token")
+	}
+
+	if len(properties.PolicyArns) == 0 && properties.PolicyDocument == "" {
+		return errors.New("policy_arns or policy_document is required for federation_token")
+	}
+
+	// Federation tokens last up to 36 hours.
+	return properties.validateAWSSessionDuration(129600)
+}
+
+func (properties TargetProperties) validateAWSIAMUser() error {
+	if len(properties.AllRoleArns()) > 0 {
+		return errors.New("role_arn and role_arns are not supported for iam_user")
+	}
+
+	if properties.ExternalID != "" || len(properties.SessionTags) > 0 || properties.SessionDuration != 0 {
+		return errors.New("external_id, session_duration and session_tags are not supported for iam_user")
+	}
+
+	if len(properties.PolicyArns) == 0 && properties.PolicyDocument == "" {
+		return errors.New("policy_arns or policy_document is required for iam_user")
+	}
+	return nil
+}
+
+// validateAWSSessionDuration validates the optional session duration against
+// the STS minimum of 15 minutes and the provided maximum.
+func (properties TargetProperties) validateAWSSessionDuration(max int) error {
+	if properties.SessionDuration != 0 && (properties.SessionDuration < 900 || properties.SessionDuration > max) {
+		return fmt.Errorf("session_duration must be between 900 and %d seconds", max)
+	}
+	return nil
+}
+
+// ValidateAzure validates TargetProperties for an azure_subscription target.
+func (properties TargetProperties) ValidateAzure() error {
+	v := []func() error{
+		func() error { return validations.ValidateStruct(properties) },
+		func() error { return properties.validateOnly(TargetTypeAzureSubscription) },
+		func() error {
+			if properties.CredentialType != AzureCredentialTypeServicePrincipal {
+				return fmt.Errorf("credential_type must be one of '%s'", AzureCredentialTypeServicePrincipal)
+			}
+
+			if !validations.IsValidUUID(properties.TenantID) {
+				return errors.New("tenant_id must be a valid uuid")
+			}
+
+			if !validations.IsValidUUID(properties.SubscriptionID) {
+				return errors.New("subscription_id must be a valid uuid")
+			}
+
+			if !validations.IsValidUUID(properties.ClientID) {
+				return errors.New("client_id must be a valid uuid")
+			}
+			return nil
+		},
+		func() error {
+			if len(properties.RoleAssignments) == 0 {
+				return errors.New("role_assignments is required")
+			}
+
+			subscriptionScope := "/subscriptions/" + strings.ToLower(properties.SubscriptionID)
+			for _, ra := range properties.RoleAssignments {
+				if (ra.RoleName == "") == (ra.RoleID == "") {
+					return errors.New("role_assignments must set one of role_name or role_id")
+				}
+
+				if ra.RoleID != "" && !validations.IsValidAzureRoleDefinitionID(ra.RoleID) {
+					return errors.New("role_assignments contains an invalid role_id")
+				}
+
+				scope := strings.ToLower(ra.Scope)
+				if scope != subscriptionScope && !strings.HasPrefix(scope, subscriptionScope+"/") {
+					return errors.New("role_assignments scope must be within the subscription")
+				}
+			}
+			return nil
+		},
+	}
+
+	return validations.Validate(v...)
+}
+
+// ValidateGCP validates TargetProperties for a gcp_project target.
+func (properties TargetProperties) ValidateGCP() error {
+	v := []func() error{
+		func() error { return validations.ValidateStruct(properties) },
+		func() error { return properties.validateOnly(TargetTypeGCPProject) },
+		func() error {
+			// Impersonated accounts are not tied to a project in Vault, the
+			// project_id is optional and only informational for them.
+			required := properties.CredentialType != GCPCredentialTypeImpersonatedAccount
+			if (required || properties.ProjectID != "") && !validations.IsValidGCPProjectID(properties.ProjectID) {
+				return errors.New("project_id must be a valid gcp project id")
+			}
+
+			if len(properties.TokenScopes) == 0 {
+				return errors.New("token_scopes is required")
+			}
+
+			for _, scope := range properties.TokenScopes {
+				if !validations.IsValidGCPTokenScope(scope) {
+					return errors.New("token_scopes contains an invalid scope")
+				}
+			}
+			return nil
+		},
+		func() error {
+			switch properties.CredentialType {
+			case GCPCredentialTypeImpersonatedAccount:
+				if !validations.IsValidGCPServiceAccountEmail(properties.ServiceAccountEmail) {
+					return errors.New("service_account_email must be a valid service account email")
+				}
+
+				if len(properties.Bindings) > 0 {
+					return errors.New("bindings are not supported for impersonated_account")
+				}
+			case GCPCredentialTypeRoleset:
+				if properties.ServiceAccountEmail != "" {
+					return errors.New("service_account_email is not supported for roleset")
+				}
+
+				if len(properties.Bindings) == 0 {
+					return errors.New("bindings is required for roleset")
+				}
+
+				for resource, roles := range properties.Bindings {
+					if resource == "" || len(roles) == 0 {
+						return errors.New("bindings must map a resource to at least one role")
+					}
+
+					for _, role := range roles {
+						if !validations.IsValidGCPRole(role) {
+							return errors.New("bindings contains an invalid role")
+						}
+					}
+				}
+			default:
+				return fmt.Errorf("credential_type must be one of '%s %s'", GCPCredentialTypeImpersonatedAccount, GCPCredentialTypeRoleset)
+			}
+			return nil
+		},
+	}
+
+	return validations.Validate(v...)
+}
+
+// ValidateKubernetes validates TargetProperties for a kubernetes_cluster
+// target.
+func (properties TargetProperties) ValidateKubernetes() error {
+	v := []func() error{
+		func() error { return validations.ValidateStruct(properties) },
+		func() error { return properties.validateOnly(TargetTypeKubernetesCluster) },
+		func() error {
+			if !validations.IsValidHTTPSURL(properties.APIServer) {
+				return errors.New("api_server must be a valid https url")
+			}
+
+			if properties.CACert != "" && !validations.IsValidPEMCertificate(properties.CACert) {
+				return errors.New("ca_cert must be a pem encoded certificate")
+			}
+
+			if len(properties.AllowedNamespaces) == 0 {
+				return errors.New("allowed_namespaces is required")
+			}
+
+			for _, ns := range properties.AllowedNamespaces {
+				if !validations.IsValidKubernetesNamespace(ns) {
+					return errors.New("allowed_namespaces contains an invalid namespace")
+				}
+			}
+			return nil
+		},
+		func() error {
+			switch properties.CredentialType {
+			case KubernetesCredentialTypeServiceAccount:
+				if !validations.IsValidKubernetesName(properties.ServiceAccountName) {
+					return errors.New("service_account_name must be a valid kubernetes name")
+				}
+
+				if properties.KubernetesRoleName != "" || properties.KubernetesRoleType != "" {
+					return errors.New("kubernetes_role_name and kubernetes_role_type are not supported for service_account")
+				}
+			case KubernetesCredentialTypeRole:
+				if !validations.IsValidKubernetesName(properties.KubernetesRoleName) {
+					return errors.New("kubernetes_role_name must be a valid kubernetes name")
+				}
+
+				if properties.KubernetesRoleType != "Role" && properties.KubernetesRoleType != "ClusterRole" {
+					return errors.New("kubernetes_role_type must be one of 'Role ClusterRole'")
+				}
+
+				if properties.ServiceAccountName != "" {
+					return errors.New("service_account_name is not supported for role")
+				}
+			default:
+				return fmt.Errorf("credential_type must be one of '%s %s'", KubernetesCredentialTypeRole, KubernetesCredentialTypeServiceAccount)
+			}
+			return nil
+		},
+	}
+
+	return validations.Validate(v...)
+}
+
 // ProjectToken represents a project token.
 type ProjectToken struct {
 	ID string `json:"token_id"`
+	// CreatedAt is only set when the token is read from the credentials
+	// provider.
+	CreatedAt string `json:"created_at,omitempty"`
 }
 
 // IsEmpty returns whether a struct is empty.
@@ -82,5 +513,108 @@ type Token struct {
 	ProjectID    string       `json:"project_id"`
 	ProjectToken ProjectToken `json:"project_token"`
 	RoleID       string       `json:"role_id"`
-	Secret       string       `json:"secret"`
+	// Scopes restricts the token, it is not restricted when empty.
+	Scopes TokenScopes `json:"scopes,omitempty"`
+	Secret string      `json:"secret"`
+}
+
+// TokenScope allows a project token to run operation types, such as diff or
+// sync, on a target.
+type TokenScope struct {
+	Target     string   `json:"target"`
+	Operations []string `json:"operations"`
+}
+
+// TokenScopes restricts a project token to the targets and operation types
+// of its scopes.
+type TokenScopes []TokenScope
+
+// Validate validates TokenScopes. The operation types are dynamic and can
+// only be validated server side.
+func (s TokenScopes) Validate() error {
+	targets := map[string]bool{}
+	for _, scope := range s {
+		if !validations.IsValidTargetName(scope.Target) {
+			return fmt.Errorf("scopes contains an invalid target name '%s'", scope.Target)
+		}
+
+		if targets[scope.Target] {
+			return fmt.Errorf("scopes contains duplicate target '%s'", scope.Target)
+		}
+		targets[scope.Target] = true
+
+		if len(scope.Operations) == 0 {
+			return fmt.Errorf("scope for target '%s' must have operations", scope.Target)
+		}
+
+		operations := map[string]bool{}
+		for _, op := range scope.Operations {
+			if op == "" || operations[op] {
+				return fmt.Errorf("scope for target '%s' contains an invalid operation '%s'", scope.Target, op)
+			}
+			operations[op] = true
+		}
+	}
+	return nil
+}
+
+// Allows returns whether the scopes allow the operation type on the target.
+// Empty scopes allow everything.
+func (s TokenScopes) Allows(target, operation string) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for _, scope := range s {
+		if scope.Target != target {
+			continue
+		}
+
+		for _, op := range scope.Operations {
+			if op == operation {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsTarget returns whether the scopes allow any operation type on the
+// target. Empty scopes allow every target.
+func (s TokenScopes) AllowsTarget(target string) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for _, scope := range s {
+		if scope.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Targets returns the sorted targets of the scopes.
+func (s TokenScopes) Targets() []string {
+	targets := make([]string, 0, len(s))
+	for _, scope := range s {
+		targets = append(targets, scope.Target)
+	}
+
+	sort.Strings(targets)
+	return targets
+}
+
+// Normalize returns the scopes sorted by target with sorted operations, so
+// equal scopes have equal representations.
+func (s TokenScopes) Normalize() TokenScopes {
+	normalized := make(TokenScopes, 0, len(s))
+	for _, scope := range s {
+		operations := append([]string{}, scope.Operations...)
+		sort.Strings(operations)
+		normalized = append(normalized, TokenScope{Target: scope.Target, Operations: operations})
+	}
+
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].Target < normalized[j].Target })
+	return normalized
 }
@@ -7,7 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestTargetPropertiesValidate(t *testing.T) {
+func TestTargetPropertiesValidateAWS(t *testing.T) {
 	tests := []struct {
 		name       string
 		properties TargetProperties
@@ -72,14 +72,111 @@ func TestTargetPropertiesValidate(t *testing.T) {
 			},
 			wantErr: errors.New("policy_arns contains an invalid arn"),
 		},
+		{
+			name: "valid assumed role with sts settings",
+			properties: TargetProperties{
+				CredentialType: "assumed_role",
+				RoleArns: []string{
+					"arn:aws:iam::012345678901:role/test-role-1",
+					"arn:aws:iam::012345678901:role/test-role-2",
+				},
+				ExternalID:      "cello-external-id",
+				SessionDuration: 3600,
+				SessionTags:     map[string]string{"team": "platform", "cost-center": "1234"},
+			},
+		},
+		{
+			name: "role_arn and role_arns",
+			properties: TargetProperties{
+				CredentialType: "assumed_role",
+				RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+				RoleArns:       []string{"arn:aws:iam::012345678901:role/test-role-2"},
+			},
+			wantErr: errors.New("only one of role_arn or role_arns can be set"),
+		},
+		{
+			name: "role_arns must be valid",
+			properties: TargetProperties{
+				CredentialType: "assumed_role",
+				RoleArns:       []string{"not-an-arn"},
+			},
+			wantErr: errors.New("role_arns contains an invalid arn"),
+		},
+		{
+			name: "session duration too short",
+			properties: TargetProperties{
+				CredentialType:  "assumed_role",
+				RoleArn:         "arn:aws:iam::012345678901:role/test-role",
+				SessionDuration: 60,
+			},
+			wantErr: errors.New("session_duration must be between 900 and 43200 seconds"),
+		},
+		{
+			name: "invalid external id",
+			properties: TargetProperties{
+				CredentialType: "assumed_role",
+				RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+				ExternalID:     "a",
+			},
+			wantErr: errors.New("external_id must be 2 to 1224 characters of letters, digits and +=,.@:/-_"),
+		},
+		{
+			name: "invalid session tag",
+			properties: TargetProperties{
+				CredentialType: "assumed_role",
+				RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+				SessionTags:    map[string]string{"team!": "platform"},
+			},
+			wantErr: errors.New("session_tags contains an invalid tag"),
+		},
+		{
+			name: "valid federation token",
+			properties: TargetProperties{
+				CredentialType:  "federation_token",
+				PolicyArns:      []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+				SessionDuration: 129600,
+			},
+		},
+		{
+			name: "federation token does not accept role arn",
+			properties: TargetProperties{
+				CredentialType: "federation_token",
+				PolicyArns:     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+				RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+			},
+			wantErr: errors.New("role_arn and role_arns are not supported for federation_token"),
+		},
+		{
+			name: "federation token requires a policy",
+			properties: TargetProperties{
+				CredentialType: "federation_token",
+			},
+			wantErr: errors.New("policy_arns or policy_document is required for federation_token"),
+		},
+		{
+			name: "valid iam user",
+			properties: TargetProperties{
+				CredentialType: "iam_user",
+				PolicyDocument: "{ \"Version\": \"2012-10-17\", \"Statement\": [ { \"Effect\": \"Allow\", \"Action\": \"s3:ListBuckets\", \"Resource\": \"*\" } ] }",
+			},
+		},
+		{
+			name: "iam user does not accept sts settings",
+			properties: TargetProperties{
+				CredentialType:  "iam_user",
+				PolicyArns:      []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+				SessionDuration: 3600,
+			},
+			wantErr: errors.New("external_id, session_duration and session_tags are not supported for iam_user"),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.wantErr != nil {
-				assert.EqualError(t, tt.properties.Validate(), tt.wantErr.Error())
+				assert.EqualError(t, tt.properties.ValidateAWS(), tt.wantErr.Error())
 			} else {
-				assert.Equal(t, tt.wantErr, tt.properties.Validate())
+				assert.Equal(t, tt.wantErr, tt.properties.ValidateAWS())
 			}
 		})
 	}
@@ -190,7 +287,47 @@ func TestCTargetValidate(t *testing.T) {
 				},
 				Type: "bad",
 			},
-			wantErr: errors.New("type must be one of 'aws_account'"),
+			wantErr: errors.New("type must be one of 'aws_account azure_subscription gcp_project kubernetes_cluster'"),
+		},
+		{
+			name: "valid gcp project",
+			target: Target{
+				Name: "target1",
+				Properties: TargetProperties{
+					CredentialType:      "impersonated_account",
+					ProjectID:           "my-project-123",
+					ServiceAccountEmail: "deployer@my-project-123.iam.gserviceaccount.com",
+					TokenScopes:         []string{"https://www.googleapis.com/auth/cloud-platform"},
+				},
+				Type: "gcp_project",
+			},
+		},
+		{
+			name: "gcp project does not accept aws properties",
+			target: Target{
+				Name: "target1",
+				Properties: TargetProperties{
+					CredentialType: "roleset",
+					ProjectID:      "my-project-123",
+					RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+					TokenScopes:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+				},
+				Type: "gcp_project",
+			},
+			wantErr: errors.New("role_arn not supported for gcp_project"),
+		},
+		{
+			name: "aws account does not accept azure properties",
+			target: Target{
+				Name: "target1",
+				Properties: TargetProperties{
+					CredentialType: "assumed_role",
+					RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+					TenantID:       "72f988bf-86f1-41af-91ab-2d7cd011db47",
+				},
+				Type: "aws_account",
+			},
+			wantErr: errors.New("tenant_id not supported for aws_account"),
 		},
 		{
 			name: "missing credential_type",
@@ -213,7 +350,7 @@ func TestCTargetValidate(t *testing.T) {
 				},
 				Type: "aws_account",
 			},
-			wantErr: errors.New("credential_type must be one of 'assumed_role'"),
+			wantErr: errors.New("credential_type must be one of 'assumed_role federation_token iam_user'"),
 		},
 		{
 			name: "missing role_arn",
@@ -286,3 +423,434 @@ func TestCTargetValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestTargetPropertiesValidateGCP(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties TargetProperties
+		wantErr    error
+	}{
+		{
+			name: "valid impersonated account",
+			properties: TargetProperties{
+				CredentialType:      "impersonated_account",
+				ProjectID:           "my-project-123",
+				ServiceAccountEmail: "deployer@my-project-123.iam.gserviceaccount.com",
+				TokenScopes:         []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+		{
+			name: "valid roleset",
+			properties: TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "my-project-123",
+				Bindings: map[string][]string{
+					"//cloudresourcemanager.googleapis.com/projects/my-project-123": {"roles/viewer", "projects/my-project-123/roles/deployer"},
+				},
+				TokenScopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+		{
+			name: "missing credential_type",
+			properties: TargetProperties{
+				ProjectID:   "my-project-123",
+				TokenScopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("credential_type is required"),
+		},
+		{
+			name: "invalid credential_type",
+			properties: TargetProperties{
+				CredentialType: "assumed_role",
+				ProjectID:      "my-project-123",
+				TokenScopes:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("credential_type must be one of 'impersonated_account roleset'"),
+		},
+		{
+			name: "invalid project id",
+			properties: TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "My_Project",
+				TokenScopes:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("project_id must be a valid gcp project id"),
+		},
+		{
+			name: "missing token scopes",
+			properties: TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "my-project-123",
+			},
+			wantErr: errors.New("token_scopes is required"),
+		},
+		{
+			name: "invalid token scope",
+			properties: TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "my-project-123",
+				TokenScopes:    []string{"cloud-platform"},
+			},
+			wantErr: errors.New("token_scopes contains an invalid scope"),
+		},
+		{
+			name: "impersonated account does not require project id",
+			properties: TargetProperties{
+				CredentialType:      "impersonated_account",
+				ServiceAccountEmail: "123456789012-compute@developer.gserviceaccount.com",
+				TokenScopes:         []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+		{
+			name: "impersonated account requires service account email",
+			properties: TargetProperties{
+				CredentialType: "impersonated_account",
+				ProjectID:      "my-project-123",
+				TokenScopes:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("service_account_email must be a valid service account email"),
+		},
+		{
+			name: "impersonated account does not accept bindings",
+			properties: TargetProperties{
+				CredentialType:      "impersonated_account",
+				ProjectID:           "my-project-123",
+				ServiceAccountEmail: "deployer@my-project-123.iam.gserviceaccount.com",
+				Bindings:            map[string][]string{"//cloudresourcemanager.googleapis.com/projects/my-project-123": {"roles/viewer"}},
+				TokenScopes:         []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("bindings are not supported for impersonated_account"),
+		},
+		{
+			name: "roleset requires bindings",
+			properties: TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "my-project-123",
+				TokenScopes:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("bindings is required for roleset"),
+		},
+		{
+			name: "roleset does not accept service account email",
+			properties: TargetProperties{
+				CredentialType:      "roleset",
+				ProjectID:           "my-project-123",
+				ServiceAccountEmail: "deployer@my-project-123.iam.gserviceaccount.com",
+				TokenScopes:         []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("service_account_email is not supported for roleset"),
+		},
+		{
+			name: "roleset bindings must contain roles",
+			properties: TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "my-project-123",
+				Bindings:       map[string][]string{"//cloudresourcemanager.googleapis.com/projects/my-project-123": {}},
+				TokenScopes:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("bindings must map a resource to at least one role"),
+		},
+		{
+			name: "roleset bindings must contain valid roles",
+			properties: TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "my-project-123",
+				Bindings:       map[string][]string{"//cloudresourcemanager.googleapis.com/projects/my-project-123": {"viewer"}},
+				TokenScopes:    []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantErr: errors.New("bindings contains an invalid role"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr != nil {
+				assert.EqualError(t, tt.properties.ValidateGCP(), tt.wantErr.Error())
+			} else {
+				assert.Equal(t, tt.wantErr, tt.properties.ValidateGCP())
+			}
+		})
+	}
+}
+
+func TestTargetPropertiesValidateAzure(t *testing.T) {
+	valid := func() TargetProperties {
+		return TargetProperties{
+			CredentialType: "service_principal",
+			ClientID:       "2b4f6e8a-1c3d-4e5f-8a9b-0c1d2e3f4a5b",
+			SubscriptionID: "8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e",
+			TenantID:       "72f988bf-86f1-41af-91ab-2d7cd011db47",
+			RoleAssignments: []AzureRoleAssignment{
+				{RoleName: "Contributor", Scope: "/subscriptions/8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e/resourceGroups/cello"},
+				{RoleID: "/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7", Scope: "/subscriptions/8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(p *TargetProperties)
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			modify: func(p *TargetProperties) {},
+		},
+		{
+			name:    "invalid credential_type",
+			modify:  func(p *TargetProperties) { p.CredentialType = "assumed_role" },
+			wantErr: errors.New("credential_type must be one of 'service_principal'"),
+		},
+		{
+			name:    "invalid tenant_id",
+			modify:  func(p *TargetProperties) { p.TenantID = "contoso.onmicrosoft.com" },
+			wantErr: errors.New("tenant_id must be a valid uuid"),
+		},
+		{
+			name:    "missing subscription_id",
+			modify:  func(p *TargetProperties) { p.SubscriptionID = "" },
+			wantErr: errors.New("subscription_id must be a valid uuid"),
+		},
+		{
+			name:    "invalid client_id",
+			modify:  func(p *TargetProperties) { p.ClientID = "client" },
+			wantErr: errors.New("client_id must be a valid uuid"),
+		},
+		{
+			name:    "missing role assignments",
+			modify:  func(p *TargetProperties) { p.RoleAssignments = nil },
+			wantErr: errors.New("role_assignments is required"),
+		},
+		{
+			name: "role assignment with role name and role id",
+			modify: func(p *TargetProperties) {
+				p.RoleAssignments[0].RoleID = "/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7"
+			},
+			wantErr: errors.New("role_assignments must set one of role_name or role_id"),
+		},
+		{
+			name:    "role assignment with invalid role id",
+			modify:  func(p *TargetProperties) { p.RoleAssignments[1].RoleID = "Reader" },
+			wantErr: errors.New("role_assignments contains an invalid role_id"),
+		},
+		{
+			name: "role assignment outside of subscription",
+			modify: func(p *TargetProperties) {
+				p.RoleAssignments[0].Scope = "/subscriptions/00000000-0000-0000-0000-000000000000"
+			},
+			wantErr: errors.New("role_assignments scope must be within the subscription"),
+		},
+		{
+			name:    "does not accept gcp properties",
+			modify:  func(p *TargetProperties) { p.ProjectID = "my-project-123" },
+			wantErr: errors.New("project_id not supported for azure_subscription"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := valid()
+			tt.modify(&properties)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, properties.ValidateAzure(), tt.wantErr.Error())
+			} else {
+				assert.Equal(t, tt.wantErr, properties.ValidateAzure())
+			}
+		})
+	}
+}
+
+// testCACert is a self-signed certificate used as a Kubernetes cluster CA.
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIBgjCCASegAwIBAgIUc/KVGEwSZi/JUdAv7OC4SZiijeYwCgYIKoZIzj0EAwIw
+FTETMBEGA1UEAwwKa3ViZXJuZXRlczAgFw0yNjEwMTgyMjA2MjBaGA8yMTI2MDky
+NDIyMDYyMFowFTETMBEGA1UEAwwKa3ViZXJuZXRlczBZMBMGByqGSM49AgEGCCqG
+SM49AwEHA0IABPcDK0jJNhAT+sVfeoq3YZS2UsUiOMmnb1R+UbjSFWtoYL5FhNOA
+3bSPBnfrjlZJQyS9jpUFpZLyhQT5JlgZFEejUzBRMB0GA1UdDgQWBBR0NtQM/4J/
+msd/vMgspVENv0fLLjAfBgNVHSMEGDAWgBR0NtQM/4J/msd/vMgspVENv0fLLjAP
+BgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0kAMEYCIQDlVJEjxfC9WPqxfhtZ
+s2x17kIXhGrFHhVUSmD/OxqLEQIhAJb+JUu3j7MH8/Y7XM0fO27q1DAD1Idbi5q+
+zCEAtmhV
+-----END CERTIFICATE-----
+`
+
+func TestTargetPropertiesValidateKubernetes(t *testing.T) {
+	valid := func() TargetProperties {
+		return TargetProperties{
+			CredentialType:     "role",
+			APIServer:          "https://kubernetes.example.com:6443",
+			CACert:             testCACert,
+			AllowedNamespaces:  []string{"team-a", "team-b"},
+			KubernetesRoleName: "edit",
+			KubernetesRoleType: "ClusterRole",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(p *TargetProperties)
+		wantErr error
+	}{
+		{
+			name:   "valid role",
+			modify: func(p *TargetProperties) {},
+		},
+		{
+			name: "valid service account",
+			modify: func(p *TargetProperties) {
+				p.CredentialType = "service_account"
+				p.KubernetesRoleName = ""
+				p.KubernetesRoleType = ""
+				p.ServiceAccountName = "cello-deployer"
+			},
+		},
+		{
+			name:   "ca cert is optional",
+			modify: func(p *TargetProperties) { p.CACert = "" },
+		},
+		{
+			name:    "api server must be https",
+			modify:  func(p *TargetProperties) { p.APIServer = "http://kubernetes.example.com" },
+			wantErr: errors.New("api_server must be a valid https url"),
+		},
+		{
+			name:    "invalid ca cert",
+			modify:  func(p *TargetProperties) { p.CACert = "not a certificate" },
+			wantErr: errors.New("ca_cert must be a pem encoded certificate"),
+		},
+		{
+			name:    "missing allowed namespaces",
+			modify:  func(p *TargetProperties) { p.AllowedNamespaces = nil },
+			wantErr: errors.New("allowed_namespaces is required"),
+		},
+		{
+			name:    "wildcard namespace",
+			modify:  func(p *TargetProperties) { p.AllowedNamespaces = []string{"*"} },
+			wantErr: errors.New("allowed_namespaces contains an invalid namespace"),
+		},
+		{
+			name:    "invalid role type",
+			modify:  func(p *TargetProperties) { p.KubernetesRoleType = "RoleBinding" },
+			wantErr: errors.New("kubernetes_role_type must be one of 'Role ClusterRole'"),
+		},
+		{
+			name:    "role does not accept service account name",
+			modify:  func(p *TargetProperties) { p.ServiceAccountName = "cello-deployer" },
+			wantErr: errors.New("service_account_name is not supported for role"),
+		},
+		{
+			name:    "service account requires service account name",
+			modify:  func(p *TargetProperties) { p.CredentialType = "service_account" },
+			wantErr: errors.New("service_account_name must be a valid kubernetes name"),
+		},
+		{
+			name:    "invalid credential type",
+			modify:  func(p *TargetProperties) { p.CredentialType = "assumed_role" },
+			wantErr: errors.New("credential_type must be one of 'role service_account'"),
+		},
+		{
+			name:    "does not accept aws properties",
+			modify:  func(p *TargetProperties) { p.PolicyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"} },
+			wantErr: errors.New("policy_arns not supported for kubernetes_cluster"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := valid()
+			tt.modify(&properties)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, properties.ValidateKubernetes(), tt.wantErr.Error())
+			} else {
+				assert.Equal(t, tt.wantErr, properties.ValidateKubernetes())
+			}
+		})
+	}
+}
+
+func TestTokenScopesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  TokenScopes
+		wantErr error
+	}{
+		{
+			name: "valid empty",
+		},
+		{
+			name: "valid",
+			scopes: TokenScopes{
+				{Target: "prod", Operations: []string{"diff"}},
+				{Target: "dev1", Operations: []string{"diff", "sync"}},
+			},
+		},
+		{
+			name:    "invalid target",
+			scopes:  TokenScopes{{Target: "pr-od", Operations: []string{"diff"}}},
+			wantErr: errors.New("scopes contains an invalid target name 'pr-od'"),
+		},
+		{
+			name: "duplicate target",
+			scopes: TokenScopes{
+				{Target: "prod", Operations: []string{"diff"}},
+				{Target: "prod", Operations: []string{"sync"}},
+			},
+			wantErr: errors.New("scopes contains duplicate target 'prod'"),
+		},
+		{
+			name:    "missing operations",
+			scopes:  TokenScopes{{Target: "prod"}},
+			wantErr: errors.New("scope for target 'prod' must have operations"),
+		},
+		{
+			name:    "duplicate operation",
+			scopes:  TokenScopes{{Target: "prod", Operations: []string{"diff", "diff"}}},
+			wantErr: errors.New("scope for target 'prod' contains an invalid operation 'diff'"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr != nil {
+				assert.EqualError(t, tt.scopes.Validate(), tt.wantErr.Error())
+			} else {
+				assert.NoError(t, tt.scopes.Validate())
+			}
+		})
+	}
+}
+
+func TestTokenScopesAllows(t *testing.T) {
+	scopes := TokenScopes{
+		{Target: "prod", Operations: []string{"diff"}},
+		{Target: "dev1", Operations: []string{"diff", "sync"}},
+	}
+
+	assert.True(t, scopes.Allows("prod", "diff"))
+	assert.False(t, scopes.Allows("prod", "sync"))
+	assert.True(t, scopes.Allows("dev1", "sync"))
+	assert.False(t, scopes.Allows("test", "diff"))
+	assert.True(t, scopes.AllowsTarget("dev1"))
+	assert.False(t, scopes.AllowsTarget("test"))
+	assert.True(t, TokenScopes{}.Allows("test", "sync"))
+	assert.True(t, TokenScopes{}.AllowsTarget("test"))
+}
+
+func TestTokenScopesNormalize(t *testing.T) {
+	scopes := TokenScopes{
+		{Target: "prod", Operations: []string{"sync", "diff"}},
+		{Target: "dev1", Operations: []string{"diff"}},
+	}
+
+	want := TokenScopes{
+		{Target: "dev1", Operations: []string{"diff"}},
+		{Target: "prod", Operations: []string{"diff", "sync"}},
+	}
+
+	assert.Equal(t, want, scopes.Normalize())
+	assert.Equal(t, []string{"dev1", "prod"}, scopes.Targets())
+	// The scopes are not modified.
+	assert.Equal(t, []string{"sync", "diff"}, scopes[0].Operations)
+}
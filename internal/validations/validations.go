@@ -1,8 +1,14 @@
 package validations
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
@@ -64,6 +70,149 @@ func IsValidARN(s string) bool {
 	return arn.IsARN(s)
 }
 
+// IsValidTargetName determines if the string is a valid target name.
+func IsValidTargetName(s string) bool {
+	return len(s) >= 4 && len(s) <= 32 && isAlphaNumbericUnderscore(s, nil)
+}
+
+// IsValidProjectName determines if the string is a valid project name.
+func IsValidProjectName(s string) bool {
+	return len(s) >= 4 && len(s) <= 32 && govalidator.IsAlphanumeric(s)
+}
+
+// IsValidAWSExternalID determines if the string is a valid external ID for
+// assuming an AWS role.
+func IsValidAWSExternalID(s string) bool {
+	return len(s) >= 2 && len(s) <= 1224 && regexp.MustCompile(`^[\w+=,.@:/-]+$`).MatchString(s)
+}
+
+// IsValidAWSTagKey determines if the string is a valid AWS session tag key.
+func IsValidAWSTagKey(s string) bool {
+	return regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{1,128}$`).MatchString(s)
+}
+
+// IsValidAWSTagValue determines if the string is a valid AWS session tag
+// value.
+func IsValidAWSTagValue(s string) bool {
+	return regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{0,256}$`).MatchString(s)
+}
+
+// IsValidGCPProjectID determines if the string is a valid GCP project ID.
+func IsValidGCPProjectID(s string) bool {
+	return regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`).MatchString(s)
+}
+
+// IsValidGCPServiceAccountEmail determines if the string is a valid GCP
+// service account email.
+func IsValidGCPServiceAccountEmail(s string) bool {
+	pattern := `^[a-z0-9-]{6,30}@([a-z][a-z0-9-]{4,28}[a-z0-9]\.iam|developer)\.gserviceaccount\.com$`
+	return regexp.MustCompile(pattern).MatchString(s)
+}
+
+// IsValidGCPTokenScope determines if the string is a valid Google OAuth 2.0
+// scope.
+func IsValidGCPTokenScope(s string) bool {
+	return regexp.MustCompile(`^https://www\.googleapis\.com/auth/[a-z0-9._-]+$`).MatchString(s)
+}
+
+// IsValidGCPRole determines if the string is a predefined or custom GCP IAM
+// role.
+func IsValidGCPRole(s string) bool {
+	pattern := `^(roles/[a-zA-Z0-9_.]+|(projects|organizations)/[a-zA-Z0-9_.-]+/roles/[a-zA-Z0-9_.]+)$`
+	return regexp.MustCompile(pattern).MatchString(s)
+}
+
+// IsValidUUID determines if the string is a valid UUID, as used for Azure
+// tenant, subscription and client IDs.
+func IsValidUUID(s string) bool {
+	return govalidator.IsUUID(s)
+}
+
+// IsValidAzureRoleDefinitionID determines if the string is a valid Azure role
+// definition resource ID.
+func IsValidAzureRoleDefinitionID(s string) bool {
+	pattern := `^(/subscriptions/[0-9a-fA-F-]{36})?/providers/Microsoft\.Authorization/roleDefinitions/[0-9a-fA-F-]{36}$`
+	return regexp.MustCompile(pattern).MatchString(s)
+}
+
+// IsValidHTTPSURL determines if the string is an absolute https URL.
+func IsValidHTTPSURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// IsValidHTTPURL determines if the string is an absolute http or https URL.
+func IsValidHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// metadataHosts are the names of cloud metadata services.
+var metadataHosts = map[string]bool{
+	"metadata":                 true,
+	"metadata.google.internal": true,
+}
+
+// metadataIPs are the addresses of cloud metadata services which are not
+// link-local.
+var metadataIPs = []net.IP{net.ParseIP("fd00:ec2::254")}
+
+// IsMetadataHost determines if the host is a link-local address, such as the
+// metadata services of AWS, Azure and GCP at 169.254.169.254, or the name of
+// a metadata service. Names resolving to such addresses are not detected.
+func IsMetadataHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if metadataHosts[host] {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, m := range metadataIPs {
+		if ip.Equal(m) {
+			return true
+		}
+	}
+	return ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+}
+
+// IsValidPEMCertificate determines if the string contains only PEM encoded
+// x509 certificates.
+func IsValidPEMCertificate(s string) bool {
+	rest := []byte(s)
+	found := false
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if _, err := x509.ParseCertificate(block.Bytes); block.Type != "CERTIFICATE" || err != nil {
+			return false
+		}
+		found = true
+	}
+
+	return found && len(bytes.TrimSpace(rest)) == 0
+}
+
+// IsValidKubernetesNamespace determines if the string is a valid Kubernetes
+// namespace (an RFC 1123 label).
+func IsValidKubernetesNamespace(s string) bool {
+	return len(s) <= 63 && regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`).MatchString(s)
+}
+
+// IsValidKubernetesName determines if the string is a valid Kubernetes object
+// name (an RFC 1123 subdomain).
+func IsValidKubernetesName(s string) bool {
+	pattern := `^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	return len(s) <= 253 && regexp.MustCompile(pattern).MatchString(s)
+}
+
 // IsValidImageURI determines if the image URI is a valid container image URI
 // format.
 func IsValidImageURI(imageURI string) bool {
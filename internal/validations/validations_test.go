@@ -215,3 +215,533 @@ func TestIsValidARN(t *testing.T) {
 		})
 	}
 }
+
+func TestIsValidGCPProjectID(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid project id",
+			testString: "my-project-123",
+			want:       true,
+		},
+		{
+			name:       "must start with a letter",
+			testString: "1-my-project",
+		},
+		{
+			name:       "must not end with a dash",
+			testString: "my-project-",
+		},
+		{
+			name:       "too short",
+			testString: "proj",
+		},
+		{
+			name:       "uppercase not allowed",
+			testString: "My-Project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidGCPProjectID(tt.testString))
+		})
+	}
+}
+
+func TestIsValidGCPServiceAccountEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "user managed service account",
+			testString: "deployer@my-project-123.iam.gserviceaccount.com",
+			want:       true,
+		},
+		{
+			name:       "default compute service account",
+			testString: "123456789012-compute@developer.gserviceaccount.com",
+			want:       true,
+		},
+		{
+			name:       "not a service account",
+			testString: "someone@example.com",
+		},
+		{
+			name:       "not an email",
+			testString: "deployer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidGCPServiceAccountEmail(tt.testString))
+		})
+	}
+}
+
+func TestIsValidGCPTokenScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid scope",
+			testString: "https://www.googleapis.com/auth/cloud-platform",
+			want:       true,
+		},
+		{
+			name:       "valid scope with dots",
+			testString: "https://www.googleapis.com/auth/devstorage.read_only",
+			want:       true,
+		},
+		{
+			name:       "not a google scope",
+			testString: "https://example.com/auth/cloud-platform",
+		},
+		{
+			name:       "short scope name",
+			testString: "cloud-platform",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidGCPTokenScope(tt.testString))
+		})
+	}
+}
+
+func TestIsValidGCPRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "predefined role",
+			testString: "roles/storage.objectViewer",
+			want:       true,
+		},
+		{
+			name:       "project custom role",
+			testString: "projects/my-project-123/roles/deployer",
+			want:       true,
+		},
+		{
+			name:       "organization custom role",
+			testString: "organizations/123456789012/roles/deployer",
+			want:       true,
+		},
+		{
+			name:       "missing prefix",
+			testString: "storage.objectViewer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidGCPRole(tt.testString))
+		})
+	}
+}
+
+func TestIsValidUUID(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid",
+			testString: "72f988bf-86f1-41af-91ab-2d7cd011db47",
+			want:       true,
+		},
+		{
+			name:       "invalid",
+			testString: "72f988bf86f141af",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidUUID(tt.testString))
+		})
+	}
+}
+
+func TestIsValidAzureRoleDefinitionID(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "built in role",
+			testString: "/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7",
+			want:       true,
+		},
+		{
+			name:       "subscription role",
+			testString: "/subscriptions/8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7",
+			want:       true,
+		},
+		{
+			name:       "role name",
+			testString: "Reader",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidAzureRoleDefinitionID(tt.testString))
+		})
+	}
+}
+
+// testCACert is a self-signed certificate used as a Kubernetes cluster CA.
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIBgjCCASegAwIBAgIUc/KVGEwSZi/JUdAv7OC4SZiijeYwCgYIKoZIzj0EAwIw
+FTETMBEGA1UEAwwKa3ViZXJuZXRlczAgFw0yNjEwMTgyMjA2MjBaGA8yMTI2MDky
+NDIyMDYyMFowFTETMBEGA1UEAwwKa3ViZXJuZXRlczBZMBMGByqGSM49AgEGCCqG
+SM49AwEHA0IABPcDK0jJNhAT+sVfeoq3YZS2UsUiOMmnb1R+UbjSFWtoYL5FhNOA
+3bSPBnfrjlZJQyS9jpUFpZLyhQT5JlgZFEejUzBRMB0GA1UdDgQWBBR0NtQM/4J/
+msd/vMgspVENv0fLLjAfBgNVHSMEGDAWgBR0NtQM/4J/msd/vMgspVENv0fLLjAP
+BgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0kAMEYCIQDlVJEjxfC9WPqxfhtZ
+s2x17kIXhGrFHhVUSmD/OxqLEQIhAJb+JUu3j7MH8/Y7XM0fO27q1DAD1Idbi5q+
+zCEAtmhV
+-----END CERTIFICATE-----
+`
+
+func TestIsValidHTTPSURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "https",
+			testString: "https://kubernetes.example.com:6443",
+			want:       true,
+		},
+		{
+			name:       "http",
+			testString: "http://kubernetes.example.com",
+		},
+		{
+			name:       "no host",
+			testString: "https://",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidHTTPSURL(tt.testString))
+		})
+	}
+}
+
+func TestIsValidHTTPURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "https",
+			testString: "https://hooks.example.com/cello",
+			want:       true,
+		},
+		{
+			name:       "http",
+			testString: "http://localhost:8080/events",
+			want:       true,
+		},
+		{
+			name:       "other scheme",
+			testString: "ftp://hooks.example.com",
+		},
+		{
+			name:       "no host",
+			testString: "http://",
+		},
+		{
+			name:       "relative",
+			testString: "/events",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidHTTPURL(tt.testString))
+		})
+	}
+}
+
+func TestIsMetadataHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "link-local ipv4",
+			testString: "169.254.169.254",
+			want:       true,
+		},
+		{
+			name:       "link-local ipv6",
+			testString: "fe80::1",
+			want:       true,
+		},
+		{
+			name:       "aws ipv6 metadata",
+			testString: "fd00:ec2:0::254",
+			want:       true,
+		},
+		{
+			name:       "gcp metadata",
+			testString: "Metadata.Google.Internal.",
+			want:       true,
+		},
+		{
+			name:       "private ipv4",
+			testString: "10.0.0.1",
+		},
+		{
+			name:       "localhost",
+			testString: "localhost",
+		},
+		{
+			name:       "name",
+			testString: "hooks.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsMetadataHost(tt.testString))
+		})
+	}
+}
+
+func TestIsValidPEMCertificate(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "certificate",
+			testString: testCACert,
+			want:       true,
+		},
+		{
+			name:       "bundle",
+			testString: testCACert + testCACert,
+			want:       true,
+		},
+		{
+			name:       "trailing data",
+			testString: testCACert + "not a certificate",
+		},
+		{
+			name:       "not pem",
+			testString: "not a certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidPEMCertificate(tt.testString))
+		})
+	}
+}
+
+func TestIsValidKubernetesNamespace(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid",
+			testString: "team-a",
+			want:       true,
+		},
+		{
+			name:       "uppercase",
+			testString: "Team-A",
+		},
+		{
+			name:       "dots",
+			testString: "team.a",
+		},
+		{
+			name:       "wildcard",
+			testString: "*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidKubernetesNamespace(tt.testString))
+		})
+	}
+}
+
+func TestIsValidKubernetesName(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid",
+			testString: "cello-deployer",
+			want:       true,
+		},
+		{
+			name:       "dots",
+			testString: "cello.deployer",
+			want:       true,
+		},
+		{
+			name:       "trailing dash",
+			testString: "cello-",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidKubernetesName(tt.testString))
+		})
+	}
+}
+
+func TestIsValidAWSExternalID(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid",
+			testString: "cello:external-id/1234",
+			want:       true,
+		},
+		{
+			name:       "too short",
+			testString: "a",
+		},
+		{
+			name:       "invalid character",
+			testString: "cello external id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidAWSExternalID(tt.testString))
+		})
+	}
+}
+
+func TestIsValidAWSTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		wantKey   bool
+		wantValue bool
+	}{
+		{
+			name:      "valid",
+			key:       "cost-center",
+			value:     "team a",
+			wantKey:   true,
+			wantValue: true,
+		},
+		{
+			name:      "empty",
+			wantValue: true,
+		},
+		{
+			name:  "invalid characters",
+			key:   "team!",
+			value: "a*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantKey, IsValidAWSTagKey(tt.key))
+			assert.Equal(t, tt.wantValue, IsValidAWSTagValue(tt.value))
+		})
+	}
+}
+
+func TestIsValidTargetName(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid",
+			testString: "target_1",
+			want:       true,
+		},
+		{
+			name:       "too short",
+			testString: "tgt",
+		},
+		{
+			name:       "invalid character",
+			testString: "target-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidTargetName(tt.testString))
+		})
+	}
+}
+
+func TestIsValidProjectName(t *testing.T) {
+	tests := []struct {
+		name       string
+		testString string
+		want       bool
+	}{
+		{
+			name:       "valid",
+			testString: "project1",
+			want:       true,
+		},
+		{
+			name:       "too short",
+			testString: "prj",
+		},
+		{
+			name:       "invalid character",
+			testString: "project_1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsValidProjectName(tt.testString))
+		})
+	}
+}
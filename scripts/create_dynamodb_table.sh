@@ -12,3 +12,10 @@ aws dynamodb create-table \
   AttributeName=pk,KeyType=HASH \
   AttributeName=sk,KeyType=RANGE \
   --billing-mode PAY_PER_REQUEST
+
+# Remove expired idempotency keys and submissions
+aws dynamodb update-time-to-live \
+  --endpoint-url http://localhost:8000 \
+  --region us-west-2 \
+  --table-name cello \
+  --time-to-live-specification Enabled=true,AttributeName=ttl
@@ -175,6 +175,46 @@ path "aws/roles/argo-cloudops-projects-*" {
 path "aws/roles/*" {
   capabilities = [ "read", "list" ]
 }
+
+# Write GCP rolesets and impersonated accounts
+path "gcp/roleset/argo-cloudops-projects-*" {
+  capabilities = [ "create", "read", "update", "delete", "list" ]
+}
+
+path "gcp/impersonated-account/argo-cloudops-projects-*" {
+  capabilities = [ "create", "read", "update", "delete", "list" ]
+}
+
+# List GCP rolesets and impersonated accounts
+path "gcp/rolesets" {
+  capabilities = [ "list" ]
+}
+
+path "gcp/impersonated-accounts" {
+  capabilities = [ "list" ]
+}
+
+# Mount and manage Azure secrets engines
+path "sys/mounts" {
+  capabilities = [ "read" ]
+}
+
+path "sys/mounts/azure-argo-cloudops-projects-*" {
+  capabilities = [ "create", "read", "update", "delete" ]
+}
+
+path "azure-argo-cloudops-projects-*" {
+  capabilities = [ "create", "read", "update", "delete", "list" ]
+}
+
+# Mount and manage Kubernetes secrets engines
+path "sys/mounts/kubernetes-argo-cloudops-projects-*" {
+  capabilities = [ "create", "read", "update", "delete" ]
+}
+
+path "kubernetes-argo-cloudops-projects-*" {
+  capabilities = [ "create", "read", "update", "delete", "list" ]
+}
 EOF
 
 vault policy write argo-cloudops-service /tmp/argo-cloudops-policy.hcl
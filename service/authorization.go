@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/certs"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/oidc"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// identityKeyPrefix prefixes the name of an identity in the key of its
+// authorization, e.g. vault:identity/teamlead1:<secret>.
+const identityKeyPrefix = "identity/"
+
+// oidcKey is the key of the authorization of Bearer tokens, whose secret is
+// the user's JWT.
+const oidcKey = "oidc"
+
+// bearerPrefix prefixes the JWTs of users in the authorization header.
+const bearerPrefix = "Bearer "
+
+// clientCertPrefix prefixes the identity of a client certificate in the name
+// of its principal, e.g. cert/spiffe://example.com/ci/runner.
+const clientCertPrefix = "cert/"
+
+// workflowTokenTTL is the TTL of the project tokens created to submit the
+// workflows of admins and identities, they are revoked once used.
+const workflowTokenTTL = time.Minute
+
+var (
+	errUnauthorized = errors.New("unauthorized")
+	errForbidden    = errors.New("forbidden")
+)
+
+type contextKey int
+
+const (
+	principalContextKey contextKey = iota
+	credentialsProviderContextKey
+)
+
+// principal is who a request is authorized for.
+type principal struct {
+	// Name identifies the principal in logs, e.g. admin, identity/teamlead1,
+	// oidc/alice@example.com, cert/ci-runner or the role ID of a project
+	// token.
+	Name string
+	// Authorization is the authorization to create the credentials provider
+	// with, identities, users and clients act through the service's admin
+	// authorization.
+	Authorization credentials.Authorization
+
+	admin        bool
+	projectToken bool
+	// roles are the roles of identities and users.
+	roles types.RoleBindings
+}
+
+// can returns whether the principal has the permission on the project.
+// Project tokens can only run and read workflows, checkProjectToken checks
+// they belong to the project.
+func (p principal) can(permission, project string) bool {
+	switch {
+	case p.admin:
+		return true
+	case p.projectToken:
+		return permission == types.PermissionWorkflowsRun || permission == types.PermissionWorkflowsRead
+	}
+	return p.roles.Allows(permission, project)
+}
+
+// identityName returns the name of the identity the authorization is for.
+func identityName(a credentials.Authorization) (string, bool) {
+	if !strings.HasPrefix(a.Key, identityKeyPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(a.Key, identityKeyPrefix), true
+}
+
+// authenticate returns the principal of the authorization, checking the
+// secret of admins, identities, users and project tokens.
+func (h handler) authenticate(ctx context.Context, header http.Header, a credentials.Authorization) (principal, error) {
+	if a.IsAdmin() {
+		if err := a.ValidateAuthorizedAdmin(h.env.AdminSecret)(); err != nil {
+			return principal{}, fmt.Errorf("%w: %s", errUnauthorized, err)
+		}
+		return principal{Name: a.Key, Authorization: a, admin: true}, nil
+	}
+
+	adminAuthorization := credentials.AdminAuthorization(h.env.CredentialsProvider, h.env.AdminSecret)
+
+	if a.Key == oidcKey {
+		if h.oidcVerifier == nil {
+			return principal{}, fmt.Errorf("%w: oidc is not configured", errUnauthorized)
+		}
+
+		claims, err := h.oidcVerifier.Verify(ctx, a.Secret)
+		if err != nil {
+			if errors.Is(err, oidc.ErrInvalidToken) {
+				return principal{}, fmt.Errorf("%w: %s", errUnauthorized, err)
+			}
+			return principal{}, err
+		}
+		return principal{
+			Name:          fmt.Sprintf("%s/%s", oidcKey, claims.Username),
+			Authorization: adminAuthorization,
+			roles:         claims.Roles,
+		}, nil
+	}
+
+	// The secret of project tokens is verified by checkProjectToken, once
+	// the project is known.
+	name, ok := identityName(a)
+	if !ok {
+		return principal{Name: a.Key, Authorization: a, projectToken: true}, nil
+	}
+
+	ie, err := h.ddbClient.ReadIdentityEntry(ctx, name)
+	if err != nil {
+		if errors.Is(err, db.ErrIdentityNotFound) {
+			return principal{}, fmt.Errorf("%w: identity %s not found", errUnauthorized, name)
+		}
+		return principal{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(ie.SecretHash), []byte(a.Secret)); err != nil {
+		return principal{}, fmt.Errorf("%w: invalid secret for identity %s", errUnauthorized, name)
+	}
+
+	return principal{Name: a.Key, Authorization: adminAuthorization, roles: ie.Roles}, nil
+}
+
+// authenticateCertificate returns the principal of a verified client
+// certificate, which is the first of its identities bound to roles.
+func (h handler) authenticateCertificate(cert *x509.Certificate) (principal, error) {
+	for _, id := range certs.Identities(cert) {
+		roles, ok := h.clientRoles[id]
+		if !ok {
+			continue
+		}
+		return principal{
+			Name:          clientCertPrefix + id,
+			Authorization: credentials.AdminAuthorization(h.env.CredentialsProvider, h.env.AdminSecret),
+			roles:         roles,
+		}, nil
+	}
+	return principal{}, fmt.Errorf("%w: client certificate %s has no roles", errUnauthorized, cert.Subject)
+}
+
+// clientCertificate returns the client certificate of the request, it is nil
+// unless the certificate was verified against the client CAs.
+func clientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// checkPrincipal checks the principal has the permission on the project, the
+// project is empty for permissions which are not specific to a project.
+func checkPrincipal(p principal, permission, project string) error {
+	if p.can(permission, project) {
+		return nil
+	}
+	if p.projectToken {
+		return fmt.Errorf("%w: must be an authorized admin, identity or user", errUnauthorized)
+	}
+	return fmt.Errorf("%w: %s does not have permission %s", errForbidden, p.Name, permission)
+}
+
+// checkProjectToken checks the project token belongs to the project and
+// verifies its secret, which fails for unknown, expired and revoked tokens.
+// The secret is looked up with admin credentials rather than used to log in,
+// which would issue a credentials provider token.
+func (h handler) checkProjectToken(ctx context.Context, header http.Header, p principal, project string) error {
+	adminAuthorization := credentials.AdminAuthorization(h.env.CredentialsProvider, h.env.AdminSecret)
+	cp, err := h.credentialsProvider(ctx, adminAuthorization, header)
+	if err != nil {
+		return err
+	}
+
+	err = cp.VerifyProjectToken(project, p.Authorization.Key, p.Authorization.Secret)
+	switch {
+	case errors.Is(err, credentials.ErrNotFound):
+		return fmt.Errorf("%w: project token does not belong to project %s", errForbidden, project)
+	case errors.Is(err, credentials.ErrInvalidProjectToken):
+		return fmt.Errorf("%w: invalid project token %s", errUnauthorized, p.Authorization.Key)
+	}
+	return err
+}
+
+// checkAccess checks the principal has the permission on the project, and
+// that project tokens belong to it.
+func (h handler) checkAccess(ctx context.Context, header http.Header, p principal, permission, project string) error {
+	if err := checkPrincipal(p, permission, project); err != nil {
+		return err
+	}
+	// Project tokens are only verified for a project.
+	if p.projectToken {
+		if project == "" {
+			return fmt.Errorf("%w: project token requires a project", errUnauthorized)
+		}
+		return h.checkProjectToken(ctx, header, p, project)
+	}
+	return nil
+}
+
+// authorize authenticates the authorization and checks it has the permission
+// on the project.
+func (h handler) authorize(ctx context.Context, header http.Header, a credentials.Authorization, permission, project string) (principal, error) {
+	p, err := h.authenticate(ctx, header, a)
+	if err != nil {
+		return principal{}, err
+	}
+	if err := h.checkAccess(ctx, header, p, permission, project); err != nil {
+		return principal{}, err
+	}
+	return p, nil
+}
+
+// requestAuthorization returns the authorization of the request, writing the
+// error response when its header is invalid.
+func (h handler) requestAuthorization(w http.ResponseWriter, r *http.Request) (*credentials.Authorization, bool) {
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, bearerPrefix) {
+		return &credentials.Authorization{
+			Provider: h.env.CredentialsProvider,
+			Key:      oidcKey,
+			Secret:   strings.TrimPrefix(header, bearerPrefix),
+		}, true
+	}
+
+	a, err := credentials.NewAuthorization(header)
+	if err != nil {
+		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err := a.Validate(a.ValidateProvider(h.env.CredentialsProvider)); err != nil {
+		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
+		return nil, false
+	}
+	return a, true
+}
+
+// requestPrincipal authenticates the request, writing the error response
+// when it fails. Requests with a verified client certificate and without an
+// authorization header are authenticated by the certificate.
+func (h handler) requestPrincipal(w http.ResponseWriter, r *http.Request, l log.Logger) (principal, bool) {
+	var (
+		p   principal
+		err error
+	)
+	if cert := clientCertificate(r); cert != nil && r.Header.Get("Authorization") == "" {
+		p, err = h.authenticateCertificate(cert)
+	} else {
+		a, ok := h.requestAuthorization(w, r)
+		if !ok {
+			return principal{}, false
+		}
+		p, err = h.authenticate(r.Context(), r.Header, *a)
+	}
+
+	if err != nil {
+		h.authorizationErrorResponse(w, l, err)
+		return principal{}, false
+	}
+	return p, true
+}
+
+// authorizationErrorResponse writes the error response of an error
+// authenticating or authorizing a request.
+func (h handler) authorizationErrorResponse(w http.ResponseWriter, l log.Logger, err error) {
+	level.Error(l).Log("message", "error authorizing request", "error", err)
+
+	switch {
+	case errors.Is(err, errUnauthorized):
+		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
+	case errors.Is(err, errForbidden):
+		h.errorResponse(w, "error forbidden, insufficient permissions", http.StatusForbidden)
+	default:
+		h.errorResponse(w, "error authorizing request", http.StatusInternalServerError)
+	}
+}
+
+// requirement is the access a route requires.
+type requirement struct {
+	// public routes are not authenticated.
+	public bool
+	// permission is checked on the project returned by project, or on all
+	// projects when project is nil. Routes without a permission only require
+	// the principal to be authenticated.
+	permission string
+	project    func(r *http.Request) string
+	// credentials creates the credentials provider of the principal.
+	credentials bool
+}
+
+// public is the requirement of routes which are not authenticated.
+func public() requirement {
+	return requirement{public: true}
+}
+
+// authenticated is the requirement of routes which any principal can use.
+// Project tokens are only verified for a project, so they cannot use them.
+func authenticated() requirement {
+	return requirement{}
+}
+
+// allProjects is the requirement of routes which need a permission which is
+// not specific to a project, such as creating projects.
+func allProjects(permission string) requirement {
+	return requirement{permission: permission}
+}
+
+// projectMember is the requirement of routes which need a permission on the
+// project returned by project.
+func projectMember(permission string, project func(r *http.Request) string) requirement {
+	return requirement{permission: permission, project: project}
+}
+
+// withCredentials returns the requirement creating the credentials provider
+// of the principal.
+func (req requirement) withCredentials() requirement {
+	req.credentials = true
+	return req
+}
+
+// projectVar returns the project of the route's path.
+func projectVar(r *http.Request) string {
+	return mux.Vars(r)["projectName"]
+}
+
+// workflowProject returns the project of the route's workflow, workflow names
+// are prefixed with their project and target, and project names cannot
+// contain dashes.
+func workflowProject(r *http.Request) string {
+	project, _, _ := strings.Cut(mux.Vars(r)["workflowName"], "-")
+	return project
+}
+
+// workflowRequestProject returns the project of the workflow created from the
+// request, the body is restored for the route's handler, which reports when
+// it cannot be read.
+func workflowRequestProject(r *http.Request) string {
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var cwr requests.CreateWorkflow
+	if err := json.Unmarshal(body, &cwr); err != nil {
+		return ""
+	}
+	return cwr.ProjectName
+}
+
+// authMiddleware authenticates requests, checks the requirement of their
+// route and adds the principal, and the credentials provider when the route
+// requires it, to the request's context. Routes without a requirement are
+// forbidden.
+func (h handler) authMiddleware(requirements map[*mux.Route]requirement) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := h.requestLogger(r, "op", "authorize")
+
+			req, ok := requirements[mux.CurrentRoute(r)]
+			if !ok {
+				level.Error(l).Log("message", "route has no requirement", "path", r.URL.Path)
+				h.errorResponse(w, "error forbidden, insufficient permissions", http.StatusForbidden)
+				return
+			}
+
+			if req.public {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			p, ok := h.requestPrincipal(w, r, l)
+			if !ok {
+				return
+			}
+
+			ctx := r.Context()
+
+			if req.permission != "" {
+				project := ""
+				if req.project != nil {
+					project = req.project(r)
+				}
+
+				if err := h.checkAccess(ctx, r.Header, p, req.permission, project); err != nil {
+					h.authorizationErrorResponse(w, l, err)
+					return
+				}
+				level.Info(l).Log("message", "authorized request", "principal", p.Name, "permission", req.permission, "project", project)
+			} else if p.projectToken {
+				h.authorizationErrorResponse(w, l, fmt.Errorf("%w: project token requires a project", errUnauthorized))
+				return
+			}
+
+			ctx = context.WithValue(ctx, principalContextKey, p)
+
+			if req.credentials {
+				cp, err := h.credentialsProvider(r.Context(), p.Authorization, r.Header)
+				if err != nil {
+					level.Error(l).Log("message", "error creating credentials provider", "error", err)
+					h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
+					return
+				}
+				ctx = context.WithValue(ctx, credentialsProviderContextKey, cp)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// principalFromContext returns the principal added by authMiddleware.
+func principalFromContext(ctx context.Context) principal {
+	p, _ := ctx.Value(principalContextKey).(principal)
+	return p
+}
+
+// credentialsProviderFromContext returns the credentials provider added by
+// authMiddleware.
+func credentialsProviderFromContext(ctx context.Context) credentials.Provider {
+	cp, _ := ctx.Value(credentialsProviderContextKey).(credentials.Provider)
+	return cp
+}
+
+// credentialsToken returns the credentials provider token a workflow of the
+// project runs with. Admins and identities have no project credentials, a
+// project token with the scopes is created for them and revoked once it has
+// been used.
+func (h handler) credentialsToken(ctx context.Context, l log.Logger, cp credentials.Provider, a credentials.Authorization, header http.Header, projectName string, scopes types.TokenScopes) (string, error) {
+	if !a.IsAdmin() {
+		return cp.GetToken()
+	}
+
+	// Scoped tokens are limited by the project policy.
+	var policy types.ProjectPolicy
+	if len(scopes) > 0 {
+		pe, err := h.ddbClient.ReadProjectEntry(ctx, projectName)
+		if err != nil {
+			return "", err
+		}
+		policy = pe.Policy
+	}
+
+	token, err := cp.CreateToken(projectName, scopes, policy, workflowTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := cp.DeleteProjectToken(projectName, token.ProjectToken.ID); err != nil {
+			level.Error(l).Log("message", "error revoking workflow project token", "tokenID", token.ProjectToken.ID, "error", err)
+		}
+	}()
+
+	pa := credentials.Authorization{Provider: a.Provider, Key: token.RoleID, Secret: token.Secret}
+	pcp, err := h.credentialsProvider(ctx, pa, header)
+	if err != nil {
+		return "", err
+	}
+	return pcp.GetToken()
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/env"
+	"github.com/cello-proj/cello/service/internal/oidc"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// newIdentityDBMock returns a DBClientMock with the identity teamlead1, whose
+// secret is testPassword, bound to the roles, and a token of project1 scoped
+// to the role scoped-role-id.
+func newIdentityDBMock(t *testing.T, roles types.RoleBindings) *th.DBClientMock {
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &th.DBClientMock{
+		ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+			if name != "teamlead1" {
+				return db.IdentityEntry{}, db.ErrIdentityNotFound
+			}
+			return db.IdentityEntry{Name: name, Roles: roles, SecretHash: string(hash)}, nil
+		},
+	}
+}
+
+// newProjectRoleCPFactory returns a credentials provider factory whose
+// providers verify the tokens of project1, whose role ID is role-id or
+// scoped-role-id and whose secret is secret.
+func newProjectRoleCPFactory() credentials.ProviderFactory {
+	return func(a credentials.Authorization, env env.Vars, h http.Header, vaultConfigFn credentials.VaultConfigFn, vaultSvcFn credentials.VaultSvcFn) (credentials.Provider, error) {
+		return &th.CredsProviderMock{
+			VerifyProjectTokenFunc: func(project, roleID, secretID string) error {
+				if project != "project1" || (roleID != "role-id" && roleID != "scoped-role-id") {
+					return credentials.ErrNotFound
+				}
+				if secretID != "secret" {
+					return credentials.ErrInvalidProjectToken
+				}
+				return nil
+			},
+		}, nil
+	}
+}
+
+// newTestOIDCVerifier returns a verifier of the JWTs signed by the returned
+// function, which maps the group team-a to project-admin of project1.
+func newTestOIDCVerifier(t *testing.T) (*oidc.Verifier, func(claims map[string]interface{}) string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "key1", Algorithm: string(jose.RS256), Use: "sig"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, jwks, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := oidc.NewVerifier(oidc.Config{
+		Issuer:     "https://sso.example.com",
+		Audience:   "cello",
+		JWKSFile:   jwksFile,
+		GroupRoles: map[string]types.RoleBindings{"team-a": {{Role: types.RoleProjectAdmin, Project: "project1"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "key1"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v, func(claims map[string]interface{}) string {
+		raw, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	verifier, sign := newTestOIDCVerifier(t)
+	userJWT := sign(map[string]interface{}{
+		"iss":    "https://sso.example.com",
+		"aud":    "cello",
+		"email":  "alice@example.com",
+		"groups": []string{"team-a"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	expiredJWT := sign(map[string]interface{}{
+		"iss":    "https://sso.example.com",
+		"aud":    "cello",
+		"email":  "alice@example.com",
+		"groups": []string{"team-a"},
+		"exp":    time.Now().Add(-time.Hour).Unix(),
+	})
+
+	adminAuthorization := credentials.AdminAuthorization("vault", testPassword)
+	identityAuthorization := credentials.Authorization{Provider: "vault", Key: "identity/teamlead1", Secret: testPassword}
+	projectAuthorization := credentials.Authorization{Provider: "vault", Key: "role-id", Secret: "secret"}
+
+	tests := []struct {
+		name       string
+		a          credentials.Authorization
+		roles      types.RoleBindings
+		noOIDC     bool
+		permission string
+		project    string
+		want       principal
+		wantErr    error
+	}{
+		{
+			name:       "admin",
+			a:          adminAuthorization,
+			permission: types.PermissionProjectsWrite,
+			want:       principal{Name: "admin", Authorization: adminAuthorization, admin: true},
+		},
+		{
+			name:       "admin with invalid secret",
+			a:          credentials.AdminAuthorization("vault", "invalid"),
+			permission: types.PermissionProjectsWrite,
+			wantErr:    errUnauthorized,
+		},
+		{
+			name:       "project token running workflows",
+			a:          projectAuthorization,
+			permission: types.PermissionWorkflowsRun,
+			project:    "project1",
+			want:       principal{Name: "role-id", Authorization: projectAuthorization, projectToken: true},
+		},
+		{
+			name:       "scoped project token running workflows",
+			a:          credentials.Authorization{Provider: "vault", Key: "scoped-role-id", Secret: "secret"},
+			permission: types.PermissionWorkflowsRun,
+			project:    "project1",
+			want: principal{
+				Name:          "scoped-role-id",
+				Authorization: credentials.Authorization{Provider: "vault", Key: "scoped-role-id", Secret: "secret"},
+				projectToken:  true,
+			},
+		},
+		{
+			name:       "project token with invalid secret",
+			a:          credentials.Authorization{Provider: "vault", Key: "role-id", Secret: "invalid"},
+			permission: types.PermissionWorkflowsRun,
+			project:    "project1",
+			wantErr:    errUnauthorized,
+		},
+		{
+			name:       "project token running workflows of another project",
+			a:          projectAuthorization,
+			permission: types.PermissionWorkflowsRun,
+			project:    "project2",
+			wantErr:    errForbidden,
+		},
+		{
+			name:       "project token reading workflows",
+			a:          projectAuthorization,
+			permission: types.PermissionWorkflowsRead,
+			project:    "project1",
+			want:       principal{Name: "role-id", Authorization: projectAuthorization, projectToken: true},
+		},
+		{
+			name:       "project token with invalid secret reading workflows",
+			a:          credentials.Authorization{Provider: "vault", Key: "role-id", Secret: "invalid"},
+			permission: types.PermissionWorkflowsRead,
+			project:    "project1",
+			wantErr:    errUnauthorized,
+		},
+		{
+			name:       "project token reading targets",
+			a:          projectAuthorization,
+			permission: types.PermissionTargetsRead,
+			project:    "project1",
+			wantErr:    errUnauthorized,
+		},
+		{
+			name:       "identity with permission acts as admin",
+			a:          identityAuthorization,
+			roles:      types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			permission: types.PermissionTargetsWrite,
+			project:    "project1",
+			want: principal{
+				Name:          "identity/teamlead1",
+				Authorization: adminAuthorization,
+				roles:         types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			},
+		},
+		{
+			name:       "identity without permission",
+			a:          identityAuthorization,
+			roles:      types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			permission: types.PermissionTargetsWrite,
+			project:    "project2",
+			wantErr:    errForbidden,
+		},
+		{
+			name:       "identity with invalid secret",
+			a:          credentials.Authorization{Provider: "vault", Key: "identity/teamlead1", Secret: "invalid"},
+			roles:      types.RoleBindings{{Role: types.RoleAdmin}},
+			permission: types.PermissionTargetsWrite,
+			project:    "project1",
+			wantErr:    errUnauthorized,
+		},
+		{
+			name:       "identity does not exist",
+			a:          credentials.Authorization{Provider: "vault", Key: "identity/someone", Secret: testPassword},
+			permission: types.PermissionTargetsRead,
+			project:    "project1",
+			wantErr:    errUnauthorized,
+		},
+		{
+			name:       "user with permission acts as admin",
+			a:          credentials.Authorization{Provider: "vault", Key: oidcKey, Secret: userJWT},
+			permission: types.PermissionWorkflowsRun,
+			project:    "project1",
+			want: principal{
+				Name:          "oidc/alice@example.com",
+				Authorization: adminAuthorization,
+				roles:         types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			},
+		},
+		{
+			name:       "user without permission",
+			a:          credentials.Authorization{Provider: "vault", Key: oidcKey, Secret: userJWT},
+			permission: types.PermissionWorkflowsRun,
+			project:    "project2",
+			wantErr:    errForbidden,
+		},
+		{
+			name:       "user with expired token",
+			a:          credentials.Authorization{Provider: "vault", Key: oidcKey, Secret: expiredJWT},
+			permission: types.PermissionWorkflowsRun,
+			project:    "project1",
+			wantErr:    errUnauthorized,
+		},
+		{
+			name:       "user when oidc is not configured",
+			a:          credentials.Authorization{Provider: "vault", Key: oidcKey, Secret: userJWT},
+			noOIDC:     true,
+			permission: types.PermissionWorkflowsRun,
+			project:    "project1",
+			wantErr:    errUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler{
+				ddbClient:              newIdentityDBMock(t, tt.roles),
+				env:                    env.Vars{AdminSecret: testPassword, CredentialsProvider: "vault"},
+				newCredentialsProvider: newProjectRoleCPFactory(),
+				oidcVerifier:           verifier,
+			}
+			if tt.noOIDC {
+				h.oidcVerifier = nil
+			}
+
+			got, err := h.authorize(context.Background(), http.Header{}, tt.a, tt.permission, tt.project)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAuthorizeDatabaseError(t *testing.T) {
+	h := handler{
+		ddbClient: &th.DBClientMock{
+			ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+				return db.IdentityEntry{}, errors.New("error")
+			},
+		},
+		env: env.Vars{AdminSecret: testPassword, CredentialsProvider: "vault"},
+	}
+
+	a := credentials.Authorization{Provider: "vault", Key: "identity/teamlead1", Secret: testPassword}
+	_, err := h.authorize(context.Background(), http.Header{}, a, types.PermissionTargetsRead, "project1")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errUnauthorized)
+	assert.NotErrorIs(t, err, errForbidden)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	identityAuthHeader := "vault:identity/teamlead1:" + testPassword
+
+	tests := []struct {
+		name       string
+		url        string
+		authHeader string
+		// clientCert is the verified client certificate of the request.
+		clientCert *x509.Certificate
+		want       int
+		// wantPrincipal is the principal the route's handler should see.
+		wantPrincipal   string
+		wantCredentials bool
+	}{
+		{
+			name: "public route",
+			url:  "/public",
+			want: http.StatusOK,
+		},
+		{
+			name:       "route without requirement",
+			url:        "/unregistered",
+			authHeader: adminAuthHeader,
+			want:       http.StatusForbidden,
+		},
+		{
+			name: "missing authorization",
+			url:  "/workflows/project1-target1-abcde",
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:          "identity on its project",
+			url:           "/workflows/project1-target1-abcde",
+			authHeader:    identityAuthHeader,
+			want:          http.StatusOK,
+			wantPrincipal: "identity/teamlead1",
+		},
+		{
+			name:          "project token on its project",
+			url:           "/workflows/project1-target1-abcde",
+			authHeader:    "vault:role-id:secret",
+			want:          http.StatusOK,
+			wantPrincipal: "role-id",
+		},
+		{
+			name:       "project token with invalid secret",
+			url:        "/workflows/project1-target1-abcde",
+			authHeader: "vault:role-id:invalid",
+			want:       http.StatusUnauthorized,
+		},
+		{
+			name:       "project token on route without a project",
+			url:        "/workflows",
+			authHeader: "vault:role-id:secret",
+			want:       http.StatusUnauthorized,
+		},
+		{
+			name:       "identity on another project",
+			url:        "/workflows/project2-target1-abcde",
+			authHeader: identityAuthHeader,
+			want:       http.StatusForbidden,
+		},
+		{
+			name:          "client certificate on its project",
+			url:           "/workflows/project1-target1-abcde",
+			clientCert:    &x509.Certificate{Subject: pkix.Name{CommonName: "ci-runner-project1"}},
+			want:          http.StatusOK,
+			wantPrincipal: "cert/ci-runner-project1",
+		},
+		{
+			name:       "client certificate on another project",
+			url:        "/workflows/project2-target1-abcde",
+			clientCert: &x509.Certificate{Subject: pkix.Name{CommonName: "ci-runner-project1"}},
+			want:       http.StatusForbidden,
+		},
+		{
+			name: "admin client certificate matched by its san",
+			url:  "/projects/project2",
+			clientCert: &x509.Certificate{
+				Subject: pkix.Name{CommonName: "unmapped"},
+				URIs:    []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/ci/deployer"}},
+			},
+			want:            http.StatusOK,
+			wantPrincipal:   "cert/spiffe://example.com/ci/deployer",
+			wantCredentials: true,
+		},
+		{
+			name:       "client certificate without roles",
+			url:        "/workflows/project1-target1-abcde",
+			clientCert: &x509.Certificate{Subject: pkix.Name{CommonName: "unmapped"}},
+			want:       http.StatusUnauthorized,
+		},
+		{
+			name:          "authorization header takes precedence over client certificate",
+			url:           "/workflows/project1-target1-abcde",
+			authHeader:    identityAuthHeader,
+			clientCert:    &x509.Certificate{Subject: pkix.Name{CommonName: "unmapped"}},
+			want:          http.StatusOK,
+			wantPrincipal: "identity/teamlead1",
+		},
+		{
+			name:            "credentials provider",
+			url:             "/projects/project1",
+			authHeader:      adminAuthHeader,
+			want:            http.StatusOK,
+			wantPrincipal:   "admin",
+			wantCredentials: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler{
+				logger:                 log.NewNopLogger(),
+				ddbClient:              newIdentityDBMock(t, types.RoleBindings{{Role: types.RoleViewer, Project: "project1"}}),
+				env:                    env.Vars{AdminSecret: testPassword, CredentialsProvider: "vault"},
+				newCredentialsProvider: newProjectRoleCPFactory(),
+				clientRoles: map[string]types.RoleBindings{
+					"spiffe://example.com/ci/deployer": {{Role: types.RoleAdmin}},
+					"ci-runner-project1":               {{Role: types.RoleOperator, Project: "project1"}},
+				},
+			}
+
+			var (
+				gotPrincipal   string
+				gotCredentials bool
+			)
+			next := func(w http.ResponseWriter, r *http.Request) {
+				gotPrincipal = principalFromContext(r.Context()).Name
+				gotCredentials = credentialsProviderFromContext(r.Context()) != nil
+			}
+
+			r := mux.NewRouter()
+			requirements := map[*mux.Route]requirement{
+				r.HandleFunc("/public", next):                   public(),
+				r.HandleFunc("/workflows", next):                authenticated(),
+				r.HandleFunc("/workflows/{workflowName}", next): projectMember(types.PermissionWorkflowsRead, workflowProject),
+				r.HandleFunc("/projects/{projectName}", next):   projectMember(types.PermissionProjectsWrite, projectVar).withCredentials(),
+			}
+			r.HandleFunc("/unregistered", next)
+			r.Use(h.authMiddleware(requirements))
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			if tt.clientCert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.clientCert}}}
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			assert.Equal(t, tt.wantPrincipal, gotPrincipal)
+			assert.Equal(t, tt.wantCredentials, gotCredentials)
+		})
+	}
+}
+
+func TestCredentialsToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		a           credentials.Authorization
+		scopes      types.TokenScopes
+		wantCreated bool
+	}{
+		{
+			name: "project token",
+			a:    credentials.Authorization{Provider: "vault", Key: "role-id", Secret: "secret"},
+		},
+		{
+			name:        "admin",
+			a:           credentials.AdminAuthorization("vault", testPassword),
+			wantCreated: true,
+		},
+		{
+			name:        "admin with scopes",
+			a:           credentials.AdminAuthorization("vault", testPassword),
+			scopes:      types.TokenScopes{{Target: "target1", Operations: []string{"sync"}}},
+			wantCreated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpMock := &th.CredsProviderMock{
+				CreateTokenFunc: func(project string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					assert.Equal(t, workflowTokenTTL, ttl)
+					assert.Equal(t, tt.scopes, scopes)
+					// Scoped tokens are limited by the project policy.
+					if len(scopes) > 0 {
+						assert.Equal(t, []string{"target1"}, policy.Targets)
+					}
+					return types.Token{ProjectToken: types.ProjectToken{ID: "token1"}, RoleID: "role-id", Secret: "secret"}, nil
+				},
+				DeleteProjectTokenFunc: func(project, tokenID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return "credentials-token", nil },
+			}
+
+			h := handler{
+				ddbClient: &th.DBClientMock{
+					ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+						return db.ProjectEntry{ProjectID: project, Policy: types.ProjectPolicy{Targets: []string{"target1"}}}, nil
+					},
+				},
+				newCredentialsProvider: func(a credentials.Authorization, env env.Vars, h http.Header, vaultConfigFn credentials.VaultConfigFn, vaultSvcFn credentials.VaultSvcFn) (credentials.Provider, error) {
+					assert.Equal(t, credentials.Authorization{Provider: "vault", Key: "role-id", Secret: "secret"}, a)
+					return cpMock, nil
+				},
+			}
+
+			got, err := h.credentialsToken(context.Background(), log.NewNopLogger(), cpMock, tt.a, http.Header{}, "project1", tt.scopes)
+			assert.NoError(t, err)
+			assert.Equal(t, "credentials-token", got)
+
+			if !tt.wantCreated {
+				assert.Empty(t, cpMock.CreateTokenCalls())
+				return
+			}
+			assert.Len(t, cpMock.CreateTokenCalls(), 1)
+			if assert.Len(t, cpMock.DeleteProjectTokenCalls(), 1) {
+				assert.Equal(t, "token1", cpMock.DeleteProjectTokenCalls()[0].S2)
+			}
+		})
+	}
+}
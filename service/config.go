@@ -8,6 +8,8 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/cello-proj/cello/internal/types"
+
 	"gopkg.in/yaml.v2"
 )
 
@@ -22,6 +24,35 @@ type CommandVariables struct {
 type Config struct {
 	Version  string
 	Commands map[string]map[string]string `yaml:"commands"`
+	OIDC     OIDCConfig                   `yaml:"oidc"`
+	MTLS     MTLSConfig                   `yaml:"mtls"`
+}
+
+// OIDCConfig represents the configuration of users authenticated with OIDC.
+type OIDCConfig struct {
+	// GroupRoles maps the groups of users to the roles bound to them.
+	GroupRoles map[string]types.RoleBindings `yaml:"group_roles"`
+}
+
+// MTLSConfig represents the configuration of clients authenticated with
+// certificates.
+type MTLSConfig struct {
+	// ClientRoles maps the identities of client certificates, their URI, DNS
+	// or email SANs or subject common name, to the roles bound to them.
+	ClientRoles map[string]types.RoleBindings `yaml:"client_roles"`
+}
+
+// validate validates the roles of the clients.
+func (c MTLSConfig) validate() error {
+	for id, roles := range c.ClientRoles {
+		if len(roles) == 0 {
+			return fmt.Errorf("no roles for client '%s'", id)
+		}
+		if err := roles.Validate(); err != nil {
+			return fmt.Errorf("invalid roles for client '%s': %w", id, err)
+		}
+	}
+	return nil
 }
 
 func loadConfig(configFilePath string) (*Config, error) {
@@ -75,6 +106,23 @@ func (c Config) listTypes(framework string) ([]string, error) {
 	return keys, nil
 }
 
+// listAllTypes returns the types of all frameworks.
+func (c Config) listAllTypes() []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, commands := range c.Commands {
+		for k := range commands {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
 func generateExecuteCommand(commandDefinition, environmentVariablesString string, arguments map[string][]string) (string, error) {
 	initArguments := ""
 	if _, ok := arguments["init"]; ok {
@@ -3,6 +3,8 @@ package main
 import (
 	"testing"
 
+	"github.com/cello-proj/cello/internal/types"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -78,3 +80,94 @@ func TestListFrameworks(t *testing.T) {
 
 	assert.Equal(t, []string{"cdk", "cool-new-framework", "terraform"}, config.listFrameworks())
 }
+
+func TestListAllTypes(t *testing.T) {
+	config, err := loadConfig(testConfigPath)
+	if err != nil {
+		t.Errorf("Unable to load config %s", err)
+	}
+
+	assert.Equal(t, []string{"diff", "sync"}, config.listAllTypes())
+}
+
+func TestDefaultConfig(t *testing.T) {
+	config, err := loadConfig("../cello.yaml")
+	if err != nil {
+		t.Fatalf("Unable to load config %s", err)
+	}
+
+	assert.Equal(t, []string{"cdk", "helm", "kubectl", "terraform"}, config.listFrameworks())
+
+	commandDefinition, err := config.getCommandDefinition("kubectl", "sync")
+	assert.NoError(t, err)
+
+	result, err := generateExecuteCommand(commandDefinition, "", map[string][]string{"execute": {"-f", "manifests/"}})
+	assert.NoError(t, err)
+	assert.Equal(t, " kubectl apply -f manifests/", result)
+}
+
+func TestLoadConfigOIDC(t *testing.T) {
+	config, err := loadConfig(testConfigPath)
+	if err != nil {
+		t.Fatalf("Unable to load config %s", err)
+	}
+
+	assert.Equal(t, map[string]types.RoleBindings{
+		"platform": {{Role: types.RoleAdmin}},
+		"team-a": {
+			{Role: types.RoleProjectAdmin, Project: "project1"},
+			{Role: types.RoleViewer},
+		},
+	}, config.OIDC.GroupRoles)
+}
+
+func TestLoadConfigMTLS(t *testing.T) {
+	config, err := loadConfig(testConfigPath)
+	if err != nil {
+		t.Fatalf("Unable to load config %s", err)
+	}
+
+	assert.Equal(t, map[string]types.RoleBindings{
+		"spiffe://example.com/ci/deployer": {{Role: types.RoleAdmin}},
+		"ci-runner-project1":               {{Role: types.RoleOperator, Project: "project1"}},
+	}, config.MTLS.ClientRoles)
+	assert.NoError(t, config.MTLS.validate())
+}
+
+func TestMTLSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   map[string]types.RoleBindings
+		wantErr string
+	}{
+		{
+			name:  "no clients",
+			roles: nil,
+		},
+		{
+			name:  "project client",
+			roles: map[string]types.RoleBindings{"ci-runner": {{Role: types.RoleOperator, Project: "project1"}}},
+		},
+		{
+			name:    "client without roles",
+			roles:   map[string]types.RoleBindings{"ci-runner": {}},
+			wantErr: "no roles for client 'ci-runner'",
+		},
+		{
+			name:    "invalid role",
+			roles:   map[string]types.RoleBindings{"ci-runner": {{Role: types.RoleAdmin, Project: "project1"}}},
+			wantErr: "invalid roles for client 'ci-runner'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MTLSConfig{ClientRoles: tt.roles}.validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
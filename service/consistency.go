@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/service/internal/credentials"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// Types of the discrepancies between the database and the credentials
+// provider.
+const (
+	discrepancyProjectNotInDatabase = "project_not_in_database"
+	discrepancyProjectNotInProvider = "project_not_in_credentials_provider"
+	discrepancyTokenNotInDatabase   = "token_not_in_database"
+	discrepancyTokenNotInProvider   = "token_not_in_credentials_provider"
+)
+
+// Actions repairing discrepancies. Repairs only remove state, as the
+// database entry of a project cannot be recreated from the credentials
+// provider.
+const (
+	repairActionNone                  = "none"
+	repairActionDeleteDatabaseProject = "delete_database_project"
+	repairActionDeleteDatabaseToken   = "delete_database_token"
+	repairActionDeleteProviderProject = "delete_credentials_provider_project"
+	repairActionDeleteProviderToken   = "delete_credentials_provider_token"
+)
+
+// repairReasonProjectHasTargets explains why projects with targets are not
+// deleted from the credentials provider.
+const repairReasonProjectHasTargets = "project has targets"
+
+// repairTokenGracePeriod is how long after their creation tokens missing from
+// the database are not deleted from the credentials provider. Tokens are
+// created in the credentials provider before the database, and the tokens the
+// workflows of admins and identities are submitted with are never stored, so
+// recent tokens can be in use. It is longer than workflowTokenTTL.
+const repairTokenGracePeriod = 10 * time.Minute
+
+// repairReasonTokenInGracePeriod explains why recent tokens are not deleted
+// from the credentials provider.
+var repairReasonTokenInGracePeriod = fmt.Sprintf("token was created less than %s ago", repairTokenGracePeriod)
+
+// Reports the discrepancies between the database and the credentials provider
+func (h handler) checkConsistency(w http.ResponseWriter, r *http.Request) {
+	l := h.requestLogger(r, "op", "check-consistency")
+
+	cp := credentialsProviderFromContext(r.Context())
+
+	report, err := h.consistencyReport(r.Context(), cp, time.Now())
+	if err != nil {
+		level.Error(l).Log("message", "error checking consistency", "error", err)
+		h.errorResponse(w, "error checking consistency", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		level.Error(l).Log("message", "error serializing consistency report", "error", err)
+		h.errorResponse(w, "error checking consistency", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Repairs the discrepancies between the database and the credentials provider
+func (h handler) repairConsistency(w http.ResponseWriter, r *http.Request) {
+	l := h.requestLogger(r, "op", "repair-consistency")
+
+	level.Debug(l).Log("message", "reading request body")
+	reqBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	// The request body is optional, discrepancies are only repaired when
+	// dry_run is false.
+	rcr := requests.RepairConsistency{DryRun: true}
+	if len(bytes.TrimSpace(reqBody)) > 0 {
+		if err := json.Unmarshal(reqBody, &rcr); err != nil {
+			level.Error(l).Log("message", "error decoding request", "error", err)
+			h.errorResponse(w, "error decoding request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+
+	cp := credentialsProviderFromContext(r.Context())
+
+	report, err := h.consistencyReport(ctx, cp, time.Now())
+	if err != nil {
+		level.Error(l).Log("message", "error checking consistency", "error", err)
+		h.errorResponse(w, "error checking consistency", http.StatusInternalServerError)
+		return
+	}
+
+	report.DryRun = rcr.DryRun
+	if !rcr.DryRun {
+		for i := range report.Discrepancies {
+			h.repairDiscrepancy(ctx, l, cp, &report.Discrepancies[i])
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		level.Error(l).Log("message", "error serializing consistency report", "error", err)
+		h.errorResponse(w, "error repairing consistency", http.StatusInternalServerError)
+		return
+	}
+}
+
+// consistencyReport lists the projects, targets and tokens of the database
+// and the credentials provider, and the discrepancies between them.
+func (h handler) consistencyReport(ctx context.Context, cp credentials.Provider, now time.Time) (responses.Consistency, error) {
+	report := responses.Consistency{
+		Projects:      []responses.ConsistencyProject{},
+		Discrepancies: []responses.ConsistencyDiscrepancy{},
+	}
+
+	dbProjects, err := h.ddbClient.ListProjectEntries(ctx)
+	if err != nil {
+		return report, fmt.Errorf("error listing database projects: %w", err)
+	}
+
+	cpProjects, err := cp.ListProjects()
+	if err != nil {
+		return report, fmt.Errorf("error listing credentials provider projects: %w", err)
+	}
+
+	projects := map[string]*responses.ConsistencyProject{}
+	project := func(name string) *responses.ConsistencyProject {
+		if _, ok := projects[name]; !ok {
+			projects[name] = &responses.ConsistencyProject{
+				Name:                      name,
+				Targets:                   []string{},
+				DatabaseTokens:            []string{},
+				CredentialsProviderTokens: []string{},
+			}
+		}
+		return projects[name]
+	}
+
+	for _, pe := range dbProjects {
+		p := project(pe.ProjectID)
+		p.InDatabase = true
+
+		tokens, err := h.ddbClient.ListTokenEntries(ctx, pe.ProjectID)
+		if err != nil {
+			return report, fmt.Errorf("error listing database tokens of project %s: %w", pe.ProjectID, err)
+		}
+		for _, te := range tokens {
+			p.DatabaseTokens = append(p.DatabaseTokens, te.TokenID)
+		}
+		sort.Strings(p.DatabaseTokens)
+	}
+
+	for _, name := range cpProjects {
+		p := project(name)
+		p.InCredentialsProvider = true
+
+		if p.Targets, err = cp.ListTargets(name); err != nil {
+			return report, fmt.Errorf("error listing targets of project %s: %w", name, err)
+		}
+
+		if p.CredentialsProviderTokens, err = cp.ListProjectTokens(name); err != nil {
+			return report, fmt.Errorf("error listing credentials provider tokens of project %s: %w", name, err)
+		}
+	}
+
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := projects[name]
+		report.Projects = append(report.Projects, *p)
+
+		discrepancies, err := holdRecentTokens(cp, projectDiscrepancies(*p), now)
+		if err != nil {
+			return report, err
+		}
+		report.Discrepancies = append(report.Discrepancies, discrepancies...)
+	}
+
+	return report, nil
+}
+
+// holdRecentTokens does not repair the tokens missing from the database which
+// were created within repairTokenGracePeriod, or whose creation time is
+// unknown. Tokens revoked since they were listed are no longer discrepancies.
+func holdRecentTokens(cp credentials.Provider, discrepancies []responses.ConsistencyDiscrepancy, now time.Time) ([]responses.ConsistencyDiscrepancy, error) {
+	held := make([]responses.ConsistencyDiscrepancy, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		if d.Type != discrepancyTokenNotInDatabase {
+			held = append(held, d)
+			continue
+		}
+
+		token, err := cp.GetProjectToken(d.Project, d.TokenID)
+		if errors.Is(err, credentials.ErrProjectTokenNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting credentials provider token %s of project %s: %w", d.TokenID, d.Project, err)
+		}
+
+		createdAt, err := time.Parse(time.RFC3339Nano, token.CreatedAt)
+		if err != nil || now.Sub(createdAt) < repairTokenGracePeriod {
+			d.Action = repairActionNone
+			d.Reason = repairReasonTokenInGracePeriod
+		}
+		held = append(held, d)
+	}
+	return held, nil
+}
+
+// projectDiscrepancies returns the discrepancies of the project. Tokens are
+// only compared for projects in both stores, as repairing the project
+// removes them.
+func projectDiscrepancies(p responses.ConsistencyProject) []responses.ConsistencyDiscrepancy {
+	switch {
+	case !p.InDatabase:
+		d := responses.ConsistencyDiscrepancy{
+			Type:    discrepancyProjectNotInDatabase,
+			Project: p.Name,
+			Action:  repairActionDeleteProviderProject,
+		}
+		// Targets only exist in the credentials provider, so a project with
+		// targets is unlikely to be left over from a failed project creation.
+		if len(p.Targets) > 0 {
+			d.Action = repairActionNone
+			d.Reason = repairReasonProjectHasTargets
+		}
+		return []responses.ConsistencyDiscrepancy{d}
+	case !p.InCredentialsProvider:
+		return []responses.ConsistencyDiscrepancy{{
+			Type:    discrepancyProjectNotInProvider,
+			Project: p.Name,
+			Action:  repairActionDeleteDatabaseProject,
+		}}
+	}
+
+	discrepancies := []responses.ConsistencyDiscrepancy{}
+
+	inProvider := map[string]bool{}
+	for _, id := range p.CredentialsProviderTokens {
+		inProvider[id] = true
+	}
+
+	inDatabase := map[string]bool{}
+	for _, id := range p.DatabaseTokens {
+		inDatabase[id] = true
+		if !inProvider[id] {
+			discrepancies = append(discrepancies, responses.ConsistencyDiscrepancy{
+				Type:    discrepancyTokenNotInProvider,
+				Project: p.Name,
+				TokenID: id,
+				Action:  repairActionDeleteDatabaseToken,
+			})
+		}
+	}
+
+	for _, id := range p.CredentialsProviderTokens {
+		if !inDatabase[id] {
+			discrepancies = append(discrepancies, responses.ConsistencyDiscrepancy{
+				Type:    discrepancyTokenNotInDatabase,
+				Project: p.Name,
+				TokenID: id,
+				Action:  repairActionDeleteProviderToken,
+			})
+		}
+	}
+
+	return discrepancies
+}
+
+// repairDiscrepancy performs the action of the discrepancy, recording the
+// outcome on it.
+func (h handler) repairDiscrepancy(ctx context.Context, l log.Logger, cp credentials.Provider, d *responses.ConsistencyDiscrepancy) {
+	l = log.With(l, "type", d.Type, "action", d.Action, "project", d.Project, "tokenID", d.TokenID)
+
+	var err error
+	switch d.Action {
+	case repairActionDeleteDatabaseProject:
+		err = h.ddbClient.DeleteProjectEntry(ctx, d.Project)
+	case repairActionDeleteDatabaseToken:
+		err = h.ddbClient.DeleteTokenEntryByProject(ctx, d.Project, d.TokenID)
+	case repairActionDeleteProviderProject:
+		err = cp.DeleteProject(d.Project)
+	case repairActionDeleteProviderToken:
+		err = cp.DeleteProjectToken(d.Project, d.TokenID)
+	default:
+		return
+	}
+
+	if err != nil {
+		level.Error(l).Log("message", "error repairing discrepancy", "error", err)
+		d.Error = err.Error()
+		return
+	}
+
+	level.Info(l).Log("message", "repaired discrepancy")
+	d.Repaired = true
+}
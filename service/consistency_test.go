@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/db"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+)
+
+// newConsistencyMocks returns mocks where project1 is in both stores with
+// differing tokens, of which token5 was just created, project2 is only in the
+// database, and project3 and project4 are only in the credentials provider.
+func newConsistencyMocks() (*th.CredsProviderMock, *th.DBClientMock) {
+	cpMock := &th.CredsProviderMock{
+		ListProjectsFunc: func() ([]string, error) {
+			return []string{"project1", "project3", "project4"}, nil
+		},
+		ListProjectTokensFunc: func(project string) ([]string, error) {
+			if project == "project1" {
+				return []string{"token1", "token4", "token5"}, nil
+			}
+			return []string{}, nil
+		},
+		GetProjectTokenFunc: func(project, token string) (types.ProjectToken, error) {
+			createdAt := time.Now().Add(-time.Hour)
+			if token == "token5" {
+				createdAt = time.Now()
+			}
+			return types.ProjectToken{ID: token, CreatedAt: createdAt.Format(time.RFC3339Nano)}, nil
+		},
+		ListTargetsFunc: func(project string) ([]string, error) {
+			if project == "project3" {
+				return []string{}, nil
+			}
+			return []string{"target1"}, nil
+		},
+	}
+
+	ddbMock := &th.DBClientMock{
+		ListProjectEntriesFunc: func(ctx context.Context) ([]db.ProjectEntry, error) {
+			return []db.ProjectEntry{{ProjectID: "project1"}, {ProjectID: "project2"}}, nil
+		},
+		ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+			if project == "project1" {
+				return []db.TokenEntry{{TokenID: "token2"}, {TokenID: "token1"}}, nil
+			}
+			return []db.TokenEntry{{TokenID: "token3"}}, nil
+		},
+	}
+
+	return cpMock, ddbMock
+}
+
+func TestCheckConsistency(t *testing.T) {
+	cpMock, ddbMock := newConsistencyMocks()
+
+	tests := []test{
+		{
+			name:       "fails when not admin",
+			want:       http.StatusUnauthorized,
+			respFile:   "TestCheckConsistency/fails_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/consistency",
+			method:     "GET",
+		},
+		{
+			name:       "can check consistency",
+			want:       http.StatusOK,
+			respFile:   "TestCheckConsistency/can_check_consistency_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/consistency",
+			method:     "GET",
+			cpMock:     cpMock,
+			ddbMock:    ddbMock,
+		},
+		{
+			name:       "fails to list projects",
+			want:       http.StatusInternalServerError,
+			respFile:   "TestCheckConsistency/fails_to_list_projects_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/consistency",
+			method:     "GET",
+			cpMock:     cpMock,
+			ddbMock: &th.DBClientMock{
+				ListProjectEntriesFunc: func(ctx context.Context) ([]db.ProjectEntry, error) {
+					return nil, errors.New("error")
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestRepairConsistency(t *testing.T) {
+	// Repairs are not made on a dry run, the mocks panic if they are.
+	dryRunCPMock, dryRunDDBMock := newConsistencyMocks()
+
+	cpMock, ddbMock := newConsistencyMocks()
+	cpMock.DeleteProjectFunc = func(project string) error { return errors.New("error") }
+	cpMock.DeleteProjectTokenFunc = func(project, token string) error {
+		if project != "project1" || token != "token4" {
+			return fmt.Errorf("unexpected token %s of project %s", token, project)
+		}
+		return nil
+	}
+	ddbMock.DeleteProjectEntryFunc = func(ctx context.Context, project string) error {
+		if project != "project2" {
+			return fmt.Errorf("unexpected project %s", project)
+		}
+		return nil
+	}
+	ddbMock.DeleteTokenEntryByProjectFunc = func(ctx context.Context, project, token string) error {
+		if project != "project1" || token != "token2" {
+			return fmt.Errorf("unexpected token %s of project %s", token, project)
+		}
+		return nil
+	}
+
+	tests := []test{
+		{
+			name:       "fails when not admin",
+			want:       http.StatusUnauthorized,
+			respFile:   "TestRepairConsistency/fails_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/consistency/repair",
+			method:     "POST",
+		},
+		{
+			name:       "dry run",
+			req:        loadJSON(t, "TestRepairConsistency/dry_run_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestRepairConsistency/dry_run_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/consistency/repair",
+			method:     "POST",
+			cpMock:     dryRunCPMock,
+			ddbMock:    dryRunDDBMock,
+		},
+		{
+			name:       "defaults to a dry run",
+			want:       http.StatusOK,
+			respFile:   "TestRepairConsistency/dry_run_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/consistency/repair",
+			method:     "POST",
+			cpMock:     dryRunCPMock,
+			ddbMock:    dryRunDDBMock,
+		},
+		{
+			name:       "can repair consistency",
+			req:        loadJSON(t, "TestRepairConsistency/can_repair_consistency_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestRepairConsistency/can_repair_consistency_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/consistency/repair",
+			method:     "POST",
+			cpMock:     cpMock,
+			ddbMock:    ddbMock,
+		},
+	}
+	runTests(t, tests)
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/env"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const localAdminAuthHeader = "local:admin:" + testPassword
+
+// newMemoryDBMock returns a DBClientMock which keeps projects, tokens and
+// identities in memory.
+func newMemoryDBMock() *th.DBClientMock {
+	var mu sync.Mutex
+	projects := map[string]db.ProjectEntry{}
+	tokens := map[string]map[string]db.TokenEntry{}
+	identities := map[string]db.IdentityEntry{}
+
+	return &th.DBClientMock{
+		CreateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := projects[pe.ProjectID]; ok {
+				return fmt.Errorf("project %s already exists", pe.ProjectID)
+			}
+			projects[pe.ProjectID] = pe
+			tokens[pe.ProjectID] = map[string]db.TokenEntry{}
+			return nil
+		},
+		ListProjectEntriesFunc: func(ctx context.Context) ([]db.ProjectEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			list := []db.ProjectEntry{}
+			for _, pe := range projects {
+				list = append(list, pe)
+			}
+			return list, nil
+		},
+		ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			pe, ok := projects[project]
+			if !ok {
+				return db.ProjectEntry{}, db.ErrProjectNotFound
+			}
+			return pe, nil
+		},
+		UpdateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			existing, ok := projects[pe.ProjectID]
+			if !ok {
+				return db.ErrProjectNotFound
+			}
+			pe.Policy = existing.Policy
+			projects[pe.ProjectID] = pe
+			return nil
+		},
+		UpdateProjectPolicyFunc: func(ctx context.Context, project string, policy types.ProjectPolicy) error {
+			mu.Lock()
+			defer mu.Unlock()
+			pe, ok := projects[project]
+			if !ok {
+				return db.ErrProjectNotFound
+			}
+			pe.Policy = policy
+			projects[project] = pe
+			return nil
+		},
+		DeleteProjectEntryFunc: func(ctx context.Context, project string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(projects, project)
+			delete(tokens, project)
+			return nil
+		},
+		CreateTokenEntryFunc: func(ctx context.Context, token types.Token) error {
+			mu.Lock()
+			defer mu.Unlock()
+			tokens[token.ProjectID][token.ProjectToken.ID] = db.TokenEntry{
+				CreatedAt: token.CreatedAt,
+				ExpiresAt: token.ExpiresAt,
+				ProjectID: token.ProjectID,
+				RoleID:    token.RoleID,
+				Scopes:    token.Scopes,
+				TokenID:   token.ProjectToken.ID,
+			}
+			return nil
+		},
+		UpdateTokenEntryFunc: func(ctx context.Context, te db.TokenEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := tokens[te.ProjectID][te.TokenID]; !ok {
+				return db.ErrTokenNotFound
+			}
+			tokens[te.ProjectID][te.TokenID] = te
+			return nil
+		},
+		ReadTokenEntryByProjectFunc: func(ctx context.Context, project, token string) (db.TokenEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			te, ok := tokens[project][token]
+			if !ok {
+				return db.TokenEntry{}, db.ErrTokenNotFound
+			}
+			return te, nil
+		},
+		DeleteTokenEntryByProjectFunc: func(ctx context.Context, project, token string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(tokens[project], token)
+			return nil
+		},
+		ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			list := []db.TokenEntry{}
+			for _, te := range tokens[project] {
+				list = append(list, te)
+			}
+			return list, nil
+		},
+		// Webhooks are not kept.
+		ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+			return []db.WebhookEntry{}, nil
+		},
+		CreateIdentityEntryFunc: func(ctx context.Context, ie db.IdentityEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := identities[ie.Name]; ok {
+				return db.ErrIdentityExists
+			}
+			identities[ie.Name] = ie
+			return nil
+		},
+		ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ie, ok := identities[name]
+			if !ok {
+				return db.IdentityEntry{}, db.ErrIdentityNotFound
+			}
+			return ie, nil
+		},
+		ListIdentityEntriesFunc: func(ctx context.Context) ([]db.IdentityEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			list := []db.IdentityEntry{}
+			for _, ie := range identities {
+				list = append(list, ie)
+			}
+			return list, nil
+		},
+		UpdateIdentityEntryFunc: func(ctx context.Context, ie db.IdentityEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := identities[ie.Name]; !ok {
+				return db.ErrIdentityNotFound
+			}
+			identities[ie.Name] = ie
+			return nil
+		},
+		DeleteIdentityEntryFunc: func(ctx context.Context, name string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(identities, name)
+			return nil
+		},
+	}
+}
+
+// TestLocalProviderEndToEnd exercises the handlers with the local credentials
+// provider and no external services.
+func TestLocalProviderEndToEnd(t *testing.T) {
+	config, err := loadConfig(testConfigPath)
+	if err != nil {
+		t.Fatalf("unable to load config %s", err)
+	}
+
+	var submitted map[string]string
+	h := handler{
+		logger:                 log.NewNopLogger(),
+		newCredentialsProvider: credentials.NewProvider,
+		argoCtx:                context.Background(),
+		config:                 config,
+		gitClient:              &th.GitClientMock{},
+		ddbClient:              newMemoryDBMock(),
+		argo: &th.WorkflowMock{
+			SubmitFunc: func(ctx context.Context, from string, parameters, labels map[string]string) (string, error) {
+				submitted = parameters
+				return workflowResponse, nil
+			},
+		},
+		env: env.Vars{
+			AdminSecret:         testPassword,
+			CredentialsProvider: credentials.ProviderLocal,
+			LocalProviderFile:   filepath.Join(t.TempDir(), "cello.enc"),
+			LocalProviderKey:    testPassword,
+			LocalProviderToken:  "local-credentials-token",
+			TokenLimit:          2,
+			TokenMinTTL:         time.Hour,
+			TokenMaxTTL:         8776 * time.Hour,
+			TokenMaxGracePeriod: 24 * time.Hour,
+		},
+	}
+
+	do := func(method, url string, req interface{}, authHeader string, wantStatus int, out interface{}) {
+		t.Helper()
+
+		resp := executeRequestWithHandler(h, method, url, serialize(req), authHeader)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		if !assert.Equal(t, wantStatus, resp.StatusCode, "%s %s: %s", method, url, body) {
+			t.FailNow()
+		}
+
+		if out != nil {
+			assert.NoError(t, json.Unmarshal(body, out))
+		}
+	}
+
+	var project responses.CreateProject
+	do(http.MethodPost, "/projects", map[string]string{
+		"name":       "project1",
+		"repository": "git@github.com:myorg/myrepo.git",
+	}, localAdminAuthHeader, http.StatusOK, &project)
+	assert.NotEmpty(t, project.TokenID)
+
+	// Vault tokens are not accepted by the local provider.
+	do(http.MethodGet, "/projects/project1/targets", nil, adminAuthHeader, http.StatusUnauthorized, nil)
+
+	target := types.Target{
+		Name: "target1",
+		Type: "aws_account",
+		Properties: types.TargetProperties{
+			CredentialType: "assumed_role",
+			RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+		},
+	}
+	do(http.MethodPost, "/projects/project1/targets", target, localAdminAuthHeader, http.StatusOK, nil)
+
+	var targets []string
+	do(http.MethodGet, "/projects/project1/targets", nil, localAdminAuthHeader, http.StatusOK, &targets)
+	assert.Equal(t, []string{"target1"}, targets)
+
+	var gotTarget types.Target
+	do(http.MethodGet, "/projects/project1/targets/target1", nil, localAdminAuthHeader, http.StatusOK, &gotTarget)
+	assert.Equal(t, target, gotTarget)
+
+	policy := types.ProjectPolicy{Targets: []string{"target1"}}
+	do(http.MethodPut, "/projects/project1/policy", policy, localAdminAuthHeader, http.StatusOK, nil)
+
+	var gotPolicy types.ProjectPolicy
+	do(http.MethodGet, "/projects/project1/policy", nil, localAdminAuthHeader, http.StatusOK, &gotPolicy)
+	assert.Equal(t, policy, gotPolicy)
+
+	cwr := map[string]interface{}{
+		"arguments":              map[string][]string{"execute": {"foobar"}},
+		"framework":              "cdk",
+		"parameters":             map[string]string{"execute_container_image_uri": "celloproj/cello-cdk:1.87.1"},
+		"project_name":           "project1",
+		"target_name":            "target1",
+		"type":                   "sync",
+		"workflow_template_name": "cello-single-step-vault-aws",
+	}
+
+	var created map[string]string
+	do(http.MethodPost, "/workflows", cwr, project.Token, http.StatusOK, &created)
+	assert.Equal(t, workflowResponse, created["workflow_name"])
+	assert.Equal(t, "local-credentials-token", submitted["credentials_token"])
+
+	var token responses.CreateToken
+	do(http.MethodPost, "/projects/project1/tokens", nil, localAdminAuthHeader, http.StatusOK, &token)
+
+	var tokens, identityTokens []responses.ListTokens
+	do(http.MethodGet, "/projects/project1/tokens", nil, localAdminAuthHeader, http.StatusOK, &tokens)
+	assert.Len(t, tokens, 2)
+
+	do(http.MethodDelete, "/projects/project1/tokens/"+project.TokenID, nil, localAdminAuthHeader, http.StatusOK, nil)
+	do(http.MethodGet, "/projects/project1/tokens", nil, localAdminAuthHeader, http.StatusOK, &tokens)
+	assert.Len(t, tokens, 1)
+
+	// The deleted token can no longer create workflows, the new one can.
+	do(http.MethodPost, "/workflows", cwr, project.Token, http.StatusUnauthorized, nil)
+	do(http.MethodPost, "/workflows", cwr, token.Token, http.StatusOK, nil)
+
+	// Tokens cannot act on another project, which is rejected before the
+	// workflow is submitted.
+	do(http.MethodPost, "/projects", map[string]string{
+		"name":       "project2",
+		"repository": "git@github.com:myorg/myrepo.git",
+	}, localAdminAuthHeader, http.StatusOK, nil)
+	submitted = nil
+	cwr["project_name"] = "project2"
+	do(http.MethodPost, "/workflows", cwr, token.Token, http.StatusForbidden, nil)
+	assert.Nil(t, submitted)
+	do(http.MethodGet, "/workflows/project2-target1-abcde", nil, token.Token, http.StatusForbidden, nil)
+	cwr["project_name"] = "project1"
+
+	// Scoped tokens can only run the operation types of their scopes.
+	var scoped responses.CreateToken
+	do(http.MethodPost, "/projects/project1/tokens", map[string]interface{}{
+		"scopes": types.TokenScopes{{Target: "target1", Operations: []string{"diff"}}},
+	}, localAdminAuthHeader, http.StatusOK, &scoped)
+	do(http.MethodPost, "/workflows", cwr, scoped.Token, http.StatusForbidden, nil)
+	cwr["type"] = "diff"
+	do(http.MethodPost, "/workflows", cwr, scoped.Token, http.StatusOK, nil)
+
+	// Rotated tokens are revoked and replaced by a token with their scopes.
+	var rotated responses.RotateToken
+	do(http.MethodPost, "/projects/project1/tokens/"+scoped.TokenID+"/rotate", nil, localAdminAuthHeader, http.StatusOK, &rotated)
+	assert.Equal(t, scoped.Scopes, rotated.Scopes)
+	do(http.MethodPost, "/workflows", cwr, scoped.Token, http.StatusUnauthorized, nil)
+	do(http.MethodPost, "/workflows", cwr, rotated.Token, http.StatusOK, nil)
+
+	// Tokens missing from the database are reported, and are not removed on
+	// repair while they may still be being created.
+	var consistency responses.Consistency
+	do(http.MethodGet, "/consistency", nil, localAdminAuthHeader, http.StatusOK, &consistency)
+	assert.Empty(t, consistency.Discrepancies)
+	assert.NoError(t, h.ddbClient.DeleteTokenEntryByProject(context.Background(), "project1", rotated.TokenID))
+	do(http.MethodPost, "/consistency/repair", nil, localAdminAuthHeader, http.StatusOK, &consistency)
+	assert.True(t, consistency.DryRun)
+	do(http.MethodPost, "/consistency/repair", map[string]bool{"dry_run": false}, localAdminAuthHeader, http.StatusOK, &consistency)
+	if assert.Len(t, consistency.Discrepancies, 1) {
+		assert.Equal(t, rotated.TokenID, consistency.Discrepancies[0].TokenID)
+		assert.Equal(t, repairActionNone, consistency.Discrepancies[0].Action)
+		assert.False(t, consistency.Discrepancies[0].Repaired)
+	}
+	do(http.MethodPost, "/workflows", cwr, rotated.Token, http.StatusOK, nil)
+
+	// Identities are authorized by their roles.
+	var teamLead, operator responses.CreateIdentity
+	do(http.MethodPost, "/identities", map[string]interface{}{
+		"name":  "teamlead1",
+		"roles": types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+	}, localAdminAuthHeader, http.StatusOK, &teamLead)
+	do(http.MethodPost, "/identities", map[string]interface{}{
+		"name":  "operator1",
+		"roles": types.RoleBindings{{Role: types.RoleOperator}},
+	}, localAdminAuthHeader, http.StatusOK, &operator)
+	do(http.MethodPost, "/identities", map[string]interface{}{
+		"name":  "operator1",
+		"roles": types.RoleBindings{{Role: types.RoleViewer}},
+	}, localAdminAuthHeader, http.StatusBadRequest, nil)
+
+	var identities []responses.GetIdentity
+	do(http.MethodGet, "/identities", nil, localAdminAuthHeader, http.StatusOK, &identities)
+	if assert.Len(t, identities, 2) {
+		assert.Equal(t, "operator1", identities[0].Name)
+		assert.Equal(t, "teamlead1", identities[1].Name)
+	}
+
+	do(http.MethodGet, "/projects/project1/tokens", nil, teamLead.Token, http.StatusOK, &tokens)
+	do(http.MethodGet, "/projects/project1/targets/target1", nil, teamLead.Token, http.StatusOK, nil)
+	do(http.MethodGet, "/projects/project2/targets", nil, teamLead.Token, http.StatusForbidden, nil)
+	do(http.MethodDelete, "/projects/project1", nil, teamLead.Token, http.StatusForbidden, nil)
+	do(http.MethodGet, "/identities", nil, teamLead.Token, http.StatusForbidden, nil)
+	do(http.MethodGet, "/projects/project1/tokens", nil, "local:identity/teamlead1:invalid", http.StatusUnauthorized, nil)
+
+	// Workflows of identities run with a project token which is revoked once
+	// used.
+	do(http.MethodPost, "/workflows", cwr, operator.Token, http.StatusOK, &created)
+	do(http.MethodGet, "/projects/project1/tokens", nil, localAdminAuthHeader, http.StatusOK, &identityTokens)
+	assert.Len(t, identityTokens, len(tokens))
+	do(http.MethodPost, "/projects/project1/tokens", nil, operator.Token, http.StatusForbidden, nil)
+
+	var updated responses.GetIdentity
+	do(http.MethodPut, "/identities/operator1/roles", map[string]interface{}{
+		"roles": types.RoleBindings{{Role: types.RoleViewer, Project: "project1"}},
+	}, localAdminAuthHeader, http.StatusOK, &updated)
+	assert.Equal(t, types.RoleBindings{{Role: types.RoleViewer, Project: "project1"}}, updated.Roles)
+	do(http.MethodPost, "/workflows", cwr, operator.Token, http.StatusForbidden, nil)
+
+	do(http.MethodDelete, "/identities/operator1", nil, localAdminAuthHeader, http.StatusOK, nil)
+	do(http.MethodGet, "/identities/operator1", nil, localAdminAuthHeader, http.StatusNotFound, nil)
+	do(http.MethodGet, "/projects/project1/targets", nil, operator.Token, http.StatusUnauthorized, nil)
+
+	do(http.MethodDelete, "/projects/project1", nil, localAdminAuthHeader, http.StatusBadRequest, nil)
+	do(http.MethodDelete, "/projects/project1/targets/target1", nil, localAdminAuthHeader, http.StatusOK, nil)
+	do(http.MethodDelete, "/projects/project1", nil, localAdminAuthHeader, http.StatusOK, nil)
+	do(http.MethodGet, "/projects/project1", nil, localAdminAuthHeader, http.StatusNotFound, nil)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/cello-proj/cello/internal/requests"
 	"github.com/cello-proj/cello/internal/responses"
@@ -16,16 +18,19 @@ import (
 	"github.com/cello-proj/cello/service/internal/db"
 	"github.com/cello-proj/cello/service/internal/env"
 	"github.com/cello-proj/cello/service/internal/git"
+	"github.com/cello-proj/cello/service/internal/health"
+	"github.com/cello-proj/cello/service/internal/metrics"
+	"github.com/cello-proj/cello/service/internal/oidc"
+	"github.com/cello-proj/cello/service/internal/ratelimit"
+	"github.com/cello-proj/cello/service/internal/tracing"
 	"github.com/cello-proj/cello/service/internal/workflow"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v2"
-)
-
-const (
-	numOfTokensLimit = 2
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // Represents a JWT token.
@@ -51,29 +56,141 @@ func generateErrorResponseJSON(message string) string {
 // HTTP handler
 type handler struct {
 	logger                 log.Logger
-	newCredentialsProvider func(a credentials.Authorization, env env.Vars, h http.Header, vaultConfig credentials.VaultConfigFn, fn credentials.VaultSvcFn) (credentials.Provider, error)
+	newCredentialsProvider credentials.ProviderFactory
 	argo                   workflow.Workflow
 	argoCtx                context.Context
 	config                 *Config
 	gitClient              git.Client
 	env                    env.Vars
 	ddbClient              db.Client
+	// oidcVerifier verifies the JWTs of users, it is nil when OIDC is not
+	// configured.
+	oidcVerifier *oidc.Verifier
+	// clientRoles maps the identities of verified client certificates to
+	// their roles, it is empty when mutual TLS is not configured.
+	clientRoles map[string]types.RoleBindings
+	// rateLimiter limits the rate of requests of each principal, a nil
+	// limiter allows every request.
+	rateLimiter *ratelimit.Limiter
+	// metrics records the service's metrics, it is nil in tests which do not
+	// check them.
+	metrics *metrics.Metrics
+	// readiness runs the checks of /health/ready, a nil checker is always
+	// ready.
+	readiness *health.Checker
+	// stopStreams is done when the service stops waiting for log streams to
+	// finish during a shutdown, they are then ended with a notice. It is nil
+	// when the service is not shut down, as in tests.
+	stopStreams context.Context
+	// submissionsQueued wakes the submission queue's worker when a
+	// submission is queued.
+	submissionsQueued chan struct{}
 }
 
+// logStreamShutdownNotice ends the log streams stopped by a shutdown, the
+// workflow keeps running and its logs can be streamed again.
+const logStreamShutdownNotice = "cello: log stream stopped as the service is shutting down, request it again to resume"
+
 // Service HealthCheck
 func (h *handler) healthCheck(w http.ResponseWriter, r *http.Request) {
-	vaultEndpoint := fmt.Sprintf("%s/v1/sys/health", h.env.VaultAddress)
-	l := h.requestLogger(r, "op", "health-check", "vault-endpoint", vaultEndpoint)
+	// Only Vault is an external dependency; other providers are always healthy.
+	if h.env.CredentialsProvider != credentials.ProviderVault {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintln(w, "Health check succeeded")
+		return
+	}
 
-	// #nosec
-	response, err := http.Get(vaultEndpoint)
-	if err != nil {
-		level.Error(l).Log("message", "received error connecting to vault", "error", err)
+	l := h.requestLogger(r, "op", "health-check", "vault-endpoint", h.vaultHealthEndpoint())
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.env.HealthCheckTimeout)
+	defer cancel()
+
+	if err := h.vaultHealth(ctx, l); err != nil {
+		level.Error(l).Log("message", "vault health check failed", "error", err)
 		w.WriteHeader(http.StatusServiceUnavailable)
 		fmt.Fprintln(w, "Health check failed")
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "Health check succeeded")
+}
+
+// liveCheck reports the process is up, it checks no dependencies so
+// restarts aren't caused by their outages.
+func (h *handler) liveCheck(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, `{"status":"ok"}`)
+}
+
+// readyCheck reports whether every dependency is available, with the status
+// and latency of each.
+func (h *handler) readyCheck(w http.ResponseWriter, r *http.Request) {
+	l := h.requestLogger(r, "op", "ready-check")
+
+	result := h.readiness.Check(r.Context())
+	for name, component := range result.Components {
+		if err := component.Err(); err != nil {
+			level.Error(l).Log("message", "dependency check failed", "component", name, "error", err)
+		}
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		level.Error(l).Log("message", "error serializing readiness", "error", err)
+		h.errorResponse(w, "error serializing readiness", http.StatusInternalServerError)
+		return
+	}
+
+	if !result.OK() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintln(w, string(jsonData))
+}
+
+// readinessChecks returns the checks of /health/ready.
+func (h handler) readinessChecks() []health.Check {
+	checks := []health.Check{
+		{Name: "dynamodb", Func: h.ddbClient.Health},
+		{Name: "argo", Func: func(ctx context.Context) error {
+			argoCtx, cancel := h.argoContext(ctx)
+			defer cancel()
+			return h.argo.Health(argoCtx)
+		}},
+	}
+
+	// The local provider keeps its state in a file, so only Vault is checked.
+	if h.env.CredentialsProvider == credentials.ProviderVault {
+		checks = append(checks, health.Check{Name: "vault", Func: func(ctx context.Context) error {
+			return h.vaultHealth(ctx, h.logger)
+		}})
+	}
+
+	if h.env.HealthGitRepository != "" {
+		checks = append(checks, health.Check{Name: "git", Func: func(ctx context.Context) error {
+			return h.gitClient.Ping(ctx, h.env.HealthGitRepository)
+		}})
+	}
+
+	return checks
+}
+
+func (h handler) vaultHealthEndpoint() string {
+	return fmt.Sprintf("%s/v1/sys/health", h.env.VaultAddress)
+}
+
+// vaultHealth returns an error unless Vault is initialized, unsealed and
+// either active or a standby.
+func (h handler) vaultHealth(ctx context.Context, l log.Logger) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.vaultHealthEndpoint(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("received error connecting to vault: %w", err)
+	}
+
 	// We don't care about the body but need to read it all and close it
 	// regardless.
 	// https://golang.org/pkg/net/http/#Client.Do
@@ -84,20 +201,15 @@ func (h *handler) healthCheck(w http.ResponseWriter, r *http.Request) {
 		// Continue on and handle the actual response code from Vault accordingly.
 	}
 
-	if response.StatusCode != 200 && response.StatusCode != 429 {
-		level.Error(l).Log("message", fmt.Sprintf("received code %d which is not 200 (initialized, unsealed, and active) or 429 (unsealed and standby) when connecting to vault", response.StatusCode))
-		w.WriteHeader(http.StatusServiceUnavailable)
-		fmt.Fprintln(w, "Health check failed")
-		return
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("received code %d which is not 200 (initialized, unsealed, and active) or 429 (unsealed and standby) when connecting to vault", response.StatusCode)
 	}
 
-	w.Header().Set("Content-Type", "text/plain")
-	fmt.Fprintln(w, "Health check succeeded")
+	return nil
 }
 
 // Lists workflows
 func (h handler) listWorkflows(w http.ResponseWriter, r *http.Request) {
-	// TODO authenticate user can list this workflow once auth figured out
 	// TODO fail if project / target does not exist or are not valid format
 	vars := mux.Vars(r)
 	projectName := vars["projectName"]
@@ -106,7 +218,9 @@ func (h handler) listWorkflows(w http.ResponseWriter, r *http.Request) {
 	l := h.requestLogger(r, "op", "list-workflows", "project", projectName, "target", targetName)
 
 	level.Debug(l).Log("message", "listing workflows")
-	workflowList, err := h.argo.ListStatus(h.argoCtx)
+	argoCtx, cancel := h.argoContext(r.Context())
+	defer cancel()
+	workflowList, err := h.argo.ListStatus(argoCtx)
 	if err != nil {
 		level.Error(l).Log("message", "error listing workflows", "error", err)
 		h.errorResponse(w, "error listing workflows", http.StatusInternalServerError)
@@ -133,9 +247,9 @@ func (h handler) listWorkflows(w http.ResponseWriter, r *http.Request) {
 }
 
 // Creates workflow init params by pulling manifest from given git repo, commit sha, and code path
-func (h handler) loadCreateWorkflowRequestFromGit(repository, commitHash, path string) (requests.CreateWorkflow, error) {
+func (h handler) loadCreateWorkflowRequestFromGit(ctx context.Context, repository, commitHash, path string) (requests.CreateWorkflow, error) {
 	level.Debug(h.logger).Log("message", fmt.Sprintf("retrieving manifest from repository %s at sha %s with path %s", repository, commitHash, path))
-	fileContents, err := h.gitClient.GetManifestFile(repository, commitHash, path)
+	fileContents, err := tracing.InstrumentGit(ctx, h.gitClient).GetManifestFile(repository, commitHash, path)
 	if err != nil {
 		return requests.CreateWorkflow{}, err
 	}
@@ -150,18 +264,9 @@ func (h handler) createWorkflowFromGit(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	level.Debug(l).Log("message", "validating authorization header for create workflow from git")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	// TODO we need to ensure this _isn't an admin...
-	if err := a.Validate(); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
+	vars := mux.Vars(r)
+	projectName := vars["projectName"]
+	targetName := vars["targetName"]
 
 	level.Debug(l).Log("message", "reading request body")
 	reqBody, err := io.ReadAll(r.Body)
@@ -185,8 +290,20 @@ func (h handler) createWorkflowFromGit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	projectName := vars["projectName"]
+	// Checked before loading the manifest, createWorkflowFromRequest checks
+	// the target and type of the manifest.
+	scopes, err := h.tokenScopes(ctx, principalFromContext(ctx).Authorization, projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error retrieving token scopes", "error", err)
+		h.errorResponse(w, "error retrieving token", http.StatusInternalServerError)
+		return
+	}
+	if !scopes.AllowsTarget(targetName) {
+		level.Error(l).Log("message", "token scopes do not allow target", "target", targetName)
+		h.errorResponse(w, fmt.Sprintf("token is not allowed to run operations on target '%s'", targetName), http.StatusForbidden)
+		return
+	}
+
 	projectEntry, err := h.ddbClient.ReadProjectEntry(ctx, projectName)
 	if err != nil {
 		level.Error(l).Log("message", "error reading project data", "error", err)
@@ -194,17 +311,25 @@ func (h handler) createWorkflowFromGit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cwr, err := h.loadCreateWorkflowRequestFromGit(projectEntry.Repository, cgwr.CommitHash, cgwr.Path)
+	cwr, err := h.loadCreateWorkflowRequestFromGit(ctx, projectEntry.Repository, cgwr.CommitHash, cgwr.Path)
 	if err != nil {
 		level.Error(l).Log("message", "error loading workflow data from git", "error", err)
 		h.errorResponse(w, "error loading workflow data from git", http.StatusInternalServerError)
 		return
 	}
 
+	// The principal is authorized for the project and target of the route,
+	// the manifest cannot run workflows for others.
+	if cwr.ProjectName != projectName || cwr.TargetName != targetName {
+		level.Error(l).Log("message", "manifest project or target does not match request", "manifestProject", cwr.ProjectName, "manifestTarget", cwr.TargetName)
+		h.errorResponse(w, fmt.Sprintf("manifest must be for project '%s' and target '%s'", projectName, targetName), http.StatusForbidden)
+		return
+	}
+
 	log.With(l, "project", cwr.ProjectName, "target", cwr.TargetName, "framework", cwr.Framework, "type", cwr.Type, "workflow-template", cwr.WorkflowTemplateName)
 
 	level.Debug(l).Log("message", "creating workflow")
-	h.createWorkflowFromRequest(ctx, w, r, a, cwr, l)
+	h.createWorkflowFromRequest(ctx, w, r, cwr, cgwr.CommitHash, l)
 }
 
 // Creates a workflow
@@ -213,18 +338,6 @@ func (h handler) createWorkflow(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	level.Debug(l).Log("message", "validating authorization header for create workflow")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
 	level.Debug(l).Log("message", "reading request body")
 	var cwr requests.CreateWorkflow
 	reqBody, err := io.ReadAll(r.Body)
@@ -242,22 +355,121 @@ func (h handler) createWorkflow(w http.ResponseWriter, r *http.Request) {
 
 	log.With(l, "project", cwr.ProjectName, "target", cwr.TargetName, "framework", cwr.Framework, "type", cwr.Type, "workflow-template", cwr.WorkflowTemplateName)
 	level.Debug(l).Log("message", "creating workflow")
-	h.createWorkflowFromRequest(ctx, w, r, a, cwr, l)
+	h.createWorkflowFromRequest(ctx, w, r, cwr, "", l)
 }
 
 // Creates a workflow
-// Context is not currently used as Argo has its own and Vault doesn't
-// currently support it.
-func (h handler) createWorkflowFromRequest(_ context.Context, w http.ResponseWriter, r *http.Request, a *credentials.Authorization, cwr requests.CreateWorkflow, l log.Logger) {
+// Context is only used for the database as Argo has its own and Vault doesn't
+// currently support it. The commit hash is that of the manifest of workflows
+// created from git.
+func (h handler) createWorkflowFromRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, cwr requests.CreateWorkflow, commitHash string, l log.Logger) {
+	ws, serr := h.newWorkflowSubmission(l, cwr)
+	if serr != nil {
+		h.submitErrorResponse(w, serr)
+		return
+	}
+
+	a := principalFromContext(ctx).Authorization
+
+	level.Debug(l).Log("message", "checking token scopes")
+	scopes, err := h.tokenScopes(ctx, a, cwr.ProjectName)
+	if err != nil {
+		level.Error(l).Log("message", "error retrieving token scopes", "error", err)
+		h.errorResponse(w, "error retrieving token", http.StatusInternalServerError)
+		return
+	}
+	if !scopes.Allows(cwr.TargetName, cwr.Type) {
+		level.Error(l).Log("message", "token scopes do not allow operation")
+		h.errorResponse(w, fmt.Sprintf("token is not allowed to run '%s' on target '%s'", cwr.Type, cwr.TargetName), http.StatusForbidden)
+		return
+	}
+
+	// The traceparent label lets the workflow's steps join the request's
+	// trace.
+	ws.labels = tracing.WorkflowLabels(ctx, workflowLabels(r.Header.Get(txIDHeader), cwr.ProjectName, commitHash))
+
+	// Queued workflows are submitted with admin credentials, the secret of
+	// project tokens was checked when the request was authenticated.
+	if h.env.SubmissionQueue && prefersAsync(r.Header) {
+		h.queueSubmission(ctx, w, l, queuedSubmission{Request: cwr, Scopes: scopes, Labels: ws.labels})
+		return
+	}
+
+	cp := credentialsProviderFromContext(ctx)
+	workflowName, serr := h.submitWorkflow(ctx, l, cp, r.Header, ws, func() (string, error) {
+		return h.credentialsToken(ctx, l, cp, a, r.Header, cwr.ProjectName, nil)
+	})
+	if serr != nil {
+		h.submitErrorResponse(w, serr)
+		return
+	}
+
+	var cwresp workflow.CreateWorkflowResponse
+	cwresp.WorkflowName = workflowName
+	jsonData, err := json.Marshal(cwresp)
+	if err != nil {
+		level.Error(l).Log("message", "error serializing workflow response", "error", err)
+		h.errorResponse(w, "error serializing workflow response", http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, string(jsonData))
+}
+
+// workflowLabels returns the labels of a workflow. The project label is
+// watched by the webhook notifier. Commit hashes which are too long for a
+// label, which are not those of SHA-1 commits, are left out.
+func workflowLabels(txID, projectName, commitHash string) map[string]string {
+	labels := map[string]string{
+		txIDHeader:            txID,
+		workflow.ProjectLabel: projectName,
+	}
+	if commitHash != "" && len(validation.IsValidLabelValue(commitHash)) == 0 {
+		labels[workflow.CommitHashLabel] = commitHash
+	}
+	return labels
+}
+
+// submitError is an error submitting a workflow, with the status and message
+// of its response.
+type submitError struct {
+	status  int
+	message string
+	// retryAfter is the delay of the Retry-After header of limits.
+	retryAfter time.Duration
+}
+
+// retryable returns whether a queued submission which failed with the error
+// can succeed later, once a dependency is available or a limit has passed.
+func (se *submitError) retryable() bool {
+	return se.status == http.StatusTooManyRequests || se.status >= http.StatusInternalServerError
+}
+
+func (h handler) submitErrorResponse(w http.ResponseWriter, se *submitError) {
+	if se.retryAfter > 0 {
+		h.tooManyRequests(w, se.retryAfter, se.message)
+		return
+	}
+	h.errorResponse(w, se.message, se.status)
+}
+
+// workflowSubmission is a validated request and the workflow it submits.
+type workflowSubmission struct {
+	request              requests.CreateWorkflow
+	environmentVariables string
+	executeCommand       string
+	labels               map[string]string
+}
+
+// newWorkflowSubmission validates the request against the config and
+// generates the command of its workflow.
+func (h handler) newWorkflowSubmission(l log.Logger, cwr requests.CreateWorkflow) (workflowSubmission, *submitError) {
 	types, err := h.config.listTypes(cwr.Framework)
 	if err != nil {
 		level.Error(l).Log("message", "error invalid framework", "error", err)
-		h.errorResponse(
-			w,
-			fmt.Sprintf("invalid request, framework must be one of '%s'", strings.Join(h.config.listFrameworks(), " ")),
-			http.StatusBadRequest,
-		)
-		return
+		return workflowSubmission{}, &submitError{
+			status:  http.StatusBadRequest,
+			message: fmt.Sprintf("invalid request, framework must be one of '%s'", strings.Join(h.config.listFrameworks(), " ")),
+		}
 	}
 
 	level.Debug(l).Log("message", "validating workflow parameters")
@@ -265,96 +477,98 @@ func (h handler) createWorkflowFromRequest(_ context.Context, w http.ResponseWri
 		cwr.ValidateType(types),
 	); err != nil {
 		level.Error(l).Log("message", "error validating request", "error", err)
-		h.errorResponse(w, fmt.Sprintf("error invalid request, %s", err), http.StatusBadRequest)
-		return
+		return workflowSubmission{}, &submitError{status: http.StatusBadRequest, message: fmt.Sprintf("error invalid request, %s", err)}
 	}
 
-	workflowFrom := fmt.Sprintf("workflowtemplate/%s", cwr.WorkflowTemplateName)
-	executeContainerImageURI := cwr.Parameters["execute_container_image_uri"]
-	environmentVariablesString := generateEnvVariablesString(cwr.EnvironmentVariables)
+	ws := workflowSubmission{
+		request:              cwr,
+		environmentVariables: generateEnvVariablesString(cwr.EnvironmentVariables),
+	}
 
 	level.Debug(l).Log("message", "generating command to execute")
 	commandDefinition, err := h.config.getCommandDefinition(cwr.Framework, cwr.Type)
 	if err != nil {
 		level.Error(l).Log("message", "unable to get command definition", "error", err)
-		h.errorResponse(w, "unable to retrieve command definition", http.StatusInternalServerError)
-		return
+		return workflowSubmission{}, &submitError{status: http.StatusInternalServerError, message: "unable to retrieve command definition"}
 	}
-	executeCommand, err := generateExecuteCommand(commandDefinition, environmentVariablesString, cwr.Arguments)
+	ws.executeCommand, err = generateExecuteCommand(commandDefinition, ws.environmentVariables, cwr.Arguments)
 	if err != nil {
 		level.Error(l).Log("message", "unable to generate command", "error", err)
-		h.errorResponse(w, "unable to generate command", http.StatusInternalServerError)
-		return
+		return workflowSubmission{}, &submitError{status: http.StatusInternalServerError, message: "unable to generate command"}
 	}
 
-	level.Debug(l).Log("message", "creating new credentials provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "bad or unknown credentials provider", "error", err)
-		h.errorResponse(w, "bad or unknown credentials provider", http.StatusInternalServerError)
-		return
-	}
+	return ws, nil
+}
 
-	level.Debug(l).Log("message", "getting credentials provider token")
-	credentialsToken, err := cp.GetToken()
-	if err != nil {
-		level.Error(l).Log("message", "error getting credentials provider token", "error", err)
-		h.errorResponse(w, "error retrieving credentials provider token", http.StatusInternalServerError)
-		return
-	}
+// submitWorkflow checks the project, target and workflow quota of the
+// submission and submits its workflow, with the credentials provider token
+// returned by token. It is used by requests and the submission queue.
+func (h handler) submitWorkflow(ctx context.Context, l log.Logger, cp credentials.Provider, header http.Header, ws workflowSubmission, token func() (string, error)) (string, *submitError) {
+	cwr := ws.request
 
 	projectExists, err := cp.ProjectExists(cwr.ProjectName)
 	if err != nil {
 		level.Error(l).Log("message", "error checking project", "error", err)
-		h.errorResponse(w, "error checking project", http.StatusInternalServerError)
-		return
+		return "", &submitError{status: http.StatusInternalServerError, message: "error checking project"}
 	}
 
 	if !projectExists {
 		level.Error(l).Log("message", "project does not exist", "error", err)
-		h.errorResponse(w, "project does not exist", http.StatusBadRequest)
-		return
+		return "", &submitError{status: http.StatusBadRequest, message: "project does not exist"}
 	}
 
-	targetExists, err := cp.TargetExists(cwr.ProjectName, cwr.TargetName)
+	// Project tokens cannot read targets, the target is read with admin
+	// credentials. Its type is passed to the workflow so setup.sh reads its
+	// credentials without looking it up.
+	adminAuthorization := credentials.AdminAuthorization(h.env.CredentialsProvider, h.env.AdminSecret)
+	adminCP, err := h.credentialsProvider(ctx, adminAuthorization, header)
 	if err != nil {
-		level.Error(l).Log("message", "error retrieving target", "error", err)
-		h.errorResponse(w, "error retrieving target", http.StatusInternalServerError)
-		return
+		level.Error(l).Log("message", "error creating credentials provider", "error", err)
+		return "", &submitError{status: http.StatusInternalServerError, message: "error retrieving target"}
 	}
-	if !targetExists {
+
+	target, err := adminCP.GetTarget(cwr.ProjectName, cwr.TargetName)
+	if errors.Is(err, credentials.ErrTargetNotFound) {
 		level.Error(l).Log("message", "target not found")
-		h.errorResponse(w, "target not found", http.StatusBadRequest)
-		return
+		return "", &submitError{status: http.StatusBadRequest, message: "target not found"}
+	}
+	if err != nil {
+		level.Error(l).Log("message", "error retrieving target", "error", err)
+		return "", &submitError{status: http.StatusInternalServerError, message: "error retrieving target"}
 	}
 
-	level.Debug(l).Log("message", "creating workflow parameters")
-	parameters := workflow.NewParameters(environmentVariablesString, executeCommand, executeContainerImageURI, cwr.TargetName, cwr.ProjectName, cwr.Parameters, credentialsToken, cwr.Type)
+	level.Debug(l).Log("message", "checking workflow quota")
+	if serr := h.checkWorkflowQuota(ctx, l, cwr.ProjectName); serr != nil {
+		return "", serr
+	}
+
+	level.Debug(l).Log("message", "getting credentials provider token")
+	credentialsToken, err := token()
+	if err != nil {
+		level.Error(l).Log("message", "error getting credentials provider token", "error", err)
+		return "", &submitError{status: http.StatusInternalServerError, message: "error retrieving credentials provider token"}
+	}
 
-	workflowLabels := map[string]string{txIDHeader: r.Header.Get(txIDHeader)}
+	level.Debug(l).Log("message", "creating workflow parameters")
+	parameters := workflow.NewParameters(ws.environmentVariables, ws.executeCommand, cwr.Parameters["execute_container_image_uri"], cwr.TargetName, target.Type, target.Properties.CredentialType, cwr.ProjectName, cwr.Parameters, credentialsToken, cwr.Type)
 
 	level.Debug(l).Log("message", "creating workflow")
-	workflowName, err := h.argo.Submit(h.argoCtx, workflowFrom, parameters, workflowLabels)
+	argoCtx, cancel := h.argoContext(ctx)
+	defer cancel()
+	workflowName, err := h.argo.Submit(argoCtx, fmt.Sprintf("workflowtemplate/%s", cwr.WorkflowTemplateName), parameters, ws.labels)
 	if err != nil {
 		level.Error(l).Log("message", "error creating workflow", "error", err)
-		h.errorResponse(w, "error creating workflow", http.StatusInternalServerError)
-		return
+		return "", &submitError{status: http.StatusInternalServerError, message: "error creating workflow"}
 	}
 
+	h.metrics.WorkflowSubmitted(cwr.ProjectName, cwr.TargetName, cwr.Framework, cwr.Type)
+
 	l = log.With(l, "workflow", workflowName)
 	level.Debug(l).Log("message", "workflow created")
 	tokenHead := credentialsToken[0:8]
 
 	level.Info(l).Log("message", fmt.Sprintf("Received token '%s...'", tokenHead))
-	var cwresp workflow.CreateWorkflowResponse
-	cwresp.WorkflowName = workflowName
-	jsonData, err := json.Marshal(cwresp)
-	if err != nil {
-		level.Error(l).Log("message", "error serializing workflow response", "error", err)
-		h.errorResponse(w, "error serializing workflow response", http.StatusInternalServerError)
-		return
-	}
-	fmt.Fprintln(w, string(jsonData))
+	return workflowName, nil
 }
 
 // Gets a workflow
@@ -364,7 +578,9 @@ func (h handler) getWorkflow(w http.ResponseWriter, r *http.Request) {
 	l := h.requestLogger(r, "op", "get-workflow", "workflow", workflowName)
 
 	level.Debug(l).Log("message", "getting workflow status")
-	status, err := h.argo.Status(h.argoCtx, workflowName)
+	argoCtx, cancel := h.argoContext(r.Context())
+	defer cancel()
+	status, err := h.argo.Status(argoCtx, workflowName)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "code = NotFound") {
@@ -397,25 +613,7 @@ func (h handler) getTarget(w http.ResponseWriter, r *http.Request) {
 
 	l := h.requestLogger(r, "op", "get-target", "project", projectName, "target", targetName)
 
-	level.Debug(l).Log("message", "validating authorization header for get target")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 
 	targetExists, err := cp.TargetExists(projectName, targetName)
 	if err != nil {
@@ -456,7 +654,9 @@ func (h handler) getWorkflowLogs(w http.ResponseWriter, r *http.Request) {
 	l := h.requestLogger(r, "op", "get-workflow-logs", "workflow", workflowName)
 
 	level.Debug(l).Log("message", "retrieving workflow logs")
-	argoWorkflowLogs, err := h.argo.Logs(h.argoCtx, workflowName)
+	argoCtx, cancel := h.argoContext(r.Context())
+	defer cancel()
+	argoWorkflowLogs, err := h.argo.Logs(argoCtx, workflowName)
 	if err != nil {
 		level.Error(l).Log("message", "error getting workflow logs", "error", err)
 		h.errorResponse(w, "error getting workflow logs", http.StatusInternalServerError)
@@ -481,8 +681,25 @@ func (h handler) getWorkflowLogStream(w http.ResponseWriter, r *http.Request) {
 
 	l := h.requestLogger(r, "op", "get-workflow-log-stream", "workflow", workflowName)
 
+	// Log streams last as long as their workflow, which is usually longer
+	// than the server's write timeout.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		level.Debug(l).Log("message", "unable to lift write deadline of log stream", "error", err)
+	}
+
 	level.Debug(l).Log("message", "retrieving workflow logs", "workflow", workflowName)
-	err := h.argo.LogStream(h.argoCtx, workflowName, w)
+	argoCtx, cancel := h.argoContext(r.Context())
+	defer cancel()
+	if h.stopStreams != nil {
+		stop := context.AfterFunc(h.stopStreams, cancel)
+		defer stop()
+	}
+	err := h.argo.LogStream(argoCtx, workflowName, w)
+	if h.stopStreams != nil && h.stopStreams.Err() != nil {
+		level.Info(l).Log("message", "log stream stopped by shutdown")
+		fmt.Fprintln(w, logStreamShutdownNotice)
+		return
+	}
 	if err != nil {
 		level.Error(l).Log("message", "error getting workflow logstream", "error", err)
 		h.errorResponse(w, "error getting workflow logs", http.StatusInternalServerError)
@@ -490,6 +707,24 @@ func (h handler) getWorkflowLogStream(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// tokenScopes returns the scopes of the project token authorizing the
+// request. Tokens with equal scopes share a role, tokens of other roles are
+// not scoped. Project tokens without a role in their token entry were created
+// before scopes and are not scoped.
+func (h handler) tokenScopes(ctx context.Context, a credentials.Authorization, projectName string) (types.TokenScopes, error) {
+	tokens, err := h.ddbClient.ListTokenEntries(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tokens {
+		if t.RoleID == a.Key {
+			return t.Scopes, nil
+		}
+	}
+	return nil, nil
+}
+
 // Returns a new Cello token
 func newCelloToken(provider string, tok types.Token) *token {
 	return &token{
@@ -497,6 +732,36 @@ func newCelloToken(provider string, tok types.Token) *token {
 	}
 }
 
+// tokenLimit returns the number of tokens the project can have.
+func (h handler) tokenLimit(pe db.ProjectEntry) int {
+	if pe.TokenLimit > 0 {
+		return pe.TokenLimit
+	}
+	return h.env.TokenLimit
+}
+
+// tokenTTL returns the TTL of a new token of the project, the requested TTL
+// in seconds when set, otherwise the project's default. The project's default
+// is kept within the bounds in case they changed since it was set.
+func (h handler) tokenTTL(requested int, pe db.ProjectEntry) time.Duration {
+	if requested > 0 {
+		return time.Duration(requested) * time.Second
+	}
+
+	if pe.TokenTTL == 0 {
+		return h.env.TokenMaxTTL
+	}
+
+	ttl := time.Duration(pe.TokenTTL) * time.Second
+	if ttl < h.env.TokenMinTTL {
+		return h.env.TokenMinTTL
+	}
+	if ttl > h.env.TokenMaxTTL {
+		return h.env.TokenMaxTTL
+	}
+	return ttl
+}
+
 // projectExists checks if a project exists using both the credential provider and database
 func (h handler) projectExists(ctx context.Context, l log.Logger, cp credentials.Provider, w http.ResponseWriter, projectName string) (bool, error) {
 	// Checking credential provider
@@ -533,18 +798,6 @@ func (h handler) projectExists(ctx context.Context, l log.Logger, cp credentials
 func (h handler) createProject(w http.ResponseWriter, r *http.Request) {
 	l := h.requestLogger(r, "op", "create-project")
 
-	level.Debug(l).Log("message", "validating authorization header for create project")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
 	ctx := r.Context()
 
 	var capp requests.CreateProject
@@ -559,7 +812,7 @@ func (h handler) createProject(w http.ResponseWriter, r *http.Request) {
 		h.errorResponse(w, "error decoding request", http.StatusBadRequest)
 		return
 	}
-	if err := capp.Validate(); err != nil {
+	if err := capp.Validate(capp.ValidateTokenTTL(h.env.TokenMinTTL, h.env.TokenMaxTTL)); err != nil {
 		level.Error(l).Log("message", "error invalid request", "error", err)
 		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err.Error()), http.StatusBadRequest)
 		return
@@ -567,13 +820,7 @@ func (h handler) createProject(w http.ResponseWriter, r *http.Request) {
 
 	l = log.With(l, "project", capp.Name)
 
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 
 	projectExists, err := cp.ProjectExists(capp.Name)
 	if err != nil {
@@ -588,35 +835,44 @@ func (h handler) createProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	level.Debug(l).Log("message", "inserting into db")
-	err = h.ddbClient.CreateProjectEntry(ctx, db.ProjectEntry{
-		ProjectID:  capp.Name,
-		Repository: capp.Repository,
-	})
-	if err != nil {
-		level.Error(l).Log("message", "error inserting project to db", "error", err)
-		h.errorResponse(w, "error creating project", http.StatusInternalServerError)
-		return
-	}
-
-	level.Debug(l).Log("message", "creating project")
-	token, err := cp.CreateProject(capp.Name)
+	projectEntry := db.ProjectEntry{
+		ProjectID:     capp.Name,
+		Repository:    capp.Repository,
+		TokenLimit:    capp.TokenLimit,
+		TokenTTL:      capp.TokenTTL,
+		WorkflowQuota: capp.WorkflowQuota,
+	}
+
+	var token types.Token
+	err = newSaga(l).
+		addStep("create project entry", func(ctx context.Context) error {
+			return h.createProjectEntry(ctx, projectEntry)
+		}, func(ctx context.Context) error {
+			return h.ddbClient.DeleteProjectEntry(ctx, capp.Name)
+		}).
+		addStep("create project", func(ctx context.Context) error {
+			token, err = cp.CreateProject(capp.Name, h.tokenTTL(0, projectEntry))
+			return err
+		}, func(ctx context.Context) error {
+			return cp.DeleteProject(capp.Name)
+		}).
+		addStep("create token entry", func(ctx context.Context) error {
+			return h.ddbClient.CreateTokenEntry(ctx, token)
+		}, nil).
+		run(ctx)
 	if err != nil {
 		level.Error(l).Log("message", "error creating project", "error", err)
+		var se *sagaError
+		if errors.As(err, &se) && se.Step == sagaStepCreateTokenEntry {
+			h.errorResponse(w, "error creating token", http.StatusInternalServerError)
+			return
+		}
 		h.errorResponse(w, "error creating project", http.StatusInternalServerError)
 		return
 	}
 
-	level.Debug(l).Log("message", "inserting token into DB")
-	err = h.ddbClient.CreateTokenEntry(ctx, token)
-	if err != nil {
-		level.Error(l).Log("message", "error inserting token into db", "error", err)
-		h.errorResponse(w, "error creating token", http.StatusInternalServerError)
-		return
-	}
-
 	level.Debug(l).Log("message", "retrieving Cello token")
-	celloToken := newCelloToken("vault", token)
+	celloToken := newCelloToken(h.env.CredentialsProvider, token)
 
 	resp := responses.CreateProject{
 		Token:   celloToken.Token,
@@ -630,6 +886,25 @@ func (h handler) createProject(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// createProjectEntry creates the database entry of the project. An equal
+// entry left behind by a previous attempt is reused, as the project does not
+// exist in the credentials provider.
+func (h handler) createProjectEntry(ctx context.Context, pe db.ProjectEntry) error {
+	err := h.ddbClient.CreateProjectEntry(ctx, pe)
+	if !errors.Is(err, db.ErrProjectExists) {
+		return err
+	}
+
+	existing, rerr := h.ddbClient.ReadProjectEntry(ctx, pe.ProjectID)
+	if rerr != nil {
+		return rerr
+	}
+	if existing.Repository != pe.Repository {
+		return err
+	}
+	return h.ddbClient.UpdateProjectEntry(ctx, pe)
+}
+
 // Get a project
 func (h handler) getProject(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -637,18 +912,6 @@ func (h handler) getProject(w http.ResponseWriter, r *http.Request) {
 
 	l := h.requestLogger(r, "op", "get-project", "project", projectName)
 
-	level.Debug(l).Log("message", "validating authorization header for get project")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
 	level.Debug(l).Log("message", "getting project from database")
 	ctx := r.Context()
 	projectEntry, err := h.ddbClient.ReadProjectEntry(ctx, projectName)
@@ -662,47 +925,102 @@ func (h handler) getProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := responses.GetProject{
-		Name:       projectName,
-		Repository: projectEntry.Repository,
-	}
-
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+	if err := json.NewEncoder(w).Encode(h.getProjectResponse(projectEntry)); err != nil {
 		level.Error(l).Log("message", "error creating response", "error", err)
 		h.errorResponse(w, "error creating response object", http.StatusInternalServerError)
 		return
 	}
 }
 
-// Delete a project
-func (h handler) deleteProject(w http.ResponseWriter, r *http.Request) {
+// getProjectResponse returns the project with the token settings and
+// workflow quota in effect.
+func (h handler) getProjectResponse(pe db.ProjectEntry) responses.GetProject {
+	return responses.GetProject{
+		Name:          pe.ProjectID,
+		Repository:    pe.Repository,
+		TokenLimit:    h.tokenLimit(pe),
+		TokenTTL:      int(h.tokenTTL(0, pe).Seconds()),
+		WorkflowQuota: h.workflowQuota(pe),
+	}
+}
+
+// Updates the token settings and workflow quota of a project
+func (h handler) updateProject(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	projectName := vars["projectName"]
 
-	l := h.requestLogger(r, "op", "delete-project", "project", projectName)
-
-	level.Debug(l).Log("message", "validating authorization header for delete project")
-	ctx := r.Context()
+	l := h.requestLogger(r, "op", "update-project", "project", projectName)
 
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
+	reqBody, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
 		return
 	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
+
+	var upr requests.UpdateProject
+	if err := json.Unmarshal(reqBody, &upr); err != nil {
+		level.Error(l).Log("message", "error decoding request", "error", err)
+		h.errorResponse(w, "error decoding request", http.StatusBadRequest)
 		return
 	}
 
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
+	if err := upr.Validate(upr.ValidateTokenTTL(h.env.TokenMinTTL, h.env.TokenMaxTTL)); err != nil {
+		level.Error(l).Log("message", "error invalid request", "error", err)
+		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	level.Debug(l).Log("message", "getting project from database")
+	projectEntry, err := h.ddbClient.ReadProjectEntry(ctx, projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error retrieving project", "error", err)
+		if errors.Is(err, db.ErrProjectNotFound) {
+			h.errorResponse(w, "project does not exist", http.StatusNotFound)
+		} else {
+			h.errorResponse(w, "error retrieving project", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	if upr.TokenLimit != nil {
+		projectEntry.TokenLimit = *upr.TokenLimit
+	}
+	if upr.TokenTTL != nil {
+		projectEntry.TokenTTL = *upr.TokenTTL
+	}
+	if upr.WorkflowQuota != nil {
+		projectEntry.WorkflowQuota = *upr.WorkflowQuota
+	}
+
+	level.Debug(l).Log("message", "updating project in database")
+	if err := h.ddbClient.UpdateProjectEntry(ctx, projectEntry); err != nil {
+		level.Error(l).Log("message", "error updating project in database", "error", err)
+		h.errorResponse(w, "error updating project", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(h.getProjectResponse(projectEntry)); err != nil {
+		level.Error(l).Log("message", "error creating response", "error", err)
+		h.errorResponse(w, "error creating response object", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Delete a project
+func (h handler) deleteProject(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectName := vars["projectName"]
+
+	l := h.requestLogger(r, "op", "delete-project", "project", projectName)
+
+	level.Debug(l).Log("message", "validating authorization header for delete project")
+	ctx := r.Context()
+
+	cp := credentialsProviderFromContext(r.Context())
+
 	level.Debug(l).Log("message", "checking if project exists")
 	projectExists, err := cp.ProjectExists(projectName)
 	if err != nil {
@@ -711,8 +1029,14 @@ func (h handler) deleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The database entry is removed even when the project does not exist in
+	// the credentials provider, so a retried deletion converges.
 	if !projectExists {
-		level.Debug(l).Log("message", "no action required because project does not exist")
+		level.Debug(l).Log("message", "project does not exist, deleting from db")
+		if err = h.ddbClient.DeleteProjectEntry(ctx, projectName); err != nil {
+			level.Error(l).Log("message", "error deleting project in database", "error", err)
+			h.errorResponse(w, "error deleting project", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -730,40 +1054,216 @@ func (h handler) deleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	level.Debug(l).Log("message", "deleting project")
-	err = cp.DeleteProject(projectName)
+	// The entries are read up front so they can be restored when the project
+	// cannot be deleted from the credentials provider.
+	level.Debug(l).Log("message", "reading project from db")
+	projectEntry, err := h.ddbClient.ReadProjectEntry(ctx, projectName)
+	if err != nil && !errors.Is(err, db.ErrProjectNotFound) {
+		level.Error(l).Log("message", "error retrieving project from database", "error", err)
+		h.errorResponse(w, "error deleting project", http.StatusInternalServerError)
+		return
+	}
+	projectEntryExists := err == nil
+
+	tokenEntries, err := h.ddbClient.ListTokenEntries(ctx, projectName)
 	if err != nil {
-		level.Error(l).Log("message", "error deleting project", "error", err)
+		level.Error(l).Log("message", "error listing tokens from DB", "error", err)
 		h.errorResponse(w, "error deleting project", http.StatusInternalServerError)
 		return
 	}
 
-	level.Debug(h.logger).Log("message", "deleting from db")
-	if err = h.ddbClient.DeleteProjectEntry(ctx, projectName); err != nil {
-		level.Error(l).Log("message", "error deleting project in database", "error", err)
+	webhookEntries, err := h.ddbClient.ListWebhookEntries(ctx, projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error listing webhooks from DB", "error", err)
+		h.errorResponse(w, "error deleting project", http.StatusInternalServerError)
+		return
+	}
+
+	err = newSaga(l).
+		addStep("delete project entry", func(ctx context.Context) error {
+			return h.ddbClient.DeleteProjectEntry(ctx, projectName)
+		}, func(ctx context.Context) error {
+			if !projectEntryExists {
+				return nil
+			}
+			return h.restoreProjectEntries(ctx, projectEntry, tokenEntries, webhookEntries)
+		}).
+		addStep("delete project", func(ctx context.Context) error {
+			return cp.DeleteProject(projectName)
+		}, nil).
+		run(ctx)
+	if err != nil {
+		level.Error(l).Log("message", "error deleting project", "error", err)
 		h.errorResponse(w, "error deleting project", http.StatusInternalServerError)
 		return
 	}
 }
 
-// Creates a target
-func (h handler) createTarget(w http.ResponseWriter, r *http.Request) {
+// restoreProjectEntries recreates the database entries of a project, its
+// tokens and its webhooks.
+func (h handler) restoreProjectEntries(ctx context.Context, pe db.ProjectEntry, tokens []db.TokenEntry, webhooks []db.WebhookEntry) error {
+	if err := h.createProjectEntry(ctx, pe); err != nil {
+		return err
+	}
+
+	for _, te := range tokens {
+		err := h.ddbClient.CreateTokenEntry(ctx, types.Token{
+			CreatedAt:    te.CreatedAt,
+			ExpiresAt:    te.ExpiresAt,
+			ProjectID:    te.ProjectID,
+			ProjectToken: types.ProjectToken{ID: te.TokenID},
+			RoleID:       te.RoleID,
+			Scopes:       te.Scopes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, we := range webhooks {
+		if err := h.ddbClient.CreateWebhookEntry(ctx, we); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the policy template of a project
+func (h handler) getProjectPolicy(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	projectName := vars["projectName"]
 
-	l := h.requestLogger(r, "op", "create-target", "project", projectName)
+	l := h.requestLogger(r, "op", "get-project-policy", "project", projectName)
+
+	level.Debug(l).Log("message", "getting project from database")
+	projectEntry, err := h.ddbClient.ReadProjectEntry(r.Context(), projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error retrieving project", "error", err)
+		if errors.Is(err, db.ErrProjectNotFound) {
+			h.errorResponse(w, "project does not exist", http.StatusNotFound)
+		} else {
+			h.errorResponse(w, "error retrieving project", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// allow empty array to render json as []
+	if projectEntry.Policy.Targets == nil {
+		projectEntry.Policy.Targets = []string{}
+	}
+
+	if err := json.NewEncoder(w).Encode(projectEntry.Policy); err != nil {
+		level.Error(l).Log("message", "error creating response", "error", err)
+		h.errorResponse(w, "error creating response object", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Replace the policy template of a project and apply it with the
+// credentials provider
+func (h handler) updateProjectPolicy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectName := vars["projectName"]
+
+	l := h.requestLogger(r, "op", "update-project-policy", "project", projectName)
 
-	level.Debug(l).Log("message", "validating authorization header for create target")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
+	ctx := r.Context()
+
+	var policy types.ProjectPolicy
+	reqBody, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
 		return
 	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "unauthorized", http.StatusUnauthorized)
+	if err := json.Unmarshal(reqBody, &policy); err != nil {
+		level.Error(l).Log("message", "error decoding request", "error", err)
+		h.errorResponse(w, "error decoding request", http.StatusBadRequest)
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		level.Error(l).Log("message", "error invalid request", "error", err)
+		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err.Error()), http.StatusBadRequest)
 		return
 	}
+
+	level.Debug(l).Log("message", "getting project from database")
+	if _, err := h.ddbClient.ReadProjectEntry(ctx, projectName); err != nil {
+		level.Error(l).Log("message", "error retrieving project", "error", err)
+		if errors.Is(err, db.ErrProjectNotFound) {
+			h.errorResponse(w, "project does not exist", http.StatusNotFound)
+		} else {
+			h.errorResponse(w, "error retrieving project", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	cp := credentialsProviderFromContext(r.Context())
+
+	for _, targetName := range policy.Targets {
+		targetExists, err := cp.TargetExists(projectName, targetName)
+		if err != nil {
+			level.Error(l).Log("message", "error retrieving target", "error", err)
+			h.errorResponse(w, "error retrieving target", http.StatusInternalServerError)
+			return
+		}
+		if !targetExists {
+			level.Error(l).Log("message", "target not found", "target", targetName)
+			h.errorResponse(w, fmt.Sprintf("invalid request, target '%s' does not exist", targetName), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The policies of scoped tokens are generated from the project policy, and
+	// are updated with it.
+	tokenEntries, err := h.ddbClient.ListTokenEntries(ctx, projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error listing tokens", "error", err)
+		h.errorResponse(w, "error retrieving tokens", http.StatusInternalServerError)
+		return
+	}
+
+	scopes := []types.TokenScopes{}
+	for _, te := range tokenEntries {
+		if len(te.Scopes) > 0 {
+			scopes = append(scopes, te.Scopes)
+		}
+	}
+
+	// The provider is updated first so the stored template never describes a
+	// policy which is not in effect.
+	level.Debug(l).Log("message", "updating project policy")
+	if err := cp.UpdateProjectPolicy(projectName, policy, scopes); err != nil {
+		level.Error(l).Log("message", "error updating project policy", "error", err)
+		h.errorResponse(w, "error updating project policy", http.StatusInternalServerError)
+		return
+	}
+
+	level.Debug(l).Log("message", "updating project policy in db")
+	if err := h.ddbClient.UpdateProjectPolicy(ctx, projectName, policy); err != nil {
+		level.Error(l).Log("message", "error updating project in database", "error", err)
+		h.errorResponse(w, "error updating project policy", http.StatusInternalServerError)
+		return
+	}
+
+	if policy.Targets == nil {
+		policy.Targets = []string{}
+	}
+
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
+		level.Error(l).Log("message", "error creating response", "error", err)
+		h.errorResponse(w, "error creating response object", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Creates a target
+func (h handler) createTarget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectName := vars["projectName"]
+
+	l := h.requestLogger(r, "op", "create-target", "project", projectName)
+
 	level.Debug(l).Log("message", "reading request body")
 
 	var ctr requests.CreateTarget
@@ -787,13 +1287,7 @@ func (h handler) createTarget(w http.ResponseWriter, r *http.Request) {
 
 	l = log.With(l, "target", ctr.Name)
 
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 
 	projectExists, err := cp.ProjectExists(projectName)
 	if err != nil {
@@ -837,28 +1331,10 @@ func (h handler) deleteTarget(w http.ResponseWriter, r *http.Request) {
 
 	l := h.requestLogger(r, "op", "delete-target", "project", projectName, "target", targetName)
 
-	level.Debug(l).Log("message", "validating authorization header for delete target")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 
 	level.Debug(l).Log("message", "deleting target")
-	err = cp.DeleteTarget(projectName, targetName)
+	err := cp.DeleteTarget(projectName, targetName)
 	if err != nil {
 		level.Error(l).Log("message", "error deleting target", "error", err)
 		h.errorResponse(w, "error deleting target", http.StatusInternalServerError)
@@ -873,25 +1349,7 @@ func (h handler) listTargets(w http.ResponseWriter, r *http.Request) {
 
 	l := h.requestLogger(r, "op", "list-targets", "project", projectName)
 
-	level.Debug(l).Log("message", "validating authorization header for target list")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 
 	level.Debug(l).Log("message", "checking if project exists")
 	projectExists, err := cp.ProjectExists(projectName)
@@ -932,25 +1390,7 @@ func (h handler) updateTarget(w http.ResponseWriter, r *http.Request) {
 
 	l := h.requestLogger(r, "op", "update-target", "project", projectName, "target", targetName)
 
-	level.Debug(l).Log("message", "validating authorization header for update target")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 
 	projectExists, err := cp.ProjectExists(projectName)
 	if err != nil {
@@ -977,13 +1417,12 @@ func (h handler) updateTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target, err := cp.GetTarget(projectName, targetName)
+	existing, err := cp.GetTarget(projectName, targetName)
 	if err != nil {
 		level.Error(l).Log("message", "error retrieving existing target")
 		h.errorResponse(w, "error retrieving target", http.StatusInternalServerError)
 		return
 	}
-	targetType := target.Type
 
 	level.Debug(l).Log("message", "reading request body")
 	reqBody, err := io.ReadAll(r.Body)
@@ -993,7 +1432,25 @@ func (h handler) updateTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// merge request data into existing target struct for update data
+	// The properties provided are merged into the existing target. role_arn
+	// and role_arns cannot both be set, the one provided replaces the other.
+	var provided struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(reqBody, &provided); err != nil {
+		level.Error(l).Log("message", "error reading target properties data", "error", err)
+		h.errorResponse(w, "error reading target properties data", http.StatusInternalServerError)
+		return
+	}
+
+	target := existing
+	if _, ok := provided.Properties["role_arns"]; ok {
+		target.Properties.RoleArn = ""
+	}
+	if _, ok := provided.Properties["role_arn"]; ok {
+		target.Properties.RoleArns = nil
+	}
+
 	if err := json.Unmarshal(reqBody, &target); err != nil {
 		level.Error(l).Log("message", "error reading target properties data", "error", err)
 		h.errorResponse(w, "error reading target properties data", http.StatusInternalServerError)
@@ -1001,7 +1458,13 @@ func (h handler) updateTarget(w http.ResponseWriter, r *http.Request) {
 	}
 	// overwrite updated target with existing target name and type values so request body doesn't overwrite these values
 	target.Name = targetName
-	target.Type = targetType
+	target.Type = existing.Type
+
+	if target.Properties.CredentialType != existing.Properties.CredentialType {
+		level.Error(l).Log("message", "credential type cannot be updated")
+		h.errorResponse(w, "invalid request, credential_type cannot be updated", http.StatusBadRequest)
+		return
+	}
 
 	if err := target.Validate(); err != nil {
 		level.Error(l).Log("message", "error invalid request", "error", err)
@@ -1036,24 +1499,7 @@ func (h handler) deleteToken(w http.ResponseWriter, r *http.Request) {
 
 	level.Debug(l).Log("message", "validating authorization header for delete token")
 
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 
 	ctx := r.Context()
 
@@ -1116,33 +1562,22 @@ func (h handler) createToken(w http.ResponseWriter, r *http.Request) {
 
 	l := h.requestLogger(r, "op", "create-token", "project", projectName)
 
-	level.Debug(l).Log("message", "validating authorization header for token create")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
-	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
-		return
-	}
-
 	ctx := r.Context()
 
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
-	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
-		return
-	}
+	cp := credentialsProviderFromContext(r.Context())
 	projectExists, err := h.projectExists(ctx, l, cp, w, projectName)
 
 	if err != nil || !projectExists {
 		return
 	}
 
+	projectEntry, err := h.ddbClient.ReadProjectEntry(ctx, projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error retrieving project from database", "error", err)
+		h.errorResponse(w, "error retrieving project", http.StatusInternalServerError)
+		return
+	}
+
 	tokens, err := h.ddbClient.ListTokenEntries(ctx, projectName)
 	if err != nil {
 		level.Error(l).Log("message", "error listing tokens from DB", "error", err)
@@ -1150,33 +1585,77 @@ func (h handler) createToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(tokens) >= numOfTokensLimit {
+	if len(tokens) >= h.tokenLimit(projectEntry) {
 		level.Error(l).Log("message", "number of tokens allowed per project has been reached")
 		h.errorResponse(w, "token limit reached", http.StatusInternalServerError)
 		return
 	}
 
-	level.Debug(l).Log("message", "creating token")
-	token, err := cp.CreateToken(projectName)
+	level.Debug(l).Log("message", "reading request body")
+	reqBody, err := io.ReadAll(r.Body)
 	if err != nil {
-		level.Error(l).Log("message", "error creating token with credentials provider", "error", err)
-		h.errorResponse(w, "error creating token with credentials provider", http.StatusInternalServerError)
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	// The request body is optional, tokens are not scoped without one.
+	var ctr requests.CreateToken
+	if len(bytes.TrimSpace(reqBody)) > 0 {
+		if err := json.Unmarshal(reqBody, &ctr); err != nil {
+			level.Error(l).Log("message", "error decoding request", "error", err)
+			h.errorResponse(w, "error decoding request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := ctr.Validate(ctr.ValidateOperations(h.config.listAllTypes()), ctr.ValidateTTL(h.env.TokenMinTTL, h.env.TokenMaxTTL)); err != nil {
+		level.Error(l).Log("message", "error invalid request", "error", err)
+		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err), http.StatusBadRequest)
 		return
 	}
 
-	level.Debug(l).Log("message", "inserting into db")
-	err = h.ddbClient.CreateTokenEntry(ctx, token)
+	for _, targetName := range ctr.Scopes.Targets() {
+		targetExists, err := cp.TargetExists(projectName, targetName)
+		if err != nil {
+			level.Error(l).Log("message", "error retrieving target", "error", err)
+			h.errorResponse(w, "error retrieving target", http.StatusInternalServerError)
+			return
+		}
+		if !targetExists {
+			level.Error(l).Log("message", "target not found", "target", targetName)
+			h.errorResponse(w, fmt.Sprintf("invalid request, target '%s' does not exist", targetName), http.StatusBadRequest)
+			return
+		}
+
+		// Scoped tokens cannot read targets the project policy does not
+		// allow.
+		if !projectEntry.Policy.AllowsTarget(targetName) {
+			level.Error(l).Log("message", "target not allowed by project policy", "target", targetName)
+			h.errorResponse(w, fmt.Sprintf("invalid request, target '%s' is not allowed by the project policy", targetName), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var token types.Token
+	err = h.addCreateTokenSteps(newSaga(l), cp, projectName, ctr.Scopes, projectEntry.Policy, h.tokenTTL(ctr.TTL, projectEntry), &token).run(ctx)
 	if err != nil {
-		level.Error(l).Log("message", "error inserting token to db", "error", err)
+		level.Error(l).Log("message", "error creating token", "error", err)
+		var se *sagaError
+		if errors.As(err, &se) && se.Step == sagaStepCreateToken {
+			h.errorResponse(w, "error creating token with credentials provider", http.StatusInternalServerError)
+			return
+		}
 		h.errorResponse(w, "error creating token", http.StatusInternalServerError)
 		return
 	}
 
-	celloToken := newCelloToken("vault", token)
+	celloToken := newCelloToken(h.env.CredentialsProvider, token)
 
 	resp := responses.CreateToken{
 		CreatedAt: token.CreatedAt,
 		ExpiresAt: token.ExpiresAt,
+		Scopes:    token.Scopes,
 		Token:     celloToken.Token,
 		TokenID:   token.ProjectToken.ID,
 	}
@@ -1188,35 +1667,205 @@ func (h handler) createToken(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Lists tokens for a project
-func (h handler) listTokens(w http.ResponseWriter, r *http.Request) {
+// Rotates a token, creating a replacement with the same scopes and revoking
+// the token once the grace period ends
+func (h handler) rotateToken(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	projectName := vars["projectName"]
+	tokenID := vars["tokenID"]
 
-	l := h.requestLogger(r, "op", "list-tokens", "project", projectName)
+	l := h.requestLogger(r, "op", "rotate-token", "project", projectName, "tokenID", tokenID)
 
-	level.Debug(l).Log("message", "validating authorization header for token list")
-	ah := r.Header.Get("Authorization")
-	a, err := credentials.NewAuthorization(ah)
+	level.Debug(l).Log("message", "reading request body")
+	reqBody, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header format", http.StatusUnauthorized)
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
 		return
 	}
-	if err := a.Validate(a.ValidateAuthorizedAdmin(h.env.AdminSecret)); err != nil {
-		h.errorResponse(w, "error unauthorized, invalid authorization header", http.StatusUnauthorized)
+
+	// The request body is optional, tokens are revoked immediately without
+	// one.
+	var rtr requests.RotateToken
+	if len(bytes.TrimSpace(reqBody)) > 0 {
+		if err := json.Unmarshal(reqBody, &rtr); err != nil {
+			level.Error(l).Log("message", "error decoding request", "error", err)
+			h.errorResponse(w, "error decoding request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := rtr.Validate(rtr.ValidateGracePeriod(h.env.TokenMaxGracePeriod), rtr.ValidateTTL(h.env.TokenMinTTL, h.env.TokenMaxTTL)); err != nil {
+		level.Error(l).Log("message", "error invalid request", "error", err)
+		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Rotated tokens are revoked by the token sweeper once the grace period
+	// ends, so a grace period requires the sweeper.
+	gracePeriod := time.Duration(rtr.GracePeriod) * time.Second
+	if gracePeriod > 0 && h.env.TokenSweepInterval == 0 {
+		level.Error(l).Log("message", "error invalid request", "error", "grace period requires the token sweeper")
+		h.errorResponse(w, "invalid request, grace_period requires the token sweeper to be enabled", http.StatusBadRequest)
 		return
 	}
 
 	ctx := r.Context()
 
-	level.Debug(l).Log("message", "creating credential provider")
-	cp, err := h.newCredentialsProvider(*a, h.env, r.Header, credentials.NewVaultConfig, credentials.NewVaultSvc)
+	cp := credentialsProviderFromContext(r.Context())
+
+	projectExists, err := h.projectExists(ctx, l, cp, w, projectName)
+	if err != nil || !projectExists {
+		return
+	}
+
+	projectEntry, err := h.ddbClient.ReadProjectEntry(ctx, projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error retrieving project from database", "error", err)
+		h.errorResponse(w, "error retrieving project", http.StatusInternalServerError)
+		return
+	}
+
+	// The rotated token counts toward the limit until the grace period ends,
+	// tokens revoked immediately are replaced one for one.
+	if gracePeriod > 0 {
+		tokens, err := h.ddbClient.ListTokenEntries(ctx, projectName)
+		if err != nil {
+			level.Error(l).Log("message", "error listing tokens from DB", "error", err)
+			h.errorResponse(w, "error listing tokens", http.StatusInternalServerError)
+			return
+		}
+
+		if len(tokens) >= h.tokenLimit(projectEntry) {
+			level.Error(l).Log("message", "number of tokens allowed per project has been reached")
+			h.errorResponse(w, "token limit reached", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	tokenEntry, err := h.ddbClient.ReadTokenEntryByProject(ctx, projectName, tokenID)
 	if err != nil {
-		level.Error(l).Log("message", "error creating credentials provider", "error", err)
-		h.errorResponse(w, "error creating credentials provider", http.StatusInternalServerError)
+		level.Error(l).Log("message", "error retrieving token from DB", "error", err)
+		if errors.Is(err, db.ErrTokenNotFound) {
+			h.errorResponse(w, "token does not exist", http.StatusNotFound)
+		} else {
+			h.errorResponse(w, "error retrieving token", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The replacement is removed when the rotated token cannot be revoked or
+	// updated.
+	var token types.Token
+	s := h.addCreateTokenSteps(newSaga(l), cp, projectName, tokenEntry.Scopes, projectEntry.Policy, h.tokenTTL(rtr.TTL, projectEntry), &token)
+
+	// The rotated token's expiry is only returned when it remains valid for
+	// the grace period.
+	previousExpiresAt := ""
+
+	if gracePeriod == 0 {
+		s.addStep("revoke token", func(ctx context.Context) error {
+			return h.revokeToken(ctx, cp, projectName, tokenID)
+		}, nil)
+	} else {
+		// The token entry expires with the grace period and is revoked by
+		// the token sweeper once it has expired.
+		revokeAt := time.Now().Add(gracePeriod)
+		if expiresAt, err := time.Parse(time.RFC3339Nano, tokenEntry.ExpiresAt); err != nil || revokeAt.Before(expiresAt) {
+			tokenEntry.ExpiresAt = revokeAt.Format(time.RFC3339Nano)
+		}
+
+		s.addStep("update token entry", func(ctx context.Context) error {
+			return h.ddbClient.UpdateTokenEntry(ctx, tokenEntry)
+		}, nil)
+		previousExpiresAt = tokenEntry.ExpiresAt
+	}
+
+	if err := s.run(ctx); err != nil {
+		level.Error(l).Log("message", "error rotating token", "error", err)
+		var se *sagaError
+		errors.As(err, &se)
+		switch se.Step {
+		case sagaStepCreateToken:
+			h.errorResponse(w, "error creating token with credentials provider", http.StatusInternalServerError)
+		case sagaStepCreateTokenEntry:
+			h.errorResponse(w, "error creating token", http.StatusInternalServerError)
+		case "revoke token":
+			h.errorResponse(w, "error revoking token", http.StatusInternalServerError)
+		default:
+			h.errorResponse(w, "error updating token", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	celloToken := newCelloToken(h.env.CredentialsProvider, token)
+
+	resp := responses.RotateToken{
+		CreatedAt:              token.CreatedAt,
+		ExpiresAt:              token.ExpiresAt,
+		Scopes:                 token.Scopes,
+		Token:                  celloToken.Token,
+		TokenID:                token.ProjectToken.ID,
+		PreviousTokenExpiresAt: previousExpiresAt,
+		PreviousTokenID:        tokenID,
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		level.Error(l).Log("message", "error serializing project token", "error", err)
+		h.errorResponse(w, "error rotating project token", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Names of the steps creating a token.
+const (
+	sagaStepCreateToken      = "create token"
+	sagaStepCreateTokenEntry = "create token entry"
+)
+
+// addCreateTokenSteps adds the steps creating a token in the credentials
+// provider and the database to the saga. The token is set once created.
+func (h handler) addCreateTokenSteps(s *saga, cp credentials.Provider, projectName string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration, token *types.Token) *saga {
+	return s.
+		addStep(sagaStepCreateToken, func(ctx context.Context) error {
+			var err error
+			*token, err = cp.CreateToken(projectName, scopes, policy, ttl)
+			return err
+		}, func(ctx context.Context) error {
+			return cp.DeleteProjectToken(projectName, token.ProjectToken.ID)
+		}).
+		addStep(sagaStepCreateTokenEntry, func(ctx context.Context) error {
+			return h.ddbClient.CreateTokenEntry(ctx, *token)
+		}, func(ctx context.Context) error {
+			return h.ddbClient.DeleteTokenEntryByProject(ctx, projectName, token.ProjectToken.ID)
+		})
+}
+
+// revokeToken deletes the token from the credentials provider and database.
+// Tokens already deleted from the credentials provider are still deleted from
+// the database.
+func (h handler) revokeToken(ctx context.Context, cp credentials.Provider, projectName, tokenID string) error {
+	if _, err := cp.GetProjectToken(projectName, tokenID); err != nil {
+		if !errors.Is(err, credentials.ErrProjectTokenNotFound) {
+			return err
+		}
+	} else if err := cp.DeleteProjectToken(projectName, tokenID); err != nil {
+		return err
+	}
+	return h.ddbClient.DeleteTokenEntryByProject(ctx, projectName, tokenID)
+}
+
+// Lists tokens for a project
+func (h handler) listTokens(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectName := vars["projectName"]
+
+	l := h.requestLogger(r, "op", "list-tokens", "project", projectName)
+
+	ctx := r.Context()
+
+	cp := credentialsProviderFromContext(r.Context())
+
 	projectExists, err := h.projectExists(ctx, l, cp, w, projectName)
 
 	if err != nil || !projectExists {
@@ -1235,6 +1884,7 @@ func (h handler) listTokens(w http.ResponseWriter, r *http.Request) {
 		resp = append(resp, responses.ListTokens{
 			CreatedAt: tokenEntry.CreatedAt,
 			ExpiresAt: tokenEntry.ExpiresAt,
+			Scopes:    tokenEntry.Scopes,
 			TokenID:   tokenEntry.TokenID,
 		})
 	}
@@ -1267,6 +1917,28 @@ func generateEnvVariablesString(environmentVariables map[string]string) string {
 	return r
 }
 
+// argoContext returns the context of calls to Argo with the span of the
+// request in ctx, so that the calls join the request's trace. The calls are
+// cancelled with ctx, or when the returned function is called.
+func (h handler) argoContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	argoCtx, cancel := context.WithCancel(trace.ContextWithSpan(h.argoCtx, trace.SpanFromContext(ctx)))
+	stop := context.AfterFunc(ctx, cancel)
+	return argoCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// credentialsProvider returns the credentials provider of the authorization,
+// whose calls join the trace of the request in ctx.
+func (h handler) credentialsProvider(ctx context.Context, a credentials.Authorization, header http.Header) (credentials.Provider, error) {
+	cp, err := h.newCredentialsProvider(a, h.env, header, credentials.NewVaultConfig, credentials.NewVaultSvc)
+	if err != nil {
+		return nil, err
+	}
+	return tracing.InstrumentProvider(ctx, cp, a.Provider), nil
+}
+
 func (h handler) requestLogger(r *http.Request, fields ...interface{}) log.Logger {
 	return log.With(
 		h.logger,
@@ -13,11 +13,14 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cello-proj/cello/internal/types"
 	"github.com/cello-proj/cello/service/internal/credentials"
 	"github.com/cello-proj/cello/service/internal/db"
 	"github.com/cello-proj/cello/service/internal/env"
+	"github.com/cello-proj/cello/service/internal/health"
+	"github.com/cello-proj/cello/service/internal/metrics"
 	"github.com/cello-proj/cello/service/internal/workflow"
 	th "github.com/cello-proj/cello/service/test/testhelpers"
 
@@ -35,6 +38,13 @@ const (
 	workflowResponse = "wf-123456"
 )
 
+// testTarget is the target workflows are created for.
+var testTarget = types.Target{
+	Name:       "target1",
+	Type:       types.TargetTypeAWSAccount,
+	Properties: types.TargetProperties{CredentialType: types.AWSCredentialTypeAssumedRole},
+}
+
 type test struct {
 	name       string
 	req        interface{}
@@ -71,7 +81,7 @@ func TestCreateProject(t *testing.T) {
 			method:     "POST",
 			cpMock: &th.CredsProviderMock{
 				ProjectExistsFunc: func(s string) (bool, error) { return false, nil },
-				CreateProjectFunc: func(s string) (types.Token, error) {
+				CreateProjectFunc: func(s string, ttl time.Duration) (types.Token, error) {
 					return types.Token{
 						CreatedAt: "createdAt",
 						ExpiresAt: "expiresAt",
@@ -132,7 +142,7 @@ func TestCreateProject(t *testing.T) {
 			method:     "POST",
 			cpMock: &th.CredsProviderMock{
 				ProjectExistsFunc: func(s string) (bool, error) { return false, nil },
-				CreateProjectFunc: func(s string) (types.Token, error) {
+				CreateProjectFunc: func(s string, ttl time.Duration) (types.Token, error) {
 					return types.Token{
 						CreatedAt: "createdAt",
 						ExpiresAt: "expiresAt",
@@ -144,12 +154,83 @@ func TestCreateProject(t *testing.T) {
 						Secret: "secret",
 					}, nil
 				},
+				DeleteProjectFunc: func(s string) error { return nil },
 			},
 			ddbMock: &th.DBClientMock{
 				CreateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error { return nil },
 				CreateTokenEntryFunc: func(ctx context.Context, token types.Token) error {
 					return errors.New("failed to write token to database")
 				},
+				DeleteProjectEntryFunc: func(ctx context.Context, project string) error { return nil },
+			},
+		},
+		{
+			name:       "project fails to create in credentials provider",
+			req:        loadJSON(t, "TestCreateProject/can_create_project_request.json"),
+			want:       http.StatusInternalServerError,
+			respFile:   "TestCreateProject/project_fails_to_create_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return false, nil },
+				CreateProjectFunc: func(s string, ttl time.Duration) (types.Token, error) {
+					return types.Token{}, errors.New("vault error")
+				},
+			},
+			ddbMock: &th.DBClientMock{
+				CreateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error { return nil },
+				DeleteProjectEntryFunc: func(ctx context.Context, project string) error { return nil },
+			},
+		},
+		{
+			name:       "reuses project entry of a previous attempt",
+			req:        loadJSON(t, "TestCreateProject/can_create_project_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestCreateProject/can_create_project_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return false, nil },
+				CreateProjectFunc: func(s string, ttl time.Duration) (types.Token, error) {
+					return types.Token{
+						CreatedAt: "createdAt",
+						ExpiresAt: "expiresAt",
+						ProjectID: "project1",
+						ProjectToken: types.ProjectToken{
+							ID: "secret-id-accessor",
+						},
+						RoleID: "role-id",
+						Secret: "secret",
+					}, nil
+				},
+			},
+			ddbMock: &th.DBClientMock{
+				CreateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error { return db.ErrProjectExists },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "PROJECT", Repository: "git@github.com:myorg/myrepo.git"}, nil
+				},
+				UpdateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error { return nil },
+				CreateTokenEntryFunc:   func(ctx context.Context, token types.Token) error { return nil },
+			},
+		},
+		{
+			name:       "project entry exists with another repository",
+			req:        loadJSON(t, "TestCreateProject/can_create_project_request.json"),
+			want:       http.StatusInternalServerError,
+			respFile:   "TestCreateProject/project_fails_to_create_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return false, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				CreateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error { return db.ErrProjectExists },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "PROJECT", Repository: "git@github.com:myorg/other.git"}, nil
+				},
 			},
 		},
 	}
@@ -167,7 +248,7 @@ func TestCreateToken(t *testing.T) {
 			url:        "/projects/undeletableprojecttargets/tokens",
 			method:     "POST",
 			cpMock: &th.CredsProviderMock{
-				CreateTokenFunc: func(s string) (types.Token, error) {
+				CreateTokenFunc: func(s string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
 					return types.Token{
 						CreatedAt: "2022-06-21T14:56:10.341066-07:00",
 						ExpiresAt: "2023-06-21T14:56:10.341066-07:00",
@@ -196,6 +277,171 @@ func TestCreateToken(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:       "can create scoped token",
+			req:        loadJSON(t, "TestCreateToken/can_create_scoped_token_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestCreateToken/can_create_scoped_token_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				CreateTokenFunc: func(s string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					return types.Token{
+						CreatedAt: "2022-06-21T14:56:10.341066-07:00",
+						ExpiresAt: "2023-06-21T14:56:10.341066-07:00",
+						ProjectID: "project1",
+						ProjectToken: types.ProjectToken{
+							ID: "secret-id-accessor",
+						},
+						RoleID: "scoped-role-id",
+						Scopes: scopes,
+						Secret: "secret",
+					}, nil
+				},
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				CreateTokenEntryFunc: func(ctx context.Context, t types.Token) error {
+					if t.RoleID != "scoped-role-id" || len(t.Scopes) != 2 {
+						return fmt.Errorf("unexpected token %v", t)
+					}
+					return nil
+				},
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
+		{
+			name:       "fails to create scoped token with invalid operation",
+			req:        loadJSON(t, "TestCreateToken/fails_to_create_scoped_token_with_invalid_operation_request.json"),
+			want:       http.StatusBadRequest,
+			respFile:   "TestCreateToken/fails_to_create_scoped_token_with_invalid_operation_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
+		{
+			name:       "fails to create scoped token for target not allowed by project policy",
+			req:        loadJSON(t, "TestCreateToken/can_create_scoped_token_request.json"),
+			want:       http.StatusBadRequest,
+			respFile:   "TestCreateToken/fails_to_create_scoped_token_for_target_not_allowed_by_project_policy_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{
+						ProjectID:  "project1",
+						Repository: "repo",
+						Policy:     types.ProjectPolicy{Targets: []string{"prod"}},
+					}, nil
+				},
+			},
+		},
+		{
+			name:       "can create token with ttl",
+			req:        loadJSON(t, "TestCreateToken/can_create_token_with_ttl_request.json"),
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				CreateTokenFunc: func(s string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					if ttl != 24*time.Hour {
+						return types.Token{}, fmt.Errorf("unexpected ttl %s", ttl)
+					}
+					return types.Token{ProjectID: "project1", ProjectToken: types.ProjectToken{ID: "secret-id-accessor"}}, nil
+				},
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				CreateTokenEntryFunc: func(ctx context.Context, t types.Token) error { return nil },
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo", TokenTTL: 3600}, nil
+				},
+			},
+		},
+		{
+			name:       "can create token with project default ttl",
+			req:        loadJSON(t, "TestCreateToken/request.json"),
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				CreateTokenFunc: func(s string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					if ttl != time.Hour {
+						return types.Token{}, fmt.Errorf("unexpected ttl %s", ttl)
+					}
+					return types.Token{ProjectID: "project1", ProjectToken: types.ProjectToken{ID: "secret-id-accessor"}}, nil
+				},
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				CreateTokenEntryFunc: func(ctx context.Context, t types.Token) error { return nil },
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo", TokenTTL: 3600}, nil
+				},
+			},
+		},
+		{
+			name:       "fails to create token with ttl out of bounds",
+			req:        loadJSON(t, "TestCreateToken/fails_to_create_token_with_ttl_out_of_bounds_request.json"),
+			want:       http.StatusBadRequest,
+			respFile:   "TestCreateToken/fails_to_create_token_with_ttl_out_of_bounds_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
+		{
+			name:       "project tokens limit reached",
+			req:        loadJSON(t, "TestCreateToken/request.json"),
+			want:       http.StatusInternalServerError,
+			respFile:   "TestCreateToken/token_limit_reached_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{ProjectID: "project1", TokenID: "secret-id-accessor"}}, nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo", TokenLimit: 1}, nil
+				},
+			},
+		},
 		{
 			name:       "project does not exist",
 			req:        loadJSON(t, "TestCreateToken/request.json"),
@@ -225,7 +471,9 @@ func TestCreateToken(t *testing.T) {
 			url:        "/projects/tokendberror/tokens",
 			method:     "POST",
 			cpMock: &th.CredsProviderMock{
-				CreateTokenFunc:   func(s string) (types.Token, error) { return types.Token{}, errors.New("error") },
+				CreateTokenFunc: func(s string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					return types.Token{}, errors.New("error")
+				},
 				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
 			},
 			ddbMock: &th.DBClientMock{
@@ -242,6 +490,36 @@ func TestCreateToken(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:       "removes token from credentials provider when db entry fails to create",
+			req:        loadJSON(t, "TestCreateToken/request.json"),
+			want:       http.StatusInternalServerError,
+			respFile:   "TestCreateToken/token_entry_fails_to_create_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/tokendberror/tokens",
+			method:     "POST",
+			cpMock: &th.CredsProviderMock{
+				CreateTokenFunc: func(s string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					return types.Token{ProjectID: s, ProjectToken: types.ProjectToken{ID: "secret-id-accessor"}}, nil
+				},
+				DeleteProjectTokenFunc: func(s1, s2 string) error {
+					if s2 != "secret-id-accessor" {
+						return fmt.Errorf("unexpected token %s", s2)
+					}
+					return nil
+				},
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				CreateTokenEntryFunc: func(ctx context.Context, t types.Token) error { return errors.New("error") },
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{}, nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
 		{
 			name:       "allowed tokens limit reached",
 			req:        loadJSON(t, "TestCreateToken/request.json"),
@@ -418,6 +696,28 @@ func TestDeleteProject(t *testing.T) {
 				ListTargetsFunc:   func(s string) ([]string, error) { return []string{}, nil },
 				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
 			},
+			ddbMock: &th.DBClientMock{
+				DeleteProjectEntryFunc: func(ctx context.Context, project string) error { return nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
+				},
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{ProjectID: project, TokenID: "token1"}}, nil
+				},
+				ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+					return []db.WebhookEntry{{ProjectID: project, ID: "webhook1"}}, nil
+				},
+			},
+		},
+		{
+			name:       "deletes db entry when project does not exist",
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/projects/projectdoesnotexist",
+			method:     "DELETE",
+			cpMock: &th.CredsProviderMock{
+				ProjectExistsFunc: func(s string) (bool, error) { return false, nil },
+			},
 			ddbMock: &th.DBClientMock{
 				DeleteProjectEntryFunc: func(ctx context.Context, project string) error { return nil },
 			},
@@ -445,43 +745,120 @@ func TestDeleteProject(t *testing.T) {
 				ListTargetsFunc:   func(s string) ([]string, error) { return []string{}, nil },
 				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
 			},
-			ddbMock: &th.DBClientMock{},
+			// The db entries are restored when the project cannot be deleted.
+			ddbMock: &th.DBClientMock{
+				DeleteProjectEntryFunc: func(ctx context.Context, project string) error { return nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
+				},
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{ProjectID: project, TokenID: "token1"}}, nil
+				},
+				ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+					return []db.WebhookEntry{{ProjectID: project, ID: "webhook1"}}, nil
+				},
+				CreateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error {
+					if pe.ProjectID != "undeletableproject" || pe.Repository != "repo" {
+						return fmt.Errorf("unexpected project entry %v", pe)
+					}
+					return nil
+				},
+				CreateTokenEntryFunc: func(ctx context.Context, token types.Token) error {
+					if token.ProjectToken.ID != "token1" {
+						return fmt.Errorf("unexpected token %s", token.ProjectToken.ID)
+					}
+					return nil
+				},
+				CreateWebhookEntryFunc: func(ctx context.Context, we db.WebhookEntry) error {
+					if we.ID != "webhook1" {
+						return fmt.Errorf("unexpected webhook %s", we.ID)
+					}
+					return nil
+				},
+			},
 		},
 		{
-			name:       "fails to delete project db entry",
+			name:       "fails to read project db entry",
 			want:       http.StatusInternalServerError,
 			authHeader: adminAuthHeader,
-			url:        "/projects/somedeletedberror",
+			url:        "/projects/somereaddberror",
 			method:     "DELETE",
 			cpMock: &th.CredsProviderMock{
-				DeleteProjectFunc: func(s string) error { return nil },
 				ListTargetsFunc:   func(s string) ([]string, error) { return []string{}, nil },
 				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
 			},
 			ddbMock: &th.DBClientMock{
-				DeleteProjectEntryFunc: func(ctx context.Context, project string) error { return errors.New("error") },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{}, errors.New("error")
+				},
 			},
 		},
-	}
-	runTests(t, tests)
-}
-
-func TestGetProject(t *testing.T) {
-	tests := []test{
-		{
-			name:       "cannot get project, when not admin",
-			want:       http.StatusUnauthorized,
-			authHeader: userAuthHeader,
-			method:     "GET",
-			url:        "/projects/project1",
-		},
 		{
-			name:       "project exists, successful get project",
-			want:       http.StatusOK,
+			name:       "fails to list webhook db entries",
+			want:       http.StatusInternalServerError,
 			authHeader: adminAuthHeader,
-			method:     "GET",
-			url:        "/projects/project1",
-			ddbMock: &th.DBClientMock{
+			url:        "/projects/somelistdberror",
+			method:     "DELETE",
+			cpMock: &th.CredsProviderMock{
+				ListTargetsFunc:   func(s string) ([]string, error) { return []string{}, nil },
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
+				},
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{ProjectID: project, TokenID: "token1"}}, nil
+				},
+				ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+					return nil, errors.New("error")
+				},
+			},
+		},
+		{
+			name:       "fails to delete project db entry",
+			want:       http.StatusInternalServerError,
+			authHeader: adminAuthHeader,
+			url:        "/projects/somedeletedberror",
+			method:     "DELETE",
+			cpMock: &th.CredsProviderMock{
+				ListTargetsFunc:   func(s string) ([]string, error) { return []string{}, nil },
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				DeleteProjectEntryFunc: func(ctx context.Context, project string) error { return errors.New("error") },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
+				},
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{ProjectID: project, TokenID: "token1"}}, nil
+				},
+				ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+					return []db.WebhookEntry{{ProjectID: project, ID: "webhook1"}}, nil
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestGetProject(t *testing.T) {
+	tests := []test{
+		{
+			name:       "cannot get project, when not admin",
+			want:       http.StatusUnauthorized,
+			authHeader: userAuthHeader,
+			method:     "GET",
+			url:        "/projects/project1",
+		},
+		{
+			name:       "project exists, successful get project",
+			want:       http.StatusOK,
+			respFile:   "TestGetProject/project_exists_successful_get_project_response.json",
+			authHeader: adminAuthHeader,
+			method:     "GET",
+			url:        "/projects/project1",
+			ddbMock: &th.DBClientMock{
 				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
 					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
 				},
@@ -503,6 +880,290 @@ func TestGetProject(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestUpdateProject(t *testing.T) {
+	tests := []test{
+		{
+			name:       "can update project",
+			req:        loadJSON(t, "TestUpdateProject/can_update_project_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestUpdateProject/can_update_project_response.json",
+			authHeader: adminAuthHeader,
+			method:     "PATCH",
+			url:        "/projects/project1",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+				UpdateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error {
+					if pe.Repository != "repo" || pe.TokenLimit != 5 || pe.TokenTTL != 86400 {
+						return fmt.Errorf("unexpected project entry %v", pe)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:       "can update project workflow quota",
+			req:        loadJSON(t, "TestUpdateProject/can_update_project_workflow_quota_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestUpdateProject/can_update_project_workflow_quota_response.json",
+			authHeader: adminAuthHeader,
+			method:     "PATCH",
+			url:        "/projects/project1",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo", TokenLimit: 5}, nil
+				},
+				UpdateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error {
+					if pe.TokenLimit != 5 || pe.WorkflowQuota != 3 {
+						return fmt.Errorf("unexpected project entry %v", pe)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:       "fails to update project when not admin",
+			req:        loadJSON(t, "TestUpdateProject/can_update_project_request.json"),
+			want:       http.StatusUnauthorized,
+			authHeader: userAuthHeader,
+			method:     "PATCH",
+			url:        "/projects/project1",
+		},
+		{
+			name:       "fails to update project with ttl out of bounds",
+			req:        loadJSON(t, "TestUpdateProject/fails_to_update_project_with_ttl_out_of_bounds_request.json"),
+			want:       http.StatusBadRequest,
+			respFile:   "TestUpdateProject/fails_to_update_project_with_ttl_out_of_bounds_response.json",
+			authHeader: adminAuthHeader,
+			method:     "PATCH",
+			url:        "/projects/project1",
+		},
+		{
+			name:       "project does not exist",
+			req:        loadJSON(t, "TestUpdateProject/can_update_project_request.json"),
+			want:       http.StatusNotFound,
+			respFile:   "TestUpdateProject/project_does_not_exist_response.json",
+			authHeader: adminAuthHeader,
+			method:     "PATCH",
+			url:        "/projects/project1",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{}, db.ErrProjectNotFound
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestGetProjectPolicy(t *testing.T) {
+	tests := []test{
+		{
+			name:       "cannot get project policy, when not admin",
+			want:       http.StatusUnauthorized,
+			authHeader: userAuthHeader,
+			method:     "GET",
+			url:        "/projects/project1/policy",
+		},
+		{
+			name:       "can get project policy",
+			want:       http.StatusOK,
+			respFile:   "TestGetProjectPolicy/can_get_project_policy_response.json",
+			authHeader: adminAuthHeader,
+			method:     "GET",
+			url:        "/projects/project1/policy",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{
+						ProjectID:  "project1",
+						Repository: "repo",
+						Policy: types.ProjectPolicy{
+							Targets:     []string{"target1"},
+							ExtraPolicy: `path "secret/data/project1/*" { capabilities = ["read"] }`,
+						},
+					}, nil
+				},
+			},
+		},
+		{
+			name:       "can get default project policy",
+			want:       http.StatusOK,
+			respFile:   "TestGetProjectPolicy/can_get_default_project_policy_response.json",
+			authHeader: adminAuthHeader,
+			method:     "GET",
+			url:        "/projects/project1/policy",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
+		{
+			name:       "project does not exist",
+			want:       http.StatusNotFound,
+			authHeader: adminAuthHeader,
+			method:     "GET",
+			url:        "/projects/projectdoesnotexist/policy",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{}, db.ErrProjectNotFound
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestUpdateProjectPolicy(t *testing.T) {
+	tests := []test{
+		{
+			name:       "can update project policy",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/can_update_project_policy_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestUpdateProjectPolicy/can_update_project_policy_response.json",
+			authHeader: adminAuthHeader,
+			method:     "PUT",
+			url:        "/projects/project1/policy",
+			cpMock: &th.CredsProviderMock{
+				TargetExistsFunc: func(s1, s2 string) (bool, error) { return true, nil },
+				UpdateProjectPolicyFunc: func(s string, policy types.ProjectPolicy, scopes []types.TokenScopes) error {
+					// The policies of scoped tokens are updated.
+					if len(scopes) != 1 || scopes[0][0].Target != "target1" {
+						return fmt.Errorf("unexpected scopes %v", scopes)
+					}
+					return nil
+				},
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{
+						{ProjectID: project, TokenID: "token1"},
+						{ProjectID: project, RoleID: "scoped-role-id", TokenID: "token2", Scopes: types.TokenScopes{{Target: "target1", Operations: []string{"diff"}}}},
+					}, nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+				UpdateProjectPolicyFunc: func(ctx context.Context, project string, policy types.ProjectPolicy) error {
+					if project != "project1" || len(policy.Targets) != 1 {
+						return fmt.Errorf("unexpected project policy %s %v", project, policy)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:       "fails to update project policy when not admin",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/can_update_project_policy_request.json"),
+			want:       http.StatusUnauthorized,
+			authHeader: userAuthHeader,
+			method:     "PUT",
+			url:        "/projects/project1/policy",
+		},
+		{
+			name:       "fails to update project policy with invalid hcl",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/fails_to_update_project_policy_with_invalid_hcl_request.json"),
+			want:       http.StatusBadRequest,
+			respFile:   "TestUpdateProjectPolicy/fails_to_update_project_policy_with_invalid_hcl_response.json",
+			authHeader: adminAuthHeader,
+			method:     "PUT",
+			url:        "/projects/project1/policy",
+		},
+		{
+			name:       "fails to update project policy when target does not exist",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/can_update_project_policy_request.json"),
+			want:       http.StatusBadRequest,
+			respFile:   "TestUpdateProjectPolicy/fails_to_update_project_policy_when_target_does_not_exist_response.json",
+			authHeader: adminAuthHeader,
+			method:     "PUT",
+			url:        "/projects/project1/policy",
+			cpMock: &th.CredsProviderMock{
+				TargetExistsFunc: func(s1, s2 string) (bool, error) { return false, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
+		{
+			name:       "fails to update project policy when project does not exist",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/can_update_project_policy_request.json"),
+			want:       http.StatusNotFound,
+			authHeader: adminAuthHeader,
+			method:     "PUT",
+			url:        "/projects/projectdoesnotexist/policy",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{}, db.ErrProjectNotFound
+				},
+			},
+		},
+		{
+			name:       "fails to update project policy when provider fails",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/can_update_project_policy_request.json"),
+			want:       http.StatusInternalServerError,
+			authHeader: adminAuthHeader,
+			method:     "PUT",
+			url:        "/projects/project1/policy",
+			cpMock: &th.CredsProviderMock{
+				TargetExistsFunc: func(s1, s2 string) (bool, error) { return true, nil },
+				UpdateProjectPolicyFunc: func(s string, policy types.ProjectPolicy, scopes []types.TokenScopes) error {
+					return errors.New("error")
+				},
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) { return nil, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
+		{
+			name:       "fails to update project policy when db fails",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/can_update_project_policy_request.json"),
+			want:       http.StatusInternalServerError,
+			authHeader: adminAuthHeader,
+			method:     "PUT",
+			url:        "/projects/project1/policy",
+			cpMock: &th.CredsProviderMock{
+				TargetExistsFunc:        func(s1, s2 string) (bool, error) { return true, nil },
+				UpdateProjectPolicyFunc: func(s string, policy types.ProjectPolicy, scopes []types.TokenScopes) error { return nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) { return nil, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+				UpdateProjectPolicyFunc: func(ctx context.Context, project string, policy types.ProjectPolicy) error {
+					return errors.New("error")
+				},
+			},
+		},
+		{
+			name:       "fails to update project policy when tokens cannot be listed",
+			req:        loadJSON(t, "TestUpdateProjectPolicy/can_update_project_policy_request.json"),
+			want:       http.StatusInternalServerError,
+			authHeader: adminAuthHeader,
+			method:     "PUT",
+			url:        "/projects/project1/policy",
+			cpMock: &th.CredsProviderMock{
+				TargetExistsFunc: func(s1, s2 string) (bool, error) { return true, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return nil, errors.New("error")
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
 func TestCreateTarget(t *testing.T) {
 	tests := []test{
 		{
@@ -643,6 +1304,38 @@ func TestUpdateTarget(t *testing.T) {
 				UpdateTargetFunc:  func(s string, target types.Target) error { return nil },
 			},
 		},
+		{
+			name:       "role_arns replace role_arn",
+			req:        loadJSON(t, "TestUpdateTarget/role_arns_replace_role_arn_request.json"),
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/projects/projectalreadyexists/targets/TARGET_EXISTS",
+			method:     "PATCH",
+			cpMock: &th.CredsProviderMock{
+				GetTargetFunc: func(s1, s2 string) (types.Target, error) {
+					return types.Target{
+						Name: "TARGET_EXISTS",
+						Properties: types.TargetProperties{
+							CredentialType: "assumed_role",
+							PolicyArns:     []string{"arn:aws:iam::012345678901:policy/test-policy"},
+							PolicyDocument: "policyDoc",
+							RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+						},
+						Type: "aws_account",
+					}, nil
+				},
+				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+				UpdateTargetFunc: func(s string, target types.Target) error {
+					// Properties which are not provided are kept.
+					p := target.Properties
+					if p.CredentialType != "assumed_role" || p.RoleArn != "" || p.PolicyDocument != "policyDoc" || len(p.RoleArns) != 2 {
+						return fmt.Errorf("unexpected target properties %+v", p)
+					}
+					return nil
+				},
+			},
+		},
 		{
 			name:       "fails to update target when not admin",
 			req:        loadJSON(t, "TestUpdateTarget/fails_to_update_target_when_not_admin_request.json"),
@@ -752,12 +1445,23 @@ func TestCreateWorkflow(t *testing.T) {
 			method:     "POST",
 			url:        "/workflows",
 			cpMock: &th.CredsProviderMock{
-				GetTokenFunc:      func() (string, error) { return testPassword, nil },
-				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
-				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project}, nil
+				},
 			},
 			wfMock: &th.WorkflowMock{
 				SubmitFunc: func(ctx context.Context, from string, parameters, labels map[string]string) (string, error) {
+					// setup.sh reads the credentials of the target type.
+					if parameters["target_type"] != types.TargetTypeAWSAccount || parameters["credential_type"] != types.AWSCredentialTypeAssumedRole {
+						return "", fmt.Errorf("unexpected target parameters %v", parameters)
+					}
 					return workflowResponse, nil
 				},
 			},
@@ -771,6 +1475,11 @@ func TestCreateWorkflow(t *testing.T) {
 			respFile:   "TestCreateWorkflow/framework_must_be_valid_response.json",
 			method:     "POST",
 			url:        "/workflows",
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{RoleID: "user", TokenID: "token1"}}, nil
+				},
+			},
 		},
 		// We test this specific validation as it's server side only.
 		{
@@ -781,11 +1490,16 @@ func TestCreateWorkflow(t *testing.T) {
 			want:       http.StatusBadRequest,
 			method:     "POST",
 			url:        "/workflows",
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{RoleID: "user", TokenID: "token1"}}, nil
+				},
+			},
 		},
 		{
 			name:       "project must exist",
 			req:        loadJSON(t, "TestCreateWorkflow/project_must_exist.json"),
-			authHeader: userAuthHeader,
+			authHeader: adminAuthHeader,
 			want:       http.StatusBadRequest,
 			method:     "POST",
 			url:        "/workflows",
@@ -793,6 +1507,9 @@ func TestCreateWorkflow(t *testing.T) {
 				GetTokenFunc:      func() (string, error) { return testPassword, nil },
 				ProjectExistsFunc: func(s string) (bool, error) { return false, nil },
 			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+			},
 		},
 		{
 			name:       "target must exist",
@@ -802,9 +1519,37 @@ func TestCreateWorkflow(t *testing.T) {
 			method:     "POST",
 			url:        "/workflows",
 			cpMock: &th.CredsProviderMock{
-				GetTokenFunc:      func() (string, error) { return testPassword, nil },
-				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
-				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return false, nil },
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return types.Target{}, credentials.ErrTargetNotFound },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+			},
+		},
+		{
+			name:       "project must be under its workflow quota",
+			req:        loadJSON(t, "TestCreateWorkflow/can_create_workflow_request.json"),
+			want:       http.StatusTooManyRequests,
+			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflow/project_must_be_under_its_workflow_quota_response.json",
+			method:     "POST",
+			url:        "/workflows",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, WorkflowQuota: 2}, nil
+				},
+			},
+			wfMock: &th.WorkflowMock{
+				CountActiveFunc: func(ctx context.Context, namePrefix string) (int, error) { return 2, nil },
 			},
 		},
 		{
@@ -815,7 +1560,101 @@ func TestCreateWorkflow(t *testing.T) {
 			method:     "POST",
 			url:        "/workflows",
 		},
-		// TODO with admin credentials should fail
+		{
+			name:       "scoped token cannot run operations outside its scopes",
+			req:        loadJSON(t, "TestCreateWorkflow/can_create_workflow_request.json"),
+			want:       http.StatusForbidden,
+			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflow/scoped_token_cannot_run_operations_outside_its_scopes_response.json",
+			method:     "POST",
+			url:        "/workflows",
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{
+						{RoleID: "role-id", TokenID: "token1"},
+						{RoleID: "user", TokenID: "token2", Scopes: types.TokenScopes{{Target: "TARGET_EXISTS", Operations: []string{"diff"}}}},
+					}, nil
+				},
+			},
+		},
+		{
+			name:       "scoped token can run operations in its scopes",
+			req:        loadJSON(t, "TestCreateWorkflow/scoped_token_can_run_operations_in_its_scopes_request.json"),
+			want:       http.StatusOK,
+			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflow/can_create_workflow_response.json",
+			method:     "POST",
+			url:        "/workflows",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{
+						{RoleID: "role-id", TokenID: "token1"},
+						{RoleID: "user", TokenID: "token2", Scopes: types.TokenScopes{{Target: "TARGET_EXISTS", Operations: []string{"diff"}}}},
+					}, nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project}, nil
+				},
+			},
+			wfMock: &th.WorkflowMock{
+				SubmitFunc: func(ctx context.Context, from string, parameters, labels map[string]string) (string, error) {
+					return workflowResponse, nil
+				},
+			},
+		},
+		{
+			name:       "project token cannot create workflows in another project",
+			req:        loadJSON(t, "TestCreateWorkflow/can_create_workflow_request.json"),
+			want:       http.StatusForbidden,
+			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflow/project_token_cannot_create_workflows_in_another_project_response.json",
+			method:     "POST",
+			url:        "/workflows",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return credentials.ErrNotFound },
+			},
+		},
+		{
+			name:       "admin creates workflows with a temporary project token",
+			req:        loadJSON(t, "TestCreateWorkflow/can_create_workflow_request.json"),
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			respFile:   "TestCreateWorkflow/can_create_workflow_response.json",
+			method:     "POST",
+			url:        "/workflows",
+			cpMock: &th.CredsProviderMock{
+				CreateTokenFunc: func(project string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					if ttl != workflowTokenTTL {
+						return types.Token{}, errors.New("unexpected ttl")
+					}
+					return types.Token{RoleID: "role-id", Secret: "secret", ProjectToken: types.ProjectToken{ID: "token1"}}, nil
+				},
+				DeleteProjectTokenFunc: func(project, tokenID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
+				},
+			},
+			wfMock: &th.WorkflowMock{
+				SubmitFunc: func(ctx context.Context, from string, parameters, labels map[string]string) (string, error) {
+					if parameters["credentials_token"] != testPassword {
+						return "", errors.New("unexpected credentials token")
+					}
+					return workflowResponse, nil
+				},
+			},
+		},
 	}
 	runTests(t, tests)
 }
@@ -831,11 +1670,113 @@ func TestCreateWorkflowFromGit(t *testing.T) {
 			method:     "POST",
 			url:        "/projects/project1/targets/target1/operations",
 			cpMock: &th.CredsProviderMock{
-				GetTokenFunc:      func() (string, error) { return testPassword, nil },
-				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
-				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{
+						ProjectID:  "project1",
+						Repository: "repo",
+					}, nil
+				},
+			},
+			gitMock: &th.GitClientMock{
+				GetManifestFileFunc: func(repository, commitHash, path string) ([]byte, error) {
+					return loadFileBytes("TestCreateWorkflowFromGit/can_create_workflow_manifest.json")
+				},
+			},
+			wfMock: &th.WorkflowMock{
+				SubmitFunc: func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
+					return workflowResponse, nil
+				},
+			},
+		},
+		{
+			name:       "workflows environment variables",
+			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
+			want:       http.StatusOK,
+			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflowFromGit/good_response.json",
+			method:     "POST",
+			url:        "/projects/project1/targets/target1/operations",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{
+						ProjectID:  "project1",
+						Repository: "repo",
+					}, nil
+				},
+			},
+			gitMock: &th.GitClientMock{
+				GetManifestFileFunc: func(repository, commitHash, path string) ([]byte, error) {
+					return loadFileBytes("TestCreateWorkflowFromGit/create_workflow_env_variables_manifest.json")
+				},
+			},
+			wfMock: &th.WorkflowMock{
+				SubmitFunc: func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
+					if !strings.Contains(parameters["environment_variables_string"], "variable_with_single_quote='someones value'") {
+						return "", errors.New("failed to quote string with single quote in it")
+					}
+					if !strings.Contains(parameters["environment_variables_string"], "single_quoted_variable='single_quoted_variable'") {
+						return "", errors.New("failed to quote string with single quotes quited it")
+					}
+					if !strings.Contains(parameters["environment_variables_string"], "double_quoted_variable='double_quoted_variable'") {
+						return "", errors.New("failed to quote string with double quotes quited it")
+					}
+					if !strings.Contains(parameters["environment_variables_string"], "user='first_name last_name'") {
+						return "", errors.New("failed to quote string with empty space it")
+					}
+					if !strings.Contains(parameters["environment_variables_string"], "foobar='barfoo'") {
+						return "", errors.New("failed to quote string")
+					}
+					if !strings.Contains(parameters["environment_variables_string"], "variable_with_double_quote='I love book Harry Potter'") {
+						return "", errors.New("failed to quote string with double quote in it")
+					}
+					return workflowResponse, nil
+				},
+			},
+		},
+		{
+			name:       "bad request",
+			req:        loadJSON(t, "TestCreateWorkflowFromGit/bad_request.json"),
+			want:       http.StatusBadRequest,
+			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflowFromGit/bad_response.json",
+			method:     "POST",
+			url:        "/projects/project1/targets/target1/operations",
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{RoleID: "user", TokenID: "token1"}}, nil
+				},
+			},
+		},
+		{
+			name:       "ddb error but continues",
+			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
+			want:       http.StatusOK,
+			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflowFromGit/good_response.json",
+			method:     "POST",
+			url:        "/projects/project1/targets/target1/operations",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
 			},
 			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
 				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
 					return db.ProjectEntry{
 						ProjectID:  "project1",
@@ -845,7 +1786,7 @@ func TestCreateWorkflowFromGit(t *testing.T) {
 			},
 			gitMock: &th.GitClientMock{
 				GetManifestFileFunc: func(repository, commitHash, path string) ([]byte, error) {
-					return loadFileBytes("TestCreateWorkflow/can_create_workflow_request.json")
+					return loadFileBytes("TestCreateWorkflowFromGit/can_create_workflow_manifest.json")
 				},
 			},
 			wfMock: &th.WorkflowMock{
@@ -855,7 +1796,7 @@ func TestCreateWorkflowFromGit(t *testing.T) {
 			},
 		},
 		{
-			name:       "workflows environment variables",
+			name:       "ddb project not found but continues",
 			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
 			want:       http.StatusOK,
 			authHeader: userAuthHeader,
@@ -863,11 +1804,13 @@ func TestCreateWorkflowFromGit(t *testing.T) {
 			method:     "POST",
 			url:        "/projects/project1/targets/target1/operations",
 			cpMock: &th.CredsProviderMock{
-				GetTokenFunc:      func() (string, error) { return testPassword, nil },
-				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
-				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
 			},
 			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
 				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
 					return db.ProjectEntry{
 						ProjectID:  "project1",
@@ -877,93 +1820,103 @@ func TestCreateWorkflowFromGit(t *testing.T) {
 			},
 			gitMock: &th.GitClientMock{
 				GetManifestFileFunc: func(repository, commitHash, path string) ([]byte, error) {
-					return loadFileBytes("TestCreateWorkflow/create_workflow_env_variables.json")
+					return loadFileBytes("TestCreateWorkflowFromGit/can_create_workflow_manifest.json")
 				},
 			},
 			wfMock: &th.WorkflowMock{
 				SubmitFunc: func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
-					if !strings.Contains(parameters["environment_variables_string"], "variable_with_single_quote='someones value'") {
-						return "", errors.New("failed to quote string with single quote in it")
-					}
-					if !strings.Contains(parameters["environment_variables_string"], "single_quoted_variable='single_quoted_variable'") {
-						return "", errors.New("failed to quote string with single quotes quited it")
-					}
-					if !strings.Contains(parameters["environment_variables_string"], "double_quoted_variable='double_quoted_variable'") {
-						return "", errors.New("failed to quote string with double quotes quited it")
-					}
-					if !strings.Contains(parameters["environment_variables_string"], "user='first_name last_name'") {
-						return "", errors.New("failed to quote string with empty space it")
-					}
-					if !strings.Contains(parameters["environment_variables_string"], "foobar='barfoo'") {
-						return "", errors.New("failed to quote string")
-					}
-					if !strings.Contains(parameters["environment_variables_string"], "variable_with_double_quote='I love book Harry Potter'") {
-						return "", errors.New("failed to quote string with double quote in it")
-					}
 					return workflowResponse, nil
 				},
 			},
 		},
 		{
-			name:       "bad request",
-			req:        loadJSON(t, "TestCreateWorkflowFromGit/bad_request.json"),
-			want:       http.StatusBadRequest,
+			name:       "scoped token cannot run operations on other targets",
+			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
+			want:       http.StatusForbidden,
 			authHeader: userAuthHeader,
-			respFile:   "TestCreateWorkflowFromGit/bad_response.json",
+			respFile:   "TestCreateWorkflowFromGit/scoped_token_cannot_run_operations_on_other_targets_response.json",
 			method:     "POST",
 			url:        "/projects/project1/targets/target1/operations",
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{
+						{RoleID: "role-id", TokenID: "token1"},
+						{RoleID: "user", TokenID: "token2", Scopes: types.TokenScopes{{Target: "TARGET_EXISTS", Operations: []string{"diff"}}}},
+					}, nil
+				},
+			},
 		},
 		{
-			name:       "ddb error but continues",
+			name:       "project token cannot run operations in another project",
 			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
-			want:       http.StatusOK,
+			want:       http.StatusForbidden,
 			authHeader: userAuthHeader,
+			respFile:   "TestCreateWorkflow/project_token_cannot_create_workflows_in_another_project_response.json",
+			method:     "POST",
+			url:        "/projects/project2/targets/target1/operations",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return credentials.ErrNotFound },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+			},
+		},
+		{
+			name:       "admin creates workflows with a temporary project token",
+			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
 			respFile:   "TestCreateWorkflowFromGit/good_response.json",
 			method:     "POST",
 			url:        "/projects/project1/targets/target1/operations",
 			cpMock: &th.CredsProviderMock{
-				GetTokenFunc:      func() (string, error) { return testPassword, nil },
-				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
-				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+				CreateTokenFunc: func(project string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					if ttl != workflowTokenTTL {
+						return types.Token{}, errors.New("unexpected ttl")
+					}
+					return types.Token{RoleID: "role-id", Secret: "secret", ProjectToken: types.ProjectToken{ID: "token1"}}, nil
+				},
+				DeleteProjectTokenFunc: func(project, tokenID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+				ProjectExistsFunc:      func(s string) (bool, error) { return true, nil },
+				GetTargetFunc:          func(s1, s2 string) (types.Target, error) { return testTarget, nil },
 			},
 			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
 				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
-					return db.ProjectEntry{
-						ProjectID:  "project1",
-						Repository: "repo",
-					}, nil
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
 				},
 			},
 			gitMock: &th.GitClientMock{
 				GetManifestFileFunc: func(repository, commitHash, path string) ([]byte, error) {
-					return loadFileBytes("TestCreateWorkflow/can_create_workflow_request.json")
+					return loadFileBytes("TestCreateWorkflowFromGit/can_create_workflow_manifest.json")
 				},
 			},
 			wfMock: &th.WorkflowMock{
-				SubmitFunc: func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
+				SubmitFunc: func(ctx context.Context, from string, parameters, labels map[string]string) (string, error) {
+					if parameters["credentials_token"] != testPassword {
+						return "", errors.New("unexpected credentials token")
+					}
 					return workflowResponse, nil
 				},
 			},
 		},
 		{
-			name:       "ddb project not found but continues",
+			name:       "manifest cannot run workflows for another project",
 			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
-			want:       http.StatusOK,
+			want:       http.StatusForbidden,
 			authHeader: userAuthHeader,
-			respFile:   "TestCreateWorkflowFromGit/good_response.json",
 			method:     "POST",
 			url:        "/projects/project1/targets/target1/operations",
 			cpMock: &th.CredsProviderMock{
-				GetTokenFunc:      func() (string, error) { return testPassword, nil },
-				ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
-				TargetExistsFunc:  func(s1, s2 string) (bool, error) { return true, nil },
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
 			},
 			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
 				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
-					return db.ProjectEntry{
-						ProjectID:  "project1",
-						Repository: "repo",
-					}, nil
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
 				},
 			},
 			gitMock: &th.GitClientMock{
@@ -971,13 +1924,30 @@ func TestCreateWorkflowFromGit(t *testing.T) {
 					return loadFileBytes("TestCreateWorkflow/can_create_workflow_request.json")
 				},
 			},
-			wfMock: &th.WorkflowMock{
-				SubmitFunc: func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
-					return workflowResponse, nil
+		},
+		{
+			name:       "manifest cannot run workflows for another target",
+			req:        loadJSON(t, "TestCreateWorkflowFromGit/good_request.json"),
+			want:       http.StatusForbidden,
+			authHeader: userAuthHeader,
+			method:     "POST",
+			url:        "/projects/project1/targets/target2/operations",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return testPassword, nil },
+			},
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) { return []db.TokenEntry{}, nil },
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, Repository: "repo"}, nil
+				},
+			},
+			gitMock: &th.GitClientMock{
+				GetManifestFileFunc: func(repository, commitHash, path string) ([]byte, error) {
+					return loadFileBytes("TestCreateWorkflowFromGit/can_create_workflow_manifest.json")
 				},
 			},
 		},
-		// TODO with admin credentials should fail
 	}
 	runTests(t, tests)
 }
@@ -1020,6 +1990,16 @@ func TestGetWorkflow(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:       "project token cannot get workflows of another project",
+			want:       http.StatusForbidden,
+			authHeader: userAuthHeader,
+			method:     "GET",
+			url:        "/workflows/project2-target1-abcde",
+			cpMock: &th.CredsProviderMock{
+				VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return credentials.ErrNotFound },
+			},
+		},
 	}
 	runTests(t, tests)
 }
@@ -1062,6 +2042,11 @@ func TestListWorkflows(t *testing.T) {
 			authHeader: userAuthHeader,
 			method:     "GET",
 			url:        "/projects/projects1/targets/target1/workflows",
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{RoleID: "user", TokenID: "token1"}}, nil
+				},
+			},
 			wfMock: &th.WorkflowMock{
 				ListStatusFunc: func(ctx context.Context) ([]workflow.Status, error) {
 					return []workflow.Status{
@@ -1087,6 +2072,11 @@ func TestListWorkflows(t *testing.T) {
 			authHeader: userAuthHeader,
 			method:     "GET",
 			url:        "/projects/projects1/targets/target1/workflows",
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, p string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{{RoleID: "user", TokenID: "token1"}}, nil
+				},
+			},
 			wfMock: &th.WorkflowMock{
 				ListStatusFunc: func(ctx context.Context) ([]workflow.Status, error) {
 					return []workflow.Status{}, nil
@@ -1097,6 +2087,39 @@ func TestListWorkflows(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestWorkflowLabels(t *testing.T) {
+	tests := []struct {
+		name       string
+		commitHash string
+		want       map[string]string
+	}{
+		{
+			name: "without commit hash",
+			want: map[string]string{txIDHeader: "tx1", workflow.ProjectLabel: "project1"},
+		},
+		{
+			name:       "with commit hash",
+			commitHash: "8458fd753f9fde51882414564c20df6d4c34a90e",
+			want: map[string]string{
+				txIDHeader:               "tx1",
+				workflow.ProjectLabel:    "project1",
+				workflow.CommitHashLabel: "8458fd753f9fde51882414564c20df6d4c34a90e",
+			},
+		},
+		{
+			name:       "commit hash too long for a label",
+			commitHash: strings.Repeat("a", 64),
+			want:       map[string]string{txIDHeader: "tx1", workflow.ProjectLabel: "project1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, workflowLabels("tx1", "project1", tt.commitHash))
+		})
+	}
+}
+
 func TestDeleteToken(t *testing.T) {
 	tests := []test{
 		{
@@ -1240,6 +2263,172 @@ func TestDeleteToken(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestRotateToken(t *testing.T) {
+	scopes := types.TokenScopes{{Target: "target1", Operations: []string{"diff"}}}
+
+	newCPMock := func() *th.CredsProviderMock {
+		return &th.CredsProviderMock{
+			CreateTokenFunc: func(s string, sc types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+				if len(sc) != 1 || ttl != 8776*time.Hour {
+					return types.Token{}, fmt.Errorf("unexpected scopes %v or ttl %s", sc, ttl)
+				}
+				return types.Token{
+					CreatedAt: "2022-07-21T14:56:10.341066-07:00",
+					ExpiresAt: "2023-07-21T14:56:10.341066-07:00",
+					ProjectID: "project1",
+					ProjectToken: types.ProjectToken{
+						ID: "new-secret-id-accessor",
+					},
+					RoleID: "scoped-role-id",
+					Scopes: sc,
+					Secret: "new-secret",
+				}, nil
+			},
+			DeleteProjectTokenFunc: func(s1, s2 string) error { return nil },
+			GetProjectTokenFunc: func(s1, s2 string) (types.ProjectToken, error) {
+				return types.ProjectToken{ID: "secret-id-accessor"}, nil
+			},
+			ProjectExistsFunc: func(s string) (bool, error) { return true, nil },
+		}
+	}
+
+	tokenEntry := db.TokenEntry{
+		CreatedAt: "2022-06-21T14:56:10.341066-07:00",
+		ExpiresAt: "2023-06-21T14:56:10.341066-07:00",
+		ProjectID: "project1",
+		RoleID:    "scoped-role-id",
+		Scopes:    scopes,
+		TokenID:   "secret-id-accessor",
+	}
+
+	tests := []test{
+		{
+			name:       "can rotate token",
+			want:       http.StatusOK,
+			respFile:   "TestRotateToken/can_rotate_token_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens/secret-id-accessor/rotate",
+			method:     "POST",
+			cpMock:     newCPMock(),
+			ddbMock: &th.DBClientMock{
+				CreateTokenEntryFunc: func(ctx context.Context, t types.Token) error { return nil },
+				DeleteTokenEntryByProjectFunc: func(ctx context.Context, p, t string) error {
+					if t != "secret-id-accessor" {
+						return fmt.Errorf("unexpected token %s", t)
+					}
+					return nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+				ReadTokenEntryByProjectFunc: func(ctx context.Context, p, t string) (db.TokenEntry, error) {
+					return tokenEntry, nil
+				},
+			},
+		},
+		{
+			name:       "can rotate token with grace period",
+			req:        loadJSON(t, "TestRotateToken/can_rotate_token_with_grace_period_request.json"),
+			want:       http.StatusOK,
+			respFile:   "TestRotateToken/can_rotate_token_with_grace_period_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens/secret-id-accessor/rotate",
+			method:     "POST",
+			cpMock:     newCPMock(),
+			ddbMock: &th.DBClientMock{
+				CreateTokenEntryFunc: func(ctx context.Context, t types.Token) error { return nil },
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{tokenEntry}, nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+				ReadTokenEntryByProjectFunc: func(ctx context.Context, p, t string) (db.TokenEntry, error) {
+					return tokenEntry, nil
+				},
+				UpdateTokenEntryFunc: func(ctx context.Context, te db.TokenEntry) error { return nil },
+			},
+		},
+		{
+			name:       "fails to rotate token with grace period when token limit reached",
+			req:        loadJSON(t, "TestRotateToken/can_rotate_token_with_grace_period_request.json"),
+			want:       http.StatusInternalServerError,
+			respFile:   "TestRotateToken/fails_to_rotate_token_with_grace_period_when_token_limit_reached_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens/secret-id-accessor/rotate",
+			method:     "POST",
+			cpMock:     newCPMock(),
+			ddbMock: &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return []db.TokenEntry{tokenEntry}, nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo", TokenLimit: 1}, nil
+				},
+			},
+		},
+		{
+			name:       "removes new token when old token fails to revoke",
+			want:       http.StatusInternalServerError,
+			respFile:   "TestRotateToken/removes_new_token_when_old_token_fails_to_revoke_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens/secret-id-accessor/rotate",
+			method:     "POST",
+			cpMock:     newCPMock(),
+			ddbMock: &th.DBClientMock{
+				CreateTokenEntryFunc: func(ctx context.Context, t types.Token) error { return nil },
+				DeleteTokenEntryByProjectFunc: func(ctx context.Context, p, t string) error {
+					if t != "new-secret-id-accessor" {
+						return errors.New("error")
+					}
+					return nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+				ReadTokenEntryByProjectFunc: func(ctx context.Context, p, t string) (db.TokenEntry, error) {
+					return tokenEntry, nil
+				},
+			},
+		},
+		{
+			name:       "fails to rotate token when not admin",
+			want:       http.StatusUnauthorized,
+			respFile:   "TestRotateToken/fails_to_rotate_token_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/projects/project1/tokens/secret-id-accessor/rotate",
+			method:     "POST",
+		},
+		{
+			name:       "fails to rotate token with grace period too long",
+			req:        loadJSON(t, "TestRotateToken/fails_to_rotate_token_with_grace_period_too_long_request.json"),
+			want:       http.StatusBadRequest,
+			respFile:   "TestRotateToken/fails_to_rotate_token_with_grace_period_too_long_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens/secret-id-accessor/rotate",
+			method:     "POST",
+		},
+		{
+			name:       "token does not exist",
+			want:       http.StatusNotFound,
+			respFile:   "TestRotateToken/token_does_not_exist_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/tokens/secret-id-accessor/rotate",
+			method:     "POST",
+			cpMock:     newCPMock(),
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, p string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: "project1", Repository: "repo"}, nil
+				},
+				ReadTokenEntryByProjectFunc: func(ctx context.Context, p, t string) (db.TokenEntry, error) {
+					return db.TokenEntry{}, db.ErrTokenNotFound
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
 func TestListTokens(t *testing.T) {
 	tests := []test{
 		{
@@ -1349,6 +2538,40 @@ func TestListTokens(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestMetrics(t *testing.T) {
+	h := handler{
+		logger:  log.NewNopLogger(),
+		env:     env.Vars{CredentialsProvider: credentials.ProviderLocal},
+		metrics: metrics.New(),
+	}
+
+	resp := executeRequestWithHandler(h, http.MethodGet, "/health/full", &bytes.Buffer{}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = executeRequestWithHandler(h, http.MethodGet, "/metrics", &bytes.Buffer{}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `cello_http_requests_total{code="200",method="GET",route="/health/full"} 1`)
+}
+
+func TestTracing(t *testing.T) {
+	h := handler{
+		logger: log.NewNopLogger(),
+		env:    env.Vars{CredentialsProvider: credentials.ProviderLocal},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health/full", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	setupRouter(h).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", w.Header().Get("X-B3-TraceId"))
+	assert.Contains(t, w.Header().Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-")
+}
+
 func TestHealthCheck(t *testing.T) {
 	tests := []struct {
 		name                  string
@@ -1426,7 +2649,9 @@ func TestHealthCheck(t *testing.T) {
 			h := handler{
 				logger: log.NewNopLogger(),
 				env: env.Vars{
-					VaultAddress: vaultEndpoint,
+					CredentialsProvider: credentials.ProviderVault,
+					VaultAddress:        vaultEndpoint,
+					HealthCheckTimeout:  time.Second,
 				},
 			}
 
@@ -1453,6 +2678,112 @@ func TestHealthCheck(t *testing.T) {
 	}
 }
 
+func TestLiveCheck(t *testing.T) {
+	h := handler{logger: log.NewNopLogger()}
+
+	resp := executeRequestWithHandler(h, http.MethodGet, "/health/live", &bytes.Buffer{}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"status":"ok"}`, string(body))
+}
+
+func TestReadyCheck(t *testing.T) {
+	tests := []struct {
+		name            string
+		vaultStatusCode int
+		ddbErr          error
+		argoErr         error
+		pingErr         error
+		gitRepository   string
+		wantStatusCode  int
+		want            map[string]string
+	}{
+		{
+			name:            "ready",
+			vaultStatusCode: http.StatusOK,
+			gitRepository:   "https://github.com/cello-proj/canary.git",
+			wantStatusCode:  http.StatusOK,
+			want:            map[string]string{"vault": "ok", "dynamodb": "ok", "argo": "ok", "git": "ok"},
+		},
+		{
+			name:            "git not checked without a canary repository",
+			vaultStatusCode: http.StatusTooManyRequests,
+			pingErr:         errors.New("unreachable"),
+			wantStatusCode:  http.StatusOK,
+			want:            map[string]string{"vault": "ok", "dynamodb": "ok", "argo": "ok"},
+		},
+		{
+			name:            "vault sealed",
+			vaultStatusCode: http.StatusServiceUnavailable,
+			wantStatusCode:  http.StatusServiceUnavailable,
+			want:            map[string]string{"vault": "unavailable", "dynamodb": "ok", "argo": "ok"},
+		},
+		{
+			name:            "dependencies unavailable",
+			vaultStatusCode: http.StatusOK,
+			ddbErr:          errors.New("access denied"),
+			argoErr:         errors.New("connection refused"),
+			pingErr:         errors.New("authentication required"),
+			gitRepository:   "https://github.com/cello-proj/canary.git",
+			wantStatusCode:  http.StatusServiceUnavailable,
+			want:            map[string]string{"vault": "ok", "dynamodb": "unavailable", "argo": "unavailable", "git": "unavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vaultSvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.vaultStatusCode)
+			}))
+			defer vaultSvc.Close()
+
+			h := handler{
+				logger: log.NewNopLogger(),
+				env: env.Vars{
+					CredentialsProvider: credentials.ProviderVault,
+					VaultAddress:        vaultSvc.URL,
+					HealthGitRepository: tt.gitRepository,
+				},
+				argoCtx: context.Background(),
+				ddbClient: &th.DBClientMock{
+					HealthFunc: func(ctx context.Context) error { return tt.ddbErr },
+				},
+				argo: &th.WorkflowMock{
+					HealthFunc: func(ctx context.Context) error { return tt.argoErr },
+				},
+				gitClient: &th.GitClientMock{
+					PingFunc: func(ctx context.Context, repository string) error {
+						assert.Equal(t, tt.gitRepository, repository)
+						return tt.pingErr
+					},
+				},
+			}
+			h.readiness = health.NewChecker(time.Second, 0, h.readinessChecks()...)
+
+			resp := executeRequestWithHandler(h, http.MethodGet, "/health/ready", &bytes.Buffer{}, "")
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+
+			var got struct {
+				Status     string `json:"status"`
+				Components map[string]struct {
+					Status    string   `json:"status"`
+					LatencyMS *float64 `json:"latency_ms"`
+				} `json:"components"`
+			}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+
+			statuses := map[string]string{}
+			for name, component := range got.Components {
+				statuses[name] = component.Status
+				assert.NotNil(t, component.LatencyMS)
+			}
+			assert.Equal(t, tt.want, statuses)
+		})
+	}
+}
+
 // Serialize a type to JSON-encoded byte buffer.
 func serialize(toMarshal interface{}) *bytes.Buffer {
 	jsonStr, _ := json.Marshal(toMarshal)
@@ -1467,8 +2798,11 @@ func runTests(t *testing.T, tests []test) {
 				panic(fmt.Sprintf("Unable to load config %s", err))
 			}
 
+			// Project tokens are verified for their project.
 			defaultCP := func(a credentials.Authorization, env env.Vars, h http.Header, f credentials.VaultConfigFn, fn credentials.VaultSvcFn) (credentials.Provider, error) {
-				return &th.CredsProviderMock{}, nil
+				return &th.CredsProviderMock{
+					VerifyProjectTokenFunc: func(project, roleID, secretID string) error { return nil },
+				}, nil
 			}
 
 			h := handler{
@@ -1478,7 +2812,13 @@ func runTests(t *testing.T, tests []test) {
 				config:                 config,
 				gitClient:              &th.GitClientMock{},
 				env: env.Vars{
-					AdminSecret: testPassword,
+					AdminSecret:         testPassword,
+					CredentialsProvider: credentials.ProviderVault,
+					TokenLimit:          2,
+					TokenMinTTL:         time.Hour,
+					TokenMaxTTL:         8776 * time.Hour,
+					TokenMaxGracePeriod: 24 * time.Hour,
+					TokenSweepInterval:  15 * time.Minute,
 				},
 			}
 
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/workflow"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader is set on the responses of replayed requests.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// idempotencyLockDuration is how long a request holds its key before the
+	// request can be retried, in case its replica stops before the workflow
+	// is submitted.
+	idempotencyLockDuration = 5 * time.Minute
+)
+
+// idempotent makes workflow submissions made with an Idempotency-Key header
+// safe to retry. The first request with a key submits the workflow, replays
+// of the request return the same workflow, or submission when it was queued,
+// until the key expires. Requests which do not submit or queue a workflow
+// release their key.
+func (h handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		l := h.requestLogger(r, "op", "idempotency")
+		if len(key) > maxIdempotencyKeyLength {
+			h.errorResponse(w, fmt.Sprintf("idempotency key cannot be longer than %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			level.Error(l).Log("message", "error reading request body", "error", err)
+			h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		now := time.Now()
+		ie := db.IdempotencyEntry{
+			Key:         key,
+			Principal:   principalFromContext(ctx).Name,
+			RequestHash: requestHash(r, body),
+			LockedUntil: now.Add(idempotencyLockDuration),
+			ExpiresAt:   now.Add(h.env.IdempotencyKeyTTL),
+		}
+
+		err = h.ddbClient.CreateIdempotencyEntry(ctx, ie)
+		if errors.Is(err, db.ErrIdempotencyKeyExists) {
+			h.replay(ctx, w, l, ie)
+			return
+		}
+		if err != nil {
+			level.Error(l).Log("message", "error creating idempotency key", "error", err)
+			h.errorResponse(w, "error checking idempotency key", http.StatusInternalServerError)
+			return
+		}
+
+		rec := &submissionRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		// The key is completed or released even when the client has gone.
+		ctx = context.WithoutCancel(ctx)
+
+		var cwresp workflow.CreateWorkflowResponse
+		var sresp responses.GetSubmission
+		switch {
+		case rec.status == http.StatusOK && json.Unmarshal(rec.body.Bytes(), &cwresp) == nil && cwresp.WorkflowName != "":
+			ie.WorkflowName = cwresp.WorkflowName
+		case rec.status == http.StatusAccepted && json.Unmarshal(rec.body.Bytes(), &sresp) == nil && sresp.ID != "":
+			ie.SubmissionID = sresp.ID
+		}
+
+		if ie.WorkflowName != "" || ie.SubmissionID != "" {
+			// Retries are submitted again once the key's lock has passed
+			// when the workflow cannot be stored.
+			if err := h.ddbClient.UpdateIdempotencyEntry(ctx, ie); err != nil {
+				level.Error(l).Log("message", "error storing workflow of idempotency key", "workflow", ie.WorkflowName, "submission", ie.SubmissionID, "error", err)
+			}
+			return
+		}
+
+		if err := h.ddbClient.DeleteIdempotencyEntry(ctx, ie.Principal, ie.Key); err != nil {
+			level.Error(l).Log("message", "error releasing idempotency key", "error", err)
+		}
+	}
+}
+
+// replay writes the response of a request whose key is already held, which
+// is the workflow submitted, or the submission queued, by the first request
+// with the key.
+func (h handler) replay(ctx context.Context, w http.ResponseWriter, l log.Logger, ie db.IdempotencyEntry) {
+	stored, err := h.ddbClient.ReadIdempotencyEntry(ctx, ie.Principal, ie.Key)
+	if err != nil && !errors.Is(err, db.ErrIdempotencyKeyNotFound) {
+		level.Error(l).Log("message", "error reading idempotency key", "error", err)
+		h.errorResponse(w, "error checking idempotency key", http.StatusInternalServerError)
+		return
+	}
+
+	// The key can have been released since it was found to be held.
+	if err == nil && stored.RequestHash != ie.RequestHash {
+		level.Warn(l).Log("message", "idempotency key reused with a different request")
+		h.errorResponse(w, "idempotency key has already been used with a different request", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err != nil || (stored.WorkflowName == "" && stored.SubmissionID == "") {
+		h.errorResponse(w, "a request with this idempotency key is in progress, retry later", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set(idempotentReplayedHeader, "true")
+
+	if stored.SubmissionID != "" {
+		se, err := h.ddbClient.ReadSubmissionEntry(ctx, stored.SubmissionID)
+		if err != nil {
+			level.Error(l).Log("message", "error retrieving submission", "submission", stored.SubmissionID, "error", err)
+			h.errorResponse(w, "error retrieving submission", http.StatusInternalServerError)
+			return
+		}
+		level.Info(l).Log("message", "replaying idempotent request", "submission", se.ID)
+		h.submissionResponse(w, l, se)
+		return
+	}
+
+	level.Info(l).Log("message", "replaying idempotent request", "workflow", stored.WorkflowName)
+	if err := json.NewEncoder(w).Encode(workflow.CreateWorkflowResponse{WorkflowName: stored.WorkflowName}); err != nil {
+		level.Error(l).Log("message", "error serializing workflow response", "error", err)
+	}
+}
+
+// requestHash identifies a request by its method, path and body.
+func requestHash(r *http.Request, body []byte) string {
+	s := sha256.New()
+	fmt.Fprintf(s, "%s %s\n", r.Method, r.URL.Path)
+	s.Write(body)
+	return hex.EncodeToString(s.Sum(nil))
+}
+
+// submissionRecorder keeps a copy of the response written by a submission.
+type submissionRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (s *submissionRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *submissionRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	s.body.Write(b)
+	return s.ResponseWriter.Write(b)
+}
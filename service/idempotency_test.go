@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/env"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotent(t *testing.T) {
+	const body = `{"project_name":"project1","target_name":"target1"}`
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		key  string
+		// stored is the entry holding the key, if any.
+		stored     *db.IdempotencyEntry
+		createErr  error
+		nextStatus int
+		wantStatus int
+		wantBody   string
+		wantNext   bool
+		// wantEntry is the entry of the key once the request is done, nil
+		// when the key is released.
+		wantEntry    *db.IdempotencyEntry
+		wantReplayed bool
+	}{
+		{
+			name:       "without key",
+			nextStatus: http.StatusOK,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"workflow_name":"project1-target1-abcde"}`,
+			wantNext:   true,
+		},
+		{
+			name:       "key too long",
+			key:        strings.Repeat("k", maxIdempotencyKeyLength+1),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error_message":"idempotency key cannot be longer than 255 characters"}`,
+		},
+		{
+			name:       "first request submits workflow",
+			key:        "build-42",
+			nextStatus: http.StatusOK,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"workflow_name":"project1-target1-abcde"}`,
+			wantNext:   true,
+			wantEntry:  &db.IdempotencyEntry{WorkflowName: "project1-target1-abcde"},
+		},
+		{
+			name:       "failed request releases key",
+			key:        "build-42",
+			nextStatus: http.StatusBadRequest,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error_message":"bad request"}`,
+			wantNext:   true,
+		},
+		{
+			name:         "replay returns original workflow",
+			key:          "build-42",
+			stored:       &db.IdempotencyEntry{WorkflowName: "project1-target1-fghij"},
+			wantStatus:   http.StatusOK,
+			wantBody:     `{"workflow_name":"project1-target1-fghij"}`,
+			wantEntry:    &db.IdempotencyEntry{WorkflowName: "project1-target1-fghij"},
+			wantReplayed: true,
+		},
+		{
+			name:       "first queued request queues submission",
+			key:        "build-42",
+			nextStatus: http.StatusAccepted,
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"submission_id":"project1-6f1c0b8e","status":"queued","attempts":0,"created_at":"2024-01-02T03:04:05Z"}`,
+			wantNext:   true,
+			wantEntry:  &db.IdempotencyEntry{SubmissionID: "project1-6f1c0b8e"},
+		},
+		{
+			name:         "replay of queued request returns submission",
+			key:          "build-42",
+			stored:       &db.IdempotencyEntry{SubmissionID: "project1-6f1c0b8e"},
+			wantStatus:   http.StatusAccepted,
+			wantBody:     `{"submission_id":"project1-6f1c0b8e","status":"submitted","workflow_name":"project1-target1-abcde","attempts":1,"created_at":"2024-01-02T03:04:05Z"}`,
+			wantEntry:    &db.IdempotencyEntry{SubmissionID: "project1-6f1c0b8e"},
+			wantReplayed: true,
+		},
+		{
+			name:       "key used with a different request",
+			key:        "build-42",
+			stored:     &db.IdempotencyEntry{RequestHash: "other", WorkflowName: "project1-target1-fghij"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"error_message":"idempotency key has already been used with a different request"}`,
+			wantEntry:  &db.IdempotencyEntry{RequestHash: "other", WorkflowName: "project1-target1-fghij"},
+		},
+		{
+			name:       "request in progress",
+			key:        "build-42",
+			stored:     &db.IdempotencyEntry{},
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"error_message":"a request with this idempotency key is in progress, retry later"}`,
+			wantEntry:  &db.IdempotencyEntry{},
+		},
+		{
+			name:       "database error",
+			key:        "build-42",
+			createErr:  errors.New("boom"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error_message":"error checking idempotency key"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/workflows", strings.NewReader(body))
+			req.Header.Set(idempotencyKeyHeader, tt.key)
+			req = req.WithContext(context.WithValue(req.Context(), principalContextKey, principal{Name: "ci-runner"}))
+			hash := requestHash(req, []byte(body))
+
+			// entries is an in memory table of idempotency keys.
+			entries := map[string]db.IdempotencyEntry{}
+			if tt.stored != nil {
+				ie := *tt.stored
+				ie.Principal, ie.Key = "ci-runner", tt.key
+				if ie.RequestHash == "" {
+					ie.RequestHash = hash
+				}
+				entries[tt.key] = ie
+			}
+
+			dbMock := &th.DBClientMock{
+				CreateIdempotencyEntryFunc: func(ctx context.Context, ie db.IdempotencyEntry) error {
+					if tt.createErr != nil {
+						return tt.createErr
+					}
+					if _, ok := entries[ie.Key]; ok {
+						return db.ErrIdempotencyKeyExists
+					}
+					entries[ie.Key] = ie
+					return nil
+				},
+				ReadIdempotencyEntryFunc: func(ctx context.Context, principal, key string) (db.IdempotencyEntry, error) {
+					assert.Equal(t, "ci-runner", principal)
+					ie, ok := entries[key]
+					if !ok {
+						return db.IdempotencyEntry{}, db.ErrIdempotencyKeyNotFound
+					}
+					return ie, nil
+				},
+				UpdateIdempotencyEntryFunc: func(ctx context.Context, ie db.IdempotencyEntry) error {
+					entries[ie.Key] = ie
+					return nil
+				},
+				DeleteIdempotencyEntryFunc: func(ctx context.Context, principal, key string) error {
+					delete(entries, key)
+					return nil
+				},
+				ReadSubmissionEntryFunc: func(ctx context.Context, id string) (db.SubmissionEntry, error) {
+					return db.SubmissionEntry{ID: id, Status: db.SubmissionSubmitted, Attempts: 1, WorkflowName: "project1-target1-abcde", CreatedAt: createdAt}, nil
+				},
+			}
+
+			h := handler{
+				logger:    log.NewNopLogger(),
+				env:       env.Vars{IdempotencyKeyTTL: time.Hour},
+				ddbClient: dbMock,
+			}
+
+			called := false
+			next := func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				// The body can still be read by the handler.
+				b := make([]byte, len(body))
+				_, err := r.Body.Read(b)
+				assert.NoError(t, err)
+				assert.Equal(t, body, string(b))
+
+				switch tt.nextStatus {
+				case http.StatusOK:
+				case http.StatusAccepted:
+					h.submissionResponse(w, log.NewNopLogger(), db.SubmissionEntry{ID: "project1-6f1c0b8e", Status: db.SubmissionQueued, CreatedAt: createdAt})
+					return
+				default:
+					h.errorResponse(w, "bad request", tt.nextStatus)
+					return
+				}
+				fmt.Fprintln(w, `{"workflow_name":"project1-target1-abcde"}`)
+			}
+
+			w := httptest.NewRecorder()
+			h.idempotent(next)(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantNext, called)
+			assert.Equal(t, tt.wantReplayed, w.Header().Get(idempotentReplayedHeader) == "true")
+
+			ie, ok := entries[tt.key]
+			if tt.wantEntry == nil || tt.key == "" {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantEntry.WorkflowName, ie.WorkflowName)
+			assert.Equal(t, tt.wantEntry.SubmissionID, ie.SubmissionID)
+			if tt.stored == nil {
+				assert.Equal(t, hash, ie.RequestHash)
+				assert.WithinDuration(t, time.Now().Add(time.Hour), ie.ExpiresAt, time.Minute)
+				assert.WithinDuration(t, time.Now().Add(idempotencyLockDuration), ie.LockedUntil, time.Minute)
+			}
+		})
+	}
+}
+
+func TestRequestHash(t *testing.T) {
+	hash := func(method, path, body string) string {
+		return requestHash(httptest.NewRequest(method, path, nil), []byte(body))
+	}
+
+	assert.Equal(t, hash(http.MethodPost, "/workflows", "{}"), hash(http.MethodPost, "/workflows", "{}"))
+	assert.NotEqual(t, hash(http.MethodPost, "/workflows", "{}"), hash(http.MethodPost, "/workflows", `{"type":"sync"}`))
+	assert.NotEqual(t,
+		hash(http.MethodPost, "/projects/project1/targets/target1/operations", "{}"),
+		hash(http.MethodPost, "/projects/project1/targets/target2/operations", "{}"),
+	)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/service/internal/db"
+
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// identitySecretBytes is the number of random bytes of an identity's secret.
+const identitySecretBytes = 32
+
+// newIdentitySecret returns a random secret and its bcrypt hash.
+func newIdentitySecret() (string, string, error) {
+	b := make([]byte, identitySecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := hex.EncodeToString(b)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, string(hash), nil
+}
+
+// getIdentityResponse returns the identity without its secret hash.
+func getIdentityResponse(ie db.IdentityEntry) responses.GetIdentity {
+	return responses.GetIdentity{
+		CreatedAt: ie.CreatedAt,
+		Name:      ie.Name,
+		Roles:     ie.Roles,
+	}
+}
+
+// Creates an identity
+func (h handler) createIdentity(w http.ResponseWriter, r *http.Request) {
+	l := h.requestLogger(r, "op", "create-identity")
+
+	level.Debug(l).Log("message", "reading request body")
+	reqBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	var cir requests.CreateIdentity
+	if err := json.Unmarshal(reqBody, &cir); err != nil {
+		level.Error(l).Log("message", "error decoding request", "error", err)
+		h.errorResponse(w, "error decoding request", http.StatusBadRequest)
+		return
+	}
+
+	if err := cir.Validate(); err != nil {
+		level.Error(l).Log("message", "error validating request", "error", err)
+		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err), http.StatusBadRequest)
+		return
+	}
+
+	secret, secretHash, err := newIdentitySecret()
+	if err != nil {
+		level.Error(l).Log("message", "error generating identity secret", "error", err)
+		h.errorResponse(w, "error creating identity", http.StatusInternalServerError)
+		return
+	}
+
+	level.Debug(l).Log("message", "creating identity in database", "identity", cir.Name)
+	err = h.ddbClient.CreateIdentityEntry(r.Context(), db.IdentityEntry{
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		Name:       cir.Name,
+		Roles:      cir.Roles,
+		SecretHash: secretHash,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdentityExists) {
+			level.Error(l).Log("error", "identity already exists")
+			h.errorResponse(w, "identity already exists", http.StatusBadRequest)
+			return
+		}
+		level.Error(l).Log("message", "error creating identity", "error", err)
+		h.errorResponse(w, "error creating identity", http.StatusInternalServerError)
+		return
+	}
+
+	resp := responses.CreateIdentity{
+		Name:  cir.Name,
+		Roles: cir.Roles,
+		Token: fmt.Sprintf("%s:%s%s:%s", h.env.CredentialsProvider, identityKeyPrefix, cir.Name, secret),
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		level.Error(l).Log("message", "error serializing identity", "error", err)
+		h.errorResponse(w, "error creating identity", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Lists identities
+func (h handler) listIdentities(w http.ResponseWriter, r *http.Request) {
+	l := h.requestLogger(r, "op", "list-identities")
+
+	identities, err := h.ddbClient.ListIdentityEntries(r.Context())
+	if err != nil {
+		level.Error(l).Log("message", "error listing identities", "error", err)
+		h.errorResponse(w, "error listing identities", http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Name < identities[j].Name })
+
+	resp := []responses.GetIdentity{}
+	for _, ie := range identities {
+		resp = append(resp, getIdentityResponse(ie))
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		level.Error(l).Log("message", "error serializing identities", "error", err)
+		h.errorResponse(w, "error listing identities", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Gets an identity
+func (h handler) getIdentity(w http.ResponseWriter, r *http.Request) {
+	identityName := mux.Vars(r)["identityName"]
+
+	l := h.requestLogger(r, "op", "get-identity", "identity", identityName)
+
+	ie, err := h.ddbClient.ReadIdentityEntry(r.Context(), identityName)
+	if err != nil {
+		if errors.Is(err, db.ErrIdentityNotFound) {
+			h.errorResponse(w, "identity does not exist", http.StatusNotFound)
+			return
+		}
+		level.Error(l).Log("message", "error retrieving identity", "error", err)
+		h.errorResponse(w, "error retrieving identity", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(getIdentityResponse(ie)); err != nil {
+		level.Error(l).Log("message", "error serializing identity", "error", err)
+		h.errorResponse(w, "error retrieving identity", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Replaces the roles of an identity
+func (h handler) updateIdentityRoles(w http.ResponseWriter, r *http.Request) {
+	identityName := mux.Vars(r)["identityName"]
+
+	l := h.requestLogger(r, "op", "update-identity-roles", "identity", identityName)
+
+	level.Debug(l).Log("message", "reading request body")
+	reqBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	var uirr requests.UpdateIdentityRoles
+	if err := json.Unmarshal(reqBody, &uirr); err != nil {
+		level.Error(l).Log("message", "error decoding request", "error", err)
+		h.errorResponse(w, "error decoding request", http.StatusBadRequest)
+		return
+	}
+
+	if err := uirr.Validate(); err != nil {
+		level.Error(l).Log("message", "error validating request", "error", err)
+		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	ie, err := h.ddbClient.ReadIdentityEntry(ctx, identityName)
+	if err != nil {
+		if errors.Is(err, db.ErrIdentityNotFound) {
+			h.errorResponse(w, "identity does not exist", http.StatusNotFound)
+			return
+		}
+		level.Error(l).Log("message", "error retrieving identity", "error", err)
+		h.errorResponse(w, "error retrieving identity", http.StatusInternalServerError)
+		return
+	}
+
+	ie.Roles = uirr.Roles
+	if err := h.ddbClient.UpdateIdentityEntry(ctx, ie); err != nil {
+		if errors.Is(err, db.ErrIdentityNotFound) {
+			h.errorResponse(w, "identity does not exist", http.StatusNotFound)
+			return
+		}
+		level.Error(l).Log("message", "error updating identity", "error", err)
+		h.errorResponse(w, "error updating identity", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(getIdentityResponse(ie)); err != nil {
+		level.Error(l).Log("message", "error serializing identity", "error", err)
+		h.errorResponse(w, "error updating identity", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Deletes an identity
+func (h handler) deleteIdentity(w http.ResponseWriter, r *http.Request) {
+	identityName := mux.Vars(r)["identityName"]
+
+	l := h.requestLogger(r, "op", "delete-identity", "identity", identityName)
+
+	ctx := r.Context()
+
+	if _, err := h.ddbClient.ReadIdentityEntry(ctx, identityName); err != nil {
+		if errors.Is(err, db.ErrIdentityNotFound) {
+			h.errorResponse(w, "identity does not exist", http.StatusNotFound)
+			return
+		}
+		level.Error(l).Log("message", "error retrieving identity", "error", err)
+		h.errorResponse(w, "error retrieving identity", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.ddbClient.DeleteIdentityEntry(ctx, identityName); err != nil {
+		level.Error(l).Log("message", "error deleting identity", "error", err)
+		h.errorResponse(w, "error deleting identity", http.StatusInternalServerError)
+		return
+	}
+}
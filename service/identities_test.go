@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/db"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+)
+
+func TestCreateIdentity(t *testing.T) {
+	tests := []test{
+		{
+			name:       "fails to create identity when not admin",
+			want:       http.StatusUnauthorized,
+			respFile:   "TestCreateIdentity/fails_to_create_identity_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/identities",
+			method:     "POST",
+		},
+		{
+			name: "fails to create identity when invalid request",
+			req: requests.CreateIdentity{
+				Name:  "teamlead1",
+				Roles: types.RoleBindings{{Role: types.RoleProjectAdmin}},
+			},
+			want:       http.StatusBadRequest,
+			respFile:   "TestCreateIdentity/fails_to_create_identity_when_invalid_request_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities",
+			method:     "POST",
+		},
+		{
+			name: "fails to create identity when identity exists",
+			req: requests.CreateIdentity{
+				Name:  "teamlead1",
+				Roles: types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			},
+			want:       http.StatusBadRequest,
+			respFile:   "TestCreateIdentity/fails_to_create_identity_when_identity_exists_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities",
+			method:     "POST",
+			ddbMock: &th.DBClientMock{
+				CreateIdentityEntryFunc: func(ctx context.Context, ie db.IdentityEntry) error {
+					return db.ErrIdentityExists
+				},
+			},
+		},
+		{
+			name: "can create identity",
+			req: requests.CreateIdentity{
+				Name:  "teamlead1",
+				Roles: types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			},
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/identities",
+			method:     "POST",
+			ddbMock: &th.DBClientMock{
+				CreateIdentityEntryFunc: func(ctx context.Context, ie db.IdentityEntry) error {
+					return nil
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestListIdentities(t *testing.T) {
+	tests := []test{
+		{
+			name:       "fails to list identities when not admin",
+			want:       http.StatusUnauthorized,
+			respFile:   "TestListIdentities/fails_to_list_identities_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/identities",
+			method:     "GET",
+		},
+		{
+			name:       "can list identities",
+			want:       http.StatusOK,
+			respFile:   "TestListIdentities/can_list_identities_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities",
+			method:     "GET",
+			ddbMock: &th.DBClientMock{
+				ListIdentityEntriesFunc: func(ctx context.Context) ([]db.IdentityEntry, error) {
+					return []db.IdentityEntry{
+						{
+							CreatedAt:  "2022-06-21T14:56:10Z",
+							Name:       "teamlead1",
+							Roles:      types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+							SecretHash: "hash",
+						},
+						{
+							CreatedAt:  "2022-06-21T14:43:16Z",
+							Name:       "operator1",
+							Roles:      types.RoleBindings{{Role: types.RoleOperator}},
+							SecretHash: "hash",
+						},
+					}, nil
+				},
+			},
+		},
+		{
+			name:       "list identities error",
+			want:       http.StatusInternalServerError,
+			respFile:   "TestListIdentities/list_identities_error_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities",
+			method:     "GET",
+			ddbMock: &th.DBClientMock{
+				ListIdentityEntriesFunc: func(ctx context.Context) ([]db.IdentityEntry, error) {
+					return nil, errors.New("error")
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestGetIdentity(t *testing.T) {
+	tests := []test{
+		{
+			name:       "fails to get identity when not admin",
+			want:       http.StatusUnauthorized,
+			respFile:   "TestGetIdentity/fails_to_get_identity_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/identities/teamlead1",
+			method:     "GET",
+		},
+		{
+			name:       "can get identity",
+			want:       http.StatusOK,
+			respFile:   "TestGetIdentity/can_get_identity_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities/teamlead1",
+			method:     "GET",
+			ddbMock: &th.DBClientMock{
+				ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+					return db.IdentityEntry{
+						CreatedAt:  "2022-06-21T14:56:10Z",
+						Name:       name,
+						Roles:      types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+						SecretHash: "hash",
+					}, nil
+				},
+			},
+		},
+		{
+			name:       "identity not found",
+			want:       http.StatusNotFound,
+			respFile:   "TestGetIdentity/identity_not_found_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities/someone",
+			method:     "GET",
+			ddbMock: &th.DBClientMock{
+				ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+					return db.IdentityEntry{}, db.ErrIdentityNotFound
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestUpdateIdentityRoles(t *testing.T) {
+	tests := []test{
+		{
+			name:       "fails to update identity roles when not admin",
+			req:        requests.UpdateIdentityRoles{Roles: types.RoleBindings{{Role: types.RoleViewer}}},
+			want:       http.StatusUnauthorized,
+			respFile:   "TestUpdateIdentityRoles/fails_to_update_identity_roles_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/identities/teamlead1/roles",
+			method:     "PUT",
+		},
+		{
+			name:       "fails to update identity roles when invalid request",
+			req:        requests.UpdateIdentityRoles{},
+			want:       http.StatusBadRequest,
+			respFile:   "TestUpdateIdentityRoles/fails_to_update_identity_roles_when_invalid_request_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities/teamlead1/roles",
+			method:     "PUT",
+		},
+		{
+			name:       "identity not found",
+			req:        requests.UpdateIdentityRoles{Roles: types.RoleBindings{{Role: types.RoleViewer}}},
+			want:       http.StatusNotFound,
+			respFile:   "TestUpdateIdentityRoles/identity_not_found_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities/someone/roles",
+			method:     "PUT",
+			ddbMock: &th.DBClientMock{
+				ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+					return db.IdentityEntry{}, db.ErrIdentityNotFound
+				},
+			},
+		},
+		{
+			name:       "can update identity roles",
+			req:        requests.UpdateIdentityRoles{Roles: types.RoleBindings{{Role: types.RoleViewer}}},
+			want:       http.StatusOK,
+			respFile:   "TestUpdateIdentityRoles/can_update_identity_roles_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities/teamlead1/roles",
+			method:     "PUT",
+			ddbMock: &th.DBClientMock{
+				ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+					return db.IdentityEntry{
+						CreatedAt:  "2022-06-21T14:56:10Z",
+						Name:       name,
+						Roles:      types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+						SecretHash: "hash",
+					}, nil
+				},
+				UpdateIdentityEntryFunc: func(ctx context.Context, ie db.IdentityEntry) error {
+					return nil
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestDeleteIdentity(t *testing.T) {
+	tests := []test{
+		{
+			name:       "fails to delete identity when not admin",
+			want:       http.StatusUnauthorized,
+			respFile:   "TestDeleteIdentity/fails_to_delete_identity_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/identities/teamlead1",
+			method:     "DELETE",
+		},
+		{
+			name:       "identity not found",
+			want:       http.StatusNotFound,
+			respFile:   "TestDeleteIdentity/identity_not_found_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/identities/someone",
+			method:     "DELETE",
+			ddbMock: &th.DBClientMock{
+				ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+					return db.IdentityEntry{}, db.ErrIdentityNotFound
+				},
+			},
+		},
+		{
+			name:       "can delete identity",
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/identities/teamlead1",
+			method:     "DELETE",
+			ddbMock: &th.DBClientMock{
+				ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+					return db.IdentityEntry{Name: name}, nil
+				},
+				DeleteIdentityEntryFunc: func(ctx context.Context, name string) error {
+					return nil
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
@@ -0,0 +1,107 @@
+// Package certs serves TLS certificates which are reloaded when their files
+// change, so renewed certificates are used without restarting the service,
+// and reads the identities of client certificates.
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloadInterval is the minimum time between checks of the files, which
+// are checked during handshakes.
+const reloadInterval = 10 * time.Second
+
+// Reloader loads a certificate and its key from files, and loads them again
+// once either file has been modified.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	// onError is called when the files can't be loaded again, the previous
+	// certificate is served until they can.
+	onError func(error)
+	now     func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewReloader returns a Reloader of the files, which are loaded immediately.
+// onError, when set, is called with the errors of later loads.
+func NewReloader(certFile, keyFile string, onError func(error)) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		onError:  onError,
+		now:      time.Now,
+	}
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	r.checkedAt = r.now()
+
+	return r, nil
+}
+
+// GetCertificate returns the certificate, loading it again when its files
+// have been modified. It is used as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.now().Sub(r.checkedAt) < reloadInterval {
+		return r.cert, nil
+	}
+	r.checkedAt = r.now()
+
+	modTime, err := r.latestModTime()
+	if err == nil && modTime.Equal(r.modTime) {
+		return r.cert, nil
+	}
+	if err == nil {
+		err = r.load(modTime)
+	}
+	if err != nil && r.onError != nil {
+		r.onError(err)
+	}
+
+	return r.cert, nil
+}
+
+// load loads the certificate, which replaces the current one.
+func (r *Reloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// latestModTime returns the modification time of the file modified last,
+// certificates and their keys are usually replaced together.
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to read certificate: %w", err)
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,116 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a self-signed certificate for the common name and its key
+// to the files, with the modification time.
+func writeCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// commonName returns the common name of the certificate served by r.
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Hour)
+	writeCert(t, certFile, keyFile, "first", start)
+
+	var errs []error
+	r, err := NewReloader(certFile, keyFile, func(err error) { errs = append(errs, err) })
+	assert.NoError(t, err)
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	assert.Equal(t, "first", commonName(t, r))
+
+	// The files are not checked again until the interval has passed.
+	writeCert(t, certFile, keyFile, "second", start.Add(time.Minute))
+	assert.Equal(t, "first", commonName(t, r))
+
+	now = now.Add(reloadInterval)
+	assert.Equal(t, "second", commonName(t, r))
+
+	// The previous certificate is served while the files are invalid.
+	assert.NoError(t, os.WriteFile(keyFile, []byte("invalid"), 0600))
+	now = now.Add(reloadInterval)
+	assert.Equal(t, "second", commonName(t, r))
+	assert.Len(t, errs, 1)
+
+	writeCert(t, certFile, keyFile, "third", start.Add(2*time.Minute))
+	now = now.Add(reloadInterval)
+	assert.Equal(t, "third", commonName(t, r))
+	assert.Len(t, errs, 1)
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	_, err := NewReloader(certFile, keyFile, nil)
+	assert.ErrorContains(t, err, "unable to read certificate")
+
+	writeCert(t, certFile, keyFile, "cello", time.Now())
+	assert.NoError(t, os.WriteFile(keyFile, []byte("invalid"), 0600))
+	_, err = NewReloader(certFile, keyFile, nil)
+	assert.ErrorContains(t, err, "unable to load certificate")
+}
@@ -0,0 +1,39 @@
+package certs
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadPool returns the pool of the PEM encoded CA certificates in the file,
+// which verifies client certificates.
+func LoadPool(caFile string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ca file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("ca file contains no certificates")
+	}
+	return pool, nil
+}
+
+// Identities returns the identities of a client certificate, most specific
+// first: its URI SANs, such as SPIFFE IDs, its DNS SANs, its email SANs and
+// its subject's common name.
+func Identities(cert *x509.Certificate) []string {
+	var ids []string
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	return ids
+}
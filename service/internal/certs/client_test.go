@@ -0,0 +1,70 @@
+package certs
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPool(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")
+	writeCert(t, certFile, keyFile, "ca", time.Now())
+
+	pool, err := LoadPool(certFile)
+	assert.NoError(t, err)
+	assert.NotNil(t, pool)
+
+	_, err = LoadPool(filepath.Join(dir, "missing.crt"))
+	assert.ErrorContains(t, err, "unable to read ca file")
+
+	// A key is not a certificate.
+	_, err = LoadPool(keyFile)
+	assert.ErrorContains(t, err, "no certificates")
+
+	assert.NoError(t, os.WriteFile(certFile, nil, 0600))
+	_, err = LoadPool(certFile)
+	assert.ErrorContains(t, err, "no certificates")
+}
+
+func TestIdentities(t *testing.T) {
+	spiffeID, _ := url.Parse("spiffe://example.com/ci/runner")
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want []string
+	}{
+		{
+			name: "common name",
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: "ci-runner"}},
+			want: []string{"ci-runner"},
+		},
+		{
+			name: "sans and common name",
+			cert: &x509.Certificate{
+				Subject:        pkix.Name{CommonName: "ci-runner"},
+				URIs:           []*url.URL{spiffeID},
+				DNSNames:       []string{"runner.ci.example.com"},
+				EmailAddresses: []string{"ci@example.com"},
+			},
+			want: []string{"spiffe://example.com/ci/runner", "runner.ci.example.com", "ci@example.com", "ci-runner"},
+		},
+		{
+			name: "no identities",
+			cert: &x509.Certificate{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Identities(tt.cert))
+		})
+	}
+}
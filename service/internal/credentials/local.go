@@ -0,0 +1,600 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/env"
+
+	"github.com/google/uuid"
+)
+
+// localSecretTTL matches the lifetime of Vault secret IDs.
+const localSecretTTL = 8776 * time.Hour
+
+// The store is read and written as a whole, so all access within the process
+// is serialized.
+var localStoreMu sync.Mutex
+
+// LocalProvider is a credentials provider backed by an encrypted file. It is
+// intended for local development and tests where Vault is not available and
+// should not be used in production.
+type LocalProvider struct {
+	roleID   string
+	secretID string
+	token    string
+	store    localStore
+}
+
+// NewLocalProvider returns a new LocalProvider.
+func NewLocalProvider(a Authorization, env env.Vars, _ http.Header, _ VaultConfigFn, _ VaultSvcFn) (Provider, error) {
+	if env.LocalProviderKey == "" {
+		return nil, errors.New("local provider key is required")
+	}
+
+	return &LocalProvider{
+		roleID:   a.Key,
+		secretID: a.Secret,
+		token:    env.LocalProviderToken,
+		store: localStore{
+			path: env.LocalProviderFile,
+			key:  sha256.Sum256([]byte(env.LocalProviderKey)),
+		},
+	}, nil
+}
+
+type localState struct {
+	Projects map[string]*localProject `json:"projects"`
+}
+
+type localProject struct {
+	// Policy is only stored, the local provider does not issue credentials
+	// to enforce it on.
+	Policy  types.ProjectPolicy     `json:"policy"`
+	RoleID  string                  `json:"role_id"`
+	Targets map[string]types.Target `json:"targets"`
+	Tokens  map[string]localToken   `json:"tokens"`
+}
+
+type localToken struct {
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	// RoleID is only set for scoped tokens, which each get their own role.
+	RoleID     string            `json:"role_id,omitempty"`
+	Scopes     types.TokenScopes `json:"scopes,omitempty"`
+	SecretHash string            `json:"secret_hash"`
+}
+
+// localStore persists the provider state as AES-GCM encrypted JSON.
+type localStore struct {
+	path string
+	key  [32]byte
+}
+
+func (s localStore) view(fn func(localState) error) error {
+	localStoreMu.Lock()
+	defer localStoreMu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	return fn(state)
+}
+
+func (s localStore) update(fn func(localState) error) error {
+	localStoreMu.Lock()
+	defer localStoreMu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(state); err != nil {
+		return err
+	}
+	return s.save(state)
+}
+
+func (s localStore) load() (localState, error) {
+	state := localState{Projects: map[string]*localProject{}}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("local provider read error: %w", err)
+	}
+
+	gcm, err := s.gcm()
+	if err != nil {
+		return state, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return state, errors.New("local provider store is corrupt")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return state, fmt.Errorf("local provider decrypt error: %w", err)
+	}
+
+	if err := json.Unmarshal(plaintext, &state); err != nil {
+		return state, fmt.Errorf("local provider decode error: %w", err)
+	}
+
+	if state.Projects == nil {
+		state.Projects = map[string]*localProject{}
+	}
+	return state, nil
+}
+
+func (s localStore) save(state localState) error {
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("local provider encode error: %w", err)
+	}
+
+	gcm, err := s.gcm()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename so a failed write never leaves a
+	// truncated store behind.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("local provider write error: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(gcm.Seal(nonce, nonce, plaintext, nil)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("local provider write error: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("local provider write error: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s localStore) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func hashLocalSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (l LocalProvider) isAdmin() bool {
+	return l.roleID == authorizationKeyAdmin
+}
+
+func (l LocalProvider) CreateProject(name string, tokenTTL time.Duration) (types.Token, error) {
+	if !l.isAdmin() {
+		return types.Token{}, errors.New("admin credentials must be used to create project")
+	}
+
+	err := l.store.update(func(state localState) error {
+		if _, ok := state.Projects[name]; ok {
+			return fmt.Errorf("project %s already exists", name)
+		}
+
+		state.Projects[name] = &localProject{
+			RoleID:  uuid.New().String(),
+			Targets: map[string]types.Target{},
+			Tokens:  map[string]localToken{},
+		}
+		return nil
+	})
+	if err != nil {
+		return types.Token{}, err
+	}
+
+	return l.CreateToken(name, nil, types.ProjectPolicy{}, tokenTTL)
+}
+
+// CreateToken creates a token of the project. The policy is not used, the
+// local provider does not issue credentials.
+func (l LocalProvider) CreateToken(name string, scopes types.TokenScopes, _ types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+	token := types.Token{}
+
+	if ttl <= 0 {
+		ttl = localSecretTTL
+	}
+
+	if !l.isAdmin() {
+		return token, errors.New("admin credentials must be used to create token")
+	}
+
+	err := l.store.update(func(state localState) error {
+		p, ok := state.Projects[name]
+		if !ok {
+			return ErrNotFound
+		}
+
+		now := time.Now()
+		token = types.Token{
+			CreatedAt:    now.Format(time.RFC3339Nano),
+			ExpiresAt:    now.Add(ttl).Format(time.RFC3339Nano),
+			ProjectID:    name,
+			ProjectToken: types.ProjectToken{ID: uuid.New().String()},
+			RoleID:       p.RoleID,
+			Scopes:       scopes,
+			Secret:       uuid.New().String(),
+		}
+
+		lt := localToken{
+			CreatedAt:  token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Scopes:     scopes,
+			SecretHash: hashLocalSecret(token.Secret),
+		}
+		if len(scopes) > 0 {
+			lt.RoleID = uuid.New().String()
+			token.RoleID = lt.RoleID
+		}
+		p.Tokens[token.ProjectToken.ID] = lt
+		return nil
+	})
+
+	return token, err
+}
+
+func (l LocalProvider) CreateTarget(projectName string, target types.Target) error {
+	if !l.isAdmin() {
+		return errors.New("admin credentials must be used to create target")
+	}
+	return l.writeTarget(projectName, target)
+}
+
+func (l LocalProvider) UpdateProjectPolicy(projectName string, policy types.ProjectPolicy, _ []types.TokenScopes) error {
+	if !l.isAdmin() {
+		return errors.New("admin credentials must be used to update project policy")
+	}
+
+	return l.store.update(func(state localState) error {
+		p, ok := state.Projects[projectName]
+		if !ok {
+			return ErrNotFound
+		}
+		p.Policy = policy
+		return nil
+	})
+}
+
+func (l LocalProvider) UpdateTarget(projectName string, target types.Target) error {
+	if !l.isAdmin() {
+		return errors.New("admin credentials must be used to update target")
+	}
+	return l.writeTarget(projectName, target)
+}
+
+func (l LocalProvider) writeTarget(projectName string, target types.Target) error {
+	return l.store.update(func(state localState) error {
+		p, ok := state.Projects[projectName]
+		if !ok {
+			return ErrNotFound
+		}
+		p.Targets[target.Name] = target
+		return nil
+	})
+}
+
+func (l LocalProvider) DeleteProject(name string) error {
+	if !l.isAdmin() {
+		return errors.New("admin credentials must be used to delete project")
+	}
+
+	return l.store.update(func(state localState) error {
+		delete(state.Projects, name)
+		return nil
+	})
+}
+
+func (l LocalProvider) DeleteTarget(projectName, targetName string) error {
+	if !l.isAdmin() {
+		return errors.New("admin credentials must be used to delete target")
+	}
+
+	return l.store.update(func(state localState) error {
+		if p, ok := state.Projects[projectName]; ok {
+			delete(p.Targets, targetName)
+		}
+		return nil
+	})
+}
+
+func (l LocalProvider) GetProject(projectName string) (responses.GetProject, error) {
+	err := l.store.view(func(state localState) error {
+		if _, ok := state.Projects[projectName]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return responses.GetProject{}, err
+	}
+
+	return responses.GetProject{Name: projectName}, nil
+}
+
+func (l LocalProvider) GetTarget(projectName, targetName string) (types.Target, error) {
+	if !l.isAdmin() {
+		return types.Target{}, errors.New("admin credentials must be used to get target information")
+	}
+
+	var target types.Target
+	err := l.store.view(func(state localState) error {
+		p, ok := state.Projects[projectName]
+		if !ok {
+			return ErrTargetNotFound
+		}
+
+		t, ok := p.Targets[targetName]
+		if !ok {
+			return ErrTargetNotFound
+		}
+
+		target = t
+		return nil
+	})
+
+	return target, err
+}
+
+// GetToken validates the project credentials and returns either the
+// configured static token or a newly generated one.
+func (l LocalProvider) GetToken() (string, error) {
+	if l.isAdmin() {
+		return "", errors.New("admin credentials cannot be used to get tokens")
+	}
+
+	err := l.store.view(func(state localState) error {
+		secretHash := hashLocalSecret(l.secretID)
+		now := time.Now()
+
+		for _, p := range state.Projects {
+			for _, t := range p.Tokens {
+				roleID := t.RoleID
+				if roleID == "" {
+					roleID = p.RoleID
+				}
+
+				if roleID != l.roleID {
+					continue
+				}
+
+				if subtle.ConstantTimeCompare([]byte(t.SecretHash), []byte(secretHash)) != 1 {
+					continue
+				}
+
+				expiresAt, err := time.Parse(time.RFC3339Nano, t.ExpiresAt)
+				if err != nil || now.After(expiresAt) {
+					return errors.New("project token has expired")
+				}
+				return nil
+			}
+		}
+
+		return errors.New("invalid project credentials")
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if l.token != "" {
+		return l.token, nil
+	}
+
+	token, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	return "local." + token, nil
+}
+
+// VerifyProjectToken checks the secret of a token of the project, whose role
+// is either the project's or its own. Scoped tokens have their own role, so
+// the roles of deleted tokens are unknown rather than of another project.
+func (l LocalProvider) VerifyProjectToken(projectName, roleID, secretID string) error {
+	if !l.isAdmin() {
+		return errors.New("admin credentials must be used to verify project tokens")
+	}
+
+	return l.store.view(func(state localState) error {
+		secretHash := hashLocalSecret(secretID)
+		now := time.Now()
+
+		for name, p := range state.Projects {
+			for _, t := range p.Tokens {
+				tokenRoleID := t.RoleID
+				if tokenRoleID == "" {
+					tokenRoleID = p.RoleID
+				}
+				if tokenRoleID != roleID {
+					continue
+				}
+
+				if name != projectName {
+					return ErrNotFound
+				}
+
+				if subtle.ConstantTimeCompare([]byte(t.SecretHash), []byte(secretHash)) != 1 {
+					continue
+				}
+
+				expiresAt, err := time.Parse(time.RFC3339Nano, t.ExpiresAt)
+				if err != nil || now.After(expiresAt) {
+					return ErrInvalidProjectToken
+				}
+				return nil
+			}
+		}
+
+		return ErrInvalidProjectToken
+	})
+}
+
+func (l LocalProvider) DeleteProjectToken(projectName, tokenID string) error {
+	if !l.isAdmin() {
+		return errors.New("admin credentials must be used to delete tokens")
+	}
+
+	return l.store.update(func(state localState) error {
+		if p, ok := state.Projects[projectName]; ok {
+			delete(p.Tokens, tokenID)
+		}
+		return nil
+	})
+}
+
+func (l LocalProvider) GetProjectToken(projectName, tokenID string) (types.ProjectToken, error) {
+	if !l.isAdmin() {
+		return types.ProjectToken{}, errors.New("admin credentials must be used to get tokens")
+	}
+
+	var createdAt string
+	err := l.store.view(func(state localState) error {
+		p, ok := state.Projects[projectName]
+		if !ok {
+			return ErrProjectTokenNotFound
+		}
+
+		t, ok := p.Tokens[tokenID]
+		if !ok {
+			return ErrProjectTokenNotFound
+		}
+		createdAt = t.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return types.ProjectToken{}, err
+	}
+
+	return types.ProjectToken{ID: tokenID, CreatedAt: createdAt}, nil
+}
+
+func (l LocalProvider) ListTargets(project string) ([]string, error) {
+	if !l.isAdmin() {
+		return nil, errors.New("admin credentials must be used to list targets")
+	}
+
+	// allow empty array to render json as []
+	list := make([]string, 0)
+	err := l.store.view(func(state localState) error {
+		if p, ok := state.Projects[project]; ok {
+			for name := range p.Targets {
+				list = append(list, name)
+			}
+		}
+		return nil
+	})
+	sort.Strings(list)
+
+	return list, err
+}
+
+func (l LocalProvider) ListProjects() ([]string, error) {
+	if !l.isAdmin() {
+		return nil, errors.New("admin credentials must be used to list projects")
+	}
+
+	// allow empty array to render json as []
+	list := make([]string, 0)
+	err := l.store.view(func(state localState) error {
+		for name := range state.Projects {
+			list = append(list, name)
+		}
+		return nil
+	})
+	sort.Strings(list)
+
+	return list, err
+}
+
+func (l LocalProvider) ListProjectTokens(projectName string) ([]string, error) {
+	if !l.isAdmin() {
+		return nil, errors.New("admin credentials must be used to list tokens")
+	}
+
+	// allow empty array to render json as []
+	list := make([]string, 0)
+	err := l.store.view(func(state localState) error {
+		if p, ok := state.Projects[projectName]; ok {
+			for id := range p.Tokens {
+				list = append(list, id)
+			}
+		}
+		return nil
+	})
+	sort.Strings(list)
+
+	return list, err
+}
+
+func (l LocalProvider) ProjectExists(name string) (bool, error) {
+	_, err := l.GetProject(name)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// TargetExists does not require admin credentials as it is used when project
+// tokens create workflows.
+func (l LocalProvider) TargetExists(projectName, targetName string) (bool, error) {
+	var exists bool
+	err := l.store.view(func(state localState) error {
+		if p, ok := state.Projects[projectName]; ok {
+			_, exists = p.Targets[targetName]
+		}
+		return nil
+	})
+
+	return exists, err
+}
@@ -0,0 +1,251 @@
+package credentials
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/env"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// #nosec
+const testLocalKey = "aeMai9ooceeG2aiw"
+
+func newTestLocalProvider(t *testing.T, file, key, secret, token string) Provider {
+	t.Helper()
+
+	cp, err := NewLocalProvider(
+		Authorization{Provider: ProviderLocal, Key: key, Secret: secret},
+		env.Vars{LocalProviderFile: file, LocalProviderKey: testLocalKey, LocalProviderToken: token},
+		nil, nil, nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cp
+}
+
+var testLocalTarget = types.Target{
+	Name: "target1",
+	Type: "aws_account",
+	Properties: types.TargetProperties{
+		CredentialType: "assumed_role",
+		RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+	},
+}
+
+func TestLocalProviderLifecycle(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cello.enc")
+	admin := newTestLocalProvider(t, file, authorizationKeyAdmin, "", "")
+
+	exists, err := admin.ProjectExists("project1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	token, err := admin.CreateProject("project1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "project1", token.ProjectID)
+	assert.NotEmpty(t, token.RoleID)
+	assert.NotEmpty(t, token.Secret)
+	assert.NotEmpty(t, token.ProjectToken.ID)
+
+	assert.NoError(t, admin.VerifyProjectToken("project1", token.RoleID, token.Secret))
+
+	exists, err = admin.ProjectExists("project1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// An existing project is not overwritten.
+	_, err = admin.CreateProject("project1", 0)
+	assert.EqualError(t, err, "project project1 already exists")
+
+	assert.NoError(t, admin.VerifyProjectToken("project1", token.RoleID, token.Secret))
+
+	assert.NoError(t, admin.CreateTarget("project1", testLocalTarget))
+
+	target, err := admin.GetTarget("project1", "target1")
+	assert.NoError(t, err)
+	assert.Equal(t, testLocalTarget, target)
+
+	targets, err := admin.ListTargets("project1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"target1"}, targets)
+
+	projects, err := admin.ListProjects()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"project1"}, projects)
+
+	tokens, err := admin.ListProjectTokens("project1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{token.ProjectToken.ID}, tokens)
+
+	projectToken, err := admin.GetProjectToken("project1", token.ProjectToken.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.ProjectToken{ID: token.ProjectToken.ID, CreatedAt: token.CreatedAt}, projectToken)
+
+	user := newTestLocalProvider(t, file, token.RoleID, token.Secret, "")
+	credentialsToken, err := user.GetToken()
+	assert.NoError(t, err)
+	assert.Contains(t, credentialsToken, "local.")
+
+	exists, err = user.TargetExists("project1", "target1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, admin.DeleteProjectToken("project1", token.ProjectToken.ID))
+	_, err = admin.GetProjectToken("project1", token.ProjectToken.ID)
+	assert.True(t, errors.Is(err, ErrProjectTokenNotFound))
+
+	_, err = user.GetToken()
+	assert.Error(t, err)
+
+	assert.NoError(t, admin.DeleteTarget("project1", "target1"))
+	_, err = admin.GetTarget("project1", "target1")
+	assert.True(t, errors.Is(err, ErrTargetNotFound))
+
+	assert.NoError(t, admin.DeleteProject("project1"))
+	exists, err = admin.ProjectExists("project1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLocalProviderScopedToken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cello.enc")
+	admin := newTestLocalProvider(t, file, authorizationKeyAdmin, "", "")
+
+	projectToken, err := admin.CreateProject("project1", 0)
+	assert.NoError(t, err)
+
+	scopes := types.TokenScopes{{Target: "target1", Operations: []string{"diff"}}}
+	token, err := admin.CreateToken("project1", scopes, types.ProjectPolicy{}, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, scopes, token.Scopes)
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, token.ExpiresAt)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+	assert.NotEqual(t, projectToken.RoleID, token.RoleID)
+
+	_, err = newTestLocalProvider(t, file, token.RoleID, token.Secret, "").GetToken()
+	assert.NoError(t, err)
+
+	// The secret is only valid for the role of the scoped token.
+	_, err = newTestLocalProvider(t, file, projectToken.RoleID, token.Secret, "").GetToken()
+	assert.Error(t, err)
+
+	assert.NoError(t, admin.VerifyProjectToken("project1", token.RoleID, token.Secret))
+	assert.ErrorIs(t, admin.VerifyProjectToken("project1", projectToken.RoleID, token.Secret), ErrInvalidProjectToken)
+
+	_, err = admin.CreateProject("project2", 0)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, admin.VerifyProjectToken("project2", token.RoleID, token.Secret), ErrNotFound)
+
+	// Deleted tokens are unknown.
+	assert.NoError(t, admin.DeleteProjectToken("project1", token.ProjectToken.ID))
+	assert.ErrorIs(t, admin.VerifyProjectToken("project1", token.RoleID, token.Secret), ErrInvalidProjectToken)
+}
+
+func TestLocalProviderGetToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		admin       bool
+		badSecret   bool
+		staticToken string
+		wantToken   string
+		errResult   bool
+	}{
+		{
+			name: "generated token",
+		},
+		{
+			name:        "static token",
+			staticToken: "static-token",
+			wantToken:   "static-token",
+		},
+		{
+			name:      "invalid secret",
+			badSecret: true,
+			errResult: true,
+		},
+		{
+			name:      "admin cannot get token",
+			admin:     true,
+			errResult: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "cello.enc")
+			token, err := newTestLocalProvider(t, file, authorizationKeyAdmin, "", "").CreateProject("project1", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			key, secret := token.RoleID, token.Secret
+			if tt.admin {
+				key = authorizationKeyAdmin
+			}
+			if tt.badSecret {
+				secret = "bad-secret"
+			}
+
+			got, err := newTestLocalProvider(t, file, key, secret, tt.staticToken).GetToken()
+			if err != nil {
+				if !tt.errResult {
+					t.Errorf("\ndid not expect error, got: %v", err)
+				}
+				return
+			}
+
+			if tt.errResult {
+				t.Errorf("\nexpected error")
+			}
+			if tt.wantToken != "" {
+				assert.Equal(t, tt.wantToken, got)
+			} else {
+				assert.Len(t, got, len("local.")+32)
+			}
+		})
+	}
+}
+
+func TestLocalProviderRequiresAdmin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cello.enc")
+	cp := newTestLocalProvider(t, file, TestRole, "secret", "")
+
+	_, err := cp.CreateProject("project1", 0)
+	assert.Error(t, err)
+	_, err = cp.CreateToken("project1", nil, types.ProjectPolicy{}, 0)
+	assert.Error(t, err)
+	assert.Error(t, cp.CreateTarget("project1", testLocalTarget))
+	assert.Error(t, cp.UpdateTarget("project1", testLocalTarget))
+	assert.Error(t, cp.DeleteProject("project1"))
+	assert.Error(t, cp.DeleteTarget("project1", "target1"))
+	_, err = cp.GetTarget("project1", "target1")
+	assert.Error(t, err)
+	_, err = cp.ListTargets("project1")
+	assert.Error(t, err)
+	assert.Error(t, cp.VerifyProjectToken("project1", TestRole, "secret"))
+}
+
+func TestLocalProviderWrongKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cello.enc")
+	if _, err := newTestLocalProvider(t, file, authorizationKeyAdmin, "", "").CreateProject("project1", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := NewLocalProvider(
+		Authorization{Provider: ProviderLocal, Key: authorizationKeyAdmin},
+		env.Vars{LocalProviderFile: file, LocalProviderKey: "a-different-key!"},
+		nil, nil, nil,
+	)
+	assert.NoError(t, err)
+
+	_, err = cp.ProjectExists("project1")
+	assert.Error(t, err)
+}
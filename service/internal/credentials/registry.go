@@ -0,0 +1,83 @@
+package credentials
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/cello-proj/cello/service/internal/env"
+)
+
+// ProviderFactory creates a Provider for an authorization. The Vault config
+// and service functions are only used by the Vault provider.
+type ProviderFactory func(a Authorization, env env.Vars, h http.Header, vaultConfigFn VaultConfigFn, vaultSvcFn VaultSvcFn) (Provider, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+func init() {
+	RegisterProvider(ProviderVault, NewVaultProvider)
+	RegisterProvider(ProviderLocal, NewLocalProvider)
+}
+
+// Names of the built in providers.
+const (
+	ProviderLocal = "local"
+	ProviderVault = "vault"
+)
+
+// RegisterProvider makes a provider available under the provided name. It
+// panics if a provider is already registered with the same name.
+func RegisterProvider(name string, f ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("credentials provider '%s' already registered", name))
+	}
+	providers[name] = f
+}
+
+// IsRegisteredProvider returns whether a provider is registered with the
+// provided name.
+func IsRegisteredProvider(name string) bool {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	_, ok := providers[name]
+	return ok
+}
+
+// RegisteredProviders returns the sorted names of all registered providers.
+func RegisteredProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider returns the provider configured for the service. The provider
+// in the authorization must match the configured provider.
+func NewProvider(a Authorization, env env.Vars, h http.Header, vaultConfigFn VaultConfigFn, vaultSvcFn VaultSvcFn) (Provider, error) {
+	if a.Provider != env.CredentialsProvider {
+		return nil, fmt.Errorf("authorization provider '%s' does not match configured provider '%s'", a.Provider, env.CredentialsProvider)
+	}
+
+	providersMu.RLock()
+	f, ok := providers[env.CredentialsProvider]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown credentials provider '%s'", env.CredentialsProvider)
+	}
+
+	return f(a, env, h, vaultConfigFn, vaultSvcFn)
+}
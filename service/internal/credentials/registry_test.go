@@ -0,0 +1,89 @@
+package credentials
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/cello-proj/cello/service/internal/env"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		authProvider string
+		envProvider  string
+		want         Provider
+		errResult    bool
+	}{
+		{
+			name:         "local provider",
+			authProvider: ProviderLocal,
+			envProvider:  ProviderLocal,
+			want:         &LocalProvider{},
+		},
+		{
+			name:         "authorization does not match configured provider",
+			authProvider: ProviderVault,
+			envProvider:  ProviderLocal,
+			errResult:    true,
+		},
+		{
+			name:         "unknown provider",
+			authProvider: "unknown",
+			envProvider:  "unknown",
+			errResult:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp, err := NewProvider(
+				Authorization{Provider: tt.authProvider, Key: authorizationKeyAdmin, Secret: "secret"},
+				env.Vars{
+					CredentialsProvider: tt.envProvider,
+					LocalProviderFile:   filepath.Join(t.TempDir(), "cello.enc"),
+					LocalProviderKey:    testLocalKey,
+				},
+				http.Header{}, NewVaultConfig, NewVaultSvc,
+			)
+			if err != nil {
+				if !tt.errResult {
+					t.Errorf("\ndid not expect error, got: %v", err)
+				}
+				return
+			}
+
+			if tt.errResult {
+				t.Errorf("\nexpected error")
+			}
+			assert.IsType(t, tt.want, cp)
+		})
+	}
+}
+
+func TestAuthorizationValidateProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		configured string
+		expectErr  bool
+	}{
+		{name: "vault", provider: ProviderVault, configured: ProviderVault},
+		{name: "local", provider: ProviderLocal, configured: ProviderLocal},
+		{name: "registered but not configured", provider: ProviderVault, configured: ProviderLocal, expectErr: true},
+		{name: "unknown", provider: "unknown", configured: "unknown", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Authorization{Provider: tt.provider, Key: "key", Secret: "secret"}
+			err := a.Validate(a.ValidateProvider(tt.configured))
+			if err != nil != tt.expectErr {
+				t.Errorf("\nwant error: %v\n got error: %v", tt.expectErr, err != nil)
+			}
+		})
+	}
+}
@@ -3,10 +3,15 @@
 package credentials
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/cello-proj/cello/internal/responses"
 	"github.com/cello-proj/cello/internal/types"
@@ -22,20 +27,32 @@ const (
 
 // Provider defines the interface required by providers.
 type Provider interface {
-	CreateProject(string) (types.Token, error)
+	CreateProject(string, time.Duration) (types.Token, error)
 	CreateTarget(string, types.Target) error
-	CreateToken(string) (types.Token, error)
+	// CreateToken creates a token of the project. Scoped tokens can only read
+	// the targets of their scopes the project policy allows.
+	CreateToken(string, types.TokenScopes, types.ProjectPolicy, time.Duration) (types.Token, error)
 	UpdateTarget(string, types.Target) error
 	DeleteProject(string) error
 	DeleteTarget(string, string) error
 	GetProject(string) (responses.GetProject, error)
 	GetTarget(string, string) (types.Target, error)
 	GetToken() (string, error)
+	// VerifyProjectToken checks the role ID and secret of a token of the
+	// project without authenticating with them. It returns ErrNotFound when
+	// the role ID is not the project's, and ErrInvalidProjectToken when the
+	// secret is unknown or expired.
+	VerifyProjectToken(string, string, string) error
 	DeleteProjectToken(string, string) error
 	GetProjectToken(string, string) (types.ProjectToken, error)
+	ListProjects() ([]string, error)
+	ListProjectTokens(string) ([]string, error)
 	ListTargets(string) ([]string, error)
 	ProjectExists(string) (bool, error)
 	TargetExists(string, string) (bool, error)
+	// UpdateProjectPolicy replaces the policy of the project and of its
+	// scoped tokens, whose scopes are given.
+	UpdateProjectPolicy(string, types.ProjectPolicy, []types.TokenScopes) error
 }
 
 type vaultLogical interface {
@@ -47,7 +64,10 @@ type vaultLogical interface {
 
 type vaultSys interface {
 	DeletePolicy(name string) error
+	ListMounts() (map[string]*vault.MountOutput, error)
+	Mount(path string, mountInfo *vault.MountInput) error
 	PutPolicy(name, rules string) error
+	Unmount(path string) error
 }
 
 // Vault
@@ -63,6 +83,9 @@ var (
 	ErrTargetNotFound = errors.New("target not found")
 	// ErrProjectTokenNotFound conveys that the token was not found.
 	ErrProjectTokenNotFound = errors.New("project token not found")
+	// ErrInvalidProjectToken conveys that the secret of a project token is
+	// unknown or expired.
+	ErrInvalidProjectToken = errors.New("invalid project token")
 )
 
 type VaultProvider struct {
@@ -74,7 +97,12 @@ type VaultProvider struct {
 
 // NewVaultProvider returns a new VaultProvider
 func NewVaultProvider(a Authorization, env env.Vars, h http.Header, vaultConfigFn VaultConfigFn, vaultSvcFn VaultSvcFn) (Provider, error) {
-	config := vaultConfigFn(&vault.Config{Address: env.VaultAddress}, env.VaultRole, env.VaultSecret)
+	auth, err := NewVaultAuth(env)
+	if err != nil {
+		return nil, err
+	}
+
+	config := vaultConfigFn(&vault.Config{Address: env.VaultAddress}, auth)
 	svc, err := vaultSvcFn(*config, h)
 	if err != nil {
 		return nil, err
@@ -89,18 +117,16 @@ func NewVaultProvider(a Authorization, env env.Vars, h http.Header, vaultConfigF
 
 type VaultConfig struct {
 	config *vault.Config
-	role   string
-	secret string
+	auth   VaultAuth
 }
 
-type VaultConfigFn func(config *vault.Config, role, secret string) *VaultConfig
+type VaultConfigFn func(config *vault.Config, auth VaultAuth) *VaultConfig
 
 // NewVaultConfig returns a new VaultConfig.
-func NewVaultConfig(config *vault.Config, role, secret string) *VaultConfig {
+func NewVaultConfig(config *vault.Config, auth VaultAuth) *VaultConfig {
 	return &VaultConfig{
 		config: config,
-		role:   role,
-		secret: secret,
+		auth:   auth,
 	}
 }
 
@@ -117,17 +143,12 @@ func NewVaultSvc(c VaultConfig, h http.Header) (*vault.Client, error) {
 
 	vaultSvc.SetHeaders(h)
 
-	options := map[string]interface{}{
-		"role_id":   c.role,
-		"secret_id": c.secret,
-	}
-
-	sec, err := vaultSvc.Logical().Write("auth/approle/login", options)
+	token, err := c.auth.Login(vaultSvc.Logical())
 	if err != nil {
 		return nil, err
 	}
 
-	vaultSvc.SetToken(sec.Auth.ClientToken)
+	vaultSvc.SetToken(token)
 	return vaultSvc, nil
 }
 
@@ -138,11 +159,27 @@ type Authorization struct {
 	Secret   string `valid:"required"`
 }
 
+// AdminAuthorization returns the admin authorization of the service, used
+// for work done by the service itself rather than on behalf of a request.
+func AdminAuthorization(provider, adminSecret string) Authorization {
+	return Authorization{
+		Provider: provider,
+		Key:      authorizationKeyAdmin,
+		Secret:   adminSecret,
+	}
+}
+
+// IsAdmin returns whether the Authorization claims to be the admin, the
+// secret is checked by ValidateAuthorizedAdmin.
+func (a Authorization) IsAdmin() bool {
+	return a.Key == authorizationKeyAdmin
+}
+
 func (a Authorization) Validate(optionalValidations ...func() error) error {
 	v := []func() error{
 		func() error {
-			if a.Provider != "vault" {
-				return errors.New("provider must be vault")
+			if !IsRegisteredProvider(a.Provider) {
+				return fmt.Errorf("provider must be one of '%s'", strings.Join(RegisteredProviders(), " "))
 			}
 			return nil
 		},
@@ -154,6 +191,18 @@ func (a Authorization) Validate(optionalValidations ...func() error) error {
 	return validations.Validate(v...)
 }
 
+// ValidateProvider determines if the Authorization is for the configured
+// provider.
+// Optional validation should be passed as parameter to Validate().
+func (a Authorization) ValidateProvider(provider string) func() error {
+	return func() error {
+		if a.Provider != provider {
+			return fmt.Errorf("provider must be %s", provider)
+		}
+		return nil
+	}
+}
+
 // ValidateAuthorizedAdmin determines if the Authorization is valid and an admin.
 // TODO See if this can be removed when refactoring auth.
 // Optional validation should be passed as parameter to Validate().
@@ -193,24 +242,52 @@ func genProjectAppRole(name string) string {
 	return fmt.Sprintf("%s/%s-%s", vaultAppRolePrefix, vaultProjectPrefix, name)
 }
 
-func (v VaultProvider) CreateToken(name string) (types.Token, error) {
+// scopeAppRolePrefix prefixes the names of the AppRoles dedicated to the
+// token scopes of a project. Project names are alphanumeric, so the prefix
+// cannot match the AppRoles of another project.
+func scopeAppRolePrefix(projectName string) string {
+	return projectName + "-scope-"
+}
+
+// scopeAppRoleName returns the name, as passed to genProjectAppRole, of the
+// AppRole dedicated to the scopes. Tokens with equal scopes share the AppRole.
+func scopeAppRoleName(projectName string, scopes types.TokenScopes) string {
+	data, _ := json.Marshal(scopes.Normalize())
+	sum := sha256.Sum256(data)
+	return scopeAppRolePrefix(projectName) + hex.EncodeToString(sum[:8])
+}
+
+// CreateToken creates a token for the project. Scoped tokens are created for
+// the AppRole dedicated to their scopes, whose policy only allows reading
+// credentials for the scoped targets the project policy allows. The operation
+// types are enforced by the service. The token expires after ttl, or the
+// secret ID TTL of the AppRole when zero.
+func (v VaultProvider) CreateToken(name string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
 	token := types.Token{}
 
 	if !v.isAdmin() {
 		return token, errors.New("admin credentials must be used to create token")
 	}
 
-	secret, err := v.generateSecrets(name)
+	appRoleName := name
+	if len(scopes) > 0 {
+		appRoleName = scopeAppRoleName(name, scopes)
+		if err := v.writeScopeState(name, appRoleName, scopeVaultPolicy(name, policy, scopes)); err != nil {
+			return token, err
+		}
+	}
+
+	secret, err := v.generateSecrets(appRoleName, ttl)
 	if err != nil {
 		return token, err
 	}
 
-	roleID, err := v.readRoleID(name)
+	roleID, err := v.readRoleID(appRoleName)
 	if err != nil {
 		return token, err
 	}
 
-	accessor, err := v.readSecretIDAccessor(name, secret.Data["secret_id_accessor"].(string))
+	accessor, err := v.readSecretIDAccessor(appRoleName, secret.Data["secret_id_accessor"].(string))
 	if err != nil {
 		return token, err
 	}
@@ -219,19 +296,47 @@ func (v VaultProvider) CreateToken(name string) (types.Token, error) {
 	token.Secret = secret.Data["secret_id"].(string)
 	token.ProjectToken.ID = secret.Data["secret_id_accessor"].(string)
 	token.RoleID = roleID
+	token.Scopes = scopes
 	token.CreatedAt = accessor.Data["creation_time"].(string)
 	token.ExpiresAt = accessor.Data["expiration_time"].(string)
 
 	return token, nil
 }
 
-func (v VaultProvider) CreateProject(name string) (types.Token, error) {
+// writeScopeState creates or updates the policy and AppRole dedicated to
+// scopes.
+func (v VaultProvider) writeScopeState(projectName, appRoleName, policy string) error {
+	if err := v.createPolicyState(appRoleName, policy); err != nil {
+		return err
+	}
+	return v.writeProjectState(appRoleName)
+}
+
+// scopeAppRoles returns the names, as passed to genProjectAppRole, of the
+// AppRoles dedicated to the token scopes of the project.
+func (v VaultProvider) scopeAppRoles(projectName string) ([]string, error) {
+	keys, err := v.listKeys(vaultAppRolePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("%s-%s", vaultProjectPrefix, scopeAppRolePrefix(projectName))
+	roles := []string{}
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			roles = append(roles, strings.TrimPrefix(key, vaultProjectPrefix+"-"))
+		}
+	}
+	return roles, nil
+}
+
+func (v VaultProvider) CreateProject(name string, tokenTTL time.Duration) (types.Token, error) {
 	token := types.Token{}
 	if !v.isAdmin() {
 		return token, errors.New("admin credentials must be used to create project")
 	}
 
-	policy := defaultVaultReadonlyPolicyAWS(name)
+	policy := defaultVaultReadonlyPolicy(name)
 	err := v.createPolicyState(name, policy)
 	if err != nil {
 		return token, err
@@ -241,7 +346,7 @@ func (v VaultProvider) CreateProject(name string) (types.Token, error) {
 		return token, err
 	}
 
-	return v.CreateToken(name)
+	return v.CreateToken(name, nil, types.ProjectPolicy{}, tokenTTL)
 }
 
 // CreateTarget creates a target for the project.
@@ -252,23 +357,76 @@ func (v VaultProvider) CreateTarget(projectName string, target types.Target) err
 		return errors.New("admin credentials must be used to create target")
 	}
 
-	options := map[string]interface{}{
-		"credential_type": target.Properties.CredentialType,
-		"policy_arns":     target.Properties.PolicyArns,
-		"policy_document": target.Properties.PolicyDocument,
-		"role_arns":       target.Properties.RoleArn,
+	return v.writeTarget(projectName, target)
+}
+
+// writeTarget writes the target to the secrets engine for its type.
+func (v VaultProvider) writeTarget(projectName string, target types.Target) error {
+	b, err := vaultTargetBackendFor(target.Type)
+	if err != nil {
+		return err
 	}
 
-	path := fmt.Sprintf("aws/roles/%s-%s-target-%s", vaultProjectPrefix, projectName, target.Name)
-	_, err := v.vaultLogicalSvc.Write(path, options)
-	return err
+	return b.write(v, projectName, target)
+}
+
+func genTargetName(projectName, targetName string) string {
+	return fmt.Sprintf("%s-%s-target-%s", vaultProjectPrefix, projectName, targetName)
+}
+
+// defaultVaultReadonlyPolicy returns the policy for a project, allowing it to
+// read credentials for its targets of any type.
+func defaultVaultReadonlyPolicy(projectName string) string {
+	return projectVaultPolicy(projectName, types.ProjectPolicy{})
+}
+
+// projectVaultPolicy returns the policy for a project generated from its
+// policy template. It allows reading credentials for the targets in the
+// template, or all targets when there are none, followed by the extra policy.
+func projectVaultPolicy(projectName string, policy types.ProjectPolicy) string {
+	targets := policy.Targets
+	if len(targets) == 0 {
+		targets = []string{""}
+	}
+	return vaultPolicy(projectName, targets, policy.ExtraPolicy)
+}
+
+// scopeVaultPolicy returns the policy of the AppRole dedicated to the scopes.
+// It allows reading credentials for the scoped targets the project policy
+// allows, followed by the project's extra policy.
+func scopeVaultPolicy(projectName string, policy types.ProjectPolicy, scopes types.TokenScopes) string {
+	targets := []string{}
+	for _, target := range scopes.Targets() {
+		if policy.AllowsTarget(target) {
+			targets = append(targets, target)
+		}
+	}
+	return vaultPolicy(projectName, targets, policy.ExtraPolicy)
 }
 
-func defaultVaultReadonlyPolicyAWS(projectName string) string {
-	return fmt.Sprintf(
-		"path \"aws/sts/argo-cloudops-projects-%s-target-*\" { capabilities = [\"read\"] }",
-		projectName,
-	)
+// emptyVaultPolicy is the policy of AppRoles which are not allowed to read
+// anything, as Vault does not accept policies without rules.
+const emptyVaultPolicy = "# No paths are allowed."
+
+// vaultPolicy returns a policy allowing reading credentials for the targets,
+// where an empty target is every target of the project, followed by the extra
+// policy.
+func vaultPolicy(projectName string, targets []string, extraPolicy string) string {
+	policies := []string{}
+	for _, target := range targets {
+		for _, t := range types.TargetTypes {
+			policies = append(policies, vaultTargetBackends[t].readonlyPolicy(projectName, target))
+		}
+	}
+
+	if extra := strings.TrimSpace(extraPolicy); extra != "" {
+		policies = append(policies, extra)
+	}
+
+	if len(policies) == 0 {
+		return emptyVaultPolicy
+	}
+	return strings.Join(policies, "\n")
 }
 
 func (v VaultProvider) deletePolicyState(name string) error {
@@ -288,6 +446,21 @@ func (v VaultProvider) DeleteProject(name string) error {
 	if _, err = v.vaultLogicalSvc.Delete(genProjectAppRole(name)); err != nil {
 		return fmt.Errorf("vault delete project error: %w", err)
 	}
+
+	scopeRoles, err := v.scopeAppRoles(name)
+	if err != nil {
+		return fmt.Errorf("vault delete project error: %w", err)
+	}
+
+	for _, role := range scopeRoles {
+		if err := v.deletePolicyState(role); err != nil {
+			return fmt.Errorf("vault delete project error: %w", err)
+		}
+
+		if _, err = v.vaultLogicalSvc.Delete(genProjectAppRole(role)); err != nil {
+			return fmt.Errorf("vault delete project error: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -296,9 +469,15 @@ func (v VaultProvider) DeleteTarget(projectName string, targetName string) error
 		return errors.New("admin credentials must be used to delete target")
 	}
 
-	path := fmt.Sprintf("aws/roles/%s-%s-target-%s", vaultProjectPrefix, projectName, targetName)
-	_, err := v.vaultLogicalSvc.Delete(path)
-	return err
+	_, b, err := v.findTarget(projectName, targetName)
+	if errors.Is(err, ErrTargetNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return b.delete(v, projectName, targetName)
 }
 
 const (
@@ -321,49 +500,72 @@ func (v VaultProvider) GetProject(projectName string) (responses.GetProject, err
 	return responses.GetProject{Name: projectName}, nil
 }
 
-func (v VaultProvider) GetTarget(projectName, targetName string) (types.Target, error) {
+// VerifyProjectToken looks up the secret ID in the AppRole of the project,
+// or of its scopes, whose role ID is the token's. The secret ID is not used
+// to log in, which would issue a Vault token.
+func (v VaultProvider) VerifyProjectToken(projectName, roleID, secretID string) error {
 	if !v.isAdmin() {
-		return types.Target{}, errors.New("admin credentials must be used to get target information")
+		return errors.New("admin credentials must be used to verify project tokens")
 	}
 
-	sec, err := v.vaultLogicalSvc.Read(fmt.Sprintf("aws/roles/argo-cloudops-projects-%s-target-%s", projectName, targetName))
+	scopeRoles, err := v.scopeAppRoles(projectName)
 	if err != nil {
-		return types.Target{}, fmt.Errorf("vault get target error: %w", err)
+		return fmt.Errorf("vault list scope roles error: %w", err)
 	}
 
-	if sec == nil {
-		return types.Target{}, ErrTargetNotFound
+	for _, role := range append([]string{projectName}, scopeRoles...) {
+		sec, err := v.vaultLogicalSvc.Read(fmt.Sprintf("%s/role-id", genProjectAppRole(role)))
+		if err != nil {
+			return fmt.Errorf("vault get role ID error: %w", err)
+		}
+		if sec == nil {
+			continue
+		}
+		if id, _ := sec.Data["role_id"].(string); id != roleID {
+			continue
+		}
+
+		return v.lookupSecretID(role, secretID)
 	}
+	return ErrNotFound
+}
 
-	// These should always exist.
-	roleArn := sec.Data["role_arns"].([]interface{})[0].(string)
-	credentialType := sec.Data["credential_type"].(string)
+// lookupSecretID checks the secret ID exists in the AppRole and has not
+// expired.
+func (v VaultProvider) lookupSecretID(appRoleName, secretID string) error {
+	options := map[string]interface{}{
+		"secret_id": secretID,
+	}
 
-	// Optional.
-	policies := []string{}
-	if val, ok := sec.Data["policy_arns"]; ok {
-		for _, v := range val.([]interface{}) {
-			policies = append(policies, v.(string))
+	sec, err := v.vaultLogicalSvc.Write(fmt.Sprintf("%s/secret-id/lookup", genProjectAppRole(appRoleName)), options)
+	if err != nil {
+		// Vault >= 1.9.0 responds 404 to unknown secret IDs, older versions
+		// respond without content.
+		var respErr *vault.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return ErrInvalidProjectToken
 		}
+		return fmt.Errorf("vault lookup secret ID error: %w", err)
+	}
+	if sec == nil || sec.Data == nil {
+		return ErrInvalidProjectToken
 	}
 
-	// Optional.
-	var policyDocument string
-	if val, ok := sec.Data["policy_document"]; ok {
-		policyDocument = val.(string)
+	// Secret IDs without a TTL have a zero expiration time.
+	expiration, _ := sec.Data["expiration_time"].(string)
+	if t, err := time.Parse(time.RFC3339Nano, expiration); err == nil && !t.IsZero() && time.Now().After(t) {
+		return ErrInvalidProjectToken
 	}
+	return nil
+}
 
-	return types.Target{
-		Name: targetName,
-		// target 'Type' always 'aws_account', currently not stored in Vault
-		Type: "aws_account",
-		Properties: types.TargetProperties{
-			CredentialType: credentialType,
-			PolicyArns:     policies,
-			PolicyDocument: policyDocument,
-			RoleArn:        roleArn,
-		},
-	}, nil
+func (v VaultProvider) GetTarget(projectName, targetName string) (types.Target, error) {
+	if !v.isAdmin() {
+		return types.Target{}, errors.New("admin credentials must be used to get target information")
+	}
+
+	target, _, err := v.findTarget(projectName, targetName)
+	return target, err
 }
 
 func (v VaultProvider) DeleteProjectToken(projectName, tokenID string) error {
@@ -377,11 +579,25 @@ func (v VaultProvider) DeleteProjectToken(projectName, tokenID string) error {
 
 	path := fmt.Sprintf("%s/secret-id-accessor/destroy", genProjectAppRole(projectName))
 	_, err := v.vaultLogicalSvc.Write(path, data)
-	if err != nil {
+	if err == nil || isSecretIDAccessorExists(err) {
 		return err
 	}
 
-	return nil
+	// Scoped tokens belong to the AppRole of their scopes.
+	scopeRoles, lerr := v.scopeAppRoles(projectName)
+	if lerr != nil {
+		return lerr
+	}
+
+	for _, role := range scopeRoles {
+		path := fmt.Sprintf("%s/secret-id-accessor/destroy", genProjectAppRole(role))
+		_, err = v.vaultLogicalSvc.Write(path, data)
+		if err == nil || isSecretIDAccessorExists(err) {
+			return err
+		}
+	}
+
+	return err
 }
 
 func (v VaultProvider) GetProjectToken(projectName, tokenID string) (types.ProjectToken, error) {
@@ -391,12 +607,21 @@ func (v VaultProvider) GetProjectToken(projectName, tokenID string) (types.Proje
 		return token, errors.New("admin credentials must be used to delete tokens")
 	}
 
-	data := map[string]interface{}{
-		"secret_id_accessor": tokenID,
-	}
+	projectToken, err := v.readSecretIDAccessor(projectName, tokenID)
+	if err != nil && !isSecretIDAccessorExists(err) {
+		// Scoped tokens belong to the AppRole of their scopes.
+		scopeRoles, lerr := v.scopeAppRoles(projectName)
+		if lerr != nil {
+			return token, fmt.Errorf("vault get secret ID accessor error: %w", lerr)
+		}
 
-	path := fmt.Sprintf("%s/secret-id-accessor/lookup", genProjectAppRole(projectName))
-	projectToken, err := v.vaultLogicalSvc.Write(path, data)
+		for _, role := range scopeRoles {
+			projectToken, err = v.readSecretIDAccessor(role, tokenID)
+			if err == nil || isSecretIDAccessorExists(err) {
+				break
+			}
+		}
+	}
 	if err != nil {
 		if !isSecretIDAccessorExists(err) {
 			return token, ErrProjectTokenNotFound
@@ -408,8 +633,10 @@ func (v VaultProvider) GetProjectToken(projectName, tokenID string) (types.Proje
 		return token, nil
 	}
 
+	createdAt, _ := projectToken.Data["creation_time"].(string)
 	return types.ProjectToken{
-		ID: projectToken.Data["secret_id_accessor"].(string),
+		ID:        projectToken.Data["secret_id_accessor"].(string),
+		CreatedAt: createdAt,
 	}, nil
 }
 
@@ -442,22 +669,81 @@ func (v VaultProvider) ListTargets(project string) ([]string, error) {
 		return nil, errors.New("admin credentials must be used to list targets")
 	}
 
-	sec, err := v.vaultLogicalSvc.List("aws/roles/")
+	// allow empty array to render json as []
+	list := make([]string, 0)
+	seen := map[string]bool{}
+	prefix := fmt.Sprintf("argo-cloudops-projects-%s-target-", project)
+
+	for _, t := range types.TargetTypes {
+		names, err := vaultTargetBackends[t].list(v)
+		if err != nil {
+			return nil, fmt.Errorf("vault list error: %w", err)
+		}
+
+		for _, value := range names {
+			if strings.HasPrefix(value, prefix) {
+				name := strings.Replace(value, prefix, "", 1)
+				if !seen[name] {
+					seen[name] = true
+					list = append(list, name)
+				}
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// ListProjects returns the names of all projects, from their AppRoles.
+func (v VaultProvider) ListProjects() ([]string, error) {
+	if !v.isAdmin() {
+		return nil, errors.New("admin credentials must be used to list projects")
+	}
+
+	keys, err := v.listKeys(vaultAppRolePrefix)
 	if err != nil {
 		return nil, fmt.Errorf("vault list error: %w", err)
 	}
 
 	// allow empty array to render json as []
 	list := make([]string, 0)
-	if sec != nil {
-		for _, target := range sec.Data["keys"].([]interface{}) {
-			value := target.(string)
-			prefix := fmt.Sprintf("argo-cloudops-projects-%s-target-", project)
-			if strings.HasPrefix(value, prefix) {
-				list = append(list, strings.Replace(value, prefix, "", 1))
-			}
+	prefix := vaultProjectPrefix + "-"
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, prefix)
+		// Project names are alphanumeric, AppRoles with a dash in their name
+		// belong to token scopes.
+		if name == key || strings.Contains(name, "-") {
+			continue
 		}
+		list = append(list, name)
 	}
+	sort.Strings(list)
+
+	return list, nil
+}
+
+// ListProjectTokens returns the IDs of all tokens of the project, which are
+// the secret ID accessors of the project's AppRole and of its scope AppRoles.
+func (v VaultProvider) ListProjectTokens(projectName string) ([]string, error) {
+	if !v.isAdmin() {
+		return nil, errors.New("admin credentials must be used to list tokens")
+	}
+
+	scopeRoles, err := v.scopeAppRoles(projectName)
+	if err != nil {
+		return nil, fmt.Errorf("vault list error: %w", err)
+	}
+
+	// allow empty array to render json as []
+	list := make([]string, 0)
+	for _, role := range append([]string{projectName}, scopeRoles...) {
+		accessors, err := v.listKeys(fmt.Sprintf("%s/secret-id", genProjectAppRole(role)))
+		if err != nil {
+			return nil, fmt.Errorf("vault list secret ID accessors error: %w", err)
+		}
+		list = append(list, accessors...)
+	}
+	sort.Strings(list)
 
 	return list, nil
 }
@@ -495,10 +781,13 @@ func (v VaultProvider) readSecretIDAccessor(appRoleName, accessor string) (*vaul
 	return secret, nil
 }
 
-func (v VaultProvider) generateSecrets(appRoleName string) (*vault.Secret, error) {
+func (v VaultProvider) generateSecrets(appRoleName string, ttl time.Duration) (*vault.Secret, error) {
 	options := map[string]interface{}{
 		"force": true,
 	}
+	if ttl > 0 {
+		options["ttl"] = fmt.Sprintf("%ds", int64(ttl.Seconds()))
+	}
 
 	secret, err := v.vaultLogicalSvc.Write(fmt.Sprintf("%s/secret-id", genProjectAppRole(appRoleName)), options)
 	if err != nil {
@@ -512,22 +801,49 @@ func (v VaultProvider) TargetExists(projectName, targetName string) (bool, error
 	return !errors.Is(err, ErrTargetNotFound), nil
 }
 
+// UpdateProjectPolicy replaces the Vault policy of the project with one
+// generated from the policy template, and the policies of its scope AppRoles
+// so scoped tokens cannot read targets the project no longer allows. Scope
+// AppRoles whose scopes are not given are not allowed to read anything.
+func (v VaultProvider) UpdateProjectPolicy(projectName string, policy types.ProjectPolicy, scopes []types.TokenScopes) error {
+	if !v.isAdmin() {
+		return errors.New("admin credentials must be used to update project policy")
+	}
+
+	if err := v.createPolicyState(projectName, projectVaultPolicy(projectName, policy)); err != nil {
+		return fmt.Errorf("vault update project policy error: %w", err)
+	}
+
+	scopePolicies := map[string]string{}
+	for _, s := range scopes {
+		scopePolicies[scopeAppRoleName(projectName, s)] = scopeVaultPolicy(projectName, policy, s)
+	}
+
+	scopeRoles, err := v.scopeAppRoles(projectName)
+	if err != nil {
+		return fmt.Errorf("vault update project policy error: %w", err)
+	}
+
+	for _, role := range scopeRoles {
+		rules, ok := scopePolicies[role]
+		if !ok {
+			rules = emptyVaultPolicy
+		}
+
+		if err := v.createPolicyState(role, rules); err != nil {
+			return fmt.Errorf("vault update project policy error: %w", err)
+		}
+	}
+	return nil
+}
+
 // UpdateTarget updates a targets policies for the project.
 func (v VaultProvider) UpdateTarget(projectName string, target types.Target) error {
 	if !v.isAdmin() {
 		return errors.New("admin credentials must be used to update target")
 	}
 
-	options := map[string]interface{}{
-		"credential_type": target.Properties.CredentialType,
-		"policy_arns":     target.Properties.PolicyArns,
-		"policy_document": target.Properties.PolicyDocument,
-		"role_arns":       target.Properties.RoleArn,
-	}
-
-	path := fmt.Sprintf("aws/roles/%s-%s-target-%s", vaultProjectPrefix, projectName, target.Name)
-	_, err := v.vaultLogicalSvc.Write(path, options)
-	return err
+	return v.writeTarget(projectName, target)
 }
 
 func (v VaultProvider) writeProjectState(name string) error {
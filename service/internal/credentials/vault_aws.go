@@ -0,0 +1,154 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cello-proj/cello/internal/types"
+)
+
+// awsTargetBackend stores aws_account targets as roles in the Vault AWS
+// secrets engine.
+type awsTargetBackend struct{}
+
+func awsTargetPath(projectName, targetName string) string {
+	return fmt.Sprintf("aws/roles/%s", genTargetName(projectName, targetName))
+}
+
+func (awsTargetBackend) read(v VaultProvider, projectName, targetName string) (types.Target, error) {
+	sec, err := v.vaultLogicalSvc.Read(awsTargetPath(projectName, targetName))
+	if err != nil {
+		return types.Target{}, err
+	}
+
+	if sec == nil {
+		return types.Target{}, ErrTargetNotFound
+	}
+
+	credentialType, _ := sec.Data["credential_type"].(string)
+
+	// A single role is returned as role_arn for compatibility with targets
+	// created before role_arns was supported.
+	var roleArn string
+	roleArns := stringSlice(sec.Data["role_arns"])
+	switch len(roleArns) {
+	case 0:
+		roleArns = nil
+	case 1:
+		roleArn, roleArns = roleArns[0], nil
+	}
+
+	// Optional.
+	policies := []string{}
+	policies = append(policies, stringSlice(sec.Data["policy_arns"])...)
+
+	// Optional.
+	policyDocument, _ := sec.Data["policy_document"].(string)
+	externalID, _ := sec.Data["external_id"].(string)
+
+	return types.Target{
+		Name: targetName,
+		Type: types.TargetTypeAWSAccount,
+		Properties: types.TargetProperties{
+			CredentialType:  credentialType,
+			ExternalID:      externalID,
+			PolicyArns:      policies,
+			PolicyDocument:  policyDocument,
+			RoleArn:         roleArn,
+			RoleArns:        roleArns,
+			SessionDuration: secondsValue(sec.Data["default_sts_ttl"]),
+			SessionTags:     awsSessionTags(sec.Data["session_tags"]),
+		},
+	}, nil
+}
+
+// write writes the role. Vault rejects STS settings for credential types
+// which do not use STS, so they are only sent when they apply.
+func (awsTargetBackend) write(v VaultProvider, projectName string, target types.Target) error {
+	properties := target.Properties
+	options := map[string]interface{}{
+		"credential_type": properties.CredentialType,
+		"policy_arns":     properties.PolicyArns,
+		"policy_document": properties.PolicyDocument,
+	}
+
+	switch properties.CredentialType {
+	case types.AWSCredentialTypeAssumedRole:
+		tags := make([]string, 0, len(properties.SessionTags))
+		for key, value := range properties.SessionTags {
+			tags = append(tags, fmt.Sprintf("%s=%s", key, value))
+		}
+		sort.Strings(tags)
+
+		options["external_id"] = properties.ExternalID
+		options["role_arns"] = properties.AllRoleArns()
+		options["session_tags"] = tags
+		fallthrough
+	case types.AWSCredentialTypeFederationToken:
+		options["default_sts_ttl"] = properties.SessionDuration
+		options["max_sts_ttl"] = properties.SessionDuration
+	}
+
+	_, err := v.vaultLogicalSvc.Write(awsTargetPath(projectName, target.Name), options)
+	return err
+}
+
+func (awsTargetBackend) delete(v VaultProvider, projectName, targetName string) error {
+	_, err := v.vaultLogicalSvc.Delete(awsTargetPath(projectName, targetName))
+	return err
+}
+
+func (awsTargetBackend) list(v VaultProvider) ([]string, error) {
+	return v.listKeys("aws/roles/")
+}
+
+// readonlyPolicy allows reading STS credentials for assumed_role and
+// federation_token targets and IAM user credentials for iam_user targets.
+func (awsTargetBackend) readonlyPolicy(projectName, targetName string) string {
+	return fmt.Sprintf(
+		"path \"%s\" { capabilities = [\"read\"] }\n"+
+			"path \"%s\" { capabilities = [\"read\"] }",
+		targetPolicyPath("aws/sts/", projectName, targetName, ""),
+		targetPolicyPath("aws/creds/", projectName, targetName, ""),
+	)
+}
+
+// awsSessionTags converts the session tags of a Vault AWS role, which are
+// either a map or a list of key=value pairs.
+func awsSessionTags(val interface{}) map[string]string {
+	tags := map[string]string{}
+
+	switch t := val.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			tags[key], _ = value.(string)
+		}
+	case []interface{}:
+		for _, pair := range stringSlice(t) {
+			if key, value, ok := strings.Cut(pair, "="); ok {
+				tags[key] = value
+			}
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// secondsValue converts a TTL in seconds returned by Vault to an int.
+func secondsValue(val interface{}) int {
+	switch n := val.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+package credentials
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAWSRolePath = "aws/roles/argo-cloudops-projects-testProject-target-testTarget"
+
+func TestVaultCreateTargetAWS(t *testing.T) {
+	tests := []struct {
+		name        string
+		properties  types.TargetProperties
+		wantOptions map[string]interface{}
+	}{
+		{
+			name: "assumed role",
+			properties: types.TargetProperties{
+				CredentialType:  "assumed_role",
+				ExternalID:      "cello-external-id",
+				RoleArns:        []string{"arn:aws:iam::012345678901:role/test-role-1", "arn:aws:iam::012345678901:role/test-role-2"},
+				SessionDuration: 3600,
+				SessionTags:     map[string]string{"team": "platform", "cost-center": "1234"},
+			},
+			wantOptions: map[string]interface{}{
+				"credential_type": "assumed_role",
+				"default_sts_ttl": 3600,
+				"external_id":     "cello-external-id",
+				"max_sts_ttl":     3600,
+				"policy_arns":     []string(nil),
+				"policy_document": "",
+				"role_arns":       []string{"arn:aws:iam::012345678901:role/test-role-1", "arn:aws:iam::012345678901:role/test-role-2"},
+				"session_tags":    []string{"cost-center=1234", "team=platform"},
+			},
+		},
+		{
+			name: "federation token",
+			properties: types.TargetProperties{
+				CredentialType: "federation_token",
+				PolicyArns:     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+			},
+			wantOptions: map[string]interface{}{
+				"credential_type": "federation_token",
+				"default_sts_ttl": 0,
+				"max_sts_ttl":     0,
+				"policy_arns":     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+				"policy_document": "",
+			},
+		},
+		{
+			name: "iam user",
+			properties: types.TargetProperties{
+				CredentialType: "iam_user",
+				PolicyArns:     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+			},
+			wantOptions: map[string]interface{}{
+				"credential_type": "iam_user",
+				"policy_arns":     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+				"policy_document": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockVaultLogicalPaths(nil)
+			v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: m}
+
+			err := v.CreateTarget("testProject", types.Target{Name: "testTarget", Type: "aws_account", Properties: tt.properties})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]map[string]interface{}{testAWSRolePath: tt.wantOptions}, m.writes)
+		})
+	}
+}
+
+func TestVaultGetTargetAWS(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want types.TargetProperties
+	}{
+		{
+			name: "single role",
+			data: map[string]interface{}{
+				"credential_type": "assumed_role",
+				"role_arns":       []interface{}{"arn:aws:iam::012345678901:role/test-role"},
+			},
+			want: types.TargetProperties{
+				CredentialType: "assumed_role",
+				PolicyArns:     []string{},
+				RoleArn:        "arn:aws:iam::012345678901:role/test-role",
+			},
+		},
+		{
+			name: "multiple roles with sts settings",
+			data: map[string]interface{}{
+				"credential_type": "assumed_role",
+				"default_sts_ttl": json.Number("3600"),
+				"external_id":     "cello-external-id",
+				"role_arns":       []interface{}{"arn:aws:iam::012345678901:role/test-role-1", "arn:aws:iam::012345678901:role/test-role-2"},
+				"session_tags":    map[string]interface{}{"team": "platform"},
+			},
+			want: types.TargetProperties{
+				CredentialType:  "assumed_role",
+				ExternalID:      "cello-external-id",
+				PolicyArns:      []string{},
+				RoleArns:        []string{"arn:aws:iam::012345678901:role/test-role-1", "arn:aws:iam::012345678901:role/test-role-2"},
+				SessionDuration: 3600,
+				SessionTags:     map[string]string{"team": "platform"},
+			},
+		},
+		{
+			name: "iam user",
+			data: map[string]interface{}{
+				"credential_type": "iam_user",
+				"default_sts_ttl": json.Number("0"),
+				"policy_arns":     []interface{}{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+				"role_arns":       []interface{}{},
+			},
+			want: types.TargetProperties{
+				CredentialType: "iam_user",
+				PolicyArns:     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockVaultLogicalPaths(map[string]map[string]interface{}{testAWSRolePath: tt.data})
+			v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: m}
+
+			got, err := v.GetTarget("testProject", "testTarget")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Properties)
+			assert.NoError(t, got.Validate())
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+const (
+	// vaultAzureMountPrefix prefixes the Vault Azure secrets engine mount of
+	// each azure_subscription target. Each target has its own mount as the
+	// tenant and subscription are part of the engine configuration.
+	vaultAzureMountPrefix = "azure-"
+	// vaultAzureRole is the role created in the mount of each
+	// azure_subscription target.
+	vaultAzureRole = "target"
+)
+
+// azureTargetBackend stores azure_subscription targets in a Vault Azure
+// secrets engine mounted per target.
+type azureTargetBackend struct{}
+
+func azureTargetMount(projectName, targetName string) string {
+	return vaultAzureMountPrefix + genTargetName(projectName, targetName)
+}
+
+func (azureTargetBackend) read(v VaultProvider, projectName, targetName string) (types.Target, error) {
+	mount := azureTargetMount(projectName, targetName)
+
+	mounts, err := v.secretsEngineMounts("azure")
+	if err != nil {
+		return types.Target{}, err
+	}
+
+	if !mounts[mount] {
+		return types.Target{}, ErrTargetNotFound
+	}
+
+	config, err := v.vaultLogicalSvc.Read(mount + "/config")
+	if err != nil {
+		return types.Target{}, err
+	}
+
+	role, err := v.vaultLogicalSvc.Read(fmt.Sprintf("%s/roles/%s", mount, vaultAzureRole))
+	if err != nil {
+		return types.Target{}, err
+	}
+
+	properties := types.TargetProperties{
+		CredentialType: types.AzureCredentialTypeServicePrincipal,
+	}
+
+	if config != nil {
+		properties.ClientID, _ = config.Data["client_id"].(string)
+		properties.SubscriptionID, _ = config.Data["subscription_id"].(string)
+		properties.TenantID, _ = config.Data["tenant_id"].(string)
+	}
+
+	if role != nil {
+		properties.RoleAssignments = azureRoleAssignmentsFromSecret(role)
+	}
+
+	return types.Target{
+		Name:       targetName,
+		Type:       types.TargetTypeAzureSubscription,
+		Properties: properties,
+	}, nil
+}
+
+// write mounts the secrets engine when needed, then writes its configuration
+// and role. Vault's client secret is not part of the target, it is expected
+// to come from the environment Vault runs in (for example a managed
+// identity).
+func (azureTargetBackend) write(v VaultProvider, projectName string, target types.Target) error {
+	mount := azureTargetMount(projectName, target.Name)
+
+	mounts, err := v.secretsEngineMounts("azure")
+	if err != nil {
+		return err
+	}
+
+	if !mounts[mount] {
+		err := v.vaultSysSvc.Mount(mount, &vault.MountInput{
+			Type:        "azure",
+			Description: fmt.Sprintf("cello project %s target %s", projectName, target.Name),
+		})
+		if err != nil {
+			return fmt.Errorf("vault mount error: %w", err)
+		}
+	}
+
+	config := map[string]interface{}{
+		"client_id":       target.Properties.ClientID,
+		"subscription_id": target.Properties.SubscriptionID,
+		"tenant_id":       target.Properties.TenantID,
+	}
+	if _, err := v.vaultLogicalSvc.Write(mount+"/config", config); err != nil {
+		return err
+	}
+
+	// Marshalling role assignments cannot fail.
+	roles, _ := json.Marshal(target.Properties.RoleAssignments)
+	role := map[string]interface{}{
+		"azure_roles": string(roles),
+	}
+	_, err = v.vaultLogicalSvc.Write(fmt.Sprintf("%s/roles/%s", mount, vaultAzureRole), role)
+	return err
+}
+
+// delete unmounts the secrets engine, which revokes its credentials.
+func (azureTargetBackend) delete(v VaultProvider, projectName, targetName string) error {
+	mount := azureTargetMount(projectName, targetName)
+
+	mounts, err := v.secretsEngineMounts("azure")
+	if err != nil {
+		return err
+	}
+
+	if !mounts[mount] {
+		return nil
+	}
+
+	if err := v.vaultSysSvc.Unmount(mount); err != nil {
+		return fmt.Errorf("vault unmount error: %w", err)
+	}
+	return nil
+}
+
+func (azureTargetBackend) list(v VaultProvider) ([]string, error) {
+	mounts, err := v.secretsEngineMounts("azure")
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{}
+	for mount := range mounts {
+		if strings.HasPrefix(mount, vaultAzureMountPrefix+vaultProjectPrefix) {
+			list = append(list, strings.TrimPrefix(mount, vaultAzureMountPrefix))
+		}
+	}
+	return list, nil
+}
+
+func (azureTargetBackend) readonlyPolicy(projectName, targetName string) string {
+	return fmt.Sprintf(
+		"path \"%s\" { capabilities = [\"read\"] }",
+		targetPolicyPath(vaultAzureMountPrefix, projectName, targetName, "/*"),
+	)
+}
+
+// azureRoleAssignmentsFromSecret converts the roles of a Vault Azure role to
+// role assignments. Vault resolves role names to IDs and back, the name is
+// kept when both are present.
+func azureRoleAssignmentsFromSecret(sec *vault.Secret) []types.AzureRoleAssignment {
+	roles, ok := sec.Data["azure_roles"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	assignments := make([]types.AzureRoleAssignment, 0, len(roles))
+	for _, r := range roles {
+		role, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var ra types.AzureRoleAssignment
+		ra.Scope, _ = role["scope"].(string)
+		ra.RoleName, _ = role["role_name"].(string)
+		if ra.RoleName == "" {
+			ra.RoleID, _ = role["role_id"].(string)
+		}
+		assignments = append(assignments, ra)
+	}
+	return assignments
+}
@@ -0,0 +1,112 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAzureMount = "azure-argo-cloudops-projects-testProject-target-testTarget"
+	testAzureScope = "/subscriptions/8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e/resourceGroups/cello"
+)
+
+var testAzureTarget = types.Target{
+	Name: "testTarget",
+	Type: "azure_subscription",
+	Properties: types.TargetProperties{
+		CredentialType: "service_principal",
+		ClientID:       "2b4f6e8a-1c3d-4e5f-8a9b-0c1d2e3f4a5b",
+		SubscriptionID: "8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e",
+		TenantID:       "72f988bf-86f1-41af-91ab-2d7cd011db47",
+		RoleAssignments: []types.AzureRoleAssignment{
+			{RoleName: "Contributor", Scope: testAzureScope},
+		},
+	},
+}
+
+func TestVaultCreateTargetAzure(t *testing.T) {
+	tests := []struct {
+		name   string
+		mounts []string
+	}{
+		{
+			name: "mounts secrets engine",
+		},
+		{
+			name:   "existing mount",
+			mounts: []string{testAzureMount},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logical := newMockVaultLogicalPaths(nil)
+			sys := newMockVaultSysMounts("azure", tt.mounts...)
+			v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: logical, vaultSysSvc: sys}
+
+			assert.NoError(t, v.CreateTarget("testProject", testAzureTarget))
+			assert.Contains(t, sys.mounted, testAzureMount+"/")
+			assert.Equal(t, map[string]map[string]interface{}{
+				testAzureMount + "/config": {
+					"client_id":       "2b4f6e8a-1c3d-4e5f-8a9b-0c1d2e3f4a5b",
+					"subscription_id": "8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e",
+					"tenant_id":       "72f988bf-86f1-41af-91ab-2d7cd011db47",
+				},
+				testAzureMount + "/roles/target": {
+					"azure_roles": `[{"role_name":"Contributor","scope":"` + testAzureScope + `"}]`,
+				},
+			}, logical.writes)
+		})
+	}
+}
+
+func TestVaultGetTargetAzure(t *testing.T) {
+	logical := newMockVaultLogicalPaths(map[string]map[string]interface{}{
+		testAzureMount + "/config": {
+			"client_id":       "2b4f6e8a-1c3d-4e5f-8a9b-0c1d2e3f4a5b",
+			"environment":     "",
+			"subscription_id": "8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e",
+			"tenant_id":       "72f988bf-86f1-41af-91ab-2d7cd011db47",
+		},
+		testAzureMount + "/roles/target": {
+			"azure_roles": []interface{}{
+				map[string]interface{}{
+					"role_id":   "/subscriptions/8d1e47a4-40b1-4f3b-9a8a-6f1a3d2c5b7e/providers/Microsoft.Authorization/roleDefinitions/b24988ac-6180-42a0-ab88-20f7382dd24c",
+					"role_name": "Contributor",
+					"scope":     testAzureScope,
+				},
+			},
+		},
+	})
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: logical, vaultSysSvc: newMockVaultSysMounts("azure", testAzureMount)}
+
+	got, err := v.GetTarget("testProject", "testTarget")
+	assert.NoError(t, err)
+	assert.Equal(t, testAzureTarget, got)
+	assert.NoError(t, got.Validate())
+}
+
+func TestVaultDeleteTargetAzure(t *testing.T) {
+	sys := newMockVaultSysMounts("azure", testAzureMount)
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: newMockVaultLogicalPaths(nil), vaultSysSvc: sys}
+
+	assert.NoError(t, v.DeleteTarget("testProject", "testTarget"))
+	assert.NotContains(t, sys.mounted, testAzureMount+"/")
+}
+
+func TestVaultListTargetsAzure(t *testing.T) {
+	sys := newMockVaultSysMounts(
+		"azure",
+		testAzureMount,
+		"azure-argo-cloudops-projects-other-target-testTarget",
+		"azure",
+	)
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: newMockVaultLogicalPaths(nil), vaultSysSvc: sys}
+
+	targets, err := v.ListTargets("testProject")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testTarget"}, targets)
+}
@@ -0,0 +1,191 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// vaultGCPMount is the path the Vault GCP secrets engine is mounted at.
+const vaultGCPMount = "gcp"
+
+// gcpServiceAccountProject extracts the project from a user managed service
+// account email.
+var gcpServiceAccountProject = regexp.MustCompile(`@([a-z][a-z0-9-]{4,28}[a-z0-9])\.iam\.gserviceaccount\.com$`)
+
+// gcpTargetBackend stores gcp_project targets as rolesets or impersonated
+// accounts in the Vault GCP secrets engine, depending on the credential type.
+type gcpTargetBackend struct{}
+
+// gcpTargetPath returns the Vault path for a gcp_project target of the
+// credential type.
+func gcpTargetPath(projectName, targetName, credentialType string) string {
+	kind := "roleset"
+	if credentialType == types.GCPCredentialTypeImpersonatedAccount {
+		kind = "impersonated-account"
+	}
+	return fmt.Sprintf("%s/%s/%s", vaultGCPMount, kind, genTargetName(projectName, targetName))
+}
+
+var gcpCredentialTypes = []string{types.GCPCredentialTypeRoleset, types.GCPCredentialTypeImpersonatedAccount}
+
+func (gcpTargetBackend) read(v VaultProvider, projectName, targetName string) (types.Target, error) {
+	for _, credentialType := range gcpCredentialTypes {
+		sec, err := v.vaultLogicalSvc.Read(gcpTargetPath(projectName, targetName, credentialType))
+		if err != nil {
+			return types.Target{}, err
+		}
+
+		if sec != nil {
+			return gcpTargetFromSecret(targetName, credentialType, sec), nil
+		}
+	}
+
+	return types.Target{}, ErrTargetNotFound
+}
+
+// write writes the target. Rolesets have Vault manage a service account with
+// the provided bindings, impersonated accounts use an existing service
+// account.
+func (b gcpTargetBackend) write(v VaultProvider, projectName string, target types.Target) error {
+	path := gcpTargetPath(projectName, target.Name, target.Properties.CredentialType)
+	options := map[string]interface{}{
+		"service_account_email": target.Properties.ServiceAccountEmail,
+		"token_scopes":          target.Properties.TokenScopes,
+	}
+
+	if target.Properties.CredentialType == types.GCPCredentialTypeRoleset {
+		options = map[string]interface{}{
+			"bindings":     gcpBindings(target.Properties.Bindings),
+			"project":      target.Properties.ProjectID,
+			"secret_type":  "access_token",
+			"token_scopes": target.Properties.TokenScopes,
+		}
+	}
+
+	// Remove the target if it is stored under a different credential type.
+	existing, err := b.read(v, projectName, target.Name)
+	if err != nil && !errors.Is(err, ErrTargetNotFound) {
+		return err
+	}
+	if err == nil && existing.Properties.CredentialType != target.Properties.CredentialType {
+		if err := b.delete(v, projectName, target.Name); err != nil {
+			return err
+		}
+	}
+
+	_, err = v.vaultLogicalSvc.Write(path, options)
+	return err
+}
+
+func (gcpTargetBackend) delete(v VaultProvider, projectName, targetName string) error {
+	for _, credentialType := range gcpCredentialTypes {
+		path := gcpTargetPath(projectName, targetName, credentialType)
+
+		sec, err := v.vaultLogicalSvc.Read(path)
+		if err != nil {
+			return err
+		}
+
+		if sec != nil {
+			if _, err := v.vaultLogicalSvc.Delete(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (gcpTargetBackend) list(v VaultProvider) ([]string, error) {
+	rolesets, err := v.listKeys(vaultGCPMount + "/rolesets/")
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := v.listKeys(vaultGCPMount + "/impersonated-accounts/")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(rolesets, accounts...), nil
+}
+
+func (gcpTargetBackend) readonlyPolicy(projectName, targetName string) string {
+	return fmt.Sprintf(
+		"path \"%s\" { capabilities = [\"read\"] }\n"+
+			"path \"%s\" { capabilities = [\"read\"] }",
+		targetPolicyPath(vaultGCPMount+"/roleset/", projectName, targetName, "/*"),
+		targetPolicyPath(vaultGCPMount+"/impersonated-account/", projectName, targetName, "/*"),
+	)
+}
+
+// gcpBindings renders the bindings in the JSON form accepted by the Vault GCP
+// secrets engine.
+func gcpBindings(bindings map[string][]string) string {
+	resources := map[string]map[string][]string{}
+	for resource, roles := range bindings {
+		resources[resource] = map[string][]string{"roles": roles}
+	}
+
+	// Marshalling a map of strings cannot fail.
+	data, _ := json.Marshal(map[string]interface{}{"resource": resources})
+	return string(data)
+}
+
+// gcpTargetFromSecret converts a roleset or impersonated account read from
+// Vault to a target.
+func gcpTargetFromSecret(targetName, credentialType string, sec *vault.Secret) types.Target {
+	properties := types.TargetProperties{
+		CredentialType: credentialType,
+		TokenScopes:    stringSlice(sec.Data["token_scopes"]),
+	}
+
+	if val, ok := sec.Data["service_account_email"].(string); ok {
+		properties.ServiceAccountEmail = val
+	}
+
+	if credentialType == types.GCPCredentialTypeRoleset {
+		// Vault manages the service account for rolesets, it is not part of
+		// the target configuration.
+		properties.ServiceAccountEmail = ""
+
+		if val, ok := sec.Data["project"].(string); ok {
+			properties.ProjectID = val
+		}
+
+		if val, ok := sec.Data["bindings"].(map[string]interface{}); ok {
+			properties.Bindings = map[string][]string{}
+			for resource, roles := range val {
+				properties.Bindings[resource] = stringSlice(roles)
+			}
+		}
+	} else if m := gcpServiceAccountProject.FindStringSubmatch(properties.ServiceAccountEmail); m != nil {
+		properties.ProjectID = m[1]
+	}
+
+	return types.Target{
+		Name:       targetName,
+		Type:       types.TargetTypeGCPProject,
+		Properties: properties,
+	}
+}
+
+func stringSlice(val interface{}) []string {
+	list, ok := val.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	s := make([]string, 0, len(list))
+	for _, v := range list {
+		if str, ok := v.(string); ok {
+			s = append(s, str)
+		}
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testGCPRolesetPath      = "gcp/roleset/argo-cloudops-projects-testProject-target-testTarget"
+	testGCPImpersonatedPath = "gcp/impersonated-account/argo-cloudops-projects-testProject-target-testTarget"
+)
+
+func TestVaultCreateTargetGCP(t *testing.T) {
+	tests := []struct {
+		name        string
+		properties  types.TargetProperties
+		wantPath    string
+		wantOptions map[string]interface{}
+	}{
+		{
+			name: "roleset",
+			properties: types.TargetProperties{
+				CredentialType: "roleset",
+				ProjectID:      "my-project-123",
+				Bindings: map[string][]string{
+					"//cloudresourcemanager.googleapis.com/projects/my-project-123": {"roles/viewer"},
+				},
+				TokenScopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantPath: testGCPRolesetPath,
+			wantOptions: map[string]interface{}{
+				"bindings":     `{"resource":{"//cloudresourcemanager.googleapis.com/projects/my-project-123":{"roles":["roles/viewer"]}}}`,
+				"project":      "my-project-123",
+				"secret_type":  "access_token",
+				"token_scopes": []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+		{
+			name: "impersonated account",
+			properties: types.TargetProperties{
+				CredentialType:      "impersonated_account",
+				ServiceAccountEmail: "deployer@my-project-123.iam.gserviceaccount.com",
+				TokenScopes:         []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+			wantPath: testGCPImpersonatedPath,
+			wantOptions: map[string]interface{}{
+				"service_account_email": "deployer@my-project-123.iam.gserviceaccount.com",
+				"token_scopes":          []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockVaultLogicalPaths(nil)
+			v := VaultProvider{roleID: authorizationKeyAdmin, vaultSysSvc: &mockVaultSys{}, vaultLogicalSvc: m}
+
+			err := v.CreateTarget("testProject", types.Target{Name: "testTarget", Type: "gcp_project", Properties: tt.properties})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]map[string]interface{}{tt.wantPath: tt.wantOptions}, m.writes)
+		})
+	}
+}
+
+func TestVaultGetTargetGCP(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]map[string]interface{}
+		want types.Target
+	}{
+		{
+			name: "roleset",
+			data: map[string]map[string]interface{}{
+				testGCPRolesetPath: {
+					"bindings": map[string]interface{}{
+						"//cloudresourcemanager.googleapis.com/projects/my-project-123": []interface{}{"roles/viewer"},
+					},
+					"project":               "my-project-123",
+					"secret_type":           "access_token",
+					"service_account_email": "vaultargo-cloudops-1234@my-project-123.iam.gserviceaccount.com",
+					"token_scopes":          []interface{}{"https://www.googleapis.com/auth/cloud-platform"},
+				},
+			},
+			want: types.Target{
+				Name: "testTarget",
+				Type: "gcp_project",
+				Properties: types.TargetProperties{
+					CredentialType: "roleset",
+					ProjectID:      "my-project-123",
+					Bindings: map[string][]string{
+						"//cloudresourcemanager.googleapis.com/projects/my-project-123": {"roles/viewer"},
+					},
+					TokenScopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+				},
+			},
+		},
+		{
+			name: "impersonated account",
+			data: map[string]map[string]interface{}{
+				testGCPImpersonatedPath: {
+					"service_account_email": "deployer@my-project-123.iam.gserviceaccount.com",
+					"token_scopes":          []interface{}{"https://www.googleapis.com/auth/cloud-platform"},
+				},
+			},
+			want: types.Target{
+				Name: "testTarget",
+				Type: "gcp_project",
+				Properties: types.TargetProperties{
+					CredentialType:      "impersonated_account",
+					ProjectID:           "my-project-123",
+					ServiceAccountEmail: "deployer@my-project-123.iam.gserviceaccount.com",
+					TokenScopes:         []string{"https://www.googleapis.com/auth/cloud-platform"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := VaultProvider{roleID: authorizationKeyAdmin, vaultSysSvc: &mockVaultSys{}, vaultLogicalSvc: newMockVaultLogicalPaths(tt.data)}
+
+			got, err := v.GetTarget("testProject", "testTarget")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, got.Validate())
+		})
+	}
+}
+
+func TestVaultGetTargetNotFound(t *testing.T) {
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultSysSvc: &mockVaultSys{}, vaultLogicalSvc: newMockVaultLogicalPaths(nil)}
+
+	_, err := v.GetTarget("testProject", "testTarget")
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+}
+
+func TestVaultDeleteTargetGCP(t *testing.T) {
+	m := newMockVaultLogicalPaths(map[string]map[string]interface{}{
+		testGCPImpersonatedPath: {"service_account_email": "deployer@my-project-123.iam.gserviceaccount.com"},
+	})
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultSysSvc: &mockVaultSys{}, vaultLogicalSvc: m}
+
+	assert.NoError(t, v.DeleteTarget("testProject", "testTarget"))
+	assert.Equal(t, []string{testGCPImpersonatedPath}, m.deletes)
+}
+
+func TestVaultListTargetsGCP(t *testing.T) {
+	m := newMockVaultLogicalPaths(map[string]map[string]interface{}{
+		"aws/roles/": {"keys": []interface{}{"argo-cloudops-projects-testProject-target-aws1"}},
+		"gcp/rolesets/": {"keys": []interface{}{
+			"argo-cloudops-projects-testProject-target-gcp1",
+			"argo-cloudops-projects-other-target-gcp2",
+		}},
+		"gcp/impersonated-accounts/": {"keys": []interface{}{"argo-cloudops-projects-testProject-target-gcp3"}},
+	})
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultSysSvc: &mockVaultSys{}, vaultLogicalSvc: m}
+
+	targets, err := v.ListTargets("testProject")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"aws1", "gcp1", "gcp3"}, targets)
+}
@@ -0,0 +1,167 @@
+package credentials
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+const (
+	// vaultKubernetesMountPrefix prefixes the Vault Kubernetes secrets engine
+	// mount of each kubernetes_cluster target. Each target has its own mount
+	// as the cluster is part of the engine configuration.
+	vaultKubernetesMountPrefix = "kubernetes-"
+	// vaultKubernetesRole is the role created in the mount of each
+	// kubernetes_cluster target.
+	vaultKubernetesRole = "target"
+	// vaultKubernetesTokenTTL is the lifetime of the service account tokens
+	// issued for kubernetes_cluster targets.
+	vaultKubernetesTokenTTL = "1h"
+)
+
+// kubernetesTargetBackend stores kubernetes_cluster targets in a Vault
+// Kubernetes secrets engine mounted per target.
+type kubernetesTargetBackend struct{}
+
+func kubernetesTargetMount(projectName, targetName string) string {
+	return vaultKubernetesMountPrefix + genTargetName(projectName, targetName)
+}
+
+func (kubernetesTargetBackend) read(v VaultProvider, projectName, targetName string) (types.Target, error) {
+	mount := kubernetesTargetMount(projectName, targetName)
+
+	mounts, err := v.secretsEngineMounts("kubernetes")
+	if err != nil {
+		return types.Target{}, err
+	}
+
+	if !mounts[mount] {
+		return types.Target{}, ErrTargetNotFound
+	}
+
+	config, err := v.vaultLogicalSvc.Read(mount + "/config")
+	if err != nil {
+		return types.Target{}, err
+	}
+
+	role, err := v.vaultLogicalSvc.Read(fmt.Sprintf("%s/roles/%s", mount, vaultKubernetesRole))
+	if err != nil {
+		return types.Target{}, err
+	}
+
+	var properties types.TargetProperties
+
+	if config != nil {
+		properties.APIServer, _ = config.Data["kubernetes_host"].(string)
+		properties.CACert, _ = config.Data["kubernetes_ca_cert"].(string)
+	}
+
+	if role != nil {
+		properties.AllowedNamespaces = stringSlice(role.Data["allowed_kubernetes_namespaces"])
+		properties.KubernetesRoleName, _ = role.Data["kubernetes_role_name"].(string)
+		properties.ServiceAccountName, _ = role.Data["service_account_name"].(string)
+
+		properties.CredentialType = types.KubernetesCredentialTypeServiceAccount
+		if properties.KubernetesRoleName != "" {
+			properties.CredentialType = types.KubernetesCredentialTypeRole
+			properties.KubernetesRoleType, _ = role.Data["kubernetes_role_type"].(string)
+		}
+	}
+
+	return types.Target{
+		Name:       targetName,
+		Type:       types.TargetTypeKubernetesCluster,
+		Properties: properties,
+	}, nil
+}
+
+// write mounts the secrets engine when needed, then writes its configuration
+// and role. Vault authenticates to the cluster with its own service account
+// token, it is not part of the target.
+func (kubernetesTargetBackend) write(v VaultProvider, projectName string, target types.Target) error {
+	mount := kubernetesTargetMount(projectName, target.Name)
+
+	mounts, err := v.secretsEngineMounts("kubernetes")
+	if err != nil {
+		return err
+	}
+
+	if !mounts[mount] {
+		err := v.vaultSysSvc.Mount(mount, &vault.MountInput{
+			Type:        "kubernetes",
+			Description: fmt.Sprintf("cello project %s target %s", projectName, target.Name),
+		})
+		if err != nil {
+			return fmt.Errorf("vault mount error: %w", err)
+		}
+	}
+
+	config := map[string]interface{}{
+		"kubernetes_ca_cert": target.Properties.CACert,
+		"kubernetes_host":    target.Properties.APIServer,
+	}
+	if _, err := v.vaultLogicalSvc.Write(mount+"/config", config); err != nil {
+		return err
+	}
+
+	role := map[string]interface{}{
+		"allowed_kubernetes_namespaces": target.Properties.AllowedNamespaces,
+		"kubernetes_role_name":          target.Properties.KubernetesRoleName,
+		"kubernetes_role_type":          target.Properties.KubernetesRoleType,
+		"service_account_name":          target.Properties.ServiceAccountName,
+		"token_default_ttl":             vaultKubernetesTokenTTL,
+		"token_max_ttl":                 vaultKubernetesTokenTTL,
+	}
+	_, err = v.vaultLogicalSvc.Write(fmt.Sprintf("%s/roles/%s", mount, vaultKubernetesRole), role)
+	return err
+}
+
+// delete unmounts the secrets engine, which revokes its service account
+// tokens.
+func (kubernetesTargetBackend) delete(v VaultProvider, projectName, targetName string) error {
+	mount := kubernetesTargetMount(projectName, targetName)
+
+	mounts, err := v.secretsEngineMounts("kubernetes")
+	if err != nil {
+		return err
+	}
+
+	if !mounts[mount] {
+		return nil
+	}
+
+	if err := v.vaultSysSvc.Unmount(mount); err != nil {
+		return fmt.Errorf("vault unmount error: %w", err)
+	}
+	return nil
+}
+
+func (kubernetesTargetBackend) list(v VaultProvider) ([]string, error) {
+	mounts, err := v.secretsEngineMounts("kubernetes")
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{}
+	for mount := range mounts {
+		if strings.HasPrefix(mount, vaultKubernetesMountPrefix+vaultProjectPrefix) {
+			list = append(list, strings.TrimPrefix(mount, vaultKubernetesMountPrefix))
+		}
+	}
+	return list, nil
+}
+
+// readonlyPolicy allows reading the cluster configuration and requesting
+// tokens. Credentials are requested with a write, the allowed parameters keep
+// projects from changing the configuration or role.
+func (kubernetesTargetBackend) readonlyPolicy(projectName, targetName string) string {
+	return fmt.Sprintf(`path "%s" {
+  capabilities = ["read", "update"]
+  allowed_parameters = { "kubernetes_namespace" = [] }
+}`,
+		targetPolicyPath(vaultKubernetesMountPrefix, projectName, targetName, "/*"),
+	)
+}
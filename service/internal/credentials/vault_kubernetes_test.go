@@ -0,0 +1,115 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubernetesMount = "kubernetes-argo-cloudops-projects-testProject-target-testTarget"
+
+func TestVaultCreateTargetKubernetes(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties types.TargetProperties
+		wantRole   map[string]interface{}
+	}{
+		{
+			name: "role",
+			properties: types.TargetProperties{
+				CredentialType:     "role",
+				APIServer:          "https://kubernetes.example.com:6443",
+				AllowedNamespaces:  []string{"team-a"},
+				KubernetesRoleName: "edit",
+				KubernetesRoleType: "ClusterRole",
+			},
+			wantRole: map[string]interface{}{
+				"allowed_kubernetes_namespaces": []string{"team-a"},
+				"kubernetes_role_name":          "edit",
+				"kubernetes_role_type":          "ClusterRole",
+				"service_account_name":          "",
+				"token_default_ttl":             "1h",
+				"token_max_ttl":                 "1h",
+			},
+		},
+		{
+			name: "service account",
+			properties: types.TargetProperties{
+				CredentialType:     "service_account",
+				APIServer:          "https://kubernetes.example.com:6443",
+				AllowedNamespaces:  []string{"team-a"},
+				ServiceAccountName: "cello-deployer",
+			},
+			wantRole: map[string]interface{}{
+				"allowed_kubernetes_namespaces": []string{"team-a"},
+				"kubernetes_role_name":          "",
+				"kubernetes_role_type":          "",
+				"service_account_name":          "cello-deployer",
+				"token_default_ttl":             "1h",
+				"token_max_ttl":                 "1h",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logical := newMockVaultLogicalPaths(nil)
+			sys := newMockVaultSysMounts("kubernetes")
+			v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: logical, vaultSysSvc: sys}
+
+			target := types.Target{Name: "testTarget", Type: "kubernetes_cluster", Properties: tt.properties}
+			assert.NoError(t, v.CreateTarget("testProject", target))
+			assert.Contains(t, sys.mounted, testKubernetesMount+"/")
+			assert.Equal(t, map[string]map[string]interface{}{
+				testKubernetesMount + "/config": {
+					"kubernetes_ca_cert": "",
+					"kubernetes_host":    "https://kubernetes.example.com:6443",
+				},
+				testKubernetesMount + "/roles/target": tt.wantRole,
+			}, logical.writes)
+		})
+	}
+}
+
+func TestVaultGetTargetKubernetes(t *testing.T) {
+	logical := newMockVaultLogicalPaths(map[string]map[string]interface{}{
+		testKubernetesMount + "/config": {
+			"disable_local_ca_jwt": false,
+			"kubernetes_ca_cert":   "",
+			"kubernetes_host":      "https://kubernetes.example.com:6443",
+		},
+		testKubernetesMount + "/roles/target": {
+			"allowed_kubernetes_namespaces": []interface{}{"team-a", "team-b"},
+			"kubernetes_role_name":          "edit",
+			"kubernetes_role_type":          "ClusterRole",
+			"service_account_name":          "",
+			"token_default_ttl":             3600,
+		},
+	})
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: logical, vaultSysSvc: newMockVaultSysMounts("kubernetes", testKubernetesMount)}
+
+	got, err := v.GetTarget("testProject", "testTarget")
+	assert.NoError(t, err)
+	assert.Equal(t, types.Target{
+		Name: "testTarget",
+		Type: "kubernetes_cluster",
+		Properties: types.TargetProperties{
+			CredentialType:     "role",
+			APIServer:          "https://kubernetes.example.com:6443",
+			AllowedNamespaces:  []string{"team-a", "team-b"},
+			KubernetesRoleName: "edit",
+			KubernetesRoleType: "ClusterRole",
+		},
+	}, got)
+	assert.NoError(t, got.Validate())
+}
+
+func TestVaultDeleteTargetKubernetes(t *testing.T) {
+	sys := newMockVaultSysMounts("kubernetes", testKubernetesMount)
+	v := VaultProvider{roleID: authorizationKeyAdmin, vaultLogicalSvc: newMockVaultLogicalPaths(nil), vaultSysSvc: sys}
+
+	assert.NoError(t, v.DeleteTarget("testProject", "testTarget"))
+	assert.NotContains(t, sys.mounted, testKubernetesMount+"/")
+}
@@ -0,0 +1,113 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cello-proj/cello/internal/types"
+)
+
+// vaultTargetBackend manages the targets of one target type in the Vault
+// secrets engine backing that type.
+type vaultTargetBackend interface {
+	// read returns the target or ErrTargetNotFound.
+	read(v VaultProvider, projectName, targetName string) (types.Target, error)
+	// write creates or updates the target.
+	write(v VaultProvider, projectName string, target types.Target) error
+	// delete removes the target.
+	delete(v VaultProvider, projectName, targetName string) error
+	// list returns the Vault names of all targets of the type, as generated
+	// by genTargetName.
+	list(v VaultProvider) ([]string, error)
+	// readonlyPolicy returns the policy allowing a project to read
+	// credentials for its targets of the type, or only for targetName when
+	// set.
+	readonlyPolicy(projectName, targetName string) string
+}
+
+// vaultTargetBackends maps each target type to its backend.
+var vaultTargetBackends = map[string]vaultTargetBackend{
+	types.TargetTypeAWSAccount:        awsTargetBackend{},
+	types.TargetTypeAzureSubscription: azureTargetBackend{},
+	types.TargetTypeGCPProject:        gcpTargetBackend{},
+	types.TargetTypeKubernetesCluster: kubernetesTargetBackend{},
+}
+
+func vaultTargetBackendFor(targetType string) (vaultTargetBackend, error) {
+	b, ok := vaultTargetBackends[targetType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported target type '%s'", targetType)
+	}
+	return b, nil
+}
+
+// findTarget returns the target and the backend it is stored in. The target
+// type is not stored in Vault, it is determined by the backend the target is
+// found in.
+func (v VaultProvider) findTarget(projectName, targetName string) (types.Target, vaultTargetBackend, error) {
+	for _, t := range types.TargetTypes {
+		b := vaultTargetBackends[t]
+
+		target, err := b.read(v, projectName, targetName)
+		if errors.Is(err, ErrTargetNotFound) {
+			continue
+		}
+		if err != nil {
+			return types.Target{}, nil, fmt.Errorf("vault get target error: %w", err)
+		}
+
+		return target, b, nil
+	}
+
+	return types.Target{}, nil, ErrTargetNotFound
+}
+
+// listKeys lists the keys at the path, returning none when it does not exist.
+func (v VaultProvider) listKeys(path string) ([]string, error) {
+	sec, err := v.vaultLogicalSvc.List(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if sec == nil {
+		return nil, nil
+	}
+
+	keys, _ := sec.Data["keys"].([]interface{})
+	list := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if s, ok := key.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list, nil
+}
+
+// secretsEngineMounts returns the paths, without the trailing slash, of the
+// Vault secrets engines of the type.
+func (v VaultProvider) secretsEngineMounts(engineType string) (map[string]bool, error) {
+	mounts, err := v.vaultSysSvc.ListMounts()
+	if err != nil {
+		return nil, fmt.Errorf("vault list mounts error: %w", err)
+	}
+
+	paths := map[string]bool{}
+	for path, m := range mounts {
+		if m != nil && m.Type == engineType {
+			paths[strings.TrimSuffix(path, "/")] = true
+		}
+	}
+	return paths, nil
+}
+
+// targetPolicyPath returns the policy path of targets beneath prefix. It
+// matches all targets of the project when targetName is empty, otherwise the
+// target's name followed by suffix, such as "/*" when credentials are read
+// beneath the name.
+func targetPolicyPath(prefix, projectName, targetName, suffix string) string {
+	if targetName == "" {
+		return prefix + genTargetName(projectName, "*")
+	}
+	return prefix + genTargetName(projectName, targetName) + suffix
+}
@@ -0,0 +1,120 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockVaultLogicalPaths returns data per path, for reads and writes, and
+// records writes and deletes.
+type mockVaultLogicalPaths struct {
+	vault.Logical
+	data    map[string]map[string]interface{}
+	writes  map[string]map[string]interface{}
+	deletes []string
+}
+
+func newMockVaultLogicalPaths(data map[string]map[string]interface{}) *mockVaultLogicalPaths {
+	return &mockVaultLogicalPaths{data: data, writes: map[string]map[string]interface{}{}}
+}
+
+func (m *mockVaultLogicalPaths) Read(path string) (*vault.Secret, error) {
+	if d, ok := m.data[path]; ok {
+		return &vault.Secret{Data: d}, nil
+	}
+	return nil, nil
+}
+
+func (m *mockVaultLogicalPaths) List(path string) (*vault.Secret, error) {
+	return m.Read(path)
+}
+
+func (m *mockVaultLogicalPaths) Write(path string, data map[string]interface{}) (*vault.Secret, error) {
+	m.writes[path] = data
+	return &vault.Secret{Data: m.data[path]}, nil
+}
+
+func (m *mockVaultLogicalPaths) Delete(path string) (*vault.Secret, error) {
+	m.deletes = append(m.deletes, path)
+	return &vault.Secret{}, nil
+}
+
+// mockVaultSysMounts keeps mounts in memory.
+type mockVaultSysMounts struct {
+	mockVaultSys
+	mounted map[string]*vault.MountOutput
+}
+
+func newMockVaultSysMounts(engineType string, mounts ...string) *mockVaultSysMounts {
+	m := &mockVaultSysMounts{mounted: map[string]*vault.MountOutput{}}
+	for _, mount := range mounts {
+		m.mounted[mount+"/"] = &vault.MountOutput{Type: engineType}
+	}
+	return m
+}
+
+func (m *mockVaultSysMounts) ListMounts() (map[string]*vault.MountOutput, error) {
+	return m.mounted, nil
+}
+
+func (m *mockVaultSysMounts) Mount(path string, mountInfo *vault.MountInput) error {
+	m.mounted[path+"/"] = &vault.MountOutput{Type: mountInfo.Type}
+	return nil
+}
+
+func (m *mockVaultSysMounts) Unmount(path string) error {
+	delete(m.mounted, path+"/")
+	return nil
+}
+
+func TestDefaultVaultReadonlyPolicy(t *testing.T) {
+	want := `path "aws/sts/argo-cloudops-projects-p1-target-*" { capabilities = ["read"] }
+path "aws/creds/argo-cloudops-projects-p1-target-*" { capabilities = ["read"] }
+path "azure-argo-cloudops-projects-p1-target-*" { capabilities = ["read"] }
+path "gcp/roleset/argo-cloudops-projects-p1-target-*" { capabilities = ["read"] }
+path "gcp/impersonated-account/argo-cloudops-projects-p1-target-*" { capabilities = ["read"] }
+path "kubernetes-argo-cloudops-projects-p1-target-*" {
+  capabilities = ["read", "update"]
+  allowed_parameters = { "kubernetes_namespace" = [] }
+}`
+
+	assert.Equal(t, want, defaultVaultReadonlyPolicy("p1"))
+}
+
+func TestProjectVaultPolicy(t *testing.T) {
+	policy := types.ProjectPolicy{
+		Targets:     []string{"t1"},
+		ExtraPolicy: `path "secret/data/p1/*" { capabilities = ["read"] }` + "\n",
+	}
+
+	want := `path "aws/sts/argo-cloudops-projects-p1-target-t1" { capabilities = ["read"] }
+path "aws/creds/argo-cloudops-projects-p1-target-t1" { capabilities = ["read"] }
+path "azure-argo-cloudops-projects-p1-target-t1/*" { capabilities = ["read"] }
+path "gcp/roleset/argo-cloudops-projects-p1-target-t1/*" { capabilities = ["read"] }
+path "gcp/impersonated-account/argo-cloudops-projects-p1-target-t1/*" { capabilities = ["read"] }
+path "kubernetes-argo-cloudops-projects-p1-target-t1/*" {
+  capabilities = ["read", "update"]
+  allowed_parameters = { "kubernetes_namespace" = [] }
+}
+path "secret/data/p1/*" { capabilities = ["read"] }`
+
+	assert.Equal(t, want, projectVaultPolicy("p1", policy))
+}
+
+func TestScopeVaultPolicy(t *testing.T) {
+	scopes := types.TokenScopes{{Target: "t1", Operations: []string{"diff"}}, {Target: "t2", Operations: []string{"diff"}}}
+	extra := `path "secret/data/p1/*" { capabilities = ["read"] }`
+
+	// Scoped targets the project policy does not allow are left out.
+	want := projectVaultPolicy("p1", types.ProjectPolicy{Targets: []string{"t1"}, ExtraPolicy: extra})
+	assert.Equal(t, want, scopeVaultPolicy("p1", types.ProjectPolicy{Targets: []string{"t1", "t3"}, ExtraPolicy: extra}, scopes))
+
+	want = projectVaultPolicy("p1", types.ProjectPolicy{Targets: []string{"t1", "t2"}})
+	assert.Equal(t, want, scopeVaultPolicy("p1", types.ProjectPolicy{}, scopes))
+
+	assert.Equal(t, emptyVaultPolicy, scopeVaultPolicy("p1", types.ProjectPolicy{Targets: []string{"t3"}}, scopes))
+}
@@ -1,8 +1,11 @@
 package credentials
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cello-proj/cello/internal/types"
 
@@ -62,7 +65,7 @@ func TestVaultCreateProject(t *testing.T) {
 				vaultSysSvc: &mockVaultSys{},
 			}
 
-			token, err := v.CreateProject("testProject")
+			token, err := v.CreateProject("testProject", 0)
 			if err != nil {
 				if !tt.errResult {
 					t.Errorf("\ndid not expect error, got: %v", err)
@@ -115,9 +118,10 @@ func TestVaultCreateTarget(t *testing.T) {
 			v := VaultProvider{
 				roleID:          role,
 				vaultLogicalSvc: &mockVaultLogical{err: tt.vaultErr},
+				vaultSysSvc:     &mockVaultSys{},
 			}
 
-			err := v.CreateTarget("test", types.Target{})
+			err := v.CreateTarget("test", types.Target{Type: types.TargetTypeAWSAccount})
 			if err != nil {
 				if !tt.errResult {
 					t.Errorf("\ndid not expect error, got: %v", err)
@@ -164,9 +168,10 @@ func TestVaultUpdateTarget(t *testing.T) {
 			v := VaultProvider{
 				roleID:          role,
 				vaultLogicalSvc: &mockVaultLogical{err: tt.vaultErr},
+				vaultSysSvc:     &mockVaultSys{},
 			}
 
-			err := v.UpdateTarget("test", types.Target{})
+			err := v.UpdateTarget("test", types.Target{Type: types.TargetTypeAWSAccount})
 			if err != nil {
 				if !tt.errResult {
 					t.Errorf("\ndid not expect error, got: %v", err)
@@ -180,6 +185,174 @@ func TestVaultUpdateTarget(t *testing.T) {
 	}
 }
 
+func TestVaultCreateScopedToken(t *testing.T) {
+	scopes := types.TokenScopes{{Target: "target1", Operations: []string{"diff"}}}
+	appRole := vaultProjectPrefix + "-" + scopeAppRoleName("project1", scopes)
+
+	logical := newMockVaultLogicalPaths(map[string]map[string]interface{}{
+		vaultAppRolePrefix + "/" + appRole + "/secret-id": {
+			"secret_id":          "test-secret",
+			"secret_id_accessor": "test-secret-accessor",
+		},
+		vaultAppRolePrefix + "/" + appRole + "/role-id": {
+			"role_id": "scoped-role",
+		},
+		vaultAppRolePrefix + "/" + appRole + "/secret-id-accessor/lookup": {
+			"creation_time":   "2022-07-01T14:56:10.341066-07:00",
+			"expiration_time": "2023-07-01T14:56:10.341066-07:00",
+		},
+	})
+
+	sys := &mockVaultSysPolicies{policies: map[string]string{}}
+	v := VaultProvider{
+		roleID:          authorizationKeyAdmin,
+		vaultLogicalSvc: logical,
+		vaultSysSvc:     sys,
+	}
+
+	policy := types.ProjectPolicy{Targets: []string{"target2"}}
+	token, err := v.CreateToken("project1", scopes, policy, time.Hour)
+	if err != nil {
+		t.Fatalf("\ndid not expect error, got: %v", err)
+	}
+
+	if token.RoleID != "scoped-role" {
+		t.Errorf("\nwant: %v\n got: %v", "scoped-role", token.RoleID)
+	}
+
+	role, ok := logical.writes[vaultAppRolePrefix+"/"+appRole]
+	if !ok {
+		t.Fatalf("\nexpected AppRole %s to be written", appRole)
+	}
+	if role["token_policies"] != appRole {
+		t.Errorf("\nwant: %v\n got: %v", appRole, role["token_policies"])
+	}
+
+	secretID := logical.writes[vaultAppRolePrefix+"/"+appRole+"/secret-id"]
+	if secretID["ttl"] != "3600s" {
+		t.Errorf("\nwant: %v\n got: %v", "3600s", secretID["ttl"])
+	}
+
+	// The project policy does not allow the scoped target.
+	if sys.policies[appRole] != emptyVaultPolicy {
+		t.Errorf("\nwant: %v\n got: %v", emptyVaultPolicy, sys.policies[appRole])
+	}
+}
+
+func TestScopeAppRoleName(t *testing.T) {
+	a := scopeAppRoleName("project1", types.TokenScopes{
+		{Target: "prod", Operations: []string{"diff"}},
+		{Target: "dev1", Operations: []string{"sync", "diff"}},
+	})
+	b := scopeAppRoleName("project1", types.TokenScopes{
+		{Target: "dev1", Operations: []string{"diff", "sync"}},
+		{Target: "prod", Operations: []string{"diff"}},
+	})
+	c := scopeAppRoleName("project1", types.TokenScopes{
+		{Target: "prod", Operations: []string{"diff", "sync"}},
+	})
+
+	if a != b {
+		t.Errorf("\nexpected equal scopes to share an AppRole, got: %v and %v", a, b)
+	}
+	if a == c {
+		t.Errorf("\nexpected different scopes to have different AppRoles")
+	}
+	if !strings.HasPrefix(a, "project1-scope-") {
+		t.Errorf("\nunexpected AppRole name %v", a)
+	}
+}
+
+func TestVaultUpdateProjectPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		admin     bool
+		vaultErr  error
+		errResult bool
+	}{
+		{
+			name:  "update project policy success",
+			admin: true,
+		},
+		{
+			name:      "update project policy admin error",
+			errResult: true,
+		},
+		{
+			name:      "update project policy error",
+			admin:     true,
+			vaultErr:  errTest,
+			errResult: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := TestRole
+			if tt.admin {
+				role = authorizationKeyAdmin
+			}
+			v := VaultProvider{
+				roleID:          role,
+				vaultLogicalSvc: &mockVaultLogical{},
+				vaultSysSvc:     &mockVaultSys{err: tt.vaultErr},
+			}
+
+			err := v.UpdateProjectPolicy("testProject", types.ProjectPolicy{Targets: []string{"target1"}}, nil)
+			if err != nil {
+				if !tt.errResult {
+					t.Errorf("\ndid not expect error, got: %v", err)
+				}
+			} else if tt.errResult {
+				t.Errorf("\nexpected error")
+			}
+		})
+	}
+}
+
+func TestVaultUpdateProjectPolicyRewritesScopePolicies(t *testing.T) {
+	scopes := types.TokenScopes{{Target: "target1", Operations: []string{"diff"}}, {Target: "target2", Operations: []string{"sync"}}}
+	scopeRole := scopeAppRoleName("project1", scopes)
+
+	logical := newMockVaultLogicalPaths(map[string]map[string]interface{}{
+		vaultAppRolePrefix: {
+			"keys": []interface{}{
+				vaultProjectPrefix + "-project1",
+				vaultProjectPrefix + "-" + scopeRole,
+				vaultProjectPrefix + "-project1-scope-unknown",
+				vaultProjectPrefix + "-project2-scope-0123456789abcdef",
+			},
+		},
+	})
+	sys := &mockVaultSysPolicies{policies: map[string]string{}}
+
+	v := VaultProvider{
+		roleID:          authorizationKeyAdmin,
+		vaultLogicalSvc: logical,
+		vaultSysSvc:     sys,
+	}
+
+	policy := types.ProjectPolicy{Targets: []string{"target1"}, ExtraPolicy: `path "secret/data/project1/*" { capabilities = ["read"] }`}
+	if err := v.UpdateProjectPolicy("project1", policy, []types.TokenScopes{scopes}); err != nil {
+		t.Fatalf("\ndid not expect error, got: %v", err)
+	}
+
+	want := map[string]string{
+		vaultProjectPrefix + "-project1":               projectVaultPolicy("project1", policy),
+		vaultProjectPrefix + "-" + scopeRole:           scopeVaultPolicy("project1", policy, scopes),
+		vaultProjectPrefix + "-project1-scope-unknown": emptyVaultPolicy,
+	}
+	if diff := cmp.Diff(want, sys.policies); diff != "" {
+		t.Errorf("\n(-want/+got)\n%s", diff)
+	}
+
+	// The scope policy no longer allows target2, and has the extra policy.
+	rules := sys.policies[vaultProjectPrefix+"-"+scopeRole]
+	if strings.Contains(rules, "target2") || !strings.Contains(rules, "secret/data/project1/*") {
+		t.Errorf("\nunexpected scope policy %v", rules)
+	}
+}
+
 func TestVaultDeleteProject(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -270,6 +443,7 @@ func TestVaultDeleteTarget(t *testing.T) {
 			v := VaultProvider{
 				roleID:          role,
 				vaultLogicalSvc: &mockVaultLogical{err: tt.vaultErr},
+				vaultSysSvc:     &mockVaultSys{},
 			}
 
 			err := v.DeleteTarget("testProject", "testTarget")
@@ -291,15 +465,17 @@ func TestVaultGetProjectToken(t *testing.T) {
 		name  string
 		admin bool
 		// TODO: use type instead?
-		expectedTokenID string
-		mockVaultData   map[string]interface{}
-		vaultErr        error
-		errResult       bool
+		expectedTokenID   string
+		expectedCreatedAt string
+		mockVaultData     map[string]interface{}
+		vaultErr          error
+		errResult         bool
 	}{
 		{
-			name:            "get project token success",
-			admin:           true,
-			expectedTokenID: "secret-id-accessor",
+			name:              "get project token success",
+			admin:             true,
+			expectedTokenID:   "secret-id-accessor",
+			expectedCreatedAt: "2022-06-21T14:43:16.172896-07:00",
 			mockVaultData: map[string]interface{}{
 				"creation_time":      "2022-06-21T14:43:16.172896-07:00",
 				"expiration_time":    "2023-06-21T14:43:16.172896-07:00",
@@ -354,6 +530,9 @@ func TestVaultGetProjectToken(t *testing.T) {
 			if !cmp.Equal(projectToken.ID, tt.expectedTokenID) {
 				t.Errorf("\nwant: %v\n got: %v", tt.expectedTokenID, projectToken.ID)
 			}
+			if !cmp.Equal(projectToken.CreatedAt, tt.expectedCreatedAt) {
+				t.Errorf("\nwant: %v\n got: %v", tt.expectedCreatedAt, projectToken.CreatedAt)
+			}
 		})
 	}
 }
@@ -389,7 +568,8 @@ func TestVaultGetTarget(t *testing.T) {
 				role = authorizationKeyAdmin
 			}
 			v := VaultProvider{
-				roleID: role,
+				roleID:      role,
+				vaultSysSvc: &mockVaultSys{},
 				vaultLogicalSvc: &mockVaultLogical{err: tt.vaultErr, data: map[string]interface{}{
 					"role_arns":       []interface{}{"test-role-arn"},
 					"policy_arns":     []interface{}{"test-policy-arn"},
@@ -412,6 +592,105 @@ func TestVaultGetTarget(t *testing.T) {
 	}
 }
 
+func TestVaultVerifyProjectToken(t *testing.T) {
+	const (
+		projectRole = "auth/approle/role/argo-cloudops-projects-project1"
+		scopeRole   = "auth/approle/role/argo-cloudops-projects-project1-scope-abc"
+	)
+
+	tests := []struct {
+		name    string
+		roleID  string
+		data    map[string]map[string]interface{}
+		token   string
+		wantErr error
+		// expectErr is set for errors other than wantErr.
+		expectErr bool
+	}{
+		{
+			name:   "project token",
+			roleID: authorizationKeyAdmin,
+			data: map[string]map[string]interface{}{
+				projectRole + "/role-id":          {"role_id": "project1-role"},
+				projectRole + "/secret-id/lookup": {"expiration_time": "0001-01-01T00:00:00Z"},
+			},
+			token: "project1-role",
+		},
+		{
+			name:   "scoped token",
+			roleID: authorizationKeyAdmin,
+			data: map[string]map[string]interface{}{
+				"auth/approle/role":             {"keys": []interface{}{"argo-cloudops-projects-project1", "argo-cloudops-projects-project1-scope-abc"}},
+				projectRole + "/role-id":        {"role_id": "project1-role"},
+				scopeRole + "/role-id":          {"role_id": "scope-role"},
+				scopeRole + "/secret-id/lookup": {"expiration_time": time.Now().Add(time.Hour).Format(time.RFC3339Nano)},
+			},
+			token: "scope-role",
+		},
+		{
+			name:   "expired secret",
+			roleID: authorizationKeyAdmin,
+			data: map[string]map[string]interface{}{
+				projectRole + "/role-id":          {"role_id": "project1-role"},
+				projectRole + "/secret-id/lookup": {"expiration_time": time.Now().Add(-time.Hour).Format(time.RFC3339Nano)},
+			},
+			token:   "project1-role",
+			wantErr: ErrInvalidProjectToken,
+		},
+		{
+			name:   "unknown secret",
+			roleID: authorizationKeyAdmin,
+			data: map[string]map[string]interface{}{
+				projectRole + "/role-id": {"role_id": "project1-role"},
+			},
+			token:   "project1-role",
+			wantErr: ErrInvalidProjectToken,
+		},
+		{
+			name:   "role of another project",
+			roleID: authorizationKeyAdmin,
+			data: map[string]map[string]interface{}{
+				projectRole + "/role-id": {"role_id": "project1-role"},
+			},
+			token:   "project2-role",
+			wantErr: ErrNotFound,
+		},
+		{
+			name:      "not admin",
+			roleID:    "role-id",
+			token:     "project1-role",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logical := newMockVaultLogicalPaths(tt.data)
+			v := VaultProvider{
+				roleID:          tt.roleID,
+				vaultLogicalSvc: logical,
+			}
+
+			err := v.VerifyProjectToken("project1", tt.token, "secret")
+			switch {
+			case tt.expectErr:
+				if err == nil {
+					t.Errorf("\nexpected error")
+				}
+				return
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("\nwant: %v\n got: %v", tt.wantErr, err)
+				return
+			}
+
+			// The secret is looked up rather than used to log in.
+			if _, ok := logical.writes["auth/approle/login"]; ok {
+				t.Errorf("\nexpected no login")
+			}
+		})
+	}
+}
+
 func TestVaultGetToken(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -502,7 +781,8 @@ func TestVaultListTargets(t *testing.T) {
 				testTargets = append(testTargets, fmt.Sprintf("argo-cloudops-projects-test-target-%s", i))
 			}
 			v := VaultProvider{
-				roleID: role,
+				roleID:      role,
+				vaultSysSvc: &mockVaultSys{},
 				vaultLogicalSvc: &mockVaultLogical{err: tt.vaultErr, data: map[string]interface{}{
 					"keys": testTargets,
 				}},
@@ -525,6 +805,74 @@ func TestVaultListTargets(t *testing.T) {
 	}
 }
 
+func TestVaultListProjects(t *testing.T) {
+	v := VaultProvider{
+		roleID:      authorizationKeyAdmin,
+		vaultSysSvc: &mockVaultSys{},
+		vaultLogicalSvc: newMockVaultLogicalPaths(map[string]map[string]interface{}{
+			"auth/approle/role": {"keys": []interface{}{
+				"argo-cloudops-projects-project2",
+				"argo-cloudops-projects-project1",
+				"argo-cloudops-projects-project1-scope-0123456789abcdef",
+				"other-role",
+			}},
+		}),
+	}
+
+	projects, err := v.ListProjects()
+	if err != nil {
+		t.Fatalf("\ndid not expect error, got: %v", err)
+	}
+
+	want := []string{"project1", "project2"}
+	if !cmp.Equal(projects, want) {
+		t.Errorf("\nwant: %v\n got: %v", want, projects)
+	}
+
+	v.roleID = TestRole
+	if _, err := v.ListProjects(); err == nil {
+		t.Errorf("\nexpected error")
+	}
+}
+
+func TestVaultListProjectTokens(t *testing.T) {
+	v := VaultProvider{
+		roleID:      authorizationKeyAdmin,
+		vaultSysSvc: &mockVaultSys{},
+		vaultLogicalSvc: newMockVaultLogicalPaths(map[string]map[string]interface{}{
+			"auth/approle/role": {"keys": []interface{}{
+				"argo-cloudops-projects-project1",
+				"argo-cloudops-projects-project1-scope-0123456789abcdef",
+				"argo-cloudops-projects-project2",
+			}},
+			"auth/approle/role/argo-cloudops-projects-project1/secret-id": {"keys": []interface{}{
+				"accessor2",
+			}},
+			"auth/approle/role/argo-cloudops-projects-project1-scope-0123456789abcdef/secret-id": {"keys": []interface{}{
+				"accessor1",
+			}},
+			"auth/approle/role/argo-cloudops-projects-project2/secret-id": {"keys": []interface{}{
+				"accessor3",
+			}},
+		}),
+	}
+
+	tokens, err := v.ListProjectTokens("project1")
+	if err != nil {
+		t.Fatalf("\ndid not expect error, got: %v", err)
+	}
+
+	want := []string{"accessor1", "accessor2"}
+	if !cmp.Equal(tokens, want) {
+		t.Errorf("\nwant: %v\n got: %v", want, tokens)
+	}
+
+	v.roleID = TestRole
+	if _, err := v.ListProjectTokens("project1"); err == nil {
+		t.Errorf("\nexpected error")
+	}
+}
+
 func TestVaultProjectExists(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -624,6 +972,22 @@ func TestValidateAuthorizedAdmin(t *testing.T) {
 	}
 }
 
+func TestAdminAuthorization(t *testing.T) {
+	a := AdminAuthorization("vault", "validSecret")
+	if err := a.Validate(a.ValidateProvider("vault"), a.ValidateAuthorizedAdmin("validSecret")); err != nil {
+		t.Errorf("\nwant error: false\n got error: %v", err)
+	}
+}
+
+func TestAuthorizationIsAdmin(t *testing.T) {
+	if !AdminAuthorization("vault", "validSecret").IsAdmin() {
+		t.Errorf("\nwant admin: true\n got admin: false")
+	}
+	if (Authorization{Provider: "vault", Key: "role-id", Secret: "secret"}).IsAdmin() {
+		t.Errorf("\nwant admin: false\n got admin: true")
+	}
+}
+
 func TestNewAuthorization(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -699,7 +1063,8 @@ func (m mockVaultLogical) Delete(path string) (*vault.Secret, error) {
 
 type mockVaultSys struct {
 	vault.Sys
-	err error
+	err    error
+	mounts map[string]*vault.MountOutput
 }
 
 func (m mockVaultSys) PutPolicy(name, rules string) error {
@@ -709,3 +1074,26 @@ func (m mockVaultSys) PutPolicy(name, rules string) error {
 func (m mockVaultSys) DeletePolicy(name string) error {
 	return m.err
 }
+
+func (m mockVaultSys) ListMounts() (map[string]*vault.MountOutput, error) {
+	return m.mounts, m.err
+}
+
+func (m mockVaultSys) Mount(path string, mountInfo *vault.MountInput) error {
+	return m.err
+}
+
+func (m mockVaultSys) Unmount(path string) error {
+	return m.err
+}
+
+// mockVaultSysPolicies keeps the rules of the policies written.
+type mockVaultSysPolicies struct {
+	mockVaultSys
+	policies map[string]string
+}
+
+func (m *mockVaultSysPolicies) PutPolicy(name, rules string) error {
+	m.policies[name] = rules
+	return nil
+}
@@ -0,0 +1,112 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cello-proj/cello/service/internal/env"
+)
+
+// Supported methods the service can use to authenticate itself with Vault.
+const (
+	VaultAuthMethodAppRole    = "approle"
+	VaultAuthMethodJWT        = "jwt"
+	VaultAuthMethodKubernetes = "kubernetes"
+
+	// Location of the projected service account token in a pod.
+	defaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// VaultAuth logs the service into Vault and returns a client token.
+type VaultAuth interface {
+	Login(vaultLogical) (string, error)
+}
+
+// NewVaultAuth returns the VaultAuth selected by the environment.
+func NewVaultAuth(env env.Vars) (VaultAuth, error) {
+	switch env.VaultAuthMethod {
+	case "", VaultAuthMethodAppRole:
+		return appRoleAuth{
+			mount:    mountOrDefault(env.VaultAuthMount, VaultAuthMethodAppRole),
+			roleID:   env.VaultRole,
+			secretID: env.VaultSecret,
+		}, nil
+	case VaultAuthMethodKubernetes:
+		tokenFile := env.VaultAuthTokenFile
+		if tokenFile == "" {
+			tokenFile = defaultKubernetesTokenFile
+		}
+		return jwtAuth{
+			mount:     mountOrDefault(env.VaultAuthMount, VaultAuthMethodKubernetes),
+			role:      env.VaultAuthRole,
+			tokenFile: tokenFile,
+		}, nil
+	case VaultAuthMethodJWT:
+		return jwtAuth{
+			mount:     mountOrDefault(env.VaultAuthMount, VaultAuthMethodJWT),
+			role:      env.VaultAuthRole,
+			tokenFile: env.VaultAuthTokenFile,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown vault auth method '%s'", env.VaultAuthMethod)
+	}
+}
+
+func mountOrDefault(mount, method string) string {
+	if mount == "" {
+		return method
+	}
+	return strings.Trim(mount, "/")
+}
+
+// appRoleAuth logs in with a static AppRole role ID and secret ID.
+type appRoleAuth struct {
+	mount    string
+	roleID   string
+	secretID string
+}
+
+func (a appRoleAuth) Login(l vaultLogical) (string, error) {
+	return vaultLogin(l, a.mount, map[string]interface{}{
+		"role_id":   a.roleID,
+		"secret_id": a.secretID,
+	})
+}
+
+// jwtAuth logs in with a JWT read from a file. This covers both the
+// Kubernetes auth method (using the projected service account token) and the
+// JWT/OIDC auth method, which share the same login payload.
+type jwtAuth struct {
+	mount     string
+	role      string
+	tokenFile string
+}
+
+func (a jwtAuth) Login(l vaultLogical) (string, error) {
+	// The token is read on every login as projected tokens are rotated by the
+	// kubelet.
+	jwt, err := os.ReadFile(a.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read vault auth token file: %w", err)
+	}
+
+	return vaultLogin(l, a.mount, map[string]interface{}{
+		"role": a.role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
+
+func vaultLogin(l vaultLogical, mount string, options map[string]interface{}) (string, error) {
+	sec, err := l.Write(fmt.Sprintf("auth/%s/login", mount), options)
+	if err != nil {
+		return "", err
+	}
+
+	if sec == nil || sec.Auth == nil {
+		return "", errors.New("vault login did not return a client token")
+	}
+
+	return sec.Auth.ClientToken, nil
+}
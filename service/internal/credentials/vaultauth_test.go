@@ -0,0 +1,173 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cello-proj/cello/service/internal/env"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestVaultServer returns a stand-in Vault server which accepts logins on
+// the provided mount when the payload matches wantPayload.
+func newTestVaultServer(t *testing.T, mount string, wantPayload map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/"+mount+"/login" || r.Method != http.MethodPut {
+			http.NotFound(w, r)
+			return
+		}
+
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if !reflect.DeepEqual(wantPayload, payload) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"auth":{"client_token":"s.testtoken"}}`)
+	}))
+}
+
+func writeTokenFile(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewVaultAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     env.Vars
+		want    VaultAuth
+		wantErr bool
+	}{
+		{
+			name: "default is approle",
+			env:  env.Vars{VaultRole: "role", VaultSecret: "secret"},
+			want: appRoleAuth{mount: "approle", roleID: "role", secretID: "secret"},
+		},
+		{
+			name: "approle with custom mount",
+			env:  env.Vars{VaultAuthMethod: "approle", VaultAuthMount: "/cello-approle/", VaultRole: "role", VaultSecret: "secret"},
+			want: appRoleAuth{mount: "cello-approle", roleID: "role", secretID: "secret"},
+		},
+		{
+			name: "kubernetes uses projected token by default",
+			env:  env.Vars{VaultAuthMethod: "kubernetes", VaultAuthRole: "cello"},
+			want: jwtAuth{mount: "kubernetes", role: "cello", tokenFile: defaultKubernetesTokenFile},
+		},
+		{
+			name: "jwt",
+			env:  env.Vars{VaultAuthMethod: "jwt", VaultAuthMount: "oidc", VaultAuthRole: "cello", VaultAuthTokenFile: "/tmp/token"},
+			want: jwtAuth{mount: "oidc", role: "cello", tokenFile: "/tmp/token"},
+		},
+		{
+			name:    "unknown method",
+			env:     env.Vars{VaultAuthMethod: "userpass"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewVaultAuth(tt.env)
+			if err != nil != tt.wantErr {
+				t.Fatalf("\nwant error: %v\n got error: %v", tt.wantErr, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVaultAuthLogin(t *testing.T) {
+	tests := []struct {
+		name        string
+		mount       string
+		wantPayload map[string]interface{}
+		auth        func(t *testing.T) VaultAuth
+		wantErr     bool
+	}{
+		{
+			name:        "approle",
+			mount:       "approle",
+			wantPayload: map[string]interface{}{"role_id": "role", "secret_id": "secret"},
+			auth: func(t *testing.T) VaultAuth {
+				return appRoleAuth{mount: "approle", roleID: "role", secretID: "secret"}
+			},
+		},
+		{
+			name:        "approle bad secret",
+			mount:       "approle",
+			wantPayload: map[string]interface{}{"role_id": "role", "secret_id": "secret"},
+			auth: func(t *testing.T) VaultAuth {
+				return appRoleAuth{mount: "approle", roleID: "role", secretID: "wrong"}
+			},
+			wantErr: true,
+		},
+		{
+			name:        "kubernetes",
+			mount:       "kubernetes",
+			wantPayload: map[string]interface{}{"role": "cello", "jwt": "sa.jwt.token"},
+			auth: func(t *testing.T) VaultAuth {
+				return jwtAuth{mount: "kubernetes", role: "cello", tokenFile: writeTokenFile(t, "sa.jwt.token\n")}
+			},
+		},
+		{
+			name:        "jwt",
+			mount:       "jwt",
+			wantPayload: map[string]interface{}{"role": "cello", "jwt": "oidc.jwt.token"},
+			auth: func(t *testing.T) VaultAuth {
+				return jwtAuth{mount: "jwt", role: "cello", tokenFile: writeTokenFile(t, "oidc.jwt.token")}
+			},
+		},
+		{
+			name:  "jwt missing token file",
+			mount: "jwt",
+			auth: func(t *testing.T) VaultAuth {
+				return jwtAuth{mount: "jwt", role: "cello", tokenFile: filepath.Join(t.TempDir(), "missing")}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestVaultServer(t, tt.mount, tt.wantPayload)
+			defer srv.Close()
+
+			cfg := NewVaultConfig(&vault.Config{Address: srv.URL}, tt.auth(t))
+
+			svc, err := NewVaultSvc(*cfg, http.Header{})
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("\ndid not expect error, got: %v", err)
+				}
+				return
+			}
+
+			if tt.wantErr {
+				t.Errorf("\nexpected error")
+			}
+			assert.Equal(t, "s.testtoken", svc.Token())
+		})
+	}
+}
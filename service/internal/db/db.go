@@ -4,8 +4,10 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,20 +22,106 @@ import (
 )
 
 type ProjectEntry struct {
-	ProjectID  string `db:"project"`
-	Repository string `db:"repository"`
+	// Policy is the template of the project's credentials provider policy.
+	Policy     types.ProjectPolicy `db:"policy"`
+	ProjectID  string              `db:"project"`
+	Repository string              `db:"repository"`
+	// TokenLimit is the number of tokens the project can have, the
+	// service's default is used when zero.
+	TokenLimit int `db:"token_limit"`
+	// TokenTTL is the default TTL, in seconds, of the project's tokens, the
+	// service's default is used when zero.
+	TokenTTL int `db:"token_ttl"`
+	// WorkflowQuota is the number of workflows the project can run
+	// concurrently, the service's default is used when zero.
+	WorkflowQuota int `db:"workflow_quota"`
 }
 
 type TokenEntry struct {
 	CreatedAt string `db:"created_at"`
 	ExpiresAt string `db:"expires_at"`
 	ProjectID string `db:"project"`
-	TokenID   string `db:"token_id"`
+	// RoleID identifies the credentials provider role of the token, scoped
+	// tokens have a role per scopes. It is empty for tokens created before
+	// scopes.
+	RoleID  string            `db:"role_id"`
+	Scopes  types.TokenScopes `db:"scopes"`
+	TokenID string            `db:"token_id"`
+}
+
+// IdentityEntry is a named identity authorized by its roles.
+type IdentityEntry struct {
+	CreatedAt string             `db:"created_at"`
+	Name      string             `db:"name"`
+	Roles     types.RoleBindings `db:"roles"`
+	// SecretHash is the bcrypt hash of the identity's secret, the secret
+	// itself is not stored.
+	SecretHash string `db:"secret_hash"`
+}
+
+// IdempotencyEntry records the request a principal made with an idempotency
+// key and the workflow it submitted.
+type IdempotencyEntry struct {
+	Key       string `db:"key"`
+	Principal string `db:"principal"`
+	// RequestHash identifies the request the key was used with.
+	RequestHash string `db:"request_hash"`
+	// WorkflowName, or SubmissionID for queued requests, is empty while the
+	// request is in progress.
+	WorkflowName string `db:"workflow_name"`
+	SubmissionID string `db:"submission_id"`
+	// LockedUntil is when a request which never completed, because its
+	// replica stopped, stops holding the key.
+	LockedUntil time.Time `db:"locked_until"`
+	// ExpiresAt is when the key can be used again. It is stored in the ttl
+	// attribute, which can be enabled as the table's TTL to remove the entry.
+	ExpiresAt time.Time `db:"ttl"`
+}
+
+// Statuses of submissions.
+const (
+	SubmissionQueued    = "queued"
+	SubmissionSubmitted = "submitted"
+	SubmissionFailed    = "failed"
+)
+
+// SubmissionEntry is a workflow submission queued to be submitted by a
+// worker, and its outcome.
+type SubmissionEntry struct {
+	ID     string `db:"id"`
+	Status string `db:"status"`
+	// Request is the JSON of the queued request.
+	Request  string `db:"request"`
+	Attempts int    `db:"attempts"`
+	// Error is why the submission failed, or why its last attempt failed
+	// while it is queued.
+	Error        string    `db:"error"`
+	WorkflowName string    `db:"workflow_name"`
+	CreatedAt    time.Time `db:"created_at"`
+	// NextAttemptAt is when a queued submission is attempted next.
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	// ExpiresAt is when the entry can be removed by the table's TTL.
+	ExpiresAt time.Time `db:"ttl"`
+}
+
+// WebhookEntry is a URL of a project which is notified of its workflow
+// events.
+type WebhookEntry struct {
+	CreatedAt string `db:"created_at"`
+	// Events are the events the webhook is notified of, all of them when
+	// empty.
+	Events    []string `db:"events"`
+	ID        string   `db:"id"`
+	ProjectID string   `db:"project"`
+	// Secret is the key of the HMAC signature of the events delivered to
+	// the webhook, it is stored so the events can be signed.
+	Secret string `db:"secret"`
+	URL    string `db:"url"`
 }
 
 // IsEmpty returns whether a struct is empty.
 func (t TokenEntry) IsEmpty() bool {
-	return t == (TokenEntry{})
+	return t.CreatedAt == "" && t.ExpiresAt == "" && t.ProjectID == "" && t.RoleID == "" && len(t.Scopes) == 0 && t.TokenID == ""
 }
 
 // Client allows for db crud operations
@@ -41,7 +129,14 @@ type Client interface {
 	CreateProjectEntry(ctx context.Context, pe ProjectEntry) error
 	DeleteProjectEntry(ctx context.Context, project string) error
 	ReadProjectEntry(ctx context.Context, project string) (ProjectEntry, error)
+	ListProjectEntries(ctx context.Context) ([]ProjectEntry, error)
+	// UpdateProjectEntry updates the project except its policy, which is
+	// updated by UpdateProjectPolicy so concurrent updates of either are
+	// not lost.
+	UpdateProjectEntry(ctx context.Context, pe ProjectEntry) error
+	UpdateProjectPolicy(ctx context.Context, project string, policy types.ProjectPolicy) error
 	CreateTokenEntry(ctx context.Context, token types.Token) error
+	UpdateTokenEntry(ctx context.Context, te TokenEntry) error
 	DeleteTokenEntry(ctx context.Context, token string) error
 	// This only exists for dynamodb, as the token ID is the sort key which also requires the project ID as the primary key.
 	DeleteTokenEntryByProject(ctx context.Context, project, token string) error
@@ -50,6 +145,42 @@ type Client interface {
 	ReadTokenEntryByProject(ctx context.Context, project, token string) (TokenEntry, error)
 	ListTokenEntries(ctx context.Context, project string) ([]TokenEntry, error)
 	Health(ctx context.Context) error
+	// AcquireLease returns whether the owner holds the named lease for the
+	// duration, it is used to coordinate background work across replicas.
+	AcquireLease(ctx context.Context, name, owner string, d time.Duration) (bool, error)
+	CreateIdentityEntry(ctx context.Context, ie IdentityEntry) error
+	ReadIdentityEntry(ctx context.Context, name string) (IdentityEntry, error)
+	ListIdentityEntries(ctx context.Context) ([]IdentityEntry, error)
+	UpdateIdentityEntry(ctx context.Context, ie IdentityEntry) error
+	DeleteIdentityEntry(ctx context.Context, name string) error
+	// CreateIdempotencyEntry claims the entry's key for the principal, it
+	// returns ErrIdempotencyKeyExists when the key is held.
+	CreateIdempotencyEntry(ctx context.Context, ie IdempotencyEntry) error
+	ReadIdempotencyEntry(ctx context.Context, principal, key string) (IdempotencyEntry, error)
+	UpdateIdempotencyEntry(ctx context.Context, ie IdempotencyEntry) error
+	DeleteIdempotencyEntry(ctx context.Context, principal, key string) error
+	// CreateSubmissionEntry stores a queued submission and adds it to the
+	// queue.
+	CreateSubmissionEntry(ctx context.Context, se SubmissionEntry) error
+	ReadSubmissionEntry(ctx context.Context, id string) (SubmissionEntry, error)
+	// ListQueuedSubmissions returns the IDs of the queued submissions which
+	// are due at the time.
+	ListQueuedSubmissions(ctx context.Context, now time.Time) ([]string, error)
+	// ClaimSubmission returns whether the queued submission was claimed for
+	// the duration, so no other replica attempts it meanwhile.
+	ClaimSubmission(ctx context.Context, id string, d time.Duration) (bool, error)
+	// UpdateSubmissionEntry stores the outcome of an attempt and releases
+	// the submission's claim.
+	UpdateSubmissionEntry(ctx context.Context, se SubmissionEntry) error
+	CreateWebhookEntry(ctx context.Context, we WebhookEntry) error
+	ListWebhookEntries(ctx context.Context, project string) ([]WebhookEntry, error)
+	// DeleteWebhookEntry returns ErrWebhookNotFound when the project has no
+	// such webhook.
+	DeleteWebhookEntry(ctx context.Context, project, id string) error
+	// RecordWebhookEvent returns whether the event of the workflow was
+	// recorded, which is false when it already was, so each event is
+	// delivered once by the replicas watching workflows.
+	RecordWebhookEvent(ctx context.Context, workflowName, event string, expiresAt time.Time) (bool, error)
 }
 
 // Verify interface implementations at compile time
@@ -69,6 +200,9 @@ type DynamoDBSvc interface {
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
 	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
 }
 
 // DynamoDBClient allows for db crud operations using dynamodb
@@ -81,14 +215,35 @@ const (
 	primaryKey = "pk"
 	sortKey    = "sk"
 
-	projectPKFmt = "PROJECT#%s"
-	metadataSK   = "METADATA"
-	tokenSKFmt   = "TOKEN#%s"
+	projectPKFmt  = "PROJECT#%s"
+	metadataSK    = "METADATA"
+	tokenSKFmt    = "TOKEN#%s"
+	leasePKFmt    = "LEASE#%s"
+	identityPKFmt = "IDENTITY#%s"
+	healthPK      = "HEALTH"
+
+	idempotencyPKFmt = "IDEMPOTENCY#%s"
+	idempotencySKFmt = "KEY#%s"
+
+	submissionPKFmt = "SUBMISSION#%s"
+	// submissionQueuePK is the partition holding an item per queued
+	// submission, which is removed once the submission is done.
+	submissionQueuePK = "SUBMISSION_QUEUE"
+
+	webhookSKFmt      = "WEBHOOK#%s"
+	webhookEventPKFmt = "WEBHOOK_EVENT#%s"
 )
 
 var (
-	ErrProjectNotFound = fmt.Errorf("project not found")
-	ErrTokenNotFound   = fmt.Errorf("token not found")
+	ErrIdempotencyKeyExists   = fmt.Errorf("idempotency key already exists")
+	ErrIdempotencyKeyNotFound = fmt.Errorf("idempotency key not found")
+	ErrIdentityExists         = fmt.Errorf("identity already exists")
+	ErrIdentityNotFound       = fmt.Errorf("identity not found")
+	ErrProjectExists          = fmt.Errorf("project already exists")
+	ErrProjectNotFound        = fmt.Errorf("project not found")
+	ErrSubmissionNotFound     = fmt.Errorf("submission not found")
+	ErrTokenNotFound          = fmt.Errorf("token not found")
+	ErrWebhookNotFound        = fmt.Errorf("webhook not found")
 )
 
 func NewDynamoDBClient(tableName string, endpointURL string, assumeRoleARN string) (*DynamoDBClient, error) {
@@ -126,19 +281,57 @@ func NewDynamoDBClient(tableName string, endpointURL string, assumeRoleARN strin
 	}, nil
 }
 
+// Health reads an item which doesn't exist, which checks the table can be
+// reached and read without consuming more than a read capacity unit.
 func (d *DynamoDBClient) Health(ctx context.Context) error {
-	// No-op implementation to avoid unnecessary API calls
-	return nil
+	_, err := d.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]ddbtypes.AttributeValue{
+			primaryKey: &ddbtypes.AttributeValueMemberS{Value: healthPK},
+			sortKey:    &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+		},
+	})
+	return err
 }
 
-func (d *DynamoDBClient) CreateProjectEntry(ctx context.Context, pe ProjectEntry) error {
+// projectItem returns the metadata item of the project. The policy is
+// stored as JSON.
+func projectItem(pe ProjectEntry) (map[string]ddbtypes.AttributeValue, error) {
 	item := map[string]ddbtypes.AttributeValue{
 		primaryKey:   &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(projectPKFmt, pe.ProjectID)},
 		sortKey:      &ddbtypes.AttributeValueMemberS{Value: metadataSK},
 		"repository": &ddbtypes.AttributeValueMemberS{Value: pe.Repository},
 	}
 
-	_, err := d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+	if len(pe.Policy.Targets) > 0 || pe.Policy.ExtraPolicy != "" {
+		policy, err := json.Marshal(pe.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy: %w", err)
+		}
+		item["policy"] = &ddbtypes.AttributeValueMemberS{Value: string(policy)}
+	}
+
+	if pe.TokenLimit > 0 {
+		item["token_limit"] = &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(pe.TokenLimit)}
+	}
+
+	if pe.TokenTTL > 0 {
+		item["token_ttl"] = &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(pe.TokenTTL)}
+	}
+
+	if pe.WorkflowQuota > 0 {
+		item["workflow_quota"] = &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(pe.WorkflowQuota)}
+	}
+	return item, nil
+}
+
+func (d *DynamoDBClient) CreateProjectEntry(ctx context.Context, pe ProjectEntry) error {
+	item, err := projectItem(pe)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.svc.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName:           aws.String(d.tableName),
 		Item:                item,
 		ConditionExpression: aws.String("attribute_not_exists(pk)"),
@@ -146,7 +339,7 @@ func (d *DynamoDBClient) CreateProjectEntry(ctx context.Context, pe ProjectEntry
 	if err != nil {
 		var ccf *ddbtypes.ConditionalCheckFailedException
 		if errors.As(err, &ccf) {
-			return fmt.Errorf("project %s already exists", pe.ProjectID)
+			return ErrProjectExists
 		}
 		return fmt.Errorf("failed to create project: %w", err)
 	}
@@ -169,15 +362,165 @@ func (d *DynamoDBClient) ReadProjectEntry(ctx context.Context, project string) (
 		return ProjectEntry{}, ErrProjectNotFound
 	}
 
-	repo, ok := result.Item["repository"].(*ddbtypes.AttributeValueMemberS)
+	return parseProjectFromItem(result.Item, project)
+}
+
+// ListProjectEntries returns the entries of all projects. It scans the table
+// and is meant for background work rather than requests.
+func (d *DynamoDBClient) ListProjectEntries(ctx context.Context) ([]ProjectEntry, error) {
+	projectPKPrefix := fmt.Sprintf(projectPKFmt, "")
+
+	scanInput := &dynamodb.ScanInput{
+		TableName:        aws.String(d.tableName),
+		FilterExpression: aws.String("sk = :sk AND begins_with(pk, :pk_prefix)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":sk":        &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+			":pk_prefix": &ddbtypes.AttributeValueMemberS{Value: projectPKPrefix},
+		},
+	}
+
+	projects := []ProjectEntry{}
+	for {
+		result, err := d.svc.Scan(ctx, scanInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan projects: %w", err)
+		}
+
+		for _, item := range result.Items {
+			pk, ok := item[primaryKey].(*ddbtypes.AttributeValueMemberS)
+			if !ok {
+				return nil, fmt.Errorf("invalid primary key attribute")
+			}
+
+			pe, err := parseProjectFromItem(item, strings.TrimPrefix(pk.Value, projectPKPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse project: %w", err)
+			}
+			projects = append(projects, pe)
+		}
+
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		scanInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return projects, nil
+}
+
+// parseProjectFromItem converts a DynamoDB item to a ProjectEntry
+func parseProjectFromItem(item map[string]ddbtypes.AttributeValue, project string) (ProjectEntry, error) {
+	var err error
+
+	repo, ok := item["repository"].(*ddbtypes.AttributeValueMemberS)
 	if !ok {
 		return ProjectEntry{}, fmt.Errorf("invalid repository attribute")
 	}
 
-	return ProjectEntry{
+	pe := ProjectEntry{
 		ProjectID:  project,
 		Repository: repo.Value,
-	}, nil
+	}
+
+	if policy, ok := item["policy"].(*ddbtypes.AttributeValueMemberS); ok {
+		if err := json.Unmarshal([]byte(policy.Value), &pe.Policy); err != nil {
+			return ProjectEntry{}, fmt.Errorf("invalid policy attribute: %w", err)
+		}
+	}
+
+	if limit, ok := item["token_limit"].(*ddbtypes.AttributeValueMemberN); ok {
+		if pe.TokenLimit, err = strconv.Atoi(limit.Value); err != nil {
+			return ProjectEntry{}, fmt.Errorf("invalid token_limit attribute: %w", err)
+		}
+	}
+
+	if ttl, ok := item["token_ttl"].(*ddbtypes.AttributeValueMemberN); ok {
+		if pe.TokenTTL, err = strconv.Atoi(ttl.Value); err != nil {
+			return ProjectEntry{}, fmt.Errorf("invalid token_ttl attribute: %w", err)
+		}
+	}
+
+	if quota, ok := item["workflow_quota"].(*ddbtypes.AttributeValueMemberN); ok {
+		if pe.WorkflowQuota, err = strconv.Atoi(quota.Value); err != nil {
+			return ProjectEntry{}, fmt.Errorf("invalid workflow_quota attribute: %w", err)
+		}
+	}
+
+	return pe, nil
+}
+
+// UpdateProjectEntry updates the repository, token settings and workflow
+// quota of an existing project, leaving its policy as is.
+func (d *DynamoDBClient) UpdateProjectEntry(ctx context.Context, pe ProjectEntry) error {
+	set := []string{"repository = :repository"}
+	remove := []string{}
+	values := map[string]ddbtypes.AttributeValue{
+		":repository": &ddbtypes.AttributeValueMemberS{Value: pe.Repository},
+	}
+
+	// Unset settings are removed, as projectItem does not store them.
+	settings := []struct {
+		name  string
+		value int
+	}{
+		{"token_limit", pe.TokenLimit},
+		{"token_ttl", pe.TokenTTL},
+		{"workflow_quota", pe.WorkflowQuota},
+	}
+	for _, s := range settings {
+		if s.value <= 0 {
+			remove = append(remove, s.name)
+			continue
+		}
+		set = append(set, fmt.Sprintf("%s = :%s", s.name, s.name))
+		values[":"+s.name] = &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(s.value)}
+	}
+
+	expr := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		expr += " REMOVE " + strings.Join(remove, ", ")
+	}
+	return d.updateProjectItem(ctx, pe.ProjectID, expr, values)
+}
+
+// UpdateProjectPolicy replaces the policy of an existing project, leaving
+// its other settings as is.
+func (d *DynamoDBClient) UpdateProjectPolicy(ctx context.Context, project string, policy types.ProjectPolicy) error {
+	// Empty policies are removed, as projectItem does not store them.
+	if len(policy.Targets) == 0 && policy.ExtraPolicy == "" {
+		return d.updateProjectItem(ctx, project, "REMOVE policy", nil)
+	}
+
+	p, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	return d.updateProjectItem(ctx, project, "SET policy = :policy", map[string]ddbtypes.AttributeValue{
+		":policy": &ddbtypes.AttributeValueMemberS{Value: string(p)},
+	})
+}
+
+// updateProjectItem applies the update expression to the metadata item of an
+// existing project.
+func (d *DynamoDBClient) updateProjectItem(ctx context.Context, project, expr string, values map[string]ddbtypes.AttributeValue) error {
+	_, err := d.svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]ddbtypes.AttributeValue{
+			primaryKey: &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(projectPKFmt, project)},
+			sortKey:    &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+		},
+		UpdateExpression:          aws.String(expr),
+		ConditionExpression:       aws.String("attribute_exists(pk)"),
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrProjectNotFound
+		}
+		return fmt.Errorf("failed to update project: %w", err)
+	}
+	return nil
 }
 
 func (d *DynamoDBClient) DeleteProjectEntry(ctx context.Context, project string) error {
@@ -282,15 +625,40 @@ func (d *DynamoDBClient) DeleteProjectEntry(ctx context.Context, project string)
 	return nil
 }
 
-func (d *DynamoDBClient) CreateTokenEntry(ctx context.Context, token types.Token) error {
+// tokenItem returns the DynamoDB item of a token entry.
+func tokenItem(te TokenEntry) (map[string]ddbtypes.AttributeValue, error) {
 	item := map[string]ddbtypes.AttributeValue{
-		primaryKey:   &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(projectPKFmt, token.ProjectID)},
-		sortKey:      &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(tokenSKFmt, token.ProjectToken.ID)},
-		"created_at": &ddbtypes.AttributeValueMemberS{Value: token.CreatedAt},
-		"expires_at": &ddbtypes.AttributeValueMemberS{Value: token.ExpiresAt},
+		primaryKey:   &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(projectPKFmt, te.ProjectID)},
+		sortKey:      &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(tokenSKFmt, te.TokenID)},
+		"created_at": &ddbtypes.AttributeValueMemberS{Value: te.CreatedAt},
+		"expires_at": &ddbtypes.AttributeValueMemberS{Value: te.ExpiresAt},
+		"role_id":    &ddbtypes.AttributeValueMemberS{Value: te.RoleID},
 	}
 
-	_, err := d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+	if len(te.Scopes) > 0 {
+		scopes, err := json.Marshal(te.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scopes: %w", err)
+		}
+		item["scopes"] = &ddbtypes.AttributeValueMemberS{Value: string(scopes)}
+	}
+	return item, nil
+}
+
+func (d *DynamoDBClient) CreateTokenEntry(ctx context.Context, token types.Token) error {
+	item, err := tokenItem(TokenEntry{
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+		ProjectID: token.ProjectID,
+		RoleID:    token.RoleID,
+		Scopes:    token.Scopes,
+		TokenID:   token.ProjectToken.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.svc.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName:           aws.String(d.tableName),
 		Item:                item,
 		ConditionExpression: aws.String("attribute_not_exists(sk)"),
@@ -305,6 +673,28 @@ func (d *DynamoDBClient) CreateTokenEntry(ctx context.Context, token types.Token
 	return nil
 }
 
+// UpdateTokenEntry replaces an existing token entry.
+func (d *DynamoDBClient) UpdateTokenEntry(ctx context.Context, te TokenEntry) error {
+	item, err := tokenItem(te)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(sk)"),
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrTokenNotFound
+		}
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	return nil
+}
+
 func (d *DynamoDBClient) ReadTokenEntryByProject(ctx context.Context, project, token string) (TokenEntry, error) {
 	result, err := d.svc.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(d.tableName),
@@ -407,10 +797,706 @@ func (d *DynamoDBClient) parseTokenFromItem(item map[string]ddbtypes.AttributeVa
 		return TokenEntry{}, fmt.Errorf("invalid expires_at attribute")
 	}
 
-	return TokenEntry{
+	te := TokenEntry{
 		CreatedAt: createdAt.Value,
 		ExpiresAt: expiresAt.Value,
 		ProjectID: project,
 		TokenID:   tokenID,
+	}
+
+	if roleID, ok := item["role_id"].(*ddbtypes.AttributeValueMemberS); ok {
+		te.RoleID = roleID.Value
+	}
+
+	if scopes, ok := item["scopes"].(*ddbtypes.AttributeValueMemberS); ok {
+		if err := json.Unmarshal([]byte(scopes.Value), &te.Scopes); err != nil {
+			return TokenEntry{}, fmt.Errorf("invalid scopes attribute: %w", err)
+		}
+	}
+
+	return te, nil
+}
+
+// AcquireLease takes the named lease for the owner until the duration has
+// passed. The lease is acquired when it does not exist, has expired or is
+// already held by the owner.
+func (d *DynamoDBClient) AcquireLease(ctx context.Context, name, owner string, dur time.Duration) (bool, error) {
+	now := time.Now()
+
+	_, err := d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]ddbtypes.AttributeValue{
+			primaryKey:   &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(leasePKFmt, name)},
+			sortKey:      &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+			"owner":      &ddbtypes.AttributeValueMemberS{Value: owner},
+			"expires_at": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(dur).Unix(), 10)},
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(pk) OR expires_at < :now OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]string{"#owner": "owner"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now":   &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":owner": &ddbtypes.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return true, nil
+}
+
+// identityItem returns the DynamoDB item of an identity entry. The roles are
+// stored as JSON.
+func identityItem(ie IdentityEntry) (map[string]ddbtypes.AttributeValue, error) {
+	roles, err := json.Marshal(ie.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid roles: %w", err)
+	}
+
+	return map[string]ddbtypes.AttributeValue{
+		primaryKey:    &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(identityPKFmt, ie.Name)},
+		sortKey:       &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+		"created_at":  &ddbtypes.AttributeValueMemberS{Value: ie.CreatedAt},
+		"roles":       &ddbtypes.AttributeValueMemberS{Value: string(roles)},
+		"secret_hash": &ddbtypes.AttributeValueMemberS{Value: ie.SecretHash},
 	}, nil
 }
+
+func (d *DynamoDBClient) CreateIdentityEntry(ctx context.Context, ie IdentityEntry) error {
+	item, err := identityItem(ie)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrIdentityExists
+		}
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoDBClient) ReadIdentityEntry(ctx context.Context, name string) (IdentityEntry, error) {
+	result, err := d.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]ddbtypes.AttributeValue{
+			primaryKey: &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(identityPKFmt, name)},
+			sortKey:    &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+		},
+	})
+	if err != nil {
+		return IdentityEntry{}, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	if result.Item == nil {
+		return IdentityEntry{}, ErrIdentityNotFound
+	}
+
+	return parseIdentityFromItem(result.Item, name)
+}
+
+// ListIdentityEntries returns the entries of all identities. It scans the
+// table, identities are only listed by admins.
+func (d *DynamoDBClient) ListIdentityEntries(ctx context.Context) ([]IdentityEntry, error) {
+	identityPKPrefix := fmt.Sprintf(identityPKFmt, "")
+
+	scanInput := &dynamodb.ScanInput{
+		TableName:        aws.String(d.tableName),
+		FilterExpression: aws.String("sk = :sk AND begins_with(pk, :pk_prefix)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":sk":        &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+			":pk_prefix": &ddbtypes.AttributeValueMemberS{Value: identityPKPrefix},
+		},
+	}
+
+	identities := []IdentityEntry{}
+	for {
+		result, err := d.svc.Scan(ctx, scanInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan identities: %w", err)
+		}
+
+		for _, item := range result.Items {
+			pk, ok := item[primaryKey].(*ddbtypes.AttributeValueMemberS)
+			if !ok {
+				return nil, fmt.Errorf("invalid primary key attribute")
+			}
+
+			ie, err := parseIdentityFromItem(item, strings.TrimPrefix(pk.Value, identityPKPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse identity: %w", err)
+			}
+			identities = append(identities, ie)
+		}
+
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		scanInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return identities, nil
+}
+
+// parseIdentityFromItem converts a DynamoDB item to an IdentityEntry
+func parseIdentityFromItem(item map[string]ddbtypes.AttributeValue, name string) (IdentityEntry, error) {
+	createdAt, ok := item["created_at"].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return IdentityEntry{}, fmt.Errorf("invalid created_at attribute")
+	}
+
+	secretHash, ok := item["secret_hash"].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return IdentityEntry{}, fmt.Errorf("invalid secret_hash attribute")
+	}
+
+	roles, ok := item["roles"].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return IdentityEntry{}, fmt.Errorf("invalid roles attribute")
+	}
+
+	ie := IdentityEntry{
+		CreatedAt:  createdAt.Value,
+		Name:       name,
+		SecretHash: secretHash.Value,
+	}
+
+	if err := json.Unmarshal([]byte(roles.Value), &ie.Roles); err != nil {
+		return IdentityEntry{}, fmt.Errorf("invalid roles attribute: %w", err)
+	}
+
+	return ie, nil
+}
+
+// UpdateIdentityEntry replaces an existing identity entry.
+func (d *DynamoDBClient) UpdateIdentityEntry(ctx context.Context, ie IdentityEntry) error {
+	item, err := identityItem(ie)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(pk)"),
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrIdentityNotFound
+		}
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoDBClient) DeleteIdentityEntry(ctx context.Context, name string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]ddbtypes.AttributeValue{
+			primaryKey: &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(identityPKFmt, name)},
+			sortKey:    &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+		},
+	}
+
+	if _, err := d.svc.DeleteItem(ctx, input); err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	return nil
+}
+
+// idempotencyKey returns the DynamoDB key of a principal's idempotency key,
+// keys are scoped to the principal which used them.
+func idempotencyKey(principal, key string) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		primaryKey: &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(idempotencyPKFmt, principal)},
+		sortKey:    &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(idempotencySKFmt, key)},
+	}
+}
+
+func idempotencyItem(ie IdempotencyEntry) map[string]ddbtypes.AttributeValue {
+	item := idempotencyKey(ie.Principal, ie.Key)
+	item["request_hash"] = &ddbtypes.AttributeValueMemberS{Value: ie.RequestHash}
+	item["locked_until"] = &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(ie.LockedUntil.Unix(), 10)}
+	item["ttl"] = &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(ie.ExpiresAt.Unix(), 10)}
+	if ie.WorkflowName != "" {
+		item["workflow_name"] = &ddbtypes.AttributeValueMemberS{Value: ie.WorkflowName}
+	}
+	if ie.SubmissionID != "" {
+		item["submission_id"] = &ddbtypes.AttributeValueMemberS{Value: ie.SubmissionID}
+	}
+	return item
+}
+
+// CreateIdempotencyEntry claims the key when it does not exist, has expired
+// or is held by a request which is no longer locked and never submitted or
+// queued a workflow.
+func (d *DynamoDBClient) CreateIdempotencyEntry(ctx context.Context, ie IdempotencyEntry) error {
+	_, err := d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.tableName),
+		Item:                idempotencyItem(ie),
+		ConditionExpression: aws.String("attribute_not_exists(pk) OR #ttl < :now OR (attribute_not_exists(workflow_name) AND attribute_not_exists(submission_id) AND locked_until < :now)"),
+		// ttl is a reserved word.
+		ExpressionAttributeNames: map[string]string{"#ttl": "ttl"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrIdempotencyKeyExists
+		}
+		return fmt.Errorf("failed to create idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReadIdempotencyEntry returns ErrIdempotencyKeyNotFound for expired keys,
+// DynamoDB can take days to remove them.
+func (d *DynamoDBClient) ReadIdempotencyEntry(ctx context.Context, principal, key string) (IdempotencyEntry, error) {
+	result, err := d.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tableName),
+		Key:            idempotencyKey(principal, key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return IdempotencyEntry{}, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if result.Item == nil {
+		return IdempotencyEntry{}, ErrIdempotencyKeyNotFound
+	}
+
+	ie, err := parseIdempotencyFromItem(result.Item, principal, key)
+	if err != nil {
+		return IdempotencyEntry{}, err
+	}
+
+	if ie.ExpiresAt.Before(time.Now()) {
+		return IdempotencyEntry{}, ErrIdempotencyKeyNotFound
+	}
+	return ie, nil
+}
+
+// parseIdempotencyFromItem converts a DynamoDB item to an IdempotencyEntry
+func parseIdempotencyFromItem(item map[string]ddbtypes.AttributeValue, principal, key string) (IdempotencyEntry, error) {
+	requestHash, ok := item["request_hash"].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return IdempotencyEntry{}, fmt.Errorf("invalid request_hash attribute")
+	}
+
+	ie := IdempotencyEntry{
+		Key:         key,
+		Principal:   principal,
+		RequestHash: requestHash.Value,
+	}
+
+	for name, t := range map[string]*time.Time{"locked_until": &ie.LockedUntil, "ttl": &ie.ExpiresAt} {
+		v, ok := item[name].(*ddbtypes.AttributeValueMemberN)
+		if !ok {
+			return IdempotencyEntry{}, fmt.Errorf("invalid %s attribute", name)
+		}
+		sec, err := strconv.ParseInt(v.Value, 10, 64)
+		if err != nil {
+			return IdempotencyEntry{}, fmt.Errorf("invalid %s attribute: %w", name, err)
+		}
+		*t = time.Unix(sec, 0)
+	}
+
+	if workflowName, ok := item["workflow_name"].(*ddbtypes.AttributeValueMemberS); ok {
+		ie.WorkflowName = workflowName.Value
+	}
+	if submissionID, ok := item["submission_id"].(*ddbtypes.AttributeValueMemberS); ok {
+		ie.SubmissionID = submissionID.Value
+	}
+
+	return ie, nil
+}
+
+// UpdateIdempotencyEntry replaces the entry of a key, it is updated with the
+// workflow name once the workflow has been submitted.
+func (d *DynamoDBClient) UpdateIdempotencyEntry(ctx context.Context, ie IdempotencyEntry) error {
+	_, err := d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      idempotencyItem(ie),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoDBClient) DeleteIdempotencyEntry(ctx context.Context, principal, key string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       idempotencyKey(principal, key),
+	}
+
+	if _, err := d.svc.DeleteItem(ctx, input); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func submissionItem(se SubmissionEntry) map[string]ddbtypes.AttributeValue {
+	item := map[string]ddbtypes.AttributeValue{
+		primaryKey:        &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(submissionPKFmt, se.ID)},
+		sortKey:           &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+		"status":          &ddbtypes.AttributeValueMemberS{Value: se.Status},
+		"request":         &ddbtypes.AttributeValueMemberS{Value: se.Request},
+		"attempts":        &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(se.Attempts)},
+		"created_at":      &ddbtypes.AttributeValueMemberS{Value: se.CreatedAt.UTC().Format(time.RFC3339)},
+		"next_attempt_at": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(se.NextAttemptAt.Unix(), 10)},
+		"ttl":             &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(se.ExpiresAt.Unix(), 10)},
+	}
+	if se.Error != "" {
+		item["error"] = &ddbtypes.AttributeValueMemberS{Value: se.Error}
+	}
+	if se.WorkflowName != "" {
+		item["workflow_name"] = &ddbtypes.AttributeValueMemberS{Value: se.WorkflowName}
+	}
+	return item
+}
+
+// submissionQueueKey returns the key of a submission's item in the queue.
+func submissionQueueKey(id string) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		primaryKey: &ddbtypes.AttributeValueMemberS{Value: submissionQueuePK},
+		sortKey:    &ddbtypes.AttributeValueMemberS{Value: id},
+	}
+}
+
+// submissionQueueItem returns the queue item of a submission, which is not
+// claimed.
+func submissionQueueItem(se SubmissionEntry) map[string]ddbtypes.AttributeValue {
+	item := submissionQueueKey(se.ID)
+	item["next_attempt_at"] = &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(se.NextAttemptAt.Unix(), 10)}
+	return item
+}
+
+func (d *DynamoDBClient) CreateSubmissionEntry(ctx context.Context, se SubmissionEntry) error {
+	_, err := d.svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []ddbtypes.TransactWriteItem{
+			{Put: &ddbtypes.Put{
+				TableName:           aws.String(d.tableName),
+				Item:                submissionItem(se),
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			}},
+			{Put: &ddbtypes.Put{
+				TableName: aws.String(d.tableName),
+				Item:      submissionQueueItem(se),
+			}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create submission: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoDBClient) ReadSubmissionEntry(ctx context.Context, id string) (SubmissionEntry, error) {
+	result, err := d.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]ddbtypes.AttributeValue{
+			primaryKey: &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(submissionPKFmt, id)},
+			sortKey:    &ddbtypes.AttributeValueMemberS{Value: metadataSK},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return SubmissionEntry{}, fmt.Errorf("failed to get submission: %w", err)
+	}
+
+	if result.Item == nil {
+		return SubmissionEntry{}, ErrSubmissionNotFound
+	}
+
+	return parseSubmissionFromItem(result.Item, id)
+}
+
+// parseSubmissionFromItem converts a DynamoDB item to a SubmissionEntry
+func parseSubmissionFromItem(item map[string]ddbtypes.AttributeValue, id string) (SubmissionEntry, error) {
+	se := SubmissionEntry{ID: id}
+
+	for name, s := range map[string]*string{"status": &se.Status, "request": &se.Request} {
+		v, ok := item[name].(*ddbtypes.AttributeValueMemberS)
+		if !ok {
+			return SubmissionEntry{}, fmt.Errorf("invalid %s attribute", name)
+		}
+		*s = v.Value
+	}
+
+	attempts, ok := item["attempts"].(*ddbtypes.AttributeValueMemberN)
+	if !ok {
+		return SubmissionEntry{}, fmt.Errorf("invalid attempts attribute")
+	}
+	var err error
+	if se.Attempts, err = strconv.Atoi(attempts.Value); err != nil {
+		return SubmissionEntry{}, fmt.Errorf("invalid attempts attribute: %w", err)
+	}
+
+	createdAt, ok := item["created_at"].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return SubmissionEntry{}, fmt.Errorf("invalid created_at attribute")
+	}
+	if se.CreatedAt, err = time.Parse(time.RFC3339, createdAt.Value); err != nil {
+		return SubmissionEntry{}, fmt.Errorf("invalid created_at attribute: %w", err)
+	}
+
+	for name, t := range map[string]*time.Time{"next_attempt_at": &se.NextAttemptAt, "ttl": &se.ExpiresAt} {
+		v, ok := item[name].(*ddbtypes.AttributeValueMemberN)
+		if !ok {
+			return SubmissionEntry{}, fmt.Errorf("invalid %s attribute", name)
+		}
+		sec, err := strconv.ParseInt(v.Value, 10, 64)
+		if err != nil {
+			return SubmissionEntry{}, fmt.Errorf("invalid %s attribute: %w", name, err)
+		}
+		*t = time.Unix(sec, 0)
+	}
+
+	if v, ok := item["error"].(*ddbtypes.AttributeValueMemberS); ok {
+		se.Error = v.Value
+	}
+	if v, ok := item["workflow_name"].(*ddbtypes.AttributeValueMemberS); ok {
+		se.WorkflowName = v.Value
+	}
+
+	return se, nil
+}
+
+func (d *DynamoDBClient) ListQueuedSubmissions(ctx context.Context, now time.Time) ([]string, error) {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		FilterExpression:       aws.String("next_attempt_at <= :now"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":pk":  &ddbtypes.AttributeValueMemberS{Value: submissionQueuePK},
+			":now": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	}
+
+	ids := []string{}
+	for {
+		result, err := d.svc.Query(ctx, queryInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query submission queue: %w", err)
+		}
+
+		for _, item := range result.Items {
+			sk, ok := item[sortKey].(*ddbtypes.AttributeValueMemberS)
+			if !ok {
+				return nil, fmt.Errorf("invalid sort key attribute")
+			}
+			ids = append(ids, sk.Value)
+		}
+
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return ids, nil
+}
+
+// ClaimSubmission claims a submission which is still queued and not claimed,
+// or whose claim has expired because its replica stopped.
+func (d *DynamoDBClient) ClaimSubmission(ctx context.Context, id string, dur time.Duration) (bool, error) {
+	now := time.Now()
+
+	_, err := d.svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.tableName),
+		Key:                 submissionQueueKey(id),
+		UpdateExpression:    aws.String("SET claimed_until = :until"),
+		ConditionExpression: aws.String("attribute_exists(pk) AND (attribute_not_exists(claimed_until) OR claimed_until < :now)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now":   &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":until": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(dur).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim submission: %w", err)
+	}
+	return true, nil
+}
+
+// UpdateSubmissionEntry replaces the entry of a submission. A submission
+// which is still queued is put back in the queue for its next attempt, others
+// are removed from it.
+func (d *DynamoDBClient) UpdateSubmissionEntry(ctx context.Context, se SubmissionEntry) error {
+	queueItem := ddbtypes.TransactWriteItem{
+		Delete: &ddbtypes.Delete{
+			TableName: aws.String(d.tableName),
+			Key:       submissionQueueKey(se.ID),
+		},
+	}
+	if se.Status == SubmissionQueued {
+		queueItem = ddbtypes.TransactWriteItem{
+			Put: &ddbtypes.Put{
+				TableName: aws.String(d.tableName),
+				Item:      submissionQueueItem(se),
+			},
+		}
+	}
+
+	_, err := d.svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []ddbtypes.TransactWriteItem{
+			{Put: &ddbtypes.Put{
+				TableName:           aws.String(d.tableName),
+				Item:                submissionItem(se),
+				ConditionExpression: aws.String("attribute_exists(pk)"),
+			}},
+			queueItem,
+		},
+	})
+	if err != nil {
+		var tce *ddbtypes.TransactionCanceledException
+		if errors.As(err, &tce) && len(tce.CancellationReasons) > 0 && aws.ToString(tce.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+			return ErrSubmissionNotFound
+		}
+		return fmt.Errorf("failed to update submission: %w", err)
+	}
+	return nil
+}
+
+// webhookKey returns the key of a webhook, which is stored with its project.
+func webhookKey(project, id string) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		primaryKey: &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(projectPKFmt, project)},
+		sortKey:    &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(webhookSKFmt, id)},
+	}
+}
+
+func (d *DynamoDBClient) CreateWebhookEntry(ctx context.Context, we WebhookEntry) error {
+	item := webhookKey(we.ProjectID, we.ID)
+	item["created_at"] = &ddbtypes.AttributeValueMemberS{Value: we.CreatedAt}
+	item["secret"] = &ddbtypes.AttributeValueMemberS{Value: we.Secret}
+	item["url"] = &ddbtypes.AttributeValueMemberS{Value: we.URL}
+	if len(we.Events) > 0 {
+		item["events"] = &ddbtypes.AttributeValueMemberSS{Value: we.Events}
+	}
+
+	_, err := d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(sk)"),
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("webhook %s already exists for project %s", we.ID, we.ProjectID)
+		}
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoDBClient) ListWebhookEntries(ctx context.Context, project string) ([]WebhookEntry, error) {
+	webhookSKPrefix := fmt.Sprintf(webhookSKFmt, "")
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk_prefix)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":pk":        &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(projectPKFmt, project)},
+			":sk_prefix": &ddbtypes.AttributeValueMemberS{Value: webhookSKPrefix},
+		},
+	}
+
+	result, err := d.svc.Query(ctx, queryInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+
+	webhooks := []WebhookEntry{}
+	for _, item := range result.Items {
+		we, err := parseWebhookFromItem(item, project, webhookSKPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook: %w", err)
+		}
+		webhooks = append(webhooks, we)
+	}
+
+	return webhooks, nil
+}
+
+// parseWebhookFromItem converts a DynamoDB item to a WebhookEntry
+func parseWebhookFromItem(item map[string]ddbtypes.AttributeValue, project, skPrefix string) (WebhookEntry, error) {
+	sk, ok := item[sortKey].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return WebhookEntry{}, fmt.Errorf("invalid sort key attribute")
+	}
+
+	we := WebhookEntry{
+		ID:        strings.TrimPrefix(sk.Value, skPrefix),
+		ProjectID: project,
+	}
+
+	for name, s := range map[string]*string{"created_at": &we.CreatedAt, "secret": &we.Secret, "url": &we.URL} {
+		v, ok := item[name].(*ddbtypes.AttributeValueMemberS)
+		if !ok {
+			return WebhookEntry{}, fmt.Errorf("invalid %s attribute", name)
+		}
+		*s = v.Value
+	}
+
+	if events, ok := item["events"].(*ddbtypes.AttributeValueMemberSS); ok {
+		we.Events = events.Value
+	}
+
+	return we, nil
+}
+
+func (d *DynamoDBClient) DeleteWebhookEntry(ctx context.Context, project, id string) error {
+	_, err := d.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(d.tableName),
+		Key:                 webhookKey(project, id),
+		ConditionExpression: aws.String("attribute_exists(sk)"),
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrWebhookNotFound
+		}
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// RecordWebhookEvent puts an item per event of a workflow, which can be
+// removed by the table's TTL once the workflow is no longer watched.
+func (d *DynamoDBClient) RecordWebhookEvent(ctx context.Context, workflowName, event string, expiresAt time.Time) (bool, error) {
+	_, err := d.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]ddbtypes.AttributeValue{
+			primaryKey: &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf(webhookEventPKFmt, workflowName)},
+			sortKey:    &ddbtypes.AttributeValueMemberS{Value: event},
+			"ttl":      &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+	if err != nil {
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+	return true, nil
+}
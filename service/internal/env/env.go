@@ -2,9 +2,11 @@ package env
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -16,11 +18,23 @@ func init() {
 const legacyAppPrefix = "ARGO_CLOUDOPS"
 const appPrefix = "CELLO"
 
+// maxTokenTTL is the lifetime of the Vault secret IDs of project tokens,
+// which token TTLs cannot exceed.
+const maxTokenTTL = 8776 * time.Hour
+
 type Vars struct {
 	AdminSecret           string   `split_words:"true" required:"true"`
-	VaultRole             string   `envconfig:"VAULT_ROLE" required:"true"`
-	VaultSecret           string   `envconfig:"VAULT_SECRET" required:"true"`
-	VaultAddress          string   `envconfig:"VAULT_ADDR" required:"true"`
+	CredentialsProvider   string   `split_words:"true" default:"vault"`
+	LocalProviderFile     string   `split_words:"true" default:"cello-local.enc"`
+	LocalProviderKey      string   `split_words:"true"`
+	LocalProviderToken    string   `split_words:"true"`
+	VaultAuthMethod       string   `envconfig:"VAULT_AUTH_METHOD" default:"approle"`
+	VaultAuthMount        string   `envconfig:"VAULT_AUTH_MOUNT"`
+	VaultAuthRole         string   `envconfig:"VAULT_AUTH_ROLE"`
+	VaultAuthTokenFile    string   `envconfig:"VAULT_AUTH_TOKEN_FILE"`
+	VaultRole             string   `envconfig:"VAULT_ROLE"`
+	VaultSecret           string   `envconfig:"VAULT_SECRET"`
+	VaultAddress          string   `envconfig:"VAULT_ADDR"`
 	ArgoAddress           string   `envconfig:"ARGO_ADDR" required:"true"`
 	ArgoNamespace         string   `envconfig:"WORKFLOW_EXECUTION_NAMESPACE" default:"argo"`
 	ConfigFilePath        string   `envconfig:"CONFIG" default:"cello.yaml"`
@@ -34,6 +48,88 @@ type Vars struct {
 	DynamoDBEndpoint      string   `envconfig:"CELLO_DYNAMODB_ENDPOINT"`
 	DynamoDBTableName     string   `envconfig:"CELLO_DYNAMODB_TABLE_NAME" required:"true"`
 	ImageURIs             []string `envconfig:"IMAGE_URIS"`
+	// TokenLimit is the number of tokens a project can have, unless set on
+	// the project.
+	TokenLimit int `split_words:"true" default:"2"`
+	// TokenMinTTL and TokenMaxTTL bound the TTLs requested for project
+	// tokens. Tokens are issued with TokenMaxTTL unless a TTL is requested or
+	// set on the project.
+	TokenMinTTL time.Duration `split_words:"true" default:"1h"`
+	TokenMaxTTL time.Duration `split_words:"true" default:"8776h"`
+	// TokenMaxGracePeriod bounds how long a rotated token remains valid.
+	TokenMaxGracePeriod time.Duration `split_words:"true" default:"24h"`
+	// TokenSweepInterval is how often expired and orphaned tokens are
+	// removed, zero disables the sweeper.
+	TokenSweepInterval time.Duration `split_words:"true" default:"15m"`
+	// OIDCIssuer enables the Bearer authentication of users with the JWTs it
+	// issues for OIDCAudience, which are signed by the keys of OIDCJWKSFile
+	// or OIDCJWKSURL.
+	OIDCIssuer        string `envconfig:"OIDC_ISSUER"`
+	OIDCAudience      string `envconfig:"OIDC_AUDIENCE"`
+	OIDCJWKSFile      string `envconfig:"OIDC_JWKS_FILE"`
+	OIDCJWKSURL       string `envconfig:"OIDC_JWKS_URL"`
+	OIDCUsernameClaim string `envconfig:"OIDC_USERNAME_CLAIM" default:"email"`
+	OIDCGroupsClaim   string `envconfig:"OIDC_GROUPS_CLAIM" default:"groups"`
+	// TracingExporter is where spans are exported, one of none, otlp or
+	// stdout. Requests are traced with every exporter, so their trace IDs
+	// are propagated and echoed.
+	TracingExporter string `split_words:"true" default:"none"`
+	// TracingEndpoint is the base URL of the OTLP/HTTP collector.
+	TracingEndpoint string `split_words:"true"`
+	// HealthCheckTimeout bounds each dependency check of the health
+	// endpoints, and HealthCacheTTL is how long /health/ready reuses their
+	// results.
+	HealthCheckTimeout time.Duration `split_words:"true" default:"5s"`
+	HealthCacheTTL     time.Duration `split_words:"true" default:"5s"`
+	// HealthGitRepository is a canary repository /health/ready checks can be
+	// reached, it is not checked when empty.
+	HealthGitRepository string `split_words:"true"`
+	// TLSDisabled serves plain HTTP, for running behind a proxy terminating
+	// TLS. Otherwise TLSCertFile and TLSKeyFile are served, and reloaded
+	// when they change.
+	TLSDisabled bool   `envconfig:"TLS_DISABLED"`
+	TLSCertFile string `envconfig:"TLS_CERT_FILE" default:"ssl/certificate.crt"`
+	TLSKeyFile  string `envconfig:"TLS_KEY_FILE" default:"ssl/certificate.key"`
+	// TLSClientCAFile enables mutual TLS, client certificates signed by its
+	// CAs authenticate requests without an authorization header.
+	TLSClientCAFile string `envconfig:"TLS_CLIENT_CA_FILE"`
+	// RateLimit is the number of requests per second each principal can
+	// make, in bursts of up to RateLimitBurst, zero disables the limit.
+	RateLimit      float64 `split_words:"true"`
+	RateLimitBurst int     `split_words:"true" default:"20"`
+	// WorkflowQuota is the number of workflows a project can run
+	// concurrently unless set on the project, zero is unlimited.
+	WorkflowQuota int `split_words:"true"`
+	// HTTPReadHeaderTimeout, HTTPReadTimeout, HTTPWriteTimeout and
+	// HTTPIdleTimeout bound connections, log streams are not bound by the
+	// write timeout.
+	HTTPReadHeaderTimeout time.Duration `envconfig:"HTTP_READ_HEADER_TIMEOUT" default:"10s"`
+	HTTPReadTimeout       time.Duration `envconfig:"HTTP_READ_TIMEOUT" default:"1m"`
+	HTTPWriteTimeout      time.Duration `envconfig:"HTTP_WRITE_TIMEOUT" default:"2m"`
+	HTTPIdleTimeout       time.Duration `envconfig:"HTTP_IDLE_TIMEOUT" default:"2m"`
+	// ShutdownTimeout is how long requests in flight, including log streams,
+	// have to finish once the service is stopped.
+	ShutdownTimeout time.Duration `split_words:"true" default:"30s"`
+	// IdempotencyKeyTTL is how long the idempotency keys of workflow
+	// submissions are remembered.
+	IdempotencyKeyTTL time.Duration `split_words:"true" default:"24h"`
+	// SubmissionQueue lets requests queue their workflow submission, which
+	// is then submitted by a worker with retries.
+	SubmissionQueue bool `split_words:"true"`
+	// SubmissionPollInterval is how often queued submissions which are due
+	// are looked for.
+	SubmissionPollInterval time.Duration `split_words:"true" default:"5s"`
+	// SubmissionRetryTimeout is how long a queued submission is retried
+	// before it fails.
+	SubmissionRetryTimeout time.Duration `split_words:"true" default:"15m"`
+	// WebhookNotifications watches the workflows submitted by the service
+	// to notify the webhooks of their projects.
+	WebhookNotifications bool `split_words:"true"`
+	// WebhookTimeout bounds each attempt to deliver an event to a webhook.
+	WebhookTimeout time.Duration `split_words:"true" default:"10s"`
+	// WebhookAllowedNetworks are the CIDRs of loopback and private networks
+	// webhooks can be delivered to, which are otherwise refused.
+	WebhookAllowedNetworks []string `split_words:"true"`
 }
 
 var (
@@ -57,6 +153,209 @@ func (values Vars) validate() error {
 	if len(values.AdminSecret) < 16 {
 		return errors.New("admin secret must be at least 16 characers long")
 	}
+
+	if err := values.validateTokens(); err != nil {
+		return err
+	}
+
+	if err := values.validateOIDC(); err != nil {
+		return err
+	}
+
+	if err := values.validateTracing(); err != nil {
+		return err
+	}
+
+	if err := values.validateServer(); err != nil {
+		return err
+	}
+
+	if err := values.validateLimits(); err != nil {
+		return err
+	}
+
+	if err := values.validateSubmissionQueue(); err != nil {
+		return err
+	}
+
+	if values.WebhookNotifications && values.WebhookTimeout <= 0 {
+		return errors.New("webhook timeout must be positive")
+	}
+
+	if values.HealthCheckTimeout <= 0 {
+		return errors.New("health check timeout must be positive")
+	}
+
+	if values.HealthCacheTTL < 0 {
+		return errors.New("health cache ttl cannot be negative")
+	}
+
+	switch values.CredentialsProvider {
+	case "vault":
+		return values.validateVault()
+	case "local":
+		return values.validateLocalProvider()
+	}
+
+	// Other providers are validated against the registered providers at
+	// startup.
+	return nil
+}
+
+func (values Vars) validateVault() error {
+	if values.VaultAddress == "" {
+		return errors.New("vault address is required for the vault credentials provider")
+	}
+
+	switch values.VaultAuthMethod {
+	case "approle":
+		if values.VaultRole == "" || values.VaultSecret == "" {
+			return errors.New("vault role and vault secret are required for approle auth")
+		}
+	case "kubernetes":
+		if values.VaultAuthRole == "" {
+			return errors.New("vault auth role is required for kubernetes auth")
+		}
+	case "jwt":
+		if values.VaultAuthRole == "" || values.VaultAuthTokenFile == "" {
+			return errors.New("vault auth role and vault auth token file are required for jwt auth")
+		}
+	default:
+		return errors.New("vault auth method must be one of 'approle jwt kubernetes'")
+	}
+
+	return nil
+}
+
+func (values Vars) validateTokens() error {
+	if values.TokenLimit < 1 {
+		return errors.New("token limit must be at least 1")
+	}
+
+	if values.TokenMinTTL <= 0 || values.TokenMinTTL > values.TokenMaxTTL {
+		return errors.New("token min ttl must be positive and not greater than token max ttl")
+	}
+
+	if values.TokenMaxTTL > maxTokenTTL {
+		return fmt.Errorf("token max ttl cannot be greater than %s", maxTokenTTL)
+	}
+
+	if values.TokenMaxGracePeriod < 0 {
+		return errors.New("token max grace period cannot be negative")
+	}
+
+	if values.TokenSweepInterval < 0 {
+		return errors.New("token sweep interval cannot be negative")
+	}
+
+	return nil
+}
+
+func (values Vars) validateOIDC() error {
+	if values.OIDCIssuer == "" {
+		if values.OIDCJWKSFile != "" || values.OIDCJWKSURL != "" {
+			return errors.New("oidc issuer is required for oidc jwks")
+		}
+		return nil
+	}
+
+	if (values.OIDCJWKSFile == "") == (values.OIDCJWKSURL == "") {
+		return errors.New("one of oidc jwks file or oidc jwks url is required for the oidc issuer")
+	}
+
+	// Without an audience, tokens the issuer signs for any other application
+	// would be accepted.
+	if values.OIDCAudience == "" {
+		return errors.New("oidc audience is required for the oidc issuer")
+	}
+
+	return nil
+}
+
+func (values Vars) validateServer() error {
+	if !values.TLSDisabled && (values.TLSCertFile == "" || values.TLSKeyFile == "") {
+		return errors.New("tls cert file and tls key file are required unless tls is disabled")
+	}
+
+	if values.TLSDisabled && values.TLSClientCAFile != "" {
+		return errors.New("tls client ca file cannot be set when tls is disabled")
+	}
+
+	for name, d := range map[string]time.Duration{
+		"http read header timeout": values.HTTPReadHeaderTimeout,
+		"http read timeout":        values.HTTPReadTimeout,
+		"http write timeout":       values.HTTPWriteTimeout,
+		"http idle timeout":        values.HTTPIdleTimeout,
+		"shutdown timeout":         values.ShutdownTimeout,
+	} {
+		if d < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+
+	return nil
+}
+
+func (values Vars) validateLimits() error {
+	if values.RateLimit < 0 {
+		return errors.New("rate limit cannot be negative")
+	}
+
+	if values.RateLimit > 0 && values.RateLimitBurst < 1 {
+		return errors.New("rate limit burst must be at least 1")
+	}
+
+	if values.WorkflowQuota < 0 {
+		return errors.New("workflow quota cannot be negative")
+	}
+
+	if values.IdempotencyKeyTTL <= 0 {
+		return errors.New("idempotency key ttl must be positive")
+	}
+
+	return nil
+}
+
+func (values Vars) validateSubmissionQueue() error {
+	if !values.SubmissionQueue {
+		return nil
+	}
+
+	if values.SubmissionPollInterval <= 0 {
+		return errors.New("submission poll interval must be positive")
+	}
+
+	if values.SubmissionRetryTimeout <= 0 {
+		return errors.New("submission retry timeout must be positive")
+	}
+
+	return nil
+}
+
+func (values Vars) validateTracing() error {
+	switch values.TracingExporter {
+	case "none", "stdout":
+	case "otlp":
+		if values.TracingEndpoint == "" {
+			return errors.New("tracing endpoint is required for the otlp tracing exporter")
+		}
+	default:
+		return errors.New("tracing exporter must be one of 'none otlp stdout'")
+	}
+
+	return nil
+}
+
+func (values Vars) validateLocalProvider() error {
+	if len(values.LocalProviderKey) < 16 {
+		return errors.New("local provider key must be at least 16 characters long")
+	}
+
+	// Handlers log the first 8 characters of the credentials token.
+	if values.LocalProviderToken != "" && len(values.LocalProviderToken) < 8 {
+		return errors.New("local provider token must be at least 8 characters long")
+	}
+
 	return nil
 }
 
@@ -2,8 +2,10 @@ package env
 
 import (
 	"os"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,6 +35,49 @@ var nonPrefixedEnvVars = map[string]string{
 	"SSH_PEM_FILE": "/app/test/ssh.pem",
 }
 
+var optionalPrefixedEnvVars = []string{
+	"_CREDENTIALS_PROVIDER",
+	"_LOCAL_PROVIDER_FILE",
+	"_LOCAL_PROVIDER_KEY",
+	"_LOCAL_PROVIDER_TOKEN",
+	"_OIDC_ISSUER",
+	"_OIDC_AUDIENCE",
+	"_OIDC_JWKS_FILE",
+	"_OIDC_JWKS_URL",
+	"_OIDC_USERNAME_CLAIM",
+	"_OIDC_GROUPS_CLAIM",
+	"_TRACING_EXPORTER",
+	"_TRACING_ENDPOINT",
+	"_HEALTH_CHECK_TIMEOUT",
+	"_HEALTH_CACHE_TTL",
+	"_HEALTH_GIT_REPOSITORY",
+	"_TLS_DISABLED",
+	"_TLS_CERT_FILE",
+	"_TLS_KEY_FILE",
+	"_TLS_CLIENT_CA_FILE",
+	"_RATE_LIMIT",
+	"_RATE_LIMIT_BURST",
+	"_WORKFLOW_QUOTA",
+	"_HTTP_READ_HEADER_TIMEOUT",
+	"_HTTP_READ_TIMEOUT",
+	"_HTTP_WRITE_TIMEOUT",
+	"_HTTP_IDLE_TIMEOUT",
+	"_SHUTDOWN_TIMEOUT",
+	"_IDEMPOTENCY_KEY_TTL",
+	"_SUBMISSION_QUEUE",
+	"_SUBMISSION_POLL_INTERVAL",
+	"_SUBMISSION_RETRY_TIMEOUT",
+	"_WEBHOOK_NOTIFICATIONS",
+	"_WEBHOOK_TIMEOUT",
+}
+
+var vaultAuthEnvVars = []string{
+	"VAULT_AUTH_METHOD",
+	"VAULT_AUTH_MOUNT",
+	"VAULT_AUTH_ROLE",
+	"VAULT_AUTH_TOKEN_FILE",
+}
+
 func reset() {
 	for k := range prefixedEnvVars {
 		os.Unsetenv(appPrefix + k)
@@ -40,6 +85,13 @@ func reset() {
 	for k := range nonPrefixedEnvVars {
 		os.Unsetenv(k)
 	}
+	for _, k := range optionalPrefixedEnvVars {
+		os.Unsetenv(appPrefix + k)
+	}
+	for _, k := range vaultAuthEnvVars {
+		os.Unsetenv(k)
+		os.Unsetenv(appPrefix + "_" + k)
+	}
 
 	instance = Vars{}
 	once = sync.Once{}
@@ -88,6 +140,7 @@ func TestDefaults(t *testing.T) {
 	os.Setenv("VAULT_ADDR", "1.2.3.4")
 	os.Setenv("ARGO_ADDR", "2.3.4.5")
 	os.Setenv(appPrefix+"_GIT_AUTH_METHOD", "https")
+	os.Setenv(appPrefix+"_DYNAMODB_TABLE_NAME", "cello")
 
 	// When
 	vars, _ := GetEnv()
@@ -97,6 +150,601 @@ func TestDefaults(t *testing.T) {
 	assert.Equal(t, "cello.yaml", vars.ConfigFilePath)
 	assert.Equal(t, 8443, vars.Port)
 	assert.Equal(t, "", vars.DynamoDBEndpoint)
+	assert.Equal(t, "approle", vars.VaultAuthMethod)
+	assert.Equal(t, "vault", vars.CredentialsProvider)
+	assert.Equal(t, "cello-local.enc", vars.LocalProviderFile)
+	assert.Equal(t, 2, vars.TokenLimit)
+	assert.Equal(t, time.Hour, vars.TokenMinTTL)
+	assert.Equal(t, 8776*time.Hour, vars.TokenMaxTTL)
+	assert.Equal(t, 24*time.Hour, vars.TokenMaxGracePeriod)
+	assert.Equal(t, 15*time.Minute, vars.TokenSweepInterval)
+	assert.Equal(t, "", vars.OIDCIssuer)
+	assert.Equal(t, "email", vars.OIDCUsernameClaim)
+	assert.Equal(t, "groups", vars.OIDCGroupsClaim)
+	assert.Equal(t, "none", vars.TracingExporter)
+	assert.Equal(t, 5*time.Second, vars.HealthCheckTimeout)
+	assert.Equal(t, 5*time.Second, vars.HealthCacheTTL)
+	assert.Equal(t, "", vars.HealthGitRepository)
+	assert.False(t, vars.TLSDisabled)
+	assert.Equal(t, "ssl/certificate.crt", vars.TLSCertFile)
+	assert.Equal(t, "ssl/certificate.key", vars.TLSKeyFile)
+	assert.Equal(t, "", vars.TLSClientCAFile)
+	assert.Equal(t, float64(0), vars.RateLimit)
+	assert.Equal(t, 20, vars.RateLimitBurst)
+	assert.Equal(t, 0, vars.WorkflowQuota)
+	assert.Equal(t, 10*time.Second, vars.HTTPReadHeaderTimeout)
+	assert.Equal(t, time.Minute, vars.HTTPReadTimeout)
+	assert.Equal(t, 2*time.Minute, vars.HTTPWriteTimeout)
+	assert.Equal(t, 2*time.Minute, vars.HTTPIdleTimeout)
+	assert.Equal(t, 30*time.Second, vars.ShutdownTimeout)
+	assert.Equal(t, 24*time.Hour, vars.IdempotencyKeyTTL)
+	assert.False(t, vars.SubmissionQueue)
+	assert.Equal(t, 5*time.Second, vars.SubmissionPollInterval)
+	assert.Equal(t, 15*time.Minute, vars.SubmissionRetryTimeout)
+	assert.False(t, vars.WebhookNotifications)
+	assert.Equal(t, 10*time.Second, vars.WebhookTimeout)
+}
+
+func TestTokenValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "custom bounds",
+			vars: map[string]string{"_TOKEN_LIMIT": "5", "_TOKEN_MIN_TTL": "24h", "_TOKEN_MAX_TTL": "720h", "_TOKEN_MAX_GRACE_PERIOD": "1h"},
+		},
+		{
+			name:    "limit too low",
+			vars:    map[string]string{"_TOKEN_LIMIT": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "min ttl greater than max ttl",
+			vars:    map[string]string{"_TOKEN_MIN_TTL": "48h", "_TOKEN_MAX_TTL": "24h"},
+			wantErr: true,
+		},
+		{
+			name:    "max ttl greater than secret id ttl",
+			vars:    map[string]string{"_TOKEN_MAX_TTL": "9000h"},
+			wantErr: true,
+		},
+		{
+			name:    "negative grace period",
+			vars:    map[string]string{"_TOKEN_MAX_GRACE_PERIOD": "-1h"},
+			wantErr: true,
+		},
+		{
+			name: "sweeper disabled",
+			vars: map[string]string{"_TOKEN_SWEEP_INTERVAL": "0"},
+		},
+		{
+			name:    "negative sweep interval",
+			vars:    map[string]string{"_TOKEN_SWEEP_INTERVAL": "-1m"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer func() {
+				for k := range tt.vars {
+					os.Unsetenv(appPrefix + k)
+				}
+				reset()
+			}()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVaultAuthMethodValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "approle",
+			vars: map[string]string{"VAULT_ROLE": "vaultRole", "VAULT_SECRET": testSecret},
+		},
+		{
+			name:    "approle missing secret",
+			vars:    map[string]string{"VAULT_ROLE": "vaultRole"},
+			wantErr: true,
+		},
+		{
+			name: "kubernetes",
+			vars: map[string]string{"VAULT_AUTH_METHOD": "kubernetes", "VAULT_AUTH_ROLE": "cello"},
+		},
+		{
+			name:    "kubernetes missing role",
+			vars:    map[string]string{"VAULT_AUTH_METHOD": "kubernetes"},
+			wantErr: true,
+		},
+		{
+			name: "jwt",
+			vars: map[string]string{"VAULT_AUTH_METHOD": "jwt", "VAULT_AUTH_ROLE": "cello", "VAULT_AUTH_TOKEN_FILE": "/var/run/token"},
+		},
+		{
+			name:    "jwt missing token file",
+			vars:    map[string]string{"VAULT_AUTH_METHOD": "jwt", "VAULT_AUTH_ROLE": "cello"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown method",
+			vars:    map[string]string{"VAULT_AUTH_METHOD": "userpass"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			os.Setenv(appPrefix+"_ADMIN_SECRET", testSecret)
+			os.Setenv("VAULT_ADDR", "1.2.3.4")
+			os.Setenv("ARGO_ADDR", "2.3.4.5")
+			os.Setenv(appPrefix+"_GIT_AUTH_METHOD", "https")
+			os.Setenv(appPrefix+"_DYNAMODB_TABLE_NAME", "cello")
+			setEnvVars(tt.vars, "")
+			defer reset()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOIDCValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name: "jwks file",
+			vars: map[string]string{"_OIDC_ISSUER": "https://sso.example.com", "_OIDC_AUDIENCE": "cello", "_OIDC_JWKS_FILE": "/etc/cello/jwks.json"},
+		},
+		{
+			name: "jwks url",
+			vars: map[string]string{"_OIDC_ISSUER": "https://sso.example.com", "_OIDC_AUDIENCE": "cello", "_OIDC_JWKS_URL": "https://sso.example.com/keys"},
+		},
+		{
+			name:    "missing jwks",
+			vars:    map[string]string{"_OIDC_ISSUER": "https://sso.example.com", "_OIDC_AUDIENCE": "cello"},
+			wantErr: true,
+		},
+		{
+			name:    "missing audience",
+			vars:    map[string]string{"_OIDC_ISSUER": "https://sso.example.com", "_OIDC_JWKS_URL": "https://sso.example.com/keys"},
+			wantErr: true,
+		},
+		{
+			name:    "both jwks",
+			vars:    map[string]string{"_OIDC_ISSUER": "https://sso.example.com", "_OIDC_AUDIENCE": "cello", "_OIDC_JWKS_FILE": "/etc/cello/jwks.json", "_OIDC_JWKS_URL": "https://sso.example.com/keys"},
+			wantErr: true,
+		},
+		{
+			name:    "jwks without issuer",
+			vars:    map[string]string{"_OIDC_JWKS_URL": "https://sso.example.com/keys"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer reset()
+
+			// When
+			vars, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.vars["_OIDC_ISSUER"], vars.OIDCIssuer)
+		})
+	}
+}
+
+func TestTracingValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "none",
+			vars: map[string]string{"_TRACING_EXPORTER": "none"},
+		},
+		{
+			name: "stdout",
+			vars: map[string]string{"_TRACING_EXPORTER": "stdout"},
+		},
+		{
+			name: "otlp",
+			vars: map[string]string{"_TRACING_EXPORTER": "otlp", "_TRACING_ENDPOINT": "http://otel-collector:4318"},
+		},
+		{
+			name:    "otlp without endpoint",
+			vars:    map[string]string{"_TRACING_EXPORTER": "otlp"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown exporter",
+			vars:    map[string]string{"_TRACING_EXPORTER": "jaeger"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer reset()
+
+			// When
+			vars, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.vars["_TRACING_EXPORTER"], vars.TracingExporter)
+		})
+	}
+}
+
+func TestServerValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "tls",
+			vars: map[string]string{"_TLS_CERT_FILE": "/etc/cello/tls.crt", "_TLS_KEY_FILE": "/etc/cello/tls.key", "_SHUTDOWN_TIMEOUT": "1m"},
+		},
+		{
+			name: "tls disabled",
+			vars: map[string]string{"_TLS_DISABLED": "true", "_TLS_CERT_FILE": "", "_TLS_KEY_FILE": ""},
+		},
+		{
+			name: "mutual tls",
+			vars: map[string]string{"_TLS_CLIENT_CA_FILE": "/etc/cello/client-ca.crt"},
+		},
+		{
+			name:    "mutual tls with tls disabled",
+			vars:    map[string]string{"_TLS_DISABLED": "true", "_TLS_CLIENT_CA_FILE": "/etc/cello/client-ca.crt"},
+			wantErr: true,
+		},
+		{
+			name:    "missing tls key file",
+			vars:    map[string]string{"_TLS_KEY_FILE": ""},
+			wantErr: true,
+		},
+		{
+			name:    "negative write timeout",
+			vars:    map[string]string{"_HTTP_WRITE_TIMEOUT": "-1s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer reset()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLimitValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "limits",
+			vars: map[string]string{"_RATE_LIMIT": "2.5", "_RATE_LIMIT_BURST": "10", "_WORKFLOW_QUOTA": "5", "_IDEMPOTENCY_KEY_TTL": "1h"},
+		},
+		{
+			name: "burst is not checked without rate limit",
+			vars: map[string]string{"_RATE_LIMIT_BURST": "0"},
+		},
+		{
+			name:    "negative rate limit",
+			vars:    map[string]string{"_RATE_LIMIT": "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "rate limit without burst",
+			vars:    map[string]string{"_RATE_LIMIT": "1", "_RATE_LIMIT_BURST": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "negative workflow quota",
+			vars:    map[string]string{"_WORKFLOW_QUOTA": "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "zero idempotency key ttl",
+			vars:    map[string]string{"_IDEMPOTENCY_KEY_TTL": "0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer reset()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSubmissionQueueValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "queue",
+			vars: map[string]string{"_SUBMISSION_QUEUE": "true", "_SUBMISSION_POLL_INTERVAL": "1s", "_SUBMISSION_RETRY_TIMEOUT": "1h"},
+		},
+		{
+			name: "intervals are not checked without queue",
+			vars: map[string]string{"_SUBMISSION_POLL_INTERVAL": "0"},
+		},
+		{
+			name:    "zero poll interval",
+			vars:    map[string]string{"_SUBMISSION_QUEUE": "true", "_SUBMISSION_POLL_INTERVAL": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "zero retry timeout",
+			vars:    map[string]string{"_SUBMISSION_QUEUE": "true", "_SUBMISSION_RETRY_TIMEOUT": "0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer reset()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWebhookValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "notifications",
+			vars: map[string]string{"_WEBHOOK_NOTIFICATIONS": "true", "_WEBHOOK_TIMEOUT": "5s"},
+		},
+		{
+			name: "timeout is not checked without notifications",
+			vars: map[string]string{"_WEBHOOK_TIMEOUT": "0"},
+		},
+		{
+			name:    "zero timeout",
+			vars:    map[string]string{"_WEBHOOK_NOTIFICATIONS": "true", "_WEBHOOK_TIMEOUT": "0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer reset()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHealthValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "custom",
+			vars: map[string]string{"_HEALTH_CHECK_TIMEOUT": "2s", "_HEALTH_CACHE_TTL": "0", "_HEALTH_GIT_REPOSITORY": "https://github.com/cello-proj/cello.git"},
+		},
+		{
+			name:    "zero timeout",
+			vars:    map[string]string{"_HEALTH_CHECK_TIMEOUT": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "negative cache ttl",
+			vars:    map[string]string{"_HEALTH_CACHE_TTL": "-1s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			setEnvVars(prefixedEnvVars, appPrefix)
+			setEnvVars(nonPrefixedEnvVars, "")
+			setEnvVars(tt.vars, appPrefix)
+			defer reset()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCredentialsProviderValidations(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "vault",
+			vars: map[string]string{"VAULT_ADDR": "1.2.3.4", "VAULT_ROLE": "vaultRole", "VAULT_SECRET": testSecret},
+		},
+		{
+			name:    "vault missing address",
+			vars:    map[string]string{"VAULT_ROLE": "vaultRole", "VAULT_SECRET": testSecret},
+			wantErr: true,
+		},
+		{
+			name: "local does not require vault",
+			vars: map[string]string{"_CREDENTIALS_PROVIDER": "local", "_LOCAL_PROVIDER_KEY": testSecret},
+		},
+		{
+			name: "local with static token",
+			vars: map[string]string{"_CREDENTIALS_PROVIDER": "local", "_LOCAL_PROVIDER_KEY": testSecret, "_LOCAL_PROVIDER_TOKEN": "local-token"},
+		},
+		{
+			name:    "local key too short",
+			vars:    map[string]string{"_CREDENTIALS_PROVIDER": "local", "_LOCAL_PROVIDER_KEY": "short"},
+			wantErr: true,
+		},
+		{
+			name:    "local token too short",
+			vars:    map[string]string{"_CREDENTIALS_PROVIDER": "local", "_LOCAL_PROVIDER_KEY": testSecret, "_LOCAL_PROVIDER_TOKEN": "short"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			reset()
+			os.Setenv(appPrefix+"_ADMIN_SECRET", testSecret)
+			os.Setenv("ARGO_ADDR", "2.3.4.5")
+			os.Setenv(appPrefix+"_GIT_AUTH_METHOD", "https")
+			os.Setenv(appPrefix+"_DYNAMODB_TABLE_NAME", "cello")
+			for k, v := range tt.vars {
+				if strings.HasPrefix(k, "_") {
+					k = appPrefix + k
+				}
+				os.Setenv(k, v)
+			}
+			defer reset()
+
+			// When
+			_, err := GetEnv()
+
+			// Then
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
 }
 
 func TestValidations(t *testing.T) {
@@ -3,6 +3,7 @@
 package git
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -11,17 +12,23 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
 // Client allows for retrieving data from git repo
 type Client interface {
 	GetManifestFile(repository, commitHash, path string) ([]byte, error)
+	// Ping returns an error when the repository can't be reached with the
+	// client's credentials.
+	Ping(ctx context.Context, repository string) error
 }
 
 type gitSvc interface {
@@ -30,6 +37,7 @@ type gitSvc interface {
 	Fetch(r *git.Repository, o *git.FetchOptions) error
 	Worktree(r *git.Repository) (*git.Worktree, error)
 	Checkout(w *git.Worktree, opts *git.CheckoutOptions) error
+	List(ctx context.Context, url string, o *git.ListOptions) ([]*plumbing.Reference, error)
 }
 
 type gitSvcImpl struct{}
@@ -54,6 +62,14 @@ func (g gitSvcImpl) Checkout(w *git.Worktree, opts *git.CheckoutOptions) error {
 	return w.Checkout(opts)
 }
 
+func (g gitSvcImpl) List(ctx context.Context, url string, o *git.ListOptions) ([]*plumbing.Reference, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+	return remote.ListContext(ctx, o)
+}
+
 // Option is a function for configuring the BasicClient
 type Option func(*BasicClient)
 
@@ -63,14 +79,23 @@ func WithProgressWriter(w io.Writer) Option {
 	}
 }
 
+// WithFetchObserver sets the function called with the duration of each
+// clone or fetch of a repository, operation is clone or fetch.
+func WithFetchObserver(fn func(operation string, d time.Duration)) Option {
+	return func(c *BasicClient) {
+		c.observeFetch = fn
+	}
+}
+
 // BasicClient connects to git using ssh
 type BasicClient struct {
-	auth    transport.AuthMethod
-	mu      *sync.Mutex
-	git     gitSvc
-	fs      fs.FS
-	baseDir string // base directory to run git operations from
-	pw      io.Writer
+	auth         transport.AuthMethod
+	mu           *sync.Mutex
+	git          gitSvc
+	fs           fs.FS
+	baseDir      string // base directory to run git operations from
+	pw           io.Writer
+	observeFetch func(operation string, d time.Duration)
 }
 
 // NewSSHBasicClient creates a new ssh based git client
@@ -110,6 +135,14 @@ func newBasicClient(auth transport.AuthMethod, opts ...Option) BasicClient {
 	return cl
 }
 
+// observe calls the fetch observer, when set, with the duration of the
+// operation which started at start.
+func (g BasicClient) observe(operation string, start time.Time) {
+	if g.observeFetch != nil {
+		g.observeFetch(operation, time.Since(start))
+	}
+}
+
 func (g BasicClient) GetManifestFile(repository, commitHash, path string) ([]byte, error) {
 	// filePath should only be used for git calls. direct fs calls should use repository directly
 	repPath := strings.ReplaceAll(repository, "/", "")
@@ -124,11 +157,13 @@ func (g BasicClient) GetManifestFile(repository, commitHash, path string) ([]byt
 
 	if _, err := fs.Stat(g.fs, repPath); os.IsNotExist(err) {
 		// TODO: use context version and make depth configurable
+		start := time.Now()
 		repo, err = g.git.PlainClone(filePath, false, &git.CloneOptions{
 			URL:      repository,
 			Auth:     g.auth,
 			Progress: g.pw,
 		})
+		g.observe("clone", start)
 		if err != nil {
 			return []byte{}, err
 		}
@@ -137,10 +172,12 @@ func (g BasicClient) GetManifestFile(repository, commitHash, path string) ([]byt
 		if err != nil {
 			return []byte{}, err
 		}
+		start := time.Now()
 		err = g.git.Fetch(repo, &git.FetchOptions{
 			Progress: g.pw,
 			Auth:     g.auth,
 		})
+		g.observe("fetch", start)
 		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
 			return []byte{}, err
 		}
@@ -170,3 +207,10 @@ func (g BasicClient) GetManifestFile(repository, commitHash, path string) ([]byt
 
 	return fs.ReadFile(g.fs, pathToManifest)
 }
+
+// Ping lists the references of the repository without cloning it, which
+// checks it can be reached with the client's credentials.
+func (g BasicClient) Ping(ctx context.Context, repository string) error {
+	_, err := g.git.List(ctx, repository, &git.ListOptions{Auth: g.auth})
+	return err
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -12,8 +13,10 @@ import (
 	"sync"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -26,6 +29,8 @@ type mockGitSvc struct {
 	fetchErr    error
 	wtErr       error
 	coErr       error
+	listURL     string
+	listErr     error
 }
 
 func (g *mockGitSvc) PlainClone(path string, isBare bool, o *git.CloneOptions) (*git.Repository, error) {
@@ -82,6 +87,15 @@ func (g *mockGitSvc) Checkout(w *git.Worktree, opts *git.CheckoutOptions) error
 	return nil
 }
 
+func (g *mockGitSvc) List(ctx context.Context, url string, o *git.ListOptions) ([]*plumbing.Reference, error) {
+	g.listURL = url
+	if g.listErr != nil {
+		return nil, g.listErr
+	}
+
+	return []*plumbing.Reference{}, nil
+}
+
 func newGitClient() (BasicClient, *mockGitSvc) {
 	paths := []string{
 		"myrepo/path/to/manifest.yaml",
@@ -246,6 +260,61 @@ func TestGetManifestFile(t *testing.T) {
 	}
 }
 
+func TestGetManifestFileFetchObserver(t *testing.T) {
+	var observed []string
+	gitClient, _ := newGitClient()
+	WithFetchObserver(func(operation string, d time.Duration) {
+		observed = append(observed, operation)
+	})(&gitClient)
+
+	// Repositories on the fs are fetched.
+	if _, err := gitClient.GetManifestFile("myrepo", "123", "path/to/manifest.yaml"); err != nil {
+		t.Fatalf("\ndid not expect error, got: %v", err)
+	}
+
+	want := []string{"fetch"}
+	if !cmp.Equal(observed, want) {
+		t.Errorf("\nwant: %v\n got: %v", want, observed)
+	}
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name    string
+		listErr error
+		wantErr error
+	}{
+		{
+			name: "reachable",
+		},
+		{
+			name:    "unreachable",
+			listErr: errors.New("authentication required"),
+			wantErr: errors.New("authentication required"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitClient, gitSvc := newGitClient()
+			gitSvc.listErr = tt.listErr
+
+			err := gitClient.Ping(context.Background(), "git@github.com:cello-proj/canary.git")
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("\nwant: %v\n got: %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("\ndid not expect error, got: %v", err)
+			}
+
+			if gitSvc.listURL != "git@github.com:cello-proj/canary.git" {
+				t.Errorf("\nwant: %s\n got: %s", "git@github.com:cello-proj/canary.git", gitSvc.listURL)
+			}
+		})
+	}
+}
+
 func TestNewClient(t *testing.T) {
 	t.Run("NewSSHBasicClient creates client with ssh auth with valid PEM", func(t *testing.T) {
 		tmp, err := os.CreateTemp("", "tmpssh*.pem")
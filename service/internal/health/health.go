@@ -0,0 +1,146 @@
+// Package health checks the readiness of the service's dependencies.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Statuses of checks and their results.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check checks a dependency, which is available when Func returns nil.
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+// Component is the result of a check.
+type Component struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	// err is logged rather than returned, as the endpoints serving results
+	// are not authenticated.
+	err error
+}
+
+// Err returns the error of the check, which is nil when it succeeded.
+func (c Component) Err() error {
+	return c.err
+}
+
+// Result is the result of all checks, its status is ok when every check
+// succeeded.
+type Result struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// OK returns whether every check succeeded.
+func (r Result) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Checker runs checks concurrently, each within the timeout, and caches
+// their result for the TTL so that frequent probes don't load the
+// dependencies.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	ttl     time.Duration
+	now     func() time.Time
+
+	// mu is held while checks run, so concurrent callers wait for and share
+	// a single run.
+	mu        sync.Mutex
+	result    Result
+	checkedAt time.Time
+}
+
+// NewChecker returns a Checker of the checks.
+func NewChecker(timeout, ttl time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// Check returns the cached result, or runs the checks when it has expired.
+// A nil Checker has no checks and is always ok.
+func (c *Checker) Check(ctx context.Context) Result {
+	if c == nil {
+		return Result{Status: StatusOK, Components: map[string]Component{}}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && c.now().Sub(c.checkedAt) < c.ttl {
+		return c.result
+	}
+
+	c.result = c.run(ctx)
+	c.checkedAt = c.now()
+	return c.result
+}
+
+func (c *Checker) run(ctx context.Context) Result {
+	components := make([]Component, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			components[i] = c.runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	result := Result{Status: StatusOK, Components: make(map[string]Component, len(c.checks))}
+	for i, check := range c.checks {
+		result.Components[check.Name] = components[i]
+		if components[i].Status != StatusOK {
+			result.Status = StatusUnavailable
+		}
+	}
+	return result
+}
+
+func (c *Checker) runCheck(ctx context.Context, check Check) Component {
+	// Checks are cancelled by their timeout rather than the request, whose
+	// cancellation would otherwise be cached as a failure.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- check.Func(ctx)
+	}()
+
+	// Checks which ignore their context still fail once the timeout has
+	// passed.
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	component := Component{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		err:       err,
+	}
+	if err != nil {
+		component.Status = StatusUnavailable
+	}
+	return component
+}
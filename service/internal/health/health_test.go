@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus string
+		want       map[string]string
+	}{
+		{
+			name:       "no checks",
+			wantStatus: StatusOK,
+			want:       map[string]string{},
+		},
+		{
+			name: "all ok",
+			checks: []Check{
+				{Name: "vault", Func: func(ctx context.Context) error { return nil }},
+				{Name: "dynamodb", Func: func(ctx context.Context) error { return nil }},
+			},
+			wantStatus: StatusOK,
+			want:       map[string]string{"vault": StatusOK, "dynamodb": StatusOK},
+		},
+		{
+			name: "one failing",
+			checks: []Check{
+				{Name: "vault", Func: func(ctx context.Context) error { return nil }},
+				{Name: "argo", Func: func(ctx context.Context) error { return errors.New("connection refused") }},
+			},
+			wantStatus: StatusUnavailable,
+			want:       map[string]string{"vault": StatusOK, "argo": StatusUnavailable},
+		},
+		{
+			name: "timeout",
+			checks: []Check{
+				{Name: "git", Func: func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}},
+			},
+			wantStatus: StatusUnavailable,
+			want:       map[string]string{"git": StatusUnavailable},
+		},
+		{
+			name: "check ignoring its context",
+			checks: []Check{
+				{Name: "git", Func: func(ctx context.Context) error {
+					time.Sleep(time.Second)
+					return nil
+				}},
+			},
+			wantStatus: StatusUnavailable,
+			want:       map[string]string{"git": StatusUnavailable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(50*time.Millisecond, 0, tt.checks...)
+
+			got := c.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, got.Status)
+			assert.Equal(t, tt.wantStatus == StatusOK, got.OK())
+
+			statuses := map[string]string{}
+			for name, component := range got.Components {
+				statuses[name] = component.Status
+				assert.Equal(t, component.Status != StatusOK, component.Err() != nil)
+			}
+			assert.Equal(t, tt.want, statuses)
+		})
+	}
+}
+
+func TestCheckConcurrently(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+	c := NewChecker(time.Second, 0, Check{Name: "a", Func: slow}, Check{Name: "b", Func: slow}, Check{Name: "c", Func: slow})
+
+	start := time.Now()
+	assert.True(t, c.Check(context.Background()).OK())
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestCheckCached(t *testing.T) {
+	var calls int32
+	now := time.Now()
+	c := NewChecker(time.Second, 5*time.Second, Check{Name: "vault", Func: func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}})
+	c.now = func() time.Time { return now }
+
+	c.Check(context.Background())
+	c.Check(context.Background())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	now = now.Add(5 * time.Second)
+	c.Check(context.Background())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCheckCancelledRequest(t *testing.T) {
+	c := NewChecker(time.Second, 0, Check{Name: "vault", Func: func(ctx context.Context) error {
+		return ctx.Err()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, c.Check(ctx).OK())
+}
+
+func TestResultJSON(t *testing.T) {
+	r := Result{
+		Status: StatusUnavailable,
+		Components: map[string]Component{
+			"vault": {Status: StatusUnavailable, LatencyMS: 1.5, err: errors.New("secret")},
+		},
+	}
+
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"status":"unavailable","components":{"vault":{"status":"unavailable","latency_ms":1.5}}}`, string(b))
+}
+
+func TestNilChecker(t *testing.T) {
+	var c *Checker
+	assert.True(t, c.Check(context.Background()).OK())
+}
@@ -0,0 +1,511 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/env"
+	"github.com/cello-proj/cello/service/internal/git"
+	"github.com/cello-proj/cello/service/internal/workflow"
+)
+
+// Verify interface implementations at compile time
+var (
+	_ credentials.Provider = instrumentedProvider{}
+	_ db.Client            = instrumentedDB{}
+	_ git.Client           = instrumentedGit{}
+	_ workflow.Workflow    = instrumentedWorkflow{}
+)
+
+// InstrumentProviderFactory returns a factory of credentials providers whose
+// calls are measured, with the provider's name as the backend.
+func InstrumentProviderFactory(f credentials.ProviderFactory, m *Metrics) credentials.ProviderFactory {
+	if m == nil {
+		return f
+	}
+
+	return func(a credentials.Authorization, env env.Vars, h http.Header, vaultConfigFn credentials.VaultConfigFn, vaultSvcFn credentials.VaultSvcFn) (credentials.Provider, error) {
+		p, err := f(a, env, h, vaultConfigFn, vaultSvcFn)
+		if err != nil {
+			return nil, err
+		}
+		return instrumentedProvider{next: p, backend: a.Provider, m: m}, nil
+	}
+}
+
+// InstrumentDB returns the database client with its calls measured.
+func InstrumentDB(c db.Client, m *Metrics) db.Client {
+	if m == nil {
+		return c
+	}
+	return instrumentedDB{next: c, m: m}
+}
+
+// InstrumentGit returns the git client with its calls measured.
+func InstrumentGit(c git.Client, m *Metrics) git.Client {
+	if m == nil {
+		return c
+	}
+	return instrumentedGit{next: c, m: m}
+}
+
+// InstrumentWorkflow returns the workflow client with its calls measured.
+func InstrumentWorkflow(w workflow.Workflow, m *Metrics) workflow.Workflow {
+	if m == nil {
+		return w
+	}
+	return instrumentedWorkflow{next: w, m: m}
+}
+
+type instrumentedProvider struct {
+	next    credentials.Provider
+	backend string
+	m       *Metrics
+}
+
+func (p instrumentedProvider) CreateProject(name string, tokenTTL time.Duration) (types.Token, error) {
+	start := time.Now()
+	v, err := p.next.CreateProject(name, tokenTTL)
+	p.m.ObserveBackend(p.backend, "CreateProject", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) CreateTarget(projectName string, target types.Target) error {
+	start := time.Now()
+	err := p.next.CreateTarget(projectName, target)
+	p.m.ObserveBackend(p.backend, "CreateTarget", start, err)
+	return err
+}
+
+func (p instrumentedProvider) CreateToken(projectName string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+	start := time.Now()
+	v, err := p.next.CreateToken(projectName, scopes, policy, ttl)
+	p.m.ObserveBackend(p.backend, "CreateToken", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) UpdateTarget(projectName string, target types.Target) error {
+	start := time.Now()
+	err := p.next.UpdateTarget(projectName, target)
+	p.m.ObserveBackend(p.backend, "UpdateTarget", start, err)
+	return err
+}
+
+func (p instrumentedProvider) DeleteProject(name string) error {
+	start := time.Now()
+	err := p.next.DeleteProject(name)
+	p.m.ObserveBackend(p.backend, "DeleteProject", start, err)
+	return err
+}
+
+func (p instrumentedProvider) DeleteTarget(projectName string, targetName string) error {
+	start := time.Now()
+	err := p.next.DeleteTarget(projectName, targetName)
+	p.m.ObserveBackend(p.backend, "DeleteTarget", start, err)
+	return err
+}
+
+func (p instrumentedProvider) GetProject(projectName string) (responses.GetProject, error) {
+	start := time.Now()
+	v, err := p.next.GetProject(projectName)
+	p.m.ObserveBackend(p.backend, "GetProject", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) GetTarget(projectName string, targetName string) (types.Target, error) {
+	start := time.Now()
+	v, err := p.next.GetTarget(projectName, targetName)
+	p.m.ObserveBackend(p.backend, "GetTarget", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) GetToken() (string, error) {
+	start := time.Now()
+	v, err := p.next.GetToken()
+	p.m.ObserveBackend(p.backend, "GetToken", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) VerifyProjectToken(projectName, roleID, secretID string) error {
+	start := time.Now()
+	err := p.next.VerifyProjectToken(projectName, roleID, secretID)
+	p.m.ObserveBackend(p.backend, "VerifyProjectToken", start, err)
+	return err
+}
+
+func (p instrumentedProvider) DeleteProjectToken(projectName string, tokenID string) error {
+	start := time.Now()
+	err := p.next.DeleteProjectToken(projectName, tokenID)
+	p.m.ObserveBackend(p.backend, "DeleteProjectToken", start, err)
+	return err
+}
+
+func (p instrumentedProvider) GetProjectToken(projectName string, tokenID string) (types.ProjectToken, error) {
+	start := time.Now()
+	v, err := p.next.GetProjectToken(projectName, tokenID)
+	p.m.ObserveBackend(p.backend, "GetProjectToken", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) ListProjects() ([]string, error) {
+	start := time.Now()
+	v, err := p.next.ListProjects()
+	p.m.ObserveBackend(p.backend, "ListProjects", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) ListProjectTokens(projectName string) ([]string, error) {
+	start := time.Now()
+	v, err := p.next.ListProjectTokens(projectName)
+	p.m.ObserveBackend(p.backend, "ListProjectTokens", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) ListTargets(projectName string) ([]string, error) {
+	start := time.Now()
+	v, err := p.next.ListTargets(projectName)
+	p.m.ObserveBackend(p.backend, "ListTargets", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) ProjectExists(name string) (bool, error) {
+	start := time.Now()
+	v, err := p.next.ProjectExists(name)
+	p.m.ObserveBackend(p.backend, "ProjectExists", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) TargetExists(projectName string, targetName string) (bool, error) {
+	start := time.Now()
+	v, err := p.next.TargetExists(projectName, targetName)
+	p.m.ObserveBackend(p.backend, "TargetExists", start, err)
+	return v, err
+}
+
+func (p instrumentedProvider) UpdateProjectPolicy(projectName string, policy types.ProjectPolicy, scopes []types.TokenScopes) error {
+	start := time.Now()
+	err := p.next.UpdateProjectPolicy(projectName, policy, scopes)
+	p.m.ObserveBackend(p.backend, "UpdateProjectPolicy", start, err)
+	return err
+}
+
+type instrumentedDB struct {
+	next db.Client
+	m    *Metrics
+}
+
+func (c instrumentedDB) CreateProjectEntry(ctx context.Context, pe db.ProjectEntry) error {
+	start := time.Now()
+	err := c.next.CreateProjectEntry(ctx, pe)
+	c.m.ObserveBackend(BackendDynamoDB, "CreateProjectEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) DeleteProjectEntry(ctx context.Context, project string) error {
+	start := time.Now()
+	err := c.next.DeleteProjectEntry(ctx, project)
+	c.m.ObserveBackend(BackendDynamoDB, "DeleteProjectEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) ReadProjectEntry(ctx context.Context, project string) (db.ProjectEntry, error) {
+	start := time.Now()
+	v, err := c.next.ReadProjectEntry(ctx, project)
+	c.m.ObserveBackend(BackendDynamoDB, "ReadProjectEntry", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) ListProjectEntries(ctx context.Context) ([]db.ProjectEntry, error) {
+	start := time.Now()
+	v, err := c.next.ListProjectEntries(ctx)
+	c.m.ObserveBackend(BackendDynamoDB, "ListProjectEntries", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) UpdateProjectEntry(ctx context.Context, pe db.ProjectEntry) error {
+	start := time.Now()
+	err := c.next.UpdateProjectEntry(ctx, pe)
+	c.m.ObserveBackend(BackendDynamoDB, "UpdateProjectEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) UpdateProjectPolicy(ctx context.Context, project string, policy types.ProjectPolicy) error {
+	start := time.Now()
+	err := c.next.UpdateProjectPolicy(ctx, project, policy)
+	c.m.ObserveBackend(BackendDynamoDB, "UpdateProjectPolicy", start, err)
+	return err
+}
+
+func (c instrumentedDB) CreateTokenEntry(ctx context.Context, token types.Token) error {
+	start := time.Now()
+	err := c.next.CreateTokenEntry(ctx, token)
+	c.m.ObserveBackend(BackendDynamoDB, "CreateTokenEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) UpdateTokenEntry(ctx context.Context, te db.TokenEntry) error {
+	start := time.Now()
+	err := c.next.UpdateTokenEntry(ctx, te)
+	c.m.ObserveBackend(BackendDynamoDB, "UpdateTokenEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) DeleteTokenEntry(ctx context.Context, token string) error {
+	start := time.Now()
+	err := c.next.DeleteTokenEntry(ctx, token)
+	c.m.ObserveBackend(BackendDynamoDB, "DeleteTokenEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) DeleteTokenEntryByProject(ctx context.Context, project, token string) error {
+	start := time.Now()
+	err := c.next.DeleteTokenEntryByProject(ctx, project, token)
+	c.m.ObserveBackend(BackendDynamoDB, "DeleteTokenEntryByProject", start, err)
+	return err
+}
+
+func (c instrumentedDB) ReadTokenEntry(ctx context.Context, token string) (db.TokenEntry, error) {
+	start := time.Now()
+	v, err := c.next.ReadTokenEntry(ctx, token)
+	c.m.ObserveBackend(BackendDynamoDB, "ReadTokenEntry", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) ReadTokenEntryByProject(ctx context.Context, project, token string) (db.TokenEntry, error) {
+	start := time.Now()
+	v, err := c.next.ReadTokenEntryByProject(ctx, project, token)
+	c.m.ObserveBackend(BackendDynamoDB, "ReadTokenEntryByProject", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) ListTokenEntries(ctx context.Context, project string) ([]db.TokenEntry, error) {
+	start := time.Now()
+	v, err := c.next.ListTokenEntries(ctx, project)
+	c.m.ObserveBackend(BackendDynamoDB, "ListTokenEntries", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) Health(ctx context.Context) error {
+	start := time.Now()
+	err := c.next.Health(ctx)
+	c.m.ObserveBackend(BackendDynamoDB, "Health", start, err)
+	return err
+}
+
+func (c instrumentedDB) AcquireLease(ctx context.Context, name, owner string, d time.Duration) (bool, error) {
+	start := time.Now()
+	v, err := c.next.AcquireLease(ctx, name, owner, d)
+	c.m.ObserveBackend(BackendDynamoDB, "AcquireLease", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) CreateIdentityEntry(ctx context.Context, ie db.IdentityEntry) error {
+	start := time.Now()
+	err := c.next.CreateIdentityEntry(ctx, ie)
+	c.m.ObserveBackend(BackendDynamoDB, "CreateIdentityEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) ReadIdentityEntry(ctx context.Context, name string) (db.IdentityEntry, error) {
+	start := time.Now()
+	v, err := c.next.ReadIdentityEntry(ctx, name)
+	c.m.ObserveBackend(BackendDynamoDB, "ReadIdentityEntry", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) ListIdentityEntries(ctx context.Context) ([]db.IdentityEntry, error) {
+	start := time.Now()
+	v, err := c.next.ListIdentityEntries(ctx)
+	c.m.ObserveBackend(BackendDynamoDB, "ListIdentityEntries", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) UpdateIdentityEntry(ctx context.Context, ie db.IdentityEntry) error {
+	start := time.Now()
+	err := c.next.UpdateIdentityEntry(ctx, ie)
+	c.m.ObserveBackend(BackendDynamoDB, "UpdateIdentityEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) DeleteIdentityEntry(ctx context.Context, name string) error {
+	start := time.Now()
+	err := c.next.DeleteIdentityEntry(ctx, name)
+	c.m.ObserveBackend(BackendDynamoDB, "DeleteIdentityEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) CreateIdempotencyEntry(ctx context.Context, ie db.IdempotencyEntry) error {
+	start := time.Now()
+	err := c.next.CreateIdempotencyEntry(ctx, ie)
+	c.m.ObserveBackend(BackendDynamoDB, "CreateIdempotencyEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) ReadIdempotencyEntry(ctx context.Context, principal, key string) (db.IdempotencyEntry, error) {
+	start := time.Now()
+	v, err := c.next.ReadIdempotencyEntry(ctx, principal, key)
+	c.m.ObserveBackend(BackendDynamoDB, "ReadIdempotencyEntry", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) UpdateIdempotencyEntry(ctx context.Context, ie db.IdempotencyEntry) error {
+	start := time.Now()
+	err := c.next.UpdateIdempotencyEntry(ctx, ie)
+	c.m.ObserveBackend(BackendDynamoDB, "UpdateIdempotencyEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) DeleteIdempotencyEntry(ctx context.Context, principal, key string) error {
+	start := time.Now()
+	err := c.next.DeleteIdempotencyEntry(ctx, principal, key)
+	c.m.ObserveBackend(BackendDynamoDB, "DeleteIdempotencyEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) CreateSubmissionEntry(ctx context.Context, se db.SubmissionEntry) error {
+	start := time.Now()
+	err := c.next.CreateSubmissionEntry(ctx, se)
+	c.m.ObserveBackend(BackendDynamoDB, "CreateSubmissionEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) ReadSubmissionEntry(ctx context.Context, id string) (db.SubmissionEntry, error) {
+	start := time.Now()
+	v, err := c.next.ReadSubmissionEntry(ctx, id)
+	c.m.ObserveBackend(BackendDynamoDB, "ReadSubmissionEntry", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) ListQueuedSubmissions(ctx context.Context, now time.Time) ([]string, error) {
+	start := time.Now()
+	v, err := c.next.ListQueuedSubmissions(ctx, now)
+	c.m.ObserveBackend(BackendDynamoDB, "ListQueuedSubmissions", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) ClaimSubmission(ctx context.Context, id string, d time.Duration) (bool, error) {
+	start := time.Now()
+	v, err := c.next.ClaimSubmission(ctx, id, d)
+	c.m.ObserveBackend(BackendDynamoDB, "ClaimSubmission", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) UpdateSubmissionEntry(ctx context.Context, se db.SubmissionEntry) error {
+	start := time.Now()
+	err := c.next.UpdateSubmissionEntry(ctx, se)
+	c.m.ObserveBackend(BackendDynamoDB, "UpdateSubmissionEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) CreateWebhookEntry(ctx context.Context, we db.WebhookEntry) error {
+	start := time.Now()
+	err := c.next.CreateWebhookEntry(ctx, we)
+	c.m.ObserveBackend(BackendDynamoDB, "CreateWebhookEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) ListWebhookEntries(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+	start := time.Now()
+	v, err := c.next.ListWebhookEntries(ctx, project)
+	c.m.ObserveBackend(BackendDynamoDB, "ListWebhookEntries", start, err)
+	return v, err
+}
+
+func (c instrumentedDB) DeleteWebhookEntry(ctx context.Context, project, id string) error {
+	start := time.Now()
+	err := c.next.DeleteWebhookEntry(ctx, project, id)
+	c.m.ObserveBackend(BackendDynamoDB, "DeleteWebhookEntry", start, err)
+	return err
+}
+
+func (c instrumentedDB) RecordWebhookEvent(ctx context.Context, workflowName, event string, expiresAt time.Time) (bool, error) {
+	start := time.Now()
+	v, err := c.next.RecordWebhookEvent(ctx, workflowName, event, expiresAt)
+	c.m.ObserveBackend(BackendDynamoDB, "RecordWebhookEvent", start, err)
+	return v, err
+}
+
+type instrumentedGit struct {
+	next git.Client
+	m    *Metrics
+}
+
+func (g instrumentedGit) GetManifestFile(repository, commitHash, path string) ([]byte, error) {
+	start := time.Now()
+	v, err := g.next.GetManifestFile(repository, commitHash, path)
+	g.m.ObserveBackend(BackendGit, "GetManifestFile", start, err)
+	return v, err
+}
+
+func (g instrumentedGit) Ping(ctx context.Context, repository string) error {
+	start := time.Now()
+	err := g.next.Ping(ctx, repository)
+	g.m.ObserveBackend(BackendGit, "Ping", start, err)
+	return err
+}
+
+type instrumentedWorkflow struct {
+	next workflow.Workflow
+	m    *Metrics
+}
+
+func (w instrumentedWorkflow) ListStatus(ctx context.Context) ([]workflow.Status, error) {
+	start := time.Now()
+	v, err := w.next.ListStatus(ctx)
+	w.m.ObserveBackend(BackendArgo, "ListStatus", start, err)
+	return v, err
+}
+
+func (w instrumentedWorkflow) Logs(ctx context.Context, workflowName string) (*workflow.Logs, error) {
+	start := time.Now()
+	v, err := w.next.Logs(ctx, workflowName)
+	w.m.ObserveBackend(BackendArgo, "Logs", start, err)
+	return v, err
+}
+
+func (w instrumentedWorkflow) LogStream(ctx context.Context, workflowName string, data http.ResponseWriter) error {
+	start := time.Now()
+	err := w.next.LogStream(ctx, workflowName, data)
+	w.m.ObserveBackend(BackendArgo, "LogStream", start, err)
+	return err
+}
+
+func (w instrumentedWorkflow) Status(ctx context.Context, workflowName string) (*workflow.Status, error) {
+	start := time.Now()
+	v, err := w.next.Status(ctx, workflowName)
+	w.m.ObserveBackend(BackendArgo, "Status", start, err)
+	return v, err
+}
+
+func (w instrumentedWorkflow) Submit(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
+	start := time.Now()
+	v, err := w.next.Submit(ctx, from, parameters, labels)
+	w.m.ObserveBackend(BackendArgo, "Submit", start, err)
+	return v, err
+}
+
+func (w instrumentedWorkflow) Health(ctx context.Context) error {
+	start := time.Now()
+	err := w.next.Health(ctx)
+	w.m.ObserveBackend(BackendArgo, "Health", start, err)
+	return err
+}
+
+func (w instrumentedWorkflow) CountActive(ctx context.Context, namePrefix string) (int, error) {
+	start := time.Now()
+	v, err := w.next.CountActive(ctx, namePrefix)
+	w.m.ObserveBackend(BackendArgo, "CountActive", start, err)
+	return v, err
+}
+
+func (w instrumentedWorkflow) Watch(ctx context.Context, f func(workflow.Run)) error {
+	start := time.Now()
+	err := w.next.Watch(ctx, f)
+	w.m.ObserveBackend(BackendArgo, "Watch", start, err)
+	return err
+}
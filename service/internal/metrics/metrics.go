@@ -0,0 +1,161 @@
+// Package metrics exposes the Prometheus metrics of the service's requests,
+// its calls to backends and its workflow submissions.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cello-proj/cello/service/internal/statuswriter"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cello"
+
+// Backends whose calls are measured.
+const (
+	BackendArgo     = "argo"
+	BackendDynamoDB = "dynamodb"
+	BackendGit      = "git"
+)
+
+// Metrics holds the service's metrics. A nil Metrics records nothing, which
+// keeps it optional for handlers and tests.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests            *prometheus.CounterVec
+	requestDuration     *prometheus.HistogramVec
+	backendDuration     *prometheus.HistogramVec
+	backendErrors       *prometheus.CounterVec
+	workflowSubmissions *prometheus.CounterVec
+	gitFetchDuration    *prometheus.HistogramVec
+}
+
+// New returns Metrics registered with a new registry, along with the Go
+// runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Requests by route template, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Request latency by route template and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		backendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "backend",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of calls to backends by backend and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "operation"}),
+		backendErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "backend",
+			Name:      "errors_total",
+			Help:      "Failed calls to backends by backend and operation.",
+		}, []string{"backend", "operation"}),
+		workflowSubmissions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "workflow",
+			Name:      "submissions_total",
+			Help:      "Submitted workflows by project, target, framework and type.",
+		}, []string{"project", "target", "framework", "type"}),
+		gitFetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "git",
+			Name:      "fetch_duration_seconds",
+			Help:      "Duration of cloning or fetching repositories by operation.",
+			// Clones of large repositories take much longer than requests.
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.backendDuration,
+		m.backendErrors,
+		m.workflowSubmissions,
+		m.gitFetchDuration,
+	)
+
+	return m
+}
+
+// Handler returns the handler serving the metrics, or a not found handler
+// when m is nil.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware measures requests by the template of their mux route, so that
+// paths with different projects or workflows share their series.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if tpl, err := cr.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		start := time.Now()
+		sw := statuswriter.New(w)
+		next.ServeHTTP(sw, r)
+
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(sw.Status())).Inc()
+		m.requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveBackend records a call to the backend which started at start.
+func (m *Metrics) ObserveBackend(backend, operation string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+
+	m.backendDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.backendErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// WorkflowSubmitted records a submitted workflow.
+func (m *Metrics) WorkflowSubmitted(project, target, framework, workflowType string) {
+	if m == nil {
+		return
+	}
+	m.workflowSubmissions.WithLabelValues(project, target, framework, workflowType).Inc()
+}
+
+// ObserveGitFetch records cloning or fetching a repository, operation is
+// clone or fetch.
+func (m *Metrics) ObserveGitFetch(operation string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.gitFetchDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
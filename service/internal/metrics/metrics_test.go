@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/service/internal/db"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// scrape returns the metrics served by m.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(w.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+
+	r := mux.NewRouter()
+	r.Use(m.Middleware)
+	r.HandleFunc("/projects/{projectName}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, project := range []string{"project1", "project2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/projects/"+project, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `cello_http_requests_total{code="404",method="GET",route="/projects/{projectName}"} 2`)
+	assert.Contains(t, body, `cello_http_request_duration_seconds_count{method="GET",route="/projects/{projectName}"} 2`)
+	assert.NotContains(t, body, "project1")
+}
+
+func TestObserveBackend(t *testing.T) {
+	m := New()
+	m.ObserveBackend(BackendDynamoDB, "ReadProjectEntry", time.Now(), nil)
+	m.ObserveBackend(BackendDynamoDB, "ReadProjectEntry", time.Now(), errors.New("error"))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `cello_backend_request_duration_seconds_count{backend="dynamodb",operation="ReadProjectEntry"} 2`)
+	assert.Contains(t, body, `cello_backend_errors_total{backend="dynamodb",operation="ReadProjectEntry"} 1`)
+}
+
+func TestWorkflowSubmitted(t *testing.T) {
+	m := New()
+	m.WorkflowSubmitted("project1", "target1", "cdk", "sync")
+	m.ObserveGitFetch("clone", time.Second)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `cello_workflow_submissions_total{framework="cdk",project="project1",target="target1",type="sync"} 1`)
+	assert.Contains(t, body, `cello_git_fetch_duration_seconds_count{operation="clone"} 1`)
+}
+
+func TestInstrumentDB(t *testing.T) {
+	m := New()
+	c := InstrumentDB(&th.DBClientMock{
+		ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+			return db.ProjectEntry{ProjectID: project}, nil
+		},
+	}, m)
+
+	pe, err := c.ReadProjectEntry(context.Background(), "project1")
+	assert.NoError(t, err)
+	assert.Equal(t, db.ProjectEntry{ProjectID: "project1"}, pe)
+	assert.Contains(t, scrape(t, m), `cello_backend_request_duration_seconds_count{backend="dynamodb",operation="ReadProjectEntry"} 1`)
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+
+	c := &th.DBClientMock{}
+	assert.Same(t, c, InstrumentDB(c, m))
+
+	m.ObserveBackend(BackendArgo, "Submit", time.Now(), nil)
+	m.WorkflowSubmitted("project1", "target1", "cdk", "sync")
+	m.ObserveGitFetch("fetch", time.Second)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,238 @@
+// Package oidc verifies the OIDC ID tokens, or other JWTs, of users against
+// the JWKS of their issuer and maps their groups to roles.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// DefaultUsernameClaim is the claim naming the user when none is
+	// configured.
+	DefaultUsernameClaim = "email"
+	// DefaultGroupsClaim is the claim listing the user's groups when none is
+	// configured.
+	DefaultGroupsClaim = "groups"
+
+	// jwksRefreshInterval is the minimum time between fetches of a JWKS URL,
+	// which is fetched again when a token is signed by an unknown key.
+	jwksRefreshInterval = time.Minute
+	jwksFetchTimeout    = 10 * time.Second
+)
+
+// ErrInvalidToken is returned when a token cannot be verified.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config configures a Verifier.
+type Config struct {
+	// Issuer must match the iss claim of tokens.
+	Issuer string
+	// Audience must be in the aud claim of tokens.
+	Audience string
+	// JWKSFile or JWKSURL is the JWKS the tokens are signed with.
+	JWKSFile string
+	JWKSURL  string
+	// UsernameClaim names the user, defaulting to DefaultUsernameClaim, the
+	// sub claim is used when it is missing.
+	UsernameClaim string
+	// GroupsClaim lists the user's groups, defaulting to DefaultGroupsClaim.
+	GroupsClaim string
+	// GroupRoles maps groups to the roles bound to their members.
+	GroupRoles map[string]types.RoleBindings
+}
+
+// Claims are the verified claims of a user.
+type Claims struct {
+	Subject  string
+	Username string
+	Groups   []string
+	Roles    types.RoleBindings
+}
+
+// Verifier verifies tokens.
+type Verifier struct {
+	cfg        Config
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu        sync.Mutex
+	keys      jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// NewVerifier returns a Verifier for the configuration. The JWKS file is read
+// immediately, a JWKS URL is fetched when the first token is verified.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("issuer is required")
+	}
+	if cfg.Audience == "" {
+		return nil, errors.New("audience is required")
+	}
+	if (cfg.JWKSFile == "") == (cfg.JWKSURL == "") {
+		return nil, errors.New("one of jwks file or jwks url is required")
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = DefaultUsernameClaim
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = DefaultGroupsClaim
+	}
+	for group, roles := range cfg.GroupRoles {
+		if err := roles.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid roles for group '%s': %w", group, err)
+		}
+	}
+
+	v := &Verifier{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: jwksFetchTimeout},
+		now:        time.Now,
+	}
+
+	if cfg.JWKSFile != "" {
+		b, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read jwks file: %w", err)
+		}
+		if err := json.Unmarshal(b, &v.keys); err != nil {
+			return nil, fmt.Errorf("unable to parse jwks file: %w", err)
+		}
+	}
+
+	return v, nil
+}
+
+// Verify verifies the token's signature, issuer, audience and expiry and
+// returns its claims.
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (Claims, error) {
+	tok, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	if len(tok.Headers) != 1 {
+		return Claims{}, fmt.Errorf("%w: token must have one signature", ErrInvalidToken)
+	}
+
+	key, err := v.key(ctx, tok.Headers[0].KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	var (
+		std jwt.Claims
+		raw map[string]interface{}
+	)
+	if err := tok.Claims(key.Key, &std, &raw); err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	if std.Expiry == nil {
+		return Claims{}, fmt.Errorf("%w: token has no expiry", ErrInvalidToken)
+	}
+
+	expected := jwt.Expected{Issuer: v.cfg.Issuer, Audience: jwt.Audience{v.cfg.Audience}, Time: v.now()}
+	if err := std.ValidateWithLeeway(expected, jwt.DefaultLeeway); err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	claims := Claims{Subject: std.Subject, Username: std.Subject}
+	if username, ok := raw[v.cfg.UsernameClaim].(string); ok && username != "" {
+		claims.Username = username
+	}
+	if claims.Username == "" {
+		return Claims{}, fmt.Errorf("%w: token does not name a user", ErrInvalidToken)
+	}
+
+	claims.Groups = stringsClaim(raw[v.cfg.GroupsClaim])
+	for _, group := range claims.Groups {
+		claims.Roles = append(claims.Roles, v.cfg.GroupRoles[group]...)
+	}
+
+	return claims, nil
+}
+
+// key returns the key with the ID, fetching the JWKS URL again when the key
+// is unknown and it has not been fetched recently.
+func (v *Verifier) key(ctx context.Context, kid string) (jose.JSONWebKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if keys := v.keys.Key(kid); len(keys) > 0 {
+		return keys[0], nil
+	}
+
+	if v.cfg.JWKSURL == "" || v.now().Sub(v.fetchedAt) < jwksRefreshInterval {
+		return jose.JSONWebKey{}, fmt.Errorf("%w: unknown key '%s'", ErrInvalidToken, kid)
+	}
+
+	keys, err := v.fetchJWKS(ctx)
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	v.keys = keys
+	v.fetchedAt = v.now()
+
+	if keys := v.keys.Key(kid); len(keys) > 0 {
+		return keys[0], nil
+	}
+	return jose.JSONWebKey{}, fmt.Errorf("%w: unknown key '%s'", ErrInvalidToken, kid)
+}
+
+func (v *Verifier) fetchJWKS(ctx context.Context) (jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return jose.JSONWebKeySet{}, fmt.Errorf("unable to create jwks request: %w", err)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return jose.JSONWebKeySet{}, fmt.Errorf("unable to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return jose.JSONWebKeySet{}, fmt.Errorf("unable to read jwks: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return jose.JSONWebKeySet{}, fmt.Errorf("unable to fetch jwks, status code: %d", resp.StatusCode)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return jose.JSONWebKeySet{}, fmt.Errorf("unable to parse jwks: %w", err)
+	}
+	return keys, nil
+}
+
+// stringsClaim returns a claim which is a string or a list of strings.
+func stringsClaim(v interface{}) []string {
+	switch c := v.(type) {
+	case string:
+		return []string{c}
+	case []interface{}:
+		var r []string
+		for _, e := range c {
+			if s, ok := e.(string); ok {
+				r = append(r, s)
+			}
+		}
+		return r
+	}
+	return nil
+}
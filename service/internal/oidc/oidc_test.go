@@ -0,0 +1,311 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const testIssuer = "https://sso.example.com"
+
+type testKey struct {
+	private *rsa.PrivateKey
+	kid     string
+}
+
+func newTestKey(t *testing.T, kid string) testKey {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{private: k, kid: kid}
+}
+
+func (k testKey) jwks() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &k.private.PublicKey, KeyID: k.kid, Algorithm: string(jose.RS256), Use: "sig"}}}
+}
+
+func (k testKey) sign(t *testing.T, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: k.private, KeyID: k.kid}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func writeJWKS(t *testing.T, keys jose.JSONWebKeySet) string {
+	b, err := json.Marshal(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(p, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":    testIssuer,
+		"aud":    "cello",
+		"sub":    "00u1",
+		"email":  "alice@example.com",
+		"groups": []string{"team-a", "everyone"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	jwksFile := writeJWKS(t, newTestKey(t, "key1").jwks())
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr error
+	}{
+		{
+			name: "good file",
+			cfg:  Config{Issuer: testIssuer, Audience: "cello", JWKSFile: jwksFile},
+		},
+		{
+			name: "good url",
+			cfg:  Config{Issuer: testIssuer, Audience: "cello", JWKSURL: "https://sso.example.com/keys"},
+		},
+		{
+			name:    "missing issuer",
+			cfg:     Config{Audience: "cello", JWKSFile: jwksFile},
+			wantErr: errors.New("issuer is required"),
+		},
+		{
+			name:    "missing audience",
+			cfg:     Config{Issuer: testIssuer, JWKSFile: jwksFile},
+			wantErr: errors.New("audience is required"),
+		},
+		{
+			name:    "missing jwks",
+			cfg:     Config{Issuer: testIssuer, Audience: "cello"},
+			wantErr: errors.New("one of jwks file or jwks url is required"),
+		},
+		{
+			name:    "both jwks",
+			cfg:     Config{Issuer: testIssuer, Audience: "cello", JWKSFile: jwksFile, JWKSURL: "https://sso.example.com/keys"},
+			wantErr: errors.New("one of jwks file or jwks url is required"),
+		},
+		{
+			name: "invalid group roles",
+			cfg: Config{
+				Issuer:     testIssuer,
+				Audience:   "cello",
+				JWKSFile:   jwksFile,
+				GroupRoles: map[string]types.RoleBindings{"team-a": {{Role: types.RoleProjectAdmin}}},
+			},
+			wantErr: errors.New("invalid roles for group 'team-a': role 'project-admin' must be bound to a project"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(tt.cfg)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := newTestKey(t, "key1")
+	otherKey := newTestKey(t, "key2")
+
+	cfg := Config{
+		Issuer:   testIssuer,
+		Audience: "cello",
+		JWKSFile: writeJWKS(t, key.jwks()),
+		GroupRoles: map[string]types.RoleBindings{
+			"team-a":   {{Role: types.RoleProjectAdmin, Project: "project1"}},
+			"platform": {{Role: types.RoleAdmin}},
+		},
+	}
+
+	withClaim := func(k string, v interface{}) map[string]interface{} {
+		c := validClaims()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    Claims
+		wantErr bool
+	}{
+		{
+			name:  "good",
+			token: key.sign(t, validClaims()),
+			want: Claims{
+				Subject:  "00u1",
+				Username: "alice@example.com",
+				Groups:   []string{"team-a", "everyone"},
+				Roles:    types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			},
+		},
+		{
+			name:  "username defaults to subject",
+			token: key.sign(t, withClaim("email", nil)),
+			want: Claims{
+				Subject:  "00u1",
+				Username: "00u1",
+				Groups:   []string{"team-a", "everyone"},
+				Roles:    types.RoleBindings{{Role: types.RoleProjectAdmin, Project: "project1"}},
+			},
+		},
+		{
+			name:  "single group",
+			token: key.sign(t, withClaim("groups", "platform")),
+			want: Claims{
+				Subject:  "00u1",
+				Username: "alice@example.com",
+				Groups:   []string{"platform"},
+				Roles:    types.RoleBindings{{Role: types.RoleAdmin}},
+			},
+		},
+		{
+			name:    "malformed token",
+			token:   "not-a-jwt",
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			token:   otherKey.sign(t, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   key.sign(t, withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			token:   key.sign(t, withClaim("exp", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   key.sign(t, withClaim("iss", "https://evil.example.com")),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   key.sign(t, withClaim("aud", "other")),
+			wantErr: true,
+		},
+	}
+
+	v, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(context.Background(), tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVerifyJWKSURL(t *testing.T) {
+	key := newTestKey(t, "key1")
+	rotatedKey := newTestKey(t, "key2")
+
+	var (
+		fetches int32
+		jwks    atomic.Value
+	)
+	jwks.Store(key.jwks())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		json.NewEncoder(w).Encode(jwks.Load())
+	}))
+	defer server.Close()
+
+	v, err := NewVerifier(Config{Issuer: testIssuer, Audience: "cello", JWKSURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	_, err = v.Verify(context.Background(), key.sign(t, validClaims()))
+	assert.NoError(t, err)
+	_, err = v.Verify(context.Background(), key.sign(t, validClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "known keys should not be fetched again")
+
+	// The issuer rotates its key, which is not fetched again until the
+	// refresh interval passes.
+	jwks.Store(rotatedKey.jwks())
+
+	_, err = v.Verify(context.Background(), rotatedKey.sign(t, validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	now = now.Add(jwksRefreshInterval)
+
+	_, err = v.Verify(context.Background(), rotatedKey.sign(t, validClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func TestVerifyJWKSURLError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	v, err := NewVerifier(Config{Issuer: testIssuer, Audience: "cello", JWKSURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.Verify(context.Background(), newTestKey(t, "key1").sign(t, validClaims()))
+	assert.EqualError(t, err, "unable to fetch jwks, status code: 500")
+}
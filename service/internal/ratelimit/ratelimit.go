@@ -0,0 +1,98 @@
+// Package ratelimit limits the rate of requests of each principal with a
+// token bucket per principal.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sweepInterval is the minimum time between removals of idle buckets.
+const sweepInterval = time.Minute
+
+// Limiter limits the rate of requests of each key. The buckets of keys which
+// have been idle long enough to refill are removed, a new bucket is full.
+type Limiter struct {
+	limit rate.Limit
+	burst int
+	// idle is how long a bucket takes to refill.
+	idle time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// NewLimiter returns a Limiter allowing each key perSecond requests per
+// second, in bursts of up to burst requests. It returns nil, which allows
+// every request, when perSecond is zero.
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &Limiter{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		idle:    time.Duration(float64(burst) / perSecond * float64(time.Second)),
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow returns whether a request of the key is allowed and, when it is not,
+// how long until it would be.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep removes the buckets which have refilled since they were last used.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepInterval {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RetryAfter returns the value of the Retry-After header of a delay, in
+// whole seconds rounded up.
+func RetryAfter(delay time.Duration) int {
+	return int(math.Ceil(delay.Seconds()))
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllow(t *testing.T) {
+	l := NewLimiter(2, 3)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	// The burst is allowed at once.
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("ci-runner")
+		assert.True(t, ok)
+	}
+
+	ok, delay := l.Allow("ci-runner")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, delay)
+
+	// Each key has its own bucket.
+	ok, _ = l.Allow("teamlead1")
+	assert.True(t, ok)
+
+	// Rejected requests do not consume tokens.
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("ci-runner")
+	assert.True(t, ok)
+	ok, _ = l.Allow("ci-runner")
+	assert.False(t, ok)
+}
+
+func TestSweep(t *testing.T) {
+	l := NewLimiter(1, 2)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Allow("ci-runner")
+	l.Allow("teamlead1")
+	assert.Len(t, l.buckets, 2)
+
+	now = now.Add(sweepInterval)
+	l.Allow("teamlead1")
+	assert.Len(t, l.buckets, 1)
+
+	// A bucket which was removed is full again.
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("ci-runner")
+		assert.True(t, ok)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	l := NewLimiter(0, 20)
+	assert.Nil(t, l)
+
+	ok, delay := l.Allow("ci-runner")
+	assert.True(t, ok)
+	assert.Zero(t, delay)
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 1, RetryAfter(time.Millisecond))
+	assert.Equal(t, 1, RetryAfter(time.Second))
+	assert.Equal(t, 3, RetryAfter(2500*time.Millisecond))
+}
@@ -0,0 +1,49 @@
+// Package statuswriter records the status code of responses for the
+// middlewares which measure them.
+package statuswriter
+
+import "net/http"
+
+// Writer records the status code of a response.
+type Writer struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// New returns a Writer for w. The status is 200 until a header is written.
+func New(w http.ResponseWriter) *Writer {
+	return &Writer{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status code of the response.
+func (w *Writer) Status() int {
+	return w.status
+}
+
+func (w *Writer) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush allows streamed responses, such as workflow log streams, to be
+// flushed through the middlewares.
+func (w *Writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the connection, e.g. to
+// lift the write deadline of log streams.
+func (w *Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
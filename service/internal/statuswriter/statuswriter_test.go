@@ -0,0 +1,63 @@
+package statuswriter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w http.ResponseWriter)
+		want  int
+	}{
+		{
+			name:  "defaults to ok",
+			write: func(w http.ResponseWriter) {},
+			want:  http.StatusOK,
+		},
+		{
+			name: "records the status",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "records the first status",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "body without status is ok",
+			write: func(w http.ResponseWriter) {
+				w.Write([]byte("ok"))
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			want: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(httptest.NewRecorder())
+			tt.write(w)
+			assert.Equal(t, tt.want, w.Status())
+		})
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := New(rec)
+
+	http.NewResponseController(w).Flush()
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, rec, w.Unwrap())
+}
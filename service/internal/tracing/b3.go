@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// B3 headers, see https://github.com/openzipkin/b3-propagation.
+const (
+	b3SingleHeader  = "b3"
+	b3TraceIDHeader = "X-B3-TraceId"
+	b3SpanIDHeader  = "X-B3-SpanId"
+	b3SampledHeader = "X-B3-Sampled"
+)
+
+// B3 propagates the trace context in the single b3 header and the multiple
+// X-B3-* headers. Extract reads either, Inject writes the multiple headers,
+// which are the ones earlier versions of the service used.
+type B3 struct{}
+
+var _ propagation.TextMapPropagator = B3{}
+
+// Inject writes the span context of ctx to the carrier.
+func (B3) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanFromContext(ctx).SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+
+	carrier.Set(b3TraceIDHeader, sc.TraceID().String())
+	carrier.Set(b3SpanIDHeader, sc.SpanID().String())
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = "1"
+	}
+	carrier.Set(b3SampledHeader, sampled)
+}
+
+// Extract reads the span context from the carrier, preferring the single b3
+// header. The context is returned unchanged when the headers are missing or
+// invalid.
+func (B3) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	var sc trace.SpanContext
+	if h := carrier.Get(b3SingleHeader); h != "" {
+		sc = extractB3Single(h)
+	} else {
+		sc = extractB3Multi(carrier.Get(b3TraceIDHeader), carrier.Get(b3SpanIDHeader), carrier.Get(b3SampledHeader))
+	}
+
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields returns the headers read and written by the propagator.
+func (B3) Fields() []string {
+	return []string{b3SingleHeader, b3TraceIDHeader, b3SpanIDHeader, b3SampledHeader}
+}
+
+// extractB3Single parses {TraceId}-{SpanId}[-{SamplingState}[-{ParentSpanId}]].
+func extractB3Single(h string) trace.SpanContext {
+	parts := strings.Split(h, "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return trace.SpanContext{}
+	}
+
+	sampled := ""
+	if len(parts) > 2 {
+		sampled = parts[2]
+	}
+	return extractB3Multi(parts[0], parts[1], sampled)
+}
+
+func extractB3Multi(traceID, spanID, sampled string) trace.SpanContext {
+	// 64 bit trace IDs are left padded to 128 bits.
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+
+	var flags trace.TraceFlags
+	switch sampled {
+	// Sampling is deferred to the service when the state is missing.
+	case "1", "d", "true", "":
+		flags = trace.FlagsSampled
+	case "0", "false":
+	default:
+		return trace.SpanContext{}
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+}
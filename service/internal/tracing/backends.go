@@ -0,0 +1,498 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/git"
+	"github.com/cello-proj/cello/service/internal/workflow"
+)
+
+// Backends whose calls are traced.
+const (
+	backendArgo     = "argo"
+	backendDynamoDB = "dynamodb"
+	backendGit      = "git"
+)
+
+// Verify interface implementations at compile time
+var (
+	_ credentials.Provider = tracedProvider{}
+	_ db.Client            = tracedDB{}
+	_ git.Client           = tracedGit{}
+	_ workflow.Workflow    = tracedWorkflow{}
+)
+
+// InstrumentProvider returns the credentials provider with spans of its calls
+// as children of the span in ctx, with the provider's name as the backend.
+// Providers are created for each request, which binds them to its context.
+func InstrumentProvider(ctx context.Context, p credentials.Provider, backend string) credentials.Provider {
+	return tracedProvider{ctx: ctx, next: p, backend: backend}
+}
+
+// InstrumentDB returns the database client with spans of its calls.
+func InstrumentDB(c db.Client) db.Client {
+	return tracedDB{next: c}
+}
+
+// InstrumentGit returns the git client with spans of its calls as children of
+// the span in ctx.
+func InstrumentGit(ctx context.Context, c git.Client) git.Client {
+	return tracedGit{ctx: ctx, next: c}
+}
+
+// InstrumentWorkflow returns the workflow client with spans of its calls.
+func InstrumentWorkflow(w workflow.Workflow) workflow.Workflow {
+	return tracedWorkflow{next: w}
+}
+
+type tracedProvider struct {
+	ctx     context.Context
+	next    credentials.Provider
+	backend string
+}
+
+func (p tracedProvider) CreateProject(name string, tokenTTL time.Duration) (types.Token, error) {
+	_, span := Start(p.ctx, p.backend, "CreateProject")
+	v, err := p.next.CreateProject(name, tokenTTL)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) CreateTarget(projectName string, target types.Target) error {
+	_, span := Start(p.ctx, p.backend, "CreateTarget")
+	err := p.next.CreateTarget(projectName, target)
+	End(span, err)
+	return err
+}
+
+func (p tracedProvider) CreateToken(projectName string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+	_, span := Start(p.ctx, p.backend, "CreateToken")
+	v, err := p.next.CreateToken(projectName, scopes, policy, ttl)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) UpdateTarget(projectName string, target types.Target) error {
+	_, span := Start(p.ctx, p.backend, "UpdateTarget")
+	err := p.next.UpdateTarget(projectName, target)
+	End(span, err)
+	return err
+}
+
+func (p tracedProvider) DeleteProject(name string) error {
+	_, span := Start(p.ctx, p.backend, "DeleteProject")
+	err := p.next.DeleteProject(name)
+	End(span, err)
+	return err
+}
+
+func (p tracedProvider) DeleteTarget(projectName string, targetName string) error {
+	_, span := Start(p.ctx, p.backend, "DeleteTarget")
+	err := p.next.DeleteTarget(projectName, targetName)
+	End(span, err)
+	return err
+}
+
+func (p tracedProvider) GetProject(projectName string) (responses.GetProject, error) {
+	_, span := Start(p.ctx, p.backend, "GetProject")
+	v, err := p.next.GetProject(projectName)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) GetTarget(projectName string, targetName string) (types.Target, error) {
+	_, span := Start(p.ctx, p.backend, "GetTarget")
+	v, err := p.next.GetTarget(projectName, targetName)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) GetToken() (string, error) {
+	_, span := Start(p.ctx, p.backend, "GetToken")
+	v, err := p.next.GetToken()
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) VerifyProjectToken(projectName, roleID, secretID string) error {
+	_, span := Start(p.ctx, p.backend, "VerifyProjectToken")
+	err := p.next.VerifyProjectToken(projectName, roleID, secretID)
+	End(span, err)
+	return err
+}
+
+func (p tracedProvider) DeleteProjectToken(projectName string, tokenID string) error {
+	_, span := Start(p.ctx, p.backend, "DeleteProjectToken")
+	err := p.next.DeleteProjectToken(projectName, tokenID)
+	End(span, err)
+	return err
+}
+
+func (p tracedProvider) GetProjectToken(projectName string, tokenID string) (types.ProjectToken, error) {
+	_, span := Start(p.ctx, p.backend, "GetProjectToken")
+	v, err := p.next.GetProjectToken(projectName, tokenID)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) ListProjects() ([]string, error) {
+	_, span := Start(p.ctx, p.backend, "ListProjects")
+	v, err := p.next.ListProjects()
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) ListProjectTokens(projectName string) ([]string, error) {
+	_, span := Start(p.ctx, p.backend, "ListProjectTokens")
+	v, err := p.next.ListProjectTokens(projectName)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) ListTargets(projectName string) ([]string, error) {
+	_, span := Start(p.ctx, p.backend, "ListTargets")
+	v, err := p.next.ListTargets(projectName)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) ProjectExists(name string) (bool, error) {
+	_, span := Start(p.ctx, p.backend, "ProjectExists")
+	v, err := p.next.ProjectExists(name)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) TargetExists(projectName string, targetName string) (bool, error) {
+	_, span := Start(p.ctx, p.backend, "TargetExists")
+	v, err := p.next.TargetExists(projectName, targetName)
+	End(span, err)
+	return v, err
+}
+
+func (p tracedProvider) UpdateProjectPolicy(projectName string, policy types.ProjectPolicy, scopes []types.TokenScopes) error {
+	_, span := Start(p.ctx, p.backend, "UpdateProjectPolicy")
+	err := p.next.UpdateProjectPolicy(projectName, policy, scopes)
+	End(span, err)
+	return err
+}
+
+type tracedDB struct {
+	next db.Client
+}
+
+func (c tracedDB) CreateProjectEntry(ctx context.Context, pe db.ProjectEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "CreateProjectEntry")
+	err := c.next.CreateProjectEntry(ctx, pe)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) DeleteProjectEntry(ctx context.Context, project string) error {
+	ctx, span := Start(ctx, backendDynamoDB, "DeleteProjectEntry")
+	err := c.next.DeleteProjectEntry(ctx, project)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) ReadProjectEntry(ctx context.Context, project string) (db.ProjectEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ReadProjectEntry")
+	v, err := c.next.ReadProjectEntry(ctx, project)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) ListProjectEntries(ctx context.Context) ([]db.ProjectEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ListProjectEntries")
+	v, err := c.next.ListProjectEntries(ctx)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) UpdateProjectEntry(ctx context.Context, pe db.ProjectEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "UpdateProjectEntry")
+	err := c.next.UpdateProjectEntry(ctx, pe)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) UpdateProjectPolicy(ctx context.Context, project string, policy types.ProjectPolicy) error {
+	ctx, span := Start(ctx, backendDynamoDB, "UpdateProjectPolicy")
+	err := c.next.UpdateProjectPolicy(ctx, project, policy)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) CreateTokenEntry(ctx context.Context, token types.Token) error {
+	ctx, span := Start(ctx, backendDynamoDB, "CreateTokenEntry")
+	err := c.next.CreateTokenEntry(ctx, token)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) UpdateTokenEntry(ctx context.Context, te db.TokenEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "UpdateTokenEntry")
+	err := c.next.UpdateTokenEntry(ctx, te)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) DeleteTokenEntry(ctx context.Context, token string) error {
+	ctx, span := Start(ctx, backendDynamoDB, "DeleteTokenEntry")
+	err := c.next.DeleteTokenEntry(ctx, token)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) DeleteTokenEntryByProject(ctx context.Context, project, token string) error {
+	ctx, span := Start(ctx, backendDynamoDB, "DeleteTokenEntryByProject")
+	err := c.next.DeleteTokenEntryByProject(ctx, project, token)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) ReadTokenEntry(ctx context.Context, token string) (db.TokenEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ReadTokenEntry")
+	v, err := c.next.ReadTokenEntry(ctx, token)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) ReadTokenEntryByProject(ctx context.Context, project, token string) (db.TokenEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ReadTokenEntryByProject")
+	v, err := c.next.ReadTokenEntryByProject(ctx, project, token)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) ListTokenEntries(ctx context.Context, project string) ([]db.TokenEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ListTokenEntries")
+	v, err := c.next.ListTokenEntries(ctx, project)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) Health(ctx context.Context) error {
+	ctx, span := Start(ctx, backendDynamoDB, "Health")
+	err := c.next.Health(ctx)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) AcquireLease(ctx context.Context, name, owner string, d time.Duration) (bool, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "AcquireLease")
+	v, err := c.next.AcquireLease(ctx, name, owner, d)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) CreateIdentityEntry(ctx context.Context, ie db.IdentityEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "CreateIdentityEntry")
+	err := c.next.CreateIdentityEntry(ctx, ie)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) ReadIdentityEntry(ctx context.Context, name string) (db.IdentityEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ReadIdentityEntry")
+	v, err := c.next.ReadIdentityEntry(ctx, name)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) ListIdentityEntries(ctx context.Context) ([]db.IdentityEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ListIdentityEntries")
+	v, err := c.next.ListIdentityEntries(ctx)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) UpdateIdentityEntry(ctx context.Context, ie db.IdentityEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "UpdateIdentityEntry")
+	err := c.next.UpdateIdentityEntry(ctx, ie)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) DeleteIdentityEntry(ctx context.Context, name string) error {
+	ctx, span := Start(ctx, backendDynamoDB, "DeleteIdentityEntry")
+	err := c.next.DeleteIdentityEntry(ctx, name)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) CreateIdempotencyEntry(ctx context.Context, ie db.IdempotencyEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "CreateIdempotencyEntry")
+	err := c.next.CreateIdempotencyEntry(ctx, ie)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) ReadIdempotencyEntry(ctx context.Context, principal, key string) (db.IdempotencyEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ReadIdempotencyEntry")
+	v, err := c.next.ReadIdempotencyEntry(ctx, principal, key)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) UpdateIdempotencyEntry(ctx context.Context, ie db.IdempotencyEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "UpdateIdempotencyEntry")
+	err := c.next.UpdateIdempotencyEntry(ctx, ie)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) DeleteIdempotencyEntry(ctx context.Context, principal, key string) error {
+	ctx, span := Start(ctx, backendDynamoDB, "DeleteIdempotencyEntry")
+	err := c.next.DeleteIdempotencyEntry(ctx, principal, key)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) CreateSubmissionEntry(ctx context.Context, se db.SubmissionEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "CreateSubmissionEntry")
+	err := c.next.CreateSubmissionEntry(ctx, se)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) ReadSubmissionEntry(ctx context.Context, id string) (db.SubmissionEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ReadSubmissionEntry")
+	v, err := c.next.ReadSubmissionEntry(ctx, id)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) ListQueuedSubmissions(ctx context.Context, now time.Time) ([]string, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ListQueuedSubmissions")
+	v, err := c.next.ListQueuedSubmissions(ctx, now)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) ClaimSubmission(ctx context.Context, id string, d time.Duration) (bool, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ClaimSubmission")
+	v, err := c.next.ClaimSubmission(ctx, id, d)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) UpdateSubmissionEntry(ctx context.Context, se db.SubmissionEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "UpdateSubmissionEntry")
+	err := c.next.UpdateSubmissionEntry(ctx, se)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) CreateWebhookEntry(ctx context.Context, we db.WebhookEntry) error {
+	ctx, span := Start(ctx, backendDynamoDB, "CreateWebhookEntry")
+	err := c.next.CreateWebhookEntry(ctx, we)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) ListWebhookEntries(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "ListWebhookEntries")
+	v, err := c.next.ListWebhookEntries(ctx, project)
+	End(span, err)
+	return v, err
+}
+
+func (c tracedDB) DeleteWebhookEntry(ctx context.Context, project, id string) error {
+	ctx, span := Start(ctx, backendDynamoDB, "DeleteWebhookEntry")
+	err := c.next.DeleteWebhookEntry(ctx, project, id)
+	End(span, err)
+	return err
+}
+
+func (c tracedDB) RecordWebhookEvent(ctx context.Context, workflowName, event string, expiresAt time.Time) (bool, error) {
+	ctx, span := Start(ctx, backendDynamoDB, "RecordWebhookEvent")
+	v, err := c.next.RecordWebhookEvent(ctx, workflowName, event, expiresAt)
+	End(span, err)
+	return v, err
+}
+
+type tracedGit struct {
+	ctx  context.Context
+	next git.Client
+}
+
+func (g tracedGit) GetManifestFile(repository, commitHash, path string) ([]byte, error) {
+	_, span := Start(g.ctx, backendGit, "GetManifestFile")
+	v, err := g.next.GetManifestFile(repository, commitHash, path)
+	End(span, err)
+	return v, err
+}
+
+func (g tracedGit) Ping(ctx context.Context, repository string) error {
+	ctx, span := Start(ctx, backendGit, "Ping")
+	err := g.next.Ping(ctx, repository)
+	End(span, err)
+	return err
+}
+
+type tracedWorkflow struct {
+	next workflow.Workflow
+}
+
+func (w tracedWorkflow) ListStatus(ctx context.Context) ([]workflow.Status, error) {
+	ctx, span := Start(ctx, backendArgo, "ListStatus")
+	v, err := w.next.ListStatus(ctx)
+	End(span, err)
+	return v, err
+}
+
+func (w tracedWorkflow) Logs(ctx context.Context, workflowName string) (*workflow.Logs, error) {
+	ctx, span := Start(ctx, backendArgo, "Logs")
+	v, err := w.next.Logs(ctx, workflowName)
+	End(span, err)
+	return v, err
+}
+
+func (w tracedWorkflow) LogStream(ctx context.Context, workflowName string, data http.ResponseWriter) error {
+	ctx, span := Start(ctx, backendArgo, "LogStream")
+	err := w.next.LogStream(ctx, workflowName, data)
+	End(span, err)
+	return err
+}
+
+func (w tracedWorkflow) Status(ctx context.Context, workflowName string) (*workflow.Status, error) {
+	ctx, span := Start(ctx, backendArgo, "Status")
+	v, err := w.next.Status(ctx, workflowName)
+	End(span, err)
+	return v, err
+}
+
+func (w tracedWorkflow) Submit(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
+	ctx, span := Start(ctx, backendArgo, "Submit")
+	v, err := w.next.Submit(ctx, from, parameters, labels)
+	End(span, err)
+	return v, err
+}
+
+func (w tracedWorkflow) Health(ctx context.Context) error {
+	ctx, span := Start(ctx, backendArgo, "Health")
+	err := w.next.Health(ctx)
+	End(span, err)
+	return err
+}
+
+func (w tracedWorkflow) CountActive(ctx context.Context, namePrefix string) (int, error) {
+	ctx, span := Start(ctx, backendArgo, "CountActive")
+	v, err := w.next.CountActive(ctx, namePrefix)
+	End(span, err)
+	return v, err
+}
+
+func (w tracedWorkflow) Watch(ctx context.Context, f func(workflow.Run)) error {
+	ctx, span := Start(ctx, backendArgo, "Watch")
+	err := w.next.Watch(ctx, f)
+	End(span, err)
+	return err
+}
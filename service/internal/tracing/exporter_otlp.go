@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	otlpTracesPath = "/v1/traces"
+	otlpTimeout    = 10 * time.Second
+)
+
+// otlpExporter exports spans to a collector with OTLP over HTTP, encoded as
+// protobuf.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+}
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	return &otlpExporter{
+		url:    strings.TrimSuffix(endpoint, "/") + otlpTracesPath,
+		client: &http.Client{Timeout: otlpTimeout},
+	}
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	// TracesData has the same encoding as ExportTraceServiceRequest.
+	body, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: toResourceSpans(spans)})
+	if err != nil {
+		return fmt.Errorf("unable to marshal spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unable to export spans, collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// toResourceSpans groups spans by their resource and instrumentation scope.
+func toResourceSpans(spans []sdktrace.ReadOnlySpan) []*tracepb.ResourceSpans {
+	var out []*tracepb.ResourceSpans
+	byResource := map[*resource.Resource]*tracepb.ResourceSpans{}
+	byScope := map[*tracepb.ResourceSpans]map[instrumentation.Scope]*tracepb.ScopeSpans{}
+
+	for _, s := range spans {
+		rs, ok := byResource[s.Resource()]
+		if !ok {
+			rs = &tracepb.ResourceSpans{Resource: &resourcepb.Resource{}}
+			if r := s.Resource(); r != nil {
+				rs.Resource.Attributes = toKeyValues(r.Attributes())
+				rs.SchemaUrl = r.SchemaURL()
+			}
+			byResource[s.Resource()] = rs
+			byScope[rs] = map[instrumentation.Scope]*tracepb.ScopeSpans{}
+			out = append(out, rs)
+		}
+
+		scope := s.InstrumentationScope()
+		ss, ok := byScope[rs][scope]
+		if !ok {
+			ss = &tracepb.ScopeSpans{
+				Scope:     &commonpb.InstrumentationScope{Name: scope.Name, Version: scope.Version},
+				SchemaUrl: scope.SchemaURL,
+			}
+			byScope[rs][scope] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+
+		ss.Spans = append(ss.Spans, toSpan(s))
+	}
+
+	return out
+}
+
+func toSpan(s sdktrace.ReadOnlySpan) *tracepb.Span {
+	sc := s.SpanContext()
+	tid, sid := sc.TraceID(), sc.SpanID()
+
+	span := &tracepb.Span{
+		TraceId:                tid[:],
+		SpanId:                 sid[:],
+		TraceState:             sc.TraceState().String(),
+		Flags:                  uint32(sc.TraceFlags()),
+		Name:                   s.Name(),
+		Kind:                   toSpanKind(s.SpanKind()),
+		StartTimeUnixNano:      uint64(s.StartTime().UnixNano()),
+		EndTimeUnixNano:        uint64(s.EndTime().UnixNano()),
+		Attributes:             toKeyValues(s.Attributes()),
+		DroppedAttributesCount: uint32(s.DroppedAttributes()),
+		DroppedEventsCount:     uint32(s.DroppedEvents()),
+		Status:                 &tracepb.Status{Message: s.Status().Description},
+	}
+
+	if p := s.Parent(); p.IsValid() {
+		psid := p.SpanID()
+		span.ParentSpanId = psid[:]
+	}
+
+	switch s.Status().Code {
+	case codes.Ok:
+		span.Status.Code = tracepb.Status_STATUS_CODE_OK
+	case codes.Error:
+		span.Status.Code = tracepb.Status_STATUS_CODE_ERROR
+	}
+
+	for _, ev := range s.Events() {
+		span.Events = append(span.Events, &tracepb.Span_Event{
+			TimeUnixNano:           uint64(ev.Time.UnixNano()),
+			Name:                   ev.Name,
+			Attributes:             toKeyValues(ev.Attributes),
+			DroppedAttributesCount: uint32(ev.DroppedAttributeCount),
+		})
+	}
+
+	return span
+}
+
+func toSpanKind(k trace.SpanKind) tracepb.Span_SpanKind {
+	switch k {
+	case trace.SpanKindInternal:
+		return tracepb.Span_SPAN_KIND_INTERNAL
+	case trace.SpanKindServer:
+		return tracepb.Span_SPAN_KIND_SERVER
+	case trace.SpanKindClient:
+		return tracepb.Span_SPAN_KIND_CLIENT
+	case trace.SpanKindProducer:
+		return tracepb.Span_SPAN_KIND_PRODUCER
+	case trace.SpanKindConsumer:
+		return tracepb.Span_SPAN_KIND_CONSUMER
+	default:
+		return tracepb.Span_SPAN_KIND_UNSPECIFIED
+	}
+}
+
+func toKeyValues(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	out := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, &commonpb.KeyValue{Key: string(kv.Key), Value: toAnyValue(kv.Value)})
+	}
+	return out
+}
+
+// toAnyValue converts scalar values, slices are emitted as strings.
+func toAnyValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
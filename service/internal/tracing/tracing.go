@@ -0,0 +1,130 @@
+// Package tracing traces the service's requests and its calls to backends
+// with OpenTelemetry. The trace context of requests is read from W3C
+// traceparent or B3 headers, and written to responses and workflows.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of spans.
+const (
+	// ExporterNone creates spans, so trace IDs are propagated and echoed,
+	// without exporting them.
+	ExporterNone = "none"
+	// ExporterOTLP exports spans to an OTLP/HTTP collector.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON lines, it is intended for tests
+	// and local development.
+	ExporterStdout = "stdout"
+)
+
+const instrumentationName = "github.com/cello-proj/cello/service"
+
+// traceparentHeader is the W3C trace context header, which is also the label
+// of workflows carrying their trace context.
+const traceparentHeader = "traceparent"
+
+// Config configures Setup.
+type Config struct {
+	// Exporter is one of ExporterNone, ExporterOTLP or ExporterStdout.
+	Exporter string
+	// Endpoint is the base URL of the OTLP/HTTP collector, e.g.
+	// http://otel-collector:4318.
+	Endpoint string
+	// ServiceName names the service in its spans.
+	ServiceName string
+	// Writer is where the stdout exporter writes, os.Stdout when nil.
+	Writer io.Writer
+}
+
+// Setup installs the global tracer provider exporting with the configured
+// exporter and the propagator of W3C and B3 headers. The returned function
+// flushes and stops the tracer provider.
+func Setup(cfg Config) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone, "":
+	case ExporterOTLP:
+		if cfg.Endpoint == "" {
+			return nil, errors.New("endpoint is required for the otlp exporter")
+		}
+		exporter = newOTLPExporter(cfg.Endpoint)
+	case ExporterStdout:
+		w := cfg.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter = newStdoutExporter(w)
+	default:
+		return nil, fmt.Errorf("unknown exporter '%s'", cfg.Exporter)
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator())
+
+	return tp.Shutdown, nil
+}
+
+// Propagator returns the propagator of W3C trace context, baggage and B3
+// headers. W3C headers take precedence over B3 headers when a request has
+// both.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(B3{}, propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// Start starts a span of the operation on the backend, which is a child of
+// the span in ctx.
+func Start(ctx context.Context, backend, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, backend+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cello.backend", backend)),
+	)
+}
+
+// End ends the span, recording the error when the call failed.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx, which is empty
+// when there is none.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get(traceparentHeader)
+}
+
+// WorkflowLabels adds the trace context of ctx to the labels of a workflow,
+// so its steps can join the trace by reading the traceparent label.
+func WorkflowLabels(ctx context.Context, labels map[string]string) map[string]string {
+	if tp := TraceParent(ctx); tp != "" {
+		labels[traceparentHeader] = tp
+	}
+	return labels
+}
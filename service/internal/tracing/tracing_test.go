@@ -0,0 +1,222 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cello-proj/cello/service/internal/db"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID      = "00f067aa0ba902b7"
+	testTraceParent = "00-" + testTraceID + "-" + testSpanID + "-01"
+)
+
+func TestB3Extract(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantTraceID string
+		wantSampled bool
+	}{
+		{
+			name: "multiple headers",
+			headers: map[string]string{
+				"X-B3-TraceId": testTraceID,
+				"X-B3-SpanId":  testSpanID,
+				"X-B3-Sampled": "1",
+			},
+			wantTraceID: testTraceID,
+			wantSampled: true,
+		},
+		{
+			name:        "single header",
+			headers:     map[string]string{"b3": testTraceID + "-" + testSpanID + "-0"},
+			wantTraceID: testTraceID,
+		},
+		{
+			name:        "64 bit trace id",
+			headers:     map[string]string{"b3": "a3ce929d0e0e4736-" + testSpanID},
+			wantTraceID: "0000000000000000a3ce929d0e0e4736",
+			wantSampled: true,
+		},
+		{
+			// Earlier versions of the service only set the trace ID.
+			name:    "trace id without span id",
+			headers: map[string]string{"X-B3-TraceId": testTraceID},
+		},
+		{
+			name:    "invalid trace id",
+			headers: map[string]string{"b3": "not-hex"},
+		},
+		{
+			name:    "invalid sampling state",
+			headers: map[string]string{"b3": testTraceID + "-" + testSpanID + "-x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+
+			sc := trace.SpanContextFromContext(B3{}.Extract(context.Background(), propagation.HeaderCarrier(h)))
+			if tt.wantTraceID == "" {
+				assert.False(t, sc.IsValid())
+				return
+			}
+			assert.Equal(t, tt.wantTraceID, sc.TraceID().String())
+			assert.Equal(t, testSpanID, sc.SpanID().String())
+			assert.Equal(t, tt.wantSampled, sc.IsSampled())
+			assert.True(t, sc.IsRemote())
+		})
+	}
+}
+
+func TestB3Inject(t *testing.T) {
+	ctx := Propagator().Extract(context.Background(), propagation.MapCarrier{"traceparent": testTraceParent})
+
+	h := http.Header{}
+	B3{}.Inject(ctx, propagation.HeaderCarrier(h))
+	assert.Equal(t, testTraceID, h.Get("X-B3-TraceId"))
+	assert.Equal(t, testSpanID, h.Get("X-B3-SpanId"))
+	assert.Equal(t, "1", h.Get("X-B3-Sampled"))
+}
+
+func TestPropagatorPrefersTraceContext(t *testing.T) {
+	ctx := Propagator().Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": testTraceParent,
+		"b3":          "0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-1",
+	})
+
+	assert.Equal(t, testTraceID, trace.SpanContextFromContext(ctx).TraceID().String())
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Setup(Config{Exporter: ExporterStdout, ServiceName: "cello", Writer: &buf})
+	assert.NoError(t, err)
+
+	var labels map[string]string
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/workflows/{workflowName}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), backendArgo, "Status")
+		End(span, errors.New("error"))
+		labels = WorkflowLabels(r.Context(), map[string]string{})
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/workflows/workflow1", nil)
+	req.Header.Set("X-B3-TraceId", testTraceID)
+	req.Header.Set("X-B3-SpanId", testSpanID)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.NoError(t, shutdown(context.Background()))
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	// The trace is continued and echoed in the response.
+	assert.Equal(t, testTraceID, w.Header().Get("X-B3-TraceId"))
+	assert.Regexp(t, "^00-"+testTraceID+"-[0-9a-f]{16}-01$", w.Header().Get("traceparent"))
+	assert.Regexp(t, "^00-"+testTraceID+"-[0-9a-f]{16}-01$", labels["traceparent"])
+
+	var spans []stdoutSpan
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var s stdoutSpan
+		assert.NoError(t, dec.Decode(&s))
+		spans = append(spans, s)
+	}
+
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "argo.Status", spans[0].Name)
+		assert.Equal(t, "Error", spans[0].Status)
+		assert.Equal(t, "GET /workflows/{workflowName}", spans[1].Name)
+		assert.Equal(t, "server", spans[1].Kind)
+		assert.Equal(t, testSpanID, spans[1].ParentSpanID)
+		assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+		assert.Equal(t, "500", spans[1].Attributes["http.response.status_code"])
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got tracepb.TracesData
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, proto.Unmarshal(body, &got))
+	}))
+	defer srv.Close()
+
+	shutdown, err := Setup(Config{Exporter: ExporterOTLP, Endpoint: srv.URL, ServiceName: "cello"})
+	assert.NoError(t, err)
+
+	c := InstrumentDB(&th.DBClientMock{
+		ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+			return db.ProjectEntry{}, errors.New("error")
+		},
+	})
+	_, err = c.ReadProjectEntry(context.Background(), "project1")
+	assert.Error(t, err)
+
+	assert.NoError(t, shutdown(context.Background()))
+	otel.SetTracerProvider(noop.NewTracerProvider())
+
+	if assert.Len(t, got.ResourceSpans, 1) && assert.Len(t, got.ResourceSpans[0].ScopeSpans, 1) {
+		rs := got.ResourceSpans[0]
+		assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+		assert.Equal(t, "cello", rs.Resource.Attributes[0].Value.GetStringValue())
+
+		spans := rs.ScopeSpans[0].Spans
+		if assert.Len(t, spans, 1) {
+			assert.Equal(t, "dynamodb.ReadProjectEntry", spans[0].Name)
+			assert.Equal(t, tracepb.Span_SPAN_KIND_CLIENT, spans[0].Kind)
+			assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, spans[0].Status.Code)
+			assert.Len(t, spans[0].TraceId, 16)
+			assert.Len(t, spans[0].Events, 1)
+		}
+	}
+}
+
+func TestOTLPExporterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	spans := tracetest.SpanStubs{{Name: "argo.Submit"}}.Snapshots()
+	err := newOTLPExporter(srv.URL+"/").ExportSpans(context.Background(), spans)
+	assert.EqualError(t, err, "unable to export spans, collector returned status 503")
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	_, err := Setup(Config{Exporter: "jaeger"})
+	assert.EqualError(t, err, "unknown exporter 'jaeger'")
+
+	_, err = Setup(Config{Exporter: ExporterOTLP})
+	assert.EqualError(t, err, "endpoint is required for the otlp exporter")
+}
@@ -9,15 +9,31 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	argoWorkflowAPIClient "github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
 	argoWorkflowAPISpec "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
 const mainContainer = "main"
 
+// completedLabel is set to true by the workflow controller once a workflow
+// has completed.
+const completedLabel = "workflows.argoproj.io/completed"
+
+// Labels of the workflows submitted by Cello.
+const (
+	// ProjectLabel is the project of the workflow, it is watched to notify
+	// the project's webhooks.
+	ProjectLabel = "cello.io/project"
+	// CommitHashLabel is the commit of the manifest of workflows created
+	// from git.
+	CommitHashLabel = "cello.io/sha"
+)
+
 // Workflow interface is used for interacting with workflow services.
 type Workflow interface {
 	ListStatus(ctx context.Context) ([]Status, error)
@@ -25,6 +41,15 @@ type Workflow interface {
 	LogStream(ctx context.Context, workflowName string, data http.ResponseWriter) error
 	Status(ctx context.Context, workflowName string) (*Status, error)
 	Submit(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error)
+	// Health returns an error when the workflow service can't be reached.
+	Health(ctx context.Context) error
+	// CountActive returns the number of workflows whose names start with the
+	// prefix and which have not completed.
+	CountActive(ctx context.Context, namePrefix string) (int, error)
+	// Watch calls f with the workflows labeled with their project, as they
+	// are when the watch starts and each time they change, until the
+	// context is done or the watch fails.
+	Watch(ctx context.Context, f func(Run)) error
 }
 
 // NewArgoWorkflow creates an Argo workflow.
@@ -46,6 +71,112 @@ type Logs struct {
 	Logs []string `json:"logs"`
 }
 
+// Health lists at most one workflow of the namespace, which checks the Argo
+// API can be reached and the service is allowed to use it.
+func (a ArgoWorkflow) Health(ctx context.Context) error {
+	_, err := a.svc.ListWorkflows(ctx, &argoWorkflowAPIClient.WorkflowListRequest{
+		Namespace:   a.namespace,
+		ListOptions: &metav1.ListOptions{Limit: 1},
+		Fields:      "metadata.name",
+	})
+	return err
+}
+
+// CountActive lists the workflows which are not labeled completed by the
+// workflow controller, which includes workflows it has not picked up yet,
+// and counts those of the prefix.
+func (a ArgoWorkflow) CountActive(ctx context.Context, namePrefix string) (int, error) {
+	workflowListResult, err := a.svc.ListWorkflows(ctx, &argoWorkflowAPIClient.WorkflowListRequest{
+		Namespace:   a.namespace,
+		ListOptions: &metav1.ListOptions{LabelSelector: completedLabel + "!=true"},
+		Fields:      "items.metadata.name",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, wf := range workflowListResult.Items {
+		if strings.HasPrefix(wf.ObjectMeta.Name, namePrefix) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Run is a workflow submitted by Cello, as seen by a watch.
+type Run struct {
+	Name    string
+	Project string
+	Target  string
+	Type    string
+	// SHA is empty for workflows which were not created from git.
+	SHA string
+	// Phase is the lower case phase of the workflow, such as running or
+	// succeeded.
+	Phase      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Watch watches the workflows with the project label. Deleted workflows are
+// not passed to f.
+func (a ArgoWorkflow) Watch(ctx context.Context, f func(Run)) error {
+	stream, err := a.svc.WatchWorkflows(ctx, &argoWorkflowAPIClient.WatchWorkflowsRequest{
+		Namespace:   a.namespace,
+		ListOptions: &metav1.ListOptions{LabelSelector: ProjectLabel},
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if event.Object == nil || event.Type == "DELETED" {
+			continue
+		}
+
+		f(newRun(event.Object))
+	}
+}
+
+// newRun reads the project, target and type of a workflow from the
+// parameters it was submitted with.
+func newRun(wf *argoWorkflowAPISpec.Workflow) Run {
+	run := Run{
+		Name:       wf.ObjectMeta.Name,
+		Project:    wf.ObjectMeta.Labels[ProjectLabel],
+		SHA:        wf.ObjectMeta.Labels[CommitHashLabel],
+		Phase:      strings.ToLower(string(wf.Status.Phase)),
+		StartedAt:  wf.Status.StartedAt.Time,
+		FinishedAt: wf.Status.FinishedAt.Time,
+	}
+
+	for _, p := range wf.Spec.Arguments.Parameters {
+		if p.Value == nil {
+			continue
+		}
+		switch p.Name {
+		case "project_name":
+			run.Project = p.Value.String()
+		case "target_name":
+			run.Target = p.Value.String()
+		case "type":
+			run.Type = p.Value.String()
+		}
+	}
+
+	return run
+}
+
 // List returns a list of workflow statuses.
 func (a ArgoWorkflow) ListStatus(ctx context.Context) ([]Status, error) {
 	workflowListResult, err := a.svc.ListWorkflows(ctx, &argoWorkflowAPIClient.WorkflowListRequest{
@@ -198,13 +329,15 @@ func (a ArgoWorkflow) Submit(ctx context.Context, from string, parameters map[st
 }
 
 // NewParameters creates workflow parameters.
-func NewParameters(environmentVariablesString, executeCommand, executeContainerImageURI, targetName, projectName string, cliParameters map[string]string, credentialsToken string, flowType string) map[string]string {
+func NewParameters(environmentVariablesString, executeCommand, executeContainerImageURI, targetName, targetType, credentialType, projectName string, cliParameters map[string]string, credentialsToken string, flowType string) map[string]string {
 	parameters := map[string]string{
 		"environment_variables_string": environmentVariablesString,
 		"execute_command":              executeCommand,
 		"execute_container_image_uri":  executeContainerImageURI,
 		"project_name":                 projectName,
 		"target_name":                  targetName,
+		"target_type":                  targetType,
+		"credential_type":              credentialType,
 		"credentials_token":            credentialsToken,
 		"type":                         flowType,
 	}
@@ -3,12 +3,16 @@ package workflow
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
+	"time"
 
+	argoWorkflowAPIClient "github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
 	mockArgoWorkflowAPIClient "github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow/mocks"
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -102,6 +106,189 @@ func TestArgoWorkflowsListStatus(t *testing.T) {
 	}
 }
 
+func TestArgoHealth(t *testing.T) {
+	tests := []struct {
+		name             string
+		listWorkflowsErr error
+		errExpected      bool
+	}{
+		{
+			name: "healthy",
+		},
+		{
+			name:             "list workflows error",
+			listWorkflowsErr: errors.New("list workflows error"),
+			errExpected:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockArgoWorkflowAPIClient.WorkflowServiceClient{}
+			mockClient.On("ListWorkflows", mock.MatchedBy(func(ctx context.Context) bool { return true }), mock.MatchedBy(func(req *argoWorkflowAPIClient.WorkflowListRequest) bool {
+				return req.Namespace == "namespace" && req.ListOptions.Limit == 1
+			})).Return(new(v1alpha1.WorkflowList), tt.listWorkflowsErr)
+
+			argoWf := NewArgoWorkflow(
+				mockClient,
+				"namespace",
+			)
+
+			err := argoWf.Health(context.Background())
+			if (err != nil) != tt.errExpected {
+				t.Errorf("\nwant error: %v\n got: %v", tt.errExpected, err)
+			}
+		})
+	}
+}
+
+func TestArgoCountActive(t *testing.T) {
+	tests := []struct {
+		name             string
+		listWorkflowsErr error
+		want             int
+		errExpected      bool
+	}{
+		{
+			name: "counts workflows of the prefix",
+			want: 2,
+		},
+		{
+			name:             "list workflows error",
+			listWorkflowsErr: errors.New("list workflows error"),
+			errExpected:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockArgoWorkflowAPIClient.WorkflowServiceClient{}
+			mockClient.On("ListWorkflows", mock.MatchedBy(func(ctx context.Context) bool { return true }), mock.MatchedBy(func(req *argoWorkflowAPIClient.WorkflowListRequest) bool {
+				return req.Namespace == "namespace" && req.ListOptions.LabelSelector == "workflows.argoproj.io/completed!=true"
+			})).Return(&v1alpha1.WorkflowList{
+				Items: v1alpha1.Workflows{
+					{ObjectMeta: v1.ObjectMeta{Name: "project1-target1-abcde"}},
+					{ObjectMeta: v1.ObjectMeta{Name: "project1-target2-fghij"}},
+					{ObjectMeta: v1.ObjectMeta{Name: "project2-target1-klmno"}},
+				},
+			}, tt.listWorkflowsErr)
+
+			argoWf := NewArgoWorkflow(
+				mockClient,
+				"namespace",
+			)
+
+			got, err := argoWf.CountActive(context.Background(), "project1-")
+			if (err != nil) != tt.errExpected {
+				t.Errorf("\nwant error: %v\n got: %v", tt.errExpected, err)
+			}
+			if got != tt.want {
+				t.Errorf("\nwant: %d\n got: %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// watchStream is a watch which returns its events and then err.
+type watchStream struct {
+	grpc.ClientStream
+	events []*argoWorkflowAPIClient.WorkflowWatchEvent
+	err    error
+}
+
+func (s *watchStream) Recv() (*argoWorkflowAPIClient.WorkflowWatchEvent, error) {
+	if len(s.events) == 0 {
+		return nil, s.err
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func TestArgoWatch(t *testing.T) {
+	running := &v1alpha1.Workflow{
+		ObjectMeta: v1.ObjectMeta{
+			Name:   "project1-target1-abcde",
+			Labels: map[string]string{ProjectLabel: "project1", CommitHashLabel: "8458fd75"},
+		},
+		Spec: v1alpha1.WorkflowSpec{
+			Arguments: v1alpha1.Arguments{Parameters: []v1alpha1.Parameter{
+				{Name: "project_name", Value: v1alpha1.AnyStringPtr("project1")},
+				{Name: "target_name", Value: v1alpha1.AnyStringPtr("target1")},
+				{Name: "type", Value: v1alpha1.AnyStringPtr("sync")},
+				{Name: "execute_command", Value: v1alpha1.AnyStringPtr("cdk deploy")},
+			}},
+		},
+		Status: v1alpha1.WorkflowStatus{
+			Phase:     v1alpha1.WorkflowRunning,
+			StartedAt: v1.Unix(1658512000, 0),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		watchErr    error
+		events      []*argoWorkflowAPIClient.WorkflowWatchEvent
+		streamErr   error
+		want        []Run
+		errExpected bool
+	}{
+		{
+			name: "passes workflows until the watch ends",
+			events: []*argoWorkflowAPIClient.WorkflowWatchEvent{
+				{Type: "ADDED", Object: running},
+				{Type: "DELETED", Object: running},
+				{Type: "MODIFIED"},
+			},
+			streamErr: io.EOF,
+			want: []Run{{
+				Name:      "project1-target1-abcde",
+				Project:   "project1",
+				Target:    "target1",
+				Type:      "sync",
+				SHA:       "8458fd75",
+				Phase:     "running",
+				StartedAt: time.Unix(1658512000, 0),
+			}},
+		},
+		{
+			name:        "watch error",
+			watchErr:    errors.New("watch error"),
+			errExpected: true,
+		},
+		{
+			name:        "stream error",
+			streamErr:   errors.New("stream error"),
+			errExpected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockArgoWorkflowAPIClient.WorkflowServiceClient{}
+			mockClient.On("WatchWorkflows", mock.MatchedBy(func(ctx context.Context) bool { return true }), mock.MatchedBy(func(req *argoWorkflowAPIClient.WatchWorkflowsRequest) bool {
+				return req.Namespace == "namespace" && req.ListOptions.LabelSelector == ProjectLabel
+			})).Return(&watchStream{events: tt.events, err: tt.streamErr}, tt.watchErr)
+
+			argoWf := NewArgoWorkflow(
+				mockClient,
+				"namespace",
+			)
+
+			var got []Run
+			err := argoWf.Watch(context.Background(), func(run Run) {
+				got = append(got, run)
+			})
+			if (err != nil) != tt.errExpected {
+				t.Errorf("\nwant error: %v\n got: %v", tt.errExpected, err)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("\nwant: %v\n got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestArgoStatus(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -230,6 +417,8 @@ func TestNewParameters(t *testing.T) {
 	executeCommand := "fake_execution_command"
 	executeContainerImageURI := "fake_execute_container_image_url"
 	targetName := "fake_target_name"
+	targetType := "aws_account"
+	credentialType := "assumed_role"
 	projectName := "fake_project_name"
 	preContainerImageURI := "fake_pre_container_image_uri"
 	credentialsToken := "fake_token"
@@ -241,6 +430,8 @@ func TestNewParameters(t *testing.T) {
 		executeCommand             string
 		executeContainerImageURI   string
 		targetName                 string
+		targetType                 string
+		credentialType             string
 		projectName                string
 		cliParameters              map[string]string
 		credentialsToken           string
@@ -253,6 +444,8 @@ func TestNewParameters(t *testing.T) {
 			executeCommand:             executeCommand,
 			executeContainerImageURI:   executeContainerImageURI,
 			targetName:                 targetName,
+			targetType:                 targetType,
+			credentialType:             credentialType,
 			projectName:                projectName,
 			cliParameters:              map[string]string{"pre_container_image_uri": preContainerImageURI},
 			credentialsToken:           credentialsToken,
@@ -263,6 +456,8 @@ func TestNewParameters(t *testing.T) {
 				"execute_container_image_uri":  executeContainerImageURI,
 				"project_name":                 projectName,
 				"target_name":                  targetName,
+				"target_type":                  targetType,
+				"credential_type":              credentialType,
 				"credentials_token":            credentialsToken,
 				"type":                         flowType,
 				"pre_container_image_uri":      preContainerImageURI,
@@ -277,6 +472,8 @@ func TestNewParameters(t *testing.T) {
 				tt.executeCommand,
 				tt.executeContainerImageURI,
 				tt.targetName,
+				tt.targetType,
+				tt.credentialType,
 				tt.projectName,
 				tt.cliParameters,
 				tt.credentialsToken,
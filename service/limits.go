@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/ratelimit"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// quotaRetryAfter is when submissions rejected by a workflow quota should be
+// retried, workflows usually run for minutes.
+const quotaRetryAfter = 30 * time.Second
+
+// rateLimitMiddleware limits the rate of requests of each principal, it runs
+// before authMiddleware so requests are limited before their credentials are
+// verified. Requests without credentials are not limited, they are rejected by
+// authMiddleware unless their route is public.
+func (h handler) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := rateLimitKey(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, delay := h.rateLimiter.Allow(key); !ok {
+			l := h.requestLogger(r, "op", "rate-limit")
+			level.Warn(l).Log("message", "rate limit exceeded", "principal", key, "path", r.URL.Path)
+			h.tooManyRequests(w, delay, "too many requests, retry later")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey returns the principal the request claims to be, which is
+// limited before it is authenticated. JWTs are keyed by their hash, as the
+// user is only known once they are verified.
+func rateLimitKey(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		if cert := clientCertificate(r); cert != nil {
+			return clientCertPrefix + cert.Subject.String()
+		}
+		return ""
+	}
+
+	if strings.HasPrefix(header, bearerPrefix) {
+		sum := sha256.Sum256([]byte(strings.TrimPrefix(header, bearerPrefix)))
+		return fmt.Sprintf("%s/%x", oidcKey, sum[:8])
+	}
+
+	a, err := credentials.NewAuthorization(header)
+	if err != nil {
+		return ""
+	}
+	return a.Key
+}
+
+// tooManyRequests writes the 429 response of a limit, which can be retried
+// after the delay.
+func (h handler) tooManyRequests(w http.ResponseWriter, delay time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfter(delay)))
+	h.errorResponse(w, message, http.StatusTooManyRequests)
+}
+
+// workflowQuota returns the number of workflows the project can run
+// concurrently, zero is unlimited.
+func (h handler) workflowQuota(pe db.ProjectEntry) int {
+	if pe.WorkflowQuota > 0 {
+		return pe.WorkflowQuota
+	}
+	return h.env.WorkflowQuota
+}
+
+// checkWorkflowQuota checks the project runs fewer workflows than its quota.
+// Submissions of the project made at the same time are not serialized, so
+// they can each pass the check.
+func (h handler) checkWorkflowQuota(ctx context.Context, l log.Logger, project string) *submitError {
+	// Projects are checked to exist in the credentials provider, those
+	// missing from the database have the default quota.
+	pe, err := h.ddbClient.ReadProjectEntry(ctx, project)
+	if err != nil && !errors.Is(err, db.ErrProjectNotFound) {
+		level.Error(l).Log("message", "error retrieving project", "error", err)
+		return &submitError{status: http.StatusInternalServerError, message: "error retrieving project"}
+	}
+
+	quota := h.workflowQuota(pe)
+	if quota == 0 {
+		return nil
+	}
+
+	argoCtx, cancel := h.argoContext(ctx)
+	defer cancel()
+	// Workflow names are prefixed with their project, which cannot contain
+	// dashes.
+	active, err := h.argo.CountActive(argoCtx, project+"-")
+	if err != nil {
+		level.Error(l).Log("message", "error counting active workflows", "error", err)
+		return &submitError{status: http.StatusInternalServerError, message: "error checking workflow quota"}
+	}
+
+	if active >= quota {
+		level.Warn(l).Log("message", "workflow quota reached", "quota", quota, "active", active)
+		return &submitError{
+			status:     http.StatusTooManyRequests,
+			message:    fmt.Sprintf("project %s has reached its quota of %d running workflows", project, quota),
+			retryAfter: quotaRetryAfter,
+		}
+	}
+	return nil
+}
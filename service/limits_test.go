@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/env"
+	"github.com/cello-proj/cello/service/internal/ratelimit"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	h := handler{
+		logger: log.NewNopLogger(),
+		env:    env.Vars{AdminSecret: testPassword, CredentialsProvider: "vault"},
+		ddbClient: &th.DBClientMock{
+			ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+				return db.ProjectEntry{ProjectID: project}, nil
+			},
+		},
+		rateLimiter: ratelimit.NewLimiter(1, 2),
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := executeRequestWithHandler(h, http.MethodGet, "/projects/project1", &bytes.Buffer{}, adminAuthHeader)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp := executeRequestWithHandler(h, http.MethodGet, "/projects/project1", &bytes.Buffer{}, adminAuthHeader)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	// Requests are limited before their credentials are verified.
+	for i := 0; i < 2; i++ {
+		resp = executeRequestWithHandler(h, http.MethodGet, "/projects/project1", &bytes.Buffer{}, "vault:role-id:invalid")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp = executeRequestWithHandler(h, http.MethodGet, "/projects/project1", &bytes.Buffer{}, "vault:role-id:invalid")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// Public routes have no principal to limit.
+	resp = executeRequestWithHandler(h, http.MethodGet, "/health/live", &bytes.Buffer{}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCheckWorkflowQuota(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultQuota int
+		projectQuota int
+		readErr      error
+		// active is nil when active workflows should not be counted.
+		active         *int
+		countErr       error
+		want           bool
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name: "unlimited",
+			want: true,
+		},
+		{
+			name:         "under project quota",
+			defaultQuota: 1,
+			projectQuota: 3,
+			active:       intPtr(2),
+			want:         true,
+		},
+		{
+			name:           "project quota reached",
+			defaultQuota:   5,
+			projectQuota:   2,
+			active:         intPtr(2),
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "30",
+		},
+		{
+			name:           "default quota reached",
+			defaultQuota:   1,
+			active:         intPtr(1),
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "30",
+		},
+		{
+			name:         "project missing from database has the default quota",
+			defaultQuota: 2,
+			readErr:      db.ErrProjectNotFound,
+			active:       intPtr(1),
+			want:         true,
+		},
+		{
+			name:       "database error",
+			readErr:    errors.New("boom"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "argo error",
+			projectQuota: 1,
+			active:       intPtr(0),
+			countErr:     errors.New("boom"),
+			wantStatus:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wfMock := &th.WorkflowMock{}
+			if tt.active != nil {
+				wfMock.CountActiveFunc = func(ctx context.Context, namePrefix string) (int, error) {
+					assert.Equal(t, "project1-", namePrefix)
+					return *tt.active, tt.countErr
+				}
+			}
+
+			h := handler{
+				logger:  log.NewNopLogger(),
+				argoCtx: context.Background(),
+				argo:    wfMock,
+				env:     env.Vars{WorkflowQuota: tt.defaultQuota},
+				ddbClient: &th.DBClientMock{
+					ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+						return db.ProjectEntry{ProjectID: project, WorkflowQuota: tt.projectQuota}, tt.readErr
+					},
+				},
+			}
+
+			serr := h.checkWorkflowQuota(context.Background(), log.NewNopLogger(), "project1")
+
+			assert.Equal(t, tt.want, serr == nil)
+			if !tt.want {
+				w := httptest.NewRecorder()
+				h.submitErrorResponse(w, serr)
+				assert.Equal(t, tt.wantStatus, w.Code)
+				assert.Equal(t, tt.wantRetryAfter, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -5,20 +5,31 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
-	"net/http"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/cello-proj/cello/internal/validations"
+	"github.com/cello-proj/cello/service/internal/certs"
 	"github.com/cello-proj/cello/service/internal/credentials"
 	"github.com/cello-proj/cello/service/internal/db"
 	"github.com/cello-proj/cello/service/internal/env"
 	"github.com/cello-proj/cello/service/internal/git"
+	"github.com/cello-proj/cello/service/internal/health"
+	"github.com/cello-proj/cello/service/internal/metrics"
+	"github.com/cello-proj/cello/service/internal/oidc"
+	"github.com/cello-proj/cello/service/internal/ratelimit"
+	"github.com/cello-proj/cello/service/internal/tracing"
 	"github.com/cello-proj/cello/service/internal/workflow"
 
 	"github.com/argoproj/argo-workflows/v3/cmd/argo/commands/client"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
 )
 
 var (
@@ -42,6 +53,14 @@ func main() {
 
 	setLogLevel(&logger, env.LogLevel)
 
+	// The service is stopped by SIGTERM, e.g. from Kubernetes, or interrupted.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if !credentials.IsRegisteredProvider(env.CredentialsProvider) {
+		panic(fmt.Sprintf("Invalid credentials provider '%s', must be one of '%s'", env.CredentialsProvider, strings.Join(credentials.RegisteredProviders(), " ")))
+	}
+
 	level.Info(logger).Log("message", fmt.Sprintf("loading config '%s'", env.ConfigFilePath))
 	config, err := loadConfig(env.ConfigFilePath)
 	if err != nil {
@@ -65,25 +84,122 @@ func main() {
 		os.Exit(1)
 	}
 
+	m := metrics.New()
+
+	shutdownTracing, err := tracing.Setup(tracing.Config{
+		Exporter:    env.TracingExporter,
+		Endpoint:    env.TracingEndpoint,
+		ServiceName: "cello",
+	})
+	if err != nil {
+		level.Error(errLogger).Log("message", "error setting up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Any Argo Workflow client method calls need the context returned from NewAPIClient, otherwise
 	// nil errors will occur. Mux sets its params in context, so passing the Argo Workflow context to
 	// setupRouter and applying it to the request will wipe out Mux vars (or any other data Mux sets in its context).
 	h := handler{
 		logger:                 logger,
-		newCredentialsProvider: credentials.NewVaultProvider,
-		argo:                   workflow.NewArgoWorkflow(argoClient.NewWorkflowServiceClient(), env.ArgoNamespace),
+		newCredentialsProvider: metrics.InstrumentProviderFactory(credentials.NewProvider, m),
+		argo:                   tracing.InstrumentWorkflow(metrics.InstrumentWorkflow(workflow.NewArgoWorkflow(argoClient.NewWorkflowServiceClient(), env.ArgoNamespace), m)),
 		argoCtx:                argoCtx,
 		config:                 config,
-		gitClient:              gitClient(env, errLogger),
+		gitClient:              metrics.InstrumentGit(gitClient(env, errLogger, m), m),
 		env:                    env,
-		ddbClient:              ddbClient,
+		ddbClient:              tracing.InstrumentDB(metrics.InstrumentDB(ddbClient, m)),
+		metrics:                m,
+		rateLimiter:            ratelimit.NewLimiter(env.RateLimit, env.RateLimitBurst),
+	}
+	h.readiness = health.NewChecker(env.HealthCheckTimeout, env.HealthCacheTTL, h.readinessChecks()...)
+
+	if env.OIDCIssuer != "" {
+		h.oidcVerifier, err = oidc.NewVerifier(oidc.Config{
+			Issuer:        env.OIDCIssuer,
+			Audience:      env.OIDCAudience,
+			JWKSFile:      env.OIDCJWKSFile,
+			JWKSURL:       env.OIDCJWKSURL,
+			UsernameClaim: env.OIDCUsernameClaim,
+			GroupsClaim:   env.OIDCGroupsClaim,
+			GroupRoles:    config.OIDC.GroupRoles,
+		})
+		if err != nil {
+			level.Error(errLogger).Log("message", "error creating oidc verifier", "error", err)
+			os.Exit(1)
+		}
+		level.Info(logger).Log("message", "oidc authentication enabled", "issuer", env.OIDCIssuer)
+	}
+
+	if env.TokenSweepInterval > 0 {
+		// Replicas are told apart by their hostname, which is the pod name in
+		// Kubernetes, with a random suffix in case it is shared.
+		hostname, _ := os.Hostname()
+		owner := fmt.Sprintf("%s-%s", hostname, uuid.NewString())
+
+		level.Info(logger).Log("message", "starting token sweeper", "interval", env.TokenSweepInterval, "owner", owner)
+		go h.runTokenSweeper(ctx, env.TokenSweepInterval, owner)
+	}
+
+	if env.SubmissionQueue {
+		h.submissionsQueued = make(chan struct{}, 1)
+		level.Info(logger).Log("message", "starting submission queue", "interval", env.SubmissionPollInterval)
+		go h.runSubmissionQueue(ctx, env.SubmissionPollInterval)
+	}
+
+	if env.WebhookNotifications {
+		client, err := newWebhookClient(env.WebhookTimeout, env.WebhookAllowedNetworks)
+		if err != nil {
+			level.Error(errLogger).Log("message", "error creating webhook client", "error", err)
+			os.Exit(1)
+		}
+
+		level.Info(logger).Log("message", "starting webhook notifier", "timeout", env.WebhookTimeout)
+		go newWebhookNotifier(h, client).run(ctx)
 	}
 
-	level.Info(logger).Log("message", "starting web service", "vault addr", env.VaultAddress, "argoAddr", env.ArgoAddress)
-	if err := http.ListenAndServeTLS(fmt.Sprintf(":%d", env.Port), "ssl/certificate.crt", "ssl/certificate.key", setupRouter(h)); err != nil {
+	var reloader *certs.Reloader
+	if !env.TLSDisabled {
+		reloader, err = certs.NewReloader(env.TLSCertFile, env.TLSKeyFile, func(err error) {
+			level.Error(errLogger).Log("message", "error reloading tls certificate", "error", err)
+		})
+		if err != nil {
+			level.Error(errLogger).Log("message", "error loading tls certificate", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var clientCAs *x509.CertPool
+	if env.TLSClientCAFile != "" {
+		clientCAs, err = certs.LoadPool(env.TLSClientCAFile)
+		if err != nil {
+			level.Error(errLogger).Log("message", "error loading tls client ca file", "error", err)
+			os.Exit(1)
+		}
+		if err := config.MTLS.validate(); err != nil {
+			level.Error(errLogger).Log("message", "error validating mtls config", "error", err)
+			os.Exit(1)
+		}
+		h.clientRoles = config.MTLS.ClientRoles
+		level.Info(logger).Log("message", "mutual tls authentication enabled", "clients", len(h.clientRoles))
+	}
+
+	streamsCtx, stopStreams := context.WithCancel(context.Background())
+	h.stopStreams = streamsCtx
+	srv := newServer(env, setupRouter(h), reloader, clientCAs)
+
+	l, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
 		level.Error(errLogger).Log("message", "error starting service", "error", err)
 		os.Exit(1)
 	}
+
+	level.Info(logger).Log("message", "starting web service", "credentials provider", env.CredentialsProvider, "vault addr", env.VaultAddress, "argoAddr", env.ArgoAddress, "tls", !env.TLSDisabled)
+	if err := serve(ctx, srv, l, env.ShutdownTimeout, stopStreams, logger); err != nil {
+		level.Error(errLogger).Log("message", "error serving", "error", err)
+		os.Exit(1)
+	}
+	level.Info(logger).Log("message", "stopped web service")
 }
 
 func setLogLevel(logger *log.Logger, logLevel string) {
@@ -95,11 +211,11 @@ func setLogLevel(logger *log.Logger, logLevel string) {
 	}
 }
 
-func gitClient(env env.Vars, errLogger log.Logger) git.BasicClient {
+func gitClient(env env.Vars, errLogger log.Logger, m *metrics.Metrics) git.BasicClient {
 	var cl git.BasicClient
 	var err error
 
-	var opts []git.Option
+	opts := []git.Option{git.WithFetchObserver(m.ObserveGitFetch)}
 	if env.LogLevel == "DEBUG" {
 		opts = append(opts, git.WithProgressWriter(os.Stdout))
 	}
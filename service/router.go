@@ -3,8 +3,12 @@ package main
 import (
 	"net/http"
 
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/tracing"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -13,27 +17,56 @@ const (
 
 func setupRouter(h handler) *mux.Router {
 	r := mux.NewRouter()
+	r.Use(tracing.Middleware)
 	r.Use(commonMiddleware)
 	r.Use(txIDMiddleware)
+	r.Use(h.metrics.Middleware)
+
+	// Every route declares the access it requires, which is checked by
+	// authMiddleware before the route's handler is called.
+	requirements := map[*mux.Route]requirement{}
+	handle := func(path, method string, f http.HandlerFunc, req requirement) {
+		requirements[r.HandleFunc(path, f).Methods(method)] = req
+	}
+	r.Use(h.rateLimitMiddleware)
+	r.Use(h.authMiddleware(requirements))
 
-	r.HandleFunc("/workflows", h.createWorkflow).Methods(http.MethodPost)
-	r.HandleFunc("/workflows/{workflowName}", h.getWorkflow).Methods(http.MethodGet)
-	r.HandleFunc("/workflows/{workflowName}/logs", h.getWorkflowLogs).Methods(http.MethodGet)
-	r.HandleFunc("/workflows/{workflowName}/logstream", h.getWorkflowLogStream).Methods(http.MethodGet)
-	r.HandleFunc("/projects", h.createProject).Methods(http.MethodPost)
-	r.HandleFunc("/projects/{projectName}", h.getProject).Methods(http.MethodGet)
-	r.HandleFunc("/projects/{projectName}", h.deleteProject).Methods(http.MethodDelete)
-	r.HandleFunc("/projects/{projectName}/targets", h.listTargets).Methods(http.MethodGet)
-	r.HandleFunc("/projects/{projectName}/targets", h.createTarget).Methods(http.MethodPost)
-	r.HandleFunc("/projects/{projectName}/targets/{targetName}", h.getTarget).Methods(http.MethodGet)
-	r.HandleFunc("/projects/{projectName}/targets/{targetName}", h.deleteTarget).Methods(http.MethodDelete)
-	r.HandleFunc("/projects/{projectName}/targets/{targetName}", h.updateTarget).Methods(http.MethodPatch)
-	r.HandleFunc("/projects/{projectName}/targets/{targetName}/operations", h.createWorkflowFromGit).Methods(http.MethodPost)
-	r.HandleFunc("/projects/{projectName}/targets/{targetName}/workflows", h.listWorkflows).Methods(http.MethodGet)
-	r.HandleFunc("/projects/{projectName}/tokens", h.createToken).Methods(http.MethodPost)
-	r.HandleFunc("/projects/{projectName}/tokens", h.listTokens).Methods(http.MethodGet)
-	r.HandleFunc("/projects/{projectName}/tokens/{tokenID}", h.deleteToken).Methods(http.MethodDelete)
-	r.HandleFunc("/health/full", h.healthCheck).Methods(http.MethodGet)
+	handle("/workflows", http.MethodPost, h.idempotent(h.createWorkflow), projectMember(types.PermissionWorkflowsRun, workflowRequestProject).withCredentials())
+	handle("/workflows/{workflowName}", http.MethodGet, h.getWorkflow, projectMember(types.PermissionWorkflowsRead, workflowProject))
+	handle("/workflows/{workflowName}/logs", http.MethodGet, h.getWorkflowLogs, projectMember(types.PermissionWorkflowsRead, workflowProject))
+	handle("/workflows/{workflowName}/logstream", http.MethodGet, h.getWorkflowLogStream, projectMember(types.PermissionWorkflowsRead, workflowProject))
+	handle("/projects", http.MethodPost, h.createProject, allProjects(types.PermissionProjectsWrite).withCredentials())
+	handle("/projects/{projectName}", http.MethodGet, h.getProject, projectMember(types.PermissionProjectsRead, projectVar))
+	handle("/projects/{projectName}", http.MethodDelete, h.deleteProject, projectMember(types.PermissionProjectsWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}", http.MethodPatch, h.updateProject, projectMember(types.PermissionProjectsWrite, projectVar))
+	handle("/projects/{projectName}/policy", http.MethodGet, h.getProjectPolicy, projectMember(types.PermissionProjectsRead, projectVar))
+	handle("/projects/{projectName}/policy", http.MethodPut, h.updateProjectPolicy, projectMember(types.PermissionProjectsWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}/targets", http.MethodGet, h.listTargets, projectMember(types.PermissionTargetsRead, projectVar).withCredentials())
+	handle("/projects/{projectName}/targets", http.MethodPost, h.createTarget, projectMember(types.PermissionTargetsWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}/targets/{targetName}", http.MethodGet, h.getTarget, projectMember(types.PermissionTargetsRead, projectVar).withCredentials())
+	handle("/projects/{projectName}/targets/{targetName}", http.MethodDelete, h.deleteTarget, projectMember(types.PermissionTargetsWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}/targets/{targetName}", http.MethodPatch, h.updateTarget, projectMember(types.PermissionTargetsWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}/targets/{targetName}/operations", http.MethodPost, h.idempotent(h.createWorkflowFromGit), projectMember(types.PermissionWorkflowsRun, projectVar).withCredentials())
+	handle("/projects/{projectName}/targets/{targetName}/workflows", http.MethodGet, h.listWorkflows, projectMember(types.PermissionWorkflowsRead, projectVar))
+	handle("/projects/{projectName}/tokens", http.MethodPost, h.createToken, projectMember(types.PermissionTokensWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}/tokens", http.MethodGet, h.listTokens, projectMember(types.PermissionTokensRead, projectVar).withCredentials())
+	handle("/projects/{projectName}/tokens/{tokenID}", http.MethodDelete, h.deleteToken, projectMember(types.PermissionTokensWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}/tokens/{tokenID}/rotate", http.MethodPost, h.rotateToken, projectMember(types.PermissionTokensWrite, projectVar).withCredentials())
+	handle("/projects/{projectName}/webhooks", http.MethodPost, h.createWebhook, projectMember(types.PermissionWebhooksWrite, projectVar))
+	handle("/projects/{projectName}/webhooks", http.MethodGet, h.listWebhooks, projectMember(types.PermissionWebhooksRead, projectVar))
+	handle("/projects/{projectName}/webhooks/{webhookID}", http.MethodDelete, h.deleteWebhook, projectMember(types.PermissionWebhooksWrite, projectVar))
+	handle("/identities", http.MethodPost, h.createIdentity, allProjects(types.PermissionIdentities))
+	handle("/identities", http.MethodGet, h.listIdentities, allProjects(types.PermissionIdentities))
+	handle("/identities/{identityName}", http.MethodGet, h.getIdentity, allProjects(types.PermissionIdentities))
+	handle("/identities/{identityName}", http.MethodDelete, h.deleteIdentity, allProjects(types.PermissionIdentities))
+	handle("/identities/{identityName}/roles", http.MethodPut, h.updateIdentityRoles, allProjects(types.PermissionIdentities))
+	handle("/submissions/{submissionID}", http.MethodGet, h.getSubmission, projectMember(types.PermissionWorkflowsRead, submissionProject))
+	handle("/consistency", http.MethodGet, h.checkConsistency, allProjects(types.PermissionConsistency).withCredentials())
+	handle("/consistency/repair", http.MethodPost, h.repairConsistency, allProjects(types.PermissionConsistency).withCredentials())
+	handle("/health/full", http.MethodGet, h.healthCheck, public())
+	handle("/health/live", http.MethodGet, h.liveCheck, public())
+	handle("/health/ready", http.MethodGet, h.readyCheck, public())
+	handle("/metrics", http.MethodGet, h.metrics.Handler().ServeHTTP, public())
 	return r
 }
 
@@ -47,7 +80,13 @@ func commonMiddleware(next http.Handler) http.Handler {
 func txIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Header.Get(txIDHeader) == "" {
-			r.Header.Set(txIDHeader, uuid.NewString())
+			// The trace ID of the request's span, which is echoed in the
+			// response, identifies the request in logs.
+			txID := uuid.NewString()
+			if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+				txID = sc.TraceID().String()
+			}
+			r.Header.Set(txIDHeader, txID)
 		}
 		next.ServeHTTP(w, r)
 	})
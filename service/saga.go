@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// sagaStep is a step of a saga. The compensation undoes the action, it is
+// optional for steps which have nothing to undo.
+type sagaStep struct {
+	name       string
+	action     func(ctx context.Context) error
+	compensate func(ctx context.Context) error
+}
+
+// saga runs operations spanning the database and the credentials provider,
+// which cannot be written to transactionally. When a step fails the
+// compensations of the completed steps run in reverse order, so a failed
+// operation leaves nothing behind and retrying it converges.
+type saga struct {
+	logger    log.Logger
+	steps     []sagaStep
+	completed []string
+}
+
+func newSaga(l log.Logger) *saga {
+	return &saga{logger: l}
+}
+
+// addStep appends a step to the saga, compensate may be nil.
+func (s *saga) addStep(name string, action, compensate func(ctx context.Context) error) *saga {
+	s.steps = append(s.steps, sagaStep{name: name, action: action, compensate: compensate})
+	return s
+}
+
+// sagaError is returned by a saga when a step fails.
+type sagaError struct {
+	// Step is the name of the failed step.
+	Step string
+	Err  error
+	// CompensationErrs are the errors of the compensations which failed, the
+	// state they were meant to undo is left behind.
+	CompensationErrs []error
+}
+
+func (e *sagaError) Error() string {
+	if len(e.CompensationErrs) > 0 {
+		return fmt.Sprintf("%s: %s (%d compensations failed)", e.Step, e.Err, len(e.CompensationErrs))
+	}
+	return fmt.Sprintf("%s: %s", e.Step, e.Err)
+}
+
+func (e *sagaError) Unwrap() error {
+	return e.Err
+}
+
+// run runs the steps in order, compensating the completed steps when one
+// fails. Compensations are not cancelled with the context, as a cancelled
+// request would otherwise leave partial state behind.
+func (s *saga) run(ctx context.Context) error {
+	for _, step := range s.steps {
+		if err := step.action(ctx); err != nil {
+			level.Error(s.logger).Log("message", "saga step failed", "step", step.name, "error", err)
+			return &sagaError{Step: step.name, Err: err, CompensationErrs: s.compensate(context.WithoutCancel(ctx))}
+		}
+
+		level.Debug(s.logger).Log("message", "saga step completed", "step", step.name)
+		s.completed = append(s.completed, step.name)
+	}
+	return nil
+}
+
+func (s *saga) compensate(ctx context.Context) []error {
+	var errs []error
+	for i := len(s.completed) - 1; i >= 0; i-- {
+		step := s.steps[i]
+		if step.compensate == nil {
+			continue
+		}
+
+		if err := step.compensate(ctx); err != nil {
+			level.Error(s.logger).Log("message", "saga compensation failed", "step", step.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		level.Info(s.logger).Log("message", "saga step compensated", "step", step.name)
+	}
+	return errs
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaga(t *testing.T) {
+	errStep := errors.New("step error")
+	errCompensation := errors.New("compensation error")
+
+	tests := []struct {
+		name                 string
+		failStep             string
+		failCompensation     string
+		wantCalls            []string
+		wantErrStep          string
+		wantCompensationErrs int
+	}{
+		{
+			name:      "runs all steps",
+			wantCalls: []string{"step1", "step2", "step3"},
+		},
+		{
+			name:        "compensates completed steps in reverse order",
+			failStep:    "step3",
+			wantCalls:   []string{"step1", "step2", "step3", "compensate step2", "compensate step1"},
+			wantErrStep: "step3",
+		},
+		{
+			name:        "does not compensate the failed step",
+			failStep:    "step1",
+			wantCalls:   []string{"step1"},
+			wantErrStep: "step1",
+		},
+		{
+			name:                 "continues compensating when a compensation fails",
+			failStep:             "step3",
+			failCompensation:     "step2",
+			wantCalls:            []string{"step1", "step2", "step3", "compensate step2", "compensate step1"},
+			wantErrStep:          "step3",
+			wantCompensationErrs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []string{}
+
+			action := func(name string) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					calls = append(calls, name)
+					if name == tt.failStep {
+						return errStep
+					}
+					return nil
+				}
+			}
+
+			compensate := func(name string) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					calls = append(calls, "compensate "+name)
+					if name == tt.failCompensation {
+						return errCompensation
+					}
+					return nil
+				}
+			}
+
+			err := newSaga(log.NewNopLogger()).
+				addStep("step1", action("step1"), compensate("step1")).
+				addStep("step2", action("step2"), compensate("step2")).
+				addStep("step3", action("step3"), nil).
+				run(context.Background())
+
+			assert.Equal(t, tt.wantCalls, calls)
+
+			if tt.wantErrStep == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var se *sagaError
+			if assert.ErrorAs(t, err, &se) {
+				assert.Equal(t, tt.wantErrStep, se.Step)
+				assert.ErrorIs(t, err, errStep)
+				assert.Len(t, se.CompensationErrs, tt.wantCompensationErrs)
+			}
+		})
+	}
+}
+
+func TestSagaCompensatesAfterCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var compensationCtxErr error
+	err := newSaga(log.NewNopLogger()).
+		addStep("step1", func(ctx context.Context) error { return nil }, func(ctx context.Context) error {
+			compensationCtxErr = ctx.Err()
+			return nil
+		}).
+		addStep("step2", func(ctx context.Context) error {
+			cancel()
+			return ctx.Err()
+		}, nil).
+		run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, compensationCtxErr)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/cello-proj/cello/service/internal/certs"
+	"github.com/cello-proj/cello/service/internal/env"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// streamNoticeTimeout is how long stopped log streams have to write their
+// notice before the remaining connections are closed.
+const streamNoticeTimeout = 5 * time.Second
+
+// newServer returns the server of the handler with the timeouts of the
+// environment. It serves the certificate of the reloader, or plain HTTP
+// when the reloader is nil. Client certificates are requested and verified
+// against clientCAs when it is set, clients without one can still connect.
+func newServer(env env.Vars, handler http.Handler, reloader *certs.Reloader, clientCAs *x509.CertPool) *http.Server {
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", env.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: env.HTTPReadHeaderTimeout,
+		ReadTimeout:       env.HTTPReadTimeout,
+		WriteTimeout:      env.HTTPWriteTimeout,
+		IdleTimeout:       env.HTTPIdleTimeout,
+	}
+
+	if reloader != nil {
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+		if clientCAs != nil {
+			srv.TLSConfig.ClientCAs = clientCAs
+			srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return srv
+}
+
+// serve serves on the listener until ctx is done, then drains the server.
+// Requests in flight, including log streams, have the timeout to finish.
+// Log streams still open are then stopped, which ends them with a notice,
+// and the remaining connections are closed.
+func serve(ctx context.Context, srv *http.Server, l net.Listener, timeout time.Duration, stopStreams context.CancelFunc, logger log.Logger) error {
+	defer stopStreams()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			serveErr <- srv.ServeTLS(l, "", "")
+			return
+		}
+		serveErr <- srv.Serve(l)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	level.Info(logger).Log("message", "draining web service", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		level.Info(logger).Log("message", "stopping log streams")
+		stopStreams()
+
+		noticeCtx, cancel := context.WithTimeout(context.Background(), streamNoticeTimeout)
+		defer cancel()
+		if err = srv.Shutdown(noticeCtx); err != nil {
+			level.Warn(logger).Log("message", "closing connections which did not finish", "error", err)
+			err = srv.Close()
+		}
+	}
+
+	if sErr := <-serveErr; !errors.Is(sErr, http.ErrServerClosed) {
+		return sErr
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/service/internal/certs"
+	"github.com/cello-proj/cello/service/internal/env"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// startServer serves the router on a local port until the returned cancel
+// function is called, serve's result is sent on the returned channel.
+func startServer(t *testing.T, router http.Handler, vars env.Vars, timeout time.Duration, stopStreams context.CancelFunc) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, newServer(vars, router, nil, nil), l, timeout, stopStreams, log.NewNopLogger())
+	}()
+
+	return "http://" + l.Addr().String(), cancel, done
+}
+
+func TestServeFinishesRequestsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "done")
+	})
+
+	url, stop, done := startServer(t, router, env.Vars{}, time.Second, func() {})
+
+	respCh := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			respCh <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		respCh <- string(body)
+	}()
+
+	<-started
+	stop()
+
+	assert.Equal(t, "done", <-respCh)
+	assert.NoError(t, <-done)
+
+	// New connections are refused once the server has stopped.
+	_, err := http.Get(url)
+	assert.Error(t, err)
+}
+
+func TestServeStopsLogStreams(t *testing.T) {
+	streamsCtx, stopStreams := context.WithCancel(context.Background())
+	h := handler{
+		logger:      log.NewNopLogger(),
+		argoCtx:     context.Background(),
+		stopStreams: streamsCtx,
+		argo: &th.WorkflowMock{
+			LogStreamFunc: func(ctx context.Context, workflowName string, w http.ResponseWriter) error {
+				fmt.Fprintln(w, "pod-1: first")
+				w.(http.Flusher).Flush()
+
+				// Log streams are not bound by the write timeout.
+				time.Sleep(100 * time.Millisecond)
+				fmt.Fprintln(w, "pod-1: second")
+				w.(http.Flusher).Flush()
+
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/workflows/{workflowName}/logstream", h.getWorkflowLogStream)
+
+	url, stop, done := startServer(t, router, env.Vars{HTTPWriteTimeout: 50 * time.Millisecond}, 100*time.Millisecond, stopStreams)
+
+	resp, err := http.Get(url + "/workflows/workflow1/logstream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+	for _, want := range []string{"pod-1: first\n", "pod-1: second\n"} {
+		line, err := r.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, want, line)
+	}
+
+	stop()
+
+	rest, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, logStreamShutdownNotice+"\n", string(rest))
+	assert.NoError(t, <-done)
+}
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCA{cert: cert, key: key}
+}
+
+// issue returns a certificate of the common name for the usage, signed by
+// the CA.
+func (ca testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeTLSFiles writes the certificate and its key to PEM files in dir.
+func writeTLSFiles(t *testing.T, dir string, cert tls.Certificate) (string, string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestServeClientCertificates(t *testing.T) {
+	serverCA, clientCA, otherCA := newTestCA(t), newTestCA(t), newTestCA(t)
+
+	certFile, keyFile := writeTLSFiles(t, t.TempDir(), serverCA.issue(t, "localhost", x509.ExtKeyUsageServerAuth))
+	reloader, err := certs.NewReloader(certFile, keyFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.cert)
+
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cert := clientCertificate(r); cert != nil {
+			fmt.Fprint(w, cert.Subject.CommonName)
+		}
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go serve(ctx, newServer(env.Vars{}, router, reloader, clientCAs), l, time.Second, func() {}, log.NewNopLogger())
+
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(serverCA.cert)
+
+	tests := []struct {
+		name    string
+		certs   []tls.Certificate
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "verified client certificate",
+			certs: []tls.Certificate{clientCA.issue(t, "ci-runner", x509.ExtKeyUsageClientAuth)},
+			want:  "ci-runner",
+		},
+		{
+			name: "no client certificate",
+		},
+		{
+			name:    "client certificate of another ca",
+			certs:   []tls.Certificate{otherCA.issue(t, "ci-runner", x509.ExtKeyUsageClientAuth)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      serverCAs,
+				ServerName:   "localhost",
+				Certificates: tt.certs,
+			}}}
+
+			resp, err := client.Get("https://" + l.Addr().String())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// submissionClaimDuration is how long a replica holds a submission it
+	// attempts, after which another replica can attempt it in case the
+	// replica stopped.
+	submissionClaimDuration = 5 * time.Minute
+	// submissionRetention is how long submissions are kept once queued.
+	submissionRetention = 7 * 24 * time.Hour
+
+	submissionMinBackoff = 5 * time.Second
+	submissionMaxBackoff = 2 * time.Minute
+)
+
+// queuedSubmission is what is stored of a queued request, the worker submits
+// it with a project token created with the scopes of the principal.
+type queuedSubmission struct {
+	Request requests.CreateWorkflow `json:"request"`
+	Scopes  types.TokenScopes       `json:"scopes,omitempty"`
+	Labels  map[string]string       `json:"labels,omitempty"`
+}
+
+// prefersAsync returns whether the request asks to be processed
+// asynchronously with Prefer: respond-async.
+func prefersAsync(header http.Header) bool {
+	for _, v := range header.Values("Prefer") {
+		for _, p := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(p), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// submissionProject returns the project of the route's submission,
+// submission IDs are prefixed with their project.
+func submissionProject(r *http.Request) string {
+	project, _, _ := strings.Cut(mux.Vars(r)["submissionID"], "-")
+	return project
+}
+
+func submissionResponse(se db.SubmissionEntry) responses.GetSubmission {
+	return responses.GetSubmission{
+		ID:           se.ID,
+		Status:       se.Status,
+		WorkflowName: se.WorkflowName,
+		Error:        se.Error,
+		Attempts:     se.Attempts,
+		CreatedAt:    se.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// queueSubmission stores a validated request to be submitted by the worker
+// and responds with its submission.
+func (h handler) queueSubmission(ctx context.Context, w http.ResponseWriter, l log.Logger, qs queuedSubmission) {
+	request, err := json.Marshal(qs)
+	if err != nil {
+		level.Error(l).Log("message", "error serializing submission", "error", err)
+		h.errorResponse(w, "error queueing submission", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	se := db.SubmissionEntry{
+		ID:            fmt.Sprintf("%s-%s", qs.Request.ProjectName, uuid.NewString()),
+		Status:        db.SubmissionQueued,
+		Request:       string(request),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+		ExpiresAt:     now.Add(submissionRetention),
+	}
+
+	if err := h.ddbClient.CreateSubmissionEntry(ctx, se); err != nil {
+		level.Error(l).Log("message", "error creating submission", "error", err)
+		h.errorResponse(w, "error queueing submission", http.StatusInternalServerError)
+		return
+	}
+
+	// The worker of this replica attempts the submission right away.
+	select {
+	case h.submissionsQueued <- struct{}{}:
+	default:
+	}
+
+	level.Info(l).Log("message", "queued submission", "submission", se.ID)
+	h.submissionResponse(w, l, se)
+}
+
+// submissionResponse writes the 202 response of a queued submission.
+func (h handler) submissionResponse(w http.ResponseWriter, l log.Logger, se db.SubmissionEntry) {
+	w.Header().Set("Preference-Applied", "respond-async")
+	w.Header().Set("Location", "/submissions/"+se.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(submissionResponse(se)); err != nil {
+		level.Error(l).Log("message", "error serializing submission", "error", err)
+	}
+}
+
+func (h handler) getSubmission(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["submissionID"]
+	l := h.requestLogger(r, "op", "get-submission", "submission", id)
+
+	se, err := h.ddbClient.ReadSubmissionEntry(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrSubmissionNotFound) {
+			h.errorResponse(w, "submission not found", http.StatusNotFound)
+			return
+		}
+		level.Error(l).Log("message", "error retrieving submission", "error", err)
+		h.errorResponse(w, "error retrieving submission", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(submissionResponse(se)); err != nil {
+		level.Error(l).Log("message", "error serializing submission", "error", err)
+	}
+}
+
+// runSubmissionQueue attempts the queued submissions which are due every
+// interval, and as soon as this replica queues one, until the context is done.
+func (h handler) runSubmissionQueue(ctx context.Context, interval time.Duration) {
+	l := log.With(h.logger, "op", "submission-queue")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-h.submissionsQueued:
+		}
+
+		h.processSubmissions(ctx, l, time.Now())
+	}
+}
+
+// processSubmissions attempts the queued submissions which are due. Each one
+// is claimed first, so replicas do not attempt the same submission at once.
+func (h handler) processSubmissions(ctx context.Context, l log.Logger, now time.Time) {
+	ids, err := h.ddbClient.ListQueuedSubmissions(ctx, now)
+	if err != nil {
+		level.Error(l).Log("message", "error listing queued submissions", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		sl := log.With(l, "submission", id)
+
+		ok, err := h.ddbClient.ClaimSubmission(ctx, id, submissionClaimDuration)
+		if err != nil {
+			level.Error(sl).Log("message", "error claiming submission", "error", err)
+			continue
+		}
+		if !ok {
+			level.Debug(sl).Log("message", "submission claimed by another replica")
+			continue
+		}
+
+		h.attemptSubmission(ctx, sl, id, now)
+	}
+}
+
+// attemptSubmission submits a claimed submission. Submissions failing with an
+// error which can succeed later are attempted again with exponential backoff,
+// until the retry timeout has passed since they were queued.
+func (h handler) attemptSubmission(ctx context.Context, l log.Logger, id string, now time.Time) {
+	se, err := h.ddbClient.ReadSubmissionEntry(ctx, id)
+	if err != nil {
+		// The submission is attempted again once its claim has expired.
+		level.Error(l).Log("message", "error retrieving submission", "error", err)
+		return
+	}
+
+	if se.Status == db.SubmissionQueued {
+		se.Attempts++
+		workflowName, serr := h.submitQueued(ctx, l, se)
+
+		delay := submissionBackoff(se.Attempts)
+		if serr != nil && serr.retryAfter > delay {
+			delay = serr.retryAfter
+		}
+
+		switch {
+		case serr == nil:
+			se.Status, se.WorkflowName, se.Error = db.SubmissionSubmitted, workflowName, ""
+			level.Info(l).Log("message", "submitted queued submission", "workflow", workflowName, "attempts", se.Attempts)
+		case serr.retryable() && now.Add(delay).Before(se.CreatedAt.Add(h.env.SubmissionRetryTimeout)):
+			se.Error, se.NextAttemptAt = serr.message, now.Add(delay)
+			level.Warn(l).Log("message", "retrying queued submission", "error", serr.message, "attempts", se.Attempts, "next_attempt_at", se.NextAttemptAt)
+		default:
+			se.Status, se.Error = db.SubmissionFailed, serr.message
+			level.Error(l).Log("message", "queued submission failed", "error", serr.message, "attempts", se.Attempts)
+		}
+	}
+
+	// Submissions which are no longer queued are removed from the queue.
+	if err := h.ddbClient.UpdateSubmissionEntry(ctx, se); err != nil {
+		level.Error(l).Log("message", "error updating submission", "status", se.Status, "workflow", se.WorkflowName, "error", err)
+	}
+}
+
+// submitQueued submits the workflow of a queued submission. The request was
+// authorized when it was queued, the workflow is submitted as an admin with
+// a project token limited to the scopes of the principal.
+func (h handler) submitQueued(ctx context.Context, l log.Logger, se db.SubmissionEntry) (string, *submitError) {
+	var qs queuedSubmission
+	if err := json.Unmarshal([]byte(se.Request), &qs); err != nil {
+		level.Error(l).Log("message", "error deserializing submission", "error", err)
+		return "", &submitError{status: http.StatusBadRequest, message: "invalid queued request"}
+	}
+
+	cwr := qs.Request
+	l = log.With(l, "txid", qs.Labels[txIDHeader], "project", cwr.ProjectName, "target", cwr.TargetName)
+
+	// The config can have changed since the request was queued, which is
+	// not fixed by retrying.
+	ws, serr := h.newWorkflowSubmission(l, cwr)
+	if serr != nil {
+		return "", &submitError{status: http.StatusBadRequest, message: serr.message}
+	}
+	ws.labels = qs.Labels
+
+	a := credentials.AdminAuthorization(h.env.CredentialsProvider, h.env.AdminSecret)
+	cp, err := h.credentialsProvider(ctx, a, http.Header{})
+	if err != nil {
+		level.Error(l).Log("message", "error creating credentials provider", "error", err)
+		return "", &submitError{status: http.StatusInternalServerError, message: "error creating credentials provider"}
+	}
+
+	return h.submitWorkflow(ctx, l, cp, http.Header{}, ws, func() (string, error) {
+		return h.credentialsToken(ctx, l, cp, a, http.Header{}, cwr.ProjectName, qs.Scopes)
+	})
+}
+
+// submissionBackoff returns the delay before the next attempt of a
+// submission which has been attempted the number of times.
+func submissionBackoff(attempts int) time.Duration {
+	delay := submissionMinBackoff
+	for i := 1; i < attempts && delay < submissionMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, submissionMaxBackoff)
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/env"
+	"github.com/cello-proj/cello/service/internal/workflow"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueSubmission(t *testing.T) {
+	tests := []struct {
+		name       string
+		reqFile    string
+		authHeader string
+		wantStatus int
+		wantQueued bool
+	}{
+		{
+			name:       "queues valid request",
+			reqFile:    "TestCreateWorkflow/can_create_workflow_request.json",
+			authHeader: adminAuthHeader,
+			wantStatus: http.StatusAccepted,
+			wantQueued: true,
+		},
+		{
+			name:       "queues valid request of project token",
+			reqFile:    "TestCreateWorkflow/can_create_workflow_request.json",
+			authHeader: "vault:role-id:secret",
+			wantStatus: http.StatusAccepted,
+			wantQueued: true,
+		},
+		{
+			name:       "project token with invalid secret is not queued",
+			reqFile:    "TestCreateWorkflow/can_create_workflow_request.json",
+			authHeader: "vault:role-id:invalid",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid request is not queued",
+			reqFile:    "TestCreateWorkflow/framework_must_be_valid_request.json",
+			authHeader: adminAuthHeader,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadConfig(testConfigPath)
+			assert.NoError(t, err)
+
+			var queued db.SubmissionEntry
+			dbMock := &th.DBClientMock{
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					return nil, nil
+				},
+				CreateSubmissionEntryFunc: func(ctx context.Context, se db.SubmissionEntry) error {
+					queued = se
+					return nil
+				},
+			}
+
+			h := handler{
+				logger:  log.NewNopLogger(),
+				argoCtx: context.Background(),
+				config:  config,
+				env:     env.Vars{AdminSecret: testPassword, CredentialsProvider: credentials.ProviderVault, SubmissionQueue: true},
+				newCredentialsProvider: func(a credentials.Authorization, env env.Vars, h http.Header, f credentials.VaultConfigFn, fn credentials.VaultSvcFn) (credentials.Provider, error) {
+					return &th.CredsProviderMock{
+						VerifyProjectTokenFunc: func(project, roleID, secretID string) error {
+							if secretID != "secret" {
+								return credentials.ErrInvalidProjectToken
+							}
+							return nil
+						},
+						GetTokenFunc: func() (string, error) { return "credentials-token", nil },
+					}, nil
+				},
+				ddbClient:         dbMock,
+				submissionsQueued: make(chan struct{}, 1),
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/workflows", serialize(loadJSON(t, tt.reqFile)))
+			req.Header.Set("Authorization", tt.authHeader)
+			req.Header.Set("Prefer", "respond-async")
+			w := httptest.NewRecorder()
+			setupRouter(h).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if !tt.wantQueued {
+				assert.Empty(t, dbMock.CreateSubmissionEntryCalls())
+				return
+			}
+
+			var resp responses.GetSubmission
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.True(t, strings.HasPrefix(resp.ID, "projectalreadyexists-"))
+			assert.Equal(t, queued.ID, resp.ID)
+			assert.Equal(t, db.SubmissionQueued, resp.Status)
+			assert.Equal(t, "respond-async", w.Header().Get("Preference-Applied"))
+			assert.Equal(t, "/submissions/"+resp.ID, w.Header().Get("Location"))
+
+			var qs queuedSubmission
+			assert.NoError(t, json.Unmarshal([]byte(queued.Request), &qs))
+			assert.Equal(t, "TARGET_EXISTS", qs.Request.TargetName)
+			assert.WithinDuration(t, time.Now(), queued.NextAttemptAt, time.Minute)
+			assert.WithinDuration(t, time.Now().Add(submissionRetention), queued.ExpiresAt, time.Minute)
+
+			// The worker is woken up.
+			assert.Len(t, h.submissionsQueued, 1)
+		})
+	}
+}
+
+func TestGetSubmission(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []test{
+		{
+			name:       "can get submission",
+			want:       http.StatusOK,
+			respFile:   "TestGetSubmission/can_get_submission_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/submissions/project1-6f1c0b8e",
+			method:     http.MethodGet,
+			ddbMock: &th.DBClientMock{
+				ReadSubmissionEntryFunc: func(ctx context.Context, id string) (db.SubmissionEntry, error) {
+					return db.SubmissionEntry{
+						ID:           id,
+						Status:       db.SubmissionSubmitted,
+						Attempts:     2,
+						WorkflowName: "project1-target1-abcde",
+						CreatedAt:    createdAt,
+					}, nil
+				},
+			},
+		},
+		{
+			name:       "submission must exist",
+			want:       http.StatusNotFound,
+			respFile:   "TestGetSubmission/submission_must_exist_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/submissions/project1-6f1c0b8e",
+			method:     http.MethodGet,
+			ddbMock: &th.DBClientMock{
+				ReadSubmissionEntryFunc: func(ctx context.Context, id string) (db.SubmissionEntry, error) {
+					return db.SubmissionEntry{}, db.ErrSubmissionNotFound
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestAttemptSubmission(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		status    string
+		attempts  int
+		createdAt time.Time
+		// request is the stored request, a valid request when empty.
+		request         string
+		targetExists    bool
+		submitErr       error
+		quota           int
+		wantStatus      string
+		wantWorkflow    string
+		wantError       string
+		wantNextAttempt time.Time
+	}{
+		{
+			name:         "submits workflow",
+			createdAt:    now,
+			targetExists: true,
+			wantStatus:   db.SubmissionSubmitted,
+			wantWorkflow: "project1-target1-abcde",
+		},
+		{
+			name:            "retries argo errors with backoff",
+			attempts:        2,
+			createdAt:       now,
+			targetExists:    true,
+			submitErr:       errors.New("argo unavailable"),
+			wantStatus:      db.SubmissionQueued,
+			wantError:       "error creating workflow",
+			wantNextAttempt: now.Add(20 * time.Second),
+		},
+		{
+			name:            "retries quota after the quota's delay",
+			createdAt:       now,
+			targetExists:    true,
+			quota:           1,
+			wantStatus:      db.SubmissionQueued,
+			wantError:       "project project1 has reached its quota of 1 running workflows",
+			wantNextAttempt: now.Add(quotaRetryAfter),
+		},
+		{
+			name:         "fails once the retry timeout has passed",
+			createdAt:    now.Add(-time.Hour),
+			targetExists: true,
+			submitErr:    errors.New("argo unavailable"),
+			wantStatus:   db.SubmissionFailed,
+			wantError:    "error creating workflow",
+		},
+		{
+			name:       "fails errors which cannot succeed later",
+			createdAt:  now,
+			wantStatus: db.SubmissionFailed,
+			wantError:  "target not found",
+		},
+		{
+			name:       "fails invalid requests",
+			createdAt:  now,
+			request:    `{"request":{"framework":"unknown"}}`,
+			wantStatus: db.SubmissionFailed,
+			wantError:  "invalid request, framework must be one of 'cdk cool-new-framework terraform'",
+		},
+		{
+			name:         "removes submissions which are no longer queued",
+			status:       db.SubmissionSubmitted,
+			attempts:     1,
+			createdAt:    now,
+			wantStatus:   db.SubmissionSubmitted,
+			wantWorkflow: "project1-target1-fghij",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadConfig(testConfigPath)
+			assert.NoError(t, err)
+
+			request := tt.request
+			if request == "" {
+				b, err := json.Marshal(queuedSubmission{
+					Request: requests.CreateWorkflow{
+						Arguments:            map[string][]string{"execute": {"foobar"}},
+						Framework:            "cdk",
+						Parameters:           map[string]string{"execute_container_image_uri": "celloproj/cello-cdk:1.87.1"},
+						ProjectName:          "project1",
+						TargetName:           "target1",
+						Type:                 "sync",
+						WorkflowTemplateName: "cello-single-step-vault-aws",
+					},
+					Scopes: types.TokenScopes{{Target: "target1", Operations: []string{"sync"}}},
+					Labels: map[string]string{txIDHeader: "txid1"},
+				})
+				assert.NoError(t, err)
+				request = string(b)
+			}
+
+			status := tt.status
+			if status == "" {
+				status = db.SubmissionQueued
+			}
+			stored := db.SubmissionEntry{
+				ID:        "project1-6f1c0b8e",
+				Status:    status,
+				Request:   request,
+				Attempts:  tt.attempts,
+				CreatedAt: tt.createdAt,
+			}
+			if status == db.SubmissionSubmitted {
+				stored.WorkflowName = "project1-target1-fghij"
+			}
+
+			var updated db.SubmissionEntry
+			dbMock := &th.DBClientMock{
+				ReadSubmissionEntryFunc: func(ctx context.Context, id string) (db.SubmissionEntry, error) {
+					return stored, nil
+				},
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project, WorkflowQuota: tt.quota}, nil
+				},
+				UpdateSubmissionEntryFunc: func(ctx context.Context, se db.SubmissionEntry) error {
+					updated = se
+					return nil
+				},
+			}
+
+			cpMock := &th.CredsProviderMock{
+				ProjectExistsFunc: func(project string) (bool, error) { return true, nil },
+				GetTargetFunc: func(project, target string) (types.Target, error) {
+					if !tt.targetExists {
+						return types.Target{}, credentials.ErrTargetNotFound
+					}
+					return types.Target{Name: target, Type: types.TargetTypeAWSAccount}, nil
+				},
+				CreateTokenFunc: func(project string, scopes types.TokenScopes, policy types.ProjectPolicy, ttl time.Duration) (types.Token, error) {
+					// Queued workflows are limited to the scopes of the
+					// principal which queued them.
+					assert.Equal(t, types.TokenScopes{{Target: "target1", Operations: []string{"sync"}}}, scopes)
+					return types.Token{ProjectToken: types.ProjectToken{ID: "token1"}, RoleID: "role-id", Secret: "secret"}, nil
+				},
+				DeleteProjectTokenFunc: func(project, tokenID string) error { return nil },
+				GetTokenFunc:           func() (string, error) { return "credentials-token", nil },
+			}
+
+			wfMock := &th.WorkflowMock{
+				CountActiveFunc: func(ctx context.Context, namePrefix string) (int, error) {
+					return 1, nil
+				},
+				SubmitFunc: func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
+					assert.Equal(t, "txid1", labels[txIDHeader])
+					return "project1-target1-abcde", tt.submitErr
+				},
+			}
+
+			h := handler{
+				logger:  log.NewNopLogger(),
+				argoCtx: context.Background(),
+				argo:    wfMock,
+				config:  config,
+				env:     env.Vars{AdminSecret: testPassword, CredentialsProvider: credentials.ProviderVault, SubmissionRetryTimeout: 15 * time.Minute},
+				newCredentialsProvider: func(a credentials.Authorization, env env.Vars, h http.Header, f credentials.VaultConfigFn, fn credentials.VaultSvcFn) (credentials.Provider, error) {
+					return cpMock, nil
+				},
+				ddbClient: dbMock,
+			}
+
+			h.attemptSubmission(context.Background(), log.NewNopLogger(), "project1-6f1c0b8e", now)
+
+			assert.Equal(t, tt.wantStatus, updated.Status)
+			assert.Equal(t, tt.wantWorkflow, updated.WorkflowName)
+			assert.Equal(t, tt.wantError, updated.Error)
+			assert.Equal(t, tt.wantNextAttempt, updated.NextAttemptAt)
+			if status == db.SubmissionQueued {
+				assert.Equal(t, tt.attempts+1, updated.Attempts)
+			}
+		})
+	}
+}
+
+func TestProcessSubmissions(t *testing.T) {
+	dbMock := &th.DBClientMock{
+		ListQueuedSubmissionsFunc: func(ctx context.Context, now time.Time) ([]string, error) {
+			return []string{"project1-claimed", "project1-held"}, nil
+		},
+		ClaimSubmissionFunc: func(ctx context.Context, id string, d time.Duration) (bool, error) {
+			assert.Equal(t, submissionClaimDuration, d)
+			return id == "project1-claimed", nil
+		},
+		ReadSubmissionEntryFunc: func(ctx context.Context, id string) (db.SubmissionEntry, error) {
+			return db.SubmissionEntry{ID: id, Status: db.SubmissionSubmitted}, nil
+		},
+		UpdateSubmissionEntryFunc: func(ctx context.Context, se db.SubmissionEntry) error {
+			return nil
+		},
+	}
+
+	h := handler{logger: log.NewNopLogger(), ddbClient: dbMock}
+	h.processSubmissions(context.Background(), log.NewNopLogger(), time.Now())
+
+	// Submissions claimed by another replica are not attempted.
+	if assert.Len(t, dbMock.ReadSubmissionEntryCalls(), 1) {
+		assert.Equal(t, "project1-claimed", dbMock.ReadSubmissionEntryCalls()[0].Id)
+	}
+}
+
+func TestPrefersAsync(t *testing.T) {
+	tests := []struct {
+		prefer []string
+		want   bool
+	}{
+		{prefer: nil},
+		{prefer: []string{"respond-async"}, want: true},
+		{prefer: []string{"wait=10, Respond-Async"}, want: true},
+		{prefer: []string{"return=minimal", "respond-async"}, want: true},
+		{prefer: []string{"return=minimal"}},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		for _, p := range tt.prefer {
+			header.Add("Prefer", p)
+		}
+		assert.Equal(t, tt.want, prefersAsync(header), tt.prefer)
+	}
+}
+
+func TestSubmissionBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, submissionBackoff(1))
+	assert.Equal(t, 10*time.Second, submissionBackoff(2))
+	assert.Equal(t, 80*time.Second, submissionBackoff(5))
+	assert.Equal(t, submissionMaxBackoff, submissionBackoff(6))
+	assert.Equal(t, submissionMaxBackoff, submissionBackoff(100))
+}
+
+// The response of a queued submission can be decoded as the workflow response
+// by clients which ignore the status code, without a workflow name.
+func TestSubmissionResponseHasNoWorkflowName(t *testing.T) {
+	b, err := json.Marshal(submissionResponse(db.SubmissionEntry{ID: "project1-6f1c0b8e", Status: db.SubmissionQueued}))
+	assert.NoError(t, err)
+
+	var cwresp workflow.CreateWorkflowResponse
+	assert.NoError(t, json.Unmarshal(b, &cwresp))
+	assert.Empty(t, cwresp.WorkflowName)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/cello-proj/cello/internal/types"
 	"github.com/cello-proj/cello/service/internal/credentials"
 	"sync"
+	"time"
 )
 
 // Ensure, that CredsProviderMock does implement credentials.Provider.
@@ -20,13 +21,13 @@ var _ credentials.Provider = &CredsProviderMock{}
 //
 //		// make and configure a mocked credentials.Provider
 //		mockedProvider := &CredsProviderMock{
-//			CreateProjectFunc: func(s string) (types.Token, error) {
+//			CreateProjectFunc: func(s string, duration time.Duration) (types.Token, error) {
 //				panic("mock out the CreateProject method")
 //			},
 //			CreateTargetFunc: func(s string, target types.Target) error {
 //				panic("mock out the CreateTarget method")
 //			},
-//			CreateTokenFunc: func(s string) (types.Token, error) {
+//			CreateTokenFunc: func(s string, tokenScopes types.TokenScopes, projectPolicy types.ProjectPolicy, duration time.Duration) (types.Token, error) {
 //				panic("mock out the CreateToken method")
 //			},
 //			DeleteProjectFunc: func(s string) error {
@@ -50,6 +51,12 @@ var _ credentials.Provider = &CredsProviderMock{}
 //			GetTokenFunc: func() (string, error) {
 //				panic("mock out the GetToken method")
 //			},
+//			ListProjectTokensFunc: func(s string) ([]string, error) {
+//				panic("mock out the ListProjectTokens method")
+//			},
+//			ListProjectsFunc: func() ([]string, error) {
+//				panic("mock out the ListProjects method")
+//			},
 //			ListTargetsFunc: func(s string) ([]string, error) {
 //				panic("mock out the ListTargets method")
 //			},
@@ -59,9 +66,15 @@ var _ credentials.Provider = &CredsProviderMock{}
 //			TargetExistsFunc: func(s1 string, s2 string) (bool, error) {
 //				panic("mock out the TargetExists method")
 //			},
+//			UpdateProjectPolicyFunc: func(s string, projectPolicy types.ProjectPolicy, tokenScopess []types.TokenScopes) error {
+//				panic("mock out the UpdateProjectPolicy method")
+//			},
 //			UpdateTargetFunc: func(s string, target types.Target) error {
 //				panic("mock out the UpdateTarget method")
 //			},
+//			VerifyProjectTokenFunc: func(s1 string, s2 string, s3 string) error {
+//				panic("mock out the VerifyProjectToken method")
+//			},
 //		}
 //
 //		// use mockedProvider in code that requires credentials.Provider
@@ -70,13 +83,13 @@ var _ credentials.Provider = &CredsProviderMock{}
 //	}
 type CredsProviderMock struct {
 	// CreateProjectFunc mocks the CreateProject method.
-	CreateProjectFunc func(s string) (types.Token, error)
+	CreateProjectFunc func(s string, duration time.Duration) (types.Token, error)
 
 	// CreateTargetFunc mocks the CreateTarget method.
 	CreateTargetFunc func(s string, target types.Target) error
 
 	// CreateTokenFunc mocks the CreateToken method.
-	CreateTokenFunc func(s string) (types.Token, error)
+	CreateTokenFunc func(s string, tokenScopes types.TokenScopes, projectPolicy types.ProjectPolicy, duration time.Duration) (types.Token, error)
 
 	// DeleteProjectFunc mocks the DeleteProject method.
 	DeleteProjectFunc func(s string) error
@@ -99,6 +112,12 @@ type CredsProviderMock struct {
 	// GetTokenFunc mocks the GetToken method.
 	GetTokenFunc func() (string, error)
 
+	// ListProjectTokensFunc mocks the ListProjectTokens method.
+	ListProjectTokensFunc func(s string) ([]string, error)
+
+	// ListProjectsFunc mocks the ListProjects method.
+	ListProjectsFunc func() ([]string, error)
+
 	// ListTargetsFunc mocks the ListTargets method.
 	ListTargetsFunc func(s string) ([]string, error)
 
@@ -108,15 +127,23 @@ type CredsProviderMock struct {
 	// TargetExistsFunc mocks the TargetExists method.
 	TargetExistsFunc func(s1 string, s2 string) (bool, error)
 
+	// UpdateProjectPolicyFunc mocks the UpdateProjectPolicy method.
+	UpdateProjectPolicyFunc func(s string, projectPolicy types.ProjectPolicy, tokenScopess []types.TokenScopes) error
+
 	// UpdateTargetFunc mocks the UpdateTarget method.
 	UpdateTargetFunc func(s string, target types.Target) error
 
+	// VerifyProjectTokenFunc mocks the VerifyProjectToken method.
+	VerifyProjectTokenFunc func(s1 string, s2 string, s3 string) error
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// CreateProject holds details about calls to the CreateProject method.
 		CreateProject []struct {
 			// S is the s argument value.
 			S string
+			// Duration is the duration argument value.
+			Duration time.Duration
 		}
 		// CreateTarget holds details about calls to the CreateTarget method.
 		CreateTarget []struct {
@@ -129,6 +156,12 @@ type CredsProviderMock struct {
 		CreateToken []struct {
 			// S is the s argument value.
 			S string
+			// TokenScopes is the tokenScopes argument value.
+			TokenScopes types.TokenScopes
+			// ProjectPolicy is the projectPolicy argument value.
+			ProjectPolicy types.ProjectPolicy
+			// Duration is the duration argument value.
+			Duration time.Duration
 		}
 		// DeleteProject holds details about calls to the DeleteProject method.
 		DeleteProject []struct {
@@ -171,6 +204,14 @@ type CredsProviderMock struct {
 		// GetToken holds details about calls to the GetToken method.
 		GetToken []struct {
 		}
+		// ListProjectTokens holds details about calls to the ListProjectTokens method.
+		ListProjectTokens []struct {
+			// S is the s argument value.
+			S string
+		}
+		// ListProjects holds details about calls to the ListProjects method.
+		ListProjects []struct {
+		}
 		// ListTargets holds details about calls to the ListTargets method.
 		ListTargets []struct {
 			// S is the s argument value.
@@ -188,6 +229,15 @@ type CredsProviderMock struct {
 			// S2 is the s2 argument value.
 			S2 string
 		}
+		// UpdateProjectPolicy holds details about calls to the UpdateProjectPolicy method.
+		UpdateProjectPolicy []struct {
+			// S is the s argument value.
+			S string
+			// ProjectPolicy is the projectPolicy argument value.
+			ProjectPolicy types.ProjectPolicy
+			// TokenScopess is the tokenScopess argument value.
+			TokenScopess []types.TokenScopes
+		}
 		// UpdateTarget holds details about calls to the UpdateTarget method.
 		UpdateTarget []struct {
 			// S is the s argument value.
@@ -195,37 +245,52 @@ type CredsProviderMock struct {
 			// Target is the target argument value.
 			Target types.Target
 		}
+		// VerifyProjectToken holds details about calls to the VerifyProjectToken method.
+		VerifyProjectToken []struct {
+			// S1 is the s1 argument value.
+			S1 string
+			// S2 is the s2 argument value.
+			S2 string
+			// S3 is the s3 argument value.
+			S3 string
+		}
 	}
-	lockCreateProject      sync.RWMutex
-	lockCreateTarget       sync.RWMutex
-	lockCreateToken        sync.RWMutex
-	lockDeleteProject      sync.RWMutex
-	lockDeleteProjectToken sync.RWMutex
-	lockDeleteTarget       sync.RWMutex
-	lockGetProject         sync.RWMutex
-	lockGetProjectToken    sync.RWMutex
-	lockGetTarget          sync.RWMutex
-	lockGetToken           sync.RWMutex
-	lockListTargets        sync.RWMutex
-	lockProjectExists      sync.RWMutex
-	lockTargetExists       sync.RWMutex
-	lockUpdateTarget       sync.RWMutex
+	lockCreateProject       sync.RWMutex
+	lockCreateTarget        sync.RWMutex
+	lockCreateToken         sync.RWMutex
+	lockDeleteProject       sync.RWMutex
+	lockDeleteProjectToken  sync.RWMutex
+	lockDeleteTarget        sync.RWMutex
+	lockGetProject          sync.RWMutex
+	lockGetProjectToken     sync.RWMutex
+	lockGetTarget           sync.RWMutex
+	lockGetToken            sync.RWMutex
+	lockListProjectTokens   sync.RWMutex
+	lockListProjects        sync.RWMutex
+	lockListTargets         sync.RWMutex
+	lockProjectExists       sync.RWMutex
+	lockTargetExists        sync.RWMutex
+	lockUpdateProjectPolicy sync.RWMutex
+	lockUpdateTarget        sync.RWMutex
+	lockVerifyProjectToken  sync.RWMutex
 }
 
 // CreateProject calls CreateProjectFunc.
-func (mock *CredsProviderMock) CreateProject(s string) (types.Token, error) {
+func (mock *CredsProviderMock) CreateProject(s string, duration time.Duration) (types.Token, error) {
 	if mock.CreateProjectFunc == nil {
 		panic("CredsProviderMock.CreateProjectFunc: method is nil but Provider.CreateProject was just called")
 	}
 	callInfo := struct {
-		S string
+		S        string
+		Duration time.Duration
 	}{
-		S: s,
+		S:        s,
+		Duration: duration,
 	}
 	mock.lockCreateProject.Lock()
 	mock.calls.CreateProject = append(mock.calls.CreateProject, callInfo)
 	mock.lockCreateProject.Unlock()
-	return mock.CreateProjectFunc(s)
+	return mock.CreateProjectFunc(s, duration)
 }
 
 // CreateProjectCalls gets all the calls that were made to CreateProject.
@@ -233,10 +298,12 @@ func (mock *CredsProviderMock) CreateProject(s string) (types.Token, error) {
 //
 //	len(mockedProvider.CreateProjectCalls())
 func (mock *CredsProviderMock) CreateProjectCalls() []struct {
-	S string
+	S        string
+	Duration time.Duration
 } {
 	var calls []struct {
-		S string
+		S        string
+		Duration time.Duration
 	}
 	mock.lockCreateProject.RLock()
 	calls = mock.calls.CreateProject
@@ -281,19 +348,25 @@ func (mock *CredsProviderMock) CreateTargetCalls() []struct {
 }
 
 // CreateToken calls CreateTokenFunc.
-func (mock *CredsProviderMock) CreateToken(s string) (types.Token, error) {
+func (mock *CredsProviderMock) CreateToken(s string, tokenScopes types.TokenScopes, projectPolicy types.ProjectPolicy, duration time.Duration) (types.Token, error) {
 	if mock.CreateTokenFunc == nil {
 		panic("CredsProviderMock.CreateTokenFunc: method is nil but Provider.CreateToken was just called")
 	}
 	callInfo := struct {
-		S string
+		S             string
+		TokenScopes   types.TokenScopes
+		ProjectPolicy types.ProjectPolicy
+		Duration      time.Duration
 	}{
-		S: s,
+		S:             s,
+		TokenScopes:   tokenScopes,
+		ProjectPolicy: projectPolicy,
+		Duration:      duration,
 	}
 	mock.lockCreateToken.Lock()
 	mock.calls.CreateToken = append(mock.calls.CreateToken, callInfo)
 	mock.lockCreateToken.Unlock()
-	return mock.CreateTokenFunc(s)
+	return mock.CreateTokenFunc(s, tokenScopes, projectPolicy, duration)
 }
 
 // CreateTokenCalls gets all the calls that were made to CreateToken.
@@ -301,10 +374,16 @@ func (mock *CredsProviderMock) CreateToken(s string) (types.Token, error) {
 //
 //	len(mockedProvider.CreateTokenCalls())
 func (mock *CredsProviderMock) CreateTokenCalls() []struct {
-	S string
+	S             string
+	TokenScopes   types.TokenScopes
+	ProjectPolicy types.ProjectPolicy
+	Duration      time.Duration
 } {
 	var calls []struct {
-		S string
+		S             string
+		TokenScopes   types.TokenScopes
+		ProjectPolicy types.ProjectPolicy
+		Duration      time.Duration
 	}
 	mock.lockCreateToken.RLock()
 	calls = mock.calls.CreateToken
@@ -547,6 +626,65 @@ func (mock *CredsProviderMock) GetTokenCalls() []struct {
 	return calls
 }
 
+// ListProjectTokens calls ListProjectTokensFunc.
+func (mock *CredsProviderMock) ListProjectTokens(s string) ([]string, error) {
+	if mock.ListProjectTokensFunc == nil {
+		panic("CredsProviderMock.ListProjectTokensFunc: method is nil but Provider.ListProjectTokens was just called")
+	}
+	callInfo := struct {
+		S string
+	}{
+		S: s,
+	}
+	mock.lockListProjectTokens.Lock()
+	mock.calls.ListProjectTokens = append(mock.calls.ListProjectTokens, callInfo)
+	mock.lockListProjectTokens.Unlock()
+	return mock.ListProjectTokensFunc(s)
+}
+
+// ListProjectTokensCalls gets all the calls that were made to ListProjectTokens.
+// Check the length with:
+//
+//	len(mockedProvider.ListProjectTokensCalls())
+func (mock *CredsProviderMock) ListProjectTokensCalls() []struct {
+	S string
+} {
+	var calls []struct {
+		S string
+	}
+	mock.lockListProjectTokens.RLock()
+	calls = mock.calls.ListProjectTokens
+	mock.lockListProjectTokens.RUnlock()
+	return calls
+}
+
+// ListProjects calls ListProjectsFunc.
+func (mock *CredsProviderMock) ListProjects() ([]string, error) {
+	if mock.ListProjectsFunc == nil {
+		panic("CredsProviderMock.ListProjectsFunc: method is nil but Provider.ListProjects was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockListProjects.Lock()
+	mock.calls.ListProjects = append(mock.calls.ListProjects, callInfo)
+	mock.lockListProjects.Unlock()
+	return mock.ListProjectsFunc()
+}
+
+// ListProjectsCalls gets all the calls that were made to ListProjects.
+// Check the length with:
+//
+//	len(mockedProvider.ListProjectsCalls())
+func (mock *CredsProviderMock) ListProjectsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockListProjects.RLock()
+	calls = mock.calls.ListProjects
+	mock.lockListProjects.RUnlock()
+	return calls
+}
+
 // ListTargets calls ListTargetsFunc.
 func (mock *CredsProviderMock) ListTargets(s string) ([]string, error) {
 	if mock.ListTargetsFunc == nil {
@@ -647,6 +785,46 @@ func (mock *CredsProviderMock) TargetExistsCalls() []struct {
 	return calls
 }
 
+// UpdateProjectPolicy calls UpdateProjectPolicyFunc.
+func (mock *CredsProviderMock) UpdateProjectPolicy(s string, projectPolicy types.ProjectPolicy, tokenScopess []types.TokenScopes) error {
+	if mock.UpdateProjectPolicyFunc == nil {
+		panic("CredsProviderMock.UpdateProjectPolicyFunc: method is nil but Provider.UpdateProjectPolicy was just called")
+	}
+	callInfo := struct {
+		S             string
+		ProjectPolicy types.ProjectPolicy
+		TokenScopess  []types.TokenScopes
+	}{
+		S:             s,
+		ProjectPolicy: projectPolicy,
+		TokenScopess:  tokenScopess,
+	}
+	mock.lockUpdateProjectPolicy.Lock()
+	mock.calls.UpdateProjectPolicy = append(mock.calls.UpdateProjectPolicy, callInfo)
+	mock.lockUpdateProjectPolicy.Unlock()
+	return mock.UpdateProjectPolicyFunc(s, projectPolicy, tokenScopess)
+}
+
+// UpdateProjectPolicyCalls gets all the calls that were made to UpdateProjectPolicy.
+// Check the length with:
+//
+//	len(mockedProvider.UpdateProjectPolicyCalls())
+func (mock *CredsProviderMock) UpdateProjectPolicyCalls() []struct {
+	S             string
+	ProjectPolicy types.ProjectPolicy
+	TokenScopess  []types.TokenScopes
+} {
+	var calls []struct {
+		S             string
+		ProjectPolicy types.ProjectPolicy
+		TokenScopess  []types.TokenScopes
+	}
+	mock.lockUpdateProjectPolicy.RLock()
+	calls = mock.calls.UpdateProjectPolicy
+	mock.lockUpdateProjectPolicy.RUnlock()
+	return calls
+}
+
 // UpdateTarget calls UpdateTargetFunc.
 func (mock *CredsProviderMock) UpdateTarget(s string, target types.Target) error {
 	if mock.UpdateTargetFunc == nil {
@@ -682,3 +860,43 @@ func (mock *CredsProviderMock) UpdateTargetCalls() []struct {
 	mock.lockUpdateTarget.RUnlock()
 	return calls
 }
+
+// VerifyProjectToken calls VerifyProjectTokenFunc.
+func (mock *CredsProviderMock) VerifyProjectToken(s1 string, s2 string, s3 string) error {
+	if mock.VerifyProjectTokenFunc == nil {
+		panic("CredsProviderMock.VerifyProjectTokenFunc: method is nil but Provider.VerifyProjectToken was just called")
+	}
+	callInfo := struct {
+		S1 string
+		S2 string
+		S3 string
+	}{
+		S1: s1,
+		S2: s2,
+		S3: s3,
+	}
+	mock.lockVerifyProjectToken.Lock()
+	mock.calls.VerifyProjectToken = append(mock.calls.VerifyProjectToken, callInfo)
+	mock.lockVerifyProjectToken.Unlock()
+	return mock.VerifyProjectTokenFunc(s1, s2, s3)
+}
+
+// VerifyProjectTokenCalls gets all the calls that were made to VerifyProjectToken.
+// Check the length with:
+//
+//	len(mockedProvider.VerifyProjectTokenCalls())
+func (mock *CredsProviderMock) VerifyProjectTokenCalls() []struct {
+	S1 string
+	S2 string
+	S3 string
+} {
+	var calls []struct {
+		S1 string
+		S2 string
+		S3 string
+	}
+	mock.lockVerifyProjectToken.RLock()
+	calls = mock.calls.VerifyProjectToken
+	mock.lockVerifyProjectToken.RUnlock()
+	return calls
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/cello-proj/cello/internal/types"
 	"github.com/cello-proj/cello/service/internal/db"
 	"sync"
+	"time"
 )
 
 // Ensure, that DBClientMock does implement db.Client.
@@ -20,12 +21,36 @@ var _ db.Client = &DBClientMock{}
 //
 //		// make and configure a mocked db.Client
 //		mockedClient := &DBClientMock{
+//			AcquireLeaseFunc: func(ctx context.Context, name string, owner string, d time.Duration) (bool, error) {
+//				panic("mock out the AcquireLease method")
+//			},
+//			ClaimSubmissionFunc: func(ctx context.Context, id string, d time.Duration) (bool, error) {
+//				panic("mock out the ClaimSubmission method")
+//			},
+//			CreateIdempotencyEntryFunc: func(ctx context.Context, ie db.IdempotencyEntry) error {
+//				panic("mock out the CreateIdempotencyEntry method")
+//			},
+//			CreateIdentityEntryFunc: func(ctx context.Context, ie db.IdentityEntry) error {
+//				panic("mock out the CreateIdentityEntry method")
+//			},
 //			CreateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error {
 //				panic("mock out the CreateProjectEntry method")
 //			},
+//			CreateSubmissionEntryFunc: func(ctx context.Context, se db.SubmissionEntry) error {
+//				panic("mock out the CreateSubmissionEntry method")
+//			},
 //			CreateTokenEntryFunc: func(ctx context.Context, token types.Token) error {
 //				panic("mock out the CreateTokenEntry method")
 //			},
+//			CreateWebhookEntryFunc: func(ctx context.Context, we db.WebhookEntry) error {
+//				panic("mock out the CreateWebhookEntry method")
+//			},
+//			DeleteIdempotencyEntryFunc: func(ctx context.Context, principal string, key string) error {
+//				panic("mock out the DeleteIdempotencyEntry method")
+//			},
+//			DeleteIdentityEntryFunc: func(ctx context.Context, name string) error {
+//				panic("mock out the DeleteIdentityEntry method")
+//			},
 //			DeleteProjectEntryFunc: func(ctx context.Context, project string) error {
 //				panic("mock out the DeleteProjectEntry method")
 //			},
@@ -35,21 +60,66 @@ var _ db.Client = &DBClientMock{}
 //			DeleteTokenEntryByProjectFunc: func(ctx context.Context, project string, token string) error {
 //				panic("mock out the DeleteTokenEntryByProject method")
 //			},
+//			DeleteWebhookEntryFunc: func(ctx context.Context, project string, id string) error {
+//				panic("mock out the DeleteWebhookEntry method")
+//			},
 //			HealthFunc: func(ctx context.Context) error {
 //				panic("mock out the Health method")
 //			},
+//			ListIdentityEntriesFunc: func(ctx context.Context) ([]db.IdentityEntry, error) {
+//				panic("mock out the ListIdentityEntries method")
+//			},
+//			ListProjectEntriesFunc: func(ctx context.Context) ([]db.ProjectEntry, error) {
+//				panic("mock out the ListProjectEntries method")
+//			},
+//			ListQueuedSubmissionsFunc: func(ctx context.Context, now time.Time) ([]string, error) {
+//				panic("mock out the ListQueuedSubmissions method")
+//			},
 //			ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
 //				panic("mock out the ListTokenEntries method")
 //			},
+//			ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+//				panic("mock out the ListWebhookEntries method")
+//			},
+//			ReadIdempotencyEntryFunc: func(ctx context.Context, principal string, key string) (db.IdempotencyEntry, error) {
+//				panic("mock out the ReadIdempotencyEntry method")
+//			},
+//			ReadIdentityEntryFunc: func(ctx context.Context, name string) (db.IdentityEntry, error) {
+//				panic("mock out the ReadIdentityEntry method")
+//			},
 //			ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
 //				panic("mock out the ReadProjectEntry method")
 //			},
+//			ReadSubmissionEntryFunc: func(ctx context.Context, id string) (db.SubmissionEntry, error) {
+//				panic("mock out the ReadSubmissionEntry method")
+//			},
 //			ReadTokenEntryFunc: func(ctx context.Context, token string) (db.TokenEntry, error) {
 //				panic("mock out the ReadTokenEntry method")
 //			},
 //			ReadTokenEntryByProjectFunc: func(ctx context.Context, project string, token string) (db.TokenEntry, error) {
 //				panic("mock out the ReadTokenEntryByProject method")
 //			},
+//			RecordWebhookEventFunc: func(ctx context.Context, workflowName string, event string, expiresAt time.Time) (bool, error) {
+//				panic("mock out the RecordWebhookEvent method")
+//			},
+//			UpdateIdempotencyEntryFunc: func(ctx context.Context, ie db.IdempotencyEntry) error {
+//				panic("mock out the UpdateIdempotencyEntry method")
+//			},
+//			UpdateIdentityEntryFunc: func(ctx context.Context, ie db.IdentityEntry) error {
+//				panic("mock out the UpdateIdentityEntry method")
+//			},
+//			UpdateProjectEntryFunc: func(ctx context.Context, pe db.ProjectEntry) error {
+//				panic("mock out the UpdateProjectEntry method")
+//			},
+//			UpdateProjectPolicyFunc: func(ctx context.Context, project string, policy types.ProjectPolicy) error {
+//				panic("mock out the UpdateProjectPolicy method")
+//			},
+//			UpdateSubmissionEntryFunc: func(ctx context.Context, se db.SubmissionEntry) error {
+//				panic("mock out the UpdateSubmissionEntry method")
+//			},
+//			UpdateTokenEntryFunc: func(ctx context.Context, te db.TokenEntry) error {
+//				panic("mock out the UpdateTokenEntry method")
+//			},
 //		}
 //
 //		// use mockedClient in code that requires db.Client
@@ -57,12 +127,36 @@ var _ db.Client = &DBClientMock{}
 //
 //	}
 type DBClientMock struct {
+	// AcquireLeaseFunc mocks the AcquireLease method.
+	AcquireLeaseFunc func(ctx context.Context, name string, owner string, d time.Duration) (bool, error)
+
+	// ClaimSubmissionFunc mocks the ClaimSubmission method.
+	ClaimSubmissionFunc func(ctx context.Context, id string, d time.Duration) (bool, error)
+
+	// CreateIdempotencyEntryFunc mocks the CreateIdempotencyEntry method.
+	CreateIdempotencyEntryFunc func(ctx context.Context, ie db.IdempotencyEntry) error
+
+	// CreateIdentityEntryFunc mocks the CreateIdentityEntry method.
+	CreateIdentityEntryFunc func(ctx context.Context, ie db.IdentityEntry) error
+
 	// CreateProjectEntryFunc mocks the CreateProjectEntry method.
 	CreateProjectEntryFunc func(ctx context.Context, pe db.ProjectEntry) error
 
+	// CreateSubmissionEntryFunc mocks the CreateSubmissionEntry method.
+	CreateSubmissionEntryFunc func(ctx context.Context, se db.SubmissionEntry) error
+
 	// CreateTokenEntryFunc mocks the CreateTokenEntry method.
 	CreateTokenEntryFunc func(ctx context.Context, token types.Token) error
 
+	// CreateWebhookEntryFunc mocks the CreateWebhookEntry method.
+	CreateWebhookEntryFunc func(ctx context.Context, we db.WebhookEntry) error
+
+	// DeleteIdempotencyEntryFunc mocks the DeleteIdempotencyEntry method.
+	DeleteIdempotencyEntryFunc func(ctx context.Context, principal string, key string) error
+
+	// DeleteIdentityEntryFunc mocks the DeleteIdentityEntry method.
+	DeleteIdentityEntryFunc func(ctx context.Context, name string) error
+
 	// DeleteProjectEntryFunc mocks the DeleteProjectEntry method.
 	DeleteProjectEntryFunc func(ctx context.Context, project string) error
 
@@ -72,23 +166,102 @@ type DBClientMock struct {
 	// DeleteTokenEntryByProjectFunc mocks the DeleteTokenEntryByProject method.
 	DeleteTokenEntryByProjectFunc func(ctx context.Context, project string, token string) error
 
+	// DeleteWebhookEntryFunc mocks the DeleteWebhookEntry method.
+	DeleteWebhookEntryFunc func(ctx context.Context, project string, id string) error
+
 	// HealthFunc mocks the Health method.
 	HealthFunc func(ctx context.Context) error
 
+	// ListIdentityEntriesFunc mocks the ListIdentityEntries method.
+	ListIdentityEntriesFunc func(ctx context.Context) ([]db.IdentityEntry, error)
+
+	// ListProjectEntriesFunc mocks the ListProjectEntries method.
+	ListProjectEntriesFunc func(ctx context.Context) ([]db.ProjectEntry, error)
+
+	// ListQueuedSubmissionsFunc mocks the ListQueuedSubmissions method.
+	ListQueuedSubmissionsFunc func(ctx context.Context, now time.Time) ([]string, error)
+
 	// ListTokenEntriesFunc mocks the ListTokenEntries method.
 	ListTokenEntriesFunc func(ctx context.Context, project string) ([]db.TokenEntry, error)
 
+	// ListWebhookEntriesFunc mocks the ListWebhookEntries method.
+	ListWebhookEntriesFunc func(ctx context.Context, project string) ([]db.WebhookEntry, error)
+
+	// ReadIdempotencyEntryFunc mocks the ReadIdempotencyEntry method.
+	ReadIdempotencyEntryFunc func(ctx context.Context, principal string, key string) (db.IdempotencyEntry, error)
+
+	// ReadIdentityEntryFunc mocks the ReadIdentityEntry method.
+	ReadIdentityEntryFunc func(ctx context.Context, name string) (db.IdentityEntry, error)
+
 	// ReadProjectEntryFunc mocks the ReadProjectEntry method.
 	ReadProjectEntryFunc func(ctx context.Context, project string) (db.ProjectEntry, error)
 
+	// ReadSubmissionEntryFunc mocks the ReadSubmissionEntry method.
+	ReadSubmissionEntryFunc func(ctx context.Context, id string) (db.SubmissionEntry, error)
+
 	// ReadTokenEntryFunc mocks the ReadTokenEntry method.
 	ReadTokenEntryFunc func(ctx context.Context, token string) (db.TokenEntry, error)
 
 	// ReadTokenEntryByProjectFunc mocks the ReadTokenEntryByProject method.
 	ReadTokenEntryByProjectFunc func(ctx context.Context, project string, token string) (db.TokenEntry, error)
 
+	// RecordWebhookEventFunc mocks the RecordWebhookEvent method.
+	RecordWebhookEventFunc func(ctx context.Context, workflowName string, event string, expiresAt time.Time) (bool, error)
+
+	// UpdateIdempotencyEntryFunc mocks the UpdateIdempotencyEntry method.
+	UpdateIdempotencyEntryFunc func(ctx context.Context, ie db.IdempotencyEntry) error
+
+	// UpdateIdentityEntryFunc mocks the UpdateIdentityEntry method.
+	UpdateIdentityEntryFunc func(ctx context.Context, ie db.IdentityEntry) error
+
+	// UpdateProjectEntryFunc mocks the UpdateProjectEntry method.
+	UpdateProjectEntryFunc func(ctx context.Context, pe db.ProjectEntry) error
+
+	// UpdateProjectPolicyFunc mocks the UpdateProjectPolicy method.
+	UpdateProjectPolicyFunc func(ctx context.Context, project string, policy types.ProjectPolicy) error
+
+	// UpdateSubmissionEntryFunc mocks the UpdateSubmissionEntry method.
+	UpdateSubmissionEntryFunc func(ctx context.Context, se db.SubmissionEntry) error
+
+	// UpdateTokenEntryFunc mocks the UpdateTokenEntry method.
+	UpdateTokenEntryFunc func(ctx context.Context, te db.TokenEntry) error
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// AcquireLease holds details about calls to the AcquireLease method.
+		AcquireLease []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+			// Owner is the owner argument value.
+			Owner string
+			// D is the d argument value.
+			D time.Duration
+		}
+		// ClaimSubmission holds details about calls to the ClaimSubmission method.
+		ClaimSubmission []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+			// D is the d argument value.
+			D time.Duration
+		}
+		// CreateIdempotencyEntry holds details about calls to the CreateIdempotencyEntry method.
+		CreateIdempotencyEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ie is the ie argument value.
+			Ie db.IdempotencyEntry
+		}
+		// CreateIdentityEntry holds details about calls to the CreateIdentityEntry method.
+		CreateIdentityEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ie is the ie argument value.
+			Ie db.IdentityEntry
+		}
 		// CreateProjectEntry holds details about calls to the CreateProjectEntry method.
 		CreateProjectEntry []struct {
 			// Ctx is the ctx argument value.
@@ -96,6 +269,13 @@ type DBClientMock struct {
 			// Pe is the pe argument value.
 			Pe db.ProjectEntry
 		}
+		// CreateSubmissionEntry holds details about calls to the CreateSubmissionEntry method.
+		CreateSubmissionEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Se is the se argument value.
+			Se db.SubmissionEntry
+		}
 		// CreateTokenEntry holds details about calls to the CreateTokenEntry method.
 		CreateTokenEntry []struct {
 			// Ctx is the ctx argument value.
@@ -103,6 +283,29 @@ type DBClientMock struct {
 			// Token is the token argument value.
 			Token types.Token
 		}
+		// CreateWebhookEntry holds details about calls to the CreateWebhookEntry method.
+		CreateWebhookEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// We is the we argument value.
+			We db.WebhookEntry
+		}
+		// DeleteIdempotencyEntry holds details about calls to the DeleteIdempotencyEntry method.
+		DeleteIdempotencyEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Principal is the principal argument value.
+			Principal string
+			// Key is the key argument value.
+			Key string
+		}
+		// DeleteIdentityEntry holds details about calls to the DeleteIdentityEntry method.
+		DeleteIdentityEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
 		// DeleteProjectEntry holds details about calls to the DeleteProjectEntry method.
 		DeleteProjectEntry []struct {
 			// Ctx is the ctx argument value.
@@ -126,11 +329,37 @@ type DBClientMock struct {
 			// Token is the token argument value.
 			Token string
 		}
+		// DeleteWebhookEntry holds details about calls to the DeleteWebhookEntry method.
+		DeleteWebhookEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Project is the project argument value.
+			Project string
+			// Id is the id argument value.
+			Id string
+		}
 		// Health holds details about calls to the Health method.
 		Health []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
+		// ListIdentityEntries holds details about calls to the ListIdentityEntries method.
+		ListIdentityEntries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ListProjectEntries holds details about calls to the ListProjectEntries method.
+		ListProjectEntries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ListQueuedSubmissions holds details about calls to the ListQueuedSubmissions method.
+		ListQueuedSubmissions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Now is the now argument value.
+			Now time.Time
+		}
 		// ListTokenEntries holds details about calls to the ListTokenEntries method.
 		ListTokenEntries []struct {
 			// Ctx is the ctx argument value.
@@ -138,6 +367,29 @@ type DBClientMock struct {
 			// Project is the project argument value.
 			Project string
 		}
+		// ListWebhookEntries holds details about calls to the ListWebhookEntries method.
+		ListWebhookEntries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Project is the project argument value.
+			Project string
+		}
+		// ReadIdempotencyEntry holds details about calls to the ReadIdempotencyEntry method.
+		ReadIdempotencyEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Principal is the principal argument value.
+			Principal string
+			// Key is the key argument value.
+			Key string
+		}
+		// ReadIdentityEntry holds details about calls to the ReadIdentityEntry method.
+		ReadIdentityEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
 		// ReadProjectEntry holds details about calls to the ReadProjectEntry method.
 		ReadProjectEntry []struct {
 			// Ctx is the ctx argument value.
@@ -145,6 +397,13 @@ type DBClientMock struct {
 			// Project is the project argument value.
 			Project string
 		}
+		// ReadSubmissionEntry holds details about calls to the ReadSubmissionEntry method.
+		ReadSubmissionEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+		}
 		// ReadTokenEntry holds details about calls to the ReadTokenEntry method.
 		ReadTokenEntry []struct {
 			// Ctx is the ctx argument value.
@@ -161,17 +420,251 @@ type DBClientMock struct {
 			// Token is the token argument value.
 			Token string
 		}
+		// RecordWebhookEvent holds details about calls to the RecordWebhookEvent method.
+		RecordWebhookEvent []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// WorkflowName is the workflowName argument value.
+			WorkflowName string
+			// Event is the event argument value.
+			Event string
+			// ExpiresAt is the expiresAt argument value.
+			ExpiresAt time.Time
+		}
+		// UpdateIdempotencyEntry holds details about calls to the UpdateIdempotencyEntry method.
+		UpdateIdempotencyEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ie is the ie argument value.
+			Ie db.IdempotencyEntry
+		}
+		// UpdateIdentityEntry holds details about calls to the UpdateIdentityEntry method.
+		UpdateIdentityEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ie is the ie argument value.
+			Ie db.IdentityEntry
+		}
+		// UpdateProjectEntry holds details about calls to the UpdateProjectEntry method.
+		UpdateProjectEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Pe is the pe argument value.
+			Pe db.ProjectEntry
+		}
+		// UpdateProjectPolicy holds details about calls to the UpdateProjectPolicy method.
+		UpdateProjectPolicy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Project is the project argument value.
+			Project string
+			// Policy is the policy argument value.
+			Policy types.ProjectPolicy
+		}
+		// UpdateSubmissionEntry holds details about calls to the UpdateSubmissionEntry method.
+		UpdateSubmissionEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Se is the se argument value.
+			Se db.SubmissionEntry
+		}
+		// UpdateTokenEntry holds details about calls to the UpdateTokenEntry method.
+		UpdateTokenEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Te is the te argument value.
+			Te db.TokenEntry
+		}
 	}
+	lockAcquireLease              sync.RWMutex
+	lockClaimSubmission           sync.RWMutex
+	lockCreateIdempotencyEntry    sync.RWMutex
+	lockCreateIdentityEntry       sync.RWMutex
 	lockCreateProjectEntry        sync.RWMutex
+	lockCreateSubmissionEntry     sync.RWMutex
 	lockCreateTokenEntry          sync.RWMutex
+	lockCreateWebhookEntry        sync.RWMutex
+	lockDeleteIdempotencyEntry    sync.RWMutex
+	lockDeleteIdentityEntry       sync.RWMutex
 	lockDeleteProjectEntry        sync.RWMutex
 	lockDeleteTokenEntry          sync.RWMutex
 	lockDeleteTokenEntryByProject sync.RWMutex
+	lockDeleteWebhookEntry        sync.RWMutex
 	lockHealth                    sync.RWMutex
+	lockListIdentityEntries       sync.RWMutex
+	lockListProjectEntries        sync.RWMutex
+	lockListQueuedSubmissions     sync.RWMutex
 	lockListTokenEntries          sync.RWMutex
+	lockListWebhookEntries        sync.RWMutex
+	lockReadIdempotencyEntry      sync.RWMutex
+	lockReadIdentityEntry         sync.RWMutex
 	lockReadProjectEntry          sync.RWMutex
+	lockReadSubmissionEntry       sync.RWMutex
 	lockReadTokenEntry            sync.RWMutex
 	lockReadTokenEntryByProject   sync.RWMutex
+	lockRecordWebhookEvent        sync.RWMutex
+	lockUpdateIdempotencyEntry    sync.RWMutex
+	lockUpdateIdentityEntry       sync.RWMutex
+	lockUpdateProjectEntry        sync.RWMutex
+	lockUpdateProjectPolicy       sync.RWMutex
+	lockUpdateSubmissionEntry     sync.RWMutex
+	lockUpdateTokenEntry          sync.RWMutex
+}
+
+// AcquireLease calls AcquireLeaseFunc.
+func (mock *DBClientMock) AcquireLease(ctx context.Context, name string, owner string, d time.Duration) (bool, error) {
+	if mock.AcquireLeaseFunc == nil {
+		panic("DBClientMock.AcquireLeaseFunc: method is nil but Client.AcquireLease was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Name  string
+		Owner string
+		D     time.Duration
+	}{
+		Ctx:   ctx,
+		Name:  name,
+		Owner: owner,
+		D:     d,
+	}
+	mock.lockAcquireLease.Lock()
+	mock.calls.AcquireLease = append(mock.calls.AcquireLease, callInfo)
+	mock.lockAcquireLease.Unlock()
+	return mock.AcquireLeaseFunc(ctx, name, owner, d)
+}
+
+// AcquireLeaseCalls gets all the calls that were made to AcquireLease.
+// Check the length with:
+//
+//	len(mockedClient.AcquireLeaseCalls())
+func (mock *DBClientMock) AcquireLeaseCalls() []struct {
+	Ctx   context.Context
+	Name  string
+	Owner string
+	D     time.Duration
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Name  string
+		Owner string
+		D     time.Duration
+	}
+	mock.lockAcquireLease.RLock()
+	calls = mock.calls.AcquireLease
+	mock.lockAcquireLease.RUnlock()
+	return calls
+}
+
+// ClaimSubmission calls ClaimSubmissionFunc.
+func (mock *DBClientMock) ClaimSubmission(ctx context.Context, id string, d time.Duration) (bool, error) {
+	if mock.ClaimSubmissionFunc == nil {
+		panic("DBClientMock.ClaimSubmissionFunc: method is nil but Client.ClaimSubmission was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+		D   time.Duration
+	}{
+		Ctx: ctx,
+		Id:  id,
+		D:   d,
+	}
+	mock.lockClaimSubmission.Lock()
+	mock.calls.ClaimSubmission = append(mock.calls.ClaimSubmission, callInfo)
+	mock.lockClaimSubmission.Unlock()
+	return mock.ClaimSubmissionFunc(ctx, id, d)
+}
+
+// ClaimSubmissionCalls gets all the calls that were made to ClaimSubmission.
+// Check the length with:
+//
+//	len(mockedClient.ClaimSubmissionCalls())
+func (mock *DBClientMock) ClaimSubmissionCalls() []struct {
+	Ctx context.Context
+	Id  string
+	D   time.Duration
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+		D   time.Duration
+	}
+	mock.lockClaimSubmission.RLock()
+	calls = mock.calls.ClaimSubmission
+	mock.lockClaimSubmission.RUnlock()
+	return calls
+}
+
+// CreateIdempotencyEntry calls CreateIdempotencyEntryFunc.
+func (mock *DBClientMock) CreateIdempotencyEntry(ctx context.Context, ie db.IdempotencyEntry) error {
+	if mock.CreateIdempotencyEntryFunc == nil {
+		panic("DBClientMock.CreateIdempotencyEntryFunc: method is nil but Client.CreateIdempotencyEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Ie  db.IdempotencyEntry
+	}{
+		Ctx: ctx,
+		Ie:  ie,
+	}
+	mock.lockCreateIdempotencyEntry.Lock()
+	mock.calls.CreateIdempotencyEntry = append(mock.calls.CreateIdempotencyEntry, callInfo)
+	mock.lockCreateIdempotencyEntry.Unlock()
+	return mock.CreateIdempotencyEntryFunc(ctx, ie)
+}
+
+// CreateIdempotencyEntryCalls gets all the calls that were made to CreateIdempotencyEntry.
+// Check the length with:
+//
+//	len(mockedClient.CreateIdempotencyEntryCalls())
+func (mock *DBClientMock) CreateIdempotencyEntryCalls() []struct {
+	Ctx context.Context
+	Ie  db.IdempotencyEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ie  db.IdempotencyEntry
+	}
+	mock.lockCreateIdempotencyEntry.RLock()
+	calls = mock.calls.CreateIdempotencyEntry
+	mock.lockCreateIdempotencyEntry.RUnlock()
+	return calls
+}
+
+// CreateIdentityEntry calls CreateIdentityEntryFunc.
+func (mock *DBClientMock) CreateIdentityEntry(ctx context.Context, ie db.IdentityEntry) error {
+	if mock.CreateIdentityEntryFunc == nil {
+		panic("DBClientMock.CreateIdentityEntryFunc: method is nil but Client.CreateIdentityEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Ie  db.IdentityEntry
+	}{
+		Ctx: ctx,
+		Ie:  ie,
+	}
+	mock.lockCreateIdentityEntry.Lock()
+	mock.calls.CreateIdentityEntry = append(mock.calls.CreateIdentityEntry, callInfo)
+	mock.lockCreateIdentityEntry.Unlock()
+	return mock.CreateIdentityEntryFunc(ctx, ie)
+}
+
+// CreateIdentityEntryCalls gets all the calls that were made to CreateIdentityEntry.
+// Check the length with:
+//
+//	len(mockedClient.CreateIdentityEntryCalls())
+func (mock *DBClientMock) CreateIdentityEntryCalls() []struct {
+	Ctx context.Context
+	Ie  db.IdentityEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ie  db.IdentityEntry
+	}
+	mock.lockCreateIdentityEntry.RLock()
+	calls = mock.calls.CreateIdentityEntry
+	mock.lockCreateIdentityEntry.RUnlock()
+	return calls
 }
 
 // CreateProjectEntry calls CreateProjectEntryFunc.
@@ -210,6 +703,42 @@ func (mock *DBClientMock) CreateProjectEntryCalls() []struct {
 	return calls
 }
 
+// CreateSubmissionEntry calls CreateSubmissionEntryFunc.
+func (mock *DBClientMock) CreateSubmissionEntry(ctx context.Context, se db.SubmissionEntry) error {
+	if mock.CreateSubmissionEntryFunc == nil {
+		panic("DBClientMock.CreateSubmissionEntryFunc: method is nil but Client.CreateSubmissionEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Se  db.SubmissionEntry
+	}{
+		Ctx: ctx,
+		Se:  se,
+	}
+	mock.lockCreateSubmissionEntry.Lock()
+	mock.calls.CreateSubmissionEntry = append(mock.calls.CreateSubmissionEntry, callInfo)
+	mock.lockCreateSubmissionEntry.Unlock()
+	return mock.CreateSubmissionEntryFunc(ctx, se)
+}
+
+// CreateSubmissionEntryCalls gets all the calls that were made to CreateSubmissionEntry.
+// Check the length with:
+//
+//	len(mockedClient.CreateSubmissionEntryCalls())
+func (mock *DBClientMock) CreateSubmissionEntryCalls() []struct {
+	Ctx context.Context
+	Se  db.SubmissionEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Se  db.SubmissionEntry
+	}
+	mock.lockCreateSubmissionEntry.RLock()
+	calls = mock.calls.CreateSubmissionEntry
+	mock.lockCreateSubmissionEntry.RUnlock()
+	return calls
+}
+
 // CreateTokenEntry calls CreateTokenEntryFunc.
 func (mock *DBClientMock) CreateTokenEntry(ctx context.Context, token types.Token) error {
 	if mock.CreateTokenEntryFunc == nil {
@@ -246,67 +775,179 @@ func (mock *DBClientMock) CreateTokenEntryCalls() []struct {
 	return calls
 }
 
-// DeleteProjectEntry calls DeleteProjectEntryFunc.
-func (mock *DBClientMock) DeleteProjectEntry(ctx context.Context, project string) error {
-	if mock.DeleteProjectEntryFunc == nil {
-		panic("DBClientMock.DeleteProjectEntryFunc: method is nil but Client.DeleteProjectEntry was just called")
+// CreateWebhookEntry calls CreateWebhookEntryFunc.
+func (mock *DBClientMock) CreateWebhookEntry(ctx context.Context, we db.WebhookEntry) error {
+	if mock.CreateWebhookEntryFunc == nil {
+		panic("DBClientMock.CreateWebhookEntryFunc: method is nil but Client.CreateWebhookEntry was just called")
 	}
 	callInfo := struct {
-		Ctx     context.Context
-		Project string
+		Ctx context.Context
+		We  db.WebhookEntry
 	}{
-		Ctx:     ctx,
-		Project: project,
+		Ctx: ctx,
+		We:  we,
 	}
-	mock.lockDeleteProjectEntry.Lock()
-	mock.calls.DeleteProjectEntry = append(mock.calls.DeleteProjectEntry, callInfo)
-	mock.lockDeleteProjectEntry.Unlock()
-	return mock.DeleteProjectEntryFunc(ctx, project)
+	mock.lockCreateWebhookEntry.Lock()
+	mock.calls.CreateWebhookEntry = append(mock.calls.CreateWebhookEntry, callInfo)
+	mock.lockCreateWebhookEntry.Unlock()
+	return mock.CreateWebhookEntryFunc(ctx, we)
 }
 
-// DeleteProjectEntryCalls gets all the calls that were made to DeleteProjectEntry.
+// CreateWebhookEntryCalls gets all the calls that were made to CreateWebhookEntry.
 // Check the length with:
 //
-//	len(mockedClient.DeleteProjectEntryCalls())
-func (mock *DBClientMock) DeleteProjectEntryCalls() []struct {
-	Ctx     context.Context
-	Project string
+//	len(mockedClient.CreateWebhookEntryCalls())
+func (mock *DBClientMock) CreateWebhookEntryCalls() []struct {
+	Ctx context.Context
+	We  db.WebhookEntry
 } {
 	var calls []struct {
-		Ctx     context.Context
-		Project string
+		Ctx context.Context
+		We  db.WebhookEntry
 	}
-	mock.lockDeleteProjectEntry.RLock()
-	calls = mock.calls.DeleteProjectEntry
-	mock.lockDeleteProjectEntry.RUnlock()
+	mock.lockCreateWebhookEntry.RLock()
+	calls = mock.calls.CreateWebhookEntry
+	mock.lockCreateWebhookEntry.RUnlock()
 	return calls
 }
 
-// DeleteTokenEntry calls DeleteTokenEntryFunc.
-func (mock *DBClientMock) DeleteTokenEntry(ctx context.Context, token string) error {
-	if mock.DeleteTokenEntryFunc == nil {
-		panic("DBClientMock.DeleteTokenEntryFunc: method is nil but Client.DeleteTokenEntry was just called")
+// DeleteIdempotencyEntry calls DeleteIdempotencyEntryFunc.
+func (mock *DBClientMock) DeleteIdempotencyEntry(ctx context.Context, principal string, key string) error {
+	if mock.DeleteIdempotencyEntryFunc == nil {
+		panic("DBClientMock.DeleteIdempotencyEntryFunc: method is nil but Client.DeleteIdempotencyEntry was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Token string
+		Ctx       context.Context
+		Principal string
+		Key       string
 	}{
-		Ctx:   ctx,
-		Token: token,
+		Ctx:       ctx,
+		Principal: principal,
+		Key:       key,
 	}
-	mock.lockDeleteTokenEntry.Lock()
-	mock.calls.DeleteTokenEntry = append(mock.calls.DeleteTokenEntry, callInfo)
-	mock.lockDeleteTokenEntry.Unlock()
-	return mock.DeleteTokenEntryFunc(ctx, token)
+	mock.lockDeleteIdempotencyEntry.Lock()
+	mock.calls.DeleteIdempotencyEntry = append(mock.calls.DeleteIdempotencyEntry, callInfo)
+	mock.lockDeleteIdempotencyEntry.Unlock()
+	return mock.DeleteIdempotencyEntryFunc(ctx, principal, key)
 }
 
-// DeleteTokenEntryCalls gets all the calls that were made to DeleteTokenEntry.
+// DeleteIdempotencyEntryCalls gets all the calls that were made to DeleteIdempotencyEntry.
 // Check the length with:
 //
-//	len(mockedClient.DeleteTokenEntryCalls())
-func (mock *DBClientMock) DeleteTokenEntryCalls() []struct {
-	Ctx   context.Context
-	Token string
+//	len(mockedClient.DeleteIdempotencyEntryCalls())
+func (mock *DBClientMock) DeleteIdempotencyEntryCalls() []struct {
+	Ctx       context.Context
+	Principal string
+	Key       string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Principal string
+		Key       string
+	}
+	mock.lockDeleteIdempotencyEntry.RLock()
+	calls = mock.calls.DeleteIdempotencyEntry
+	mock.lockDeleteIdempotencyEntry.RUnlock()
+	return calls
+}
+
+// DeleteIdentityEntry calls DeleteIdentityEntryFunc.
+func (mock *DBClientMock) DeleteIdentityEntry(ctx context.Context, name string) error {
+	if mock.DeleteIdentityEntryFunc == nil {
+		panic("DBClientMock.DeleteIdentityEntryFunc: method is nil but Client.DeleteIdentityEntry was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockDeleteIdentityEntry.Lock()
+	mock.calls.DeleteIdentityEntry = append(mock.calls.DeleteIdentityEntry, callInfo)
+	mock.lockDeleteIdentityEntry.Unlock()
+	return mock.DeleteIdentityEntryFunc(ctx, name)
+}
+
+// DeleteIdentityEntryCalls gets all the calls that were made to DeleteIdentityEntry.
+// Check the length with:
+//
+//	len(mockedClient.DeleteIdentityEntryCalls())
+func (mock *DBClientMock) DeleteIdentityEntryCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockDeleteIdentityEntry.RLock()
+	calls = mock.calls.DeleteIdentityEntry
+	mock.lockDeleteIdentityEntry.RUnlock()
+	return calls
+}
+
+// DeleteProjectEntry calls DeleteProjectEntryFunc.
+func (mock *DBClientMock) DeleteProjectEntry(ctx context.Context, project string) error {
+	if mock.DeleteProjectEntryFunc == nil {
+		panic("DBClientMock.DeleteProjectEntryFunc: method is nil but Client.DeleteProjectEntry was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Project string
+	}{
+		Ctx:     ctx,
+		Project: project,
+	}
+	mock.lockDeleteProjectEntry.Lock()
+	mock.calls.DeleteProjectEntry = append(mock.calls.DeleteProjectEntry, callInfo)
+	mock.lockDeleteProjectEntry.Unlock()
+	return mock.DeleteProjectEntryFunc(ctx, project)
+}
+
+// DeleteProjectEntryCalls gets all the calls that were made to DeleteProjectEntry.
+// Check the length with:
+//
+//	len(mockedClient.DeleteProjectEntryCalls())
+func (mock *DBClientMock) DeleteProjectEntryCalls() []struct {
+	Ctx     context.Context
+	Project string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Project string
+	}
+	mock.lockDeleteProjectEntry.RLock()
+	calls = mock.calls.DeleteProjectEntry
+	mock.lockDeleteProjectEntry.RUnlock()
+	return calls
+}
+
+// DeleteTokenEntry calls DeleteTokenEntryFunc.
+func (mock *DBClientMock) DeleteTokenEntry(ctx context.Context, token string) error {
+	if mock.DeleteTokenEntryFunc == nil {
+		panic("DBClientMock.DeleteTokenEntryFunc: method is nil but Client.DeleteTokenEntry was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Token string
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	mock.lockDeleteTokenEntry.Lock()
+	mock.calls.DeleteTokenEntry = append(mock.calls.DeleteTokenEntry, callInfo)
+	mock.lockDeleteTokenEntry.Unlock()
+	return mock.DeleteTokenEntryFunc(ctx, token)
+}
+
+// DeleteTokenEntryCalls gets all the calls that were made to DeleteTokenEntry.
+// Check the length with:
+//
+//	len(mockedClient.DeleteTokenEntryCalls())
+func (mock *DBClientMock) DeleteTokenEntryCalls() []struct {
+	Ctx   context.Context
+	Token string
 } {
 	var calls []struct {
 		Ctx   context.Context
@@ -358,6 +999,46 @@ func (mock *DBClientMock) DeleteTokenEntryByProjectCalls() []struct {
 	return calls
 }
 
+// DeleteWebhookEntry calls DeleteWebhookEntryFunc.
+func (mock *DBClientMock) DeleteWebhookEntry(ctx context.Context, project string, id string) error {
+	if mock.DeleteWebhookEntryFunc == nil {
+		panic("DBClientMock.DeleteWebhookEntryFunc: method is nil but Client.DeleteWebhookEntry was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Project string
+		Id      string
+	}{
+		Ctx:     ctx,
+		Project: project,
+		Id:      id,
+	}
+	mock.lockDeleteWebhookEntry.Lock()
+	mock.calls.DeleteWebhookEntry = append(mock.calls.DeleteWebhookEntry, callInfo)
+	mock.lockDeleteWebhookEntry.Unlock()
+	return mock.DeleteWebhookEntryFunc(ctx, project, id)
+}
+
+// DeleteWebhookEntryCalls gets all the calls that were made to DeleteWebhookEntry.
+// Check the length with:
+//
+//	len(mockedClient.DeleteWebhookEntryCalls())
+func (mock *DBClientMock) DeleteWebhookEntryCalls() []struct {
+	Ctx     context.Context
+	Project string
+	Id      string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Project string
+		Id      string
+	}
+	mock.lockDeleteWebhookEntry.RLock()
+	calls = mock.calls.DeleteWebhookEntry
+	mock.lockDeleteWebhookEntry.RUnlock()
+	return calls
+}
+
 // Health calls HealthFunc.
 func (mock *DBClientMock) Health(ctx context.Context) error {
 	if mock.HealthFunc == nil {
@@ -390,6 +1071,106 @@ func (mock *DBClientMock) HealthCalls() []struct {
 	return calls
 }
 
+// ListIdentityEntries calls ListIdentityEntriesFunc.
+func (mock *DBClientMock) ListIdentityEntries(ctx context.Context) ([]db.IdentityEntry, error) {
+	if mock.ListIdentityEntriesFunc == nil {
+		panic("DBClientMock.ListIdentityEntriesFunc: method is nil but Client.ListIdentityEntries was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListIdentityEntries.Lock()
+	mock.calls.ListIdentityEntries = append(mock.calls.ListIdentityEntries, callInfo)
+	mock.lockListIdentityEntries.Unlock()
+	return mock.ListIdentityEntriesFunc(ctx)
+}
+
+// ListIdentityEntriesCalls gets all the calls that were made to ListIdentityEntries.
+// Check the length with:
+//
+//	len(mockedClient.ListIdentityEntriesCalls())
+func (mock *DBClientMock) ListIdentityEntriesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListIdentityEntries.RLock()
+	calls = mock.calls.ListIdentityEntries
+	mock.lockListIdentityEntries.RUnlock()
+	return calls
+}
+
+// ListProjectEntries calls ListProjectEntriesFunc.
+func (mock *DBClientMock) ListProjectEntries(ctx context.Context) ([]db.ProjectEntry, error) {
+	if mock.ListProjectEntriesFunc == nil {
+		panic("DBClientMock.ListProjectEntriesFunc: method is nil but Client.ListProjectEntries was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListProjectEntries.Lock()
+	mock.calls.ListProjectEntries = append(mock.calls.ListProjectEntries, callInfo)
+	mock.lockListProjectEntries.Unlock()
+	return mock.ListProjectEntriesFunc(ctx)
+}
+
+// ListProjectEntriesCalls gets all the calls that were made to ListProjectEntries.
+// Check the length with:
+//
+//	len(mockedClient.ListProjectEntriesCalls())
+func (mock *DBClientMock) ListProjectEntriesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListProjectEntries.RLock()
+	calls = mock.calls.ListProjectEntries
+	mock.lockListProjectEntries.RUnlock()
+	return calls
+}
+
+// ListQueuedSubmissions calls ListQueuedSubmissionsFunc.
+func (mock *DBClientMock) ListQueuedSubmissions(ctx context.Context, now time.Time) ([]string, error) {
+	if mock.ListQueuedSubmissionsFunc == nil {
+		panic("DBClientMock.ListQueuedSubmissionsFunc: method is nil but Client.ListQueuedSubmissions was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Now time.Time
+	}{
+		Ctx: ctx,
+		Now: now,
+	}
+	mock.lockListQueuedSubmissions.Lock()
+	mock.calls.ListQueuedSubmissions = append(mock.calls.ListQueuedSubmissions, callInfo)
+	mock.lockListQueuedSubmissions.Unlock()
+	return mock.ListQueuedSubmissionsFunc(ctx, now)
+}
+
+// ListQueuedSubmissionsCalls gets all the calls that were made to ListQueuedSubmissions.
+// Check the length with:
+//
+//	len(mockedClient.ListQueuedSubmissionsCalls())
+func (mock *DBClientMock) ListQueuedSubmissionsCalls() []struct {
+	Ctx context.Context
+	Now time.Time
+} {
+	var calls []struct {
+		Ctx context.Context
+		Now time.Time
+	}
+	mock.lockListQueuedSubmissions.RLock()
+	calls = mock.calls.ListQueuedSubmissions
+	mock.lockListQueuedSubmissions.RUnlock()
+	return calls
+}
+
 // ListTokenEntries calls ListTokenEntriesFunc.
 func (mock *DBClientMock) ListTokenEntries(ctx context.Context, project string) ([]db.TokenEntry, error) {
 	if mock.ListTokenEntriesFunc == nil {
@@ -426,6 +1207,118 @@ func (mock *DBClientMock) ListTokenEntriesCalls() []struct {
 	return calls
 }
 
+// ListWebhookEntries calls ListWebhookEntriesFunc.
+func (mock *DBClientMock) ListWebhookEntries(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+	if mock.ListWebhookEntriesFunc == nil {
+		panic("DBClientMock.ListWebhookEntriesFunc: method is nil but Client.ListWebhookEntries was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Project string
+	}{
+		Ctx:     ctx,
+		Project: project,
+	}
+	mock.lockListWebhookEntries.Lock()
+	mock.calls.ListWebhookEntries = append(mock.calls.ListWebhookEntries, callInfo)
+	mock.lockListWebhookEntries.Unlock()
+	return mock.ListWebhookEntriesFunc(ctx, project)
+}
+
+// ListWebhookEntriesCalls gets all the calls that were made to ListWebhookEntries.
+// Check the length with:
+//
+//	len(mockedClient.ListWebhookEntriesCalls())
+func (mock *DBClientMock) ListWebhookEntriesCalls() []struct {
+	Ctx     context.Context
+	Project string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Project string
+	}
+	mock.lockListWebhookEntries.RLock()
+	calls = mock.calls.ListWebhookEntries
+	mock.lockListWebhookEntries.RUnlock()
+	return calls
+}
+
+// ReadIdempotencyEntry calls ReadIdempotencyEntryFunc.
+func (mock *DBClientMock) ReadIdempotencyEntry(ctx context.Context, principal string, key string) (db.IdempotencyEntry, error) {
+	if mock.ReadIdempotencyEntryFunc == nil {
+		panic("DBClientMock.ReadIdempotencyEntryFunc: method is nil but Client.ReadIdempotencyEntry was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Principal string
+		Key       string
+	}{
+		Ctx:       ctx,
+		Principal: principal,
+		Key:       key,
+	}
+	mock.lockReadIdempotencyEntry.Lock()
+	mock.calls.ReadIdempotencyEntry = append(mock.calls.ReadIdempotencyEntry, callInfo)
+	mock.lockReadIdempotencyEntry.Unlock()
+	return mock.ReadIdempotencyEntryFunc(ctx, principal, key)
+}
+
+// ReadIdempotencyEntryCalls gets all the calls that were made to ReadIdempotencyEntry.
+// Check the length with:
+//
+//	len(mockedClient.ReadIdempotencyEntryCalls())
+func (mock *DBClientMock) ReadIdempotencyEntryCalls() []struct {
+	Ctx       context.Context
+	Principal string
+	Key       string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Principal string
+		Key       string
+	}
+	mock.lockReadIdempotencyEntry.RLock()
+	calls = mock.calls.ReadIdempotencyEntry
+	mock.lockReadIdempotencyEntry.RUnlock()
+	return calls
+}
+
+// ReadIdentityEntry calls ReadIdentityEntryFunc.
+func (mock *DBClientMock) ReadIdentityEntry(ctx context.Context, name string) (db.IdentityEntry, error) {
+	if mock.ReadIdentityEntryFunc == nil {
+		panic("DBClientMock.ReadIdentityEntryFunc: method is nil but Client.ReadIdentityEntry was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockReadIdentityEntry.Lock()
+	mock.calls.ReadIdentityEntry = append(mock.calls.ReadIdentityEntry, callInfo)
+	mock.lockReadIdentityEntry.Unlock()
+	return mock.ReadIdentityEntryFunc(ctx, name)
+}
+
+// ReadIdentityEntryCalls gets all the calls that were made to ReadIdentityEntry.
+// Check the length with:
+//
+//	len(mockedClient.ReadIdentityEntryCalls())
+func (mock *DBClientMock) ReadIdentityEntryCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockReadIdentityEntry.RLock()
+	calls = mock.calls.ReadIdentityEntry
+	mock.lockReadIdentityEntry.RUnlock()
+	return calls
+}
+
 // ReadProjectEntry calls ReadProjectEntryFunc.
 func (mock *DBClientMock) ReadProjectEntry(ctx context.Context, project string) (db.ProjectEntry, error) {
 	if mock.ReadProjectEntryFunc == nil {
@@ -462,6 +1355,42 @@ func (mock *DBClientMock) ReadProjectEntryCalls() []struct {
 	return calls
 }
 
+// ReadSubmissionEntry calls ReadSubmissionEntryFunc.
+func (mock *DBClientMock) ReadSubmissionEntry(ctx context.Context, id string) (db.SubmissionEntry, error) {
+	if mock.ReadSubmissionEntryFunc == nil {
+		panic("DBClientMock.ReadSubmissionEntryFunc: method is nil but Client.ReadSubmissionEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockReadSubmissionEntry.Lock()
+	mock.calls.ReadSubmissionEntry = append(mock.calls.ReadSubmissionEntry, callInfo)
+	mock.lockReadSubmissionEntry.Unlock()
+	return mock.ReadSubmissionEntryFunc(ctx, id)
+}
+
+// ReadSubmissionEntryCalls gets all the calls that were made to ReadSubmissionEntry.
+// Check the length with:
+//
+//	len(mockedClient.ReadSubmissionEntryCalls())
+func (mock *DBClientMock) ReadSubmissionEntryCalls() []struct {
+	Ctx context.Context
+	Id  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+	}
+	mock.lockReadSubmissionEntry.RLock()
+	calls = mock.calls.ReadSubmissionEntry
+	mock.lockReadSubmissionEntry.RUnlock()
+	return calls
+}
+
 // ReadTokenEntry calls ReadTokenEntryFunc.
 func (mock *DBClientMock) ReadTokenEntry(ctx context.Context, token string) (db.TokenEntry, error) {
 	if mock.ReadTokenEntryFunc == nil {
@@ -537,3 +1466,267 @@ func (mock *DBClientMock) ReadTokenEntryByProjectCalls() []struct {
 	mock.lockReadTokenEntryByProject.RUnlock()
 	return calls
 }
+
+// RecordWebhookEvent calls RecordWebhookEventFunc.
+func (mock *DBClientMock) RecordWebhookEvent(ctx context.Context, workflowName string, event string, expiresAt time.Time) (bool, error) {
+	if mock.RecordWebhookEventFunc == nil {
+		panic("DBClientMock.RecordWebhookEventFunc: method is nil but Client.RecordWebhookEvent was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		WorkflowName string
+		Event        string
+		ExpiresAt    time.Time
+	}{
+		Ctx:          ctx,
+		WorkflowName: workflowName,
+		Event:        event,
+		ExpiresAt:    expiresAt,
+	}
+	mock.lockRecordWebhookEvent.Lock()
+	mock.calls.RecordWebhookEvent = append(mock.calls.RecordWebhookEvent, callInfo)
+	mock.lockRecordWebhookEvent.Unlock()
+	return mock.RecordWebhookEventFunc(ctx, workflowName, event, expiresAt)
+}
+
+// RecordWebhookEventCalls gets all the calls that were made to RecordWebhookEvent.
+// Check the length with:
+//
+//	len(mockedClient.RecordWebhookEventCalls())
+func (mock *DBClientMock) RecordWebhookEventCalls() []struct {
+	Ctx          context.Context
+	WorkflowName string
+	Event        string
+	ExpiresAt    time.Time
+} {
+	var calls []struct {
+		Ctx          context.Context
+		WorkflowName string
+		Event        string
+		ExpiresAt    time.Time
+	}
+	mock.lockRecordWebhookEvent.RLock()
+	calls = mock.calls.RecordWebhookEvent
+	mock.lockRecordWebhookEvent.RUnlock()
+	return calls
+}
+
+// UpdateIdempotencyEntry calls UpdateIdempotencyEntryFunc.
+func (mock *DBClientMock) UpdateIdempotencyEntry(ctx context.Context, ie db.IdempotencyEntry) error {
+	if mock.UpdateIdempotencyEntryFunc == nil {
+		panic("DBClientMock.UpdateIdempotencyEntryFunc: method is nil but Client.UpdateIdempotencyEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Ie  db.IdempotencyEntry
+	}{
+		Ctx: ctx,
+		Ie:  ie,
+	}
+	mock.lockUpdateIdempotencyEntry.Lock()
+	mock.calls.UpdateIdempotencyEntry = append(mock.calls.UpdateIdempotencyEntry, callInfo)
+	mock.lockUpdateIdempotencyEntry.Unlock()
+	return mock.UpdateIdempotencyEntryFunc(ctx, ie)
+}
+
+// UpdateIdempotencyEntryCalls gets all the calls that were made to UpdateIdempotencyEntry.
+// Check the length with:
+//
+//	len(mockedClient.UpdateIdempotencyEntryCalls())
+func (mock *DBClientMock) UpdateIdempotencyEntryCalls() []struct {
+	Ctx context.Context
+	Ie  db.IdempotencyEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ie  db.IdempotencyEntry
+	}
+	mock.lockUpdateIdempotencyEntry.RLock()
+	calls = mock.calls.UpdateIdempotencyEntry
+	mock.lockUpdateIdempotencyEntry.RUnlock()
+	return calls
+}
+
+// UpdateIdentityEntry calls UpdateIdentityEntryFunc.
+func (mock *DBClientMock) UpdateIdentityEntry(ctx context.Context, ie db.IdentityEntry) error {
+	if mock.UpdateIdentityEntryFunc == nil {
+		panic("DBClientMock.UpdateIdentityEntryFunc: method is nil but Client.UpdateIdentityEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Ie  db.IdentityEntry
+	}{
+		Ctx: ctx,
+		Ie:  ie,
+	}
+	mock.lockUpdateIdentityEntry.Lock()
+	mock.calls.UpdateIdentityEntry = append(mock.calls.UpdateIdentityEntry, callInfo)
+	mock.lockUpdateIdentityEntry.Unlock()
+	return mock.UpdateIdentityEntryFunc(ctx, ie)
+}
+
+// UpdateIdentityEntryCalls gets all the calls that were made to UpdateIdentityEntry.
+// Check the length with:
+//
+//	len(mockedClient.UpdateIdentityEntryCalls())
+func (mock *DBClientMock) UpdateIdentityEntryCalls() []struct {
+	Ctx context.Context
+	Ie  db.IdentityEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ie  db.IdentityEntry
+	}
+	mock.lockUpdateIdentityEntry.RLock()
+	calls = mock.calls.UpdateIdentityEntry
+	mock.lockUpdateIdentityEntry.RUnlock()
+	return calls
+}
+
+// UpdateProjectEntry calls UpdateProjectEntryFunc.
+func (mock *DBClientMock) UpdateProjectEntry(ctx context.Context, pe db.ProjectEntry) error {
+	if mock.UpdateProjectEntryFunc == nil {
+		panic("DBClientMock.UpdateProjectEntryFunc: method is nil but Client.UpdateProjectEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Pe  db.ProjectEntry
+	}{
+		Ctx: ctx,
+		Pe:  pe,
+	}
+	mock.lockUpdateProjectEntry.Lock()
+	mock.calls.UpdateProjectEntry = append(mock.calls.UpdateProjectEntry, callInfo)
+	mock.lockUpdateProjectEntry.Unlock()
+	return mock.UpdateProjectEntryFunc(ctx, pe)
+}
+
+// UpdateProjectEntryCalls gets all the calls that were made to UpdateProjectEntry.
+// Check the length with:
+//
+//	len(mockedClient.UpdateProjectEntryCalls())
+func (mock *DBClientMock) UpdateProjectEntryCalls() []struct {
+	Ctx context.Context
+	Pe  db.ProjectEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Pe  db.ProjectEntry
+	}
+	mock.lockUpdateProjectEntry.RLock()
+	calls = mock.calls.UpdateProjectEntry
+	mock.lockUpdateProjectEntry.RUnlock()
+	return calls
+}
+
+// UpdateProjectPolicy calls UpdateProjectPolicyFunc.
+func (mock *DBClientMock) UpdateProjectPolicy(ctx context.Context, project string, policy types.ProjectPolicy) error {
+	if mock.UpdateProjectPolicyFunc == nil {
+		panic("DBClientMock.UpdateProjectPolicyFunc: method is nil but Client.UpdateProjectPolicy was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Project string
+		Policy  types.ProjectPolicy
+	}{
+		Ctx:     ctx,
+		Project: project,
+		Policy:  policy,
+	}
+	mock.lockUpdateProjectPolicy.Lock()
+	mock.calls.UpdateProjectPolicy = append(mock.calls.UpdateProjectPolicy, callInfo)
+	mock.lockUpdateProjectPolicy.Unlock()
+	return mock.UpdateProjectPolicyFunc(ctx, project, policy)
+}
+
+// UpdateProjectPolicyCalls gets all the calls that were made to UpdateProjectPolicy.
+// Check the length with:
+//
+//	len(mockedClient.UpdateProjectPolicyCalls())
+func (mock *DBClientMock) UpdateProjectPolicyCalls() []struct {
+	Ctx     context.Context
+	Project string
+	Policy  types.ProjectPolicy
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Project string
+		Policy  types.ProjectPolicy
+	}
+	mock.lockUpdateProjectPolicy.RLock()
+	calls = mock.calls.UpdateProjectPolicy
+	mock.lockUpdateProjectPolicy.RUnlock()
+	return calls
+}
+
+// UpdateSubmissionEntry calls UpdateSubmissionEntryFunc.
+func (mock *DBClientMock) UpdateSubmissionEntry(ctx context.Context, se db.SubmissionEntry) error {
+	if mock.UpdateSubmissionEntryFunc == nil {
+		panic("DBClientMock.UpdateSubmissionEntryFunc: method is nil but Client.UpdateSubmissionEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Se  db.SubmissionEntry
+	}{
+		Ctx: ctx,
+		Se:  se,
+	}
+	mock.lockUpdateSubmissionEntry.Lock()
+	mock.calls.UpdateSubmissionEntry = append(mock.calls.UpdateSubmissionEntry, callInfo)
+	mock.lockUpdateSubmissionEntry.Unlock()
+	return mock.UpdateSubmissionEntryFunc(ctx, se)
+}
+
+// UpdateSubmissionEntryCalls gets all the calls that were made to UpdateSubmissionEntry.
+// Check the length with:
+//
+//	len(mockedClient.UpdateSubmissionEntryCalls())
+func (mock *DBClientMock) UpdateSubmissionEntryCalls() []struct {
+	Ctx context.Context
+	Se  db.SubmissionEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Se  db.SubmissionEntry
+	}
+	mock.lockUpdateSubmissionEntry.RLock()
+	calls = mock.calls.UpdateSubmissionEntry
+	mock.lockUpdateSubmissionEntry.RUnlock()
+	return calls
+}
+
+// UpdateTokenEntry calls UpdateTokenEntryFunc.
+func (mock *DBClientMock) UpdateTokenEntry(ctx context.Context, te db.TokenEntry) error {
+	if mock.UpdateTokenEntryFunc == nil {
+		panic("DBClientMock.UpdateTokenEntryFunc: method is nil but Client.UpdateTokenEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Te  db.TokenEntry
+	}{
+		Ctx: ctx,
+		Te:  te,
+	}
+	mock.lockUpdateTokenEntry.Lock()
+	mock.calls.UpdateTokenEntry = append(mock.calls.UpdateTokenEntry, callInfo)
+	mock.lockUpdateTokenEntry.Unlock()
+	return mock.UpdateTokenEntryFunc(ctx, te)
+}
+
+// UpdateTokenEntryCalls gets all the calls that were made to UpdateTokenEntry.
+// Check the length with:
+//
+//	len(mockedClient.UpdateTokenEntryCalls())
+func (mock *DBClientMock) UpdateTokenEntryCalls() []struct {
+	Ctx context.Context
+	Te  db.TokenEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		Te  db.TokenEntry
+	}
+	mock.lockUpdateTokenEntry.RLock()
+	calls = mock.calls.UpdateTokenEntry
+	mock.lockUpdateTokenEntry.RUnlock()
+	return calls
+}
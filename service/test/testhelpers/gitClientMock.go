@@ -4,6 +4,7 @@
 package testhelpers
 
 import (
+	"context"
 	"github.com/cello-proj/cello/service/internal/git"
 	"sync"
 )
@@ -21,6 +22,9 @@ var _ git.Client = &GitClientMock{}
 //			GetManifestFileFunc: func(repository string, commitHash string, path string) ([]byte, error) {
 //				panic("mock out the GetManifestFile method")
 //			},
+//			PingFunc: func(ctx context.Context, repository string) error {
+//				panic("mock out the Ping method")
+//			},
 //		}
 //
 //		// use mockedClient in code that requires git.Client
@@ -31,6 +35,9 @@ type GitClientMock struct {
 	// GetManifestFileFunc mocks the GetManifestFile method.
 	GetManifestFileFunc func(repository string, commitHash string, path string) ([]byte, error)
 
+	// PingFunc mocks the Ping method.
+	PingFunc func(ctx context.Context, repository string) error
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// GetManifestFile holds details about calls to the GetManifestFile method.
@@ -42,8 +49,16 @@ type GitClientMock struct {
 			// Path is the path argument value.
 			Path string
 		}
+		// Ping holds details about calls to the Ping method.
+		Ping []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Repository is the repository argument value.
+			Repository string
+		}
 	}
 	lockGetManifestFile sync.RWMutex
+	lockPing            sync.RWMutex
 }
 
 // GetManifestFile calls GetManifestFileFunc.
@@ -85,3 +100,39 @@ func (mock *GitClientMock) GetManifestFileCalls() []struct {
 	mock.lockGetManifestFile.RUnlock()
 	return calls
 }
+
+// Ping calls PingFunc.
+func (mock *GitClientMock) Ping(ctx context.Context, repository string) error {
+	if mock.PingFunc == nil {
+		panic("GitClientMock.PingFunc: method is nil but Client.Ping was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		Repository string
+	}{
+		Ctx:        ctx,
+		Repository: repository,
+	}
+	mock.lockPing.Lock()
+	mock.calls.Ping = append(mock.calls.Ping, callInfo)
+	mock.lockPing.Unlock()
+	return mock.PingFunc(ctx, repository)
+}
+
+// PingCalls gets all the calls that were made to Ping.
+// Check the length with:
+//
+//	len(mockedClient.PingCalls())
+func (mock *GitClientMock) PingCalls() []struct {
+	Ctx        context.Context
+	Repository string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Repository string
+	}
+	mock.lockPing.RLock()
+	calls = mock.calls.Ping
+	mock.lockPing.RUnlock()
+	return calls
+}
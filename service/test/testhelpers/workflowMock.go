@@ -20,6 +20,12 @@ var _ workflow.Workflow = &WorkflowMock{}
 //
 //		// make and configure a mocked workflow.Workflow
 //		mockedWorkflow := &WorkflowMock{
+//			CountActiveFunc: func(ctx context.Context, namePrefix string) (int, error) {
+//				panic("mock out the CountActive method")
+//			},
+//			HealthFunc: func(ctx context.Context) error {
+//				panic("mock out the Health method")
+//			},
 //			ListStatusFunc: func(ctx context.Context) ([]workflow.Status, error) {
 //				panic("mock out the ListStatus method")
 //			},
@@ -35,6 +41,9 @@ var _ workflow.Workflow = &WorkflowMock{}
 //			SubmitFunc: func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error) {
 //				panic("mock out the Submit method")
 //			},
+//			WatchFunc: func(ctx context.Context, f func(workflow.Run)) error {
+//				panic("mock out the Watch method")
+//			},
 //		}
 //
 //		// use mockedWorkflow in code that requires workflow.Workflow
@@ -42,6 +51,12 @@ var _ workflow.Workflow = &WorkflowMock{}
 //
 //	}
 type WorkflowMock struct {
+	// CountActiveFunc mocks the CountActive method.
+	CountActiveFunc func(ctx context.Context, namePrefix string) (int, error)
+
+	// HealthFunc mocks the Health method.
+	HealthFunc func(ctx context.Context) error
+
 	// ListStatusFunc mocks the ListStatus method.
 	ListStatusFunc func(ctx context.Context) ([]workflow.Status, error)
 
@@ -57,8 +72,23 @@ type WorkflowMock struct {
 	// SubmitFunc mocks the Submit method.
 	SubmitFunc func(ctx context.Context, from string, parameters map[string]string, labels map[string]string) (string, error)
 
+	// WatchFunc mocks the Watch method.
+	WatchFunc func(ctx context.Context, f func(workflow.Run)) error
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// CountActive holds details about calls to the CountActive method.
+		CountActive []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// NamePrefix is the namePrefix argument value.
+			NamePrefix string
+		}
+		// Health holds details about calls to the Health method.
+		Health []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 		// ListStatus holds details about calls to the ListStatus method.
 		ListStatus []struct {
 			// Ctx is the ctx argument value.
@@ -98,12 +128,90 @@ type WorkflowMock struct {
 			// Labels is the labels argument value.
 			Labels map[string]string
 		}
+		// Watch holds details about calls to the Watch method.
+		Watch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// F is the f argument value.
+			F func(workflow.Run)
+		}
 	}
-	lockListStatus sync.RWMutex
-	lockLogStream  sync.RWMutex
-	lockLogs       sync.RWMutex
-	lockStatus     sync.RWMutex
-	lockSubmit     sync.RWMutex
+	lockCountActive sync.RWMutex
+	lockHealth      sync.RWMutex
+	lockListStatus  sync.RWMutex
+	lockLogStream   sync.RWMutex
+	lockLogs        sync.RWMutex
+	lockStatus      sync.RWMutex
+	lockSubmit      sync.RWMutex
+	lockWatch       sync.RWMutex
+}
+
+// CountActive calls CountActiveFunc.
+func (mock *WorkflowMock) CountActive(ctx context.Context, namePrefix string) (int, error) {
+	if mock.CountActiveFunc == nil {
+		panic("WorkflowMock.CountActiveFunc: method is nil but Workflow.CountActive was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		NamePrefix string
+	}{
+		Ctx:        ctx,
+		NamePrefix: namePrefix,
+	}
+	mock.lockCountActive.Lock()
+	mock.calls.CountActive = append(mock.calls.CountActive, callInfo)
+	mock.lockCountActive.Unlock()
+	return mock.CountActiveFunc(ctx, namePrefix)
+}
+
+// CountActiveCalls gets all the calls that were made to CountActive.
+// Check the length with:
+//
+//	len(mockedWorkflow.CountActiveCalls())
+func (mock *WorkflowMock) CountActiveCalls() []struct {
+	Ctx        context.Context
+	NamePrefix string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		NamePrefix string
+	}
+	mock.lockCountActive.RLock()
+	calls = mock.calls.CountActive
+	mock.lockCountActive.RUnlock()
+	return calls
+}
+
+// Health calls HealthFunc.
+func (mock *WorkflowMock) Health(ctx context.Context) error {
+	if mock.HealthFunc == nil {
+		panic("WorkflowMock.HealthFunc: method is nil but Workflow.Health was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockHealth.Lock()
+	mock.calls.Health = append(mock.calls.Health, callInfo)
+	mock.lockHealth.Unlock()
+	return mock.HealthFunc(ctx)
+}
+
+// HealthCalls gets all the calls that were made to Health.
+// Check the length with:
+//
+//	len(mockedWorkflow.HealthCalls())
+func (mock *WorkflowMock) HealthCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockHealth.RLock()
+	calls = mock.calls.Health
+	mock.lockHealth.RUnlock()
+	return calls
 }
 
 // ListStatus calls ListStatusFunc.
@@ -293,3 +401,39 @@ func (mock *WorkflowMock) SubmitCalls() []struct {
 	mock.lockSubmit.RUnlock()
 	return calls
 }
+
+// Watch calls WatchFunc.
+func (mock *WorkflowMock) Watch(ctx context.Context, f func(workflow.Run)) error {
+	if mock.WatchFunc == nil {
+		panic("WorkflowMock.WatchFunc: method is nil but Workflow.Watch was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		F   func(workflow.Run)
+	}{
+		Ctx: ctx,
+		F:   f,
+	}
+	mock.lockWatch.Lock()
+	mock.calls.Watch = append(mock.calls.Watch, callInfo)
+	mock.lockWatch.Unlock()
+	return mock.WatchFunc(ctx, f)
+}
+
+// WatchCalls gets all the calls that were made to Watch.
+// Check the length with:
+//
+//	len(mockedWorkflow.WatchCalls())
+func (mock *WorkflowMock) WatchCalls() []struct {
+	Ctx context.Context
+	F   func(workflow.Run)
+} {
+	var calls []struct {
+		Ctx context.Context
+		F   func(workflow.Run)
+	}
+	mock.lockWatch.RLock()
+	calls = mock.calls.Watch
+	mock.lockWatch.RUnlock()
+	return calls
+}
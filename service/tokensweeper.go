@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// tokenSweeperLease is the name of the lease held by the replica sweeping
+// tokens.
+const tokenSweeperLease = "token-sweeper"
+
+// Events logged for each token removed by the sweeper.
+const (
+	tokenEventExpired  = "token_expired"
+	tokenEventOrphaned = "token_orphaned"
+)
+
+// runTokenSweeper sweeps tokens every interval until the context is done.
+// Replicas share a lease so only one of them sweeps per interval, sweeping is
+// idempotent so an overlap when a lease expires is harmless.
+func (h handler) runTokenSweeper(ctx context.Context, interval time.Duration, owner string) {
+	l := log.With(h.logger, "op", "sweep-tokens", "owner", owner)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := h.ddbClient.AcquireLease(ctx, tokenSweeperLease, owner, interval)
+		if err != nil {
+			level.Error(l).Log("message", "error acquiring token sweeper lease", "error", err)
+			continue
+		}
+		if !ok {
+			level.Debug(l).Log("message", "token sweeper lease held by another replica")
+			continue
+		}
+
+		cp, err := h.newCredentialsProvider(credentials.AdminAuthorization(h.env.CredentialsProvider, h.env.AdminSecret), h.env, http.Header{}, credentials.NewVaultConfig, credentials.NewVaultSvc)
+		if err != nil {
+			level.Error(l).Log("message", "error creating credentials provider", "error", err)
+			continue
+		}
+
+		if err := h.sweepTokens(ctx, l, cp, time.Now()); err != nil {
+			level.Error(l).Log("message", "error sweeping tokens", "error", err)
+		}
+	}
+}
+
+// sweepTokens removes the token entries of all projects which have expired,
+// or whose token no longer exists in the credentials provider. Errors with a
+// single token are logged and the sweep continues with the next one.
+func (h handler) sweepTokens(ctx context.Context, l log.Logger, cp credentials.Provider, now time.Time) error {
+	projects, err := h.ddbClient.ListProjectEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, pe := range projects {
+		tokens, err := h.ddbClient.ListTokenEntries(ctx, pe.ProjectID)
+		if err != nil {
+			level.Error(l).Log("message", "error listing tokens", "project", pe.ProjectID, "error", err)
+			continue
+		}
+
+		for _, te := range tokens {
+			h.sweepToken(ctx, l, cp, te, now)
+		}
+	}
+
+	return nil
+}
+
+func (h handler) sweepToken(ctx context.Context, l log.Logger, cp credentials.Provider, te db.TokenEntry, now time.Time) {
+	l = log.With(l, "project", te.ProjectID, "tokenID", te.TokenID)
+
+	// Tokens without an expiration never expire.
+	if expiresAt, err := time.Parse(time.RFC3339Nano, te.ExpiresAt); err == nil && !expiresAt.IsZero() && expiresAt.Before(now) {
+		if err := h.revokeToken(ctx, cp, te.ProjectID, te.TokenID); err != nil {
+			level.Error(l).Log("message", "error revoking expired token", "error", err)
+			return
+		}
+		level.Info(l).Log("message", "removed expired token", "event", tokenEventExpired, "expires_at", te.ExpiresAt)
+		return
+	}
+
+	if _, err := cp.GetProjectToken(te.ProjectID, te.TokenID); err != nil {
+		if !errors.Is(err, credentials.ErrProjectTokenNotFound) {
+			level.Error(l).Log("message", "error retrieving token", "error", err)
+			return
+		}
+
+		if err := h.ddbClient.DeleteTokenEntryByProject(ctx, te.ProjectID, te.TokenID); err != nil {
+			level.Error(l).Log("message", "error deleting orphaned token", "error", err)
+			return
+		}
+		level.Info(l).Log("message", "removed orphaned token", "event", tokenEventOrphaned)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/credentials"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/env"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweepTokens(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		tokens            []db.TokenEntry
+		providerTokens    map[string]bool
+		getTokenErr       error
+		wantDeletedTokens []string
+		wantRevoked       []string
+	}{
+		{
+			name: "keeps valid tokens",
+			tokens: []db.TokenEntry{
+				{TokenID: "valid", ExpiresAt: "2024-01-03T00:00:00Z"},
+			},
+			providerTokens: map[string]bool{"valid": true},
+		},
+		{
+			name: "removes expired tokens",
+			tokens: []db.TokenEntry{
+				{TokenID: "expired", ExpiresAt: "2024-01-01T00:00:00Z"},
+				{TokenID: "valid", ExpiresAt: "2024-01-03T00:00:00Z"},
+			},
+			providerTokens:    map[string]bool{"expired": true, "valid": true},
+			wantDeletedTokens: []string{"expired"},
+			wantRevoked:       []string{"expired"},
+		},
+		{
+			name: "removes expired tokens already removed from the provider",
+			tokens: []db.TokenEntry{
+				{TokenID: "expired", ExpiresAt: "2024-01-01T00:00:00Z"},
+			},
+			wantDeletedTokens: []string{"expired"},
+		},
+		{
+			name: "removes orphaned tokens",
+			tokens: []db.TokenEntry{
+				{TokenID: "orphaned", ExpiresAt: "2024-01-03T00:00:00Z"},
+				{TokenID: "valid", ExpiresAt: "2024-01-03T00:00:00Z"},
+			},
+			providerTokens:    map[string]bool{"valid": true},
+			wantDeletedTokens: []string{"orphaned"},
+		},
+		{
+			name: "keeps tokens without expiration",
+			tokens: []db.TokenEntry{
+				{TokenID: "noexpiry", ExpiresAt: "0001-01-01T00:00:00Z"},
+			},
+			providerTokens: map[string]bool{"noexpiry": true},
+		},
+		{
+			name: "keeps tokens when the provider errors",
+			tokens: []db.TokenEntry{
+				{TokenID: "valid", ExpiresAt: "2024-01-03T00:00:00Z"},
+			},
+			getTokenErr: errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpMock := &th.CredsProviderMock{
+				GetProjectTokenFunc: func(project, tokenID string) (types.ProjectToken, error) {
+					if tt.getTokenErr != nil {
+						return types.ProjectToken{}, tt.getTokenErr
+					}
+					if !tt.providerTokens[tokenID] {
+						return types.ProjectToken{}, credentials.ErrProjectTokenNotFound
+					}
+					return types.ProjectToken{ID: tokenID}, nil
+				},
+				DeleteProjectTokenFunc: func(project, tokenID string) error { return nil },
+			}
+
+			ddbMock := &th.DBClientMock{
+				ListProjectEntriesFunc: func(ctx context.Context) ([]db.ProjectEntry, error) {
+					return []db.ProjectEntry{{ProjectID: "project1"}}, nil
+				},
+				ListTokenEntriesFunc: func(ctx context.Context, project string) ([]db.TokenEntry, error) {
+					tokens := []db.TokenEntry{}
+					for _, te := range tt.tokens {
+						te.ProjectID = project
+						tokens = append(tokens, te)
+					}
+					return tokens, nil
+				},
+				DeleteTokenEntryByProjectFunc: func(ctx context.Context, project, token string) error { return nil },
+			}
+
+			h := handler{logger: log.NewNopLogger(), ddbClient: ddbMock}
+
+			err := h.sweepTokens(context.Background(), log.NewNopLogger(), cpMock, now)
+			assert.NoError(t, err)
+
+			deleted := []string{}
+			for _, c := range ddbMock.DeleteTokenEntryByProjectCalls() {
+				assert.Equal(t, "project1", c.Project)
+				deleted = append(deleted, c.Token)
+			}
+			assert.ElementsMatch(t, tt.wantDeletedTokens, deleted)
+
+			revoked := []string{}
+			for _, c := range cpMock.DeleteProjectTokenCalls() {
+				revoked = append(revoked, c.S2)
+			}
+			assert.ElementsMatch(t, tt.wantRevoked, revoked)
+		})
+	}
+}
+
+func TestRunTokenSweeper(t *testing.T) {
+	tests := []struct {
+		name      string
+		leaseHeld bool
+		wantSweep bool
+	}{
+		{
+			name:      "sweeps when holding the lease",
+			leaseHeld: true,
+			wantSweep: true,
+		},
+		{
+			name:      "skips sweep when the lease is held by another replica",
+			leaseHeld: false,
+			wantSweep: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			leased := make(chan struct{}, 1)
+			swept := make(chan struct{}, 1)
+
+			ddbMock := &th.DBClientMock{
+				AcquireLeaseFunc: func(ctx context.Context, name, owner string, d time.Duration) (bool, error) {
+					assert.Equal(t, tokenSweeperLease, name)
+					assert.Equal(t, "replica1", owner)
+					select {
+					case leased <- struct{}{}:
+					default:
+					}
+					return tt.leaseHeld, nil
+				},
+				ListProjectEntriesFunc: func(ctx context.Context) ([]db.ProjectEntry, error) {
+					select {
+					case swept <- struct{}{}:
+					default:
+					}
+					return []db.ProjectEntry{}, nil
+				},
+			}
+
+			h := handler{
+				logger: log.NewNopLogger(),
+				newCredentialsProvider: func(a credentials.Authorization, env env.Vars, h http.Header, vaultConfigFn credentials.VaultConfigFn, vaultSvcFn credentials.VaultSvcFn) (credentials.Provider, error) {
+					assert.Equal(t, credentials.AdminAuthorization("vault", testPassword), a)
+					return &th.CredsProviderMock{}, nil
+				},
+				env:       env.Vars{AdminSecret: testPassword, CredentialsProvider: "vault"},
+				ddbClient: ddbMock,
+			}
+
+			go h.runTokenSweeper(ctx, time.Millisecond, "replica1")
+
+			select {
+			case <-leased:
+			case <-time.After(time.Second):
+				t.Fatal("lease was not acquired")
+			}
+
+			select {
+			case <-swept:
+				assert.True(t, tt.wantSweep, "unexpected sweep")
+			case <-time.After(50 * time.Millisecond):
+				assert.False(t, tt.wantSweep, "tokens were not swept")
+			}
+		})
+	}
+}
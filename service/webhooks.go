@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/responses"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/internal/validations"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/workflow"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// webhookSecretBytes is the number of random bytes of a webhook's
+	// secret.
+	webhookSecretBytes = 32
+
+	// webhookEventMaxAge is how long after an event its workflow can be seen
+	// for the event to be notified. A new watch sees every workflow Argo
+	// still has, whose older events are not notified again.
+	webhookEventMaxAge = 10 * time.Minute
+	// webhookEventRetention is how long notified events are recorded, which
+	// is longer than they can be notified.
+	webhookEventRetention = 24 * time.Hour
+
+	webhookMaxAttempts = 5
+	webhookMinBackoff  = time.Second
+	// webhookRewatchDelay is how long the notifier waits to watch workflows
+	// again once a watch has ended.
+	webhookRewatchDelay = 5 * time.Second
+
+	webhookEventHeader     = "X-Cello-Event"
+	webhookDeliveryHeader  = "X-Cello-Delivery"
+	webhookSignatureHeader = "X-Cello-Signature-256"
+)
+
+// webhookPhaseEvents maps the phases of workflows to their events.
+var webhookPhaseEvents = map[string]string{
+	"running":   types.WebhookEventStarted,
+	"succeeded": types.WebhookEventSucceeded,
+	"failed":    types.WebhookEventFailed,
+	"error":     types.WebhookEventErrored,
+}
+
+// webhookEvent is the body of the requests delivering an event. Its ID is
+// the same for every webhook and attempt the event is delivered to.
+type webhookEvent struct {
+	ID           string `json:"id"`
+	Event        string `json:"event"`
+	Timestamp    string `json:"timestamp"`
+	Project      string `json:"project"`
+	Target       string `json:"target"`
+	Type         string `json:"type"`
+	SHA          string `json:"sha,omitempty"`
+	WorkflowName string `json:"workflow_name"`
+}
+
+// newWebhookSecret returns a random secret to sign a webhook's events with.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// webhookSignature returns the signature of the body, which is its
+// HMAC-SHA256 with the webhook's secret.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Creates a webhook
+func (h handler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	projectName := mux.Vars(r)["projectName"]
+
+	l := h.requestLogger(r, "op", "create-webhook", "project", projectName)
+
+	level.Debug(l).Log("message", "reading request body")
+	reqBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		level.Error(l).Log("message", "error reading request body", "error", err)
+		h.errorResponse(w, "error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	var cwr requests.CreateWebhook
+	if err := json.Unmarshal(reqBody, &cwr); err != nil {
+		level.Error(l).Log("message", "error decoding request", "error", err)
+		h.errorResponse(w, "error decoding request", http.StatusBadRequest)
+		return
+	}
+
+	if err := cwr.Validate(); err != nil {
+		level.Error(l).Log("message", "error validating request", "error", err)
+		h.errorResponse(w, fmt.Sprintf("invalid request, %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if !h.projectEntryExists(ctx, l, w, projectName) {
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		level.Error(l).Log("message", "error generating webhook secret", "error", err)
+		h.errorResponse(w, "error creating webhook", http.StatusInternalServerError)
+		return
+	}
+
+	we := db.WebhookEntry{
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Events:    cwr.Events,
+		ID:        uuid.NewString(),
+		ProjectID: projectName,
+		Secret:    secret,
+		URL:       cwr.URL,
+	}
+
+	level.Debug(l).Log("message", "creating webhook in database", "webhook", we.ID)
+	if err := h.ddbClient.CreateWebhookEntry(ctx, we); err != nil {
+		level.Error(l).Log("message", "error creating webhook", "error", err)
+		h.errorResponse(w, "error creating webhook", http.StatusInternalServerError)
+		return
+	}
+
+	resp := responses.CreateWebhook{
+		CreatedAt: we.CreatedAt,
+		Events:    we.Events,
+		Secret:    we.Secret,
+		URL:       we.URL,
+		WebhookID: we.ID,
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		level.Error(l).Log("message", "error serializing webhook", "error", err)
+		h.errorResponse(w, "error creating webhook", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Lists the webhooks of a project, without their secrets
+func (h handler) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	projectName := mux.Vars(r)["projectName"]
+
+	l := h.requestLogger(r, "op", "list-webhooks", "project", projectName)
+
+	ctx := r.Context()
+	if !h.projectEntryExists(ctx, l, w, projectName) {
+		return
+	}
+
+	webhooks, err := h.ddbClient.ListWebhookEntries(ctx, projectName)
+	if err != nil {
+		level.Error(l).Log("message", "error listing webhooks", "error", err)
+		h.errorResponse(w, "error listing webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt < webhooks[j].CreatedAt })
+
+	resp := []responses.ListWebhooks{}
+	for _, we := range webhooks {
+		resp = append(resp, responses.ListWebhooks{
+			CreatedAt: we.CreatedAt,
+			Events:    we.Events,
+			URL:       we.URL,
+			WebhookID: we.ID,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		level.Error(l).Log("message", "error serializing webhooks", "error", err)
+		h.errorResponse(w, "error listing webhooks", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Deletes a webhook
+func (h handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	projectName := vars["projectName"]
+	webhookID := vars["webhookID"]
+
+	l := h.requestLogger(r, "op", "delete-webhook", "project", projectName, "webhook", webhookID)
+
+	if err := h.ddbClient.DeleteWebhookEntry(r.Context(), projectName, webhookID); err != nil {
+		if errors.Is(err, db.ErrWebhookNotFound) {
+			h.errorResponse(w, "webhook does not exist", http.StatusNotFound)
+			return
+		}
+		level.Error(l).Log("message", "error deleting webhook", "error", err)
+		h.errorResponse(w, "error deleting webhook", http.StatusInternalServerError)
+		return
+	}
+}
+
+// projectEntryExists returns whether the project is in the database, it
+// writes the error response when it is not.
+func (h handler) projectEntryExists(ctx context.Context, l log.Logger, w http.ResponseWriter, projectName string) bool {
+	if _, err := h.ddbClient.ReadProjectEntry(ctx, projectName); err != nil {
+		if errors.Is(err, db.ErrProjectNotFound) {
+			h.errorResponse(w, "project does not exist", http.StatusNotFound)
+			return false
+		}
+		level.Error(l).Log("message", "error retrieving project", "error", err)
+		h.errorResponse(w, "error retrieving project", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// webhookNotifier watches the workflows submitted by the service and
+// delivers their events to the webhooks of their projects. Every replica
+// watches workflows, each event is delivered by the replica which records it
+// first.
+type webhookNotifier struct {
+	h      handler
+	l      log.Logger
+	client *http.Client
+	// backoff is the delay before the second attempt of a delivery, which
+	// doubles with each attempt.
+	backoff time.Duration
+	// notified is the time of the events seen recently, by workflow and
+	// event, so updates of workflows which do not change their phase are
+	// skipped without reading the database.
+	notified   map[string]time.Time
+	deliveries sync.WaitGroup
+}
+
+// newWebhookClient returns the client webhooks are delivered with. It
+// refuses to connect to metadata services, and to loopback, private and
+// unspecified addresses outside of the allowed networks, which webhook URLs
+// with names resolving to them would otherwise reach. Webhooks are not
+// delivered through a proxy, whose address would be checked instead.
+func newWebhookClient(timeout time.Duration, allowedNetworks []string) (*http.Client, error) {
+	var allowed []*net.IPNet
+	for _, n := range allowedNetworks {
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook allowed network %q: %w", n, err)
+		}
+		allowed = append(allowed, ipNet)
+	}
+
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if validations.IsMetadataHost(host) {
+				return fmt.Errorf("webhook address %s is a link-local or metadata service host", host)
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified()) {
+				return nil
+			}
+			for _, n := range allowed {
+				if n.Contains(ip) {
+					return nil
+				}
+			}
+			return fmt.Errorf("webhook address %s is a loopback, private or unspecified address", host)
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+func newWebhookNotifier(h handler, client *http.Client) *webhookNotifier {
+	return &webhookNotifier{
+		h:        h,
+		l:        log.With(h.logger, "op", "webhook-notifier"),
+		client:   client,
+		backoff:  webhookMinBackoff,
+		notified: map[string]time.Time{},
+	}
+}
+
+// run watches workflows until the context is done, watching them again when
+// a watch ends, and waits for the deliveries in progress.
+func (n *webhookNotifier) run(ctx context.Context) {
+	defer n.deliveries.Wait()
+
+	for {
+		argoCtx, cancel := n.h.argoContext(ctx)
+		err := n.h.argo.Watch(argoCtx, func(run workflow.Run) {
+			n.notify(ctx, run, time.Now())
+		})
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+		level.Warn(n.l).Log("message", "workflow watch ended", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(webhookRewatchDelay):
+		}
+	}
+}
+
+// notify delivers the events of a workflow which have not been notified.
+// Workflows which completed before they were seen running have their start
+// notified with their completion.
+func (n *webhookNotifier) notify(ctx context.Context, run workflow.Run, now time.Time) {
+	event, ok := webhookPhaseEvents[run.Phase]
+	if !ok || run.Project == "" {
+		return
+	}
+
+	if event != types.WebhookEventStarted {
+		n.notifyEvent(ctx, run, types.WebhookEventStarted, run.StartedAt, now)
+		n.notifyEvent(ctx, run, event, run.FinishedAt, now)
+		return
+	}
+	n.notifyEvent(ctx, run, event, run.StartedAt, now)
+}
+
+func (n *webhookNotifier) notifyEvent(ctx context.Context, run workflow.Run, event string, at, now time.Time) {
+	key := run.Name + "/" + event
+	if _, ok := n.notified[key]; ok || at.IsZero() || now.Sub(at) > webhookEventMaxAge {
+		return
+	}
+
+	for k, t := range n.notified {
+		if now.Sub(t) > webhookEventMaxAge {
+			delete(n.notified, k)
+		}
+	}
+	n.notified[key] = at
+
+	l := log.With(n.l, "workflow", run.Name, "event", event)
+
+	recorded, err := n.h.ddbClient.RecordWebhookEvent(ctx, run.Name, event, now.Add(webhookEventRetention))
+	if err != nil {
+		// The event is notified when the workflow is seen again.
+		delete(n.notified, key)
+		level.Error(l).Log("message", "error recording webhook event", "error", err)
+		return
+	}
+	if !recorded {
+		level.Debug(l).Log("message", "event notified by another replica")
+		return
+	}
+
+	webhooks, err := n.h.ddbClient.ListWebhookEntries(ctx, run.Project)
+	if err != nil {
+		level.Error(l).Log("message", "error listing webhooks", "project", run.Project, "error", err)
+		return
+	}
+
+	id := uuid.NewString()
+	body, err := json.Marshal(webhookEvent{
+		ID:           id,
+		Event:        event,
+		Timestamp:    at.UTC().Format(time.RFC3339),
+		Project:      run.Project,
+		Target:       run.Target,
+		Type:         run.Type,
+		SHA:          run.SHA,
+		WorkflowName: run.Name,
+	})
+	if err != nil {
+		level.Error(l).Log("message", "error serializing webhook event", "error", err)
+		return
+	}
+
+	for _, we := range webhooks {
+		if len(we.Events) > 0 && !containsString(we.Events, event) {
+			continue
+		}
+
+		n.deliveries.Add(1)
+		go func(we db.WebhookEntry) {
+			defer n.deliveries.Done()
+			n.deliver(ctx, log.With(l, "webhook", we.ID), we, event, id, body)
+		}(we)
+	}
+}
+
+// deliver posts the event to the webhook. Deliveries failing with an error
+// or a 429 or 5xx status are attempted again with exponential backoff.
+func (n *webhookNotifier) deliver(ctx context.Context, l log.Logger, we db.WebhookEntry, event, id string, body []byte) {
+	signature := webhookSignature(we.Secret, body)
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.post(ctx, we.URL, event, id, signature, body)
+		if err == nil {
+			level.Info(l).Log("message", "delivered webhook event", "attempts", attempt)
+			return
+		}
+
+		if !retryable || attempt == webhookMaxAttempts {
+			level.Error(l).Log("message", "failed to deliver webhook event", "attempts", attempt, "error", err)
+			return
+		}
+		level.Warn(l).Log("message", "retrying webhook event", "attempts", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes an attempt to deliver an event, and returns whether a failed
+// attempt can be retried.
+func (n *webhookNotifier) post(ctx context.Context, url, event, id, signature string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cello")
+	req.Header.Set(webhookEventHeader, event)
+	req.Header.Set(webhookDeliveryHeader, id)
+	req.Header.Set(webhookSignatureHeader, signature)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError, err
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
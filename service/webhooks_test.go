@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cello-proj/cello/internal/requests"
+	"github.com/cello-proj/cello/internal/types"
+	"github.com/cello-proj/cello/service/internal/db"
+	"github.com/cello-proj/cello/service/internal/workflow"
+	th "github.com/cello-proj/cello/service/test/testhelpers"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	tests := []test{
+		{
+			name:       "fails to create webhook when not admin",
+			req:        requests.CreateWebhook{URL: "https://hooks.example.com/cello"},
+			want:       http.StatusUnauthorized,
+			respFile:   "TestCreateWebhook/fails_to_create_webhook_when_not_admin_response.json",
+			authHeader: userAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "POST",
+		},
+		{
+			name:       "fails to create webhook when invalid request",
+			req:        requests.CreateWebhook{URL: "https://hooks.example.com/cello", Events: []string{"workflow.deleted"}},
+			want:       http.StatusBadRequest,
+			respFile:   "TestCreateWebhook/fails_to_create_webhook_when_invalid_request_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "POST",
+		},
+		{
+			name:       "fails to create webhook when project does not exist",
+			req:        requests.CreateWebhook{URL: "https://hooks.example.com/cello"},
+			want:       http.StatusNotFound,
+			respFile:   "TestCreateWebhook/fails_to_create_webhook_when_project_does_not_exist_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "POST",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{}, db.ErrProjectNotFound
+				},
+			},
+		},
+		{
+			name:       "fails to create webhook when database error",
+			req:        requests.CreateWebhook{URL: "https://hooks.example.com/cello"},
+			want:       http.StatusInternalServerError,
+			respFile:   "TestCreateWebhook/fails_to_create_webhook_when_database_error_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "POST",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project}, nil
+				},
+				CreateWebhookEntryFunc: func(ctx context.Context, we db.WebhookEntry) error {
+					return errors.New("boom")
+				},
+			},
+		},
+		{
+			name:       "can create webhook",
+			req:        requests.CreateWebhook{URL: "https://hooks.example.com/cello", Events: []string{types.WebhookEventFailed}},
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "POST",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project}, nil
+				},
+				CreateWebhookEntryFunc: func(ctx context.Context, we db.WebhookEntry) error {
+					if we.ProjectID != "project1" || we.URL != "https://hooks.example.com/cello" || we.ID == "" || len(we.Secret) != 2*webhookSecretBytes {
+						return errors.New("unexpected webhook")
+					}
+					return nil
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestListWebhooks(t *testing.T) {
+	tests := []test{
+		{
+			name:       "fails to list webhooks when project does not exist",
+			want:       http.StatusNotFound,
+			respFile:   "TestListWebhooks/fails_to_list_webhooks_when_project_does_not_exist_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "GET",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{}, db.ErrProjectNotFound
+				},
+			},
+		},
+		{
+			name:       "can list webhooks",
+			want:       http.StatusOK,
+			respFile:   "TestListWebhooks/can_list_webhooks_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "GET",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project}, nil
+				},
+				ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+					return []db.WebhookEntry{
+						{
+							CreatedAt: "2024-01-02T04:00:00Z",
+							ID:        "6f1c0b8e-7d2a-4c1e-9b5f-2a3d4e5f6a7b",
+							ProjectID: project,
+							Secret:    "secret2",
+							URL:       "http://tracker.deploy.svc.cluster.local/cello",
+						},
+						{
+							CreatedAt: "2024-01-02T03:00:00Z",
+							Events:    []string{types.WebhookEventFailed, types.WebhookEventErrored},
+							ID:        "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d",
+							ProjectID: project,
+							Secret:    "secret1",
+							URL:       "https://hooks.slack.com/services/T000/B000/XXXX",
+						},
+					}, nil
+				},
+			},
+		},
+		{
+			name:       "list webhooks error",
+			want:       http.StatusInternalServerError,
+			respFile:   "TestListWebhooks/list_webhooks_error_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks",
+			method:     "GET",
+			ddbMock: &th.DBClientMock{
+				ReadProjectEntryFunc: func(ctx context.Context, project string) (db.ProjectEntry, error) {
+					return db.ProjectEntry{ProjectID: project}, nil
+				},
+				ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+					return nil, errors.New("boom")
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	tests := []test{
+		{
+			name:       "webhook not found",
+			want:       http.StatusNotFound,
+			respFile:   "TestDeleteWebhook/webhook_not_found_response.json",
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks/0a1b2c3d",
+			method:     "DELETE",
+			ddbMock: &th.DBClientMock{
+				DeleteWebhookEntryFunc: func(ctx context.Context, project, id string) error {
+					return db.ErrWebhookNotFound
+				},
+			},
+		},
+		{
+			name:       "can delete webhook",
+			want:       http.StatusOK,
+			authHeader: adminAuthHeader,
+			url:        "/projects/project1/webhooks/0a1b2c3d",
+			method:     "DELETE",
+			ddbMock: &th.DBClientMock{
+				DeleteWebhookEntryFunc: func(ctx context.Context, project, id string) error {
+					if project != "project1" || id != "0a1b2c3d" {
+						return errors.New("unexpected webhook")
+					}
+					return nil
+				},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+// delivery is a request received by the test webhook.
+type delivery struct {
+	event     webhookEvent
+	header    http.Header
+	signature string
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	run := workflow.Run{
+		Name:      "project1-target1-abcde",
+		Project:   "project1",
+		Target:    "target1",
+		Type:      "sync",
+		SHA:       "8458fd753f9fde51882414564c20df6d4c34a90e",
+		Phase:     "running",
+		StartedAt: now.Add(-time.Minute),
+	}
+
+	tests := []struct {
+		name   string
+		run    func(workflow.Run) workflow.Run
+		events []string
+		// recorded are the events already notified by another replica.
+		recorded map[string]bool
+		// statuses are the statuses the webhook responds with, then 200.
+		statuses     []int
+		wantEvents   []string
+		wantAttempts int
+	}{
+		{
+			name:         "notifies start",
+			wantEvents:   []string{types.WebhookEventStarted},
+			wantAttempts: 1,
+		},
+		{
+			name: "notifies start with completion",
+			run: func(r workflow.Run) workflow.Run {
+				r.Phase, r.FinishedAt = "failed", now.Add(-time.Second)
+				return r
+			},
+			wantEvents:   []string{types.WebhookEventStarted, types.WebhookEventFailed},
+			wantAttempts: 2,
+		},
+		{
+			name: "notifies events of the webhook",
+			run: func(r workflow.Run) workflow.Run {
+				r.Phase, r.FinishedAt = "error", now.Add(-time.Second)
+				return r
+			},
+			events:       []string{types.WebhookEventErrored},
+			wantEvents:   []string{types.WebhookEventErrored},
+			wantAttempts: 1,
+		},
+		{
+			name:     "skips events notified by another replica",
+			recorded: map[string]bool{types.WebhookEventStarted: true},
+		},
+		{
+			name: "skips old events",
+			run: func(r workflow.Run) workflow.Run {
+				r.Phase, r.StartedAt, r.FinishedAt = "succeeded", now.Add(-time.Hour), now.Add(-webhookEventMaxAge-time.Second)
+				return r
+			},
+		},
+		{
+			name: "skips pending workflows",
+			run: func(r workflow.Run) workflow.Run {
+				r.Phase, r.StartedAt = "pending", time.Time{}
+				return r
+			},
+		},
+		{
+			name:         "retries server errors",
+			statuses:     []int{http.StatusBadGateway, http.StatusTooManyRequests},
+			wantEvents:   []string{types.WebhookEventStarted},
+			wantAttempts: 3,
+		},
+		{
+			name:         "does not retry client errors",
+			statuses:     []int{http.StatusBadRequest},
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var deliveries []delivery
+			attempts := 0
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				if len(tt.statuses) >= attempts {
+					w.WriteHeader(tt.statuses[attempts-1])
+					return
+				}
+
+				var event webhookEvent
+				assert.NoError(t, json.Unmarshal(body, &event))
+				assert.Equal(t, webhookSignature("secret1", body), r.Header.Get(webhookSignatureHeader))
+				deliveries = append(deliveries, delivery{event: event, header: r.Header})
+			}))
+			defer receiver.Close()
+
+			dbMock := &th.DBClientMock{
+				RecordWebhookEventFunc: func(ctx context.Context, workflowName, event string, expiresAt time.Time) (bool, error) {
+					assert.Equal(t, run.Name, workflowName)
+					assert.Equal(t, now.Add(webhookEventRetention), expiresAt)
+					return !tt.recorded[event], nil
+				},
+				ListWebhookEntriesFunc: func(ctx context.Context, project string) ([]db.WebhookEntry, error) {
+					assert.Equal(t, "project1", project)
+					return []db.WebhookEntry{{ID: "0a1b2c3d", ProjectID: project, Events: tt.events, Secret: "secret1", URL: receiver.URL}}, nil
+				},
+			}
+
+			n := newWebhookNotifier(handler{logger: log.NewNopLogger(), ddbClient: dbMock}, receiver.Client())
+			n.backoff = time.Millisecond
+
+			r := run
+			if tt.run != nil {
+				r = tt.run(r)
+			}
+			n.notify(context.Background(), r, now)
+			n.deliveries.Wait()
+
+			assert.Equal(t, tt.wantAttempts, attempts)
+
+			var gotEvents []string
+			for _, d := range deliveries {
+				gotEvents = append(gotEvents, d.event.Event)
+
+				assert.Equal(t, d.event.Event, d.header.Get(webhookEventHeader))
+				assert.Equal(t, d.event.ID, d.header.Get(webhookDeliveryHeader))
+				assert.Equal(t, "application/json", d.header.Get("Content-Type"))
+				assert.Equal(t, "project1", d.event.Project)
+				assert.Equal(t, "target1", d.event.Target)
+				assert.Equal(t, "sync", d.event.Type)
+				assert.Equal(t, run.SHA, d.event.SHA)
+				assert.Equal(t, run.Name, d.event.WorkflowName)
+			}
+			assert.ElementsMatch(t, tt.wantEvents, gotEvents)
+		})
+	}
+}
+
+func TestWebhookNotifierSkipsNotifiedEvents(t *testing.T) {
+	now := time.Now()
+	dbMock := &th.DBClientMock{
+		RecordWebhookEventFunc: func(ctx context.Context, workflowName, event string, expiresAt time.Time) (bool, error) {
+			return false, nil
+		},
+	}
+
+	n := newWebhookNotifier(handler{logger: log.NewNopLogger(), ddbClient: dbMock}, http.DefaultClient)
+	run := workflow.Run{Name: "project1-target1-abcde", Project: "project1", Phase: "running", StartedAt: now}
+
+	// A running workflow is updated as its steps run.
+	n.notify(context.Background(), run, now)
+	n.notify(context.Background(), run, now.Add(time.Second))
+	assert.Len(t, dbMock.RecordWebhookEventCalls(), 1)
+
+	// Events are forgotten once they can no longer be notified.
+	later := now.Add(webhookEventMaxAge + time.Minute)
+	run.Name, run.StartedAt = "project1-target1-fghij", later
+	n.notify(context.Background(), run, later)
+	assert.Len(t, dbMock.RecordWebhookEventCalls(), 2)
+	assert.Len(t, n.notified, 1)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	assert.Equal(t,
+		"sha256=ddcb4966f26a0ec7fa80110c63bf45a6d097e8acdaff19dab9fad1a1d1257a35",
+		webhookSignature("secret", []byte(`{"event":"workflow.started"}`)),
+	)
+}
+
+func TestWebhookClient(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	_, err := newWebhookClient(time.Second, []string{"127.0.0.1"})
+	assert.ErrorContains(t, err, `invalid webhook allowed network "127.0.0.1"`)
+
+	// Loopback addresses are refused unless their network is allowed.
+	client, err := newWebhookClient(time.Second, nil)
+	assert.NoError(t, err)
+
+	_, err = client.Get(receiver.URL)
+	assert.ErrorContains(t, err, "webhook address 127.0.0.1 is a loopback, private or unspecified address")
+
+	client, err = newWebhookClient(time.Second, []string{"127.0.0.0/8"})
+	assert.NoError(t, err)
+
+	resp, err := client.Get(receiver.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// Metadata services are refused before connecting.
+	_, err = client.Get("http://169.254.169.254/latest/meta-data/")
+	assert.ErrorContains(t, err, "webhook address 169.254.169.254 is a link-local or metadata service host")
+}